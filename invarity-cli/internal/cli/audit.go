@@ -3,9 +3,13 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"os/signal"
+	"strings"
 	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 
 	"github.com/invarity/invarity-cli/internal/client"
@@ -20,17 +24,112 @@ var auditCmd = &cobra.Command{
 var auditShowCmd = &cobra.Command{
 	Use:   "show <audit_id>",
 	Short: "Show an audit record",
-	Long: `Retrieves and displays an audit record by its ID.
+	Long: `Retrieves and displays one of the tenant's audit records by its ID.
 
 If the server does not yet support audit retrieval, a helpful message is displayed.`,
 	Example: `  invarity audit show abc123
-  invarity audit show abc123 --json`,
+  invarity audit show abc123 --tenant acme --json`,
 	Args: cobra.ExactArgs(1),
 	RunE: runAuditShow,
 }
 
+var auditListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List a tenant's audit records",
+	Long: `Lists the tenant's audit records, newest first, one page at a time.
+
+Filters combine. --since and --until take an RFC 3339 timestamp or a duration
+before now (e.g. 24h). Pass the printed cursor to --cursor for the next page,
+or use --all to fetch every page.`,
+	Example: `  invarity audit list --tenant acme
+  invarity audit list --decision deny --since 24h
+  invarity audit list --principal billing-agent --risk-tier critical --all --json`,
+	Args: cobra.NoArgs,
+	RunE: runAuditList,
+}
+
+var auditSearchCmd = &cobra.Command{
+	Use:   "search <text>",
+	Short: "Search audit records by user intent",
+	Long: `Lists the tenant's audit records whose user intent contains the text
+(case-insensitive), newest first. Accepts the same filters as 'audit list'.`,
+	Example: `  invarity audit search "wire transfer"
+  invarity audit search invoice --reason threat --since 168h`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuditList,
+}
+
+var auditTailCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Show the latest audit records",
+	Long: `Shows the tenant's most recent audit records, oldest first. With --follow,
+keeps streaming new records as they are written until interrupted.`,
+	Example: `  invarity audit tail -n 50
+  invarity audit tail --follow --tenant acme
+  invarity audit tail -f --json | jq .decision`,
+	Args: cobra.NoArgs,
+	RunE: runAuditTail,
+}
+
+var (
+	auditFilter    client.AuditListOptions
+	auditAll       bool
+	auditFollow    bool
+	auditTailLines int
+)
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify a tenant's tamper-evident audit chain",
+	Long: `Asks the server to walk the tenant's hash-chained audit log and check every
+record's hash, its link to the previous record, and the signed checkpoints.
+
+Reports gaps (deleted records), altered records, broken links and checkpoint
+mismatches. Exits with status 1 if the chain does not verify.`,
+	Example: `  invarity audit verify --tenant acme
+  invarity audit verify --tenant acme --json`,
+	Args: cobra.NoArgs,
+	RunE: runAuditVerify,
+}
+
+var auditReproduceCmd = &cobra.Command{
+	Use:   "reproduce <audit_id>",
+	Short: "Re-run a recorded decision with the artifacts it was made with",
+	Long: `Asks the server to re-run the deterministic stages of an audited decision with
+the tool manifest version, schema hash and configuration recorded in its
+snapshot, reusing the recorded model votes, and to compare the outcome.
+
+Exits with status 1 if the outcome differs or the decision cannot be reproduced
+(the record predates snapshots, or its tool manifest changed).`,
+	Example: `  invarity audit reproduce 6f1c2b9e-...
+  invarity audit reproduce 6f1c2b9e-... --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAuditReproduce,
+}
+
 func init() {
+	for _, cmd := range []*cobra.Command{auditListCmd, auditSearchCmd} {
+		cmd.Flags().StringVar(&auditFilter.PrincipalID, "principal", "", "Only records for this principal")
+		cmd.Flags().StringVar(&auditFilter.ActorID, "actor", "", "Only records from this actor")
+		cmd.Flags().StringVar(&auditFilter.ActionID, "action", "", "Only calls to this tool action")
+		cmd.Flags().StringVar(&auditFilter.Decision, "decision", "", "Only this decision (allow, escalate, deny)")
+		cmd.Flags().StringVar(&auditFilter.RiskTier, "risk-tier", "", "Only this risk tier (low, medium, high, critical)")
+		cmd.Flags().StringVar(&auditFilter.Reason, "reason", "", "Only records with this reason or reason code (e.g. threat)")
+		cmd.Flags().StringVar(&auditFilter.Since, "since", "", "Only records at or after this time (RFC 3339 or duration, e.g. 24h)")
+		cmd.Flags().StringVar(&auditFilter.Until, "until", "", "Only records at or before this time (RFC 3339 or duration)")
+		cmd.Flags().IntVar(&auditFilter.Limit, "limit", 50, "Records per page (max 500)")
+		cmd.Flags().StringVar(&auditFilter.Cursor, "cursor", "", "Resume from a cursor printed by a previous page")
+		cmd.Flags().BoolVar(&auditAll, "all", false, "Fetch every page")
+	}
+	auditTailCmd.Flags().BoolVarP(&auditFollow, "follow", "f", false, "Keep streaming new records")
+	auditTailCmd.Flags().IntVarP(&auditTailLines, "lines", "n", 20, "Number of recent records to show first")
+
 	auditCmd.AddCommand(auditShowCmd)
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditSearchCmd)
+	auditCmd.AddCommand(auditTailCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+	auditCmd.AddCommand(auditReproduceCmd)
 }
 
 func runAuditShow(cmd *cobra.Command, args []string) error {
@@ -50,7 +149,7 @@ func runAuditShow(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	audit, rawJSON, err := c.GetAudit(ctx, auditID)
+	audit, rawJSON, err := c.GetAudit(ctx, auditTenant(cfg.TenantID), auditID)
 	if err != nil {
 		if client.IsNotSupportedError(err) {
 			printWarn("Server does not support audit retrieval yet.")
@@ -69,24 +168,407 @@ func runAuditShow(cmd *cobra.Command, args []string) error {
 	// Human-readable output
 	printSection("Audit Record")
 	printKeyValue("Audit ID", audit.AuditID)
-	if audit.Timestamp != "" {
-		printKeyValue("Timestamp", audit.Timestamp)
+	if audit.CreatedAt != "" {
+		printKeyValue("Timestamp", audit.CreatedAt)
 	}
 	if audit.Decision != "" {
-		printKeyValue("Decision", audit.Decision)
+		printKeyValue("Decision", getDecisionColor(audit.Decision).Sprint(audit.Decision))
+	}
+	if audit.RiskTier != "" {
+		printKeyValue("Risk Tier", getRiskColor(audit.RiskTier).Sprint(audit.RiskTier))
+	}
+	if audit.PrincipalID != "" {
+		printKeyValue("Principal", audit.PrincipalID)
+	}
+	printKeyValue("Action", audit.ToolCall.ActionID)
+	if audit.PipelineStep != "" {
+		printKeyValue("Decided At", audit.PipelineStep)
+	}
+	if audit.Sequence > 0 {
+		printKeyValue("Sequence", fmt.Sprintf("%d", audit.Sequence))
 	}
 
-	if audit.Request != nil {
-		printSection("Request")
-		reqJSON, _ := json.MarshalIndent(audit.Request, "  ", "  ")
-		dimColor.Fprintf(os.Stdout, "  %s\n", string(reqJSON))
+	if audit.UserIntent != "" {
+		printSection("User Intent")
+		fmt.Fprintf(os.Stdout, "  %s\n", audit.UserIntent)
 	}
 
-	if audit.Response != nil {
-		printSection("Response")
-		respJSON, _ := json.MarshalIndent(audit.Response, "  ", "  ")
-		dimColor.Fprintf(os.Stdout, "  %s\n", string(respJSON))
+	if len(audit.Reasons) > 0 {
+		printSection("Reasons")
+		for _, reason := range audit.Reasons {
+			fmt.Fprintf(os.Stdout, "  • %s\n", reason)
+		}
 	}
 
+	if len(audit.ToolCall.Args) > 0 {
+		printSection("Arguments")
+		argsJSON, _ := json.MarshalIndent(audit.ToolCall.Args, "  ", "  ")
+		dimColor.Fprintf(os.Stdout, "  %s\n", string(argsJSON))
+	}
+
+	if audit.Snapshot != nil {
+		printSnapshot(audit.Snapshot)
+	}
+
+	return nil
+}
+
+// printSnapshot prints the artifacts a decision was made with.
+func printSnapshot(snapshot *client.DecisionSnapshot) {
+	printSection("Snapshot")
+	printKeyValue("Firewall", snapshot.FirewallVersion)
+	if tool := snapshot.Tool; tool != nil {
+		printKeyValue("Tool", fmt.Sprintf("%s@%s (%s)", tool.ToolID, tool.Version, tool.SchemaHash))
+		if tool.ToolsetID != "" {
+			printKeyValue("Toolset", fmt.Sprintf("%s@%s", tool.ToolsetID, tool.ToolsetRevision))
+		}
+		printKeyValue("Resolved Via", tool.ResolvedVia)
+	}
+	for _, voter := range snapshot.Voters {
+		printKeyValue("Voter "+voter.VoterID, formatModelSnapshot(voter))
+	}
+	if snapshot.Sentinel != nil {
+		printKeyValue("Sentinel", formatModelSnapshot(*snapshot.Sentinel))
+	}
+}
+
+func formatModelSnapshot(m client.ModelSnapshot) string {
+	model := m.Model
+	if model == "" {
+		model = "unknown model"
+	}
+	if m.PromptVersion == "" {
+		return model
+	}
+	return model + ", prompt " + m.PromptVersion
+}
+
+func runAuditList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	opts := auditFilter
+	if len(args) > 0 {
+		opts.Query = args[0]
+	}
+	now := time.Now()
+	if opts.Since, err = parseAuditTime(opts.Since, now); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if opts.Until, err = parseAuditTime(opts.Until, now); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+
+	c := newClient(cfg)
+	tenantID := auditTenant(cfg.TenantID)
+
+	var records []*client.AuditRecord
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		page, rawJSON, err := c.ListAudit(ctx, tenantID, opts)
+		cancel()
+		if err != nil {
+			if client.IsNotSupportedError(err) {
+				printWarn("Server does not support audit listing yet.")
+				os.Exit(ExitNetworkError)
+			}
+			printError("Failed to list audit records: %v", err)
+			os.Exit(ExitNetworkError)
+		}
+
+		if !auditAll {
+			if cfgJSON {
+				printJSON(rawJSON)
+				return nil
+			}
+			printAuditRecords(page.Records)
+			if page.NextCursor != "" {
+				printDim("\nMore records: --cursor %s", page.NextCursor)
+			}
+			return nil
+		}
+
+		records = append(records, page.Records...)
+		if page.NextCursor == "" {
+			break
+		}
+		opts.Cursor = page.NextCursor
+	}
+
+	if cfgJSON {
+		data, _ := json.MarshalIndent(client.AuditListResponse{TenantID: tenantID, Records: records}, "", "  ")
+		printJSON(data)
+		return nil
+	}
+	printAuditRecords(records)
+	return nil
+}
+
+func runAuditTail(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+	tenantID := auditTenant(cfg.TenantID)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cursor := ""
+	onEvent := func(event *client.AuditTailEvent) error {
+		if event.Cursor != "" {
+			cursor = event.Cursor
+		}
+		if event.Record == nil {
+			return nil
+		}
+		if cfgJSON {
+			printJSON(event.RawRecord)
+			return nil
+		}
+		printAuditRecord(event.Record)
+		return nil
+	}
+
+	if !cfgJSON {
+		printAuditHeader()
+	}
+	for {
+		// The server ends followed streams periodically; resume after the last record
+		err := c.TailAudit(ctx, tenantID, cursor, auditTailLines, auditFollow, onEvent)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case client.IsNotSupportedError(err):
+			printWarn("Server does not support audit tail yet.")
+			os.Exit(ExitNetworkError)
+		case err != nil && !auditFollow:
+			printError("Failed to tail audit records: %v", err)
+			os.Exit(ExitNetworkError)
+		case err != nil:
+			printWarn("Audit stream interrupted, reconnecting: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(2 * time.Second):
+			}
+		}
+		if !auditFollow {
+			return nil
+		}
+	}
+}
+
+// auditTenant returns the configured tenant, or "default".
+func auditTenant(tenantID string) string {
+	if tenantID == "" {
+		return "default"
+	}
+	return tenantID
+}
+
+// parseAuditTime accepts an RFC 3339 timestamp or a duration before now.
+func parseAuditTime(value string, now time.Time) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d).UTC().Format(time.RFC3339), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return "", fmt.Errorf("want an RFC 3339 timestamp or a duration like 24h, got %q", value)
+	}
+	return t.UTC().Format(time.RFC3339), nil
+}
+
+func printAuditRecords(records []*client.AuditRecord) {
+	if len(records) == 0 {
+		printDim("No audit records found")
+		return
+	}
+	printAuditHeader()
+	for _, record := range records {
+		printAuditRecord(record)
+	}
+}
+
+func printAuditHeader() {
+	dimColor.Fprintf(os.Stdout, "%-20s  %-36s  %-8s  %-8s  %-24s  %s\n", "TIME", "AUDIT ID", "DECISION", "RISK", "ACTION", "PRINCIPAL")
+}
+
+func printAuditRecord(record *client.AuditRecord) {
+	created := record.CreatedAt
+	if t, err := time.Parse(time.RFC3339Nano, record.CreatedAt); err == nil {
+		created = t.Local().Format("2006-01-02 15:04:05")
+	}
+	fmt.Fprintf(os.Stdout, "%-20s  %-36s  %s  %s  %-24s  %s\n",
+		created,
+		record.AuditID,
+		getDecisionColor(record.Decision).Sprintf("%-8s", record.Decision),
+		getRiskColor(record.RiskTier).Sprintf("%-8s", record.RiskTier),
+		record.ToolCall.ActionID,
+		record.PrincipalID,
+	)
+}
+
+// getDecisionColor matches the decision colors of the simulate summary.
+func getDecisionColor(decision string) *color.Color {
+	switch strings.ToLower(decision) {
+	case "allow", "allowed", "approve", "approved":
+		return successColor
+	case "deny", "denied", "block", "blocked", "reject", "rejected":
+		return errorColor
+	case "review", "escalate", "pending":
+		return warnColor
+	default:
+		return infoColor
+	}
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	tenantID := auditTenant(cfg.TenantID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	report, rawJSON, err := c.VerifyAudit(ctx, tenantID)
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support audit verification yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to verify audit chain: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	// JSON output
+	if cfgJSON {
+		printJSON(rawJSON)
+		if !report.Valid {
+			os.Exit(ExitValidationError)
+		}
+		return nil
+	}
+
+	// Human-readable output
+	if report.Valid {
+		printSuccess("Audit chain verified")
+	} else {
+		printError("Audit chain verification failed")
+	}
+	printKeyValue("Tenant", report.TenantID)
+	printKeyValue("Records", fmt.Sprintf("%d", report.Records))
+	if report.Records > 0 {
+		printKeyValue("Sequences", fmt.Sprintf("%d-%d", report.FirstSequence, report.LastSequence))
+	}
+	if report.PrunedThrough > 0 {
+		printKeyValue("Purged", fmt.Sprintf("1-%d (retention, anchored by checkpoint)", report.PrunedThrough))
+	}
+	printKeyValue("Checkpoints", fmt.Sprintf("%d", report.Checkpoints))
+	if report.Unchained > 0 {
+		printWarn("%d records predate chaining and were not verified", report.Unchained)
+	}
+
+	if len(report.Issues) > 0 {
+		printSection("Issues")
+		for _, issue := range report.Issues {
+			line := fmt.Sprintf("  [%s] %s", issue.Type, issue.Detail)
+			if issue.AuditID != "" {
+				line += fmt.Sprintf(" (audit %s)", issue.AuditID)
+			}
+			errorColor.Fprintln(os.Stdout, line)
+		}
+		os.Exit(ExitValidationError)
+	}
+
+	return nil
+}
+
+func runAuditReproduce(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	result, rawJSON, err := c.ReproduceAudit(ctx, auditTenant(cfg.TenantID), args[0])
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support decision reproduction yet.")
+			os.Exit(ExitNetworkError)
+		}
+		if rawJSON != nil {
+			printError("%v", err)
+			os.Exit(ExitValidationError)
+		}
+		printError("Failed to reproduce decision: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	// JSON output
+	if cfgJSON {
+		printJSON(rawJSON)
+		if !result.Reproduced {
+			os.Exit(ExitValidationError)
+		}
+		return nil
+	}
+
+	// Human-readable output
+	if result.Reproduced {
+		printSuccess("Decision reproduced")
+	} else {
+		printError("Decision did not reproduce")
+	}
+	printKeyValue("Audit ID", result.AuditID)
+	printKeyValue("Recorded", getDecisionColor(result.Recorded.Decision).Sprint(result.Recorded.Decision))
+	printKeyValue("Re-run", getDecisionColor(result.Rerun.Decision).Sprint(result.Rerun.Decision))
+	printKeyValue("Re-run By", result.FirewallVersion)
+	if result.Incomplete {
+		printWarn("A model stage had no recorded output and was treated as unavailable")
+	}
+	if result.Snapshot != nil {
+		printSnapshot(result.Snapshot)
+	}
+
+	if len(result.Differences) > 0 {
+		printSection("Differences")
+		for _, d := range result.Differences {
+			errorColor.Fprintf(os.Stdout, "  • %s\n", d)
+		}
+		os.Exit(ExitValidationError)
+	}
 	return nil
 }
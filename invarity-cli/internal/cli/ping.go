@@ -3,17 +3,25 @@ package cli
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
 	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/invarity/invarity-cli/internal/client"
 )
 
 var pingCmd = &cobra.Command{
 	Use:   "ping",
-	Short: "Check server health",
-	Long:  `Sends a health check request to the Invarity server and displays the status.`,
-	RunE:  runPing,
+	Short: "Check server health and readiness",
+	Long: `Sends a health check request to the Invarity server, then checks its
+readiness and displays the result of each dependency check.
+
+Exits with code 2 if the server is unreachable or not ready. A server that is
+degraded (only optional dependencies failing) is reported with a warning.`,
+	RunE: runPing,
 }
 
 func runPing(cmd *cobra.Command, args []string) error {
@@ -37,9 +45,22 @@ func runPing(cmd *cobra.Command, args []string) error {
 		os.Exit(ExitNetworkError)
 	}
 
+	ready, err := c.Ready(ctx)
+	if err != nil && !client.IsNotSupportedError(err) {
+		printError("Readiness check failed: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
 	if cfgJSON {
-		jsonOut, _ := json.MarshalIndent(health, "", "  ")
+		out := map[string]interface{}{"health": health}
+		if ready != nil {
+			out["readiness"] = ready
+		}
+		jsonOut, _ := json.MarshalIndent(out, "", "  ")
 		printJSON(jsonOut)
+		if ready != nil && ready.Status == "not_ready" {
+			os.Exit(ExitNetworkError)
+		}
 		return nil
 	}
 
@@ -51,5 +72,48 @@ func runPing(cmd *cobra.Command, args []string) error {
 		printKeyValue("Version", health.Version)
 	}
 
+	if ready == nil {
+		printWarn("Server does not support readiness checks yet.")
+		return nil
+	}
+
+	printSection("Dependencies")
+	printReadiness(ready)
+
+	switch ready.Status {
+	case "not_ready":
+		printError("Server is not ready: a required dependency is failing")
+		os.Exit(ExitNetworkError)
+	case "degraded":
+		printWarn("Server is degraded: an optional dependency is failing")
+	default:
+		printSuccess("Server is ready")
+	}
+
 	return nil
 }
+
+// printReadiness prints each dependency check, sorted by name.
+func printReadiness(ready *client.ReadinessResponse) {
+	names := make([]string, 0, len(ready.Checks))
+	for name := range ready.Checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		check := ready.Checks[name]
+		requirement := "optional"
+		if check.Required {
+			requirement = "required"
+		}
+		line := fmt.Sprintf("%-4s  %s, %.1fms", check.Status, requirement, check.LatencyMs)
+		if check.Cached {
+			line += ", cached"
+		}
+		printKeyValue(name, line)
+		if check.Error != "" {
+			printKeyValue("  error", check.Error)
+		}
+	}
+}
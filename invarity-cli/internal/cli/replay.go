@@ -0,0 +1,417 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/invarity/invarity-cli/internal/client"
+	"github.com/invarity/invarity-cli/internal/validate"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Replay past traffic against a candidate toolset or policy",
+	Long: `Re-evaluates the tenant's audited tool calls against a candidate toolset
+revision, candidate tool manifests or candidate decision thresholds, and reports
+which decisions would change.`,
+}
+
+var replayRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Start a replay and wait for its report",
+	Long: `Starts a replay job on the server and waits for its diff report.
+
+Records are selected with --since, --until, --action and --limit (the most
+recent records are replayed, oldest first). The candidate is a registered
+toolset revision (--toolset and --revision), tool manifest files (--tool, may be
+repeated) and/or threshold overrides; anything not given keeps the current
+configuration.
+
+By default the voter and sentinel outputs recorded with each decision are
+reused (--llm recorded), so replays are fast and free. Records whose candidate
+reaches a model stage the original request never reached are reported as
+incomplete. --llm live calls the models again.
+
+With --fail-on-change, exits with status 1 if any decision changes.`,
+	Example: `  invarity replay run --since 168h --toolset billing --revision 4
+  invarity replay run --since 24h --tool tools/send_email.yaml --action send_email
+  invarity replay run --since 72h --min-safe-probability 0.85 --fail-on-change
+  invarity replay run --since 24h --llm live --no-wait`,
+	Args: cobra.NoArgs,
+	RunE: runReplayRun,
+}
+
+var replayShowCmd = &cobra.Command{
+	Use:   "show <job_id>",
+	Short: "Show a replay job and its report",
+	Example: `  invarity replay show 6f1c...
+  invarity replay show 6f1c... --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runReplayShow,
+}
+
+var replayListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the tenant's replay jobs",
+	Args:  cobra.NoArgs,
+	RunE:  runReplayList,
+}
+
+var (
+	replaySpec         client.ReplaySpec
+	replayToolFiles    []string
+	replayMinSafe      float64
+	replayMinDeny      float64
+	replayMinThreat    float64
+	replayThreat       bool
+	replayContextScan  bool
+	replayNoWait       bool
+	replayFailOnChange bool
+)
+
+func init() {
+	f := replayRunCmd.Flags()
+	f.StringVar(&replaySpec.Since, "since", "", "Only records at or after this time (RFC 3339 or duration, e.g. 24h)")
+	f.StringVar(&replaySpec.Until, "until", "", "Only records at or before this time (RFC 3339 or duration)")
+	f.StringVar(&replaySpec.ActionID, "action", "", "Only calls to this tool action")
+	f.IntVar(&replaySpec.Limit, "limit", 0, "Most recent records to replay (server default 1000, max 10000)")
+	f.StringVar(&replaySpec.LLM, "llm", "recorded", "Model stages: recorded (reuse recorded outputs) or live")
+	f.StringVar(&replaySpec.Candidate.ToolsetID, "toolset", "", "Candidate toolset ID")
+	f.StringVar(&replaySpec.Candidate.ToolsetRevision, "revision", "", "Candidate toolset revision")
+	f.StringArrayVar(&replayToolFiles, "tool", nil, "Candidate tool manifest file (may be repeated)")
+	f.Float64Var(&replayMinSafe, "min-safe-probability", 0, "Candidate minimum calibrated probability for SAFE votes")
+	f.Float64Var(&replayMinDeny, "min-deny-probability", 0, "Candidate minimum calibrated probability for DENY votes")
+	f.Float64Var(&replayMinThreat, "min-threat-probability", 0, "Candidate minimum calibrated probability for threat labels")
+	f.BoolVar(&replayThreat, "threat-sentinel", true, "Candidate runs the threat sentinel")
+	f.BoolVar(&replayContextScan, "context-scan", true, "Candidate scans bounded context for injection")
+	f.BoolVar(&replayNoWait, "no-wait", false, "Print the job ID and return without waiting for the report")
+	f.BoolVar(&replayFailOnChange, "fail-on-change", false, "Exit with status 1 if any decision changes")
+
+	replayCmd.AddCommand(replayRunCmd)
+	replayCmd.AddCommand(replayShowCmd)
+	replayCmd.AddCommand(replayListCmd)
+}
+
+func runReplayRun(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	spec := replaySpec
+	now := time.Now()
+	if spec.Since, err = parseAuditTime(spec.Since, now); err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	if spec.Until, err = parseAuditTime(spec.Until, now); err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	for _, file := range replayToolFiles {
+		tool, err := validate.ParseToolFile(file)
+		if err != nil {
+			printError("Failed to parse tool file %s: %v", file, err)
+			os.Exit(ExitValidationError)
+		}
+		tool, err = validate.EnsureSchemaHash(validate.NormalizeToolEnums(tool))
+		if err != nil {
+			printError("Failed to compute schema_hash for %s: %v", file, err)
+			os.Exit(ExitValidationError)
+		}
+		spec.Candidate.Tools = append(spec.Candidate.Tools, tool)
+	}
+	spec.Candidate.Policy = replayPolicyFromFlags(cmd)
+
+	c := newClient(cfg)
+	tenantID := auditTenant(cfg.TenantID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	job, rawJSON, err := c.SubmitReplay(ctx, tenantID, &spec)
+	cancel()
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support historical replay yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to start replay: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	if replayNoWait {
+		if cfgJSON {
+			printJSON(rawJSON)
+			return nil
+		}
+		printSuccess("Replay started")
+		printKeyValue("Job ID", job.JobID)
+		printDim("\nCheck progress: invarity replay show %s", job.JobID)
+		return nil
+	}
+
+	job, rawJSON, err = waitForReplay(c, tenantID, job.JobID)
+	if err != nil {
+		printError("Failed to get replay status: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+	return printReplayJob(job, rawJSON)
+}
+
+// replayPolicyFromFlags returns the threshold overrides given on the command line.
+func replayPolicyFromFlags(cmd *cobra.Command) *client.ReplayPolicy {
+	flags := cmd.Flags()
+	policy := &client.ReplayPolicy{}
+	set := false
+	if flags.Changed("min-safe-probability") {
+		policy.MinSafeProbability, set = &replayMinSafe, true
+	}
+	if flags.Changed("min-deny-probability") {
+		policy.MinDenyProbability, set = &replayMinDeny, true
+	}
+	if flags.Changed("min-threat-probability") {
+		policy.MinThreatProbability, set = &replayMinThreat, true
+	}
+	if flags.Changed("threat-sentinel") {
+		policy.EnableThreatSentinel, set = &replayThreat, true
+	}
+	if flags.Changed("context-scan") {
+		policy.EnableContextScan, set = &replayContextScan, true
+	}
+	if !set {
+		return nil
+	}
+	return policy
+}
+
+// waitForReplay polls the job until it finishes or the user interrupts.
+func waitForReplay(c *client.Client, tenantID, jobID string) (*client.ReplayJob, []byte, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	lastProgress := -1
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		job, rawJSON, err := c.GetReplay(reqCtx, tenantID, jobID)
+		cancel()
+		if ctx.Err() != nil {
+			printWarn("Stopped waiting; the replay keeps running: invarity replay show %s", jobID)
+			os.Exit(ExitNetworkError)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if job.Done() {
+			return job, rawJSON, nil
+		}
+		if !cfgJSON && job.Total > 0 && job.Replayed != lastProgress {
+			printDim("Replayed %d of %d records", job.Replayed, job.Total)
+			lastProgress = job.Replayed
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+func runReplayShow(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	job, rawJSON, err := c.GetReplay(ctx, auditTenant(cfg.TenantID), args[0])
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support historical replay yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to get replay: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+	return printReplayJob(job, rawJSON)
+}
+
+func runReplayList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	list, rawJSON, err := c.ListReplays(ctx, auditTenant(cfg.TenantID))
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support historical replay yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to list replays: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	if cfgJSON {
+		printJSON(rawJSON)
+		return nil
+	}
+
+	if len(list.Jobs) == 0 {
+		printDim("No replay jobs found")
+		return nil
+	}
+	dimColor.Fprintf(os.Stdout, "%-36s  %-10s  %-9s  %s\n", "JOB ID", "STATUS", "RECORDS", "CREATED")
+	for _, job := range list.Jobs {
+		created := job.CreatedAt
+		if t, err := time.Parse(time.RFC3339Nano, job.CreatedAt); err == nil {
+			created = t.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(os.Stdout, "%-36s  %-10s  %-9s  %s\n", job.JobID, job.Status, fmt.Sprintf("%d/%d", job.Replayed, job.Total), created)
+	}
+	return nil
+}
+
+// printReplayJob prints a job and its report, exiting non-zero if the job
+// failed or, with --fail-on-change, if decisions changed.
+func printReplayJob(job *client.ReplayJob, rawJSON []byte) error {
+	changed := job.Report != nil && job.Report.Changed > 0
+
+	if cfgJSON {
+		printJSON(rawJSON)
+	} else {
+		printReplayReport(job)
+	}
+
+	switch {
+	case job.Status == "failed":
+		os.Exit(ExitNetworkError)
+	case changed && replayFailOnChange:
+		os.Exit(ExitValidationError)
+	}
+	return nil
+}
+
+func printReplayReport(job *client.ReplayJob) {
+	switch job.Status {
+	case "failed":
+		printError("Replay failed: %s", job.Error)
+		return
+	case "succeeded":
+	default:
+		printInfo("Replay %s (%d of %d records)", job.Status, job.Replayed, job.Total)
+		return
+	}
+
+	report := job.Report
+	if report.Changed == 0 {
+		printSuccess("No decisions change")
+	} else {
+		printWarn("%d of %d decisions change", report.Changed, report.Evaluated)
+	}
+	printKeyValue("Job ID", job.JobID)
+	printKeyValue("Tenant", report.TenantID)
+	printKeyValue("Model stages", report.LLM)
+	printKeyValue("Replayed", fmt.Sprintf("%d", report.Evaluated))
+	if report.Skipped > 0 {
+		printKeyValue("Skipped", fmt.Sprintf("%d (no tool call)", report.Skipped))
+	}
+	if report.Errors > 0 {
+		printKeyValue("Errors", fmt.Sprintf("%d", report.Errors))
+	}
+	if report.Incomplete > 0 {
+		printWarn("%d records reached a model stage with no recorded output; re-run with --llm live to evaluate them fully", report.Incomplete)
+	}
+
+	if len(report.Transitions) > 0 {
+		printSection("Transitions")
+		for _, transition := range sortedTransitions(report.Transitions) {
+			fmt.Fprintf(os.Stdout, "  %-20s %d\n", strings.ReplaceAll(transition, "->", " → "), report.Transitions[transition])
+		}
+	}
+
+	printSection("By Tool")
+	for _, tool := range report.ByTool {
+		line := fmt.Sprintf("  %-32s %d of %d changed", tool.ActionID, tool.Changed, tool.Evaluated)
+		if tool.Changed > 0 {
+			line += "  (" + formatTransitions(tool.Transitions) + ")"
+		}
+		fmt.Fprintln(os.Stdout, line)
+	}
+
+	if len(report.ByReason) > 0 {
+		printSection("By Reason")
+		for _, reason := range report.ByReason {
+			sign := "+"
+			if reason.Change == "removed" {
+				sign = "-"
+			}
+			fmt.Fprintf(os.Stdout, "  %s %-40s %d  (%s)\n", sign, reason.Reason, reason.Count, formatTransitions(reason.Transitions))
+		}
+	}
+
+	if len(report.Changes) > 0 {
+		printSection("Changes")
+		for _, change := range report.Changes {
+			line := fmt.Sprintf("  %s  %-24s %s → %s",
+				change.AuditID,
+				change.ActionID,
+				getDecisionColor(change.From).Sprint(change.From),
+				getDecisionColor(change.To).Sprint(change.To),
+			)
+			if change.Incomplete {
+				line += dimColor.Sprint("  (incomplete)")
+			}
+			fmt.Fprintln(os.Stdout, line)
+		}
+		if report.ChangesTruncated {
+			printDim("  ... more changes not listed; use --json for the counts above")
+		}
+	}
+}
+
+func sortedTransitions(transitions map[string]int) []string {
+	keys := make([]string, 0, len(transitions))
+	for key := range transitions {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if transitions[keys[i]] != transitions[keys[j]] {
+			return transitions[keys[i]] > transitions[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func formatTransitions(transitions map[string]int) string {
+	parts := make([]string, 0, len(transitions))
+	for _, transition := range sortedTransitions(transitions) {
+		parts = append(parts, fmt.Sprintf("%s %d", strings.ReplaceAll(transition, "->", "→"), transitions[transition]))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -81,7 +81,9 @@ func init() {
 	RootCmd.AddCommand(toolsCmd)
 	RootCmd.AddCommand(toolsetsCmd)
 	RootCmd.AddCommand(principalsCmd)
+	RootCmd.AddCommand(tokensCmd)
 	RootCmd.AddCommand(auditCmd)
+	RootCmd.AddCommand(replayCmd)
 	RootCmd.AddCommand(versionCmd)
 }
 
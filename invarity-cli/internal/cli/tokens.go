@@ -0,0 +1,322 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/invarity/invarity-cli/internal/client"
+)
+
+var tokensCmd = &cobra.Command{
+	Use:   "tokens",
+	Short: "Manage API tokens",
+	Long: `Commands for managing a tenant's API tokens.
+
+Agent tokens are bound to a principal and authenticate its evaluate calls.
+Developer tokens have no principal and may carry control plane scopes held by
+your role. A token's secret is shown only when it is created or rotated.`,
+}
+
+var tokensCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an API token",
+	Long: `Creates an API token and prints its secret. The secret cannot be retrieved
+again; store it before closing the terminal.
+
+With --principal the token is an agent token for that principal, limited to the
+evaluate scope. Without it the token is a developer token.`,
+	Example: `  # Agent token for a principal
+  invarity tokens create --name billing-agent-prod --principal billing-agent
+
+  # Developer token for CI, expiring in 90 days
+  invarity tokens create --name ci --scope tools:write --scope toolsets:write --expires-in 2160h`,
+	Args: cobra.NoArgs,
+	RunE: runTokensCreate,
+}
+
+var tokensListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List a tenant's API tokens",
+	Long: `Lists the tenant's API tokens, newest first, by ID and key prefix, including
+expired and revoked tokens.`,
+	Example: `  invarity tokens list --tenant acme
+  invarity tokens list --json`,
+	Args: cobra.NoArgs,
+	RunE: runTokensList,
+}
+
+var tokensRotateCmd = &cobra.Command{
+	Use:   "rotate <token_id>",
+	Short: "Replace an API token",
+	Long: `Issues a replacement with the same name, principal and scopes, and prints its
+secret. The old token keeps working for --overlap (default 1h, at most 720h) so
+clients can switch over; --overlap 0 revokes it at once.`,
+	Example: `  invarity tokens rotate 0b7e3c52-...
+  invarity tokens rotate 0b7e3c52-... --overlap 24h`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTokensRotate,
+}
+
+var tokensRevokeCmd = &cobra.Command{
+	Use:     "revoke <token_id>",
+	Short:   "Revoke an API token",
+	Long:    `Revokes an API token. Revoking a revoked token succeeds without change.`,
+	Example: `  invarity tokens revoke 0b7e3c52-...`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    runTokensRevoke,
+}
+
+var (
+	tokensName      string
+	tokensType      string
+	tokensScopes    []string
+	tokensExpiresIn time.Duration
+	tokensOverlap   time.Duration
+)
+
+func init() {
+	tokensCreateCmd.Flags().StringVar(&tokensName, "name", "", "Token name (required)")
+	tokensCreateCmd.Flags().StringVar(&tokensType, "type", "", "Token type: developer or agent (default: agent with --principal, else developer)")
+	tokensCreateCmd.Flags().StringArrayVar(&tokensScopes, "scope", nil, "Scope to grant, repeatable (default: evaluate)")
+	tokensCreateCmd.Flags().DurationVar(&tokensExpiresIn, "expires-in", 0, "Expire the token after this duration (default: never)")
+	tokensCreateCmd.MarkFlagRequired("name")
+
+	tokensRotateCmd.Flags().DurationVar(&tokensOverlap, "overlap", time.Hour, "How long the old token keeps working")
+	tokensRotateCmd.Flags().DurationVar(&tokensExpiresIn, "expires-in", 0, "Expire the new token after this duration (default: the old token's lifetime)")
+
+	tokensCmd.AddCommand(tokensCreateCmd)
+	tokensCmd.AddCommand(tokensListCmd)
+	tokensCmd.AddCommand(tokensRotateCmd)
+	tokensCmd.AddCommand(tokensRevokeCmd)
+}
+
+func runTokensCreate(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if tokensExpiresIn < 0 {
+		printError("--expires-in must not be negative")
+		os.Exit(ExitValidationError)
+	}
+
+	c := newClient(cfg)
+
+	// Only an explicit --principal makes an agent token, not the configured default
+	req := &client.CreateTokenRequest{
+		Name:        tokensName,
+		Type:        tokensType,
+		PrincipalID: cfgPrincipal,
+		Scopes:      tokensScopes,
+	}
+	if tokensExpiresIn > 0 {
+		req.ExpiresAt = time.Now().Add(tokensExpiresIn).UTC().Format(time.RFC3339)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	created, rawJSON, err := c.CreateToken(ctx, auditTenant(cfg.TenantID), req)
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support token management yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to create token: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	// JSON output
+	if cfgJSON {
+		printJSON(rawJSON)
+		return nil
+	}
+
+	// Human-readable output
+	printSuccess("Token created")
+	printTokenInfo(&created.TokenInfo)
+	printTokenSecret(created.Token)
+
+	return nil
+}
+
+func runTokensList(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	list, rawJSON, err := c.ListTokens(ctx, auditTenant(cfg.TenantID))
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support token management yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to list tokens: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	if cfgJSON {
+		printJSON(rawJSON)
+		return nil
+	}
+
+	if len(list.Tokens) == 0 {
+		printDim("No tokens found")
+		return nil
+	}
+	dimColor.Fprintf(os.Stdout, "%-36s  %-8s  %-20s  %-9s  %-7s  %-19s  %s\n", "TOKEN ID", "PREFIX", "NAME", "TYPE", "STATUS", "LAST USED", "SCOPES")
+	for _, token := range list.Tokens {
+		lastUsed := "never"
+		if token.LastUsedAt != "" {
+			lastUsed = formatTokenTime(token.LastUsedAt)
+		}
+		fmt.Fprintf(os.Stdout, "%-36s  %-8s  %-20s  %-9s  %-7s  %-19s  %s\n",
+			token.TokenID, token.KeyPrefix, token.Name, token.Type, token.Status, lastUsed, strings.Join(token.Scopes, ","))
+	}
+	return nil
+}
+
+func runTokensRotate(cmd *cobra.Command, args []string) error {
+	tokenID := args[0]
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	if tokensOverlap < 0 || tokensExpiresIn < 0 {
+		printError("--overlap and --expires-in must not be negative")
+		os.Exit(ExitValidationError)
+	}
+
+	c := newClient(cfg)
+
+	overlap := int64(tokensOverlap / time.Second)
+	req := &client.RotateTokenRequest{OverlapSeconds: &overlap}
+	if tokensExpiresIn > 0 {
+		req.ExpiresAt = time.Now().Add(tokensExpiresIn).UTC().Format(time.RFC3339)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rotated, rawJSON, err := c.RotateToken(ctx, auditTenant(cfg.TenantID), tokenID, req)
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support token rotation yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to rotate token: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	if cfgJSON {
+		printJSON(rawJSON)
+		return nil
+	}
+
+	printSuccess("Token rotated")
+	printTokenInfo(&rotated.TokenInfo)
+	if rotated.Previous.Status == "revoked" {
+		printKeyValue("Previous Token", rotated.Previous.TokenID+" (revoked)")
+	} else {
+		printKeyValue("Previous Token", rotated.Previous.TokenID+" (expires "+formatTokenTime(rotated.Previous.ExpiresAt)+")")
+	}
+	printTokenSecret(rotated.Token)
+
+	return nil
+}
+
+func runTokensRevoke(cmd *cobra.Command, args []string) error {
+	tokenID := args[0]
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	c := newClient(cfg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	token, rawJSON, err := c.RevokeToken(ctx, auditTenant(cfg.TenantID), tokenID)
+	if err != nil {
+		if client.IsNotSupportedError(err) {
+			printWarn("Server does not support token revocation yet.")
+			os.Exit(ExitNetworkError)
+		}
+		printError("Failed to revoke token: %v", err)
+		os.Exit(ExitNetworkError)
+	}
+
+	if cfgJSON {
+		printJSON(rawJSON)
+		return nil
+	}
+
+	printSuccess("Token revoked")
+	printTokenInfo(token)
+
+	return nil
+}
+
+func printTokenInfo(token *client.TokenInfo) {
+	printKeyValue("Token ID", token.TokenID)
+	printKeyValue("Prefix", token.KeyPrefix)
+	printKeyValue("Name", token.Name)
+	printKeyValue("Type", token.Type)
+	if token.PrincipalID != "" {
+		printKeyValue("Principal", token.PrincipalID)
+	}
+	printKeyValue("Scopes", strings.Join(token.Scopes, ", "))
+	printKeyValue("Status", token.Status)
+	if token.ExpiresAt != "" {
+		printKeyValue("Expires", formatTokenTime(token.ExpiresAt))
+	}
+	if token.RevokedAt != "" {
+		printKeyValue("Revoked", formatTokenTime(token.RevokedAt)+" by "+token.RevokedBy)
+	}
+}
+
+// printTokenSecret prints a token's plaintext, which the server returns only once.
+func printTokenSecret(plaintext string) {
+	fmt.Fprintln(os.Stdout)
+	printWarn("Store this token now; it will not be shown again:")
+	fmt.Fprintln(os.Stdout, plaintext)
+}
+
+func formatTokenTime(value string) string {
+	if t, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return t.Local().Format("2006-01-02 15:04:05")
+	}
+	return value
+}
@@ -2,6 +2,7 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -68,9 +69,9 @@ func New(cfg *config.Config, opts ...Option) *Client {
 // doRequest performs an HTTP request with common handling.
 func (c *Client) doRequest(ctx context.Context, method, path string, body interface{}) (*http.Response, []byte, error) {
 	// Build URL
-	u, err := url.JoinPath(c.baseURL, path)
+	u, err := c.buildURL(path)
 	if err != nil {
-		return nil, nil, fmt.Errorf("invalid URL path: %w", err)
+		return nil, nil, err
 	}
 
 	// Prepare body
@@ -133,6 +134,19 @@ func (c *Client) doRequest(ctx context.Context, method, path string, body interf
 	return resp, respBody, nil
 }
 
+// buildURL joins path onto the base URL, keeping any query string intact.
+func (c *Client) buildURL(path string) (string, error) {
+	path, query, hasQuery := strings.Cut(path, "?")
+	u, err := url.JoinPath(c.baseURL, path)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL path: %w", err)
+	}
+	if hasQuery {
+		u += "?" + query
+	}
+	return u, nil
+}
+
 func (c *Client) printTrace(t RequestTrace) {
 	fmt.Fprintf(c.traceOut, "\n[TRACE] %s %s\n", t.Method, t.URL)
 	fmt.Fprintf(c.traceOut, "[TRACE] Status: %d | Duration: %s | Request: %d bytes | Response: %d bytes\n",
@@ -165,6 +179,48 @@ func (c *Client) Ping(ctx context.Context) (*HealthResponse, error) {
 	return &health, nil
 }
 
+// ReadinessResponse represents the response from /readyz.
+type ReadinessResponse struct {
+	Status    string                 `json:"status"`
+	Version   string                 `json:"version,omitempty"`
+	Checks    map[string]HealthCheck `json:"checks,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// HealthCheck is the result of checking one server dependency.
+type HealthCheck struct {
+	Status    string    `json:"status"`
+	Required  bool      `json:"required"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached,omitempty"`
+}
+
+// Ready checks the server's dependencies. A server that is not ready returns
+// 503 with the same body, so the response is returned for both.
+func (c *Client) Ready(ctx context.Context) (*ReadinessResponse, error) {
+	resp, body, err := c.doRequest(ctx, http.MethodGet, "/readyz", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &NotSupportedError{Feature: "readiness checks"}
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var ready ReadinessResponse
+	if err := json.Unmarshal(body, &ready); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &ready, nil
+}
+
 // EvaluateRequest represents a tool call evaluation request.
 type EvaluateRequest map[string]interface{}
 
@@ -376,30 +432,71 @@ func (c *Client) ApplyPolicy(ctx context.Context, policy map[string]interface{})
 
 // AuditRecord represents an audit record.
 type AuditRecord struct {
-	AuditID   string                 `json:"audit_id"`
-	Timestamp string                 `json:"timestamp,omitempty"`
-	Decision  string                 `json:"decision,omitempty"`
-	Request   map[string]interface{} `json:"request,omitempty"`
-	Response  map[string]interface{} `json:"response,omitempty"`
+	AuditID      string                 `json:"audit_id"`
+	RequestID    string                 `json:"request_id,omitempty"`
+	OrgID        string                 `json:"org_id,omitempty"`
+	TenantID     string                 `json:"tenant_id,omitempty"`
+	PrincipalID  string                 `json:"principal_id,omitempty"`
+	Actor        map[string]interface{} `json:"actor,omitempty"`
+	ToolCall     AuditToolCall          `json:"tool_call"`
+	UserIntent   string                 `json:"user_intent,omitempty"`
+	Decision     string                 `json:"decision,omitempty"`
+	RiskTier     string                 `json:"risk_tier,omitempty"`
+	Reasons      []string               `json:"reasons,omitempty"`
+	PipelineStep string                 `json:"pipeline_step,omitempty"`
+	CreatedAt    string                 `json:"created_at,omitempty"`
+	Sequence     int64                  `json:"sequence,omitempty"`
+	Snapshot     *DecisionSnapshot      `json:"snapshot,omitempty"`
+}
+
+// DecisionSnapshot records the artifacts and configuration a decision was made with.
+type DecisionSnapshot struct {
+	FirewallVersion string                 `json:"firewall_version"`
+	Tool            *ToolSnapshot          `json:"tool,omitempty"`
+	Voters          []ModelSnapshot        `json:"voters,omitempty"`
+	Sentinel        *ModelSnapshot         `json:"sentinel,omitempty"`
+	Config          map[string]interface{} `json:"config,omitempty"`
+}
+
+// ToolSnapshot identifies the exact tool manifest a call was evaluated against.
+type ToolSnapshot struct {
+	ToolID          string `json:"tool_id"`
+	Version         string `json:"version"`
+	SchemaHash      string `json:"schema_hash"`
+	ToolsetID       string `json:"toolset_id,omitempty"`
+	ToolsetRevision string `json:"toolset_revision,omitempty"`
+	ResolvedVia     string `json:"resolved_via"`
+}
+
+// ModelSnapshot identifies the model and prompt template behind a model stage.
+type ModelSnapshot struct {
+	VoterID       string `json:"voter_id,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
 }
 
-// GetAudit retrieves an audit record by ID.
-func (c *Client) GetAudit(ctx context.Context, auditID string) (*AuditRecord, []byte, error) {
-	path := fmt.Sprintf("/v1/audit/%s", url.PathEscape(auditID))
+// AuditToolCall is the tool call recorded in an audit record.
+type AuditToolCall struct {
+	ActionID string                 `json:"action_id"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// GetAudit retrieves a tenant's audit record by ID.
+// GET /v1/tenants/{tenant_id}/audit/{audit_id}
+func (c *Client) GetAudit(ctx context.Context, tenantID, auditID string) (*AuditRecord, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/audit/%s", url.PathEscape(tenantID), url.PathEscape(auditID))
 	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
-		// Check if it's a "feature not supported" vs "record not found"
+		// A JSON NOT_FOUND error means the record is missing; anything else means the route is
 		var errResp map[string]interface{}
-		if json.Unmarshal(body, &errResp) == nil {
-			if msg, ok := errResp["error"].(string); ok && strings.Contains(strings.ToLower(msg), "not found") {
-				return nil, nil, &NotSupportedError{Feature: "audit retrieval"}
-			}
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, nil, fmt.Errorf("audit record not found: %s", auditID)
 		}
-		return nil, nil, fmt.Errorf("audit record not found: %s", auditID)
+		return nil, nil, &NotSupportedError{Feature: "audit retrieval"}
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -414,6 +511,444 @@ func (c *Client) GetAudit(ctx context.Context, auditID string) (*AuditRecord, []
 	return &audit, body, nil
 }
 
+// AuditListOptions filters an audit listing. Empty fields are not sent.
+type AuditListOptions struct {
+	ActorID     string
+	PrincipalID string
+	ActionID    string
+	Decision    string
+	RiskTier    string
+	Reason      string
+	Query       string // Free-text intent search
+	Since       string // RFC 3339
+	Until       string // RFC 3339
+	Limit       int
+	Cursor      string
+}
+
+func (o AuditListOptions) values() url.Values {
+	v := url.Values{}
+	for key, value := range map[string]string{
+		"actor_id":     o.ActorID,
+		"principal_id": o.PrincipalID,
+		"action_id":    o.ActionID,
+		"decision":     o.Decision,
+		"risk_tier":    o.RiskTier,
+		"reason":       o.Reason,
+		"q":            o.Query,
+		"since":        o.Since,
+		"until":        o.Until,
+		"cursor":       o.Cursor,
+	} {
+		if value != "" {
+			v.Set(key, value)
+		}
+	}
+	if o.Limit > 0 {
+		v.Set("limit", fmt.Sprintf("%d", o.Limit))
+	}
+	return v
+}
+
+// AuditListResponse is a page of audit records, newest first.
+type AuditListResponse struct {
+	TenantID   string         `json:"tenant_id"`
+	Records    []*AuditRecord `json:"records"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// ListAudit lists or searches a tenant's audit records.
+// GET /v1/tenants/{tenant_id}/audit
+func (c *Client) ListAudit(ctx context.Context, tenantID string, opts AuditListOptions) (*AuditListResponse, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/audit", url.PathEscape(tenantID))
+	if query := opts.values().Encode(); query != "" {
+		path += "?" + query
+	}
+	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, &NotSupportedError{Feature: "audit listing"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var list AuditListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, body, fmt.Errorf("failed to parse audit list response: %w", err)
+	}
+
+	return &list, body, nil
+}
+
+// AuditTailEvent is one line of an audit tail stream. Heartbeats carry only the cursor.
+type AuditTailEvent struct {
+	Cursor    string
+	Record    *AuditRecord
+	RawRecord []byte // The record as the server sent it
+}
+
+// TailAudit streams a tenant's audit records, oldest first, calling fn for each
+// event until the stream ends, ctx is done or fn returns an error. The server
+// ends followed streams periodically; callers resume with the last cursor.
+// GET /v1/tenants/{tenant_id}/audit/tail
+func (c *Client) TailAudit(ctx context.Context, tenantID, cursor string, limit int, follow bool, fn func(*AuditTailEvent) error) error {
+	v := url.Values{}
+	if cursor != "" {
+		v.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		v.Set("limit", fmt.Sprintf("%d", limit))
+	}
+	if follow {
+		v.Set("follow", "true")
+	}
+	u, err := c.buildURL(fmt.Sprintf("/v1/tenants/%s/audit/tail?%s", url.PathEscape(tenantID), v.Encode()))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("invarity-cli/%s", Version))
+	req.Header.Set("Accept", "application/x-ndjson")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+	}
+
+	// The client timeout would cut off a followed stream; ctx bounds it instead
+	stream := &http.Client{Transport: c.httpClient.Transport}
+	resp, err := stream.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if c.trace && c.traceOut != nil {
+		c.printTrace(RequestTrace{Method: http.MethodGet, URL: u, StatusCode: resp.StatusCode})
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return &NotSupportedError{Feature: "audit tail"}
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var wire struct {
+			Cursor string          `json:"cursor"`
+			Record json.RawMessage `json:"record"`
+		}
+		if err := json.Unmarshal(line, &wire); err != nil {
+			return fmt.Errorf("failed to parse audit tail event: %w", err)
+		}
+		event := AuditTailEvent{Cursor: wire.Cursor}
+		if len(wire.Record) > 0 {
+			if err := json.Unmarshal(wire.Record, &event.Record); err != nil {
+				return fmt.Errorf("failed to parse audit tail record: %w", err)
+			}
+			event.RawRecord = wire.Record
+		}
+		if err := fn(&event); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("audit tail stream failed: %w", err)
+	}
+	return nil
+}
+
+// AuditVerifyIssue is a single audit chain verification failure.
+type AuditVerifyIssue struct {
+	Type     string `json:"type"`
+	Sequence int64  `json:"sequence,omitempty"`
+	AuditID  string `json:"audit_id,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// AuditVerifyReport represents the result of verifying a tenant's audit chain.
+type AuditVerifyReport struct {
+	TenantID      string             `json:"tenant_id"`
+	Valid         bool               `json:"valid"`
+	Records       int                `json:"records"`
+	Unchained     int                `json:"unchained"`
+	FirstSequence int64              `json:"first_sequence,omitempty"`
+	LastSequence  int64              `json:"last_sequence,omitempty"`
+	PrunedThrough int64              `json:"pruned_through,omitempty"`
+	Checkpoints   int                `json:"checkpoints"`
+	Issues        []AuditVerifyIssue `json:"issues"`
+	VerifiedAt    string             `json:"verified_at,omitempty"`
+}
+
+// VerifyAudit walks a tenant's hash-chained audit log on the server.
+// GET /v1/tenants/{tenant_id}/audit/verify
+func (c *Client) VerifyAudit(ctx context.Context, tenantID string) (*AuditVerifyReport, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/audit/verify", url.PathEscape(tenantID))
+	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, &NotSupportedError{Feature: "audit verification"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var report AuditVerifyReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, body, fmt.Errorf("failed to parse verify response: %w", err)
+	}
+
+	return &report, body, nil
+}
+
+// Reproduction is the outcome of re-running a recorded decision.
+type Reproduction struct {
+	AuditID         string            `json:"audit_id"`
+	Reproduced      bool              `json:"reproduced"`
+	Recorded        DecisionOutcome   `json:"recorded"`
+	Rerun           DecisionOutcome   `json:"rerun"`
+	Differences     []string          `json:"differences,omitempty"`
+	Incomplete      bool              `json:"incomplete,omitempty"`
+	Snapshot        *DecisionSnapshot `json:"snapshot"`
+	FirewallVersion string            `json:"firewall_version"`
+}
+
+// DecisionOutcome is a decision and what led to it.
+type DecisionOutcome struct {
+	Decision string   `json:"decision"`
+	RiskTier string   `json:"risk_tier"`
+	Reasons  []string `json:"reasons"`
+}
+
+// ReproduceAudit re-runs an audited decision's deterministic stages with the
+// artifacts recorded in its snapshot.
+// POST /v1/tenants/{tenant_id}/audit/{audit_id}/reproduce
+func (c *Client) ReproduceAudit(ctx context.Context, tenantID, auditID string) (*Reproduction, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/audit/%s/reproduce", url.PathEscape(tenantID), url.PathEscape(auditID))
+	resp, body, err := c.doRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var errResp map[string]interface{}
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, nil, fmt.Errorf("audit record not found: %s", auditID)
+		}
+		return nil, nil, &NotSupportedError{Feature: "decision reproduction"}
+	case http.StatusConflict:
+		if json.Unmarshal(body, &errResp) == nil {
+			return nil, body, fmt.Errorf("decision cannot be reproduced: %v", errResp["error"])
+		}
+		return nil, body, fmt.Errorf("decision cannot be reproduced: %s", string(body))
+	default:
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result Reproduction
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, body, fmt.Errorf("failed to parse reproduce response: %w", err)
+	}
+
+	return &result, body, nil
+}
+
+// ReplaySpec selects recorded traffic to replay and the candidate to replay it against.
+type ReplaySpec struct {
+	Since     string          `json:"since,omitempty"` // RFC 3339
+	Until     string          `json:"until,omitempty"` // RFC 3339
+	ActionID  string          `json:"action_id,omitempty"`
+	Limit     int             `json:"limit,omitempty"`
+	LLM       string          `json:"llm,omitempty"` // "recorded" or "live"
+	Candidate ReplayCandidate `json:"candidate"`
+}
+
+// ReplayCandidate is the toolset, tools or policy thresholds to replay against.
+type ReplayCandidate struct {
+	ToolsetID       string                   `json:"toolset_id,omitempty"`
+	ToolsetRevision string                   `json:"toolset_revision,omitempty"`
+	Tools           []map[string]interface{} `json:"tools,omitempty"`
+	Policy          *ReplayPolicy            `json:"policy,omitempty"`
+}
+
+// ReplayPolicy overrides decision thresholds and stage switches.
+type ReplayPolicy struct {
+	MinSafeProbability   *float64 `json:"min_safe_probability,omitempty"`
+	MinDenyProbability   *float64 `json:"min_deny_probability,omitempty"`
+	MinThreatProbability *float64 `json:"min_threat_probability,omitempty"`
+	EnableThreatSentinel *bool    `json:"enable_threat_sentinel,omitempty"`
+	EnableContextScan    *bool    `json:"enable_context_scan,omitempty"`
+}
+
+// ReplayJob is a replay running or finished on the server.
+type ReplayJob struct {
+	JobID      string        `json:"job_id"`
+	TenantID   string        `json:"tenant_id"`
+	Status     string        `json:"status"` // pending, running, succeeded, failed
+	Replayed   int           `json:"replayed"`
+	Total      int           `json:"total"`
+	Report     *ReplayReport `json:"report,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	CreatedAt  string        `json:"created_at,omitempty"`
+	FinishedAt string        `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job has finished.
+func (j *ReplayJob) Done() bool {
+	return j.Status == "succeeded" || j.Status == "failed"
+}
+
+// ReplayReport is the difference between recorded decisions and the candidate's.
+type ReplayReport struct {
+	TenantID         string             `json:"tenant_id"`
+	LLM              string             `json:"llm"`
+	Evaluated        int                `json:"evaluated"`
+	Changed          int                `json:"changed"`
+	Skipped          int                `json:"skipped"`
+	Errors           int                `json:"errors"`
+	Incomplete       int                `json:"incomplete"`
+	Transitions      map[string]int     `json:"transitions"`
+	ByTool           []ReplayToolDiff   `json:"by_tool"`
+	ByReason         []ReplayReasonDiff `json:"by_reason"`
+	Changes          []ReplayChange     `json:"changes"`
+	ChangesTruncated bool               `json:"changes_truncated,omitempty"`
+}
+
+// ReplayToolDiff counts replayed and changed decisions for one tool.
+type ReplayToolDiff struct {
+	ActionID    string         `json:"action_id"`
+	Evaluated   int            `json:"evaluated"`
+	Changed     int            `json:"changed"`
+	Transitions map[string]int `json:"transitions,omitempty"`
+}
+
+// ReplayReasonDiff counts changed decisions where a reason was added or removed.
+type ReplayReasonDiff struct {
+	Reason      string         `json:"reason"`
+	Change      string         `json:"change"` // "added" or "removed"
+	Count       int            `json:"count"`
+	Transitions map[string]int `json:"transitions"`
+}
+
+// ReplayChange is one record whose decision the candidate changes.
+type ReplayChange struct {
+	AuditID        string   `json:"audit_id"`
+	ActionID       string   `json:"action_id"`
+	PrincipalID    string   `json:"principal_id,omitempty"`
+	CreatedAt      string   `json:"created_at"`
+	From           string   `json:"from"`
+	To             string   `json:"to"`
+	AddedReasons   []string `json:"added_reasons,omitempty"`
+	RemovedReasons []string `json:"removed_reasons,omitempty"`
+	Incomplete     bool     `json:"incomplete,omitempty"`
+}
+
+// ReplayListResponse lists a tenant's replay jobs, newest first, without reports.
+type ReplayListResponse struct {
+	TenantID string       `json:"tenant_id"`
+	Jobs     []*ReplayJob `json:"jobs"`
+}
+
+// SubmitReplay starts a replay job on the server.
+// POST /v1/tenants/{tenant_id}/replays
+func (c *Client) SubmitReplay(ctx context.Context, tenantID string, spec *ReplaySpec) (*ReplayJob, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/replays", url.PathEscape(tenantID))
+	resp, body, err := c.doRequest(ctx, http.MethodPost, path, spec)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, &NotSupportedError{Feature: "historical replay"}
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var job ReplayJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, body, fmt.Errorf("failed to parse replay response: %w", err)
+	}
+
+	return &job, body, nil
+}
+
+// GetReplay retrieves a replay job and, once it has finished, its report.
+// GET /v1/tenants/{tenant_id}/replays/{job_id}
+func (c *Client) GetReplay(ctx context.Context, tenantID, jobID string) (*ReplayJob, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/replays/%s", url.PathEscape(tenantID), url.PathEscape(jobID))
+	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		var errResp map[string]interface{}
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, nil, fmt.Errorf("replay job not found: %s", jobID)
+		}
+		return nil, nil, &NotSupportedError{Feature: "historical replay"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var job ReplayJob
+	if err := json.Unmarshal(body, &job); err != nil {
+		return nil, body, fmt.Errorf("failed to parse replay response: %w", err)
+	}
+
+	return &job, body, nil
+}
+
+// ListReplays lists a tenant's replay jobs.
+// GET /v1/tenants/{tenant_id}/replays
+func (c *Client) ListReplays(ctx context.Context, tenantID string) (*ReplayListResponse, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/replays", url.PathEscape(tenantID))
+	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, &NotSupportedError{Feature: "historical replay"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var list ReplayListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, body, fmt.Errorf("failed to parse replay list response: %w", err)
+	}
+
+	return &list, body, nil
+}
+
 // NotSupportedError indicates a feature is not yet supported by the server.
 type NotSupportedError struct {
 	Feature string
@@ -750,3 +1285,164 @@ func (c *Client) ApplyToolsetToPrincipal(ctx context.Context, tenantID, principa
 
 	return &applyResp, body, nil
 }
+
+// TokenInfo describes an API token without its secret.
+type TokenInfo struct {
+	TokenID     string   `json:"token_id"`
+	KeyPrefix   string   `json:"key_prefix"`
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	PrincipalID string   `json:"principal_id,omitempty"`
+	Scopes      []string `json:"scopes"`
+	Status      string   `json:"status"`
+	CreatedAt   string   `json:"created_at"`
+	CreatedBy   string   `json:"created_by"`
+	ExpiresAt   string   `json:"expires_at,omitempty"`
+	LastUsedAt  string   `json:"last_used_at,omitempty"`
+	RevokedAt   string   `json:"revoked_at,omitempty"`
+	RevokedBy   string   `json:"revoked_by,omitempty"`
+}
+
+// CreateTokenRequest represents a request to create an API token.
+type CreateTokenRequest struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type,omitempty"`
+	PrincipalID string   `json:"principal_id,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	ExpiresAt   string   `json:"expires_at,omitempty"` // RFC 3339
+}
+
+// CreateTokenResponse is a new token. Token is the plaintext, returned only once.
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+	TokenInfo
+}
+
+// RotateTokenRequest represents a request to rotate an API token.
+type RotateTokenRequest struct {
+	OverlapSeconds *int64 `json:"overlap_seconds,omitempty"`
+	ExpiresAt      string `json:"expires_at,omitempty"` // RFC 3339
+}
+
+// RotateTokenResponse is the replacement token and the token it replaces.
+type RotateTokenResponse struct {
+	CreateTokenResponse
+	Previous TokenInfo `json:"previous"`
+}
+
+// TokenListResponse represents a tenant's API tokens.
+type TokenListResponse struct {
+	Tokens []TokenInfo `json:"tokens"`
+}
+
+// CreateToken creates an API token.
+// POST /v1/tenants/{tenant_id}/tokens
+func (c *Client) CreateToken(ctx context.Context, tenantID string, req *CreateTokenRequest) (*CreateTokenResponse, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/tokens", url.PathEscape(tenantID))
+	resp, body, err := c.doRequest(ctx, http.MethodPost, path, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		var errResp map[string]interface{}
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, body, fmt.Errorf("principal not found: %s", req.PrincipalID)
+		}
+		return nil, nil, &NotSupportedError{Feature: "token management"}
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var createResp CreateTokenResponse
+	if err := json.Unmarshal(body, &createResp); err != nil {
+		return nil, body, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &createResp, body, nil
+}
+
+// ListTokens lists a tenant's API tokens, newest first.
+// GET /v1/tenants/{tenant_id}/tokens
+func (c *Client) ListTokens(ctx context.Context, tenantID string) (*TokenListResponse, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/tokens", url.PathEscape(tenantID))
+	resp, body, err := c.doRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil, &NotSupportedError{Feature: "token management"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var listResp TokenListResponse
+	if err := json.Unmarshal(body, &listResp); err != nil {
+		return nil, body, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &listResp, body, nil
+}
+
+// RotateToken replaces an API token, keeping the old one valid for the overlap.
+// POST /v1/tenants/{tenant_id}/tokens/{token_id}/rotate
+func (c *Client) RotateToken(ctx context.Context, tenantID, tokenID string, req *RotateTokenRequest) (*RotateTokenResponse, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/tokens/%s/rotate", url.PathEscape(tenantID), url.PathEscape(tokenID))
+	resp, body, err := c.doRequest(ctx, http.MethodPost, path, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		var errResp map[string]interface{}
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, body, fmt.Errorf("token not found: %s", tokenID)
+		}
+		return nil, nil, &NotSupportedError{Feature: "token rotation"}
+	}
+
+	if resp.StatusCode != http.StatusCreated {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var rotateResp RotateTokenResponse
+	if err := json.Unmarshal(body, &rotateResp); err != nil {
+		return nil, body, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &rotateResp, body, nil
+}
+
+// RevokeToken revokes an API token.
+// POST /v1/tenants/{tenant_id}/tokens/{token_id}/revoke
+func (c *Client) RevokeToken(ctx context.Context, tenantID, tokenID string) (*TokenInfo, []byte, error) {
+	path := fmt.Sprintf("/v1/tenants/%s/tokens/%s/revoke", url.PathEscape(tenantID), url.PathEscape(tokenID))
+	resp, body, err := c.doRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		var errResp map[string]interface{}
+		if json.Unmarshal(body, &errResp) == nil && errResp["code"] == "NOT_FOUND" {
+			return nil, body, fmt.Errorf("token not found: %s", tokenID)
+		}
+		return nil, nil, &NotSupportedError{Feature: "token revocation"}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, body, fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var token TokenInfo
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, body, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &token, body, nil
+}
@@ -0,0 +1,104 @@
+package validate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// manifestParityFile is shared with the server's gitops tests, which parse the
+// same examples with their own copy of this package's parsing rules.
+const manifestParityFile = "../../../invarity-go/test/testdata/manifest_parity.json"
+
+type manifestParity struct {
+	ExamplesDir string `json:"examples_dir"` // Relative to the parity file
+	Tools       []struct {
+		File      string `json:"file"`
+		Name      string `json:"name"`
+		ID        string `json:"id"`
+		Version   string `json:"version"`
+		RawSHA256 string `json:"raw_sha256"` // Of the parsed document marshalled as JSON
+	} `json:"tools"`
+	Toolsets []struct {
+		File        string            `json:"file"`
+		ToolsetID   string            `json:"toolset_id"`
+		Revision    string            `json:"revision"`
+		DisplayName string            `json:"display_name"`
+		Tools       []ToolRef         `json:"tools"`
+		Labels      map[string]string `json:"labels"`
+		RawSHA256   string            `json:"raw_sha256"`
+	} `json:"toolsets"`
+}
+
+func rawSHA256(t *testing.T, raw map[string]interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatalf("marshal parsed document: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExamplesParseLikeServer(t *testing.T) {
+	data, err := os.ReadFile(manifestParityFile)
+	if err != nil {
+		t.Fatalf("read parity file: %v", err)
+	}
+	var parity manifestParity
+	if err := json.Unmarshal(data, &parity); err != nil {
+		t.Fatalf("parse parity file: %v", err)
+	}
+	dir := filepath.Join(filepath.Dir(manifestParityFile), parity.ExamplesDir)
+
+	toolValidator, err := NewValidator()
+	if err != nil {
+		t.Fatalf("tool validator: %v", err)
+	}
+	for _, want := range parity.Tools {
+		path := filepath.Join(dir, want.File)
+		if result, err := toolValidator.ValidateFile(path); err != nil || !result.Valid {
+			t.Errorf("%s: not valid: %v %+v", want.File, err, result)
+		}
+		tool, err := ParseToolWithMetadata(path)
+		if err != nil {
+			t.Errorf("%s: %v", want.File, err)
+			continue
+		}
+		if tool.Name != want.Name || tool.ID != want.ID || tool.Version != want.Version {
+			t.Errorf("%s: parsed %s %s@%s, want %s %s@%s", want.File, tool.Name, tool.ID, tool.Version, want.Name, want.ID, want.Version)
+		}
+		if got := rawSHA256(t, tool.Raw); got != want.RawSHA256 {
+			t.Errorf("%s: parsed document hash %s, want %s", want.File, got, want.RawSHA256)
+		}
+	}
+
+	toolsetValidator, err := NewToolsetValidator()
+	if err != nil {
+		t.Fatalf("toolset validator: %v", err)
+	}
+	for _, want := range parity.Toolsets {
+		path := filepath.Join(dir, want.File)
+		if result, err := toolsetValidator.ValidateFile(path); err != nil || !result.Valid {
+			t.Errorf("%s: not valid: %v %+v", want.File, err, result)
+		}
+		toolset, err := ParseToolsetWithMetadata(path)
+		if err != nil {
+			t.Errorf("%s: %v", want.File, err)
+			continue
+		}
+		if toolset.ToolsetID != want.ToolsetID || toolset.Revision != want.Revision || toolset.DisplayName != want.DisplayName {
+			t.Errorf("%s: parsed %s@%s %q", want.File, toolset.ToolsetID, toolset.Revision, toolset.DisplayName)
+		}
+		if !reflect.DeepEqual(toolset.Tools, want.Tools) || !reflect.DeepEqual(toolset.Labels, want.Labels) {
+			t.Errorf("%s: parsed tools %v labels %v", want.File, toolset.Tools, toolset.Labels)
+		}
+		if got := rawSHA256(t, toolset.Raw); got != want.RawSHA256 {
+			t.Errorf("%s: parsed document hash %s, want %s", want.File, got, want.RawSHA256)
+		}
+	}
+}
@@ -3,22 +3,39 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"invarity/internal/audit"
+	"invarity/internal/auth"
+	"invarity/internal/calibration"
 	"invarity/internal/config"
 	"invarity/internal/firewall"
+	"invarity/internal/gitops"
+	"invarity/internal/health"
 	invarhttp "invarity/internal/http"
 	"invarity/internal/llm"
+	"invarity/internal/metrics"
+	"invarity/internal/postgres"
+	"invarity/internal/prompts"
 	"invarity/internal/registry"
+	"invarity/internal/replay"
+	"invarity/internal/store"
+	"invarity/internal/tracing"
+	"invarity/internal/usage"
 )
 
 const (
@@ -51,39 +68,371 @@ func run() error {
 		zap.Int("port", cfg.Port),
 	)
 
-	// Initialize stores
-	registryStore := registry.NewInMemoryStoreWithDefaults()
-	auditStore := audit.NewInMemoryStore()
+	// Initialize stores. In GitOps mode tools resolve from the tree alone, so
+	// the sample registry is not loaded.
+	var registryStore registry.Store
+	var gitopsSource *gitops.Source
+	if cfg.GitOpsDir != "" {
+		gitopsSource, err = gitops.NewSource(gitops.Config{
+			Dir:          cfg.GitOpsDir,
+			PollInterval: cfg.GitOpsPollInterval,
+			Logger:       logger,
+		})
+		if err != nil {
+			return err
+		}
+		snap := gitopsSource.Snapshot()
+		logger.Info("gitops mode enabled",
+			zap.String("dir", cfg.GitOpsDir),
+			zap.String("digest", snap.Digest),
+			zap.Int("tenants", snap.Stats.Tenants),
+			zap.Int("tools", snap.Stats.Tools),
+			zap.Int("toolsets", snap.Stats.Toolsets),
+			zap.Int("assignments", snap.Stats.Assignments),
+		)
+	} else {
+		registryStore = registry.NewInMemoryStoreWithDefaults()
+	}
+	backingAuditStore, checkpoints, err := newAuditStore(context.Background(), cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init audit store: %w", err)
+	}
+
+	// Hash-chain audit records per tenant, with periodic checkpoints
+	var signer *audit.Signer
+	var publicKey ed25519.PublicKey
+	if cfg.AuditSigningKey != "" {
+		signer, err = audit.NewSigner(cfg.AuditSigningKey)
+		if err != nil {
+			return err
+		}
+		publicKey = signer.PublicKey()
+	} else {
+		logger.Warn("audit signing key not configured, checkpoints are unsigned")
+	}
+	chainedAuditStore := audit.NewChainedStore(backingAuditStore, audit.ChainConfig{
+		Checkpoints:        checkpoints,
+		CheckpointInterval: cfg.AuditCheckpointSize,
+		Signer:             signer,
+		Logger:             logger,
+	})
+
+	// Export chained records to the configured SIEM sinks
+	sinkConfigs, err := audit.ParseSinkConfigs(cfg.AuditSinks)
+	if err != nil {
+		return err
+	}
+	exporter, err := audit.NewExporterFromConfigs(sinkConfigs, audit.ExporterConfig{Logger: logger})
+	if err != nil {
+		return err
+	}
+	for _, sc := range sinkConfigs {
+		logger.Info("audit sink configured",
+			zap.String("name", sc.Name),
+			zap.String("type", sc.Type),
+			zap.String("format", string(sc.Format)),
+			zap.Strings("tenants", sc.Tenants),
+		)
+	}
+
+	// Redact records per tenant before they are chained, stored or exported
+	auditPolicies, err := audit.ParsePolicies(cfg.AuditPolicies)
+	if err != nil {
+		return err
+	}
+	auditRedactor := audit.NewRedactor(auditPolicies, cfg.AuditHashKey)
+	if cfg.AuditHashKey == "" {
+		logger.Warn("audit hash key not configured, hashed arguments use plain SHA-256")
+	}
+
+	// Purge records past their tenant's retention period, archiving them first if configured
+	var archiveSink audit.Sink
+	if cfg.AuditArchiveSink != "" {
+		archiveConfig, err := audit.ParseSinkConfig(cfg.AuditArchiveSink)
+		if err != nil {
+			return err
+		}
+		archiveSink, err = audit.NewSink(*archiveConfig)
+		if err != nil {
+			return fmt.Errorf("failed to create audit archive sink: %w", err)
+		}
+		defer archiveSink.Close()
+		logger.Info("audit archive sink configured", zap.String("name", archiveConfig.Name), zap.String("type", archiveConfig.Type))
+	}
+	retention := audit.NewRetention(backingAuditStore, audit.RetentionConfig{
+		Policies:    auditPolicies,
+		Checkpoints: checkpoints,
+		Archive:     archiveSink,
+		Interval:    cfg.AuditPurgeInterval,
+		Logger:      logger,
+	})
+	retention.Start()
+	defer retention.Close()
+
+	// Write audit records asynchronously so request latency does not depend on the store
+	overflow, err := audit.ParseOverflowPolicy(cfg.AuditOverflowPolicy)
+	if err != nil {
+		return fmt.Errorf("failed to init audit writer: %w", err)
+	}
+	var spool *audit.Spool
+	if cfg.AuditSpoolDir != "" {
+		spool, err = audit.OpenSpool(cfg.AuditSpoolDir)
+		if err != nil {
+			return fmt.Errorf("failed to open audit spool: %w", err)
+		}
+		defer spool.Close()
+		logger.Info("audit spool opened", zap.String("path", spool.Path()), zap.Int("pending", spool.Len()))
+	} else {
+		logger.Warn("audit spool not configured, records are lost while the audit store is unavailable")
+	}
+	auditStore := audit.NewAsyncStore(audit.NewExportingStore(chainedAuditStore, exporter), audit.AsyncConfig{
+		BufferSize:    cfg.AuditBufferSize,
+		BatchSize:     cfg.AuditBatchSize,
+		FlushInterval: cfg.AuditFlushInterval,
+		Overflow:      overflow,
+		Spool:         spool,
+		Logger:        logger,
+	})
+
+	// Load prompt templates (embedded defaults + tenant overrides)
+	promptRegistry, err := prompts.NewRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to load prompt templates: %w", err)
+	}
+	if cfg.PromptOverridesDir != "" {
+		if err := promptRegistry.LoadTenantOverridesDir(cfg.PromptOverridesDir); err != nil {
+			return fmt.Errorf("failed to load prompt overrides: %w", err)
+		}
+		logger.Info("loaded prompt overrides", zap.String("dir", cfg.PromptOverridesDir))
+	}
+
+	// Initialize confidence calibration (curves are fitted via the admin API)
+	calibrator, err := calibration.NewCalibrator(cfg.CalibrationMethod, cfg.CalibrationMinSamples)
+	if err != nil {
+		return fmt.Errorf("failed to init calibration: %w", err)
+	}
+	labelStore := calibration.NewInMemoryLabelStore()
+
+	// Initialize token usage accounting
+	pricing, err := usage.ParsePricing(cfg.ModelPricing)
+	if err != nil {
+		return fmt.Errorf("failed to load model pricing: %w", err)
+	}
+	quotas, err := usage.ParseQuotas(cfg.TokenQuotas)
+	if err != nil {
+		return fmt.Errorf("failed to load token quotas: %w", err)
+	}
+	// Control plane stores for tenant-scoped tools and onboarding, and Cognito auth
+	controlPlaneStore, manifestStore, err := newControlPlaneStores(context.Background(), cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to init control plane stores: %w", err)
+	}
+	if closer, ok := controlPlaneStore.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Usage is stored alongside the control plane so replicas share quotas
+	usageStore, err := newUsageStore(context.Background(), cfg, controlPlaneStore)
+	if err != nil {
+		return fmt.Errorf("failed to init usage store: %w", err)
+	}
+
+	// Quotas follow each tenant's plan when the control plane holds tenants
+	meterCfg := usage.MeterConfig{
+		Store:       usageStore,
+		Pricing:     pricing,
+		Quotas:      quotas,
+		DefaultPlan: cfg.DefaultPlan,
+	}
+	if controlPlaneStore != nil {
+		meterCfg.Plans = controlPlaneStore
+	}
+	meter := usage.NewMeter(meterCfg)
+	if len(quotas) > 0 && cfg.UsageInMemory() {
+		logger.Warn("token quotas are enforced per replica from in-memory usage, which resets on restart")
+	}
+
+	// Tools resolve from the GitOps tree when one is configured
+	var toolReader store.ToolReader = controlPlaneStore
+	toolManifests := manifestStore
+	if gitopsSource != nil {
+		toolReader, toolManifests = gitopsSource, gitopsSource
+		if controlPlaneStore != nil {
+			logger.Warn("gitops mode enabled, tools and toolsets registered through the control plane are not used for evaluation")
+		}
+	}
+	var cognitoVerifier *auth.CognitoVerifier
+	if cfg.CognitoEnabled && cfg.CognitoIssuer != "" {
+		cognitoVerifier = auth.NewCognitoVerifier(auth.CognitoConfig{
+			Issuer:   cfg.CognitoIssuer,
+			Audience: cfg.CognitoAudience,
+			Region:   cfg.AWSRegion,
+		})
+	}
+
+	// Initialize OpenTelemetry tracing (nil when no collector is configured, which exports nothing)
+	var tracerProvider trace.TracerProvider
+	if cfg.OTLPEndpoint != "" {
+		provider, err := tracing.NewProvider(context.Background(), tracing.Config{
+			Endpoint:       cfg.OTLPEndpoint,
+			ServiceName:    cfg.TracingServiceName,
+			ServiceVersion: version,
+			SampleRatio:    cfg.TracingSampleRatio,
+		})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			// Flush buffered spans on shutdown
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := provider.Shutdown(flushCtx); err != nil {
+				logger.Error("trace export flush failed", zap.Error(err))
+			}
+		}()
+		tracerProvider = provider
+		logger.Info("tracing enabled", zap.String("endpoint", cfg.OTLPEndpoint), zap.Float64("sample_ratio", cfg.TracingSampleRatio))
+	}
+
+	// Initialize Prometheus metrics (nil when disabled, which records nothing)
+	var metricsRegistry *metrics.Metrics
+	if cfg.MetricsEnabled {
+		maxTenants := cfg.MetricsMaxTenants
+		if maxTenants == 0 {
+			maxTenants = -1 // Every tenant is labelled "other"
+		}
+		metricsRegistry = metrics.New(metrics.Config{MaxTenants: maxTenants})
+		metricsRegistry.RegisterAudit(auditStore, exporter)
+	}
+
+	// Cache tool resolutions (nil when the TTL is 0, which resolves every call)
+	var resolverCache *firewall.ResolverCache
+	if cfg.ResolverCacheTTL > 0 {
+		resolverCache = firewall.NewResolverCache(firewall.ResolverCacheConfig{
+			TTL:         cfg.ResolverCacheTTL,
+			NegativeTTL: cfg.ResolverNegativeTTL,
+			MaxEntries:  cfg.ResolverCacheSize,
+			Metrics:     metricsRegistry,
+		})
+	}
+	if gitopsSource != nil {
+		gitopsSource.OnSwap(func(*gitops.Snapshot) { resolverCache.Purge() })
+		gitopsSource.Start()
+		defer gitopsSource.Close()
+	}
 
 	// Initialize LLM clients
 	alignmentClient := llm.NewClient(llm.ClientConfig{
-		BaseURL: cfg.FunctionGemmaBaseURL,
-		APIKey:  cfg.FunctionGemmaAPIKey,
-		Model:   "functiongemma",
-		Timeout: 30 * time.Second,
+		BaseURL:        cfg.FunctionGemmaBaseURL,
+		APIKey:         cfg.FunctionGemmaAPIKey,
+		Model:          "functiongemma",
+		Timeout:        30 * time.Second,
+		Transport:      metricsRegistry.InstrumentTransport("alignment", nil),
+		TracerProvider: tracerProvider,
 	})
 
 	threatClient := llm.NewClient(llm.ClientConfig{
-		BaseURL: cfg.LlamaGuardBaseURL,
-		APIKey:  cfg.LlamaGuardAPIKey,
-		Model:   "llama-guard-3",
-		Timeout: 30 * time.Second,
+		BaseURL:        cfg.LlamaGuardBaseURL,
+		APIKey:         cfg.LlamaGuardAPIKey,
+		Model:          "llama-guard-3",
+		Timeout:        30 * time.Second,
+		Transport:      metricsRegistry.InstrumentTransport("threat", nil),
+		TracerProvider: tracerProvider,
 	})
 
 	// Initialize pipeline
-	pipeline := firewall.NewPipeline(firewall.PipelineConfig{
-		Config:          cfg,
-		Logger:          logger,
-		RegistryStore:   registryStore,
-		AuditStore:      auditStore,
-		AlignmentClient: alignmentClient,
-		ThreatClient:    threatClient,
+	pipelineConfig := firewall.PipelineConfig{
+		Config:            cfg,
+		Version:           version,
+		Logger:            logger,
+		RegistryStore:     registryStore,
+		AuditStore:        auditStore,
+		AlignmentClient:   alignmentClient,
+		ThreatClient:      threatClient,
+		Prompts:           promptRegistry,
+		Calibrator:        calibrator,
+		Meter:             meter,
+		AuditRedactor:     auditRedactor,
+		Metrics:           metricsRegistry,
+		TracerProvider:    tracerProvider,
+		ControlPlaneStore: toolReader,
+		ManifestStore:     toolManifests,
+		ResolverCache:     resolverCache,
+	}
+	pipeline := firewall.NewPipeline(pipelineConfig)
+
+	// Replay audited traffic against candidate toolsets and policies
+	replayJobs := replay.NewJobs(replay.NewEngine(replay.EngineConfig{
+		Store:    auditStore,
+		Pipeline: pipelineConfig,
+		Logger:   logger,
+	}), replay.JobsConfig{
+		Concurrency: cfg.ReplayConcurrency,
+		Logger:      logger,
 	})
+	defer replayJobs.Close()
+
+	auditVerifier := audit.NewVerifier(auditStore, checkpoints, publicKey)
+
+	// Dependencies checked by /readyz
+	healthPolicy, err := health.ParsePolicy(cfg.HealthRequired, cfg.HealthTimeouts, cfg.HealthCheckTimeout, cfg.HealthCheckCacheTTL)
+	if err != nil {
+		return err
+	}
+	healthChecks := health.NewRegistry()
+	healthChecks.Register(healthPolicy.Dependency("alignment_llm", health.CheckerFunc(alignmentClient.Probe)))
+	healthChecks.Register(healthPolicy.Dependency("threat_llm", health.CheckerFunc(threatClient.Probe)))
+	healthChecks.Register(healthPolicy.Dependency("audit_store", health.CheckerFunc(func(ctx context.Context) error {
+		return audit.Ping(ctx, auditStore)
+	})))
+	healthChecks.Register(healthPolicy.Dependency("audit_queue", health.CheckerFunc(auditStore.PingQueue)))
+	if controlPlaneStore != nil && cfg.StoreBackend != "memory" {
+		healthChecks.Register(healthPolicy.Dependency(cfg.StoreBackend, health.CheckerFunc(controlPlaneStore.Ping)))
+	}
+	switch manifestStore.(type) {
+	case *store.S3Client:
+		healthChecks.Register(healthPolicy.Dependency("s3", health.CheckerFunc(manifestStore.Ping)))
+	case *store.DirBlobStore:
+		healthChecks.Register(healthPolicy.Dependency("manifest_dir", health.CheckerFunc(manifestStore.Ping)))
+	}
+	if gitopsSource != nil {
+		healthChecks.Register(healthPolicy.Dependency("gitops_dir", health.CheckerFunc(gitopsSource.Ping)))
+	}
+	if cognitoVerifier != nil {
+		healthChecks.Register(healthPolicy.Dependency("jwks", health.CheckerFunc(cognitoVerifier.Ping)))
+	}
+
+	// Authenticate data plane calls by API token (nil leaves /v1/firewall open)
+	var tokenAuth *invarhttp.TokenAuthenticator
+	if cfg.RequireAPIToken {
+		tokenAuth = invarhttp.NewTokenAuthenticator(invarhttp.TokenAuthConfig{
+			Store:         controlPlaneStore,
+			CacheTTL:      cfg.TokenCacheTTL,
+			TouchInterval: cfg.TokenTouchInterval,
+			Logger:        logger,
+		})
+		logger.Info("API token authentication enabled", zap.Duration("cache_ttl", cfg.TokenCacheTTL))
+	}
 
 	// Initialize router
 	router := invarhttp.NewRouter(invarhttp.RouterConfig{
-		Logger:   logger,
-		Pipeline: pipeline,
+		Logger:             logger,
+		Pipeline:           pipeline,
+		AdminAPIKey:        cfg.AdminAPIKey,
+		AuditStore:         auditStore,
+		Calibrator:         calibrator,
+		LabelStore:         labelStore,
+		Meter:              meter,
+		AuditVerifier:      auditVerifier,
+		ReplayJobs:         replayJobs,
+		TracerProvider:     tracerProvider,
+		Health:             healthChecks,
+		EnableControlPlane: cfg.EnableControlPlane,
+		Store:              controlPlaneStore,
+		ManifestStore:      manifestStore,
+		ResolverCache:      resolverCache,
+		CognitoVerifier:    cognitoVerifier,
+		TokenAuth:          tokenAuth,
 	})
 
 	// Create server
@@ -95,14 +444,35 @@ func run() error {
 		IdleTimeout:  120 * time.Second,
 	}
 
-	// Start server in goroutine
-	serverErr := make(chan error, 1)
+	// Metrics get their own listener, so /metrics is not reachable through the API port
+	var metricsSrv *http.Server
+	if metricsRegistry != nil {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metricsRegistry.Handler())
+		metricsSrv = &http.Server{
+			Addr:         fmt.Sprintf(":%d", cfg.MetricsPort),
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+	}
+
+	// Start servers in goroutines
+	serverErr := make(chan error, 2)
 	go func() {
 		logger.Info("server listening", zap.String("addr", srv.Addr))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			serverErr <- err
 		}
 	}()
+	if metricsSrv != nil {
+		go func() {
+			logger.Info("metrics listening", zap.String("addr", metricsSrv.Addr))
+			if err := metricsSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				serverErr <- fmt.Errorf("metrics listener: %w", err)
+			}
+		}()
+	}
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -119,14 +489,155 @@ func run() error {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		return fmt.Errorf("server shutdown failed: %w", err)
+	shutdownErr := srv.Shutdown(ctx)
+	if metricsSrv != nil {
+		metricsSrv.Shutdown(ctx)
+	}
+
+	// Flush queued audit records once no more requests can arrive
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer drainCancel()
+	if err := auditStore.Close(drainCtx); err != nil {
+		logger.Error("audit drain failed", zap.Error(err), zap.Any("stats", auditStore.Stats()))
+	} else {
+		logger.Info("audit drained", zap.Any("stats", auditStore.Stats()))
+	}
+	if tokenAuth != nil {
+		if err := tokenAuth.Close(drainCtx); err != nil {
+			logger.Error("API token last-used flush failed", zap.Error(err))
+		}
+	}
+	chainedAuditStore.CheckpointAll(drainCtx)
+	if err := exporter.Close(drainCtx); err != nil {
+		logger.Error("audit export drain failed", zap.Error(err), zap.Any("sinks", exporter.Stats()))
+	}
+
+	if shutdownErr != nil {
+		return fmt.Errorf("server shutdown failed: %w", shutdownErr)
 	}
 
 	logger.Info("server stopped")
 	return nil
 }
 
+// newAuditStore returns the Postgres or S3 audit store (with its DynamoDB index)
+// and checkpoint store when configured, otherwise in-memory stores whose records
+// are lost on restart.
+func newAuditStore(ctx context.Context, cfg *config.Config, logger *zap.Logger) (audit.RetentionStore, audit.CheckpointStore, error) {
+	if cfg.AuditBackend == "postgres" {
+		db, err := postgres.Open(ctx, cfg.PostgresURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		logger.Info("using Postgres audit store")
+		return audit.NewPostgresStore(db), audit.NewPostgresCheckpointStore(db), nil
+	}
+	if cfg.AuditBucket == "" {
+		logger.Warn("audit storage not configured, using in-memory store")
+		return audit.NewInMemoryStore(), audit.NewInMemoryCheckpointStore(), nil
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	ddb := dynamodb.NewFromConfig(awsCfg)
+	index := audit.NewDynamoDBIndex(ddb, cfg.AuditIndexTable)
+	logger.Info("using S3 audit store",
+		zap.String("bucket", cfg.AuditBucket),
+		zap.String("prefix", cfg.AuditPrefix),
+		zap.String("index_table", cfg.AuditIndexTable),
+	)
+	store := audit.NewS3Store(s3.NewFromConfig(awsCfg), cfg.AuditBucket, cfg.AuditPrefix, index)
+	return store, audit.NewDynamoDBCheckpointStore(ddb, cfg.AuditIndexTable), nil
+}
+
+// newControlPlaneStores returns the control plane store for the configured
+// backend and the manifest store: a local directory when one is configured,
+// otherwise the backend's own blob storage (S3 for DynamoDB, when a bucket is
+// configured). Both are nil unless the control plane is enabled.
+func newControlPlaneStores(ctx context.Context, cfg *config.Config, logger *zap.Logger) (store.Store, store.BlobStore, error) {
+	if !cfg.EnableControlPlane {
+		return nil, nil, nil
+	}
+
+	var controlPlaneStore store.Store
+	var manifestStore store.BlobStore
+	switch cfg.StoreBackend {
+	case "memory":
+		logger.Warn("control plane using in-memory store, data is lost on restart")
+		controlPlaneStore, manifestStore = store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	case "sqlite":
+		sqlStore, err := store.NewSQLiteStore(ctx, cfg.SQLitePath)
+		if err != nil {
+			return nil, nil, err
+		}
+		controlPlaneStore, manifestStore = sqlStore, sqlStore.Blobs()
+	case "postgres":
+		db, err := postgres.Open(ctx, cfg.PostgresURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		controlPlaneStore, manifestStore = store.NewPostgresStore(db), store.NewPostgresBlobStore(db)
+	default:
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		controlPlaneStore = store.NewDynamoDBStore(dynamodb.NewFromConfig(awsCfg), store.DynamoDBConfig{
+			TenantsTable:     cfg.DDBTableTenants,
+			UsersTable:       cfg.DDBTableUsers,
+			MembershipsTable: cfg.DDBTableMemberships,
+			PrincipalsTable:  cfg.DDBTablePrincipals,
+			TokensTable:      cfg.DDBTableTokens,
+			ToolsTable:       cfg.DDBTableTools,
+			ToolsetsTable:    cfg.DDBTableToolsets,
+		})
+		if cfg.S3Bucket != "" {
+			manifestStore = store.NewS3Client(s3.NewFromConfig(awsCfg), cfg.S3Bucket)
+		}
+	}
+
+	if cfg.ManifestDir != "" {
+		dirStore, err := store.NewDirBlobStore(cfg.ManifestDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifestStore = dirStore
+	} else if manifestStore == nil {
+		logger.Warn("manifest bucket not configured, tenant-scoped tools cannot be loaded")
+	}
+	logger.Info("control plane enabled",
+		zap.String("backend", cfg.StoreBackend),
+		zap.String("manifest_bucket", cfg.S3Bucket),
+		zap.String("manifest_dir", cfg.ManifestDir),
+	)
+	return controlPlaneStore, manifestStore, nil
+}
+
+// newUsageStore returns the usage store for the control plane backend: the
+// same SQLite or Postgres database, or the DynamoDB usage table. Usage is kept
+// in memory without a control plane or on the memory backend.
+func newUsageStore(ctx context.Context, cfg *config.Config, controlPlaneStore store.Store) (usage.Store, error) {
+	if cfg.UsageInMemory() {
+		return usage.NewInMemoryStore(), nil
+	}
+
+	switch cfg.StoreBackend {
+	case "sqlite":
+		return usage.NewSQLiteStore(ctx, controlPlaneStore.(*store.SQLStore).DB())
+	case "postgres":
+		return usage.NewPostgresStore(controlPlaneStore.(*store.SQLStore).DB()), nil
+	default:
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.AWSRegion))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		}
+		return usage.NewDynamoDBStore(dynamodb.NewFromConfig(awsCfg), cfg.DDBTableUsage), nil
+	}
+}
+
 func initLogger(level string) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
@@ -0,0 +1,362 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"invarity/internal/types"
+)
+
+// OverflowPolicy decides what Write does when the queue is full.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock waits for room in the queue.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDrop discards the record and counts it in AsyncStats.Dropped.
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowFailClosed rejects the record with ErrBufferFull so the request fails.
+	OverflowFailClosed OverflowPolicy = "fail_closed"
+)
+
+// ParseOverflowPolicy parses an overflow policy name.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case OverflowBlock, OverflowDrop, OverflowFailClosed:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown audit overflow policy %q (want block, drop or fail_closed)", s)
+	}
+}
+
+var (
+	// ErrBufferFull is returned by AsyncStore.Write under OverflowFailClosed when the queue is full.
+	ErrBufferFull = errors.New("audit buffer is full")
+	// ErrStoreClosed is returned by AsyncStore.Write after Close.
+	ErrStoreClosed = errors.New("audit store is closed")
+)
+
+// storeWriteTimeout bounds the backend writes of a single flush.
+const storeWriteTimeout = 30 * time.Second
+
+// AsyncConfig configures an AsyncStore.
+type AsyncConfig struct {
+	BufferSize    int            // Queue capacity (default 1024)
+	BatchSize     int            // Records per flush (default 100)
+	FlushInterval time.Duration  // Maximum time a record waits before a flush (default 1s)
+	Overflow      OverflowPolicy // Behavior when the queue is full (default block)
+	Spool         *Spool         // Holds records the store rejects; records are lost without one
+	Logger        *zap.Logger
+}
+
+// AsyncStats are counters describing an AsyncStore.
+type AsyncStats struct {
+	Queued   int   `json:"queued"`   // Records waiting to be flushed
+	Written  int64 `json:"written"`  // Records written to the store, including drained spool records
+	Dropped  int64 `json:"dropped"`  // Records discarded under OverflowDrop
+	Rejected int64 `json:"rejected"` // Records refused under OverflowFailClosed
+	Spooled  int   `json:"spooled"`  // Records waiting in the spool
+	Lost     int64 `json:"lost"`     // Records the store rejected that could not be spooled
+}
+
+// AsyncStore queues audit records and writes them to the underlying store in
+// batches from a background goroutine, so request latency does not depend on
+// the store. Records the store rejects go to the spool and are retried on every
+// flush interval.
+type AsyncStore struct {
+	store    Store
+	queue    chan *types.AuditRecord
+	batch    int
+	interval time.Duration
+	overflow OverflowPolicy
+	spool    *Spool
+	logger   *zap.Logger
+
+	mu       sync.RWMutex // Guards closed, so no write starts after Close
+	closed   bool
+	inflight sync.WaitGroup // Writes that may still send to the queue
+	stop     chan struct{}  // Closed by Close
+	done     chan struct{}
+
+	pendingMu sync.RWMutex
+	pending   map[string]*types.AuditRecord // Queued or in-flight, for Get
+
+	written  atomic.Int64
+	dropped  atomic.Int64
+	rejected atomic.Int64
+	lost     atomic.Int64
+}
+
+// NewAsyncStore wraps store and starts the background writer. Call Close to drain it.
+func NewAsyncStore(store Store, cfg AsyncConfig) *AsyncStore {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Overflow == "" {
+		cfg.Overflow = OverflowBlock
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	s := &AsyncStore{
+		store:    store,
+		queue:    make(chan *types.AuditRecord, cfg.BufferSize),
+		batch:    cfg.BatchSize,
+		interval: cfg.FlushInterval,
+		overflow: cfg.Overflow,
+		spool:    cfg.Spool,
+		logger:   cfg.Logger,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		pending:  make(map[string]*types.AuditRecord),
+	}
+	go s.run()
+	return s
+}
+
+// Write assigns the record its ID and timestamp and queues it. Under
+// OverflowDrop a record that does not fit is discarded and "" is returned.
+// Under OverflowBlock a write waiting for room fails with ErrStoreClosed once
+// Close is called.
+func (s *AsyncStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	if record.AuditID == "" {
+		record.AuditID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return "", ErrStoreClosed
+	}
+	s.inflight.Add(1)
+	s.mu.RUnlock()
+	defer s.inflight.Done()
+
+	s.setPending(record)
+	select {
+	case s.queue <- record:
+		return record.AuditID, nil
+	default:
+	}
+
+	switch s.overflow {
+	case OverflowDrop:
+		s.clearPending(record.AuditID)
+		s.dropped.Add(1)
+		s.logger.Warn("audit buffer full, dropping record", zap.String("audit_id", record.AuditID))
+		return "", nil
+	case OverflowFailClosed:
+		s.clearPending(record.AuditID)
+		s.rejected.Add(1)
+		return "", ErrBufferFull
+	default:
+		select {
+		case s.queue <- record:
+			return record.AuditID, nil
+		case <-s.stop:
+			s.clearPending(record.AuditID)
+			return "", ErrStoreClosed
+		case <-ctx.Done():
+			s.clearPending(record.AuditID)
+			return "", ctx.Err()
+		}
+	}
+}
+
+// Get returns a queued record, or reads it from the store.
+func (s *AsyncStore) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
+	s.pendingMu.RLock()
+	record, ok := s.pending[auditID]
+	s.pendingMu.RUnlock()
+	if ok {
+		return record, nil
+	}
+	return s.store.Get(ctx, auditID)
+}
+
+// List reads from the store; records still queued or spooled are not included.
+func (s *AsyncStore) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	return s.store.List(ctx, filter)
+}
+
+// Ping reports an error when the store is closed, and otherwise pings the
+// underlying store. A full queue is reported by PingQueue instead, since it is
+// usually a burst the writer catches up with.
+func (s *AsyncStore) Ping(ctx context.Context) error {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return fmt.Errorf("audit store closed")
+	}
+	return Ping(ctx, s.store)
+}
+
+// PingQueue reports ErrBufferFull while the queue is full.
+func (s *AsyncStore) PingQueue(ctx context.Context) error {
+	if len(s.queue) == cap(s.queue) {
+		return fmt.Errorf("%w: %d records queued", ErrBufferFull, len(s.queue))
+	}
+	return nil
+}
+
+// Stats returns the current counters.
+func (s *AsyncStore) Stats() AsyncStats {
+	stats := AsyncStats{
+		Queued:   len(s.queue),
+		Written:  s.written.Load(),
+		Dropped:  s.dropped.Load(),
+		Rejected: s.rejected.Load(),
+		Lost:     s.lost.Load(),
+	}
+	if s.spool != nil {
+		stats.Spooled = s.spool.Len()
+	}
+	return stats
+}
+
+// Close stops accepting records and waits until the queue has been flushed and
+// the spool drained once, or ctx is done. Records the store still rejects remain
+// in the spool for the next start.
+func (s *AsyncStore) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.stop)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("audit drain incomplete: %w", ctx.Err())
+	}
+}
+
+func (s *AsyncStore) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	batch := make([]*types.AuditRecord, 0, s.batch)
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.batch {
+				s.flush(batch)
+				batch = make([]*types.AuditRecord, 0, s.batch)
+			}
+		case <-s.stop:
+			// Writes still in flight either queue their record or give up on stop
+			s.inflight.Wait()
+			for len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+				if len(batch) >= s.batch {
+					s.flush(batch)
+					batch = make([]*types.AuditRecord, 0, s.batch)
+				}
+			}
+			s.flush(batch)
+			s.drainSpool()
+			return
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flush(batch)
+				batch = make([]*types.AuditRecord, 0, s.batch)
+			}
+			s.drainSpool()
+		}
+	}
+}
+
+// flush writes a batch to the store. On the first failure the store is assumed
+// unavailable and the rest of the batch is spooled.
+func (s *AsyncStore) flush(batch []*types.AuditRecord) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeWriteTimeout)
+	defer cancel()
+
+	for i, record := range batch {
+		if _, err := s.store.Write(ctx, record); err != nil && !errors.Is(err, ErrAlreadyExists) {
+			s.logger.Warn("failed to write audit batch, spooling",
+				zap.Error(err),
+				zap.Int("records", len(batch)-i),
+			)
+			s.spoolRecords(batch[i:])
+			return
+		}
+		s.written.Add(1)
+		s.clearPending(record.AuditID)
+	}
+}
+
+func (s *AsyncStore) spoolRecords(records []*types.AuditRecord) {
+	defer func() {
+		for _, record := range records {
+			s.clearPending(record.AuditID)
+		}
+	}()
+
+	if s.spool == nil {
+		s.lost.Add(int64(len(records)))
+		s.logger.Error("audit records lost: store unavailable and no spool configured", zap.Int("records", len(records)))
+		return
+	}
+	if err := s.spool.Append(records); err != nil {
+		s.lost.Add(int64(len(records)))
+		s.logger.Error("audit records lost: failed to spool", zap.Error(err), zap.Int("records", len(records)))
+	}
+}
+
+func (s *AsyncStore) drainSpool() {
+	if s.spool == nil || s.spool.Len() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), storeWriteTimeout)
+	defer cancel()
+
+	n, err := s.spool.Drain(ctx, s.store)
+	s.written.Add(int64(n))
+	if n > 0 {
+		s.logger.Info("drained audit spool", zap.Int("records", n), zap.Int("remaining", s.spool.Len()))
+	}
+	if err != nil {
+		s.logger.Debug("audit store still unavailable", zap.Error(err))
+	}
+}
+
+func (s *AsyncStore) setPending(record *types.AuditRecord) {
+	s.pendingMu.Lock()
+	s.pending[record.AuditID] = record
+	s.pendingMu.Unlock()
+}
+
+func (s *AsyncStore) clearPending(auditID string) {
+	s.pendingMu.Lock()
+	delete(s.pending, auditID)
+	s.pendingMu.Unlock()
+}
@@ -24,16 +24,36 @@ type Store interface {
 	List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error)
 }
 
+// Pinger is implemented by stores that can check they accept writes without
+// storing a record.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that store is usable. Stores that do not implement Pinger are
+// assumed usable.
+func Ping(ctx context.Context, store Store) error {
+	if p, ok := store.(Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
 // ListFilter contains optional filters for listing audit records.
 type ListFilter struct {
-	OrgID     string
-	ActorID   string
-	ActionID  string
-	Decision  types.Decision
-	StartTime time.Time
-	EndTime   time.Time
-	Limit     int
-	Offset    int
+	OrgID       string
+	ActorID     string
+	PrincipalID string
+	ActionID    string
+	Decision    types.Decision
+	RiskTier    types.RiskTier
+	ReasonCode  string // Matches a full reason or its code (the part before ':')
+	Query       string // Case-insensitive substring of the user intent
+	StartTime   time.Time
+	EndTime     time.Time
+	Limit       int
+	Offset      int
+	Cursor      string // From EncodeCursor; lists records after it
 }
 
 // InMemoryStore is an in-memory implementation of Store.
@@ -75,7 +95,7 @@ func (s *InMemoryStore) Get(ctx context.Context, auditID string) (*types.AuditRe
 
 	record, ok := s.records[auditID]
 	if !ok {
-		return nil, fmt.Errorf("audit record not found: %s", auditID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, auditID)
 	}
 
 	return record, nil
@@ -86,6 +106,16 @@ func (s *InMemoryStore) List(ctx context.Context, filter *ListFilter) ([]*types.
 	defer s.mu.RUnlock()
 
 	result := make([]*types.AuditRecord, 0)
+	skipped := 0
+
+	var after string
+	if filter != nil && filter.Cursor != "" {
+		key, _, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = key
+	}
 
 	// Iterate in reverse order (newest first)
 	for i := len(s.order) - 1; i >= 0; i-- {
@@ -98,18 +128,37 @@ func (s *InMemoryStore) List(ctx context.Context, filter *ListFilter) ([]*types.
 			if filter.ActorID != "" && record.Actor.ID != filter.ActorID {
 				continue
 			}
+			if filter.PrincipalID != "" && record.PrincipalID != filter.PrincipalID {
+				continue
+			}
 			if filter.ActionID != "" && record.ToolCall.ActionID != filter.ActionID {
 				continue
 			}
 			if filter.Decision != "" && record.Decision != filter.Decision {
 				continue
 			}
+			if filter.RiskTier != "" && record.RiskTier != filter.RiskTier {
+				continue
+			}
+			if filter.ReasonCode != "" && !hasReason(record.Reasons, filter.ReasonCode) {
+				continue
+			}
+			if filter.Query != "" && !matchesIntent(record.UserIntent, filter.Query) {
+				continue
+			}
 			if !filter.StartTime.IsZero() && record.CreatedAt.Before(filter.StartTime) {
 				continue
 			}
 			if !filter.EndTime.IsZero() && record.CreatedAt.After(filter.EndTime) {
 				continue
 			}
+			if after != "" && SortKey(record) >= after {
+				continue
+			}
+			if skipped < filter.Offset {
+				skipped++
+				continue
+			}
 		}
 
 		result = append(result, record)
@@ -122,53 +171,52 @@ func (s *InMemoryStore) List(ctx context.Context, filter *ListFilter) ([]*types.
 	return result, nil
 }
 
-// S3Store is a stub for S3-backed audit storage.
-type S3Store struct {
-	bucket string
-	region string
-	prefix string
-}
+// Tenants returns the IDs of tenants with stored records.
+func (s *InMemoryStore) Tenants(ctx context.Context) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-// NewS3Store creates a new S3-backed audit store.
-func NewS3Store(bucket, region, prefix string) *S3Store {
-	if prefix == "" {
-		prefix = "audit"
-	}
-	return &S3Store{
-		bucket: bucket,
-		region: region,
-		prefix: prefix,
+	seen := make(map[string]bool)
+	tenants := make([]string, 0)
+	for _, id := range s.order {
+		tenantID := recordTenant(s.records[id])
+		if !seen[tenantID] {
+			seen[tenantID] = true
+			tenants = append(tenants, tenantID)
+		}
 	}
+	return tenants, nil
 }
 
-func (s *S3Store) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
-	// TODO: Implement S3 write
-	// Key format: {prefix}/{org_id}/{year}/{month}/{day}/{audit_id}.json
-	// Consider using a buffer/batch writer for high throughput
-
-	if record.AuditID == "" {
-		record.AuditID = uuid.New().String()
-	}
-
-	return record.AuditID, fmt.Errorf("S3Store.Write not implemented")
+// ListKeys lists records like List.
+func (s *InMemoryStore) ListKeys(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	return s.List(ctx, filter)
 }
 
-func (s *S3Store) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
-	// TODO: Implement S3 read
-	// This requires knowing the org_id and date to construct the key
-	// Consider maintaining an index (DynamoDB) for lookups by audit_id
-	return nil, fmt.Errorf("S3Store.Get not implemented")
-}
+// Delete removes records by their audit IDs.
+func (s *InMemoryStore) Delete(ctx context.Context, records []*types.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-func (s *S3Store) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
-	// TODO: Implement S3 list with prefix scanning
-	// This is expensive for S3; consider using an index for queries
-	return nil, fmt.Errorf("S3Store.List not implemented")
+	deleted := make(map[string]bool, len(records))
+	for _, record := range records {
+		delete(s.records, record.AuditID)
+		deleted[record.AuditID] = true
+	}
+	order := s.order[:0]
+	for _, id := range s.order {
+		if !deleted[id] {
+			order = append(order, id)
+		}
+	}
+	s.order = order
+	return nil
 }
 
 // Writer wraps a Store and provides convenience methods.
 type Writer struct {
-	store Store
+	store    Store
+	redactor *Redactor
 }
 
 // NewWriter creates a new audit writer.
@@ -176,28 +224,54 @@ func NewWriter(store Store) *Writer {
 	return &Writer{store: store}
 }
 
+// NewRedactingWriter creates an audit writer that applies the redactor's
+// tenant policies to each record before it is stored.
+func NewRedactingWriter(store Store, redactor *Redactor) *Writer {
+	return &Writer{store: store, redactor: redactor}
+}
+
 // WriteFromResponse creates and stores an audit record from a firewall response.
+// sensitive lists the resolved tool's sensitive argument paths (nil if the tool
+// was not resolved); snapshot records the artifacts the decision was made with,
+// and flags any types.AuditFlag* findings.
 func (w *Writer) WriteFromResponse(
 	ctx context.Context,
 	req *types.ToolCallRequest,
 	resp *types.FirewallDecisionResponse,
 	pipelineStep string,
+	sensitive []types.SensitiveArg,
+	snapshot *types.DecisionSnapshot,
+	flags []string,
 ) (string, error) {
 	record := &types.AuditRecord{
-		RequestID:    resp.RequestID,
-		OrgID:        req.OrgID,
-		Actor:        req.Actor,
-		Environment:  req.Environment,
-		ToolCall:     req.ToolCall,
-		UserIntent:   req.UserIntent,
-		Decision:     resp.Decision,
-		RiskTier:     resp.RiskTier,
-		Reasons:      resp.Reasons,
-		Constraints:  resp.Constraints,
-		Alignment:    resp.Alignment,
-		Threat:       resp.Threat,
-		Timing:       resp.Timing,
-		PipelineStep: pipelineStep,
+		RequestID:      resp.RequestID,
+		OrgID:          req.OrgID,
+		TenantID:       req.TenantID,
+		PrincipalID:    req.PrincipalID,
+		Actor:          req.Actor,
+		Environment:    req.Environment,
+		ToolCall:       req.ToolCall,
+		UserIntent:     req.UserIntent,
+		BoundedContext: req.BoundedContext,
+		Decision:       resp.Decision,
+		RiskTier:       resp.RiskTier,
+		Reasons:        resp.Reasons,
+		Constraints:    resp.Constraints,
+		Alignment:      resp.Alignment,
+		Threat:         resp.Threat,
+		ContextScan:    resp.ContextScan,
+		Usage:          resp.Usage,
+		Timing:         resp.Timing,
+		PipelineStep:   pipelineStep,
+		Snapshot:       snapshot,
+		Flags:          flags,
+	}
+
+	if w.redactor != nil {
+		if err := w.redactor.Apply(record, sensitive); err != nil {
+			// Never store what could not be redacted
+			return "", fmt.Errorf("failed to redact audit record: %w", err)
+		}
 	}
 
 	return w.store.Write(ctx, record)
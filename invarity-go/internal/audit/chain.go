@@ -0,0 +1,304 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"invarity/internal/types"
+	"invarity/internal/util"
+)
+
+// DefaultCheckpointInterval is the number of records per checkpoint window.
+const DefaultCheckpointInterval = 1000
+
+// chainPageSize is the page size used when reading a tenant's chain back from the store.
+const chainPageSize = 500
+
+// chainWriteAttempts is how many times Write reloads the head and retries when
+// another writer took the next sequence.
+const chainWriteAttempts = 5
+
+// RecordHash returns the hash of a record over its canonical JSON with Hash empty.
+func RecordHash(record *types.AuditRecord) (string, error) {
+	unhashed := *record
+	unhashed.Hash = ""
+	hash, err := util.HashJSON(unhashed)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash audit record: %w", err)
+	}
+	return hash, nil
+}
+
+// ChainConfig configures a ChainedStore.
+type ChainConfig struct {
+	Checkpoints        CheckpointStore // Optional: checkpoints are not cut without one
+	CheckpointInterval int             // Records per checkpoint window (default 1000)
+	Signer             *Signer         // Optional: checkpoints are unsigned without one
+	Logger             *zap.Logger
+}
+
+// chainHead is the tip of a tenant's chain and its unchecked window.
+type chainHead struct {
+	sequence int64
+	hash     string
+	window   []string // Hashes of records after the last checkpoint
+}
+
+// ChainedStore links each tenant's audit records into a hash chain before
+// writing them to the underlying store, and cuts a checkpoint every
+// CheckpointInterval records. Writes are serialized; wrap it in an AsyncStore
+// so the chain is extended off the request path.
+//
+// Stores that reject a taken sequence with ErrSequenceTaken (the S3 store's
+// DynamoDB index and Postgres) let several replicas extend the same chain: a
+// writer whose head is stale reloads it and rechains the record.
+type ChainedStore struct {
+	store       Store
+	checkpoints CheckpointStore
+	interval    int
+	signer      *Signer
+	logger      *zap.Logger
+
+	mu    sync.Mutex
+	heads map[string]*chainHead
+}
+
+// NewChainedStore wraps store with per-tenant hash chaining.
+func NewChainedStore(store Store, cfg ChainConfig) *ChainedStore {
+	if cfg.CheckpointInterval <= 0 {
+		cfg.CheckpointInterval = DefaultCheckpointInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &ChainedStore{
+		store:       store,
+		checkpoints: cfg.Checkpoints,
+		interval:    cfg.CheckpointInterval,
+		signer:      cfg.Signer,
+		logger:      cfg.Logger,
+		heads:       make(map[string]*chainHead),
+	}
+}
+
+// Write sets the record's sequence, previous hash and hash, then stores it.
+// The chain only advances when the store accepts the record. A record an
+// earlier attempt already stored is not chained again: Write returns
+// ErrAlreadyExists with the record's stored chain fields, and adopts it as the
+// head if it extends the chain. If another writer took the sequence, the head
+// is reloaded from the store and the record chained after it.
+func (s *ChainedStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tenantID := recordTenant(record)
+	for attempt := 1; ; attempt++ {
+		auditID, err := s.write(ctx, tenantID, record)
+		if !errors.Is(err, ErrSequenceTaken) || attempt == chainWriteAttempts {
+			return auditID, err
+		}
+		s.logger.Debug("audit chain head moved, reloading", zap.String("tenant_id", tenantID), zap.Int64("sequence", record.Sequence))
+		delete(s.heads, tenantID)
+	}
+}
+
+// write chains the record after the tenant's cached head and stores it.
+func (s *ChainedStore) write(ctx context.Context, tenantID string, record *types.AuditRecord) (string, error) {
+	head, err := s.head(ctx, tenantID)
+	if err != nil {
+		return record.AuditID, err
+	}
+
+	record.Sequence = head.sequence + 1
+	record.PrevHash = head.hash
+	record.Hash = ""
+	if record.AuditID == "" {
+		// The ID is part of the hash, so it must be set before hashing
+		record.AuditID = uuid.New().String()
+	}
+	hash, err := RecordHash(record)
+	if err != nil {
+		return record.AuditID, err
+	}
+	record.Hash = hash
+
+	auditID, err := s.store.Write(ctx, record)
+	if errors.Is(err, ErrAlreadyExists) {
+		s.adoptStored(ctx, tenantID, head, record)
+		return auditID, err
+	}
+	if err != nil {
+		return auditID, err
+	}
+
+	head.sequence = record.Sequence
+	head.hash = hash
+	head.window = append(head.window, hash)
+	if len(head.window) >= s.interval {
+		s.cutCheckpoint(ctx, tenantID, head)
+	}
+	return auditID, nil
+}
+
+// adoptStored copies the stored copy's chain fields into record and advances
+// the head past it when it is the head's next record, which happens when an
+// earlier write was stored but reported as failed.
+func (s *ChainedStore) adoptStored(ctx context.Context, tenantID string, head *chainHead, record *types.AuditRecord) {
+	stored, err := s.store.Get(ctx, record.AuditID)
+	if err != nil {
+		s.logger.Warn("failed to read stored audit record", zap.Error(err), zap.String("audit_id", record.AuditID))
+		return
+	}
+	record.Sequence = stored.Sequence
+	record.PrevHash = stored.PrevHash
+	record.Hash = stored.Hash
+	if stored.Sequence != head.sequence+1 || stored.PrevHash != head.hash || stored.Hash == "" {
+		return
+	}
+	head.sequence = stored.Sequence
+	head.hash = stored.Hash
+	head.window = append(head.window, stored.Hash)
+	if len(head.window) >= s.interval {
+		s.cutCheckpoint(ctx, tenantID, head)
+	}
+}
+
+func (s *ChainedStore) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
+	return s.store.Get(ctx, auditID)
+}
+
+func (s *ChainedStore) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	return s.store.List(ctx, filter)
+}
+
+func (s *ChainedStore) Ping(ctx context.Context) error {
+	return Ping(ctx, s.store)
+}
+
+// CheckpointAll cuts a checkpoint for every tenant with unchecked records, so
+// the chain's tail is covered (e.g. on shutdown).
+func (s *ChainedStore) CheckpointAll(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tenantID, head := range s.heads {
+		if len(head.window) > 0 {
+			s.cutCheckpoint(ctx, tenantID, head)
+		}
+	}
+}
+
+// cutCheckpoint commits the head's window. On failure the window is kept and
+// the checkpoint is retried on the next write. Records another writer has
+// already checkpointed are dropped from the window first.
+func (s *ChainedStore) cutCheckpoint(ctx context.Context, tenantID string, head *chainHead) {
+	if s.checkpoints == nil {
+		head.window = nil
+		return
+	}
+
+	latest, err := s.checkpoints.Latest(ctx, tenantID)
+	if err != nil {
+		s.logger.Error("failed to load audit checkpoint", zap.Error(err), zap.String("tenant_id", tenantID))
+		return
+	}
+	if latest != nil {
+		from := head.sequence - int64(len(head.window)) + 1
+		if covered := latest.ToSequence - from + 1; covered > 0 {
+			head.window = head.window[min(covered, int64(len(head.window))):]
+		}
+		if len(head.window) == 0 {
+			return
+		}
+	}
+
+	root, err := MerkleRoot(head.window)
+	if err != nil {
+		s.logger.Error("failed to compute checkpoint root", zap.Error(err), zap.String("tenant_id", tenantID))
+		return
+	}
+	cp := &Checkpoint{
+		TenantID:     tenantID,
+		FromSequence: head.sequence - int64(len(head.window)) + 1,
+		ToSequence:   head.sequence,
+		MerkleRoot:   root,
+		LastHash:     head.hash,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if s.signer != nil {
+		if err := s.signer.Sign(cp); err != nil {
+			s.logger.Error("failed to sign checkpoint", zap.Error(err), zap.String("tenant_id", tenantID))
+			return
+		}
+	}
+	if err := s.checkpoints.Put(ctx, cp); err != nil {
+		s.logger.Error("failed to store checkpoint", zap.Error(err), zap.String("tenant_id", tenantID))
+		return
+	}
+	head.window = nil
+}
+
+// head returns the tenant's chain head, loading it from the store on first use.
+func (s *ChainedStore) head(ctx context.Context, tenantID string) (*chainHead, error) {
+	if head, ok := s.heads[tenantID]; ok {
+		return head, nil
+	}
+
+	head := &chainHead{}
+	if s.checkpoints != nil {
+		cp, err := s.checkpoints.Latest(ctx, tenantID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audit checkpoint: %w", err)
+		}
+		if cp != nil {
+			head.sequence = cp.ToSequence
+			head.hash = cp.LastHash
+		}
+	}
+	checkpointed := head.sequence
+
+	// Read newest first until the last checkpoint; without checkpoints the first page has the tip
+	var window []*types.AuditRecord
+	for offset := 0; ; offset += chainPageSize {
+		page, err := s.store.List(ctx, &ListFilter{OrgID: tenantID, Limit: chainPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("failed to load audit chain head: %w", err)
+		}
+		reachedCheckpoint := false
+		for _, record := range page {
+			if record.Sequence == 0 {
+				continue
+			}
+			if record.Sequence > head.sequence {
+				head.sequence = record.Sequence
+				head.hash = record.Hash
+			}
+			if record.Sequence > checkpointed {
+				window = append(window, record)
+			} else {
+				reachedCheckpoint = true
+			}
+		}
+		if len(page) < chainPageSize || reachedCheckpoint || s.checkpoints == nil {
+			break
+		}
+	}
+
+	sort.Slice(window, func(i, j int) bool { return window[i].Sequence < window[j].Sequence })
+	for _, record := range window {
+		head.window = append(head.window, record.Hash)
+	}
+	s.heads[tenantID] = head
+	return head, nil
+}
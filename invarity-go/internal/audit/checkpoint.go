@@ -0,0 +1,267 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"invarity/internal/util"
+)
+
+// Checkpoint commits to a window of a tenant's audit chain with the Merkle root
+// of the record hashes from FromSequence to ToSequence (inclusive).
+type Checkpoint struct {
+	TenantID     string    `json:"tenant_id"`
+	FromSequence int64     `json:"from_sequence"`
+	ToSequence   int64     `json:"to_sequence"`
+	MerkleRoot   string    `json:"merkle_root"`
+	LastHash     string    `json:"last_hash"` // Hash of the record at ToSequence
+	CreatedAt    time.Time `json:"created_at"`
+	KeyID        string    `json:"key_id,omitempty"`
+	Signature    string    `json:"signature,omitempty"` // base64 ed25519 over the canonical JSON with Signature empty
+}
+
+// MerkleRoot returns the RFC 6962 Merkle tree hash of hex-encoded leaf hashes.
+func MerkleRoot(leaves []string) (string, error) {
+	nodes := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		b, err := hex.DecodeString(leaf)
+		if err != nil {
+			return "", fmt.Errorf("invalid leaf hash %q: %w", leaf, err)
+		}
+		nodes[i] = b
+	}
+	return hex.EncodeToString(merkleTreeHash(nodes)), nil
+}
+
+func merkleTreeHash(leaves [][]byte) []byte {
+	switch len(leaves) {
+	case 0:
+		h := sha256.Sum256(nil)
+		return h[:]
+	case 1:
+		h := sha256.Sum256(append([]byte{0x00}, leaves[0]...))
+		return h[:]
+	}
+
+	// Split at the largest power of two smaller than n
+	k := 1
+	for k*2 < len(leaves) {
+		k *= 2
+	}
+	node := append([]byte{0x01}, merkleTreeHash(leaves[:k])...)
+	node = append(node, merkleTreeHash(leaves[k:])...)
+	h := sha256.Sum256(node)
+	return h[:]
+}
+
+// Signer signs checkpoints with an ed25519 key.
+type Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewSigner creates a signer from a base64-encoded 32-byte ed25519 seed.
+func NewSigner(seed string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid audit signing key: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid audit signing key: want %d-byte ed25519 seed, got %d bytes", ed25519.SeedSize, len(raw))
+	}
+	key := ed25519.NewKeyFromSeed(raw)
+	return &Signer{key: key, keyID: KeyID(key.Public().(ed25519.PublicKey))}, nil
+}
+
+// PublicKey returns the verification key.
+func (s *Signer) PublicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// Sign sets the checkpoint's key ID and signature.
+func (s *Signer) Sign(cp *Checkpoint) error {
+	cp.KeyID = s.keyID
+	payload, err := checkpointPayload(cp)
+	if err != nil {
+		return err
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, payload))
+	return nil
+}
+
+// KeyID identifies a public key by the first 16 hex characters of its SHA-256.
+func KeyID(pub ed25519.PublicKey) string {
+	return util.HashBytes(pub)[:16]
+}
+
+// VerifyCheckpointSignature reports whether cp carries a valid signature by pub.
+func VerifyCheckpointSignature(cp *Checkpoint, pub ed25519.PublicKey) bool {
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil || cp.Signature == "" {
+		return false
+	}
+	payload, err := checkpointPayload(cp)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(pub, payload, sig)
+}
+
+func checkpointPayload(cp *Checkpoint) ([]byte, error) {
+	unsigned := *cp
+	unsigned.Signature = ""
+	payload, err := util.CanonicalJSON(unsigned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to canonicalize checkpoint: %w", err)
+	}
+	return payload, nil
+}
+
+// CheckpointStore persists checkpoints.
+type CheckpointStore interface {
+	// Put stores a checkpoint.
+	Put(ctx context.Context, cp *Checkpoint) error
+	// List returns a tenant's checkpoints ordered by ToSequence.
+	List(ctx context.Context, tenantID string) ([]*Checkpoint, error)
+	// Latest returns the tenant's most recent checkpoint, or nil if there is none.
+	Latest(ctx context.Context, tenantID string) (*Checkpoint, error)
+}
+
+// InMemoryCheckpointStore is an in-memory implementation of CheckpointStore.
+type InMemoryCheckpointStore struct {
+	mu          sync.RWMutex
+	checkpoints map[string][]*Checkpoint
+}
+
+// NewInMemoryCheckpointStore creates a new in-memory checkpoint store.
+func NewInMemoryCheckpointStore() *InMemoryCheckpointStore {
+	return &InMemoryCheckpointStore{checkpoints: make(map[string][]*Checkpoint)}
+}
+
+func (s *InMemoryCheckpointStore) Put(ctx context.Context, cp *Checkpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := append(s.checkpoints[cp.TenantID], cp)
+	sort.Slice(list, func(i, j int) bool { return list[i].ToSequence < list[j].ToSequence })
+	s.checkpoints[cp.TenantID] = list
+	return nil
+}
+
+func (s *InMemoryCheckpointStore) List(ctx context.Context, tenantID string) ([]*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*Checkpoint(nil), s.checkpoints[tenantID]...), nil
+}
+
+func (s *InMemoryCheckpointStore) Latest(ctx context.Context, tenantID string) (*Checkpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := s.checkpoints[tenantID]
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return list[len(list)-1], nil
+}
+
+// checkpointKeyPrefix is the reserved partition key prefix for checkpoints in the audit index table.
+const checkpointKeyPrefix = "checkpoint#"
+
+// DynamoDBCheckpointStore keeps checkpoints in the audit index table under
+// tenant_id "checkpoint#{tenant}" with a zero-padded ToSequence sort key.
+type DynamoDBCheckpointStore struct {
+	client DynamoDBAPI
+	table  string
+}
+
+// NewDynamoDBCheckpointStore creates a checkpoint store on the audit index table.
+func NewDynamoDBCheckpointStore(client DynamoDBAPI, table string) *DynamoDBCheckpointStore {
+	return &DynamoDBCheckpointStore{
+		client: client,
+		table:  table,
+	}
+}
+
+func (s *DynamoDBCheckpointStore) Put(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+
+	_, err = s.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []ddbtypes.TransactWriteItem{{Put: &ddbtypes.Put{
+			TableName: aws.String(s.table),
+			Item: map[string]ddbtypes.AttributeValue{
+				"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: checkpointKeyPrefix + cp.TenantID},
+				"created_audit": &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf("%020d", cp.ToSequence)},
+				"checkpoint":    &ddbtypes.AttributeValueMemberS{Value: string(data)},
+			},
+			ConditionExpression: aws.String("attribute_not_exists(tenant_id)"), // Checkpoints are never replaced
+		}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *DynamoDBCheckpointStore) List(ctx context.Context, tenantID string) ([]*Checkpoint, error) {
+	return s.query(ctx, tenantID, true, 0)
+}
+
+func (s *DynamoDBCheckpointStore) Latest(ctx context.Context, tenantID string) (*Checkpoint, error) {
+	list, err := s.query(ctx, tenantID, false, 1)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (s *DynamoDBCheckpointStore) query(ctx context.Context, tenantID string, ascending bool, limit int32) ([]*Checkpoint, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.table),
+		KeyConditionExpression: aws.String("tenant_id = :tid"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":tid": &ddbtypes.AttributeValueMemberS{Value: checkpointKeyPrefix + tenantID},
+		},
+		ScanIndexForward: aws.Bool(ascending),
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(limit)
+	}
+
+	checkpoints := make([]*Checkpoint, 0)
+	for {
+		result, err := s.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+		}
+		for _, item := range result.Items {
+			attr, ok := item["checkpoint"].(*ddbtypes.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			var cp Checkpoint
+			if err := json.Unmarshal([]byte(attr.Value), &cp); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+			}
+			checkpoints = append(checkpoints, &cp)
+		}
+		if len(result.LastEvaluatedKey) == 0 || (limit > 0 && len(checkpoints) >= int(limit)) {
+			return checkpoints, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
@@ -0,0 +1,255 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/types"
+)
+
+// sinkSendTimeout bounds one Send call, including a webhook's retries.
+const sinkSendTimeout = time.Minute
+
+// ExporterConfig configures an Exporter.
+type ExporterConfig struct {
+	BufferSize    int           // Records queued per sink before new ones are dropped (default 1024)
+	BatchSize     int           // Records per Send (default 100)
+	FlushInterval time.Duration // Maximum time a record waits before a Send (default 1s)
+	Logger        *zap.Logger
+}
+
+// ExportStats are counters for one sink.
+type ExportStats struct {
+	Sink     string `json:"sink"`
+	Queued   int    `json:"queued"`
+	Exported int64  `json:"exported"` // Records the sink accepted
+	Failed   int64  `json:"failed"`   // Records in batches the sink rejected
+	Dropped  int64  `json:"dropped"`  // Records discarded because the sink's queue was full
+}
+
+// Exporter fans written audit records out to sinks. Each sink has its own
+// queue and goroutine, so a slow or failing sink never delays audit writes or
+// other sinks; records that do not fit in a sink's queue are dropped and counted.
+type Exporter struct {
+	cfg ExporterConfig
+
+	mu     sync.RWMutex // Guards closed against sends on closed queues
+	routes []*sinkRoute
+	closed bool
+}
+
+type sinkRoute struct {
+	sink     Sink
+	filter   SinkFilter
+	queue    chan *types.AuditRecord
+	batch    int
+	interval time.Duration
+	logger   *zap.Logger
+	done     chan struct{}
+
+	exported atomic.Int64
+	failed   atomic.Int64
+	dropped  atomic.Int64
+}
+
+// NewExporter creates an exporter with no sinks.
+func NewExporter(cfg ExporterConfig) *Exporter {
+	if cfg.BufferSize <= 0 {
+		cfg.BufferSize = 1024
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &Exporter{cfg: cfg}
+}
+
+// NewExporterFromConfigs creates an exporter with a sink for each config.
+func NewExporterFromConfigs(configs []SinkConfig, cfg ExporterConfig) (*Exporter, error) {
+	e := NewExporter(cfg)
+	for _, sc := range configs {
+		sink, err := NewSink(sc)
+		if err != nil {
+			e.Close(context.Background())
+			return nil, fmt.Errorf("failed to create audit sink %s: %w", sc.Name, err)
+		}
+		e.Add(sink, sc.Filter())
+	}
+	return e, nil
+}
+
+// Add starts delivering the records that match filter to sink.
+func (e *Exporter) Add(sink Sink, filter SinkFilter) {
+	route := &sinkRoute{
+		sink:     sink,
+		filter:   filter,
+		queue:    make(chan *types.AuditRecord, e.cfg.BufferSize),
+		batch:    e.cfg.BatchSize,
+		interval: e.cfg.FlushInterval,
+		logger:   e.cfg.Logger.With(zap.String("sink", sink.Name())),
+		done:     make(chan struct{}),
+	}
+	e.mu.Lock()
+	e.routes = append(e.routes, route)
+	e.mu.Unlock()
+	go route.run()
+}
+
+// Export queues the record for every sink whose filter matches. It never blocks.
+func (e *Exporter) Export(record *types.AuditRecord) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		return
+	}
+
+	for _, route := range e.routes {
+		if !route.filter.Matches(record) {
+			continue
+		}
+		select {
+		case route.queue <- record:
+		default:
+			if route.dropped.Add(1) == 1 {
+				route.logger.Warn("audit sink queue full, dropping records")
+			}
+		}
+	}
+}
+
+// Stats returns the counters of every sink.
+func (e *Exporter) Stats() []ExportStats {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	stats := make([]ExportStats, 0, len(e.routes))
+	for _, route := range e.routes {
+		stats = append(stats, ExportStats{
+			Sink:     route.sink.Name(),
+			Queued:   len(route.queue),
+			Exported: route.exported.Load(),
+			Failed:   route.failed.Load(),
+			Dropped:  route.dropped.Load(),
+		})
+	}
+	return stats
+}
+
+// Close stops accepting records, sends what is queued and closes the sinks,
+// waiting until done or ctx is done.
+func (e *Exporter) Close(ctx context.Context) error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		for _, route := range e.routes {
+			close(route.queue)
+		}
+	}
+	routes := e.routes
+	e.mu.Unlock()
+
+	var errs []error
+	for _, route := range routes {
+		select {
+		case <-route.done:
+		case <-ctx.Done():
+			return fmt.Errorf("audit export drain incomplete: %w", ctx.Err())
+		}
+		if err := route.sink.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close audit sink %s: %w", route.sink.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *sinkRoute) run() {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	batch := make([]*types.AuditRecord, 0, r.batch)
+	for {
+		select {
+		case record, ok := <-r.queue:
+			if !ok {
+				r.send(batch)
+				return
+			}
+			batch = append(batch, record)
+			if len(batch) >= r.batch {
+				r.send(batch)
+				batch = make([]*types.AuditRecord, 0, r.batch)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				r.send(batch)
+				batch = make([]*types.AuditRecord, 0, r.batch)
+			}
+		}
+	}
+}
+
+// send delivers a batch. Failed batches are logged and counted, not retried:
+// the audit store remains the system of record.
+func (r *sinkRoute) send(batch []*types.AuditRecord) {
+	if len(batch) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sinkSendTimeout)
+	defer cancel()
+
+	if err := r.sink.Send(ctx, batch); err != nil {
+		r.failed.Add(int64(len(batch)))
+		r.logger.Error("failed to export audit records", zap.Error(err), zap.Int("records", len(batch)))
+		return
+	}
+	r.exported.Add(int64(len(batch)))
+}
+
+// ExportingStore passes every record the underlying store accepts to an Exporter.
+type ExportingStore struct {
+	store    Store
+	exporter *Exporter
+}
+
+// NewExportingStore wraps store so written records are exported.
+func NewExportingStore(store Store, exporter *Exporter) *ExportingStore {
+	return &ExportingStore{
+		store:    store,
+		exporter: exporter,
+	}
+}
+
+func (s *ExportingStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	auditID, err := s.store.Write(ctx, record)
+	if err != nil {
+		return auditID, err
+	}
+	s.exporter.Export(record)
+	return auditID, nil
+}
+
+func (s *ExportingStore) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
+	return s.store.Get(ctx, auditID)
+}
+
+func (s *ExportingStore) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	return s.store.List(ctx, filter)
+}
+
+func (s *ExportingStore) Ping(ctx context.Context) error {
+	return Ping(ctx, s.store)
+}
@@ -0,0 +1,131 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"invarity/internal/types"
+)
+
+// File sink rotation defaults.
+const (
+	defaultSinkMaxBytes = 100 << 20
+	defaultSinkMaxFiles = 10
+)
+
+// FileSink appends one encoded record per line to a file, rotating it to
+// path.1, path.2, ... when it would grow past maxBytes.
+type FileSink struct {
+	name     string
+	path     string
+	format   Format
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewFileSink opens (or creates) the file at path.
+func NewFileSink(name, path string, format Format, maxBytes int64, maxFiles int) (*FileSink, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultSinkMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultSinkMaxFiles
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create audit sink directory: %w", err)
+	}
+	s := &FileSink{name: name, path: path, format: format, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Name() string { return s.name }
+
+func (s *FileSink) Send(ctx context.Context, records []*types.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Reopen after a failed rotation
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range records {
+		line, err := Encode(s.format, record)
+		if err != nil {
+			return err
+		}
+		line = append(line, '\n')
+
+		if s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+			if err := s.rotate(); err != nil {
+				return err
+			}
+		}
+		n, err := s.file.Write(line)
+		s.size += int64(n)
+		if err != nil {
+			return fmt.Errorf("failed to write audit sink file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest, and starts a new file.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit sink file: %w", err)
+	}
+	s.file = nil
+
+	os.Remove(fmt.Sprintf("%s.%d", s.path, s.maxFiles))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", s.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", s.path, i+1)); err != nil {
+				return fmt.Errorf("failed to rotate audit sink file: %w", err)
+			}
+		}
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("failed to rotate audit sink file: %w", err)
+	}
+	return s.open()
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to open audit sink file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit sink file: %w", err)
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
@@ -0,0 +1,125 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"invarity/internal/types"
+)
+
+// Product identifiers used in exported events.
+const (
+	vendorName     = "Invarity"
+	productName    = "Invarity Firewall"
+	productVersion = "1.0"
+)
+
+// Format is the encoding of exported audit events.
+type Format string
+
+const (
+	FormatJSON Format = "json" // The AuditRecord as stored
+	FormatOCSF Format = "ocsf" // OCSF API Activity (class 6003)
+	FormatCEF  Format = "cef"  // ArcSight Common Event Format
+)
+
+// ParseFormat parses a format name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(s)); f {
+	case FormatJSON, FormatOCSF, FormatCEF:
+		return f, nil
+	default:
+		return "", fmt.Errorf("invalid audit export format %q: must be json, ocsf or cef", s)
+	}
+}
+
+// Encode renders a record as a single line (without trailing newline) in the format.
+func Encode(format Format, record *types.AuditRecord) ([]byte, error) {
+	switch format {
+	case FormatOCSF:
+		return json.Marshal(ToOCSF(record))
+	case FormatCEF:
+		return []byte(ToCEF(record)), nil
+	default:
+		return json.Marshal(record)
+	}
+}
+
+// ToCEF renders a record as a CEF:0 line.
+func ToCEF(record *types.AuditRecord) string {
+	ext := [][2]string{
+		{"rt", strconv.FormatInt(record.CreatedAt.UnixMilli(), 10)},
+		{"act", string(record.Decision)},
+		{"externalId", record.AuditID},
+		{"suser", record.Actor.ID},
+		{"suid", record.PrincipalID},
+		{"request", record.ToolCall.ActionID},
+		{"msg", record.UserIntent},
+		{"cs1Label", "tenant"},
+		{"cs1", recordTenant(record)},
+		{"cs2Label", "riskTier"},
+		{"cs2", string(record.RiskTier)},
+		{"cs3Label", "reasons"},
+		{"cs3", strings.Join(record.Reasons, ",")},
+		{"cs4Label", "requestId"},
+		{"cs4", record.RequestID},
+		{"cs5Label", "pipelineStep"},
+		{"cs5", record.PipelineStep},
+	}
+	if record.Sequence > 0 {
+		ext = append(ext, [2]string{"cn1Label", "sequence"}, [2]string{"cn1", strconv.FormatInt(record.Sequence, 10)})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeader(vendorName),
+		cefHeader(productName),
+		cefHeader(productVersion),
+		cefHeader("tool_call:"+strings.ToLower(string(record.Decision))),
+		cefHeader("Tool call "+string(record.Decision)+" "+record.ToolCall.ActionID),
+		cefSeverity(record),
+	)
+	first := true
+	for _, kv := range ext {
+		if kv[1] == "" {
+			continue
+		}
+		if !first {
+			b.WriteByte(' ')
+		}
+		first = false
+		b.WriteString(kv[0])
+		b.WriteByte('=')
+		b.WriteString(cefExtension(kv[1]))
+	}
+	return b.String()
+}
+
+// cefSeverity maps the risk tier to 0-10, raised to at least 7 for denials.
+func cefSeverity(record *types.AuditRecord) int {
+	severity := 0
+	switch record.RiskTier {
+	case types.RiskTierLow:
+		severity = 3
+	case types.RiskTierMedium:
+		severity = 5
+	case types.RiskTierHigh:
+		severity = 8
+	case types.RiskTierCritical:
+		severity = 10
+	}
+	if record.Decision == types.DecisionDeny && severity < 7 {
+		severity = 7
+	}
+	return severity
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+func cefHeader(s string) string    { return cefHeaderEscaper.Replace(s) }
+func cefExtension(s string) string { return cefExtensionEscaper.Replace(s) }
@@ -0,0 +1,396 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"invarity/internal/types"
+)
+
+// ErrTenantRequired is returned when an index query has no org_id to partition on.
+var ErrTenantRequired = errors.New("org_id is required to list audit records")
+
+// ErrNotFound is returned when an audit ID is not in the store.
+var ErrNotFound = errors.New("audit record not found")
+
+// ErrAlreadyExists is returned when an audit ID has already been stored.
+var ErrAlreadyExists = errors.New("audit record already exists")
+
+// ErrSequenceTaken is returned when another record already holds a chained
+// record's sequence in its tenant's chain, because another writer extended it.
+var ErrSequenceTaken = errors.New("audit chain sequence already taken")
+
+// DefaultListLimit is the number of records List returns when the filter sets no limit.
+const DefaultListLimit = 100
+
+// sortKeyTimeFormat is fixed-width so created_audit sort keys order chronologically.
+const sortKeyTimeFormat = "2006-01-02T15:04:05.000000000Z"
+
+// IndexEntry is the searchable summary of an audit record and the location of its body.
+type IndexEntry struct {
+	TenantID    string         `dynamodbav:"tenant_id"`
+	SortKey     string         `dynamodbav:"created_audit"` // created_at#audit_id
+	AuditID     string         `dynamodbav:"audit_id"`
+	CreatedAt   string         `dynamodbav:"created_at"`
+	ActorID     string         `dynamodbav:"actor_id,omitempty"`
+	PrincipalID string         `dynamodbav:"principal_id,omitempty"`
+	TenantPrin  string         `dynamodbav:"tenant_principal,omitempty"` // tenant_id#principal_id (principal-index GSI)
+	ActionID    string         `dynamodbav:"action_id"`
+	Decision    types.Decision `dynamodbav:"decision"`
+	RiskTier    types.RiskTier `dynamodbav:"risk_tier,omitempty"`
+	Reasons     []string       `dynamodbav:"reasons,omitempty"` // Reasons and their codes, for contains()
+	Intent      string         `dynamodbav:"intent,omitempty"`  // Lowercased, truncated user intent, for contains()
+	Sequence    int64          `dynamodbav:"sequence,omitempty"`
+	S3Key       string         `dynamodbav:"s3_key"`
+}
+
+// indexPointer maps an audit ID to its object so Get does not need the tenant or date.
+// Pointers share the index table under a reserved partition key prefix.
+type indexPointer struct {
+	Key     string `dynamodbav:"tenant_id"`     // audit#{audit_id}
+	SortKey string `dynamodbav:"created_audit"` // always "ref"
+	S3Key   string `dynamodbav:"s3_key"`
+}
+
+const (
+	pointerKeyPrefix = "audit#"
+	pointerSortKey   = "ref"
+)
+
+// sequenceKeyPrefix is the reserved partition key prefix of chain sequence
+// claims: tenant_id "chain#{tenant}" with a zero-padded sequence sort key and
+// the audit ID that holds it. Claims make each sequence a conditional write,
+// so writers on several replicas cannot fork a chain.
+const sequenceKeyPrefix = "chain#"
+
+// sequenceKey returns the index key of a chained record's sequence claim.
+func sequenceKey(entry *IndexEntry) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: sequenceKeyPrefix + entry.TenantID},
+		"created_audit": &ddbtypes.AttributeValueMemberS{Value: fmt.Sprintf("%020d", entry.Sequence)},
+	}
+}
+
+// Index locates audit records without listing S3.
+type Index interface {
+	// Put indexes a stored record.
+	Put(ctx context.Context, entry *IndexEntry) error
+	// Lookup returns the S3 key of an audit record, or "" if it is not indexed.
+	Lookup(ctx context.Context, auditID string) (string, error)
+	// Query returns the entries matching the filter, newest first.
+	Query(ctx context.Context, filter *ListFilter) ([]*IndexEntry, error)
+	// Delete removes an entry and its audit ID pointer.
+	Delete(ctx context.Context, entry *IndexEntry) error
+}
+
+// NewIndexEntry builds the index entry for a record stored at s3Key.
+func NewIndexEntry(record *types.AuditRecord, s3Key string) *IndexEntry {
+	tenantID := recordTenant(record)
+	createdAt := record.CreatedAt.UTC().Format(sortKeyTimeFormat)
+	entry := &IndexEntry{
+		TenantID:    tenantID,
+		SortKey:     createdAt + "#" + record.AuditID,
+		AuditID:     record.AuditID,
+		CreatedAt:   createdAt,
+		ActorID:     record.Actor.ID,
+		PrincipalID: record.PrincipalID,
+		ActionID:    record.ToolCall.ActionID,
+		Decision:    record.Decision,
+		RiskTier:    record.RiskTier,
+		Reasons:     searchableReasons(record.Reasons),
+		Intent:      indexedIntent(record.UserIntent),
+		Sequence:    record.Sequence,
+		S3Key:       s3Key,
+	}
+	if record.PrincipalID != "" {
+		entry.TenantPrin = tenantID + "#" + record.PrincipalID
+	}
+	return entry
+}
+
+// indexedIntent lowercases the intent and truncates it to maxIndexedIntent characters.
+func indexedIntent(intent string) string {
+	runes := []rune(strings.ToLower(intent))
+	if len(runes) > maxIndexedIntent {
+		runes = runes[:maxIndexedIntent]
+	}
+	return string(runes)
+}
+
+// recordTenant returns the org a record is partitioned under, falling back to tenant_id.
+func recordTenant(record *types.AuditRecord) string {
+	if record.OrgID != "" {
+		return record.OrgID
+	}
+	return record.TenantID
+}
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBIndex.
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	TransactWriteItems(ctx context.Context, params *dynamodb.TransactWriteItemsInput, optFns ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error)
+}
+
+// DynamoDBIndex indexes audit records in the audit-index table
+// (PK tenant_id, SK created_audit, GSI principal-index on tenant_principal).
+type DynamoDBIndex struct {
+	client DynamoDBAPI
+	table  string
+}
+
+// NewDynamoDBIndex creates a new DynamoDB audit index.
+func NewDynamoDBIndex(client DynamoDBAPI, table string) *DynamoDBIndex {
+	return &DynamoDBIndex{
+		client: client,
+		table:  table,
+	}
+}
+
+// Put writes the entry, its audit ID pointer and, for a chained record, its
+// sequence claim in one transaction. It returns ErrAlreadyExists if the audit
+// ID is indexed and ErrSequenceTaken if another record holds the sequence.
+func (x *DynamoDBIndex) Put(ctx context.Context, entry *IndexEntry) error {
+	item, err := attributevalue.MarshalMap(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit index entry: %w", err)
+	}
+	pointer, err := attributevalue.MarshalMap(indexPointer{
+		Key:     pointerKeyPrefix + entry.AuditID,
+		SortKey: pointerSortKey,
+		S3Key:   entry.S3Key,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit index pointer: %w", err)
+	}
+
+	items := []ddbtypes.TransactWriteItem{
+		{Put: &ddbtypes.Put{TableName: aws.String(x.table), Item: item}},
+		{Put: &ddbtypes.Put{
+			TableName:           aws.String(x.table),
+			Item:                pointer,
+			ConditionExpression: aws.String("attribute_not_exists(tenant_id)"), // Audit IDs are never reused
+		}},
+	}
+	if entry.Sequence > 0 {
+		claim := sequenceKey(entry)
+		claim["audit_id"] = &ddbtypes.AttributeValueMemberS{Value: entry.AuditID}
+		items = append(items, ddbtypes.TransactWriteItem{Put: &ddbtypes.Put{
+			TableName:           aws.String(x.table),
+			Item:                claim,
+			ConditionExpression: aws.String("attribute_not_exists(tenant_id)"),
+		}})
+	}
+
+	_, err = x.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{TransactItems: items})
+	if err != nil {
+		var canceled *ddbtypes.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			// Reasons are in item order: entry, pointer, sequence claim
+			failed := func(i int) bool {
+				return i < len(canceled.CancellationReasons) && aws.ToString(canceled.CancellationReasons[i].Code) == "ConditionalCheckFailed"
+			}
+			if failed(1) {
+				return fmt.Errorf("%w: %s", ErrAlreadyExists, entry.AuditID)
+			}
+			if failed(2) {
+				return fmt.Errorf("%w: %s sequence %d", ErrSequenceTaken, entry.TenantID, entry.Sequence)
+			}
+		}
+		return fmt.Errorf("failed to index audit record: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the entry and its audit ID pointer in one transaction. A
+// chained record's sequence claim is kept, so a writer with a stale head can
+// never reuse a purged sequence.
+func (x *DynamoDBIndex) Delete(ctx context.Context, entry *IndexEntry) error {
+	_, err := x.client.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []ddbtypes.TransactWriteItem{
+			{Delete: &ddbtypes.Delete{
+				TableName: aws.String(x.table),
+				Key: map[string]ddbtypes.AttributeValue{
+					"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: entry.TenantID},
+					"created_audit": &ddbtypes.AttributeValueMemberS{Value: entry.SortKey},
+				},
+			}},
+			{Delete: &ddbtypes.Delete{
+				TableName: aws.String(x.table),
+				Key: map[string]ddbtypes.AttributeValue{
+					"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: pointerKeyPrefix + entry.AuditID},
+					"created_audit": &ddbtypes.AttributeValueMemberS{Value: pointerSortKey},
+				},
+			}},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete audit index entry: %w", err)
+	}
+	return nil
+}
+
+func (x *DynamoDBIndex) Lookup(ctx context.Context, auditID string) (string, error) {
+	result, err := x.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(x.table),
+		Key: map[string]ddbtypes.AttributeValue{
+			"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: pointerKeyPrefix + auditID},
+			"created_audit": &ddbtypes.AttributeValueMemberS{Value: pointerSortKey},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to look up audit record: %w", err)
+	}
+	if result.Item == nil {
+		return "", nil
+	}
+
+	var pointer indexPointer
+	if err := attributevalue.UnmarshalMap(result.Item, &pointer); err != nil {
+		return "", fmt.Errorf("failed to unmarshal audit index pointer: %w", err)
+	}
+	return pointer.S3Key, nil
+}
+
+// Query pages through the tenant's partition (or the principal-index GSI when a
+// principal is given), applying the other fields as filter expressions. Intent
+// search only sees the first maxIndexedIntent characters.
+func (x *DynamoDBIndex) Query(ctx context.Context, filter *ListFilter) ([]*IndexEntry, error) {
+	if filter == nil || filter.OrgID == "" {
+		return nil, ErrTenantRequired
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	input := buildIndexQuery(x.table, filter)
+	if filter.Cursor != "" {
+		after, _, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		// The start key need not exist; the query resumes below it
+		input.ExclusiveStartKey = map[string]ddbtypes.AttributeValue{
+			"tenant_id":     &ddbtypes.AttributeValueMemberS{Value: filter.OrgID},
+			"created_audit": &ddbtypes.AttributeValueMemberS{Value: after},
+		}
+		if filter.PrincipalID != "" {
+			input.ExclusiveStartKey["tenant_principal"] = &ddbtypes.AttributeValueMemberS{Value: filter.OrgID + "#" + filter.PrincipalID}
+		}
+	}
+	entries := make([]*IndexEntry, 0, limit)
+	skipped := 0
+	for {
+		result, err := x.client.Query(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query audit index: %w", err)
+		}
+
+		var page []*IndexEntry
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal audit index entries: %w", err)
+		}
+		for _, entry := range page {
+			if skipped < filter.Offset {
+				skipped++
+				continue
+			}
+			entries = append(entries, entry)
+			if len(entries) >= limit {
+				return entries, nil
+			}
+		}
+
+		if len(result.LastEvaluatedKey) == 0 {
+			return entries, nil
+		}
+		input.ExclusiveStartKey = result.LastEvaluatedKey
+	}
+}
+
+// buildIndexQuery translates a filter into a DynamoDB query, newest first.
+func buildIndexQuery(table string, filter *ListFilter) *dynamodb.QueryInput {
+	values := map[string]ddbtypes.AttributeValue{}
+	names := map[string]string{}
+
+	// Tenant queries read their own writes, so a chain head reloaded after a
+	// sequence conflict sees the record that took it; the GSI cannot
+	input := &dynamodb.QueryInput{
+		TableName:        aws.String(table),
+		ScanIndexForward: aws.Bool(false),
+		ConsistentRead:   aws.Bool(true),
+	}
+
+	keyCond := "tenant_id = :tid"
+	values[":tid"] = &ddbtypes.AttributeValueMemberS{Value: filter.OrgID}
+	if filter.PrincipalID != "" {
+		input.ConsistentRead = nil
+		input.IndexName = aws.String("principal-index")
+		keyCond = "tenant_principal = :tid"
+		values[":tid"] = &ddbtypes.AttributeValueMemberS{Value: filter.OrgID + "#" + filter.PrincipalID}
+	}
+
+	// '$' sorts after '#', so an end bound includes every audit ID at that instant
+	switch {
+	case !filter.StartTime.IsZero() && !filter.EndTime.IsZero():
+		keyCond += " AND created_audit BETWEEN :start AND :end"
+	case !filter.StartTime.IsZero():
+		keyCond += " AND created_audit >= :start"
+	case !filter.EndTime.IsZero():
+		keyCond += " AND created_audit <= :end"
+	}
+	if !filter.StartTime.IsZero() {
+		values[":start"] = &ddbtypes.AttributeValueMemberS{Value: filter.StartTime.UTC().Format(sortKeyTimeFormat)}
+	}
+	if !filter.EndTime.IsZero() {
+		values[":end"] = &ddbtypes.AttributeValueMemberS{Value: filter.EndTime.UTC().Format(sortKeyTimeFormat) + "$"}
+	}
+	input.KeyConditionExpression = aws.String(keyCond)
+
+	conds := make([]string, 0, 6)
+	if filter.ActorID != "" {
+		conds = append(conds, "actor_id = :actor")
+		values[":actor"] = &ddbtypes.AttributeValueMemberS{Value: filter.ActorID}
+	}
+	if filter.ActionID != "" {
+		conds = append(conds, "action_id = :action")
+		values[":action"] = &ddbtypes.AttributeValueMemberS{Value: filter.ActionID}
+	}
+	if filter.Decision != "" {
+		conds = append(conds, "#decision = :decision")
+		names["#decision"] = "decision"
+		values[":decision"] = &ddbtypes.AttributeValueMemberS{Value: string(filter.Decision)}
+	}
+	if filter.RiskTier != "" {
+		conds = append(conds, "risk_tier = :risk")
+		values[":risk"] = &ddbtypes.AttributeValueMemberS{Value: string(filter.RiskTier)}
+	}
+	if filter.ReasonCode != "" {
+		conds = append(conds, "contains(#reasons, :reason)")
+		names["#reasons"] = "reasons"
+		values[":reason"] = &ddbtypes.AttributeValueMemberS{Value: filter.ReasonCode}
+	}
+	if filter.Query != "" {
+		conds = append(conds, "contains(#intent, :query)")
+		names["#intent"] = "intent"
+		values[":query"] = &ddbtypes.AttributeValueMemberS{Value: strings.ToLower(filter.Query)}
+	}
+	if len(conds) > 0 {
+		input.FilterExpression = aws.String(strings.Join(conds, " AND "))
+	}
+	if len(names) > 0 {
+		input.ExpressionAttributeNames = names
+	}
+	input.ExpressionAttributeValues = values
+
+	return input
+}
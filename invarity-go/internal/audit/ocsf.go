@@ -0,0 +1,188 @@
+package audit
+
+import (
+	"invarity/internal/types"
+)
+
+// OCSF API Activity (class 6003) identifiers. Firewall decisions are
+// authorization outcomes on a tool call, reported as activity "Other".
+const (
+	ocsfVersion          = "1.1.0"
+	ocsfCategoryUID      = 6 // Application Activity
+	ocsfClassUID         = 6003
+	ocsfActivityOther    = 99
+	ocsfStatusSuccess    = 1
+	ocsfStatusFailure    = 2
+	ocsfActionAllowed    = 1
+	ocsfActionDenied     = 2
+	ocsfActionOther      = 99
+	ocsfDispositionAllow = 1
+	ocsfDispositionBlock = 2
+	ocsfDispositionOther = 99
+)
+
+// OCSFEvent is an OCSF API Activity event for a firewall decision.
+type OCSFEvent struct {
+	CategoryUID   int    `json:"category_uid"`
+	CategoryName  string `json:"category_name"`
+	ClassUID      int    `json:"class_uid"`
+	ClassName     string `json:"class_name"`
+	ActivityID    int    `json:"activity_id"`
+	ActivityName  string `json:"activity_name"`
+	TypeUID       int    `json:"type_uid"`
+	TypeName      string `json:"type_name"`
+	Time          int64  `json:"time"` // Epoch milliseconds
+	SeverityID    int    `json:"severity_id"`
+	Severity      string `json:"severity"`
+	StatusID      int    `json:"status_id"`
+	Status        string `json:"status"`
+	StatusDetail  string `json:"status_detail,omitempty"`
+	ActionID      int    `json:"action_id"`
+	Action        string `json:"action"`
+	DispositionID int    `json:"disposition_id"`
+	Disposition   string `json:"disposition"`
+	Message       string `json:"message"`
+
+	Metadata OCSFMetadata   `json:"metadata"`
+	Actor    OCSFActor      `json:"actor"`
+	API      OCSFAPI        `json:"api"`
+	Unmapped map[string]any `json:"unmapped,omitempty"`
+}
+
+// OCSFMetadata is the OCSF metadata object.
+type OCSFMetadata struct {
+	Version        string      `json:"version"`
+	Product        OCSFProduct `json:"product"`
+	UID            string      `json:"uid"`                       // Audit ID
+	CorrelationUID string      `json:"correlation_uid,omitempty"` // Request ID
+	LogName        string      `json:"log_name"`
+	Sequence       int64       `json:"sequence,omitempty"`
+	TenantUID      string      `json:"tenant_uid,omitempty"`
+}
+
+// OCSFProduct identifies the reporting product.
+type OCSFProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version"`
+}
+
+// OCSFActor is the agent that proposed the tool call.
+type OCSFActor struct {
+	User    OCSFUser `json:"user"`
+	AppUID  string   `json:"app_uid,omitempty"` // Principal ID
+	AppName string   `json:"app_name,omitempty"`
+}
+
+// OCSFUser is the OCSF user object.
+type OCSFUser struct {
+	UID     string `json:"uid"`
+	Type    string `json:"type,omitempty"`
+	OrgUID  string `json:"org_uid,omitempty"`
+	Account string `json:"account_type,omitempty"` // Actor role
+}
+
+// OCSFAPI describes the tool call as an API operation.
+type OCSFAPI struct {
+	Operation string         `json:"operation"` // Tool action ID
+	Version   string         `json:"version,omitempty"`
+	Service   OCSFService    `json:"service"`
+	Request   OCSFAPIRequest `json:"request"`
+}
+
+// OCSFService is the OCSF service object.
+type OCSFService struct {
+	Name string `json:"name"`
+}
+
+// OCSFAPIRequest is the OCSF API request object.
+type OCSFAPIRequest struct {
+	UID string `json:"uid,omitempty"`
+}
+
+// ToOCSF maps an audit record to an OCSF API Activity event.
+func ToOCSF(record *types.AuditRecord) *OCSFEvent {
+	severityID, severity := ocsfSeverity(record.RiskTier)
+	event := &OCSFEvent{
+		CategoryUID:  ocsfCategoryUID,
+		CategoryName: "Application Activity",
+		ClassUID:     ocsfClassUID,
+		ClassName:    "API Activity",
+		ActivityID:   ocsfActivityOther,
+		ActivityName: "Tool Call Authorization",
+		TypeUID:      ocsfClassUID*100 + ocsfActivityOther,
+		TypeName:     "API Activity: Other",
+		Time:         record.CreatedAt.UnixMilli(),
+		SeverityID:   severityID,
+		Severity:     severity,
+		StatusDetail: string(record.Decision),
+		Message:      string(record.Decision) + " " + record.ToolCall.ActionID,
+		Metadata: OCSFMetadata{
+			Version:        ocsfVersion,
+			Product:        OCSFProduct{Name: productName, VendorName: vendorName, Version: productVersion},
+			UID:            record.AuditID,
+			CorrelationUID: record.RequestID,
+			LogName:        "audit",
+			Sequence:       record.Sequence,
+			TenantUID:      recordTenant(record),
+		},
+		Actor: OCSFActor{
+			User: OCSFUser{
+				UID:     record.Actor.ID,
+				Type:    record.Actor.Type,
+				OrgUID:  record.Actor.OrgID,
+				Account: record.Actor.Role,
+			},
+			AppUID: record.PrincipalID,
+		},
+		API: OCSFAPI{
+			Operation: record.ToolCall.ActionID,
+			Version:   record.ToolCall.Version,
+			Service:   OCSFService{Name: "invarity-firewall"},
+			Request:   OCSFAPIRequest{UID: record.RequestID},
+		},
+		Unmapped: map[string]any{
+			"risk_tier":     record.RiskTier,
+			"reasons":       record.Reasons,
+			"pipeline_step": record.PipelineStep,
+			"environment":   record.Environment,
+			"user_intent":   record.UserIntent,
+		},
+	}
+
+	switch record.Decision {
+	case types.DecisionAllow:
+		event.StatusID, event.Status = ocsfStatusSuccess, "Success"
+		event.ActionID, event.Action = ocsfActionAllowed, "Allowed"
+		event.DispositionID, event.Disposition = ocsfDispositionAllow, "Allowed"
+	case types.DecisionDeny:
+		event.StatusID, event.Status = ocsfStatusFailure, "Failure"
+		event.ActionID, event.Action = ocsfActionDenied, "Denied"
+		event.DispositionID, event.Disposition = ocsfDispositionBlock, "Blocked"
+	default:
+		// Escalations are held for approval: neither allowed nor blocked yet
+		event.StatusID, event.Status = ocsfStatusFailure, "Failure"
+		event.ActionID, event.Action = ocsfActionOther, "Escalated"
+		event.DispositionID, event.Disposition = ocsfDispositionOther, "Escalated"
+	}
+	if record.Hash != "" {
+		event.Unmapped["hash"] = record.Hash
+	}
+	return event
+}
+
+// ocsfSeverity maps a risk tier to an OCSF severity.
+func ocsfSeverity(tier types.RiskTier) (int, string) {
+	switch tier {
+	case types.RiskTierLow:
+		return 1, "Informational"
+	case types.RiskTierMedium:
+		return 2, "Low"
+	case types.RiskTierHigh:
+		return 3, "Medium"
+	case types.RiskTierCritical:
+		return 4, "High"
+	default:
+		return 0, "Unknown"
+	}
+}
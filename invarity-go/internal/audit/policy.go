@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultPolicyKey is the INVARITY_AUDIT_POLICIES key for tenants without their own policy.
+const DefaultPolicyKey = "*"
+
+// Policy is a tenant's audit settings: what is kept in its records and for how long.
+type Policy struct {
+	RedactSensitive bool `json:"redact_sensitive"` // Redact or hash the argument paths the tool manifest declares sensitive
+	KeepContext     bool `json:"keep_context"`     // Store bounded context and context-scan excerpts
+	RetentionDays   int  `json:"retention_days"`   // Purge records older than this; 0 keeps them forever
+	Archive         bool `json:"archive"`          // Send expired records to the archive sink before purging
+}
+
+// Expires reports whether the policy purges old records.
+func (p Policy) Expires() bool {
+	return p.RetentionDays > 0
+}
+
+// DefaultPolicy redacts declared sensitive arguments, omits context and never expires records.
+func DefaultPolicy() Policy {
+	return Policy{RedactSensitive: true}
+}
+
+// Policies holds per-tenant audit policies and the default for other tenants.
+type Policies struct {
+	fallback Policy
+
+	mu      sync.RWMutex
+	tenants map[string]Policy
+}
+
+// NewPolicies creates a policy set where every tenant uses fallback.
+func NewPolicies(fallback Policy) *Policies {
+	return &Policies{
+		fallback: fallback,
+		tenants:  make(map[string]Policy),
+	}
+}
+
+// ParsePolicies parses a JSON object of tenant ID -> policy, where the "*" entry
+// replaces DefaultPolicy. Fields missing from a tenant's entry are taken from
+// the default. Empty input gives DefaultPolicy to every tenant.
+func ParsePolicies(s string) (*Policies, error) {
+	if strings.TrimSpace(s) == "" {
+		return NewPolicies(DefaultPolicy()), nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &raw); err != nil {
+		return nil, fmt.Errorf("invalid audit policies: %w", err)
+	}
+
+	fallback := DefaultPolicy()
+	if data, ok := raw[DefaultPolicyKey]; ok {
+		if err := parsePolicy(data, &fallback); err != nil {
+			return nil, fmt.Errorf("invalid audit policy %q: %w", DefaultPolicyKey, err)
+		}
+	}
+
+	policies := NewPolicies(fallback)
+	for tenantID, data := range raw {
+		if tenantID == DefaultPolicyKey {
+			continue
+		}
+		policy := fallback
+		if err := parsePolicy(data, &policy); err != nil {
+			return nil, fmt.Errorf("invalid audit policy %q: %w", tenantID, err)
+		}
+		policies.Set(tenantID, policy)
+	}
+	return policies, nil
+}
+
+func parsePolicy(data json.RawMessage, policy *Policy) error {
+	if err := json.Unmarshal(data, policy); err != nil {
+		return err
+	}
+	if policy.RetentionDays < 0 {
+		return fmt.Errorf("retention_days must not be negative")
+	}
+	return nil
+}
+
+// Set installs a tenant's policy, replacing any previous one.
+func (p *Policies) Set(tenantID string, policy Policy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tenants[tenantID] = policy
+}
+
+// For returns the tenant's policy, or the default if it has none.
+func (p *Policies) For(tenantID string) Policy {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if policy, ok := p.tenants[tenantID]; ok {
+		return policy
+	}
+	return p.fallback
+}
@@ -0,0 +1,282 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"invarity/internal/types"
+)
+
+// PostgresStore stores audit records in the audit_records table of a Postgres
+// database opened with postgres.Open. Each record is kept verbatim as JSON
+// beside the IndexEntry fields, so List filters on indexed columns.
+type PostgresStore struct {
+	db *sql.DB
+}
+
+// NewPostgresStore creates a Postgres-backed audit store.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	if record.AuditID == "" {
+		record.AuditID = uuid.New().String()
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return record.AuditID, fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	entry := NewIndexEntry(record, "")
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_records (audit_id, tenant_id, created_audit, actor_id, principal_id, action_id, decision, risk_tier, reasons, intent, sequence, record)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		 ON CONFLICT DO NOTHING`,
+		entry.AuditID, entry.TenantID, entry.SortKey, entry.ActorID, entry.PrincipalID, entry.ActionID,
+		string(entry.Decision), string(entry.RiskTier), entry.Reasons, entry.Intent, entry.Sequence, string(data))
+	if err != nil {
+		return record.AuditID, fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return record.AuditID, s.conflict(ctx, entry)
+	}
+	return record.AuditID, nil
+}
+
+// conflict tells which unique key a skipped insert hit: the audit ID, which is
+// never reused, or the tenant's chain sequence, which another writer took.
+func (s *PostgresStore) conflict(ctx context.Context, entry *IndexEntry) error {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM audit_records WHERE audit_id = $1)`, entry.AuditID).Scan(&exists); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", ErrAlreadyExists, entry.AuditID)
+	}
+	return fmt.Errorf("%w: %s sequence %d", ErrSequenceTaken, entry.TenantID, entry.Sequence)
+}
+
+func (s *PostgresStore) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT record FROM audit_records WHERE audit_id = $1`, auditID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, auditID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit record: %w", err)
+	}
+	return unmarshalRecord(data)
+}
+
+// List returns the tenant's matching records newest first. Like the DynamoDB
+// index, it requires OrgID and intent search only sees the first
+// maxIndexedIntent characters.
+func (s *PostgresStore) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	query, args, err := buildPostgresListQuery(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit records: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*types.AuditRecord, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan audit record: %w", err)
+		}
+		record, err := unmarshalRecord(data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// buildPostgresListQuery translates a filter into a newest-first query. The
+// time bounds and cursor compare created_audit as DynamoDBIndex does.
+func buildPostgresListQuery(filter *ListFilter) (string, []any, error) {
+	if filter == nil || filter.OrgID == "" {
+		return "", nil, ErrTenantRequired
+	}
+
+	conds := []string{"tenant_id = $1"}
+	args := []any{filter.OrgID}
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if filter.PrincipalID != "" {
+		add("principal_id = ?", filter.PrincipalID)
+	}
+	if filter.ActorID != "" {
+		add("actor_id = ?", filter.ActorID)
+	}
+	if filter.ActionID != "" {
+		add("action_id = ?", filter.ActionID)
+	}
+	if filter.Decision != "" {
+		add("decision = ?", string(filter.Decision))
+	}
+	if filter.RiskTier != "" {
+		add("risk_tier = ?", string(filter.RiskTier))
+	}
+	if filter.ReasonCode != "" {
+		add("reasons @> ARRAY[?]::text[]", filter.ReasonCode)
+	}
+	if filter.Query != "" {
+		add("strpos(intent, ?) > 0", strings.ToLower(filter.Query))
+	}
+	// '$' sorts after '#', so an end bound includes every audit ID at that instant
+	if !filter.StartTime.IsZero() {
+		add("created_audit >= ?", filter.StartTime.UTC().Format(sortKeyTimeFormat))
+	}
+	if !filter.EndTime.IsZero() {
+		add("created_audit <= ?", filter.EndTime.UTC().Format(sortKeyTimeFormat)+"$")
+	}
+	if filter.Cursor != "" {
+		after, _, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		add("created_audit < ?", after)
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+	args = append(args, limit, filter.Offset)
+	query := fmt.Sprintf(`SELECT record FROM audit_records WHERE %s ORDER BY created_audit DESC LIMIT $%d OFFSET $%d`,
+		strings.Join(conds, " AND "), len(args)-1, len(args))
+	return query, args, nil
+}
+
+// Ping checks that the database is reachable.
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach audit database: %w", err)
+	}
+	return nil
+}
+
+// Tenants returns the IDs of tenants with stored records.
+func (s *PostgresStore) Tenants(ctx context.Context) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT tenant_id FROM audit_records ORDER BY tenant_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit tenants: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := make([]string, 0)
+	for rows.Next() {
+		var tenantID string
+		if err := rows.Scan(&tenantID); err != nil {
+			return nil, fmt.Errorf("failed to list audit tenants: %w", err)
+		}
+		tenants = append(tenants, tenantID)
+	}
+	return tenants, rows.Err()
+}
+
+// ListKeys lists records like List; the row holds the whole record.
+func (s *PostgresStore) ListKeys(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	return s.List(ctx, filter)
+}
+
+// Delete removes records by their audit IDs.
+func (s *PostgresStore) Delete(ctx context.Context, records []*types.AuditRecord) error {
+	ids := make([]string, len(records))
+	for i, record := range records {
+		ids[i] = record.AuditID
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM audit_records WHERE audit_id = ANY($1)`, ids); err != nil {
+		return fmt.Errorf("failed to delete audit records: %w", err)
+	}
+	return nil
+}
+
+func unmarshalRecord(data []byte) (*types.AuditRecord, error) {
+	var record types.AuditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit record: %w", err)
+	}
+	return &record, nil
+}
+
+// PostgresCheckpointStore keeps checkpoints in the audit_checkpoints table.
+type PostgresCheckpointStore struct {
+	db *sql.DB
+}
+
+// NewPostgresCheckpointStore creates a checkpoint store on a Postgres database.
+func NewPostgresCheckpointStore(db *sql.DB) *PostgresCheckpointStore {
+	return &PostgresCheckpointStore{db: db}
+}
+
+func (s *PostgresCheckpointStore) Put(ctx context.Context, cp *Checkpoint) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to marshal checkpoint: %w", err)
+	}
+	// Checkpoints are never replaced
+	if _, err := s.db.ExecContext(ctx,
+		`INSERT INTO audit_checkpoints (tenant_id, to_sequence, checkpoint) VALUES ($1, $2, $3)`,
+		cp.TenantID, cp.ToSequence, string(data)); err != nil {
+		return fmt.Errorf("failed to store checkpoint: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresCheckpointStore) List(ctx context.Context, tenantID string) ([]*Checkpoint, error) {
+	return s.query(ctx, `SELECT checkpoint FROM audit_checkpoints WHERE tenant_id = $1 ORDER BY to_sequence`, tenantID)
+}
+
+func (s *PostgresCheckpointStore) Latest(ctx context.Context, tenantID string) (*Checkpoint, error) {
+	list, err := s.query(ctx, `SELECT checkpoint FROM audit_checkpoints WHERE tenant_id = $1 ORDER BY to_sequence DESC LIMIT 1`, tenantID)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func (s *PostgresCheckpointStore) query(ctx context.Context, query, tenantID string) ([]*Checkpoint, error) {
+	rows, err := s.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := make([]*Checkpoint, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan checkpoint: %w", err)
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal checkpoint: %w", err)
+		}
+		checkpoints = append(checkpoints, &cp)
+	}
+	return checkpoints, rows.Err()
+}
@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid audit cursor")
+
+// maxIndexedIntent is the number of intent characters kept in the index for search.
+const maxIndexedIntent = 1024
+
+// SortKey returns the record's position in newest-first listings (created_at#audit_id).
+func SortKey(record *types.AuditRecord) string {
+	return record.CreatedAt.UTC().Format(sortKeyTimeFormat) + "#" + record.AuditID
+}
+
+// EncodeCursor returns an opaque cursor positioned after record.
+func EncodeCursor(record *types.AuditRecord) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(SortKey(record)))
+}
+
+// decodeCursor returns the sort key and creation time a cursor points at.
+func decodeCursor(cursor string) (string, time.Time, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidCursor
+	}
+	key := string(raw)
+	createdAt, _, ok := strings.Cut(key, "#")
+	if !ok {
+		return "", time.Time{}, ErrInvalidCursor
+	}
+	t, err := time.Parse(sortKeyTimeFormat, createdAt)
+	if err != nil {
+		return "", time.Time{}, ErrInvalidCursor
+	}
+	return key, t, nil
+}
+
+// ValidateCursor reports whether cursor was produced by EncodeCursor.
+func ValidateCursor(cursor string) error {
+	_, _, err := decodeCursor(cursor)
+	return err
+}
+
+// ReasonCode returns the code of a reason, the part before any ':' detail
+// (e.g. "threat" for "threat:prompt_injection").
+func ReasonCode(reason string) string {
+	code, _, _ := strings.Cut(reason, ":")
+	return code
+}
+
+// searchableReasons returns a record's reasons and their codes, deduplicated.
+func searchableReasons(reasons []string) []string {
+	seen := make(map[string]bool, len(reasons))
+	out := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		for _, v := range []string{reason, ReasonCode(reason)} {
+			if v != "" && !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// hasReason reports whether any reason equals code or has it as its code.
+func hasReason(reasons []string, code string) bool {
+	for _, reason := range reasons {
+		if reason == code || ReasonCode(reason) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesIntent reports whether the intent contains the query, ignoring case.
+func matchesIntent(intent, query string) bool {
+	return strings.Contains(strings.ToLower(intent), strings.ToLower(query))
+}
+
+// Since returns the tenant's records written after cursor, oldest first. An
+// empty cursor returns the newest limit records.
+func Since(ctx context.Context, store Store, tenantID, cursor string, limit int) ([]*types.AuditRecord, error) {
+	if tenantID == "" {
+		return nil, ErrTenantRequired
+	}
+	if limit <= 0 {
+		limit = DefaultListLimit
+	}
+
+	if cursor == "" {
+		records, err := store.List(ctx, &ListFilter{OrgID: tenantID, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		reverse(records)
+		return records, nil
+	}
+
+	after, start, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Page newest first back to the cursor's instant, keeping what sorts after it
+	records := make([]*types.AuditRecord, 0)
+	filter := &ListFilter{OrgID: tenantID, StartTime: start, Limit: chainPageSize}
+	for {
+		page, err := store.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range page {
+			if SortKey(record) > after {
+				records = append(records, record)
+			}
+		}
+		if len(page) < chainPageSize {
+			break
+		}
+		filter.Cursor = EncodeCursor(page[len(page)-1])
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return SortKey(records[i]) < SortKey(records[j]) })
+	return records, nil
+}
+
+func reverse(records []*types.AuditRecord) {
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+}
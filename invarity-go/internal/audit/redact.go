@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"invarity/internal/types"
+	"invarity/internal/util"
+)
+
+// RedactedValue replaces redacted argument values.
+const RedactedValue = "[REDACTED]"
+
+// Redactor applies tenants' audit policies to records before they are stored,
+// so redacted values never reach the store, the spool or export sinks.
+type Redactor struct {
+	policies *Policies
+	hashKey  []byte
+}
+
+// NewRedactor creates a redactor. Hashed values are HMAC-SHA256 under hashKey;
+// without a key they are plain SHA-256, which guessable values do not survive.
+func NewRedactor(policies *Policies, hashKey string) *Redactor {
+	return &Redactor{
+		policies: policies,
+		hashKey:  []byte(hashKey),
+	}
+}
+
+// Apply rewrites the record according to its tenant's policy. sensitive is the
+// resolved tool's SensitiveArgs; the record's args are replaced, not modified
+// in place, so the request they came from is unaffected.
+func (r *Redactor) Apply(record *types.AuditRecord, sensitive []types.SensitiveArg) error {
+	policy := r.policies.For(recordTenant(record))
+
+	if policy.RedactSensitive && len(sensitive) > 0 && len(record.ToolCall.Args) > 0 {
+		args, err := r.redactArgs(record.ToolCall.Args, sensitive)
+		if err != nil {
+			return err
+		}
+		record.ToolCall.Args = args
+	}
+
+	if !policy.KeepContext {
+		record.BoundedContext = nil
+		if record.ContextScan != nil && len(record.ContextScan.Findings) > 0 {
+			scan := *record.ContextScan
+			scan.Findings = make([]types.ContextFinding, len(record.ContextScan.Findings))
+			for i, finding := range record.ContextScan.Findings {
+				finding.Excerpt = ""
+				scan.Findings[i] = finding
+			}
+			record.ContextScan = &scan
+		}
+	}
+	return nil
+}
+
+func (r *Redactor) redactArgs(raw json.RawMessage, sensitive []types.SensitiveArg) (json.RawMessage, error) {
+	// UseNumber keeps numbers that are not redacted exactly as they were
+	var args any
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&args); err != nil {
+		// Args that are not JSON cannot be redacted by path, so none of them are kept
+		return json.RawMessage(strconv.Quote(RedactedValue)), nil
+	}
+
+	for _, arg := range sensitive {
+		replace := func(value any) (any, error) { return RedactedValue, nil }
+		if arg.Action == types.SensitiveArgHash {
+			replace = r.hash
+		}
+		var err error
+		args, err = redactPath(args, strings.Split(arg.Path, "."), replace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact %s: %w", arg.Path, err)
+		}
+	}
+
+	redacted, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal redacted args: %w", err)
+	}
+	return redacted, nil
+}
+
+// hash returns "hmac-sha256:<hex>" (or "sha256:<hex>" without a key) of the
+// value's canonical JSON.
+func (r *Redactor) hash(value any) (any, error) {
+	data, err := util.CanonicalJSON(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(r.hashKey) == 0 {
+		return "sha256:" + util.HashBytes(data), nil
+	}
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write(data)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// redactPath replaces the values at path within value. Paths that do not
+// exist are ignored.
+func redactPath(value any, path []string, replace func(any) (any, error)) (any, error) {
+	if len(path) == 0 {
+		return replace(value)
+	}
+	segment, rest := path[0], path[1:]
+
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if segment != "*" && segment != key {
+				continue
+			}
+			redacted, err := redactPath(child, rest, replace)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = redacted
+		}
+	case []any:
+		for i, child := range v {
+			if segment != "*" && segment != strconv.Itoa(i) {
+				continue
+			}
+			redacted, err := redactPath(child, rest, replace)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = redacted
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,292 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/types"
+)
+
+// DefaultRetentionInterval is how often the retention job runs.
+const DefaultRetentionInterval = time.Hour
+
+// retentionBatchSize is the number of records archived or deleted at a time.
+const retentionBatchSize = 100
+
+// RetentionStore is a Store that expired records can be purged from.
+type RetentionStore interface {
+	Store
+	// Tenants returns the IDs of tenants with stored records.
+	Tenants(ctx context.Context) ([]string, error)
+	// ListKeys lists records like List, but may return only the fields that
+	// identify them (audit ID, tenant, creation time and sequence), which is
+	// all Delete needs.
+	ListKeys(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error)
+	// Delete removes records from the store.
+	Delete(ctx context.Context, records []*types.AuditRecord) error
+}
+
+// RetentionConfig configures a Retention job.
+type RetentionConfig struct {
+	Policies    *Policies
+	Checkpoints CheckpointStore // Chained records are purged only through checkpointed windows
+	Archive     Sink            // Optional: receives expired records of tenants whose policy archives them
+	Interval    time.Duration   // Time between runs (default 1h)
+	Logger      *zap.Logger
+}
+
+// PurgeResult summarizes one tenant's retention run.
+type PurgeResult struct {
+	TenantID string `json:"tenant_id"`
+	Expired  int    `json:"expired"`  // Records older than the tenant's retention period
+	Purged   int    `json:"purged"`   // Records deleted
+	Archived int    `json:"archived"` // Records sent to the archive sink before deletion
+	// Highest sequence purged; the chain now starts after it, anchored by the
+	// checkpoint that ends there
+	PrunedThrough int64 `json:"pruned_through,omitempty"`
+}
+
+// Retention periodically purges records older than their tenant's retention
+// period, archiving them first where the policy asks for it.
+//
+// A tenant's hash chain is only cut at a checkpoint boundary with a record
+// after it, so verification can anchor the remaining chain on that signed
+// checkpoint. Expired records after the last such boundary are kept until a
+// later checkpoint covers them.
+type Retention struct {
+	store       RetentionStore
+	policies    *Policies
+	checkpoints CheckpointStore
+	archive     Sink
+	interval    time.Duration
+	logger      *zap.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewRetention creates a retention job. Call Start to run it periodically.
+func NewRetention(store RetentionStore, cfg RetentionConfig) *Retention {
+	if cfg.Policies == nil {
+		cfg.Policies = NewPolicies(DefaultPolicy())
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultRetentionInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	return &Retention{
+		store:       store,
+		policies:    cfg.Policies,
+		checkpoints: cfg.Checkpoints,
+		archive:     cfg.Archive,
+		interval:    cfg.Interval,
+		logger:      cfg.Logger,
+		done:        make(chan struct{}),
+	}
+}
+
+// Start runs Purge every interval until Close.
+func (r *Retention) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	go r.run(ctx)
+}
+
+// Close stops the job, cancelling a run in progress, and waits for it to exit.
+// The archive sink is not closed.
+func (r *Retention) Close() {
+	if r.cancel == nil {
+		return
+	}
+	r.cancel()
+	<-r.done
+}
+
+func (r *Retention) run(ctx context.Context) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		runCtx, cancel := context.WithTimeout(ctx, r.interval)
+		results, err := r.Purge(runCtx, time.Now().UTC())
+		cancel()
+		if err != nil {
+			r.logger.Error("audit retention run failed", zap.Error(err))
+		}
+		for _, result := range results {
+			if result.Expired > 0 {
+				r.logger.Info("audit retention",
+					zap.String("tenant_id", result.TenantID),
+					zap.Int("expired", result.Expired),
+					zap.Int("purged", result.Purged),
+					zap.Int("archived", result.Archived),
+				)
+			}
+		}
+	}
+}
+
+// Purge applies every tenant's retention period as of now. A failing tenant is
+// logged and does not stop the others; the first error is returned.
+func (r *Retention) Purge(ctx context.Context, now time.Time) ([]PurgeResult, error) {
+	tenants, err := r.store.Tenants(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit tenants: %w", err)
+	}
+	sort.Strings(tenants)
+
+	results := make([]PurgeResult, 0, len(tenants))
+	var firstErr error
+	for _, tenantID := range tenants {
+		policy := r.policies.For(tenantID)
+		if tenantID == "" || !policy.Expires() {
+			continue
+		}
+		result, err := r.purgeTenant(ctx, tenantID, policy, now)
+		if err != nil {
+			r.logger.Error("failed to purge expired audit records", zap.Error(err), zap.String("tenant_id", tenantID))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+		results = append(results, *result)
+	}
+	return results, firstErr
+}
+
+func (r *Retention) purgeTenant(ctx context.Context, tenantID string, policy Policy, now time.Time) (*PurgeResult, error) {
+	result := &PurgeResult{TenantID: tenantID}
+	cutoff := now.Add(-time.Duration(policy.RetentionDays) * 24 * time.Hour)
+	if policy.Archive && r.archive == nil {
+		return result, fmt.Errorf("audit policy for %s archives expired records but no archive sink is configured", tenantID)
+	}
+
+	checkpoints, err := r.listCheckpoints(ctx, tenantID)
+	if err != nil {
+		return result, err
+	}
+
+	// Count the expired records by key, noting which checkpoint ends expired and
+	// where each page starts
+	expiredEnds := make(map[int64]bool, len(checkpoints))
+	for _, cp := range checkpoints {
+		expiredEnds[cp.ToSequence] = false
+	}
+	cursors := []string{""}
+	filter := &ListFilter{OrgID: tenantID, EndTime: cutoff, Limit: chainPageSize}
+	for {
+		page, err := r.store.ListKeys(ctx, filter)
+		if err != nil {
+			return result, fmt.Errorf("failed to list expired audit records: %w", err)
+		}
+		result.Expired += len(page)
+		for _, record := range page {
+			if _, ok := expiredEnds[record.Sequence]; ok {
+				expiredEnds[record.Sequence] = true
+			}
+		}
+		if len(page) < chainPageSize {
+			break
+		}
+		filter.Cursor = EncodeCursor(page[len(page)-1])
+		cursors = append(cursors, filter.Cursor)
+	}
+	if result.Expired == 0 {
+		return result, nil
+	}
+
+	boundary, err := r.pruneBoundary(ctx, tenantID, checkpoints, expiredEnds)
+	if err != nil {
+		return result, err
+	}
+	result.PrunedThrough = boundary
+
+	// Purge the oldest page first, so a failed run never leaves a gap in the
+	// chain. Deleting a page does not move the cursors of the newer ones.
+	list := r.store.ListKeys
+	if policy.Archive {
+		list = r.store.List
+	}
+	for i := len(cursors) - 1; i >= 0; i-- {
+		page, err := list(ctx, &ListFilter{OrgID: tenantID, EndTime: cutoff, Limit: chainPageSize, Cursor: cursors[i]})
+		if err != nil {
+			return result, fmt.Errorf("failed to list expired audit records: %w", err)
+		}
+		purge := make([]*types.AuditRecord, 0, len(page))
+		for _, record := range page {
+			if record.Sequence <= boundary {
+				purge = append(purge, record)
+			}
+		}
+		sort.Slice(purge, func(i, j int) bool { return SortKey(purge[i]) < SortKey(purge[j]) })
+
+		for start := 0; start < len(purge); start += retentionBatchSize {
+			batch := purge[start:min(start+retentionBatchSize, len(purge))]
+			if policy.Archive {
+				// Records are only deleted once archived
+				if err := r.archive.Send(ctx, batch); err != nil {
+					return result, fmt.Errorf("failed to archive audit records: %w", err)
+				}
+				result.Archived += len(batch)
+			}
+			if err := r.store.Delete(ctx, batch); err != nil {
+				return result, fmt.Errorf("failed to delete audit records: %w", err)
+			}
+			result.Purged += len(batch)
+		}
+	}
+	return result, nil
+}
+
+// listCheckpoints returns the tenant's checkpoints, or none without a checkpoint store.
+func (r *Retention) listCheckpoints(ctx context.Context, tenantID string) ([]*Checkpoint, error) {
+	if r.checkpoints == nil {
+		return nil, nil
+	}
+	checkpoints, err := r.checkpoints.List(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// pruneBoundary returns the highest sequence the tenant's chain may be purged
+// through: the end of the newest checkpoint whose last record has expired and
+// is followed by another record. Unchained records (sequence 0) are always
+// within the boundary.
+func (r *Retention) pruneBoundary(ctx context.Context, tenantID string, checkpoints []*Checkpoint, expiredEnds map[int64]bool) (int64, error) {
+	if len(checkpoints) == 0 {
+		return 0, nil
+	}
+
+	// The chain's tip is among the newest records
+	newest, err := r.store.ListKeys(ctx, &ListFilter{OrgID: tenantID, Limit: chainPageSize})
+	if err != nil {
+		return 0, fmt.Errorf("failed to read audit chain head: %w", err)
+	}
+	var head int64
+	for _, record := range newest {
+		head = max(head, record.Sequence)
+	}
+
+	for i := len(checkpoints) - 1; i >= 0; i-- {
+		cp := checkpoints[i]
+		if expiredEnds[cp.ToSequence] && head > cp.ToSequence {
+			return cp.ToSequence, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,253 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/google/uuid"
+
+	"invarity/internal/types"
+)
+
+// listFetchConcurrency bounds the objects List fetches at once.
+const listFetchConcurrency = 16
+
+// S3API is the subset of the S3 client used by S3Store.
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3Store stores audit records as JSON objects in S3, with an index for lookups.
+// Key format: {prefix}/{org_id}/{yyyy}/{mm}/{dd}/{audit_id}.json
+type S3Store struct {
+	client S3API
+	bucket string
+	prefix string
+	index  Index
+}
+
+// NewS3Store creates a new S3-backed audit store.
+func NewS3Store(client S3API, bucket, prefix string, index Index) *S3Store {
+	if prefix == "" {
+		prefix = "audit"
+	}
+	return &S3Store{
+		client: client,
+		bucket: bucket,
+		prefix: prefix,
+		index:  index,
+	}
+}
+
+// ObjectKey returns the S3 key for a record.
+func (s *S3Store) ObjectKey(record *types.AuditRecord) string {
+	t := record.CreatedAt.UTC()
+	return fmt.Sprintf("%s/%s/%04d/%02d/%02d/%s.json", s.prefix, recordTenant(record), t.Year(), t.Month(), t.Day(), record.AuditID)
+}
+
+// Write stores the record in S3 and then indexes it. A record that fails to
+// index is still durable in S3 but is not visible to Get or List; writing it
+// again replaces the unindexed object. An indexed record is never overwritten:
+// Write returns ErrAlreadyExists before touching S3.
+func (s *S3Store) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	if record.AuditID == "" {
+		record.AuditID = uuid.New().String()
+	} else {
+		existing, err := s.index.Lookup(ctx, record.AuditID)
+		if err != nil {
+			return record.AuditID, err
+		}
+		if existing != "" {
+			return record.AuditID, fmt.Errorf("%w: %s", ErrAlreadyExists, record.AuditID)
+		}
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return record.AuditID, fmt.Errorf("failed to marshal audit record: %w", err)
+	}
+
+	key := s.ObjectKey(record)
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return record.AuditID, fmt.Errorf("failed to put audit record to S3: %w", err)
+	}
+
+	if err := s.index.Put(ctx, NewIndexEntry(record, key)); err != nil {
+		return record.AuditID, err
+	}
+
+	return record.AuditID, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, auditID string) (*types.AuditRecord, error) {
+	key, err := s.index.Lookup(ctx, auditID)
+	if err != nil {
+		return nil, err
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, auditID)
+	}
+	return s.getObject(ctx, key)
+}
+
+func (s *S3Store) List(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	entries, err := s.index.Query(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch the bodies concurrently, keeping the index order
+	records := make([]*types.AuditRecord, len(entries))
+	errs := make([]error, len(entries))
+	slots := make(chan struct{}, listFetchConcurrency)
+	var wg sync.WaitGroup
+	for i, entry := range entries {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			records[i], errs[i] = s.getObject(ctx, entry.S3Key)
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// ListKeys lists records from the index alone, with only their audit ID,
+// tenant, creation time and sequence. Entries indexed without a sequence,
+// unchained or from before sequences were indexed, are read from S3.
+func (s *S3Store) ListKeys(ctx context.Context, filter *ListFilter) ([]*types.AuditRecord, error) {
+	entries, err := s.index.Query(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*types.AuditRecord, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Sequence == 0 {
+			record, err := s.getObject(ctx, entry.S3Key)
+			if err != nil {
+				return nil, err
+			}
+			records = append(records, record)
+			continue
+		}
+		createdAt, err := time.Parse(sortKeyTimeFormat, entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit index entry %s: %w", entry.AuditID, err)
+		}
+		records = append(records, &types.AuditRecord{
+			AuditID:   entry.AuditID,
+			OrgID:     entry.TenantID,
+			CreatedAt: createdAt,
+			Sequence:  entry.Sequence,
+		})
+	}
+	return records, nil
+}
+
+// Ping lists at most one record key to check that the bucket is readable,
+// without writing to it.
+func (s *S3Store) Ping(ctx context.Context) error {
+	_, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(s.bucket),
+		Prefix:  aws.String(s.prefix + "/"),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reach audit bucket %s: %w", s.bucket, err)
+	}
+	return nil
+}
+
+// Tenants returns the tenants with records, from the first level of the key prefix.
+func (s *S3Store) Tenants(ctx context.Context) ([]string, error) {
+	prefix := s.prefix + "/"
+	input := &s3.ListObjectsV2Input{
+		Bucket:    aws.String(s.bucket),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+
+	tenants := make([]string, 0)
+	for {
+		result, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit tenants: %w", err)
+		}
+		for _, p := range result.CommonPrefixes {
+			tenants = append(tenants, strings.TrimSuffix(strings.TrimPrefix(aws.ToString(p.Prefix), prefix), "/"))
+		}
+		if !aws.ToBool(result.IsTruncated) {
+			return tenants, nil
+		}
+		input.ContinuationToken = result.NextContinuationToken
+	}
+}
+
+// Delete removes each record from the index and then from S3, so a record is
+// never listed without its object.
+func (s *S3Store) Delete(ctx context.Context, records []*types.AuditRecord) error {
+	for _, record := range records {
+		key := s.ObjectKey(record)
+		if err := s.index.Delete(ctx, NewIndexEntry(record, key)); err != nil {
+			return err
+		}
+		_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(s.bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete audit record from S3: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *S3Store) getObject(ctx context.Context, key string) (*types.AuditRecord, error) {
+	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit record from S3: %w", err)
+	}
+	defer result.Body.Close()
+
+	data, err := io.ReadAll(result.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit record: %w", err)
+	}
+
+	var record types.AuditRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal audit record: %w", err)
+	}
+	return &record, nil
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"invarity/internal/types"
+)
+
+// Sink delivers audit records to an external system (a SIEM, log pipeline or file).
+type Sink interface {
+	// Name identifies the sink in logs and stats.
+	Name() string
+	// Send delivers a batch of records. It is called from one goroutine per sink.
+	Send(ctx context.Context, records []*types.AuditRecord) error
+	// Close flushes and releases the sink's resources.
+	Close() error
+}
+
+// Sink types.
+const (
+	SinkTypeFile    = "file"
+	SinkTypeWebhook = "webhook"
+	SinkTypeSyslog  = "syslog"
+)
+
+// SinkConfig configures one sink and the records routed to it. It is read
+// from the INVARITY_AUDIT_SINKS JSON array.
+type SinkConfig struct {
+	Name      string           `json:"name"`
+	Type      string           `json:"type"`                 // "file", "webhook" or "syslog"
+	Format    Format           `json:"format,omitempty"`     // "json", "ocsf" or "cef" (default: json, syslog: cef)
+	Tenants   []string         `json:"tenants,omitempty"`    // Empty routes every tenant
+	Decisions []types.Decision `json:"decisions,omitempty"`  // Empty routes every decision
+	RiskTiers []types.RiskTier `json:"risk_tiers,omitempty"` // Empty routes every risk tier
+
+	// file
+	Path     string `json:"path,omitempty"`
+	MaxBytes int64  `json:"max_bytes,omitempty"` // Rotate when the file would exceed this (default 100MB)
+	MaxFiles int    `json:"max_files,omitempty"` // Rotated files kept (default 10)
+
+	// webhook
+	URL        string `json:"url,omitempty"`
+	Secret     string `json:"secret,omitempty"`      // HMAC-SHA256 key for X-Invarity-Signature
+	MaxRetries int    `json:"max_retries,omitempty"` // Retries after the first attempt (default 3)
+
+	// syslog
+	Network string `json:"network,omitempty"` // "udp", "tcp" or "tls" (default udp)
+	Address string `json:"address,omitempty"` // host:port
+	AppName string `json:"app_name,omitempty"`
+}
+
+// ParseSinkConfigs parses a JSON array of sink configs. Empty input configures no sinks.
+func ParseSinkConfigs(s string) ([]SinkConfig, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var configs []SinkConfig
+	if err := json.Unmarshal([]byte(s), &configs); err != nil {
+		return nil, fmt.Errorf("invalid audit sinks: %w", err)
+	}
+	names := make(map[string]bool, len(configs))
+	for i := range configs {
+		if err := configs[i].validate(); err != nil {
+			return nil, fmt.Errorf("invalid audit sink %d: %w", i, err)
+		}
+		if names[configs[i].Name] {
+			return nil, fmt.Errorf("invalid audit sinks: duplicate name %q", configs[i].Name)
+		}
+		names[configs[i].Name] = true
+	}
+	return configs, nil
+}
+
+// ParseSinkConfig parses a single sink config, as used for the archive sink.
+func ParseSinkConfig(s string) (*SinkConfig, error) {
+	var cfg SinkConfig
+	if err := json.Unmarshal([]byte(s), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid audit sink: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid audit sink: %w", err)
+	}
+	return &cfg, nil
+}
+
+func (c *SinkConfig) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if c.Format == "" {
+		c.Format = FormatJSON
+		if c.Type == SinkTypeSyslog {
+			c.Format = FormatCEF
+		}
+	}
+	if _, err := ParseFormat(string(c.Format)); err != nil {
+		return fmt.Errorf("%s: %w", c.Name, err)
+	}
+	for i, d := range c.Decisions {
+		c.Decisions[i] = types.Decision(strings.ToUpper(string(d)))
+	}
+	for i, t := range c.RiskTiers {
+		c.RiskTiers[i] = types.RiskTier(strings.ToUpper(string(t)))
+	}
+
+	switch c.Type {
+	case SinkTypeFile:
+		if c.Path == "" {
+			return fmt.Errorf("%s: path is required for file sinks", c.Name)
+		}
+	case SinkTypeWebhook:
+		if c.URL == "" {
+			return fmt.Errorf("%s: url is required for webhook sinks", c.Name)
+		}
+	case SinkTypeSyslog:
+		if c.Address == "" {
+			return fmt.Errorf("%s: address is required for syslog sinks", c.Name)
+		}
+		switch c.Network {
+		case "", "udp", "tcp", "tls":
+		default:
+			return fmt.Errorf("%s: network must be udp, tcp or tls", c.Name)
+		}
+	default:
+		return fmt.Errorf("%s: type must be file, webhook or syslog", c.Name)
+	}
+	return nil
+}
+
+// Filter returns the routing filter for the sink.
+func (c SinkConfig) Filter() SinkFilter {
+	return SinkFilter{Tenants: c.Tenants, Decisions: c.Decisions, RiskTiers: c.RiskTiers}
+}
+
+// NewSink creates the sink described by cfg.
+func NewSink(cfg SinkConfig) (Sink, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	switch cfg.Type {
+	case SinkTypeFile:
+		return NewFileSink(cfg.Name, cfg.Path, cfg.Format, cfg.MaxBytes, cfg.MaxFiles)
+	case SinkTypeWebhook:
+		return NewWebhookSink(cfg.Name, cfg.URL, cfg.Secret, cfg.Format, cfg.MaxRetries), nil
+	default:
+		return NewSyslogSink(cfg.Name, cfg.Network, cfg.Address, cfg.AppName, cfg.Format), nil
+	}
+}
+
+// SinkFilter selects the records routed to a sink. Empty fields match everything.
+type SinkFilter struct {
+	Tenants   []string
+	Decisions []types.Decision
+	RiskTiers []types.RiskTier
+}
+
+// Matches reports whether the record passes every non-empty field.
+func (f SinkFilter) Matches(record *types.AuditRecord) bool {
+	if len(f.Tenants) > 0 && !slices.Contains(f.Tenants, recordTenant(record)) {
+		return false
+	}
+	if len(f.Decisions) > 0 && !slices.Contains(f.Decisions, record.Decision) {
+		return false
+	}
+	if len(f.RiskTiers) > 0 && !slices.Contains(f.RiskTiers, record.RiskTier) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"invarity/internal/types"
+)
+
+// spoolFileName is the spool file within the spool directory.
+const spoolFileName = "audit-spool.jsonl"
+
+// Spool is an on-disk JSON Lines file holding audit records that could not be
+// written to the store. Appends are fsynced so spooled records survive a restart.
+type Spool struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	count int
+}
+
+// OpenSpool opens (or creates) the spool in dir. Records left by a previous
+// process are kept and drained on the next Drain.
+func OpenSpool(dir string) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit spool dir: %w", err)
+	}
+
+	s := &Spool{path: filepath.Join(dir, spoolFileName)}
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	s.count = len(records)
+
+	if err := s.openAppend(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Path returns the spool file path.
+func (s *Spool) Path() string {
+	return s.path
+}
+
+// Len returns the number of spooled records.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Append durably adds records to the end of the spool.
+func (s *Spool) Append(records []*types.AuditRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal spooled audit record: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write audit spool: %w", err)
+	}
+	if err := s.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit spool: %w", err)
+	}
+	s.count += len(records)
+	return nil
+}
+
+// Drain writes spooled records to store in order, stopping at the first failure.
+// Written records are removed from the spool; the rest stay for the next attempt.
+// It returns the number of records written.
+func (s *Spool) Drain(ctx context.Context, store Store) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.count == 0 {
+		return 0, nil
+	}
+
+	records, err := s.readAll()
+	if err != nil {
+		return 0, err
+	}
+
+	written := 0
+	var writeErr error
+	for _, record := range records {
+		// A record may already be stored if the process stopped before the spool was rewritten
+		if _, err := store.Write(ctx, record); err != nil && !errors.Is(err, ErrAlreadyExists) {
+			writeErr = err
+			break
+		}
+		written++
+	}
+
+	if written > 0 || len(records) != s.count {
+		if err := s.rewrite(records[written:]); err != nil {
+			return written, err
+		}
+	}
+	return written, writeErr
+}
+
+// Close closes the spool file.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// readAll reads every record in the spool. A line that does not parse (a write
+// torn by a crash) is skipped.
+func (s *Spool) readAll() ([]*types.AuditRecord, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit spool: %w", err)
+	}
+
+	records := make([]*types.AuditRecord, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var record types.AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan audit spool: %w", err)
+	}
+	return records, nil
+}
+
+// rewrite atomically replaces the spool with the given records.
+func (s *Spool) rewrite(records []*types.AuditRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal spooled audit record: %w", err)
+		}
+	}
+
+	tmp := s.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite audit spool: %w", err)
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to rewrite audit spool: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync audit spool: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite audit spool: %w", err)
+	}
+
+	s.file.Close()
+	if err := os.Rename(tmp, s.path); err != nil {
+		if reopenErr := s.openAppend(); reopenErr != nil {
+			return reopenErr
+		}
+		return fmt.Errorf("failed to replace audit spool: %w", err)
+	}
+	s.count = len(records)
+	return s.openAppend()
+}
+
+func (s *Spool) openAppend() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit spool: %w", err)
+	}
+	s.file = f
+	return nil
+}
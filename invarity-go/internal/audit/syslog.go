@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// syslogFacility is "log audit" (13).
+const syslogFacility = 13
+
+const syslogDialTimeout = 5 * time.Second
+
+// SyslogSink sends each record as an RFC 5424 message whose body is the encoded
+// record (CEF by default). TCP and TLS use octet-counted framing (RFC 6587).
+// The connection is opened on first use and reopened after a write error.
+type SyslogSink struct {
+	name     string
+	network  string
+	address  string
+	appName  string
+	format   Format
+	hostname string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates a syslog sink. network is "udp" (default), "tcp" or "tls".
+func NewSyslogSink(name, network, address, appName string, format Format) *SyslogSink {
+	if network == "" {
+		network = "udp"
+	}
+	if appName == "" {
+		appName = "invarity"
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		name:     name,
+		network:  network,
+		address:  address,
+		appName:  appName,
+		format:   format,
+		hostname: hostname,
+	}
+}
+
+func (s *SyslogSink) Name() string { return s.name }
+
+func (s *SyslogSink) Send(ctx context.Context, records []*types.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, record := range records {
+		body, err := Encode(s.format, record)
+		if err != nil {
+			return err
+		}
+		msg := s.message(record, body)
+		if s.network != "udp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+
+		// Retry once on a new connection: a stale TCP connection fails on first write
+		if err = s.write(ctx, msg); err != nil {
+			err = s.write(ctx, msg)
+		}
+		if err != nil {
+			return fmt.Errorf("syslog %s: %w", s.name, err)
+		}
+	}
+	return nil
+}
+
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// message formats an RFC 5424 message: <PRI>1 TIMESTAMP HOST APP PROCID MSGID SD MSG.
+func (s *SyslogSink) message(record *types.AuditRecord, body []byte) []byte {
+	pri := syslogFacility*8 + syslogSeverity(record.Decision)
+	header := fmt.Sprintf("<%d>1 %s %s %s %d audit - ",
+		pri,
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+		s.hostname,
+		s.appName,
+		os.Getpid(),
+	)
+	return append([]byte(header), body...)
+}
+
+func (s *SyslogSink) write(ctx context.Context, msg []byte) error {
+	if s.conn == nil {
+		conn, err := s.dial(ctx)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetWriteDeadline(deadline)
+	}
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("write failed: %w", err)
+	}
+	return nil
+}
+
+func (s *SyslogSink) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: syslogDialTimeout}
+	if s.network == "tls" {
+		conn, err := (&tls.Dialer{NetDialer: dialer}).DialContext(ctx, "tcp", s.address)
+		if err != nil {
+			return nil, fmt.Errorf("dial failed: %w", err)
+		}
+		return conn, nil
+	}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %w", err)
+	}
+	return conn, nil
+}
+
+// syslogSeverity maps a decision to a syslog severity.
+func syslogSeverity(decision types.Decision) int {
+	switch decision {
+	case types.DecisionDeny:
+		return 4 // warning
+	case types.DecisionEscalate:
+		return 5 // notice
+	default:
+		return 6 // informational
+	}
+}
@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// Issue types reported by Verify.
+const (
+	IssueGap                = "gap"                 // Sequence numbers are missing (records deleted)
+	IssueDuplicate          = "duplicate_sequence"  // Two records share a sequence number
+	IssueHashMismatch       = "hash_mismatch"       // The record does not match its hash (record altered)
+	IssueChainBreak         = "chain_break"         // prev_hash does not match the previous record's hash
+	IssueCheckpointMismatch = "checkpoint_mismatch" // Records in a checkpoint window do not match its Merkle root
+	IssueBadSignature       = "bad_signature"       // A checkpoint is unsigned or its signature does not verify
+)
+
+// Issue is a single verification failure.
+type Issue struct {
+	Type     string `json:"type"`
+	Sequence int64  `json:"sequence,omitempty"`
+	AuditID  string `json:"audit_id,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// VerifyReport is the result of walking a tenant's audit chain.
+type VerifyReport struct {
+	TenantID      string    `json:"tenant_id"`
+	Valid         bool      `json:"valid"`
+	Records       int       `json:"records"`   // Chained records checked
+	Unchained     int       `json:"unchained"` // Records written before chaining, not verifiable
+	FirstSequence int64     `json:"first_sequence,omitempty"`
+	LastSequence  int64     `json:"last_sequence,omitempty"`
+	PrunedThrough int64     `json:"pruned_through,omitempty"` // Sequences up to this were purged by retention
+	Checkpoints   int       `json:"checkpoints"`
+	Issues        []Issue   `json:"issues"`
+	VerifiedAt    time.Time `json:"verified_at"`
+}
+
+// Verifier checks audit chains against their records and checkpoints.
+type Verifier struct {
+	store       Store
+	checkpoints CheckpointStore
+	publicKey   ed25519.PublicKey
+}
+
+// NewVerifier creates a verifier. checkpoints and publicKey are optional; without
+// a public key checkpoint signatures are not checked.
+func NewVerifier(store Store, checkpoints CheckpointStore, publicKey ed25519.PublicKey) *Verifier {
+	return &Verifier{
+		store:       store,
+		checkpoints: checkpoints,
+		publicKey:   publicKey,
+	}
+}
+
+// PublicKey returns the base64 checkpoint verification key, or "" if signatures are not checked.
+func (v *Verifier) PublicKey() string {
+	if v.publicKey == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(v.publicKey)
+}
+
+// Checkpoints returns the tenant's checkpoints ordered by sequence.
+func (v *Verifier) Checkpoints(ctx context.Context, tenantID string) ([]*Checkpoint, error) {
+	if v.checkpoints == nil {
+		return []*Checkpoint{}, nil
+	}
+	return v.checkpoints.List(ctx, tenantID)
+}
+
+// Verify walks the tenant's chain in sequence order, reporting gaps, altered
+// records, broken links and checkpoints whose roots or signatures do not match.
+func (v *Verifier) Verify(ctx context.Context, tenantID string) (*VerifyReport, error) {
+	if tenantID == "" {
+		return nil, ErrTenantRequired
+	}
+
+	records, unchained, err := v.chainRecords(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	checkpoints, err := v.Checkpoints(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &VerifyReport{
+		TenantID:    tenantID,
+		Records:     len(records),
+		Unchained:   unchained,
+		Checkpoints: len(checkpoints),
+		Issues:      make([]Issue, 0),
+		VerifiedAt:  time.Now().UTC(),
+	}
+	if len(records) > 0 {
+		report.FirstSequence = records[0].Sequence
+		report.LastSequence = records[len(records)-1].Sequence
+		report.PrunedThrough = prunedThrough(records[0], checkpoints)
+	}
+
+	// Recomputed hashes by sequence, for checkpoint roots
+	hashes := make(map[int64]string, len(records))
+	var prev *types.AuditRecord
+	for _, record := range records {
+		expected := report.PrunedThrough + 1
+		if prev != nil {
+			expected = prev.Sequence + 1
+		}
+
+		switch {
+		case prev != nil && record.Sequence == prev.Sequence:
+			report.addIssue(IssueDuplicate, record, fmt.Sprintf("sequence %d is also used by audit %s", record.Sequence, prev.AuditID))
+		case record.Sequence > expected:
+			report.Issues = append(report.Issues, Issue{
+				Type:     IssueGap,
+				Sequence: expected,
+				Detail:   fmt.Sprintf("sequences %d-%d are missing", expected, record.Sequence-1),
+			})
+		}
+
+		hash, err := RecordHash(record)
+		if err != nil {
+			return nil, err
+		}
+		if hash != record.Hash {
+			report.addIssue(IssueHashMismatch, record, "record content does not match its hash")
+		}
+		if _, seen := hashes[record.Sequence]; !seen {
+			hashes[record.Sequence] = hash
+		}
+
+		switch {
+		case record.Sequence == 1 && record.PrevHash != "":
+			report.addIssue(IssueChainBreak, record, "first record has a prev_hash")
+		case prev != nil && record.Sequence == prev.Sequence+1 && record.PrevHash != prev.Hash:
+			report.addIssue(IssueChainBreak, record, "prev_hash does not match the previous record")
+		}
+		prev = record
+	}
+
+	for _, cp := range checkpoints {
+		if cp.ToSequence <= report.PrunedThrough {
+			continue
+		}
+		v.verifyCheckpoint(report, cp, hashes)
+	}
+
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+func (v *Verifier) verifyCheckpoint(report *VerifyReport, cp *Checkpoint, hashes map[int64]string) {
+	window := fmt.Sprintf("checkpoint %d-%d", cp.FromSequence, cp.ToSequence)
+
+	if v.publicKey != nil && !VerifyCheckpointSignature(cp, v.publicKey) {
+		report.Issues = append(report.Issues, Issue{Type: IssueBadSignature, Sequence: cp.ToSequence, Detail: window + " signature does not verify"})
+	}
+
+	leaves := make([]string, 0, cp.ToSequence-cp.FromSequence+1)
+	for seq := cp.FromSequence; seq <= cp.ToSequence; seq++ {
+		hash, ok := hashes[seq]
+		if !ok {
+			// Reported here as well so a truncated tail is caught
+			if seq > report.LastSequence {
+				report.Issues = append(report.Issues, Issue{
+					Type:     IssueGap,
+					Sequence: seq,
+					Detail:   fmt.Sprintf("sequences %d-%d covered by %s are missing", seq, cp.ToSequence, window),
+				})
+			}
+			return
+		}
+		leaves = append(leaves, hash)
+	}
+
+	root, err := MerkleRoot(leaves)
+	if err != nil || root != cp.MerkleRoot {
+		report.Issues = append(report.Issues, Issue{Type: IssueCheckpointMismatch, Sequence: cp.ToSequence, Detail: window + " Merkle root does not match its records"})
+	}
+}
+
+// prunedThrough returns the sequence retention purged the chain through, or 0.
+// A purged prefix is accepted only where a checkpoint ends immediately before
+// the first remaining record and its last hash is that record's prev_hash, so
+// the remaining chain is anchored on the checkpoint's signature.
+func prunedThrough(first *types.AuditRecord, checkpoints []*Checkpoint) int64 {
+	if first.Sequence <= 1 {
+		return 0
+	}
+	for _, cp := range checkpoints {
+		if cp.ToSequence == first.Sequence-1 && cp.LastHash == first.PrevHash {
+			return cp.ToSequence
+		}
+	}
+	return 0
+}
+
+// chainRecords reads all of a tenant's chained records in sequence order.
+func (v *Verifier) chainRecords(ctx context.Context, tenantID string) ([]*types.AuditRecord, int, error) {
+	records := make([]*types.AuditRecord, 0)
+	unchained := 0
+	for offset := 0; ; offset += chainPageSize {
+		page, err := v.store.List(ctx, &ListFilter{OrgID: tenantID, Limit: chainPageSize, Offset: offset})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to list audit records: %w", err)
+		}
+		for _, record := range page {
+			if record.Sequence == 0 {
+				unchained++
+				continue
+			}
+			records = append(records, record)
+		}
+		if len(page) < chainPageSize {
+			break
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Sequence < records[j].Sequence })
+	return records, unchained, nil
+}
+
+func (r *VerifyReport) addIssue(issueType string, record *types.AuditRecord, detail string) {
+	r.Issues = append(r.Issues, Issue{
+		Type:     issueType,
+		Sequence: record.Sequence,
+		AuditID:  record.AuditID,
+		Detail:   detail,
+	})
+}
@@ -0,0 +1,153 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// Webhook signature headers. The signature is the hex HMAC-SHA256 of
+// "{timestamp}.{body}" so receivers can reject replays of old deliveries.
+const (
+	WebhookTimestampHeader = "X-Invarity-Timestamp"
+	WebhookSignatureHeader = "X-Invarity-Signature"
+)
+
+// Webhook delivery defaults.
+const (
+	defaultWebhookRetries = 3
+	webhookTimeout        = 10 * time.Second
+	webhookBackoff        = 500 * time.Millisecond
+)
+
+// WebhookSink POSTs batches of records to a URL. JSON and OCSF batches are sent
+// as a JSON array; CEF batches as newline-separated text. Network errors, 429
+// and 5xx responses are retried with exponential backoff.
+type WebhookSink struct {
+	name       string
+	url        string
+	secret     []byte
+	format     Format
+	maxRetries int
+	backoff    time.Duration
+	client     *http.Client
+}
+
+// NewWebhookSink creates a webhook sink. Deliveries are unsigned without a secret.
+func NewWebhookSink(name, url, secret string, format Format, maxRetries int) *WebhookSink {
+	if maxRetries <= 0 {
+		maxRetries = defaultWebhookRetries
+	}
+	return &WebhookSink{
+		name:       name,
+		url:        url,
+		secret:     []byte(secret),
+		format:     format,
+		maxRetries: maxRetries,
+		backoff:    webhookBackoff,
+		client:     &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// SignWebhook returns the signature header value for a delivery.
+func SignWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookSink) Name() string { return s.name }
+
+func (s *WebhookSink) Send(ctx context.Context, records []*types.AuditRecord) error {
+	body, contentType, err := s.encode(records)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := s.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.maxRetries {
+			return fmt.Errorf("webhook %s: %w", s.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook %s: %w", s.name, ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (s *WebhookSink) Close() error { return nil }
+
+// post makes one delivery attempt and reports whether a failure is retryable.
+func (s *WebhookSink) post(ctx context.Context, body []byte, contentType string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "invarity-audit-webhook")
+	if len(s.secret) > 0 {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(WebhookTimestampHeader, timestamp)
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("server returned status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("server returned status %d", resp.StatusCode)
+	}
+}
+
+func (s *WebhookSink) encode(records []*types.AuditRecord) ([]byte, string, error) {
+	if s.format == FormatCEF {
+		var buf bytes.Buffer
+		for _, record := range records {
+			buf.WriteString(ToCEF(record))
+			buf.WriteByte('\n')
+		}
+		return buf.Bytes(), "text/plain; charset=utf-8", nil
+	}
+
+	events := make([]json.RawMessage, 0, len(records))
+	for _, record := range records {
+		event, err := Encode(s.format, record)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, event)
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal webhook batch: %w", err)
+	}
+	return body, "application/json", nil
+}
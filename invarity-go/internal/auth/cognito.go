@@ -42,6 +42,11 @@ func NewCognitoVerifier(cfg CognitoConfig) *CognitoVerifier {
 	}
 }
 
+// Ping checks that the user pool's signing keys can be fetched.
+func (v *CognitoVerifier) Ping(ctx context.Context) error {
+	return v.jwksCache.Ping(ctx)
+}
+
 // JWTClaims represents the claims in a Cognito JWT.
 type JWTClaims struct {
 	Sub       string `json:"sub"`
@@ -18,6 +18,22 @@ type AuthContext struct {
 	ActorType ActorType
 	UserID    string // Cognito sub claim
 	Email     string // From Cognito email claim (if present)
+
+	// Set for API tokens, which are bound to a tenant and optionally a principal
+	TokenID     string
+	TenantID    string
+	PrincipalID string
+	Scopes      []Scope
+}
+
+// HasScope reports whether a token-authenticated actor holds scope.
+func (a *AuthContext) HasScope(scope Scope) bool {
+	for _, s := range a.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
 }
 
 // contextKey is a private type for context keys to avoid collisions.
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"crypto/rsa"
 	"encoding/base64"
 	"encoding/json"
@@ -83,22 +84,51 @@ func (c *JWKSCache) refresh() error {
 		return nil
 	}
 
-	resp, err := c.client.Get(c.jwksURL)
+	newKeys, err := c.fetch(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to fetch JWKS: %w", err)
+		return err
+	}
+
+	c.keys = newKeys
+	c.expiresAt = time.Now().Add(c.ttl)
+	return nil
+}
+
+// Ping fetches the key set, without replacing the cached keys, and checks that
+// it contains a signing key.
+func (c *JWKSCache) Ping(ctx context.Context) error {
+	keys, err := c.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("JWKS has no RSA signing keys")
+	}
+	return nil
+}
+
+// fetch downloads the key set and returns its RSA signing keys by key ID.
+func (c *JWKSCache) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.jwksURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("JWKS fetch returned status %d", resp.StatusCode)
+		return nil, fmt.Errorf("JWKS fetch returned status %d", resp.StatusCode)
 	}
 
 	var jwks JWKS
 	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
-		return fmt.Errorf("failed to decode JWKS: %w", err)
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
 	}
 
-	newKeys := make(map[string]*rsa.PublicKey)
+	keys := make(map[string]*rsa.PublicKey)
 	for _, jwk := range jwks.Keys {
 		if jwk.Kty != "RSA" || jwk.Use != "sig" {
 			continue
@@ -108,12 +138,9 @@ func (c *JWKSCache) refresh() error {
 		if err != nil {
 			continue // Skip invalid keys
 		}
-		newKeys[jwk.Kid] = key
+		keys[jwk.Kid] = key
 	}
-
-	c.keys = newKeys
-	c.expiresAt = time.Now().Add(c.ttl)
-	return nil
+	return keys, nil
 }
 
 func jwkToRSAPublicKey(jwk JWK) (*rsa.PublicKey, error) {
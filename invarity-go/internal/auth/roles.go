@@ -49,6 +49,15 @@ const (
 
 	// Audit scopes
 	ScopeAuditRead Scope = "audit:read"
+
+	// Replay scopes (re-evaluating audited traffic against a candidate)
+	ScopeReplayRun Scope = "replay:run"
+
+	// Usage and billing scopes
+	ScopeUsageRead Scope = "usage:read"
+
+	// Data plane scopes, held by API tokens rather than roles
+	ScopeEvaluate Scope = "evaluate"
 )
 
 // RoleScopes maps roles to their allowed scopes.
@@ -61,6 +70,8 @@ var RoleScopes = map[Role][]Scope{
 		ScopeToolsetsRead, ScopeToolsetsWrite,
 		ScopeMembersRead, ScopeMembersWrite,
 		ScopeAuditRead,
+		ScopeReplayRun,
+		ScopeUsageRead,
 	},
 	RoleAdmin: {
 		ScopeTenantRead, ScopeTenantWrite,
@@ -70,6 +81,8 @@ var RoleScopes = map[Role][]Scope{
 		ScopeToolsetsRead, ScopeToolsetsWrite,
 		ScopeMembersRead, ScopeMembersWrite,
 		ScopeAuditRead,
+		ScopeReplayRun,
+		ScopeUsageRead,
 	},
 	RoleDeveloper: {
 		ScopeTenantRead,
@@ -78,6 +91,7 @@ var RoleScopes = map[Role][]Scope{
 		ScopeToolsRead, ScopeToolsWrite,
 		ScopeToolsetsRead, ScopeToolsetsWrite,
 		ScopeAuditRead,
+		ScopeReplayRun,
 	},
 	RoleViewer: {
 		ScopeTenantRead,
@@ -102,6 +116,11 @@ func (r Role) HasScope(scope Scope) bool {
 	return false
 }
 
+// ValidScope reports whether scope is one a role or API token can hold.
+func ValidScope(scope Scope) bool {
+	return scope == ScopeEvaluate || RoleOwner.HasScope(scope)
+}
+
 // GetScopes returns all scopes for a role.
 func (r Role) GetScopes() []Scope {
 	return RoleScopes[r]
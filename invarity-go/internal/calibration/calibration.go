@@ -0,0 +1,331 @@
+// Package calibration maps raw voter and sentinel confidences to calibrated
+// probabilities, fitted from human-labeled audit outcomes.
+package calibration
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"invarity/internal/audit"
+)
+
+// Calibration methods.
+const (
+	MethodIsotonic = "isotonic" // Pool-adjacent-violators isotonic regression
+	MethodPlatt    = "platt"    // Logistic (Platt) scaling
+)
+
+// DefaultMinSamples is the number of labeled samples a voter needs before its curve is used.
+const DefaultMinSamples = 30
+
+// reliabilityBins is the number of equal-width confidence bins in reliability reports.
+const reliabilityBins = 10
+
+// curve maps a raw confidence to a calibrated probability.
+type curve interface {
+	predict(confidence float64) float64
+}
+
+// Calibrator holds fitted calibration curves keyed by voter and model.
+// Voters without a fitted curve pass their raw confidence through unchanged.
+type Calibrator struct {
+	mu         sync.RWMutex
+	method     string
+	minSamples int
+	curves     map[string]curve
+	report     *ReliabilityReport
+}
+
+// NewCalibrator creates a calibrator using the given method.
+// minSamples <= 0 uses DefaultMinSamples.
+func NewCalibrator(method string, minSamples int) (*Calibrator, error) {
+	if method == "" {
+		method = MethodIsotonic
+	}
+	if method != MethodIsotonic && method != MethodPlatt {
+		return nil, fmt.Errorf("unknown calibration method: %s", method)
+	}
+	if minSamples <= 0 {
+		minSamples = DefaultMinSamples
+	}
+	return &Calibrator{
+		method:     method,
+		minSamples: minSamples,
+		curves:     make(map[string]curve),
+		report: &ReliabilityReport{
+			Method:     method,
+			MinSamples: minSamples,
+			Voters:     []ReliabilityStats{},
+		},
+	}, nil
+}
+
+// Calibrate returns the calibrated probability that a vote with the given raw
+// confidence is correct, and whether a fitted curve was applied.
+func (c *Calibrator) Calibrate(voterID, model string, confidence float64) (float64, bool) {
+	confidence = clamp01(confidence)
+	if c == nil {
+		return confidence, false
+	}
+	c.mu.RLock()
+	fitted, ok := c.curves[sampleKey(voterID, model)]
+	c.mu.RUnlock()
+	if !ok {
+		return confidence, false
+	}
+	return clamp01(fitted.predict(confidence)), true
+}
+
+// Fit replaces all curves with ones fitted from samples.
+// Voters with fewer than the minimum number of samples are reported but not calibrated.
+func (c *Calibrator) Fit(samples []Sample, labels int) {
+	groups := make(map[string][]Sample)
+	for _, s := range samples {
+		k := sampleKey(s.VoterID, s.Model)
+		groups[k] = append(groups[k], s)
+	}
+
+	curves := make(map[string]curve)
+	stats := make([]ReliabilityStats, 0, len(groups))
+	for k, group := range groups {
+		var fitted curve
+		if len(group) >= c.minSamples {
+			if c.method == MethodPlatt {
+				fitted = fitPlatt(group)
+			} else {
+				fitted = fitIsotonic(group)
+			}
+			curves[k] = fitted
+		}
+		stats = append(stats, reliability(group, fitted))
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].VoterID != stats[j].VoterID {
+			return stats[i].VoterID < stats[j].VoterID
+		}
+		return stats[i].Model < stats[j].Model
+	})
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.curves = curves
+	c.report = &ReliabilityReport{
+		Method:     c.method,
+		MinSamples: c.minSamples,
+		Labels:     labels,
+		Samples:    len(samples),
+		FittedAt:   time.Now().UTC(),
+		Voters:     stats,
+	}
+}
+
+// Refit refits all curves from the labeled audit records.
+// Labels whose audit record cannot be found are skipped.
+func (c *Calibrator) Refit(ctx context.Context, auditStore audit.Store, labelStore LabelStore) (*ReliabilityReport, error) {
+	labels, err := labelStore.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calibration labels: %w", err)
+	}
+
+	samples := make([]Sample, 0)
+	used := 0
+	for _, label := range labels {
+		record, err := auditStore.Get(ctx, label.AuditID)
+		if err != nil || record == nil {
+			continue
+		}
+		samples = append(samples, SamplesFromRecord(record, label.Outcome)...)
+		used++
+	}
+
+	c.Fit(samples, used)
+	return c.Reliability(), nil
+}
+
+// Reliability returns the reliability report from the most recent fit.
+func (c *Calibrator) Reliability() *ReliabilityReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.report
+}
+
+func sampleKey(voterID, model string) string {
+	return voterID + "|" + model
+}
+
+func clamp01(x float64) float64 {
+	if math.IsNaN(x) || x < 0 {
+		return 0
+	}
+	if x > 1 {
+		return 1
+	}
+	return x
+}
+
+// plattCurve is p = 1 / (1 + exp(a*x + b)).
+type plattCurve struct {
+	a, b float64
+}
+
+func (p plattCurve) predict(x float64) float64 {
+	return 1 / (1 + math.Exp(p.a*x+p.b))
+}
+
+// fitPlatt fits a Platt sigmoid by Newton's method with backtracking, using
+// Platt's smoothed targets to avoid overfitting small samples (Lin, Lin & Weng, 2007).
+func fitPlatt(samples []Sample) plattCurve {
+	var pos, neg float64
+	for _, s := range samples {
+		if s.Correct {
+			pos++
+		} else {
+			neg++
+		}
+	}
+	hi := (pos + 1) / (pos + 2)
+	lo := 1 / (neg + 2)
+
+	x := make([]float64, len(samples))
+	t := make([]float64, len(samples))
+	for i, s := range samples {
+		x[i] = clamp01(s.Confidence)
+		if s.Correct {
+			t[i] = hi
+		} else {
+			t[i] = lo
+		}
+	}
+
+	const (
+		maxIter = 100
+		minStep = 1e-10
+		sigma   = 1e-12
+		eps     = 1e-5
+	)
+
+	a, b := 0.0, math.Log((neg+1)/(pos+1))
+	objective := func(a, b float64) float64 {
+		var f float64
+		for i := range x {
+			fApB := x[i]*a + b
+			if fApB >= 0 {
+				f += t[i]*fApB + math.Log1p(math.Exp(-fApB))
+			} else {
+				f += (t[i]-1)*fApB + math.Log1p(math.Exp(fApB))
+			}
+		}
+		return f
+	}
+	fval := objective(a, b)
+
+	for iter := 0; iter < maxIter; iter++ {
+		h11, h22, h21, g1, g2 := sigma, sigma, 0.0, 0.0, 0.0
+		for i := range x {
+			fApB := x[i]*a + b
+			var p, q float64
+			if fApB >= 0 {
+				p = math.Exp(-fApB) / (1 + math.Exp(-fApB))
+				q = 1 / (1 + math.Exp(-fApB))
+			} else {
+				p = 1 / (1 + math.Exp(fApB))
+				q = math.Exp(fApB) / (1 + math.Exp(fApB))
+			}
+			d2 := p * q
+			h11 += x[i] * x[i] * d2
+			h22 += d2
+			h21 += x[i] * d2
+			d1 := t[i] - p
+			g1 += x[i] * d1
+			g2 += d1
+		}
+		if math.Abs(g1) < eps && math.Abs(g2) < eps {
+			break
+		}
+
+		det := h11*h22 - h21*h21
+		dA := -(h22*g1 - h21*g2) / det
+		dB := -(-h21*g1 + h11*g2) / det
+		gd := g1*dA + g2*dB
+
+		step := 1.0
+		for step >= minStep {
+			newA, newB := a+step*dA, b+step*dB
+			newF := objective(newA, newB)
+			if newF < fval+0.0001*step*gd {
+				a, b, fval = newA, newB, newF
+				break
+			}
+			step /= 2
+		}
+		if step < minStep {
+			break
+		}
+	}
+
+	return plattCurve{a: a, b: b}
+}
+
+// isotonicCurve is a monotone piecewise-linear curve through block means.
+type isotonicCurve struct {
+	x, y []float64
+}
+
+func (c isotonicCurve) predict(v float64) float64 {
+	n := len(c.x)
+	if n == 0 {
+		return v
+	}
+	if v <= c.x[0] {
+		return c.y[0]
+	}
+	if v >= c.x[n-1] {
+		return c.y[n-1]
+	}
+	i := sort.SearchFloat64s(c.x, v)
+	x0, x1 := c.x[i-1], c.x[i]
+	y0, y1 := c.y[i-1], c.y[i]
+	if x1 == x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(v-x0)/(x1-x0)
+}
+
+// fitIsotonic fits a non-decreasing curve with the pool-adjacent-violators algorithm.
+func fitIsotonic(samples []Sample) isotonicCurve {
+	sorted := make([]Sample, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Confidence < sorted[j].Confidence })
+
+	type block struct {
+		sumX, sumY, n float64
+	}
+	blocks := make([]block, 0, len(sorted))
+	for _, s := range sorted {
+		y := 0.0
+		if s.Correct {
+			y = 1
+		}
+		blocks = append(blocks, block{sumX: clamp01(s.Confidence), sumY: y, n: 1})
+		// Merge while the last two blocks violate monotonicity (or share a confidence)
+		for len(blocks) > 1 {
+			last, prev := blocks[len(blocks)-1], blocks[len(blocks)-2]
+			if prev.sumY/prev.n < last.sumY/last.n && prev.sumX/prev.n < last.sumX/last.n {
+				break
+			}
+			blocks = blocks[:len(blocks)-2]
+			blocks = append(blocks, block{sumX: prev.sumX + last.sumX, sumY: prev.sumY + last.sumY, n: prev.n + last.n})
+		}
+	}
+
+	c := isotonicCurve{x: make([]float64, len(blocks)), y: make([]float64, len(blocks))}
+	for i, b := range blocks {
+		c.x[i] = b.sumX / b.n
+		c.y[i] = b.sumY / b.n
+	}
+	return c
+}
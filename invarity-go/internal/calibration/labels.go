@@ -0,0 +1,220 @@
+package calibration
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// ThreatSentinelID is the voter ID under which threat sentinel samples are calibrated.
+const ThreatSentinelID = "threat_sentinel"
+
+// Outcome is the ground-truth label for an audited tool call.
+type Outcome string
+
+const (
+	OutcomeSafe   Outcome = "SAFE"   // The call was legitimate and should have been allowed
+	OutcomeUnsafe Outcome = "UNSAFE" // The call should have been blocked
+)
+
+// Label is a human review of an audited decision.
+// Labels are stored separately so audit records stay immutable.
+type Label struct {
+	AuditID   string    `json:"audit_id"`
+	Outcome   Outcome   `json:"outcome"`
+	LabeledBy string    `json:"labeled_by,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	LabeledAt time.Time `json:"labeled_at"`
+}
+
+// Validate validates a label.
+func (l *Label) Validate() error {
+	if l.AuditID == "" {
+		return fmt.Errorf("audit_id is required")
+	}
+	if l.Outcome != OutcomeSafe && l.Outcome != OutcomeUnsafe {
+		return fmt.Errorf("outcome must be SAFE or UNSAFE")
+	}
+	return nil
+}
+
+// LabelStore stores audit outcome labels. Labeling an audit again replaces its label.
+type LabelStore interface {
+	Put(ctx context.Context, label *Label) error
+	List(ctx context.Context) ([]*Label, error)
+}
+
+// InMemoryLabelStore is an in-memory implementation of LabelStore.
+type InMemoryLabelStore struct {
+	mu     sync.RWMutex
+	labels map[string]*Label
+	order  []string
+}
+
+// NewInMemoryLabelStore creates a new in-memory label store.
+func NewInMemoryLabelStore() *InMemoryLabelStore {
+	return &InMemoryLabelStore{
+		labels: make(map[string]*Label),
+	}
+}
+
+func (s *InMemoryLabelStore) Put(ctx context.Context, label *Label) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if label.LabeledAt.IsZero() {
+		label.LabeledAt = time.Now().UTC()
+	}
+	if _, exists := s.labels[label.AuditID]; !exists {
+		s.order = append(s.order, label.AuditID)
+	}
+	s.labels[label.AuditID] = label
+	return nil
+}
+
+func (s *InMemoryLabelStore) List(ctx context.Context) ([]*Label, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Label, 0, len(s.order))
+	for _, id := range s.order {
+		result = append(result, s.labels[id])
+	}
+	return result, nil
+}
+
+// Sample is a single (confidence, correct) observation for one voter and model.
+type Sample struct {
+	VoterID    string
+	Model      string
+	Confidence float64
+	Correct    bool
+}
+
+// SamplesFromRecord extracts calibration samples from a labeled audit record.
+// A SAFE vote is correct when the outcome is SAFE and a DENY vote when it is UNSAFE;
+// ABSTAIN votes carry no signal and are skipped. A CLEAR threat label is correct when
+// the outcome is SAFE, SUSPICIOUS and MALICIOUS when it is UNSAFE.
+func SamplesFromRecord(record *types.AuditRecord, outcome Outcome) []Sample {
+	samples := make([]Sample, 0)
+	safe := outcome == OutcomeSafe
+
+	if record.Alignment != nil {
+		for _, v := range record.Alignment.Voters {
+			var correct bool
+			switch v.Vote {
+			case types.IntentVoteSafe:
+				correct = safe
+			case types.IntentVoteDeny:
+				correct = !safe
+			default:
+				continue
+			}
+			samples = append(samples, Sample{VoterID: v.VoterID, Model: v.Model, Confidence: v.Confidence, Correct: correct})
+		}
+	}
+
+	if record.Threat != nil {
+		correct := (record.Threat.Label == types.ThreatClear) == safe
+		samples = append(samples, Sample{VoterID: ThreatSentinelID, Model: record.Threat.Model, Confidence: record.Threat.Confidence, Correct: correct})
+	}
+
+	return samples
+}
+
+// ReliabilityReport summarizes calibration quality per voter and model.
+type ReliabilityReport struct {
+	Method     string             `json:"method"`
+	MinSamples int                `json:"min_samples"`
+	Labels     int                `json:"labels"`
+	Samples    int                `json:"samples"`
+	FittedAt   time.Time          `json:"fitted_at,omitempty"`
+	Voters     []ReliabilityStats `json:"voters"`
+}
+
+// ReliabilityStats describes how well one voter's confidence predicts correctness.
+// ECE is the expected calibration error over equal-width confidence bins.
+type ReliabilityStats struct {
+	VoterID         string           `json:"voter_id"`
+	Model           string           `json:"model,omitempty"`
+	Samples         int              `json:"samples"`
+	Calibrated      bool             `json:"calibrated"` // Whether a curve was fitted (enough samples)
+	Accuracy        float64          `json:"accuracy"`
+	BrierRaw        float64          `json:"brier_raw"`
+	BrierCalibrated float64          `json:"brier_calibrated"`
+	ECERaw          float64          `json:"ece_raw"`
+	ECECalibrated   float64          `json:"ece_calibrated"`
+	Bins            []ReliabilityBin `json:"bins"`
+}
+
+// ReliabilityBin is one bucket of a reliability diagram.
+type ReliabilityBin struct {
+	Lower          float64 `json:"lower"`
+	Upper          float64 `json:"upper"`
+	Count          int     `json:"count"`
+	MeanConfidence float64 `json:"mean_confidence"`
+	MeanCalibrated float64 `json:"mean_calibrated"`
+	Accuracy       float64 `json:"accuracy"`
+}
+
+// reliability computes stats for one voter's samples. fitted may be nil.
+func reliability(samples []Sample, fitted curve) ReliabilityStats {
+	stats := ReliabilityStats{
+		VoterID:    samples[0].VoterID,
+		Model:      samples[0].Model,
+		Samples:    len(samples),
+		Calibrated: fitted != nil,
+		Bins:       make([]ReliabilityBin, reliabilityBins),
+	}
+	for i := range stats.Bins {
+		stats.Bins[i].Lower = float64(i) / reliabilityBins
+		stats.Bins[i].Upper = float64(i+1) / reliabilityBins
+	}
+
+	n := float64(len(samples))
+	var correct float64
+	for _, s := range samples {
+		raw := clamp01(s.Confidence)
+		cal := raw
+		if fitted != nil {
+			cal = clamp01(fitted.predict(raw))
+		}
+		y := 0.0
+		if s.Correct {
+			y = 1
+			correct++
+		}
+		stats.BrierRaw += (raw - y) * (raw - y) / n
+		stats.BrierCalibrated += (cal - y) * (cal - y) / n
+
+		idx := int(raw * reliabilityBins)
+		if idx == reliabilityBins {
+			idx--
+		}
+		bin := &stats.Bins[idx]
+		bin.Count++
+		bin.MeanConfidence += raw
+		bin.MeanCalibrated += cal
+		bin.Accuracy += y
+	}
+	stats.Accuracy = correct / n
+
+	for i := range stats.Bins {
+		bin := &stats.Bins[i]
+		if bin.Count == 0 {
+			continue
+		}
+		c := float64(bin.Count)
+		bin.MeanConfidence /= c
+		bin.MeanCalibrated /= c
+		bin.Accuracy /= c
+		stats.ECERaw += c / n * math.Abs(bin.MeanConfidence-bin.Accuracy)
+		stats.ECECalibrated += c / n * math.Abs(bin.MeanCalibrated-bin.Accuracy)
+	}
+
+	return stats
+}
@@ -13,6 +13,7 @@ type Config struct {
 	// Server settings
 	Port     int
 	LogLevel string
+	Env      string // Deployment environment, e.g. local, dev, prod
 
 	// AWS settings
 	S3Bucket  string
@@ -31,6 +32,69 @@ type Config struct {
 	DDBTableTokens      string
 	DDBTableTools       string
 	DDBTableToolsets    string
+	DDBTableUsage       string
+
+	// Control plane storage
+	StoreBackend string // "dynamodb", "memory", "sqlite" or "postgres"
+	SQLitePath   string // Database file for the sqlite backend
+	ManifestDir  string // Local directory for manifests, used instead of the backend's blob storage
+	PostgresURL  string // Database for the postgres store and audit backends
+
+	// GitOps mode: tools, toolsets and assignments are resolved from a
+	// directory tree instead of the sample registry and control plane store
+	GitOpsDir          string        // Tree of {tenant_id}/tools, toolsets and assignments.yaml
+	GitOpsPollInterval time.Duration // Time between checks of the tree for changes
+
+	// Tool resolution cache (disabled when the TTL is 0)
+	ResolverCacheTTL    time.Duration // How long a resolved tool is reused
+	ResolverNegativeTTL time.Duration // How long an unknown tool is remembered
+	ResolverCacheSize   int           // Maximum cached resolutions
+
+	// Audit storage (Postgres when AuditBackend is "postgres", otherwise S3 when
+	// both bucket and index table are set, otherwise in-memory)
+	AuditBackend    string
+	AuditBucket     string
+	AuditPrefix     string
+	AuditIndexTable string
+
+	// Asynchronous audit writing
+	AuditBufferSize     int           // Records queued in memory before the overflow policy applies
+	AuditBatchSize      int           // Records per flush
+	AuditFlushInterval  time.Duration // Maximum time a record waits in the queue
+	AuditOverflowPolicy string        // "block", "drop" or "fail_closed"
+	AuditSpoolDir       string        // Directory for the on-disk spool used while the store is unavailable
+
+	// Tamper-evident audit chain
+	AuditCheckpointSize int    // Records per signed checkpoint window
+	AuditSigningKey     string // base64 ed25519 seed for signing checkpoints (unsigned when empty)
+
+	// Audit export
+	AuditSinks string // JSON array of audit sink configs (file, webhook, syslog)
+
+	// Audit redaction and retention
+	AuditPolicies      string        // JSON of tenant_id ("*" for the default) -> audit policy
+	AuditHashKey       string        // HMAC key for hashed sensitive arguments (plain SHA-256 when empty)
+	AuditArchiveSink   string        // JSON sink config receiving expired records before they are purged
+	AuditPurgeInterval time.Duration // Time between retention runs
+
+	// Historical replay
+	ReplayConcurrency int // Replay jobs run at once
+
+	// Prometheus metrics
+	MetricsEnabled    bool // Serve /metrics
+	MetricsPort       int  // Port of the metrics listener, kept off the API port
+	MetricsMaxTenants int  // Tenants labelled individually, the rest as "other"
+
+	// OpenTelemetry tracing
+	OTLPEndpoint       string  // OTLP/HTTP collector URL (tracing is disabled when empty)
+	TracingServiceName string  // service.name of exported spans
+	TracingSampleRatio float64 // Fraction of new traces sampled (sampled parents are always followed)
+
+	// Readiness checks
+	HealthCheckTimeout  time.Duration // Time allowed for one dependency check
+	HealthCheckCacheTTL time.Duration // How long a dependency's result is reused
+	HealthTimeouts      string        // Per-dependency timeout overrides, e.g. "alignment_llm=5s"
+	HealthRequired      string        // Comma-separated dependencies whose failure makes the pod unready ("*" for all)
 
 	// LLM endpoints
 	FunctionGemmaBaseURL string
@@ -45,6 +109,30 @@ type Config struct {
 	IntentModelAPIKey   string
 	IntentModelTimeout  time.Duration
 
+	// Prompt templates
+	PromptOverridesDir string // Directory of {tenant_id}.json prompt overrides (optional)
+
+	// Confidence calibration
+	CalibrationMethod     string  // "isotonic" or "platt"
+	CalibrationMinSamples int     // Labeled samples a voter needs before its curve is used
+	MinSafeProbability    float64 // SAFE votes below this calibrated probability escalate
+	MinDenyProbability    float64 // DENY votes below this calibrated probability escalate
+	MinThreatProbability  float64 // CLEAR/MALICIOUS threat labels below this calibrated probability escalate
+
+	// Usage accounting
+	ModelPricing          string // JSON of model -> {prompt_per_mtok, completion_per_mtok} in USD
+	TokenQuotas           string // Monthly token quotas by plan, e.g. "free=1000000,pro=50000000"
+	TokenQuotasPerReplica bool   // Acknowledges that quotas count in-memory usage per replica (memory store backend or no control plane)
+	DefaultPlan           string // Plan for tenants without a known plan
+
+	// Admin API
+	AdminAPIKey string // Bearer key for /v1/admin endpoints (disabled when empty)
+
+	// Data plane authentication (API tokens from the control plane store)
+	RequireAPIToken    bool          // Require a token with the evaluate scope on /v1/firewall
+	TokenCacheTTL      time.Duration // How long a validated token is reused (0 validates every call)
+	TokenTouchInterval time.Duration // Time between writes of tokens' last-used times
+
 	// Request limits
 	RequestMaxBytes int
 	MaxContextChars int
@@ -55,41 +143,95 @@ type Config struct {
 
 	// Feature flags
 	EnableThreatSentinel bool
+	EnableContextScan    bool // Scan bounded context for indirect prompt injection
 	EnableControlPlane   bool // Whether to enable control plane endpoints (onboarding, etc.)
 }
 
 // DefaultConfig returns a configuration with sensible defaults.
 func DefaultConfig() *Config {
 	return &Config{
-		Port:                 8080,
-		LogLevel:             "info",
-		S3Bucket:             "",
-		AWSRegion:            "us-east-1",
-		CognitoIssuer:        "",
-		CognitoAudience:      "",
-		CognitoEnabled:       false,
-		DDBTableTenants:      "invarity-tenants",
-		DDBTableUsers:        "invarity-users",
-		DDBTableMemberships:  "invarity-memberships",
-		DDBTablePrincipals:   "invarity-principals",
-		DDBTableTokens:       "invarity-tokens",
-		DDBTableTools:        "invarity-tools",
-		DDBTableToolsets:     "invarity-toolsets",
-		FunctionGemmaBaseURL: "http://localhost:8001/v1",
-		FunctionGemmaAPIKey:  "",
-		LlamaGuardBaseURL:    "http://localhost:8002/v1",
-		LlamaGuardAPIKey:     "",
-		QwenBaseURL:          "http://localhost:8003/v1",
-		QwenAPIKey:           "",
-		IntentModelEndpoint:  "", // Set via INTENT_MODEL_ENDPOINT env var
-		IntentModelAPIKey:    "",
-		IntentModelTimeout:   1500 * time.Millisecond,
-		RequestMaxBytes:      1 << 20, // 1MB
-		MaxContextChars:      32000,
-		MaxIntentChars:       4000,
-		CacheTTL:             5 * time.Minute,
-		EnableThreatSentinel: true,
-		EnableControlPlane:   false,
+		Port:                  8080,
+		LogLevel:              "info",
+		Env:                   "local",
+		S3Bucket:              "",
+		AWSRegion:             "us-east-1",
+		CognitoIssuer:         "",
+		CognitoAudience:       "",
+		CognitoEnabled:        false,
+		DDBTableTenants:       "invarity-tenants",
+		DDBTableUsers:         "invarity-users",
+		DDBTableMemberships:   "invarity-memberships",
+		DDBTablePrincipals:    "invarity-principals",
+		DDBTableTokens:        "invarity-tokens",
+		DDBTableTools:         "invarity-tools",
+		DDBTableToolsets:      "invarity-toolsets",
+		DDBTableUsage:         "invarity-usage",
+		StoreBackend:          "dynamodb",
+		SQLitePath:            "invarity.db",
+		ManifestDir:           "",
+		PostgresURL:           "",
+		GitOpsDir:             "",
+		GitOpsPollInterval:    10 * time.Second,
+		ResolverCacheTTL:      30 * time.Second,
+		ResolverNegativeTTL:   5 * time.Second,
+		ResolverCacheSize:     10000,
+		AuditBackend:          "",
+		AuditBucket:           "",
+		AuditPrefix:           "audit",
+		AuditIndexTable:       "",
+		AuditBufferSize:       1024,
+		AuditBatchSize:        100,
+		AuditFlushInterval:    time.Second,
+		AuditOverflowPolicy:   "block",
+		AuditSpoolDir:         "",
+		AuditCheckpointSize:   1000,
+		AuditSigningKey:       "",
+		AuditSinks:            "",
+		AuditPolicies:         "",
+		AuditHashKey:          "",
+		AuditArchiveSink:      "",
+		AuditPurgeInterval:    time.Hour,
+		ReplayConcurrency:     2,
+		MetricsEnabled:        true,
+		MetricsPort:           9090,
+		MetricsMaxTenants:     100,
+		OTLPEndpoint:          "",
+		TracingServiceName:    "invarity-firewall",
+		TracingSampleRatio:    1.0,
+		HealthCheckTimeout:    2 * time.Second,
+		HealthCheckCacheTTL:   10 * time.Second,
+		HealthTimeouts:        "",
+		HealthRequired:        "audit_store,dynamodb",
+		FunctionGemmaBaseURL:  "http://localhost:8001/v1",
+		FunctionGemmaAPIKey:   "",
+		LlamaGuardBaseURL:     "http://localhost:8002/v1",
+		LlamaGuardAPIKey:      "",
+		QwenBaseURL:           "http://localhost:8003/v1",
+		QwenAPIKey:            "",
+		IntentModelEndpoint:   "", // Set via INTENT_MODEL_ENDPOINT env var
+		IntentModelAPIKey:     "",
+		IntentModelTimeout:    1500 * time.Millisecond,
+		PromptOverridesDir:    "",
+		CalibrationMethod:     "isotonic",
+		CalibrationMinSamples: 30,
+		MinSafeProbability:    0,
+		MinDenyProbability:    0,
+		MinThreatProbability:  0,
+		ModelPricing:          "",
+		TokenQuotas:           "",
+		TokenQuotasPerReplica: false,
+		DefaultPlan:           "free",
+		AdminAPIKey:           "",
+		RequireAPIToken:       false,
+		TokenCacheTTL:         30 * time.Second,
+		TokenTouchInterval:    10 * time.Second,
+		RequestMaxBytes:       1 << 20, // 1MB
+		MaxContextChars:       32000,
+		MaxIntentChars:        4000,
+		CacheTTL:              5 * time.Minute,
+		EnableThreatSentinel:  true,
+		EnableContextScan:     true,
+		EnableControlPlane:    false,
 	}
 }
 
@@ -109,6 +251,10 @@ func LoadFromEnv() (*Config, error) {
 		cfg.LogLevel = v
 	}
 
+	if v := os.Getenv("INVARITY_ENV"); v != "" {
+		cfg.Env = v
+	}
+
 	if v := os.Getenv("S3_BUCKET"); v != "" {
 		cfg.S3Bucket = v
 	}
@@ -157,6 +303,78 @@ func LoadFromEnv() (*Config, error) {
 		cfg.IntentModelTimeout = time.Duration(timeout) * time.Millisecond
 	}
 
+	if v := os.Getenv("INVARITY_PROMPT_OVERRIDES_DIR"); v != "" {
+		cfg.PromptOverridesDir = v
+	}
+
+	// Calibration settings
+	if v := os.Getenv("INVARITY_CALIBRATION_METHOD"); v != "" {
+		cfg.CalibrationMethod = v
+	}
+
+	if v := os.Getenv("INVARITY_CALIBRATION_MIN_SAMPLES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_CALIBRATION_MIN_SAMPLES: %w", err)
+		}
+		cfg.CalibrationMinSamples = n
+	}
+
+	for name, target := range map[string]*float64{
+		"INVARITY_MIN_SAFE_PROBABILITY":   &cfg.MinSafeProbability,
+		"INVARITY_MIN_DENY_PROBABILITY":   &cfg.MinDenyProbability,
+		"INVARITY_MIN_THREAT_PROBABILITY": &cfg.MinThreatProbability,
+	} {
+		if v := os.Getenv(name); v != "" {
+			p, err := strconv.ParseFloat(v, 64)
+			if err != nil || p < 0 || p > 1 {
+				return nil, fmt.Errorf("invalid %s: must be a number between 0 and 1", name)
+			}
+			*target = p
+		}
+	}
+
+	// Usage accounting
+	if v := os.Getenv("INVARITY_MODEL_PRICING"); v != "" {
+		cfg.ModelPricing = v
+	}
+
+	if v := os.Getenv("INVARITY_TOKEN_QUOTAS"); v != "" {
+		cfg.TokenQuotas = v
+	}
+
+	if v := os.Getenv("INVARITY_TOKEN_QUOTAS_PER_REPLICA"); v != "" {
+		cfg.TokenQuotasPerReplica = v == "true" || v == "1"
+	}
+
+	if v := os.Getenv("INVARITY_DEFAULT_PLAN"); v != "" {
+		cfg.DefaultPlan = v
+	}
+
+	if v := os.Getenv("INVARITY_ADMIN_API_KEY"); v != "" {
+		cfg.AdminAPIKey = v
+	}
+
+	// Data plane authentication: required by default outside local and dev
+	cfg.RequireAPIToken = !cfg.IsDevelopment()
+	if v := os.Getenv("INVARITY_REQUIRE_API_TOKEN"); v != "" {
+		cfg.RequireAPIToken = v == "true" || v == "1"
+	}
+	if v := os.Getenv("INVARITY_TOKEN_CACHE_TTL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_TOKEN_CACHE_TTL_SECONDS: %w", err)
+		}
+		cfg.TokenCacheTTL = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("INVARITY_TOKEN_TOUCH_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_TOKEN_TOUCH_SECONDS: %w", err)
+		}
+		cfg.TokenTouchInterval = time.Duration(seconds) * time.Second
+	}
+
 	if v := os.Getenv("REQUEST_MAX_BYTES"); v != "" {
 		maxBytes, err := strconv.Atoi(v)
 		if err != nil {
@@ -193,6 +411,10 @@ func LoadFromEnv() (*Config, error) {
 		cfg.EnableThreatSentinel = v == "true" || v == "1"
 	}
 
+	if v := os.Getenv("ENABLE_CONTEXT_SCAN"); v != "" {
+		cfg.EnableContextScan = v == "true" || v == "1"
+	}
+
 	// Cognito settings
 	if v := os.Getenv("INVARITY_COGNITO_ISSUER"); v != "" {
 		cfg.CognitoIssuer = v
@@ -241,6 +463,214 @@ func LoadFromEnv() (*Config, error) {
 		cfg.DDBTableToolsets = v
 	}
 
+	if v := os.Getenv("INVARITY_DDB_TABLE_USAGE"); v != "" {
+		cfg.DDBTableUsage = v
+	}
+
+	// Control plane storage
+	if v := os.Getenv("INVARITY_STORE_BACKEND"); v != "" {
+		cfg.StoreBackend = v
+	}
+	if v := os.Getenv("INVARITY_SQLITE_PATH"); v != "" {
+		cfg.SQLitePath = v
+	}
+	if v := os.Getenv("INVARITY_MANIFEST_DIR"); v != "" {
+		cfg.ManifestDir = v
+	}
+	if v := os.Getenv("INVARITY_POSTGRES_URL"); v != "" {
+		cfg.PostgresURL = v
+	}
+
+	// GitOps mode
+	if v := os.Getenv("INVARITY_GITOPS_DIR"); v != "" {
+		cfg.GitOpsDir = v
+	}
+	if v := os.Getenv("INVARITY_GITOPS_POLL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_GITOPS_POLL_SECONDS: %w", err)
+		}
+		cfg.GitOpsPollInterval = time.Duration(seconds) * time.Second
+	}
+
+	// Tool resolution cache
+	if v := os.Getenv("INVARITY_RESOLVER_CACHE_TTL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_RESOLVER_CACHE_TTL_SECONDS: %w", err)
+		}
+		cfg.ResolverCacheTTL = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("INVARITY_RESOLVER_CACHE_NEGATIVE_TTL_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_RESOLVER_CACHE_NEGATIVE_TTL_SECONDS: %w", err)
+		}
+		cfg.ResolverNegativeTTL = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("INVARITY_RESOLVER_CACHE_SIZE"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_RESOLVER_CACHE_SIZE: %w", err)
+		}
+		cfg.ResolverCacheSize = size
+	}
+
+	// Audit storage (names injected by the infra stack, overridable with INVARITY_ vars)
+	if v := os.Getenv("INVARITY_AUDIT_BACKEND"); v != "" {
+		cfg.AuditBackend = v
+	}
+
+	if v := os.Getenv("AUDIT_BLOBS_BUCKET"); v != "" {
+		cfg.AuditBucket = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_BUCKET"); v != "" {
+		cfg.AuditBucket = v
+	}
+
+	if v := os.Getenv("AUDIT_PREFIX"); v != "" {
+		cfg.AuditPrefix = v
+	}
+
+	if v := os.Getenv("AUDIT_INDEX_TABLE"); v != "" {
+		cfg.AuditIndexTable = v
+	}
+
+	if v := os.Getenv("INVARITY_DDB_TABLE_AUDIT_INDEX"); v != "" {
+		cfg.AuditIndexTable = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_BUFFER_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_AUDIT_BUFFER_SIZE: %w", err)
+		}
+		cfg.AuditBufferSize = n
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_AUDIT_BATCH_SIZE: %w", err)
+		}
+		cfg.AuditBatchSize = n
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_FLUSH_INTERVAL_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_AUDIT_FLUSH_INTERVAL_MS: %w", err)
+		}
+		cfg.AuditFlushInterval = time.Duration(ms) * time.Millisecond
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_OVERFLOW_POLICY"); v != "" {
+		cfg.AuditOverflowPolicy = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_SPOOL_DIR"); v != "" {
+		cfg.AuditSpoolDir = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_CHECKPOINT_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_AUDIT_CHECKPOINT_SIZE: %w", err)
+		}
+		cfg.AuditCheckpointSize = n
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_SIGNING_KEY"); v != "" {
+		cfg.AuditSigningKey = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_SINKS"); v != "" {
+		cfg.AuditSinks = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_POLICIES"); v != "" {
+		cfg.AuditPolicies = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_HASH_KEY"); v != "" {
+		cfg.AuditHashKey = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_ARCHIVE_SINK"); v != "" {
+		cfg.AuditArchiveSink = v
+	}
+
+	if v := os.Getenv("INVARITY_AUDIT_PURGE_INTERVAL_MINUTES"); v != "" {
+		minutes, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_AUDIT_PURGE_INTERVAL_MINUTES: %w", err)
+		}
+		cfg.AuditPurgeInterval = time.Duration(minutes) * time.Minute
+	}
+
+	if v := os.Getenv("INVARITY_REPLAY_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_REPLAY_CONCURRENCY: %w", err)
+		}
+		cfg.ReplayConcurrency = n
+	}
+
+	if v := os.Getenv("INVARITY_METRICS_ENABLED"); v != "" {
+		cfg.MetricsEnabled = v == "true" || v == "1"
+	}
+	if v := os.Getenv("INVARITY_METRICS_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_METRICS_PORT: %w", err)
+		}
+		cfg.MetricsPort = port
+	}
+	if v := os.Getenv("INVARITY_METRICS_MAX_TENANTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_METRICS_MAX_TENANTS: %w", err)
+		}
+		cfg.MetricsMaxTenants = n
+	}
+
+	if v := os.Getenv("INVARITY_OTLP_ENDPOINT"); v != "" {
+		cfg.OTLPEndpoint = v
+	}
+	if v := os.Getenv("INVARITY_TRACING_SERVICE_NAME"); v != "" {
+		cfg.TracingServiceName = v
+	}
+	if v := os.Getenv("INVARITY_TRACING_SAMPLE_RATIO"); v != "" {
+		ratio, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_TRACING_SAMPLE_RATIO: %w", err)
+		}
+		cfg.TracingSampleRatio = ratio
+	}
+
+	if v := os.Getenv("INVARITY_HEALTH_CHECK_TIMEOUT_MS"); v != "" {
+		ms, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_HEALTH_CHECK_TIMEOUT_MS: %w", err)
+		}
+		cfg.HealthCheckTimeout = time.Duration(ms) * time.Millisecond
+	}
+	if v := os.Getenv("INVARITY_HEALTH_CHECK_CACHE_SECONDS"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid INVARITY_HEALTH_CHECK_CACHE_SECONDS: %w", err)
+		}
+		cfg.HealthCheckCacheTTL = time.Duration(seconds) * time.Second
+	}
+	if v := os.Getenv("INVARITY_HEALTH_TIMEOUTS"); v != "" {
+		cfg.HealthTimeouts = v
+	}
+	// Set but empty makes no dependency required
+	if v, ok := os.LookupEnv("INVARITY_HEALTH_REQUIRED"); ok {
+		cfg.HealthRequired = v
+	}
+
 	// Control plane feature flag
 	if v := os.Getenv("INVARITY_ENABLE_CONTROL_PLANE"); v != "" {
 		cfg.EnableControlPlane = v == "true" || v == "1"
@@ -250,6 +680,17 @@ func LoadFromEnv() (*Config, error) {
 }
 
 // Validate checks if the configuration is valid.
+// UsageInMemory reports whether token usage is kept in memory on each replica.
+// Usage is stored alongside the control plane unless it uses the memory backend.
+func (c *Config) UsageInMemory() bool {
+	return !c.EnableControlPlane || c.StoreBackend == "memory"
+}
+
+// IsDevelopment reports whether the server runs in the local or dev environment.
+func (c *Config) IsDevelopment() bool {
+	return c.Env == "local" || c.Env == "dev"
+}
+
 func (c *Config) Validate() error {
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("port must be between 1 and 65535")
@@ -267,6 +708,102 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("MAX_INTENT_CHARS must be at least 10")
 	}
 
+	if (c.AuditBucket == "") != (c.AuditIndexTable == "") {
+		return fmt.Errorf("AUDIT_BLOBS_BUCKET and AUDIT_INDEX_TABLE must be set together")
+	}
+
+	if c.AuditBufferSize < 1 || c.AuditBatchSize < 1 {
+		return fmt.Errorf("INVARITY_AUDIT_BUFFER_SIZE and INVARITY_AUDIT_BATCH_SIZE must be at least 1")
+	}
+
+	if c.AuditFlushInterval <= 0 {
+		return fmt.Errorf("INVARITY_AUDIT_FLUSH_INTERVAL_MS must be positive")
+	}
+
+	if c.AuditCheckpointSize < 1 {
+		return fmt.Errorf("INVARITY_AUDIT_CHECKPOINT_SIZE must be at least 1")
+	}
+
+	if c.AuditPurgeInterval <= 0 {
+		return fmt.Errorf("INVARITY_AUDIT_PURGE_INTERVAL_MINUTES must be positive")
+	}
+
+	if c.ReplayConcurrency < 1 {
+		return fmt.Errorf("INVARITY_REPLAY_CONCURRENCY must be at least 1")
+	}
+
+	if c.MetricsEnabled && (c.MetricsPort < 1 || c.MetricsPort > 65535 || c.MetricsPort == c.Port) {
+		return fmt.Errorf("INVARITY_METRICS_PORT must be between 1 and 65535 and differ from PORT")
+	}
+
+	if c.MetricsMaxTenants < 0 {
+		return fmt.Errorf("INVARITY_METRICS_MAX_TENANTS must not be negative")
+	}
+
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		return fmt.Errorf("INVARITY_TRACING_SAMPLE_RATIO must be between 0 and 1")
+	}
+
+	if c.HealthCheckTimeout <= 0 {
+		return fmt.Errorf("INVARITY_HEALTH_CHECK_TIMEOUT_MS must be positive")
+	}
+
+	if c.HealthCheckCacheTTL <= 0 {
+		return fmt.Errorf("INVARITY_HEALTH_CHECK_CACHE_SECONDS must be positive")
+	}
+
+	if c.GitOpsPollInterval <= 0 {
+		return fmt.Errorf("INVARITY_GITOPS_POLL_SECONDS must be positive")
+	}
+
+	if c.ResolverCacheTTL < 0 {
+		return fmt.Errorf("INVARITY_RESOLVER_CACHE_TTL_SECONDS must be non-negative")
+	}
+
+	if c.ResolverCacheTTL > 0 && (c.ResolverNegativeTTL <= 0 || c.ResolverCacheSize <= 0) {
+		return fmt.Errorf("INVARITY_RESOLVER_CACHE_NEGATIVE_TTL_SECONDS and INVARITY_RESOLVER_CACHE_SIZE must be positive")
+	}
+
+	if c.RequireAPIToken && !c.EnableControlPlane {
+		return fmt.Errorf("INVARITY_REQUIRE_API_TOKEN requires INVARITY_ENABLE_CONTROL_PLANE, whose store holds the tokens")
+	}
+
+	if c.TokenQuotas != "" && c.UsageInMemory() && !c.TokenQuotasPerReplica {
+		return fmt.Errorf("INVARITY_TOKEN_QUOTAS counts usage in memory on each replica without a persistent control plane store, which resets on restart; set INVARITY_TOKEN_QUOTAS_PER_REPLICA=true to accept this")
+	}
+
+	if c.TokenCacheTTL < 0 {
+		return fmt.Errorf("INVARITY_TOKEN_CACHE_TTL_SECONDS must be non-negative")
+	}
+
+	if c.TokenTouchInterval <= 0 {
+		return fmt.Errorf("INVARITY_TOKEN_TOUCH_SECONDS must be positive")
+	}
+
+	switch c.StoreBackend {
+	case "dynamodb", "memory":
+	case "sqlite":
+		if c.SQLitePath == "" {
+			return fmt.Errorf("INVARITY_SQLITE_PATH must be set for the sqlite store backend")
+		}
+	case "postgres":
+		if c.PostgresURL == "" {
+			return fmt.Errorf("INVARITY_POSTGRES_URL must be set for the postgres store backend")
+		}
+	default:
+		return fmt.Errorf("INVARITY_STORE_BACKEND must be one of: dynamodb, memory, sqlite, postgres")
+	}
+
+	switch c.AuditBackend {
+	case "":
+	case "postgres":
+		if c.PostgresURL == "" {
+			return fmt.Errorf("INVARITY_POSTGRES_URL must be set for the postgres audit backend")
+		}
+	default:
+		return fmt.Errorf("INVARITY_AUDIT_BACKEND must be empty or postgres")
+	}
+
 	validLogLevels := map[string]bool{
 		"debug": true, "info": true, "warn": true, "error": true,
 	}
@@ -0,0 +1,291 @@
+// Package contextscan detects indirect prompt injection in bounded context snippets.
+package contextscan
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"invarity/internal/types"
+)
+
+// Snippet sources.
+const (
+	SourceRelevantDocuments   = "relevant_documents"
+	SourceConversationHistory = "conversation_history"
+)
+
+// Heuristic names recorded in findings.
+const (
+	HeuristicInstructionPhrasing = "instruction_phrasing"
+	HeuristicRoleSwitch          = "role_switch"
+	HeuristicHiddenUnicode       = "hidden_unicode"
+	HeuristicBase64Blob          = "base64_blob"
+)
+
+// MinTraceLength is the minimum length of an argument string for it to be traced to a snippet.
+// Shorter values (ids, flags, small numbers) match context text by coincidence.
+const MinTraceLength = 8
+
+// maxExcerpt bounds the excerpt recorded with each finding.
+const maxExcerpt = 80
+
+// Snippet is a single bounded context item.
+type Snippet struct {
+	Source string
+	Index  int
+	Text   string
+}
+
+// Key identifies a snippet within the bounded context.
+func (s Snippet) Key() string {
+	return fmt.Sprintf("%s[%d]", s.Source, s.Index)
+}
+
+// Snippets flattens a bounded context into snippets, documents first.
+func Snippets(bc *types.BoundedContext) []Snippet {
+	if bc == nil {
+		return nil
+	}
+	snippets := make([]Snippet, 0, len(bc.RelevantDocuments)+len(bc.ConversationHistory))
+	for i, doc := range bc.RelevantDocuments {
+		snippets = append(snippets, Snippet{Source: SourceRelevantDocuments, Index: i, Text: doc})
+	}
+	for i, turn := range bc.ConversationHistory {
+		snippets = append(snippets, Snippet{Source: SourceConversationHistory, Index: i, Text: turn})
+	}
+	return snippets
+}
+
+var instructionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override)\b.{0,20}\b(previous|prior|above|earlier|preceding|all|your|the)\b.{0,20}\b(instructions?|directions?|prompts?|rules|guidelines|context)\b`),
+	regexp.MustCompile(`(?i)\byou are now\b`),
+	regexp.MustCompile(`(?i)\bnew (instructions?|system prompt|task)\s*:`),
+	regexp.MustCompile(`(?i)\b(do not|don't|never)\s+(tell|inform|alert|notify)\s+the\s+user\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|output|repeat)\s+(your|the)\s+(system prompt|instructions)\b`),
+	regexp.MustCompile(`(?i)\b(instructions?|message|note)\s+(for|to)\s+(the\s+)?(ai|assistant|agent|model|llm)\b`),
+	regexp.MustCompile(`(?i)\b(ai|assistant|agent)\b.{0,30}\b(must|should)\s+(now\s+)?(call|invoke|execute|run|send|forward|transfer)\b`),
+}
+
+// Special tokens and tags used by chat templates are suspicious anywhere in a snippet.
+var roleTokenPattern = regexp.MustCompile(`(?i)<\|?(im_start|im_end|system|assistant|user|endoftext)\|?>|</?(system|assistant|developer)>|\[/?INST\]|<<SYS>>|###\s*(system|instruction)`)
+
+// A "role:" prefix is normal on the first line of a conversation turn, but not mid-snippet.
+var roleLinePattern = regexp.MustCompile(`(?im)^\s*(system|assistant|user|developer|tool)\s*:`)
+
+var base64Pattern = regexp.MustCompile(`[A-Za-z0-9+/_-]{40,}={0,2}`)
+
+// Scanner applies deterministic indirect-injection heuristics to bounded context.
+type Scanner struct{}
+
+// NewScanner creates a new context scanner.
+func NewScanner() *Scanner {
+	return &Scanner{}
+}
+
+// Scan returns heuristic findings for every snippet in the bounded context.
+// Each heuristic is reported at most once per snippet.
+func (s *Scanner) Scan(bc *types.BoundedContext) []types.ContextFinding {
+	findings := make([]types.ContextFinding, 0)
+	for _, snippet := range Snippets(bc) {
+		findings = append(findings, s.ScanSnippet(snippet)...)
+	}
+	return findings
+}
+
+// ScanSnippet returns heuristic findings for a single snippet.
+func (s *Scanner) ScanSnippet(snippet Snippet) []types.ContextFinding {
+	findings := make([]types.ContextFinding, 0)
+	add := func(heuristic, excerpt string) {
+		findings = append(findings, types.ContextFinding{
+			Source:    snippet.Source,
+			Index:     snippet.Index,
+			Heuristic: heuristic,
+			Excerpt:   truncate(excerpt),
+		})
+	}
+
+	if m := matchInstruction(snippet.Text); m != "" {
+		add(HeuristicInstructionPhrasing, m)
+	}
+	if m := matchRoleSwitch(snippet.Text); m != "" {
+		add(HeuristicRoleSwitch, m)
+	}
+	if m := hiddenUnicode(snippet.Text); m != "" {
+		add(HeuristicHiddenUnicode, m)
+	}
+	if m := base64Blob(snippet.Text); m != "" {
+		add(HeuristicBase64Blob, m)
+	}
+
+	return findings
+}
+
+func matchInstruction(text string) string {
+	for _, p := range instructionPatterns {
+		if m := p.FindString(text); m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+func matchRoleSwitch(text string) string {
+	if m := roleTokenPattern.FindString(text); m != "" {
+		return m
+	}
+	// Skip a leading role marker on the first line
+	firstLine := strings.IndexByte(text, '\n')
+	if firstLine < 0 {
+		return ""
+	}
+	if m := roleLinePattern.FindString(text[firstLine:]); m != "" {
+		return strings.TrimSpace(m)
+	}
+	return ""
+}
+
+// hiddenUnicode reports zero-width, bidi-control and tag characters, which render
+// invisibly but are read by the model.
+func hiddenUnicode(text string) string {
+	counts := make(map[rune]int)
+	order := make([]rune, 0)
+	for _, r := range text {
+		if isHidden(r) {
+			if counts[r] == 0 {
+				order = append(order, r)
+			}
+			counts[r]++
+		}
+	}
+	if len(order) == 0 {
+		return ""
+	}
+	parts := make([]string, len(order))
+	for i, r := range order {
+		parts[i] = fmt.Sprintf("U+%04X x%d", r, counts[r])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func isHidden(r rune) bool {
+	switch {
+	case r >= 0x200B && r <= 0x200F: // zero-width space/joiners, LRM/RLM
+		return true
+	case r >= 0x202A && r <= 0x202E: // bidi embedding and override
+		return true
+	case r >= 0x2060 && r <= 0x2064: // word joiner, invisible operators
+		return true
+	case r >= 0x2066 && r <= 0x2069: // bidi isolates
+		return true
+	case r == 0xFEFF, r == 0x00AD, r == 0x180E:
+		return true
+	case r >= 0xE0000 && r <= 0xE007F: // tag characters
+		return true
+	}
+	return false
+}
+
+// base64Blob reports long base64 runs that decode to readable text. Runs that decode
+// to binary (hashes, keys, image data) are not reported.
+func base64Blob(text string) string {
+	for _, m := range base64Pattern.FindAllString(text, -1) {
+		decoded, ok := decodeBase64(m)
+		if ok && isMostlyPrintable(decoded) {
+			return "decoded: " + decoded
+		}
+	}
+	return ""
+}
+
+func decodeBase64(s string) (string, bool) {
+	trimmed := strings.TrimRight(s, "=")
+	for _, enc := range []*base64.Encoding{base64.RawStdEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(trimmed); err == nil {
+			return string(b), true
+		}
+	}
+	return "", false
+}
+
+func isMostlyPrintable(s string) bool {
+	if !utf8.ValidString(s) || len(s) == 0 {
+		return false
+	}
+	printable, total := 0, 0
+	for _, r := range s {
+		total++
+		if r == '\n' || r == '\t' || (r >= 0x20 && r != 0x7F && r != utf8.RuneError) {
+			printable++
+		}
+	}
+	return printable*10 >= total*9
+}
+
+func truncate(s string) string {
+	if len(s) <= maxExcerpt {
+		return s
+	}
+	cut := maxExcerpt
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// TraceArgs returns the argument values that appear verbatim (case-insensitively) in
+// any of the given snippets. Paths are reported as "args.field[0].sub".
+func TraceArgs(args json.RawMessage, snippets []Snippet) []types.ArgTrace {
+	if len(args) == 0 || len(snippets) == 0 {
+		return nil
+	}
+	var root any
+	if err := json.Unmarshal(args, &root); err != nil {
+		return nil
+	}
+
+	lowered := make([]string, len(snippets))
+	for i, s := range snippets {
+		lowered[i] = strings.ToLower(s.Text)
+	}
+
+	traces := make([]types.ArgTrace, 0)
+	walkStrings(root, "args", func(path, value string) {
+		value = strings.TrimSpace(value)
+		if len(value) < MinTraceLength {
+			return
+		}
+		needle := strings.ToLower(value)
+		for i, s := range snippets {
+			if strings.Contains(lowered[i], needle) {
+				traces = append(traces, types.ArgTrace{Path: path, Source: s.Source, Index: s.Index})
+			}
+		}
+	})
+	return traces
+}
+
+func walkStrings(v any, path string, fn func(path, value string)) {
+	switch val := v.(type) {
+	case string:
+		fn(path, val)
+	case map[string]any:
+		// Sorted so traces are deterministic across runs
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			walkStrings(val[k], path+"."+k, fn)
+		}
+	case []any:
+		for i, child := range val {
+			walkStrings(child, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
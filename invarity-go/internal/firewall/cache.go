@@ -0,0 +1,236 @@
+package firewall
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"invarity/internal/metrics"
+)
+
+// Resolver cache defaults.
+const (
+	DefaultResolverCacheTTL         = 30 * time.Second
+	DefaultResolverCacheNegativeTTL = 5 * time.Second
+	DefaultResolverCacheSize        = 10000
+)
+
+// Resolver cache results, as counted in the resolver_cache_requests_total metric.
+const (
+	cacheHit         = "hit"
+	cacheNegativeHit = "negative_hit"
+	cacheMiss        = "miss"
+)
+
+// ResolverCacheConfig configures a ResolverCache.
+type ResolverCacheConfig struct {
+	TTL         time.Duration    // How long a resolved tool is reused (default 30s)
+	NegativeTTL time.Duration    // How long an unknown tool is remembered (default 5s)
+	MaxEntries  int              // Least recently used entries are evicted past this (default 10000)
+	Metrics     *metrics.Metrics // Optional: hit rate and size are not recorded without it
+}
+
+// ResolverCacheStats counts a cache's lookups.
+type ResolverCacheStats struct {
+	Hits         uint64 `json:"hits"`
+	NegativeHits uint64 `json:"negative_hits"`
+	Misses       uint64 `json:"misses"`
+	Entries      int    `json:"entries"`
+}
+
+// ResolverCache caches tool resolutions by tenant, principal, tool and
+// version, sparing the store and manifest reads behind each one. Tools that
+// do not resolve (ErrToolNotFound) are cached for the shorter negative TTL;
+// other errors are not cached. Concurrent misses for the same key share one
+// resolution.
+//
+// The control plane handlers invalidate entries when tools, toolsets or
+// assignments change. Invalidation is local to the process, so other replicas
+// see a change once their entries expire. A nil *ResolverCache caches nothing.
+type ResolverCache struct {
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+	metrics     *metrics.Metrics
+
+	mu      sync.Mutex
+	entries map[resolverKey]*list.Element
+	lru     *list.List // Front is most recently used
+	gen     uint64     // Incremented by every invalidation
+	group   singleflight.Group
+
+	hits, negativeHits, misses atomic.Uint64
+}
+
+type resolverKey struct {
+	tenantID, principalID, toolID, version string
+}
+
+func (k resolverKey) String() string {
+	return k.tenantID + "\x00" + k.principalID + "\x00" + k.toolID + "\x00" + k.version
+}
+
+type resolverEntry struct {
+	key     resolverKey
+	result  *ResolveToolResult
+	err     error // Set for unknown tools
+	expires time.Time
+}
+
+// NewResolverCache creates a resolver cache.
+func NewResolverCache(cfg ResolverCacheConfig) *ResolverCache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultResolverCacheTTL
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = DefaultResolverCacheNegativeTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultResolverCacheSize
+	}
+	return &ResolverCache{
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		maxEntries:  cfg.MaxEntries,
+		metrics:     cfg.Metrics,
+		entries:     make(map[resolverKey]*list.Element),
+		lru:         list.New(),
+	}
+}
+
+// resolve returns the cached resolution for key, or calls load and caches
+// its result.
+func (c *ResolverCache) resolve(ctx context.Context, key resolverKey, load func(context.Context) (*ResolveToolResult, error)) (*ResolveToolResult, error) {
+	if c == nil {
+		return load(ctx)
+	}
+
+	c.mu.Lock()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*resolverEntry)
+		if time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			if entry.err != nil {
+				c.count(&c.negativeHits, cacheNegativeHit)
+				return nil, entry.err
+			}
+			c.count(&c.hits, cacheHit)
+			return entry.result, nil
+		}
+		c.removeLocked(el)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+	c.count(&c.misses, cacheMiss)
+
+	v, err, _ := c.group.Do(key.String(), func() (any, error) {
+		// Waiting callers share this resolution, so one caller's cancellation
+		// must not fail the others
+		result, err := load(context.WithoutCancel(ctx))
+		if err == nil || errors.Is(err, ErrToolNotFound) {
+			c.put(key, gen, result, err)
+		}
+		return result, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ResolveToolResult), nil
+}
+
+func (c *ResolverCache) count(counter *atomic.Uint64, result string) {
+	counter.Add(1)
+	c.metrics.CountResolverCache(result)
+}
+
+// put caches a resolution unless the cache was invalidated since the lookup
+// that missed began, in which case the result may already be stale.
+func (c *ResolverCache) put(key resolverKey, gen uint64, result *ResolveToolResult, err error) {
+	ttl := c.ttl
+	if err != nil {
+		ttl = c.negativeTTL
+	}
+	entry := &resolverEntry{key: key, result: result, err: err, expires: time.Now().Add(ttl)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.maxEntries {
+		c.removeLocked(c.lru.Back())
+	}
+	c.metrics.SetResolverCacheEntries(c.lru.Len())
+}
+
+func (c *ResolverCache) removeLocked(el *list.Element) {
+	delete(c.entries, el.Value.(*resolverEntry).key)
+	c.lru.Remove(el)
+}
+
+// invalidate removes the entries matching a key and stops in-flight
+// resolutions from being cached.
+func (c *ResolverCache) invalidate(match func(resolverKey) bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if match(el.Value.(*resolverEntry).key) {
+			c.removeLocked(el)
+		}
+		el = next
+	}
+	c.metrics.SetResolverCacheEntries(c.lru.Len())
+}
+
+// InvalidateTool drops every cached resolution of a tenant's tool, such as
+// after a version of it is registered.
+func (c *ResolverCache) InvalidateTool(tenantID, toolID string) {
+	c.invalidate(func(k resolverKey) bool { return k.tenantID == tenantID && k.toolID == toolID })
+}
+
+// InvalidatePrincipal drops a principal's cached resolutions, such as after a
+// toolset is applied to it.
+func (c *ResolverCache) InvalidatePrincipal(tenantID, principalID string) {
+	c.invalidate(func(k resolverKey) bool { return k.tenantID == tenantID && k.principalID == principalID })
+}
+
+// InvalidateTenant drops a tenant's cached resolutions.
+func (c *ResolverCache) InvalidateTenant(tenantID string) {
+	c.invalidate(func(k resolverKey) bool { return k.tenantID == tenantID })
+}
+
+// Purge drops every cached resolution.
+func (c *ResolverCache) Purge() {
+	c.invalidate(func(resolverKey) bool { return true })
+}
+
+// Stats returns the cache's lookup counts and size.
+func (c *ResolverCache) Stats() ResolverCacheStats {
+	if c == nil {
+		return ResolverCacheStats{}
+	}
+	c.mu.Lock()
+	entries := c.lru.Len()
+	c.mu.Unlock()
+	return ResolverCacheStats{
+		Hits:         c.hits.Load(),
+		NegativeHits: c.negativeHits.Load(),
+		Misses:       c.misses.Load(),
+		Entries:      entries,
+	}
+}
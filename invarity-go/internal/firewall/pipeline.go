@@ -3,19 +3,28 @@ package firewall
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
 	"invarity/internal/audit"
+	"invarity/internal/calibration"
 	"invarity/internal/config"
 	"invarity/internal/constraints"
+	"invarity/internal/contextscan"
 	"invarity/internal/llm"
+	"invarity/internal/metrics"
+	"invarity/internal/prompts"
 	"invarity/internal/registry"
 	"invarity/internal/store"
+	"invarity/internal/tracing"
 	"invarity/internal/types"
+	"invarity/internal/usage"
 	"invarity/internal/util"
 )
 
@@ -24,6 +33,7 @@ import (
 // S0: Canonicalize & bounds-check request
 // S1: Tool Resolution & Schema Validation (deterministic)
 // S2: Deterministic Constraints Evaluation
+// S2b: Context Scan (indirect prompt injection in bounded context)
 // S3: Intent Alignment Quorum (ALWAYS-ON)
 // S4: Threat Sentinel (conditional: risk_tier >= MEDIUM)
 // S5: Aggregate Decision (deterministic)
@@ -33,39 +43,63 @@ type Pipeline struct {
 	registryStore        registry.Store      // Legacy registry (fallback)
 	toolResolver         *ToolResolver       // New: tenant-scoped tool resolution
 	auditStore           audit.Store
+	auditRedactor        *audit.Redactor
 	schemaValidator      *registry.SchemaValidator
 	constraintsEvaluator *constraints.Evaluator
+	contextScanner       *contextscan.Scanner
 	intentQuorum         *llm.IntentQuorum
 	threatSentinel       *llm.ThreatSentinel
+	calibrator           *calibration.Calibrator
+	meter                *usage.Meter
+	metrics              *metrics.Metrics
+	tracer               trace.Tracer
+	version              string
 }
 
 // PipelineConfig holds dependencies for the pipeline.
 type PipelineConfig struct {
-	Config        *config.Config
-	Logger        *zap.Logger
-	RegistryStore registry.Store           // Legacy registry (optional, for fallback)
-	DDBStore      *store.DynamoDBStore     // DynamoDB store for tenant-scoped tools
-	S3Client      *store.S3Client          // S3 client for tool manifests
-	AuditStore    audit.Store
+	Config            *config.Config
+	Version           string // Firewall build version recorded in decision snapshots
+	Logger            *zap.Logger
+	RegistryStore     registry.Store   // Legacy registry (optional, for fallback)
+	ControlPlaneStore store.ToolReader // Control plane store or GitOps source for tenant-scoped tools
+	ManifestStore     store.BlobStore  // Blob store for tool manifests
+	AuditStore        audit.Store      // Optional: decisions are not audited without one (replays only)
 	// All LLM clients use RunPod endpoints
-	AlignmentClient *llm.Client // Intent alignment quorum
-	ThreatClient    *llm.Client // Threat sentinel
+	AlignmentClient llm.ChatCompleter // Intent alignment quorum
+	ThreatClient    llm.ChatCompleter // Threat sentinel
+	// Prompt templates for voters and sentinel (optional, defaults to embedded templates)
+	Prompts *prompts.Registry
+	// Confidence calibration for voters and sentinel (optional, raw confidence is used without it)
+	Calibrator *calibration.Calibrator
+	// Token usage accounting and quotas (optional, usage is neither priced nor recorded without it)
+	Meter *usage.Meter
+	// Tenant audit redaction policies (optional, records are stored unredacted without it)
+	AuditRedactor *audit.Redactor
+	// Tool resolution cache (optional, every call resolves against the stores without it)
+	ResolverCache *ResolverCache
+	// Prometheus metrics (optional, nothing is recorded without it)
+	Metrics *metrics.Metrics
+	// Tracer provider for evaluation, stage and voter spans (optional, no spans are recorded without it)
+	TracerProvider trace.TracerProvider
 }
 
 // NewPipeline creates a new firewall pipeline.
 func NewPipeline(cfg PipelineConfig) *Pipeline {
 	// Create intent quorum config with timeout from config
-	var intentQuorumCfg *llm.IntentQuorumConfig
+	intentQuorumCfg := llm.DefaultIntentQuorumConfig()
 	if cfg.Config.IntentModelTimeout > 0 {
-		intentQuorumCfg = &llm.IntentQuorumConfig{
-			VoterTimeout: cfg.Config.IntentModelTimeout,
-		}
+		intentQuorumCfg.VoterTimeout = cfg.Config.IntentModelTimeout
 	}
+	intentQuorumCfg.Calibrator = cfg.Calibrator
+	intentQuorumCfg.MinSafeProbability = cfg.Config.MinSafeProbability
+	intentQuorumCfg.MinDenyProbability = cfg.Config.MinDenyProbability
+	intentQuorumCfg.TracerProvider = cfg.TracerProvider
 
 	// Create tool resolver if DynamoDB store is provided
 	var toolResolver *ToolResolver
-	if cfg.DDBStore != nil {
-		toolResolver = NewToolResolver(cfg.DDBStore, cfg.S3Client)
+	if cfg.ControlPlaneStore != nil {
+		toolResolver = NewToolResolver(cfg.ControlPlaneStore, cfg.ManifestStore).WithCache(cfg.ResolverCache)
 	}
 
 	return &Pipeline{
@@ -74,10 +108,17 @@ func NewPipeline(cfg PipelineConfig) *Pipeline {
 		registryStore:        cfg.RegistryStore,
 		toolResolver:         toolResolver,
 		auditStore:           cfg.AuditStore,
+		auditRedactor:        cfg.AuditRedactor,
 		schemaValidator:      registry.NewSchemaValidator(),
 		constraintsEvaluator: constraints.NewEvaluator(),
-		intentQuorum:         llm.NewIntentQuorum(cfg.AlignmentClient, intentQuorumCfg),
-		threatSentinel:       llm.NewThreatSentinel(cfg.ThreatClient),
+		contextScanner:       contextscan.NewScanner(),
+		intentQuorum:         llm.NewIntentQuorum(cfg.AlignmentClient, cfg.Prompts, intentQuorumCfg),
+		threatSentinel:       llm.NewThreatSentinel(cfg.ThreatClient, cfg.Prompts),
+		calibrator:           cfg.Calibrator,
+		meter:                cfg.Meter,
+		metrics:              cfg.Metrics,
+		tracer:               tracing.Tracer(cfg.TracerProvider),
+		version:              cfg.Version,
 	}
 }
 
@@ -85,15 +126,22 @@ func NewPipeline(cfg PipelineConfig) *Pipeline {
 type PipelineState struct {
 	Request      *types.ToolCallRequest
 	RequestID    string
+	Started      time.Time // When evaluation began
 	Tool         *types.ToolRegistryEntry
 	RiskTier     types.RiskTier
 	Constraints  *types.ConstraintsResult
 	Alignment    *types.IntentAlignmentResult
 	Threat       *types.ThreatResult
+	ContextScan  *types.ContextScanResult
 	Timing       *types.PipelineTiming
 	Reasons      []string
 	Decision     types.Decision
-	DecisionStep string // Which step made the decision
+	DecisionStep string              // Which step made the decision
+	Resolved     *types.ToolSnapshot // Tool manifest the call resolved to (nil if it did not)
+	AuditFlags   []string            // types.AuditFlag* findings recorded with the decision
+	// Model outputs recorded for the request when it is replayed (nil for live
+	// traffic); stages with a recorded output reuse it instead of calling the model
+	Recorded *RecordedOutputs
 }
 
 // Evaluate runs the full firewall decision pipeline.
@@ -112,7 +160,31 @@ func (p *Pipeline) Evaluate(ctx context.Context, req *types.ToolCallRequest) (*t
 		state.RequestID = uuid.New().String()
 	}
 
+	return p.evaluate(ctx, state, totalStart)
+}
+
+// evaluate runs the pipeline steps for a prepared state.
+func (p *Pipeline) evaluate(ctx context.Context, state *PipelineState, totalStart time.Time) (resp *types.FirewallDecisionResponse, err error) {
+	req := state.Request
 	logger := p.logger.With(zap.String("request_id", state.RequestID))
+	state.Started = totalStart
+
+	ctx, span := p.tracer.Start(ctx, "firewall.evaluate", trace.WithAttributes(
+		attribute.String("invarity.request_id", state.RequestID),
+		attribute.String("invarity.tenant_id", tenantIDFor(req)),
+		attribute.String("invarity.action_id", req.ToolCall.ActionID),
+	))
+	defer func() {
+		span.SetAttributes(
+			attribute.String("invarity.decision", string(state.Decision)),
+			attribute.String("invarity.risk_tier", string(state.RiskTier)),
+			attribute.String("invarity.decision_step", state.DecisionStep),
+		)
+		if resp != nil {
+			span.SetAttributes(attribute.String("invarity.audit_id", resp.AuditID))
+		}
+		tracing.End(span, err)
+	}()
 
 	// S0: Canonicalize & bounds-check
 	if err := p.stepCanonicalize(ctx, state); err != nil {
@@ -135,6 +207,30 @@ func (p *Pipeline) Evaluate(ctx context.Context, req *types.ToolCallRequest) (*t
 		return p.buildDenyResponse(state, "S2_CONSTRAINTS", state.Constraints.Violations...)
 	}
 
+	// Monthly token quota (checked before the first LLM stage). A denial is
+	// audited like any other before the error is returned
+	if p.meter != nil {
+		if err := p.meter.CheckQuota(ctx, tenantIDFor(req)); err != nil {
+			var quotaErr *usage.QuotaExceededError
+			if errors.As(err, &quotaErr) {
+				resp, auditErr := p.buildDenyResponse(state, "S2_QUOTA", "token_quota_exceeded")
+				if auditErr != nil {
+					return nil, auditErr
+				}
+				quotaErr.AuditID = resp.AuditID
+			}
+			return nil, err
+		}
+	}
+
+	// S2b: Context Scan (indirect prompt injection in bounded context)
+	if p.cfg.EnableContextScan {
+		p.stepContextScan(ctx, state)
+		if contextScanDenies(state.ContextScan) {
+			return p.buildDenyResponse(state, "S2B_CONTEXT_SCAN", "args_traced_to_injected_context")
+		}
+	}
+
 	// S3: Intent Alignment Quorum (ALWAYS-ON)
 	if err := p.stepIntentAlignment(ctx, state); err != nil {
 		logger.Warn("intent alignment quorum error", zap.Error(err))
@@ -154,7 +250,8 @@ func (p *Pipeline) Evaluate(ctx context.Context, req *types.ToolCallRequest) (*t
 		if err := p.stepThreatSentinel(ctx, state); err != nil {
 			logger.Warn("threat sentinel error", zap.Error(err))
 		}
-		if state.Threat != nil && state.Threat.Label == types.ThreatMalicious {
+		if state.Threat != nil && state.Threat.Label == types.ThreatMalicious &&
+			state.Threat.CalibratedConfidence >= p.cfg.MinThreatProbability {
 			return p.buildDenyResponse(state, "S4_THREAT_SENTINEL", "threat_malicious")
 		}
 	}
@@ -168,11 +265,13 @@ func (p *Pipeline) Evaluate(ctx context.Context, req *types.ToolCallRequest) (*t
 }
 
 // S0: Canonicalize & bounds-check request
-func (p *Pipeline) stepCanonicalize(ctx context.Context, state *PipelineState) error {
+func (p *Pipeline) stepCanonicalize(ctx context.Context, state *PipelineState) (err error) {
 	start := time.Now()
 	defer func() {
 		state.Timing.Canonicalize = types.Duration(time.Since(start))
 	}()
+	_, span := p.tracer.Start(ctx, "S0_CANONICALIZE")
+	defer func() { tracing.End(span, err) }()
 
 	req := state.Request
 
@@ -214,17 +313,54 @@ func (p *Pipeline) stepCanonicalize(ctx context.Context, state *PipelineState) e
 }
 
 // S1: Tool Resolution & Schema Validation
-func (p *Pipeline) stepSchemaValidation(ctx context.Context, state *PipelineState) error {
+func (p *Pipeline) stepSchemaValidation(ctx context.Context, state *PipelineState) (err error) {
 	start := time.Now()
 	defer func() {
 		state.Timing.SchemaValidate = types.Duration(time.Since(start))
 	}()
+	ctx, span := p.tracer.Start(ctx, "S1_SCHEMA_VALIDATION")
+	defer func() {
+		if state.Resolved != nil {
+			span.SetAttributes(
+				attribute.String("invarity.tool_id", state.Resolved.ToolID),
+				attribute.String("invarity.tool_version", state.Resolved.Version),
+				attribute.String("invarity.resolved_via", state.Resolved.ResolvedVia),
+			)
+		}
+		tracing.End(span, err)
+	}()
 
 	var tool *types.ToolRegistryEntry
 
 	// Try new tenant-scoped resolution first
 	if p.toolResolver != nil && (state.Request.TenantID != "" || state.Request.OrgID != "") {
 		result, err := p.toolResolver.ResolveTool(ctx, state.Request)
+		if errors.Is(err, ErrManifestIntegrity) {
+			// The tenant registered this tool, so the legacy registry must not
+			// stand in for a manifest that fails its integrity check
+			p.logger.Error("tool manifest integrity check failed",
+				zap.Error(err),
+				zap.String("tenant_id", tenantIDFor(state.Request)),
+				zap.String("action_id", state.Request.ToolCall.ActionID),
+			)
+			state.AuditFlags = append(state.AuditFlags, types.AuditFlagManifestIntegrity)
+			state.Reasons = append(state.Reasons, "tool_manifest_integrity_failure")
+			return err
+		}
+		if errors.Is(err, ErrPrincipalNotFound) || errors.Is(err, ErrPrincipalInactive) {
+			// Nor may it stand in for a principal the tenant does not vouch for
+			p.logger.Warn("tool call rejected for its principal",
+				zap.Error(err),
+				zap.String("tenant_id", tenantIDFor(state.Request)),
+				zap.String("principal_id", state.Request.PrincipalID),
+			)
+			if errors.Is(err, ErrPrincipalInactive) {
+				state.Reasons = append(state.Reasons, "principal_inactive")
+			} else {
+				state.Reasons = append(state.Reasons, "principal_not_found")
+			}
+			return err
+		}
 		if err != nil {
 			// Log but try legacy fallback
 			p.logger.Debug("tool resolver failed, trying legacy registry",
@@ -236,6 +372,11 @@ func (p *Pipeline) stepSchemaValidation(ctx context.Context, state *PipelineStat
 			if result.ToolsetID != "" {
 				state.Reasons = append(state.Reasons, "resolved_via_toolset:"+result.ToolsetID)
 			}
+			state.Resolved = &types.ToolSnapshot{
+				ToolsetID:       result.ToolsetID,
+				ToolsetRevision: result.ToolsetRev,
+				ResolvedVia:     result.ResolvedVia,
+			}
 		}
 	}
 
@@ -253,6 +394,7 @@ func (p *Pipeline) stepSchemaValidation(ctx context.Context, state *PipelineStat
 			return fmt.Errorf("registry lookup failed: %w", err)
 		}
 		state.Reasons = append(state.Reasons, "resolved_via_legacy_registry")
+		state.Resolved = &types.ToolSnapshot{ResolvedVia: "legacy"}
 	}
 
 	if tool == nil {
@@ -260,6 +402,18 @@ func (p *Pipeline) stepSchemaValidation(ctx context.Context, state *PipelineStat
 	}
 
 	state.Tool = tool
+	state.Resolved.ToolID = tool.ActionID
+	state.Resolved.Version = tool.Version
+	state.Resolved.SchemaHash = tool.SchemaHash
+
+	// A pinned schema hash must match the manifest the call resolved to (the
+	// legacy registry checks it during lookup)
+	if pinned := state.Request.ToolCall.SchemaHash; pinned != "" && pinned != tool.SchemaHash {
+		state.AuditFlags = append(state.AuditFlags, types.AuditFlagSchemaHashMismatch)
+		state.Reasons = append(state.Reasons, "schema_hash_mismatch")
+		return fmt.Errorf("%w: %s version %s has schema hash %s, call pinned %s",
+			ErrSchemaHashMismatch, tool.ActionID, tool.Version, tool.SchemaHash, pinned)
+	}
 
 	// Check if tool is deprecated
 	if tool.Deprecated {
@@ -307,11 +461,18 @@ func (p *Pipeline) extractRiskTier(state *PipelineState) {
 }
 
 // S2: Deterministic Constraints Evaluation
-func (p *Pipeline) stepConstraintsEvaluation(ctx context.Context, state *PipelineState) error {
+func (p *Pipeline) stepConstraintsEvaluation(ctx context.Context, state *PipelineState) (err error) {
 	start := time.Now()
 	defer func() {
 		state.Timing.Constraints = types.Duration(time.Since(start))
 	}()
+	ctx, span := p.tracer.Start(ctx, "S2_CONSTRAINTS")
+	defer func() {
+		if state.Constraints != nil {
+			span.SetAttributes(attribute.Bool("invarity.constraints_passed", state.Constraints.Passed))
+		}
+		tracing.End(span, err)
+	}()
 
 	result, err := p.constraintsEvaluator.Evaluate(ctx, state.Tool, state.Request)
 	if err != nil {
@@ -331,14 +492,138 @@ func (p *Pipeline) stepConstraintsEvaluation(ctx context.Context, state *Pipelin
 	return nil
 }
 
+// S2b: Context Scan
+// Heuristics flag suspicious snippets, the sentinel classifies them, and tool call
+// arguments are traced back to any snippet the sentinel did not clear.
+func (p *Pipeline) stepContextScan(ctx context.Context, state *PipelineState) {
+	start := time.Now()
+	defer func() {
+		state.Timing.ContextScan = types.Duration(time.Since(start))
+	}()
+	ctx, span := p.tracer.Start(ctx, "S2B_CONTEXT_SCAN")
+	defer span.End()
+
+	snippets := contextscan.Snippets(state.Request.BoundedContext)
+	if len(snippets) == 0 {
+		return
+	}
+
+	result := &types.ContextScanResult{
+		Findings: p.contextScanner.Scan(state.Request.BoundedContext),
+	}
+	state.ContextScan = result
+	defer func() {
+		result.Latency = types.Duration(time.Since(start))
+	}()
+
+	if len(result.Findings) == 0 {
+		return
+	}
+
+	// Collect flagged snippets in context order
+	flaggedKeys := make(map[string]bool)
+	for _, f := range result.Findings {
+		flaggedKeys[contextscan.Snippet{Source: f.Source, Index: f.Index}.Key()] = true
+		state.Reasons = append(state.Reasons, "context_injection_heuristic:"+f.Heuristic)
+	}
+	flagged := make([]contextscan.Snippet, 0, len(flaggedKeys))
+	for _, snippet := range snippets {
+		if flaggedKeys[snippet.Key()] {
+			flagged = append(flagged, snippet)
+		}
+	}
+
+	labels := make(map[string]types.ThreatLabel)
+	if p.cfg.EnableThreatSentinel {
+		classified, err := p.classifySnippets(ctx, state, flagged)
+		if err != nil {
+			p.logger.Warn("context snippet classification error", zap.Error(err))
+			state.Reasons = append(state.Reasons, "context_scan_sentinel_error")
+			classified = &llm.SnippetResult{}
+		}
+		result.Verdicts = classified.Verdicts
+		result.Usage = classified.Usage
+		for _, v := range classified.Verdicts {
+			labels[contextscan.Snippet{Source: v.Source, Index: v.Index}.Key()] = v.Label
+			if v.Label == types.ThreatMalicious {
+				result.Injected = true
+				state.Reasons = append(state.Reasons, "context_injection_detected")
+			}
+		}
+	}
+
+	// Snippets the sentinel cleared are not traced; unclassified snippets are
+	suspect := make([]contextscan.Snippet, 0, len(flagged))
+	for _, snippet := range flagged {
+		if labels[snippet.Key()] != types.ThreatClear {
+			suspect = append(suspect, snippet)
+		}
+	}
+	result.TracedArgs = contextscan.TraceArgs(state.Request.ToolCall.Args, suspect)
+	for i := range result.TracedArgs {
+		trace := &result.TracedArgs[i]
+		trace.Label = labels[contextscan.Snippet{Source: trace.Source, Index: trace.Index}.Key()]
+	}
+	if len(result.TracedArgs) > 0 {
+		result.Injected = true
+		state.Reasons = append(state.Reasons, "args_traced_to_flagged_context")
+	}
+}
+
+// classifySnippets asks the sentinel to classify flagged snippets, or reuses the
+// recorded verdicts when the request is replayed with them.
+func (p *Pipeline) classifySnippets(ctx context.Context, state *PipelineState, flagged []contextscan.Snippet) (*llm.SnippetResult, error) {
+	if recorded := state.Recorded; recorded != nil && recorded.ContextScan != nil && len(recorded.ContextScan.Verdicts) > 0 {
+		return &llm.SnippetResult{Verdicts: recorded.ContextScan.Verdicts}, nil
+	}
+	return p.threatSentinel.ClassifySnippets(ctx, &llm.SnippetRequest{
+		TenantID:   tenantIDFor(state.Request),
+		UserIntent: state.Request.UserIntent,
+		Snippets:   flagged,
+	})
+}
+
+// contextScanDenies reports whether any argument traces to a snippet classified MALICIOUS.
+func contextScanDenies(result *types.ContextScanResult) bool {
+	if result == nil {
+		return false
+	}
+	for _, trace := range result.TracedArgs {
+		if trace.Label == types.ThreatMalicious {
+			return true
+		}
+	}
+	return false
+}
+
 // S3: Intent Alignment Quorum
-func (p *Pipeline) stepIntentAlignment(ctx context.Context, state *PipelineState) error {
+func (p *Pipeline) stepIntentAlignment(ctx context.Context, state *PipelineState) (err error) {
 	start := time.Now()
 	defer func() {
 		state.Timing.Alignment = types.Duration(time.Since(start))
 	}()
+	ctx, span := p.tracer.Start(ctx, "S3_INTENT_ALIGNMENT")
+	defer func() {
+		if state.Alignment != nil {
+			span.SetAttributes(attribute.String("invarity.intent_decision", string(state.Alignment.Decision)))
+		}
+		tracing.End(span, err)
+	}()
+
+	if recorded := state.Recorded; recorded != nil && recorded.Alignment != nil {
+		if recorded.Calibrated {
+			state.Alignment = p.intentQuorum.ReaggregateCalibrated(recorded.Alignment)
+		} else {
+			state.Alignment = p.intentQuorum.Reaggregate(recorded.Alignment)
+		}
+		for _, voter := range state.Alignment.Voters {
+			state.Reasons = append(state.Reasons, voter.Reasons...)
+		}
+		return nil
+	}
 
 	result, err := p.intentQuorum.Run(ctx, &llm.IntentQuorumRequest{
+		TenantID:    tenantIDFor(state.Request),
 		UserIntent:  state.Request.UserIntent,
 		ToolCall:    state.Request.ToolCall,
 		Tool:        state.Tool,
@@ -362,25 +647,44 @@ func (p *Pipeline) stepIntentAlignment(ctx context.Context, state *PipelineState
 }
 
 // S4: Threat Sentinel
-func (p *Pipeline) stepThreatSentinel(ctx context.Context, state *PipelineState) error {
+func (p *Pipeline) stepThreatSentinel(ctx context.Context, state *PipelineState) (err error) {
 	start := time.Now()
 	defer func() {
 		state.Timing.ThreatSentinel = types.Duration(time.Since(start))
 	}()
+	ctx, span := p.tracer.Start(ctx, "S4_THREAT_SENTINEL")
+	defer func() {
+		if state.Threat != nil {
+			span.SetAttributes(attribute.String("invarity.threat_label", string(state.Threat.Label)))
+		}
+		tracing.End(span, err)
+	}()
 
-	result, err := p.threatSentinel.Run(ctx, &llm.ThreatRequest{
-		UserIntent:  state.Request.UserIntent,
-		ToolCall:    state.Request.ToolCall,
-		Tool:        state.Tool,
-		Actor:       state.Request.Actor,
-		Environment: state.Request.Environment,
-		Context:     state.Request.BoundedContext,
-	})
-
-	if err != nil {
-		return err
+	var result *types.ThreatResult
+	recalibrate := true
+	if recorded := state.Recorded; recorded != nil && recorded.Threat != nil {
+		threat := *recorded.Threat
+		result = &threat
+		recalibrate = !recorded.Calibrated
+	} else {
+		var err error
+		result, err = p.threatSentinel.Run(ctx, &llm.ThreatRequest{
+			TenantID:    tenantIDFor(state.Request),
+			UserIntent:  state.Request.UserIntent,
+			ToolCall:    state.Request.ToolCall,
+			Tool:        state.Tool,
+			Actor:       state.Request.Actor,
+			Environment: state.Request.Environment,
+			Context:     state.Request.BoundedContext,
+		})
+		if err != nil {
+			return err
+		}
 	}
 
+	if recalibrate {
+		result.CalibratedConfidence, _ = p.calibrator.Calibrate(calibration.ThreatSentinelID, result.Model, result.Confidence)
+	}
 	state.Threat = result
 
 	if len(result.ThreatTypes) > 0 {
@@ -398,6 +702,8 @@ func (p *Pipeline) stepAggregateDecision(ctx context.Context, state *PipelineSta
 	defer func() {
 		state.Timing.Aggregate = types.Duration(time.Since(start))
 	}()
+	_, span := p.tracer.Start(ctx, "S5_AGGREGATE")
+	defer span.End()
 
 	// Check for ESCALATE signals
 	escalate := false
@@ -408,12 +714,26 @@ func (p *Pipeline) stepAggregateDecision(ctx context.Context, state *PipelineSta
 		state.Reasons = append(state.Reasons, "intent_alignment_escalate")
 	}
 
+	// Indirect prompt injection in bounded context
+	if state.ContextScan != nil && state.ContextScan.Injected {
+		escalate = true
+		state.Reasons = append(state.Reasons, "context_injection_escalate")
+	}
+
 	// Threat suspicious
 	if state.Threat != nil && state.Threat.Label == types.ThreatSuspicious {
 		escalate = true
 		state.Reasons = append(state.Reasons, "threat_suspicious")
 	}
 
+	// Threat labels the sentinel is not confident in (a low-confidence MALICIOUS
+	// reaches here instead of denying at S4)
+	if state.Threat != nil && state.Threat.Label != types.ThreatSuspicious &&
+		state.Threat.CalibratedConfidence < p.cfg.MinThreatProbability {
+		escalate = true
+		state.Reasons = append(state.Reasons, "threat_low_calibrated_confidence")
+	}
+
 	// High/Critical risk with requires_approval
 	if state.Tool != nil && state.Tool.RiskProfile.RequiresApproval {
 		if state.RiskTier == types.RiskTierHigh || state.RiskTier == types.RiskTierCritical {
@@ -434,6 +754,14 @@ func (p *Pipeline) stepAggregateDecision(ctx context.Context, state *PipelineSta
 	state.Reasons = util.DedupeStrings(state.Reasons)
 }
 
+// tenantIDFor returns the request's tenant, falling back to the deprecated org_id.
+func tenantIDFor(req *types.ToolCallRequest) string {
+	if req.TenantID != "" {
+		return req.TenantID
+	}
+	return req.OrgID
+}
+
 // shouldRunThreatSentinel determines if threat sentinel should run.
 func (p *Pipeline) shouldRunThreatSentinel(state *PipelineState) bool {
 	if !p.cfg.EnableThreatSentinel {
@@ -464,11 +792,63 @@ func (p *Pipeline) buildErrorResponse(state *PipelineState, err error, step stri
 	return p.buildResponse(state)
 }
 
+// recordMetrics records the decision, the latency of each stage and the model outputs.
+func (p *Pipeline) recordMetrics(state *PipelineState) {
+	if p.metrics == nil {
+		return
+	}
+	toolID := ""
+	if state.Tool != nil {
+		toolID = state.Tool.ActionID
+	}
+	p.metrics.ObserveTiming(state.Timing)
+	p.metrics.CountDecision(tenantIDFor(state.Request), toolID, state.Decision, state.RiskTier, time.Since(state.Started))
+	p.metrics.CountVotes(state.Alignment)
+	if state.Threat != nil {
+		p.metrics.CountThreatLabel(state.Threat.Label)
+	}
+}
+
+// accountUsage prices every model call made for the request, records it against
+// the tenant, principal and tool, and returns the total.
+func (p *Pipeline) accountUsage(ctx context.Context, state *PipelineState) *types.TokenUsage {
+	if p.meter == nil {
+		return nil
+	}
+
+	calls := make([]*types.TokenUsage, 0)
+	if state.ContextScan != nil && state.ContextScan.Usage != nil {
+		calls = append(calls, state.ContextScan.Usage)
+	}
+	if state.Alignment != nil {
+		for _, v := range state.Alignment.Voters {
+			if v.Usage != nil {
+				calls = append(calls, v.Usage)
+			}
+		}
+	}
+	if state.Threat != nil && state.Threat.Usage != nil {
+		calls = append(calls, state.Threat.Usage)
+	}
+	if len(calls) == 0 {
+		return nil
+	}
+
+	p.meter.Price(calls...)
+	total := &types.TokenUsage{}
+	for _, u := range calls {
+		total.Add(u)
+	}
+
+	req := state.Request
+	if err := p.meter.Record(ctx, tenantIDFor(req), req.PrincipalID, req.ToolCall.ActionID, calls...); err != nil {
+		p.logger.Error("failed to record token usage", zap.Error(err), zap.String("request_id", state.RequestID))
+	}
+	return total
+}
+
 // buildResponse creates the final response.
 func (p *Pipeline) buildResponse(state *PipelineState) (*types.FirewallDecisionResponse, error) {
-	// Write audit record
-	auditWriter := audit.NewWriter(p.auditStore)
-
 	resp := &types.FirewallDecisionResponse{
 		RequestID:   state.RequestID,
 		Decision:    state.Decision,
@@ -477,13 +857,34 @@ func (p *Pipeline) buildResponse(state *PipelineState) (*types.FirewallDecisionR
 		Constraints: state.Constraints,
 		Alignment:   state.Alignment,
 		Threat:      state.Threat,
+		ContextScan: state.ContextScan,
+		Usage:       p.accountUsage(context.Background(), state),
 		Timing:      state.Timing,
 		EvaluatedAt: time.Now().UTC(),
 	}
+	if state.Resolved != nil {
+		resp.ExpectedSchemaHash = state.Resolved.SchemaHash
+	}
+	p.recordMetrics(state)
+
+	if p.auditStore == nil {
+		return resp, nil
+	}
 
 	// Write audit
-	auditID, err := auditWriter.WriteFromResponse(context.Background(), state.Request, resp, state.DecisionStep)
+	auditWriter := audit.NewRedactingWriter(p.auditStore, p.auditRedactor)
+	var sensitive []types.SensitiveArg
+	if state.Tool != nil {
+		sensitive = state.Tool.SensitiveArgs
+	}
+	auditID, err := auditWriter.WriteFromResponse(context.Background(), state.Request, resp, state.DecisionStep, sensitive, p.snapshot(state), state.AuditFlags)
+	if errors.Is(err, audit.ErrBufferFull) {
+		// Fail closed: a decision must not be returned without its audit record
+		p.metrics.CountAuditWriteFailure("buffer_full")
+		return nil, err
+	}
 	if err != nil {
+		p.metrics.CountAuditWriteFailure("error")
 		p.logger.Error("failed to write audit record", zap.Error(err))
 	}
 	resp.AuditID = auditID
@@ -0,0 +1,45 @@
+package firewall
+
+import (
+	"context"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// RecordedOutputs are the model outputs recorded in an audit record. A replayed
+// request reuses them in place of the voter, sentinel and snippet-classifier
+// calls; stages without a recorded output call the pipeline's clients.
+type RecordedOutputs struct {
+	Alignment   *types.IntentAlignmentResult
+	Threat      *types.ThreatResult
+	ContextScan *types.ContextScanResult
+	// Keep the recorded calibrated confidences instead of recalibrating with the
+	// pipeline's current calibration curves
+	Calibrated bool
+}
+
+// RecordedOutputsFrom returns the model outputs recorded in an audit record.
+func RecordedOutputsFrom(record *types.AuditRecord) *RecordedOutputs {
+	return &RecordedOutputs{
+		Alignment:   record.Alignment,
+		Threat:      record.Threat,
+		ContextScan: record.ContextScan,
+	}
+}
+
+// Replay evaluates a reconstructed request. With recorded outputs, recorded
+// votes are recalibrated and re-aggregated under the pipeline's thresholds and
+// the recorded sentinel label is reused; with nil, every stage runs live.
+// Replayed decisions are audited only if the pipeline has an audit store.
+func (p *Pipeline) Replay(ctx context.Context, req *types.ToolCallRequest, recorded *RecordedOutputs) (*types.FirewallDecisionResponse, error) {
+	state := &PipelineState{
+		Request:   req,
+		RequestID: req.RequestID,
+		Timing:    &types.PipelineTiming{},
+		Reasons:   make([]string, 0),
+		RiskTier:  types.RiskTierLow,
+		Recorded:  recorded,
+	}
+	return p.evaluate(ctx, state, time.Now())
+}
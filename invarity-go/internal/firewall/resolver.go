@@ -3,26 +3,75 @@ package firewall
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 
 	"invarity/internal/store"
 	"invarity/internal/types"
+	"invarity/internal/util"
 )
 
+// ErrToolNotFound is returned when a tool does not resolve for a principal.
+var ErrToolNotFound = errors.New("tool not found")
+
+// ErrManifestIntegrity is returned when a stored tool manifest's args schema
+// does not hash to the schema hash its tool record was registered with, which
+// means the manifest was tampered with or drifted from the record.
+var ErrManifestIntegrity = errors.New("tool manifest does not match its registered schema hash")
+
+// ErrPrincipalNotFound is returned when a call names a principal that is not
+// in its tenant, including a principal of another tenant.
+var ErrPrincipalNotFound = errors.New("principal not found")
+
+// ErrPrincipalInactive is returned when a call names a principal that is
+// suspended or deleted.
+var ErrPrincipalInactive = errors.New("principal is not active")
+
+// ErrSchemaHashMismatch is returned when a tool call pins a schema hash that
+// differs from the hash of the tool it resolves to.
+var ErrSchemaHashMismatch = errors.New("schema hash mismatch")
+
 // ToolResolver resolves tools through the principal -> toolset -> tool chain.
 type ToolResolver struct {
-	ddbStore *store.DynamoDBStore
-	s3Client *store.S3Client
+	store     store.ToolReader
+	manifests store.BlobStore
+	cache     *ResolverCache
 }
 
-// NewToolResolver creates a new tool resolver.
-func NewToolResolver(ddbStore *store.DynamoDBStore, s3Client *store.S3Client) *ToolResolver {
+// ViewSource is a ToolReader whose contents are replaced as a whole, such as
+// a GitOps directory. The resolver reads each call's tools and manifests from
+// one View, so a swap cannot mix two versions.
+type ViewSource interface {
+	store.ToolReader
+	View() (store.ToolReader, store.BlobStore)
+}
+
+// NewToolResolver creates a new tool resolver. manifests may be nil, in which
+// case tools are constructed from their metadata.
+func NewToolResolver(controlPlane store.ToolReader, manifests store.BlobStore) *ToolResolver {
 	return &ToolResolver{
-		ddbStore: ddbStore,
-		s3Client: s3Client,
+		store:     controlPlane,
+		manifests: manifests,
 	}
 }
 
+// WithCache caches the resolver's resolutions in c and returns the resolver.
+func (r *ToolResolver) WithCache(c *ResolverCache) *ToolResolver {
+	r.cache = c
+	return r
+}
+
+// view returns a resolver pinned to the current view of a ViewSource.
+func (r *ToolResolver) view() *ToolResolver {
+	source, ok := r.store.(ViewSource)
+	if !ok {
+		return r
+	}
+	controlPlane, manifests := source.View()
+	return &ToolResolver{store: controlPlane, manifests: manifests}
+}
+
 // ResolveToolResult contains the result of tool resolution.
 type ResolveToolResult struct {
 	Tool          *types.ToolManifestV3
@@ -42,12 +91,17 @@ func (r *ToolResolver) ResolveTool(ctx context.Context, req *types.ToolCallReque
 		tenantID = req.OrgID // Backwards compatibility
 	}
 
-	toolID := req.ToolCall.ActionID
-	version := req.ToolCall.Version
+	key := resolverKey{tenantID: tenantID, principalID: req.PrincipalID, toolID: req.ToolCall.ActionID, version: req.ToolCall.Version}
+	return r.cache.resolve(ctx, key, func(ctx context.Context) (*ResolveToolResult, error) {
+		return r.view().resolve(ctx, tenantID, req.PrincipalID, key.toolID, key.version)
+	})
+}
 
+// resolve resolves a tool without the cache.
+func (r *ToolResolver) resolve(ctx context.Context, tenantID, principalID, toolID, version string) (*ResolveToolResult, error) {
 	// Case 1: Principal-based resolution (recommended path)
-	if req.PrincipalID != "" && tenantID != "" && r.ddbStore != nil {
-		result, err := r.resolveViaPrincipal(ctx, tenantID, req.PrincipalID, toolID, version)
+	if principalID != "" && tenantID != "" && r.store != nil {
+		result, err := r.resolveViaPrincipal(ctx, tenantID, principalID, toolID, version)
 		if err != nil {
 			return nil, err
 		}
@@ -58,7 +112,7 @@ func (r *ToolResolver) ResolveTool(ctx context.Context, req *types.ToolCallReque
 	}
 
 	// Case 2: Direct tenant lookup
-	if tenantID != "" && r.ddbStore != nil {
+	if tenantID != "" && r.store != nil {
 		result, err := r.resolveDirectFromTenant(ctx, tenantID, toolID, version)
 		if err != nil {
 			return nil, err
@@ -69,13 +123,26 @@ func (r *ToolResolver) ResolveTool(ctx context.Context, req *types.ToolCallReque
 	}
 
 	// Case 3: No resolution possible with new system
-	return nil, fmt.Errorf("tool not found: %s (tenant: %s, principal: %s)", toolID, tenantID, req.PrincipalID)
+	return nil, fmt.Errorf("%w: %s (tenant: %s, principal: %s)", ErrToolNotFound, toolID, tenantID, principalID)
 }
 
 // resolveViaPrincipal resolves a tool through the principal's active toolset.
+// The principal must be an active principal of the tenant; the IDs come from
+// the request, so neither is trusted on its own.
 func (r *ToolResolver) resolveViaPrincipal(ctx context.Context, tenantID, principalID, toolID, version string) (*ResolveToolResult, error) {
+	principal, err := r.store.GetPrincipal(ctx, tenantID, principalID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get principal: %w", err)
+	}
+	if principal == nil {
+		return nil, fmt.Errorf("%w: %s (tenant: %s)", ErrPrincipalNotFound, principalID, tenantID)
+	}
+	if principal.Status != "active" {
+		return nil, fmt.Errorf("%w: %s is %s", ErrPrincipalInactive, principalID, principal.Status)
+	}
+
 	// Get principal's active toolset
-	toolsetID, toolsetRev, err := r.ddbStore.GetPrincipalActiveToolset(ctx, principalID)
+	toolsetID, toolsetRev, err := r.store.GetPrincipalActiveToolset(ctx, tenantID, principalID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get principal active toolset: %w", err)
 	}
@@ -84,23 +151,23 @@ func (r *ToolResolver) resolveViaPrincipal(ctx context.Context, tenantID, princi
 		return nil, nil
 	}
 
-	// Get toolset manifest from S3
+	// Get toolset manifest
 	s3Key := store.ToolsetManifestKey(tenantID, toolsetID, toolsetRev)
 	var toolsetManifest types.ToolsetManifest
 
-	if r.s3Client != nil {
-		if err := r.s3Client.GetJSON(ctx, s3Key, &toolsetManifest); err != nil {
+	if r.manifests != nil {
+		if err := r.manifests.GetJSON(ctx, s3Key, &toolsetManifest); err != nil {
 			return nil, fmt.Errorf("failed to load toolset manifest: %w", err)
 		}
 	} else {
-		// No S3 client, fall back to checking if tool exists in DynamoDB
+		// No manifest store, fall back to checking if tool exists in the control plane store
 		// This is a degraded mode without full toolset validation
 		return nil, nil
 	}
 
-	// Find the tool in the toolset
+	// Find the tool in the toolset's locked versions
 	var matchedRef *types.ToolRef
-	for _, ref := range toolsetManifest.Tools {
+	for _, ref := range toolsetManifest.ResolvedTools() {
 		if ref.ToolID == toolID {
 			// If version specified, must match
 			if version != "" && ref.Version != version {
@@ -112,7 +179,7 @@ func (r *ToolResolver) resolveViaPrincipal(ctx context.Context, tenantID, princi
 	}
 
 	if matchedRef == nil {
-		return nil, fmt.Errorf("tool %s not found in principal's active toolset %s", toolID, toolsetID)
+		return nil, fmt.Errorf("%w: %s is not in principal's active toolset %s", ErrToolNotFound, toolID, toolsetID)
 	}
 
 	// Load the tool manifest
@@ -131,11 +198,16 @@ func (r *ToolResolver) resolveViaPrincipal(ctx context.Context, tenantID, princi
 
 // resolveDirectFromTenant resolves a tool directly from the tenant's tool library.
 func (r *ToolResolver) resolveDirectFromTenant(ctx context.Context, tenantID, toolID, version string) (*ResolveToolResult, error) {
-	// If no version specified, we need to find the latest
+	// If no version specified, use the latest non-deprecated release
 	if version == "" {
-		// For now, require explicit version in direct lookup
-		// TODO: Implement latest version lookup via DynamoDB query
-		return nil, fmt.Errorf("version required for direct tool lookup")
+		latest, err := LatestToolVersion(ctx, r.store, tenantID, toolID, "")
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil {
+			return nil, nil
+		}
+		version = latest.Version
 	}
 
 	tool, err := r.loadToolManifest(ctx, tenantID, toolID, version)
@@ -152,10 +224,45 @@ func (r *ToolResolver) resolveDirectFromTenant(ctx context.Context, tenantID, to
 	}, nil
 }
 
-// loadToolManifest loads a tool manifest from S3 or DynamoDB metadata.
+// LoadToolset loads every tool manifest a registered toolset revision references.
+func (r *ToolResolver) LoadToolset(ctx context.Context, tenantID, toolsetID, revision string) ([]*types.ToolManifestV3, error) {
+	r = r.view()
+	if r.manifests == nil {
+		return nil, fmt.Errorf("toolset manifests are not available without a manifest store")
+	}
+
+	var toolsetManifest types.ToolsetManifest
+	if err := r.manifests.GetJSON(ctx, store.ToolsetManifestKey(tenantID, toolsetID, revision), &toolsetManifest); err != nil {
+		return nil, fmt.Errorf("failed to load toolset manifest: %w", err)
+	}
+
+	refs := toolsetManifest.ResolvedTools()
+	tools := make([]*types.ToolManifestV3, 0, len(refs))
+	for _, ref := range refs {
+		tool, err := r.loadToolManifest(ctx, tenantID, ref.ToolID, ref.Version)
+		if err != nil {
+			return nil, err
+		}
+		if tool == nil {
+			return nil, fmt.Errorf("toolset %s@%s references missing tool %s@%s", toolsetID, revision, ref.ToolID, ref.Version)
+		}
+		tools = append(tools, tool)
+	}
+	return tools, nil
+}
+
+// LoadTool loads one version of a tool from the tenant's library. It returns
+// nil if that version is not registered.
+func (r *ToolResolver) LoadTool(ctx context.Context, tenantID, toolID, version string) (*types.ToolManifestV3, error) {
+	return r.view().loadToolManifest(ctx, tenantID, toolID, version)
+}
+
+// loadToolManifest loads a tool manifest from the manifest store, or constructs
+// it from the tool's metadata when there is no manifest store. A registered
+// tool whose manifest is missing or unreadable fails the integrity check.
 func (r *ToolResolver) loadToolManifest(ctx context.Context, tenantID, toolID, version string) (*types.ToolManifestV3, error) {
-	// First check if tool exists in DynamoDB
-	record, err := r.ddbStore.GetToolRecord(ctx, tenantID, toolID, version)
+	// First check if tool exists in the control plane store
+	record, err := r.store.GetToolRecord(ctx, tenantID, toolID, version)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tool record: %w", err)
 	}
@@ -163,22 +270,38 @@ func (r *ToolResolver) loadToolManifest(ctx context.Context, tenantID, toolID, v
 		return nil, nil
 	}
 
-	// Try to load full manifest from S3
-	if r.s3Client != nil {
+	// Try to load full manifest
+	if r.manifests != nil {
+		data, err := r.manifests.GetRaw(ctx, record.S3Key)
+		if errors.Is(err, store.ErrBlobNotFound) {
+			return nil, fmt.Errorf("%w: %s version %s (manifest %s is missing)", ErrManifestIntegrity, toolID, version, record.S3Key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tool manifest: %w", err)
+		}
 		var manifest types.ToolManifestV3
-		if err := r.s3Client.GetJSON(ctx, record.S3Key, &manifest); err != nil {
-			// Fall back to constructing from metadata
-			return r.constructManifestFromRecord(record), nil
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("%w: %s version %s (manifest %s is not valid JSON)", ErrManifestIntegrity, toolID, version, record.S3Key)
+		}
+		// Re-hash the schema rather than trusting the manifest's own hash
+		hash, err := util.HashJSON(manifest.ArgsSchema)
+		if err != nil || hash != record.SchemaHash || manifest.ToolID != toolID || manifest.Version != record.Version {
+			return nil, fmt.Errorf("%w: %s version %s (manifest %s)", ErrManifestIntegrity, toolID, version, record.S3Key)
+		}
+		manifest.SchemaHash = hash
+		// A version deprecated after registration keeps its original manifest
+		if record.Status == "deprecated" {
+			manifest.Deprecated = true
 		}
 		return &manifest, nil
 	}
 
-	// No S3, construct from DynamoDB metadata
+	// No manifest store, construct from metadata
 	return r.constructManifestFromRecord(record), nil
 }
 
-// constructManifestFromRecord creates a minimal manifest from DynamoDB record.
-// This is a fallback when S3 is unavailable.
+// constructManifestFromRecord creates a minimal manifest from a tool record.
+// This is a fallback when no manifest store is configured.
 func (r *ToolResolver) constructManifestFromRecord(record *store.ToolRecord) *types.ToolManifestV3 {
 	return &types.ToolManifestV3{
 		SchemaVersion: "3",
@@ -189,6 +312,7 @@ func (r *ToolResolver) constructManifestFromRecord(record *store.ToolRecord) *ty
 		RiskProfile: types.RiskProfileV3{
 			BaseRiskLevel: record.RiskLevel,
 		},
+		Deprecated: record.Status == "deprecated",
 	}
 }
 
@@ -0,0 +1,66 @@
+package firewall
+
+import (
+	"time"
+
+	"invarity/internal/config"
+	"invarity/internal/llm"
+	"invarity/internal/types"
+)
+
+// snapshot records the artifacts and configuration behind the state's decision.
+func (p *Pipeline) snapshot(state *PipelineState) *types.DecisionSnapshot {
+	snapshot := &types.DecisionSnapshot{
+		FirewallVersion: p.version,
+		Tool:            state.Resolved,
+		Config:          SnapshotConfig(p.cfg),
+	}
+	if state.Alignment != nil {
+		for _, voter := range state.Alignment.Voters {
+			snapshot.Voters = append(snapshot.Voters, types.ModelSnapshot{
+				VoterID:       voter.VoterID,
+				Model:         voter.Model,
+				PromptVersion: voter.PromptVersion,
+			})
+		}
+	}
+	if state.Threat != nil {
+		snapshot.Sentinel = &types.ModelSnapshot{
+			Model:         state.Threat.Model,
+			PromptVersion: state.Threat.PromptVersion,
+		}
+	}
+	return snapshot
+}
+
+// SnapshotConfig returns the configuration values that affect a decision.
+func SnapshotConfig(cfg *config.Config) types.ConfigSnapshot {
+	timeout := cfg.IntentModelTimeout
+	if timeout <= 0 {
+		timeout = llm.DefaultIntentQuorumConfig().VoterTimeout
+	}
+	return types.ConfigSnapshot{
+		EnableThreatSentinel: cfg.EnableThreatSentinel,
+		EnableContextScan:    cfg.EnableContextScan,
+		QuorumTimeout:        types.Duration(timeout),
+		MinSafeProbability:   cfg.MinSafeProbability,
+		MinDenyProbability:   cfg.MinDenyProbability,
+		MinThreatProbability: cfg.MinThreatProbability,
+		MaxIntentChars:       cfg.MaxIntentChars,
+		MaxContextChars:      cfg.MaxContextChars,
+	}
+}
+
+// ApplySnapshotConfig returns a copy of cfg with a snapshot's values restored.
+func ApplySnapshotConfig(cfg *config.Config, snapshot types.ConfigSnapshot) *config.Config {
+	restored := *cfg
+	restored.EnableThreatSentinel = snapshot.EnableThreatSentinel
+	restored.EnableContextScan = snapshot.EnableContextScan
+	restored.IntentModelTimeout = time.Duration(snapshot.QuorumTimeout)
+	restored.MinSafeProbability = snapshot.MinSafeProbability
+	restored.MinDenyProbability = snapshot.MinDenyProbability
+	restored.MinThreatProbability = snapshot.MinThreatProbability
+	restored.MaxIntentChars = snapshot.MaxIntentChars
+	restored.MaxContextChars = snapshot.MaxContextChars
+	return &restored
+}
@@ -0,0 +1,90 @@
+package firewall
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"invarity/internal/semver"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// LatestToolVersion returns the highest version of a tool in a semver range,
+// or in any range if constraint is empty. Deprecated versions, prereleases and
+// versions that are not semantic versions are skipped. It returns nil if no
+// version qualifies.
+func LatestToolVersion(ctx context.Context, reader store.ToolReader, tenantID, toolID, constraint string) (*store.ToolRecord, error) {
+	var r *semver.Range
+	if constraint != "" {
+		parsed, err := semver.ParseRange(constraint)
+		if err != nil {
+			return nil, err
+		}
+		r = &parsed
+	}
+
+	records, err := reader.ListToolVersions(ctx, tenantID, toolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool versions: %w", err)
+	}
+
+	var latest *store.ToolRecord
+	var latestVersion semver.Version
+	for i := range records {
+		if records[i].Status == "deprecated" {
+			continue
+		}
+		v, err := semver.Parse(records[i].Version)
+		if err != nil || v.IsPrerelease() {
+			continue
+		}
+		if r != nil && !r.Contains(v) {
+			continue
+		}
+		if latest == nil || semver.Compare(v, latestVersion) > 0 {
+			latest, latestVersion = &records[i], v
+		}
+	}
+	return latest, nil
+}
+
+// LockToolset resolves each of a toolset's references, exact versions and
+// semver ranges alike, and records the result in its lock. A range resolves
+// to the latest version it matches. References that do not resolve are
+// returned joined, each wrapping ErrToolNotFound.
+func LockToolset(ctx context.Context, reader store.ToolReader, tenantID string, manifest *types.ToolsetManifest) error {
+	lock := make([]types.LockedToolRef, 0, len(manifest.Tools))
+	var missing []error
+	for _, ref := range manifest.Tools {
+		var record *store.ToolRecord
+		var err error
+		if semver.IsRange(ref.Version) {
+			record, err = LatestToolVersion(ctx, reader, tenantID, ref.ToolID, ref.Version)
+		} else {
+			record, err = reader.GetToolRecord(ctx, tenantID, ref.ToolID, ref.Version)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve tool %s version %s: %w", ref.ToolID, ref.Version, err)
+		}
+		if record == nil {
+			if semver.IsRange(ref.Version) {
+				missing = append(missing, fmt.Errorf("%w: no version of %s matches %s", ErrToolNotFound, ref.ToolID, ref.Version))
+			} else {
+				missing = append(missing, fmt.Errorf("%w: %s version %s", ErrToolNotFound, ref.ToolID, ref.Version))
+			}
+			continue
+		}
+		lock = append(lock, types.LockedToolRef{
+			ToolID:     ref.ToolID,
+			Constraint: ref.Version,
+			Version:    record.Version,
+			SchemaHash: record.SchemaHash,
+		})
+	}
+	if len(missing) > 0 {
+		return errors.Join(missing...)
+	}
+	manifest.Lock = lock
+	return nil
+}
@@ -0,0 +1,377 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"invarity/internal/types"
+)
+
+// The parsing rules below mirror the CLI's validate package, so a tree that
+// `invarity tools validate` and `invarity toolsets lint` accept loads the same
+// way here. Both are tested against the CLI's examples in
+// test/testdata/manifest_parity.json; keep them in step.
+
+// ParseFile parses a YAML (.yaml, .yml) or JSON (.json) manifest into a map.
+func ParseFile(filePath string) (map[string]any, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(filePath))
+	var obj map[string]any
+
+	switch ext {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+		converted, _ := convertYAMLToJSON(obj).(map[string]any)
+		obj = converted
+	case ".json":
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported file extension: %s", ext)
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("empty manifest")
+	}
+
+	return obj, nil
+}
+
+// convertYAMLToJSON converts YAML-parsed data to JSON-compatible structures.
+func convertYAMLToJSON(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		result := make(map[string]any)
+		for k, v := range val {
+			result[k] = convertYAMLToJSON(v)
+		}
+		return result
+	case map[any]any:
+		result := make(map[string]any)
+		for k, v := range val {
+			result[fmt.Sprintf("%v", k)] = convertYAMLToJSON(v)
+		}
+		return result
+	case []any:
+		result := make([]any, len(val))
+		for i, v := range val {
+			result[i] = convertYAMLToJSON(v)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+// findManifestFiles finds all manifest files (*.json, *.yaml, *.yml) in a
+// directory recursively. A missing directory has none.
+func findManifestFiles(dir string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == dir && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext == ".json" || ext == ".yaml" || ext == ".yml" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk directory: %w", err)
+	}
+
+	return files, nil
+}
+
+// ParsedTool is a tool file with its invarity metadata.
+type ParsedTool struct {
+	FilePath string
+	Name     string
+	ID       string
+	Version  string
+	Raw      map[string]any
+}
+
+// ParseToolWithMetadata parses a tool file and extracts its invarity metadata.
+func ParseToolWithMetadata(filePath string) (*ParsedTool, error) {
+	data, err := ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	tool := &ParsedTool{
+		FilePath: filePath,
+		Raw:      data,
+	}
+
+	if name, ok := data["name"].(string); ok {
+		tool.Name = name
+	}
+
+	if invarity, ok := data["invarity"].(map[string]any); ok {
+		if id, ok := invarity["id"].(string); ok {
+			tool.ID = id
+		}
+		if version, ok := invarity["version"].(string); ok {
+			tool.Version = version
+		}
+	}
+
+	return tool, nil
+}
+
+// toolDocument is the rest of a tool file: the provider-facing args schema
+// and the invarity risk and constraints blocks.
+type toolDocument struct {
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`   // OpenAI style
+	InputSchema json.RawMessage `json:"input_schema"` // Claude style
+	Invarity    struct {
+		Risk struct {
+			BaseRisk            string   `json:"base_risk"`
+			Operation           string   `json:"operation"`
+			RequiresHumanReview bool     `json:"requires_human_review"`
+			Tags                []string `json:"tags"`
+		} `json:"risk"`
+		Constraints struct {
+			RequiresJustification bool     `json:"requires_justification"`
+			RequiredArgs          []string `json:"required_args"`
+			DisallowWildcards     bool     `json:"disallow_wildcards"`
+			MaxBulk               *int     `json:"max_bulk"`
+			AmountLimit           *struct {
+				Max      float64 `json:"max"`
+				Currency string  `json:"currency"`
+				ArgKey   string  `json:"arg_key"`
+			} `json:"amount_limit"`
+		} `json:"constraints"`
+	} `json:"invarity"`
+}
+
+// Manifest converts the tool to the v3 manifest the registration API stores.
+// The schema hash is computed from the args schema as the API does; a CLI
+// invarity.schema_hash (a hash of the invarity block) is not used.
+func (t *ParsedTool) Manifest() (*types.ToolManifestV3, error) {
+	data, err := json.Marshal(t.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal tool: %w", err)
+	}
+	var doc toolDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid tool: %w", err)
+	}
+
+	argsSchema := doc.Parameters
+	if len(argsSchema) == 0 || string(argsSchema) == "null" {
+		argsSchema = doc.InputSchema
+	}
+	if string(argsSchema) == "null" {
+		argsSchema = nil
+	}
+
+	risk := doc.Invarity.Risk
+	constraints := doc.Invarity.Constraints
+	manifest := &types.ToolManifestV3{
+		SchemaVersion: "3",
+		ToolID:        t.ID,
+		Version:       t.Version,
+		Name:          t.Name,
+		Description:   doc.Description,
+		ArgsSchema:    argsSchema,
+		Constraints: types.ToolConstraintsV3{
+			MaxBatchSize:          constraints.MaxBulk,
+			RequiredArgs:          constraints.RequiredArgs,
+			DisallowWildcards:     constraints.DisallowWildcards,
+			RequiresJustification: constraints.RequiresJustification,
+		},
+		RiskProfile: types.RiskProfileV3{
+			BaseRiskLevel:    strings.ToUpper(risk.BaseRisk),
+			RequiresApproval: risk.RequiresHumanReview,
+		},
+		Tags: risk.Tags,
+	}
+	if limit := constraints.AmountLimit; limit != nil {
+		maxAmount := limit.Max
+		manifest.Constraints.MaxAmount = &maxAmount
+		manifest.Constraints.AmountLimit = &maxAmount
+	}
+	if risk.Operation != "" {
+		manifest.Metadata = map[string]any{"operation": strings.ToLower(risk.Operation)}
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	hash, err := schemaHash(manifest.ArgsSchema)
+	if err != nil {
+		return nil, err
+	}
+	manifest.SchemaHash = hash
+	return manifest, nil
+}
+
+// ToolRef is a tool reference in a toolset.
+type ToolRef struct {
+	ID      string
+	Version string
+}
+
+// Toolset is a parsed toolset file.
+type Toolset struct {
+	FilePath    string
+	ToolsetID   string
+	Revision    string
+	DisplayName string
+	Description string
+	Envs        []string
+	Status      string
+	Tools       []ToolRef
+	Labels      map[string]string
+	Raw         map[string]any
+}
+
+// ParseToolsetWithMetadata parses a toolset file and extracts its metadata.
+func ParseToolsetWithMetadata(filePath string) (*Toolset, error) {
+	data, err := ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	toolset := &Toolset{FilePath: filePath, Raw: data}
+
+	if id, ok := data["toolset_id"].(string); ok {
+		toolset.ToolsetID = id
+	}
+	if rev, ok := data["revision"].(string); ok {
+		toolset.Revision = rev
+	}
+	if displayName, ok := data["display_name"].(string); ok {
+		toolset.DisplayName = displayName
+	}
+	if desc, ok := data["description"].(string); ok {
+		toolset.Description = desc
+	}
+	if status, ok := data["status"].(string); ok {
+		toolset.Status = status
+	}
+
+	if envs, ok := data["envs"].([]any); ok {
+		for _, e := range envs {
+			if s, ok := e.(string); ok {
+				toolset.Envs = append(toolset.Envs, s)
+			}
+		}
+	}
+
+	if tools, ok := data["tools"].([]any); ok {
+		for _, t := range tools {
+			if tm, ok := t.(map[string]any); ok {
+				ref := ToolRef{}
+				// Support both "tool_id" (new schema) and "id" (old schema)
+				if id, ok := tm["tool_id"].(string); ok {
+					ref.ID = id
+				} else if id, ok := tm["id"].(string); ok {
+					ref.ID = id
+				}
+				if ver, ok := tm["version"].(string); ok {
+					ref.Version = ver
+				}
+				toolset.Tools = append(toolset.Tools, ref)
+			}
+		}
+	}
+
+	if labels, ok := data["labels"].(map[string]any); ok {
+		toolset.Labels = make(map[string]string)
+		for k, v := range labels {
+			if s, ok := v.(string); ok {
+				toolset.Labels[k] = s
+			}
+		}
+	}
+
+	return toolset, nil
+}
+
+// Manifest converts the toolset to the manifest the registration API stores.
+func (t *Toolset) Manifest() (*types.ToolsetManifest, error) {
+	manifest := &types.ToolsetManifest{
+		ToolsetID:   t.ToolsetID,
+		Revision:    t.Revision,
+		Name:        t.DisplayName,
+		Description: t.Description,
+		CreatedBy:   "gitops",
+	}
+	for _, ref := range t.Tools {
+		manifest.Tools = append(manifest.Tools, types.ToolRef{ToolID: ref.ID, Version: ref.Version})
+	}
+	if len(t.Labels) > 0 || len(t.Envs) > 0 {
+		manifest.Metadata = make(map[string]any)
+		if len(t.Labels) > 0 {
+			manifest.Metadata["labels"] = t.Labels
+		}
+		if len(t.Envs) > 0 {
+			manifest.Metadata["envs"] = t.Envs
+		}
+	}
+
+	if err := manifest.Validate(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Assignment assigns a toolset revision to a principal.
+type Assignment struct {
+	PrincipalID string `json:"principal_id"`
+	ToolsetID   string `json:"toolset_id"`
+	Revision    string `json:"revision"`
+}
+
+// ParseAssignments parses an assignments file: an "assignments" list of
+// principal_id, toolset_id and revision.
+func ParseAssignments(filePath string) ([]Assignment, error) {
+	data, err := ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var assignments []Assignment
+	entries, _ := data["assignments"].([]any)
+	for i, e := range entries {
+		em, ok := e.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("assignments[%d] must be an object", i)
+		}
+		a := Assignment{}
+		a.PrincipalID, _ = em["principal_id"].(string)
+		a.ToolsetID, _ = em["toolset_id"].(string)
+		a.Revision, _ = em["revision"].(string)
+		if a.PrincipalID == "" || a.ToolsetID == "" || a.Revision == "" {
+			return nil, fmt.Errorf("assignments[%d] requires principal_id, toolset_id and revision strings", i)
+		}
+		assignments = append(assignments, a)
+	}
+
+	return assignments, nil
+}
@@ -0,0 +1,402 @@
+// Package gitops loads tenants' tool libraries, toolsets and principal
+// assignments from a directory tree kept in git, and serves them to the tool
+// resolver in place of the control plane store.
+//
+// The tree holds one directory per tenant:
+//
+//	<dir>/<tenant_id>/tools/**/*.yaml       tool files, as validated by the CLI
+//	<dir>/<tenant_id>/toolsets/**/*.yaml    toolset files
+//	<dir>/<tenant_id>/assignments.yaml      principal -> toolset revision
+//
+// JSON (.json) and .yml files are accepted too. Top-level entries starting
+// with a dot, such as .git, are ignored.
+package gitops
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"invarity/internal/firewall"
+	"invarity/internal/store"
+	"invarity/internal/util"
+)
+
+// ErrReadOnly is returned when writing to a GitOps source; changes go
+// through git.
+var ErrReadOnly = errors.New("gitops source is read-only")
+
+// assignmentFiles are the names tried, in order, for a tenant's assignments.
+var assignmentFiles = []string{"assignments.yaml", "assignments.yml", "assignments.json"}
+
+// Stats counts what a snapshot holds.
+type Stats struct {
+	Tenants     int `json:"tenants"`
+	Tools       int `json:"tools"`
+	Toolsets    int `json:"toolsets"`
+	Assignments int `json:"assignments"`
+}
+
+// Snapshot is an immutable, validated view of a directory tree.
+type Snapshot struct {
+	Digest   string // SHA-256 of the tree's manifest files
+	LoadedAt time.Time
+	Stats    Stats
+
+	tools       map[string]*store.ToolRecord // by tool manifest key
+	assignments map[string]Assignment        // by tenant ID/principal ID
+	blobs       map[string][]byte            // manifest JSON by key
+}
+
+// Load reads and validates the tree at dir with the rules the registration
+// API applies: every tool and toolset must validate, tool versions and
+// toolset revisions must be unique within a tenant, toolsets may only
+// reference the tenant's tools, and assignments may only reference its
+// toolsets. Every problem found is returned, joined.
+func Load(dir string) (*Snapshot, error) {
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open gitops directory: %w", err)
+	}
+	digest, err := digestTree(root)
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := tenantDirs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	createdAt := now.Format(time.RFC3339)
+	snap := &Snapshot{
+		Digest:      digest,
+		LoadedAt:    now,
+		tools:       make(map[string]*store.ToolRecord),
+		assignments: make(map[string]Assignment),
+		blobs:       make(map[string][]byte),
+	}
+	var errs []error
+	fail := func(path string, err error) {
+		rel, relErr := filepath.Rel(root, path)
+		if relErr != nil {
+			rel = path
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", filepath.ToSlash(rel), err))
+	}
+	assigned := make(map[string]string) // principal ID -> tenant ID
+
+	for _, tenantID := range tenants {
+		tenantDir := filepath.Join(root, tenantID)
+		snap.Stats.Tenants++
+
+		toolFiles, err := findManifestFiles(filepath.Join(tenantDir, "tools"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range toolFiles {
+			parsed, err := ParseToolWithMetadata(path)
+			if err != nil {
+				fail(path, err)
+				continue
+			}
+			manifest, err := parsed.Manifest()
+			if err != nil {
+				fail(path, err)
+				continue
+			}
+			key := store.ToolManifestKey(tenantID, manifest.ToolID, manifest.Version)
+			if _, ok := snap.tools[key]; ok {
+				fail(path, fmt.Errorf("tool %s version %s is defined more than once", manifest.ToolID, manifest.Version))
+				continue
+			}
+			manifest.CreatedAt, manifest.UpdatedAt = now, now
+			if err := snap.put(key, manifest); err != nil {
+				return nil, err
+			}
+			snap.tools[key] = &store.ToolRecord{
+				TenantID:   tenantID,
+				SK:         manifest.ToolID + "#" + manifest.Version,
+				ToolID:     manifest.ToolID,
+				Version:    manifest.Version,
+				SchemaHash: manifest.SchemaHash,
+				Name:       manifest.Name,
+				RiskLevel:  riskLevel(manifest.RiskProfile.BaseRiskLevel),
+				S3Key:      key,
+				Status:     "active",
+				CreatedAt:  createdAt,
+				UpdatedAt:  createdAt,
+			}
+			snap.Stats.Tools++
+		}
+
+		toolsetFiles, err := findManifestFiles(filepath.Join(tenantDir, "toolsets"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range toolsetFiles {
+			parsed, err := ParseToolsetWithMetadata(path)
+			if err != nil {
+				fail(path, err)
+				continue
+			}
+			manifest, err := parsed.Manifest()
+			if err != nil {
+				fail(path, err)
+				continue
+			}
+			key := store.ToolsetManifestKey(tenantID, manifest.ToolsetID, manifest.Revision)
+			if _, ok := snap.blobs[key]; ok {
+				fail(path, fmt.Errorf("toolset %s revision %s is defined more than once", manifest.ToolsetID, manifest.Revision))
+				continue
+			}
+			// Ranges resolve against the tenant's tools in this tree, as
+			// registration resolves them against its library
+			if err := firewall.LockToolset(context.Background(), snap, tenantID, manifest); err != nil {
+				if joined, ok := err.(interface{ Unwrap() []error }); ok {
+					for _, err := range joined.Unwrap() {
+						fail(path, err)
+					}
+				} else {
+					fail(path, err)
+				}
+				continue
+			}
+			manifest.CreatedAt, manifest.UpdatedAt = now, now
+			if err := snap.put(key, manifest); err != nil {
+				return nil, err
+			}
+			snap.Stats.Toolsets++
+		}
+
+		path, ok := findAssignments(tenantDir)
+		if !ok {
+			continue
+		}
+		assignments, err := ParseAssignments(path)
+		if err != nil {
+			fail(path, err)
+			continue
+		}
+		for _, a := range assignments {
+			if other, ok := assigned[a.PrincipalID]; ok {
+				if other == tenantID {
+					fail(path, fmt.Errorf("principal %s is assigned more than once", a.PrincipalID))
+				} else {
+					fail(path, fmt.Errorf("principal %s is also assigned in tenant %s", a.PrincipalID, other))
+				}
+				continue
+			}
+			assigned[a.PrincipalID] = tenantID
+			if _, ok := snap.blobs[store.ToolsetManifestKey(tenantID, a.ToolsetID, a.Revision)]; !ok {
+				fail(path, fmt.Errorf("toolset not found: %s revision %s", a.ToolsetID, a.Revision))
+				continue
+			}
+			snap.assignments[tenantID+"/"+a.PrincipalID] = a
+			snap.Stats.Assignments++
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return snap, nil
+}
+
+// put stores a manifest as JSON under key.
+func (s *Snapshot) put(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", key, err)
+	}
+	s.blobs[key] = data
+	return nil
+}
+
+// riskLevel defaults an unset risk level to LOW, as tool registration does.
+func riskLevel(level string) string {
+	if level == "" {
+		return "LOW"
+	}
+	return level
+}
+
+// schemaHash computes a tool's schema hash from its args schema, as tool
+// registration does.
+func schemaHash(argsSchema json.RawMessage) (string, error) {
+	canonical, err := util.CanonicalJSON(argsSchema)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize args_schema: %w", err)
+	}
+	hash := sha256.Sum256(canonical)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// tenantDirs lists the tenant directories under root.
+func tenantDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gitops directory: %w", err)
+	}
+	tenants := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(root, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gitops directory: %w", err)
+		}
+		if info.IsDir() {
+			tenants = append(tenants, entry.Name())
+		}
+	}
+	return tenants, nil
+}
+
+// findAssignments returns the tenant's assignments file, if it has one.
+func findAssignments(tenantDir string) (string, bool) {
+	for _, name := range assignmentFiles {
+		path := filepath.Join(tenantDir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// digestTree hashes the paths and contents of the manifest files Load reads,
+// so a changed tree can be detected without parsing it.
+func digestTree(root string) (string, error) {
+	tenants, err := tenantDirs(root)
+	if err != nil {
+		return "", err
+	}
+
+	var files []string
+	for _, tenantID := range tenants {
+		tenantDir := filepath.Join(root, tenantID)
+		for _, sub := range []string{"tools", "toolsets"} {
+			found, err := findManifestFiles(filepath.Join(tenantDir, sub))
+			if err != nil {
+				return "", err
+			}
+			files = append(files, found...)
+		}
+		if path, ok := findAssignments(tenantDir); ok {
+			files = append(files, path)
+		}
+	}
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		rel, _ := filepath.Rel(root, path)
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(rel), len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetToolRecord returns a tool version, or nil if the tree does not define it.
+func (s *Snapshot) GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*store.ToolRecord, error) {
+	record, ok := s.tools[store.ToolManifestKey(tenantID, toolID, version)]
+	if !ok {
+		return nil, nil
+	}
+	copied := *record
+	return &copied, nil
+}
+
+// ListToolVersions lists every version of a tool the tree defines.
+func (s *Snapshot) ListToolVersions(ctx context.Context, tenantID, toolID string) ([]store.ToolRecord, error) {
+	tools := make([]store.ToolRecord, 0)
+	for _, record := range s.tools {
+		if record.TenantID == tenantID && record.ToolID == toolID {
+			tools = append(tools, *record)
+		}
+	}
+	return tools, nil
+}
+
+// GetPrincipal returns a principal the tenant's assignments name, which is
+// always active, or nil if they do not name it. The tree defines principals
+// only through their assignments.
+func (s *Snapshot) GetPrincipal(ctx context.Context, tenantID, principalID string) (*store.Principal, error) {
+	if _, ok := s.assignments[tenantID+"/"+principalID]; !ok {
+		return nil, nil
+	}
+	return &store.Principal{
+		PrincipalID: principalID,
+		TenantID:    tenantID,
+		Name:        principalID,
+		Type:        "agent",
+		Status:      "active",
+		CreatedAt:   s.LoadedAt,
+		UpdatedAt:   s.LoadedAt,
+	}, nil
+}
+
+// GetPrincipalActiveToolset returns the toolset revision assigned to a
+// tenant's principal, or empty strings if none is.
+func (s *Snapshot) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (string, string, error) {
+	a, ok := s.assignments[tenantID+"/"+principalID]
+	if !ok {
+		return "", "", nil
+	}
+	return a.ToolsetID, a.Revision, nil
+}
+
+// Ping always succeeds; a snapshot is in memory.
+func (s *Snapshot) Ping(ctx context.Context) error {
+	return nil
+}
+
+// PutJSON fails with ErrReadOnly.
+func (s *Snapshot) PutJSON(ctx context.Context, key string, v any) error {
+	return ErrReadOnly
+}
+
+// GetJSON reads a manifest and unmarshals it.
+func (s *Snapshot) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := s.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// PutRaw fails with ErrReadOnly.
+func (s *Snapshot) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	return ErrReadOnly
+}
+
+// GetRaw reads a manifest as JSON.
+func (s *Snapshot) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	data, ok := s.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("failed to get %s: %w", key, store.ErrBlobNotFound)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Exists checks if a manifest exists.
+func (s *Snapshot) Exists(ctx context.Context, key string) (bool, error) {
+	_, ok := s.blobs[key]
+	return ok, nil
+}
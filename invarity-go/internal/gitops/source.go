@@ -0,0 +1,231 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/store"
+)
+
+// DefaultPollInterval is how often a Source checks its tree for changes.
+const DefaultPollInterval = 10 * time.Second
+
+// Config configures a Source.
+type Config struct {
+	Dir          string        // Root of the tree
+	PollInterval time.Duration // Time between checks for changes (default 10s)
+	Logger       *zap.Logger
+}
+
+// Source serves the latest valid snapshot of a directory tree. It is a
+// store.ToolReader and a read-only store.BlobStore; View returns both halves
+// from the same snapshot, so a resolution never mixes two versions of the
+// tree.
+//
+// An update that fails to load is logged and rejected, and the last good
+// snapshot keeps being served until the tree is fixed.
+type Source struct {
+	dir      string
+	interval time.Duration
+	logger   *zap.Logger
+	current  atomic.Pointer[Snapshot]
+
+	mu        sync.Mutex // Serializes reloads
+	rejected  string     // Digest of the last tree that failed to load
+	rejectErr error      // Why it failed
+	onSwap    []func(*Snapshot)
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewSource loads the tree at cfg.Dir. It fails if the tree is invalid, since
+// there is no earlier snapshot to fall back on. Call Start to watch it.
+func NewSource(cfg Config) (*Source, error) {
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultPollInterval
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	snap, err := Load(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gitops tree %s: %w", cfg.Dir, err)
+	}
+	s := &Source{
+		dir:      cfg.Dir,
+		interval: cfg.PollInterval,
+		logger:   cfg.Logger,
+		done:     make(chan struct{}),
+	}
+	s.current.Store(snap)
+	return s, nil
+}
+
+// Snapshot returns the snapshot being served.
+func (s *Source) Snapshot() *Snapshot {
+	return s.current.Load()
+}
+
+// View returns the snapshot being served as a tool reader and manifest store.
+func (s *Source) View() (store.ToolReader, store.BlobStore) {
+	snap := s.current.Load()
+	return snap, snap
+}
+
+// OnSwap registers fn to be called with each snapshot Reload swaps in, such
+// as to drop caches built from the previous one. Call it before Start.
+func (s *Source) OnSwap(fn func(*Snapshot)) {
+	s.onSwap = append(s.onSwap, fn)
+}
+
+// Reload loads the tree if it changed since the served snapshot and swaps it
+// in. It returns true if the snapshot was replaced; on error the served
+// snapshot is kept.
+func (s *Source) Reload() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	root, err := filepath.EvalSymlinks(s.dir)
+	if err != nil {
+		return false, fmt.Errorf("failed to open gitops directory: %w", err)
+	}
+	digest, err := digestTree(root)
+	if err != nil {
+		return false, err
+	}
+	if digest == s.current.Load().Digest {
+		return false, nil
+	}
+	if digest == s.rejected {
+		return false, s.rejectErr
+	}
+
+	snap, err := Load(s.dir)
+	if err != nil {
+		s.rejected, s.rejectErr = digest, err
+		return false, err
+	}
+	s.rejected, s.rejectErr = "", nil
+	s.current.Store(snap)
+	for _, fn := range s.onSwap {
+		fn(snap)
+	}
+	return true, nil
+}
+
+// Start checks the tree for changes every poll interval until Close.
+func (s *Source) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.run(ctx)
+}
+
+// Close stops watching the tree. The last snapshot keeps being served.
+func (s *Source) Close() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+}
+
+func (s *Source) run(ctx context.Context) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		swapped, err := s.Reload()
+		if err != nil {
+			// A rejected tree is logged once, not on every poll
+			if err != lastErr {
+				s.logger.Error("gitops update rejected, keeping last good snapshot",
+					zap.String("dir", s.dir),
+					zap.String("digest", s.current.Load().Digest),
+					zap.Error(err),
+				)
+			}
+			lastErr = err
+			continue
+		}
+		lastErr = nil
+		if swapped {
+			snap := s.current.Load()
+			s.logger.Info("gitops snapshot loaded",
+				zap.String("dir", s.dir),
+				zap.String("digest", snap.Digest),
+				zap.Int("tenants", snap.Stats.Tenants),
+				zap.Int("tools", snap.Stats.Tools),
+				zap.Int("toolsets", snap.Stats.Toolsets),
+				zap.Int("assignments", snap.Stats.Assignments),
+			)
+		}
+	}
+}
+
+// GetToolRecord returns a tool version from the served snapshot.
+func (s *Source) GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*store.ToolRecord, error) {
+	return s.current.Load().GetToolRecord(ctx, tenantID, toolID, version)
+}
+
+// ListToolVersions lists a tool's versions in the served snapshot.
+func (s *Source) ListToolVersions(ctx context.Context, tenantID, toolID string) ([]store.ToolRecord, error) {
+	return s.current.Load().ListToolVersions(ctx, tenantID, toolID)
+}
+
+// GetPrincipal returns a tenant's principal from the served snapshot.
+func (s *Source) GetPrincipal(ctx context.Context, tenantID, principalID string) (*store.Principal, error) {
+	return s.current.Load().GetPrincipal(ctx, tenantID, principalID)
+}
+
+// GetPrincipalActiveToolset returns a principal's assignment from the served
+// snapshot.
+func (s *Source) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (string, string, error) {
+	return s.current.Load().GetPrincipalActiveToolset(ctx, tenantID, principalID)
+}
+
+// Ping checks that the tree is still readable.
+func (s *Source) Ping(ctx context.Context) error {
+	_, err := tenantDirs(s.dir)
+	return err
+}
+
+// PutJSON fails with ErrReadOnly.
+func (s *Source) PutJSON(ctx context.Context, key string, v any) error {
+	return ErrReadOnly
+}
+
+// GetJSON reads a manifest from the served snapshot.
+func (s *Source) GetJSON(ctx context.Context, key string, v any) error {
+	return s.current.Load().GetJSON(ctx, key, v)
+}
+
+// PutRaw fails with ErrReadOnly.
+func (s *Source) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	return ErrReadOnly
+}
+
+// GetRaw reads a manifest from the served snapshot.
+func (s *Source) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	return s.current.Load().GetRaw(ctx, key)
+}
+
+// Exists checks if the served snapshot has a manifest.
+func (s *Source) Exists(ctx context.Context, key string) (bool, error) {
+	return s.current.Load().Exists(ctx, key)
+}
@@ -0,0 +1,229 @@
+// Package health checks the firewall's dependencies for the readiness probe.
+package health
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// Overall readiness statuses.
+const (
+	StatusOK       = "ok"        // Every dependency passed
+	StatusDegraded = "degraded"  // Only optional dependencies failed; the firewall stays ready
+	StatusNotReady = "not_ready" // A required dependency failed
+)
+
+// Dependency check statuses.
+const (
+	CheckOK   = "ok"
+	CheckFail = "fail"
+)
+
+// Defaults for dependencies registered without a timeout or cache TTL.
+const (
+	DefaultTimeout  = 2 * time.Second
+	DefaultCacheTTL = 10 * time.Second
+)
+
+// Checker checks that a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to a Checker.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Dependency is a dependency checked by the readiness probe.
+type Dependency struct {
+	Name    string
+	Checker Checker
+	// Time allowed for one check (default DefaultTimeout)
+	Timeout time.Duration
+	// How long a result is reused before the dependency is checked again
+	// (default DefaultCacheTTL), so frequent probes do not load the dependency
+	CacheTTL time.Duration
+	// A failure makes the firewall not ready; otherwise it is only degraded
+	Required bool
+}
+
+// Registry holds the checked dependencies.
+type Registry struct {
+	mu   sync.RWMutex
+	deps []*dependency
+}
+
+// dependency is a registered dependency and its cached result.
+type dependency struct {
+	Dependency
+
+	mu      sync.Mutex // Held while checking, so concurrent probes share one check
+	result  types.HealthCheck
+	expires time.Time
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a dependency. Registering a name again replaces it.
+func (r *Registry) Register(dep Dependency) {
+	if dep.Timeout <= 0 {
+		dep.Timeout = DefaultTimeout
+	}
+	if dep.CacheTTL <= 0 {
+		dep.CacheTTL = DefaultCacheTTL
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.deps {
+		if existing.Name == dep.Name {
+			r.deps[i] = &dependency{Dependency: dep}
+			return
+		}
+	}
+	r.deps = append(r.deps, &dependency{Dependency: dep})
+}
+
+// Check checks every dependency concurrently, reusing results that are still
+// cached, and returns the overall status with each dependency's result.
+func (r *Registry) Check(ctx context.Context) (string, map[string]types.HealthCheck) {
+	r.mu.RLock()
+	deps := append([]*dependency(nil), r.deps...)
+	r.mu.RUnlock()
+
+	results := make([]types.HealthCheck, len(deps))
+	var wg sync.WaitGroup
+	for i, dep := range deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = dep.check(ctx)
+		}()
+	}
+	wg.Wait()
+
+	status := StatusOK
+	checks := make(map[string]types.HealthCheck, len(deps))
+	for i, dep := range deps {
+		result := results[i]
+		checks[dep.Name] = result
+		if result.Status == CheckOK {
+			continue
+		}
+		if result.Required {
+			status = StatusNotReady
+		} else if status == StatusOK {
+			status = StatusDegraded
+		}
+	}
+	return status, checks
+}
+
+// check returns the cached result, or checks the dependency once the cache has
+// expired. The check is not cancelled with ctx, so a probe that gives up does
+// not cache a failure.
+func (d *dependency) check(ctx context.Context) types.HealthCheck {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(d.expires) {
+		result := d.result
+		result.Cached = true
+		return result
+	}
+
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- d.Checker.Check(checkCtx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		// The checker ignored its context; its result is discarded
+		err = fmt.Errorf("timed out after %s", d.Timeout)
+	}
+
+	d.result = types.HealthCheck{
+		Status:    CheckOK,
+		Required:  d.Required,
+		Latency:   types.Duration(time.Since(now)),
+		CheckedAt: now.UTC(),
+	}
+	if err != nil {
+		d.result.Status = CheckFail
+		d.result.Error = err.Error()
+	}
+	d.expires = now.Add(d.CacheTTL)
+	return d.result
+}
+
+// Policy sets each dependency's timeout and whether it is required.
+type Policy struct {
+	Timeout  time.Duration            // Default time allowed for one check
+	CacheTTL time.Duration            // How long results are reused
+	Timeouts map[string]time.Duration // Per-dependency overrides of Timeout
+	Required map[string]bool          // Dependencies whose failure makes the firewall not ready
+}
+
+// ParsePolicy parses a comma-separated list of required dependencies ("*" for
+// all) and comma-separated name=duration timeout overrides, e.g.
+// "alignment_llm=5s,threat_llm=5s".
+func ParsePolicy(required, timeouts string, timeout, cacheTTL time.Duration) (*Policy, error) {
+	p := &Policy{
+		Timeout:  timeout,
+		CacheTTL: cacheTTL,
+		Timeouts: make(map[string]time.Duration),
+		Required: make(map[string]bool),
+	}
+	for _, name := range strings.Split(required, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			p.Required[name] = true
+		}
+	}
+	for _, entry := range strings.Split(timeouts, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid health check timeout %q: expected name=duration", entry)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid health check timeout %q: expected a positive duration", entry)
+		}
+		p.Timeouts[strings.TrimSpace(name)] = d
+	}
+	return p, nil
+}
+
+// Dependency returns the named dependency configured by the policy.
+func (p *Policy) Dependency(name string, checker Checker) Dependency {
+	timeout, ok := p.Timeouts[name]
+	if !ok {
+		timeout = p.Timeout
+	}
+	return Dependency{
+		Name:     name,
+		Checker:  checker,
+		Timeout:  timeout,
+		CacheTTL: p.CacheTTL,
+		Required: p.Required[name] || p.Required["*"],
+	}
+}
@@ -0,0 +1,101 @@
+// Package http provides HTTP handlers and routing for the Invarity Firewall.
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/calibration"
+	"invarity/internal/types"
+)
+
+// AdminHandler handles operator endpoints under /v1/admin.
+type AdminHandler struct {
+	auditStore audit.Store
+	labels     calibration.LabelStore
+	calibrator *calibration.Calibrator
+	logger     *zap.Logger
+}
+
+// NewAdminHandler creates a new admin handler.
+func NewAdminHandler(auditStore audit.Store, labels calibration.LabelStore, calibrator *calibration.Calibrator, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{
+		auditStore: auditStore,
+		labels:     labels,
+		calibrator: calibrator,
+		logger:     logger,
+	}
+}
+
+// HandleLabelAudit handles POST /v1/admin/calibration/labels.
+// Records the ground-truth outcome for an audited decision.
+func (h *AdminHandler) HandleLabelAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+
+	var label calibration.Label
+	if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "PARSE_ERROR", requestID)
+		return
+	}
+	label.Outcome = calibration.Outcome(strings.ToUpper(string(label.Outcome)))
+	label.LabeledAt = label.LabeledAt.UTC()
+	if err := label.Validate(); err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation error: "+err.Error(), "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	record, err := h.auditStore.Get(ctx, label.AuditID)
+	if err != nil || record == nil {
+		h.writeError(w, http.StatusNotFound, "audit record not found", "NOT_FOUND", requestID)
+		return
+	}
+
+	if err := h.labels.Put(ctx, &label); err != nil {
+		h.logger.Error("failed to store calibration label", zap.Error(err), zap.String("audit_id", label.AuditID))
+		h.writeError(w, http.StatusInternalServerError, "failed to store label", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, label)
+}
+
+// HandleFitCalibration handles POST /v1/admin/calibration/fit.
+// Refits every voter's curve from the labeled audits and returns the new reliability report.
+func (h *AdminHandler) HandleFitCalibration(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+
+	report, err := h.calibrator.Refit(ctx, h.auditStore, h.labels)
+	if err != nil {
+		h.logger.Error("failed to fit calibration", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to fit calibration", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	h.logger.Info("calibration refit",
+		zap.Int("labels", report.Labels),
+		zap.Int("samples", report.Samples),
+	)
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HandleGetReliability handles GET /v1/admin/calibration/reliability.
+func (h *AdminHandler) HandleGetReliability(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.calibrator.Reliability())
+}
+
+// writeError writes an error response.
+func (h *AdminHandler) writeError(w http.ResponseWriter, status int, message, code, requestID string) {
+	resp := types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	}
+	writeJSON(w, status, resp)
+}
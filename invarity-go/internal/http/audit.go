@@ -0,0 +1,363 @@
+// Package http provides HTTP handlers and routing for the Invarity Firewall.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/types"
+)
+
+// Audit listing limits.
+const (
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 500
+)
+
+// Tail streaming timings. Streams end after tailMaxDuration, or a margin
+// before the request's deadline if that is sooner; clients reconnect with the
+// last cursor.
+const (
+	tailPollInterval   = 500 * time.Millisecond
+	tailHeartbeatEvery = 15 * time.Second
+	tailMaxDuration    = 50 * time.Second
+	tailDeadlineMargin = time.Second
+)
+
+// AuditHandler handles audit log endpoints.
+type AuditHandler struct {
+	store    audit.Store     // Optional: records are not listed without one
+	verifier *audit.Verifier // Optional: chains are not verified without one
+	logger   *zap.Logger
+}
+
+// NewAuditHandler creates a new audit handler.
+func NewAuditHandler(store audit.Store, verifier *audit.Verifier, logger *zap.Logger) *AuditHandler {
+	return &AuditHandler{
+		store:    store,
+		verifier: verifier,
+		logger:   logger,
+	}
+}
+
+// AuditListResponse is a page of audit records, newest first.
+type AuditListResponse struct {
+	TenantID   string               `json:"tenant_id"`
+	Records    []*types.AuditRecord `json:"records"`
+	NextCursor string               `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// AuditTailEvent is one line of a tail stream. Heartbeats carry only the cursor.
+type AuditTailEvent struct {
+	Cursor string             `json:"cursor,omitempty"`
+	Record *types.AuditRecord `json:"record,omitempty"`
+}
+
+// HandleListAudit handles GET /v1/tenants/{tenant_id}/audit.
+// Query params: actor_id, principal_id, action_id, decision, risk_tier, reason,
+// q (intent text), since, until (RFC 3339), limit (default 50, max 500), cursor.
+func (h *AuditHandler) HandleListAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR", requestID)
+		return
+	}
+	filter.OrgID = tenantID
+
+	// Read one extra record to tell whether there is another page
+	limit := filter.Limit
+	filter.Limit = limit + 1
+	records, err := h.store.List(ctx, filter)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidCursor) {
+			h.writeError(w, http.StatusBadRequest, "invalid cursor", "VALIDATION_ERROR", requestID)
+			return
+		}
+		h.logger.Error("failed to list audit records", zap.Error(err), zap.String("tenant_id", tenantID))
+		h.writeError(w, http.StatusInternalServerError, "failed to list audit records", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	resp := AuditListResponse{TenantID: tenantID, Records: records}
+	if len(records) > limit {
+		resp.Records = records[:limit]
+		resp.NextCursor = audit.EncodeCursor(records[limit-1])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// HandleGetAudit handles GET /v1/tenants/{tenant_id}/audit/{audit_id}.
+func (h *AuditHandler) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+	auditID := chi.URLParam(r, "audit_id")
+
+	record, err := h.store.Get(ctx, auditID)
+	if err != nil && !errors.Is(err, audit.ErrNotFound) {
+		h.logger.Error("failed to get audit record", zap.Error(err), zap.String("audit_id", auditID))
+		h.writeError(w, http.StatusInternalServerError, "failed to get audit record", "INTERNAL_ERROR", requestID)
+		return
+	}
+	// Another tenant's record is reported as missing
+	if record == nil || (record.OrgID != tenantID && record.TenantID != tenantID) {
+		h.writeError(w, http.StatusNotFound, "audit record not found", "NOT_FOUND", requestID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, record)
+}
+
+// HandleTailAudit handles GET /v1/tenants/{tenant_id}/audit/tail.
+// Streams newline-delimited AuditTailEvents, oldest first. Query params: cursor
+// (resume after it), limit (recent records to start with when there is no
+// cursor, default 50), follow (keep polling until the client disconnects or 50s pass).
+func (h *AuditHandler) HandleTailAudit(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+	query := r.URL.Query()
+
+	limit, err := parseLimit(query.Get("limit"))
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR", requestID)
+		return
+	}
+	cursor := query.Get("cursor")
+	if cursor != "" && audit.ValidateCursor(cursor) != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid cursor", "VALIDATION_ERROR", requestID)
+		return
+	}
+	follow, _ := strconv.ParseBool(query.Get("follow"))
+
+	records, err := audit.Since(ctx, h.store, tenantID, cursor, limit)
+	if err != nil {
+		h.logger.Error("failed to tail audit records", zap.Error(err), zap.String("tenant_id", tenantID))
+		h.writeError(w, http.StatusInternalServerError, "failed to tail audit records", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher := http.NewResponseController(w)
+	enc := json.NewEncoder(w)
+
+	send := func(event AuditTailEvent) bool {
+		if err := enc.Encode(event); err != nil {
+			return false
+		}
+		return flusher.Flush() == nil
+	}
+	emit := func(records []*types.AuditRecord) bool {
+		for _, record := range records {
+			cursor = audit.EncodeCursor(record)
+			if !send(AuditTailEvent{Cursor: cursor, Record: record}) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if !emit(records) || !follow {
+		return
+	}
+	if !send(AuditTailEvent{Cursor: cursor}) {
+		return
+	}
+
+	poll := time.NewTicker(tailPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(tailHeartbeatEvery)
+	defer heartbeat.Stop()
+	maxDuration := tailMaxDuration
+	if until, ok := ctx.Deadline(); ok {
+		// Ending at the deadline would leave the timeout middleware to answer
+		maxDuration = min(maxDuration, time.Until(until)-tailDeadlineMargin)
+	}
+	deadline := time.NewTimer(maxDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			return
+		case <-heartbeat.C:
+			if !send(AuditTailEvent{Cursor: cursor}) {
+				return
+			}
+		case <-poll.C:
+			if cursor == "" {
+				// Nothing seen yet: start from the newest record
+				records, err = audit.Since(ctx, h.store, tenantID, "", maxAuditPageSize)
+			} else {
+				records, err = audit.Since(ctx, h.store, tenantID, cursor, 0)
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					h.logger.Warn("failed to poll audit records", zap.Error(err), zap.String("tenant_id", tenantID))
+				}
+				continue
+			}
+			if !emit(records) {
+				return
+			}
+		}
+	}
+}
+
+// CheckpointsResponse lists a tenant's checkpoints with the key that signs them.
+type CheckpointsResponse struct {
+	TenantID    string              `json:"tenant_id"`
+	PublicKey   string              `json:"public_key,omitempty"` // base64 ed25519
+	Checkpoints []*audit.Checkpoint `json:"checkpoints"`
+}
+
+// HandleTenantVerify handles GET /v1/tenants/{tenant_id}/audit/verify.
+func (h *AuditHandler) HandleTenantVerify(w http.ResponseWriter, r *http.Request) {
+	h.writeVerify(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleVerify handles GET /v1/admin/audit/verify?tenant_id=...
+func (h *AuditHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	h.writeVerify(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+// HandleTenantCheckpoints handles GET /v1/tenants/{tenant_id}/audit/checkpoints.
+func (h *AuditHandler) HandleTenantCheckpoints(w http.ResponseWriter, r *http.Request) {
+	h.writeCheckpoints(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleCheckpoints handles GET /v1/admin/audit/checkpoints?tenant_id=...
+func (h *AuditHandler) HandleCheckpoints(w http.ResponseWriter, r *http.Request) {
+	h.writeCheckpoints(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+func (h *AuditHandler) writeVerify(w http.ResponseWriter, r *http.Request, tenantID string) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+
+	if tenantID == "" {
+		h.writeError(w, http.StatusBadRequest, "tenant_id is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	report, err := h.verifier.Verify(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("failed to verify audit chain", zap.Error(err), zap.String("tenant_id", tenantID))
+		h.writeError(w, http.StatusInternalServerError, "failed to verify audit chain", "INTERNAL_ERROR", requestID)
+		return
+	}
+	if !report.Valid {
+		h.logger.Warn("audit chain verification failed",
+			zap.String("tenant_id", tenantID),
+			zap.Int("issues", len(report.Issues)),
+		)
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *AuditHandler) writeCheckpoints(w http.ResponseWriter, r *http.Request, tenantID string) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+
+	if tenantID == "" {
+		h.writeError(w, http.StatusBadRequest, "tenant_id is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	checkpoints, err := h.verifier.Checkpoints(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("failed to list audit checkpoints", zap.Error(err), zap.String("tenant_id", tenantID))
+		h.writeError(w, http.StatusInternalServerError, "failed to list audit checkpoints", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, CheckpointsResponse{
+		TenantID:    tenantID,
+		PublicKey:   h.verifier.PublicKey(),
+		Checkpoints: checkpoints,
+	})
+}
+
+// parseAuditFilter reads the list filters from the query string.
+func parseAuditFilter(r *http.Request) (*audit.ListFilter, error) {
+	query := r.URL.Query()
+	filter := &audit.ListFilter{
+		ActorID:     query.Get("actor_id"),
+		PrincipalID: query.Get("principal_id"),
+		ActionID:    query.Get("action_id"),
+		Decision:    types.Decision(strings.ToUpper(query.Get("decision"))),
+		RiskTier:    types.RiskTier(strings.ToUpper(query.Get("risk_tier"))),
+		ReasonCode:  query.Get("reason"),
+		Query:       query.Get("q"),
+		Cursor:      query.Get("cursor"),
+	}
+
+	switch filter.Decision {
+	case "", types.DecisionAllow, types.DecisionEscalate, types.DecisionDeny:
+	default:
+		return nil, errors.New("decision must be ALLOW, ESCALATE or DENY")
+	}
+	switch filter.RiskTier {
+	case "", types.RiskTierLow, types.RiskTierMedium, types.RiskTierHigh, types.RiskTierCritical:
+	default:
+		return nil, errors.New("risk_tier must be LOW, MEDIUM, HIGH or CRITICAL")
+	}
+
+	var err error
+	if filter.StartTime, err = parseTime(query.Get("since")); err != nil {
+		return nil, errors.New("since must be an RFC 3339 timestamp")
+	}
+	if filter.EndTime, err = parseTime(query.Get("until")); err != nil {
+		return nil, errors.New("until must be an RFC 3339 timestamp")
+	}
+	if filter.Limit, err = parseLimit(query.Get("limit")); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return defaultAuditPageSize, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxAuditPageSize {
+		return 0, errors.New("limit must be between 1 and 500")
+	}
+	return limit, nil
+}
+
+// writeError writes an error response.
+func (h *AuditHandler) writeError(w http.ResponseWriter, status int, message, code, requestID string) {
+	resp := types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	}
+	writeJSON(w, status, resp)
+}
@@ -3,6 +3,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"time"
@@ -10,7 +11,11 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 	"go.uber.org/zap"
 
+	"invarity/internal/audit"
+	"invarity/internal/auth"
+	"invarity/internal/health"
 	"invarity/internal/types"
+	"invarity/internal/usage"
 )
 
 // handleHealthz handles the liveness probe.
@@ -22,30 +27,17 @@ func (r *Router) handleHealthz(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
-// handleReadyz handles the readiness probe.
+// handleReadyz handles the readiness probe. Only a failed required dependency
+// makes the firewall not ready; failed optional dependencies report degraded.
 func (r *Router) handleReadyz(w http.ResponseWriter, req *http.Request) {
-	checks := make(map[string]string)
-
-	// TODO: Add actual readiness checks
-	// - Registry store connectivity
-	// - Policy store connectivity
-	// - LLM endpoint connectivity
-	checks["registry"] = "ok"
-	checks["policy"] = "ok"
-	checks["llm"] = "ok"
-
-	allOk := true
-	for _, status := range checks {
-		if status != "ok" {
-			allOk = false
-			break
-		}
+	status := health.StatusOK
+	var checks map[string]types.HealthCheck
+	if r.health != nil {
+		status, checks = r.health.Check(req.Context())
 	}
 
-	status := "ok"
 	httpStatus := http.StatusOK
-	if !allOk {
-		status = "not_ready"
+	if status == health.StatusNotReady {
 		httpStatus = http.StatusServiceUnavailable
 	}
 
@@ -80,6 +72,14 @@ func (r *Router) handleEvaluate(w http.ResponseWriter, req *http.Request) {
 		toolCallReq.RequestID = requestID
 	}
 
+	// An API token fixes the tenant and principal the call is evaluated for
+	if authCtx := auth.GetAuthContext(ctx); authCtx != nil && authCtx.TokenID != "" {
+		if msg := bindTokenIdentity(authCtx, &toolCallReq); msg != "" {
+			r.writeError(w, http.StatusForbidden, msg, "IDENTITY_MISMATCH", requestID)
+			return
+		}
+	}
+
 	// Validate required fields
 	if toolCallReq.OrgID == "" {
 		r.writeError(w, http.StatusBadRequest, "org_id is required", "VALIDATION_ERROR", requestID)
@@ -96,6 +96,22 @@ func (r *Router) handleEvaluate(w http.ResponseWriter, req *http.Request) {
 
 	// Run pipeline
 	resp, err := r.pipeline.Evaluate(ctx, &toolCallReq)
+	var quotaErr *usage.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		r.logger.Warn("token quota exceeded",
+			zap.String("tenant_id", quotaErr.TenantID),
+			zap.String("plan", quotaErr.Plan),
+			zap.String("request_id", requestID),
+			zap.String("audit_id", quotaErr.AuditID),
+		)
+		r.writeError(w, http.StatusTooManyRequests, quotaErr.Error(), "QUOTA_EXCEEDED", requestID)
+		return
+	}
+	if errors.Is(err, audit.ErrBufferFull) {
+		r.logger.Error("audit buffer full, failing closed", zap.String("request_id", requestID))
+		r.writeError(w, http.StatusServiceUnavailable, "audit pipeline is saturated", "AUDIT_UNAVAILABLE", requestID)
+		return
+	}
 	if err != nil {
 		r.logger.Error("pipeline evaluation failed",
 			zap.Error(err),
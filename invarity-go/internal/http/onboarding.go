@@ -16,12 +16,12 @@ import (
 
 // OnboardingHandler handles onboarding-related endpoints.
 type OnboardingHandler struct {
-	store  *store.DynamoDBStore
+	store  store.Store
 	logger *zap.Logger
 }
 
 // NewOnboardingHandler creates a new onboarding handler.
-func NewOnboardingHandler(store *store.DynamoDBStore, logger *zap.Logger) *OnboardingHandler {
+func NewOnboardingHandler(store store.Store, logger *zap.Logger) *OnboardingHandler {
 	return &OnboardingHandler{
 		store:  store,
 		logger: logger,
@@ -0,0 +1,177 @@
+// Package http provides HTTP handlers and routing for the Invarity Firewall.
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/replay"
+	"invarity/internal/types"
+)
+
+// ReplayHandler handles historical replay job endpoints.
+type ReplayHandler struct {
+	jobs   *replay.Jobs
+	logger *zap.Logger
+}
+
+// NewReplayHandler creates a new replay handler.
+func NewReplayHandler(jobs *replay.Jobs, logger *zap.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		jobs:   jobs,
+		logger: logger,
+	}
+}
+
+// ReplayListResponse lists a tenant's replay jobs, newest first, without reports.
+type ReplayListResponse struct {
+	TenantID string        `json:"tenant_id"`
+	Jobs     []*replay.Job `json:"jobs"`
+}
+
+// HandleTenantSubmitReplay handles POST /v1/tenants/{tenant_id}/replays.
+func (h *ReplayHandler) HandleTenantSubmitReplay(w http.ResponseWriter, r *http.Request) {
+	h.submit(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleSubmitReplay handles POST /v1/admin/replays. The spec names the tenant.
+func (h *ReplayHandler) HandleSubmitReplay(w http.ResponseWriter, r *http.Request) {
+	h.submit(w, r, "")
+}
+
+// HandleTenantListReplays handles GET /v1/tenants/{tenant_id}/replays.
+func (h *ReplayHandler) HandleTenantListReplays(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleListReplays handles GET /v1/admin/replays?tenant_id=...
+func (h *ReplayHandler) HandleListReplays(w http.ResponseWriter, r *http.Request) {
+	h.list(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+// HandleTenantGetReplay handles GET /v1/tenants/{tenant_id}/replays/{job_id}.
+func (h *ReplayHandler) HandleTenantGetReplay(w http.ResponseWriter, r *http.Request) {
+	h.get(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleGetReplay handles GET /v1/admin/replays/{job_id}?tenant_id=...
+func (h *ReplayHandler) HandleGetReplay(w http.ResponseWriter, r *http.Request) {
+	h.get(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+// HandleTenantReproduce handles POST /v1/tenants/{tenant_id}/audit/{audit_id}/reproduce.
+func (h *ReplayHandler) HandleTenantReproduce(w http.ResponseWriter, r *http.Request) {
+	h.reproduce(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleReproduce handles POST /v1/admin/audit/{audit_id}/reproduce?tenant_id=...
+func (h *ReplayHandler) HandleReproduce(w http.ResponseWriter, r *http.Request) {
+	h.reproduce(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+// submit queues a replay job. A tenant in the path overrides the spec's.
+func (h *ReplayHandler) submit(w http.ResponseWriter, r *http.Request, tenantID string) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+
+	var spec replay.Spec
+	if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "PARSE_ERROR", requestID)
+		return
+	}
+	if tenantID != "" {
+		spec.TenantID = tenantID
+	}
+
+	job, err := h.jobs.Submit(spec)
+	if errors.Is(err, replay.ErrTooManyJobs) {
+		h.writeError(w, http.StatusTooManyRequests, err.Error(), "TOO_MANY_JOBS", requestID)
+		return
+	}
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "validation error: "+err.Error(), "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	h.logger.Info("replay job submitted",
+		zap.String("job_id", job.JobID),
+		zap.String("tenant_id", job.TenantID),
+		zap.String("llm", job.Spec.LLM),
+	)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+func (h *ReplayHandler) list(w http.ResponseWriter, r *http.Request, tenantID string) {
+	requestID := middleware.GetReqID(r.Context())
+	if tenantID == "" {
+		h.writeError(w, http.StatusBadRequest, "tenant_id is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+	writeJSON(w, http.StatusOK, ReplayListResponse{
+		TenantID: tenantID,
+		Jobs:     h.jobs.List(tenantID),
+	})
+}
+
+func (h *ReplayHandler) get(w http.ResponseWriter, r *http.Request, tenantID string) {
+	requestID := middleware.GetReqID(r.Context())
+	if tenantID == "" {
+		h.writeError(w, http.StatusBadRequest, "tenant_id is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+	job := h.jobs.Get(tenantID, chi.URLParam(r, "job_id"))
+	if job == nil {
+		h.writeError(w, http.StatusNotFound, "replay job not found", "NOT_FOUND", requestID)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// reproduce re-runs an audited decision with the artifacts it was made with.
+func (h *ReplayHandler) reproduce(w http.ResponseWriter, r *http.Request, tenantID string) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	if tenantID == "" {
+		h.writeError(w, http.StatusBadRequest, "tenant_id is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+	auditID := chi.URLParam(r, "audit_id")
+
+	result, err := h.jobs.Engine().Reproduce(ctx, tenantID, auditID)
+	switch {
+	case errors.Is(err, audit.ErrNotFound):
+		h.writeError(w, http.StatusNotFound, "audit record not found", "NOT_FOUND", requestID)
+		return
+	case errors.Is(err, replay.ErrNoSnapshot), errors.Is(err, replay.ErrNoToolCall), errors.Is(err, replay.ErrArtifactUnavailable):
+		h.writeError(w, http.StatusConflict, err.Error(), "NOT_REPRODUCIBLE", requestID)
+		return
+	case err != nil:
+		h.logger.Error("failed to reproduce decision", zap.Error(err), zap.String("audit_id", auditID))
+		h.writeError(w, http.StatusInternalServerError, "failed to reproduce decision", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	if !result.Reproduced {
+		h.logger.Warn("decision did not reproduce",
+			zap.String("audit_id", auditID),
+			zap.String("tenant_id", tenantID),
+			zap.Strings("differences", result.Differences),
+		)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *ReplayHandler) writeError(w http.ResponseWriter, status int, message, code, requestID string) {
+	resp := types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	}
+	writeJSON(w, status, resp)
+}
@@ -7,35 +7,63 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 
+	"invarity/internal/audit"
 	"invarity/internal/auth"
+	"invarity/internal/calibration"
 	"invarity/internal/firewall"
+	"invarity/internal/health"
+	"invarity/internal/replay"
 	"invarity/internal/store"
+	"invarity/internal/tracing"
+	"invarity/internal/usage"
 )
 
+// DefaultRequestTimeout is the deadline of each request when RouterConfig sets none.
+const DefaultRequestTimeout = 60 * time.Second
+
 // Router wraps chi.Router with Invarity-specific configuration.
 type Router struct {
 	*chi.Mux
 	logger            *zap.Logger
 	pipeline          *firewall.Pipeline
 	cognitoVerifier   *auth.CognitoVerifier
-	store             *store.DynamoDBStore
-	s3Client          *store.S3Client
 	onboardingHandler *OnboardingHandler
 	toolsHandler      *ToolsHandler
 	toolsetsHandler   *ToolsetsHandler
+	tokensHandler     *TokensHandler
 	tenantAuth        *auth.TenantAuthMiddleware
+	adminHandler      *AdminHandler
+	usageHandler      *UsageHandler
+	auditHandler      *AuditHandler
+	replayHandler     *ReplayHandler
+	health            *health.Registry
 }
 
 // RouterConfig holds configuration for creating a router.
 type RouterConfig struct {
 	Logger             *zap.Logger
 	Pipeline           *firewall.Pipeline
-	CognitoVerifier    *auth.CognitoVerifier  // Optional: for control plane auth
-	Store              *store.DynamoDBStore   // Optional: for control plane endpoints
-	S3Client           *store.S3Client        // Optional: for storing manifests
-	EnableControlPlane bool                   // Whether to enable control plane endpoints
+	CognitoVerifier    *auth.CognitoVerifier   // Optional: for control plane auth
+	Store              store.Store             // Optional: for control plane endpoints
+	ManifestStore      store.BlobStore         // Optional: for storing manifests
+	ResolverCache      *firewall.ResolverCache // Optional: invalidated by control plane writes
+	EnableControlPlane bool                    // Whether to enable control plane endpoints
+	AdminAPIKey        string                  // Optional: enables /v1/admin endpoints when set
+	AuditStore         audit.Store             // Required for admin and audit log endpoints
+	Calibrator         *calibration.Calibrator // Required for admin endpoints
+	LabelStore         calibration.LabelStore  // Required for admin endpoints
+	Meter              *usage.Meter            // Optional: enables usage reporting endpoints
+	AuditVerifier      *audit.Verifier         // Optional: enables audit chain verification endpoints
+	ReplayJobs         *replay.Jobs            // Optional: enables historical replay endpoints
+	TracerProvider     trace.TracerProvider    // Optional: records a span per request
+	Health             *health.Registry        // Optional: dependencies checked by /readyz
+	TokenAuth          *TokenAuthenticator     // Optional: requires API tokens on /v1/firewall
+	RequestTimeout     time.Duration           // Optional: deadline of each request (default 60s)
 }
 
 // NewRouter creates a new HTTP router with all routes configured.
@@ -45,24 +73,43 @@ func NewRouter(cfg RouterConfig) *Router {
 		logger:          cfg.Logger,
 		pipeline:        cfg.Pipeline,
 		cognitoVerifier: cfg.CognitoVerifier,
-		store:           cfg.Store,
-		s3Client:        cfg.S3Client,
+		health:          cfg.Health,
 	}
 
 	// Initialize control plane handlers if enabled
 	if cfg.EnableControlPlane && cfg.Store != nil {
 		r.onboardingHandler = NewOnboardingHandler(cfg.Store, cfg.Logger)
 		r.tenantAuth = auth.NewTenantAuthMiddleware(cfg.Store)
-		r.toolsHandler = NewToolsHandler(cfg.Store, cfg.S3Client, cfg.Logger)
-		r.toolsetsHandler = NewToolsetsHandler(cfg.Store, cfg.S3Client, cfg.Logger)
+		r.toolsHandler = NewToolsHandler(cfg.Store, cfg.ManifestStore, cfg.ResolverCache, cfg.Logger)
+		r.toolsetsHandler = NewToolsetsHandler(cfg.Store, cfg.ManifestStore, cfg.ResolverCache, cfg.Logger)
+		r.tokensHandler = NewTokensHandler(cfg.Store, cfg.TokenAuth, cfg.Logger)
+	}
+
+	// Initialize admin handler if an operator key is configured
+	if cfg.AdminAPIKey != "" && cfg.AuditStore != nil && cfg.Calibrator != nil && cfg.LabelStore != nil {
+		r.adminHandler = NewAdminHandler(cfg.AuditStore, cfg.LabelStore, cfg.Calibrator, cfg.Logger)
+	}
+	if cfg.Meter != nil {
+		r.usageHandler = NewUsageHandler(cfg.Meter, cfg.Logger)
+	}
+	if cfg.AuditStore != nil || cfg.AuditVerifier != nil {
+		r.auditHandler = NewAuditHandler(cfg.AuditStore, cfg.AuditVerifier, cfg.Logger)
+	}
+	if cfg.ReplayJobs != nil {
+		r.replayHandler = NewReplayHandler(cfg.ReplayJobs, cfg.Logger)
 	}
 
 	// Middleware
 	r.Use(middleware.RequestID)
+	r.Use(Tracing(cfg.TracerProvider))
 	r.Use(middleware.RealIP)
 	r.Use(RequestLogger(cfg.Logger))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
+	timeout := cfg.RequestTimeout
+	if timeout <= 0 {
+		timeout = DefaultRequestTimeout
+	}
+	r.Use(middleware.Timeout(timeout))
 
 	// Health endpoints (no auth)
 	r.Get("/healthz", r.handleHealthz)
@@ -70,11 +117,49 @@ func NewRouter(cfg RouterConfig) *Router {
 
 	// API v1
 	r.Route("/v1", func(v1 chi.Router) {
-		// Firewall endpoints (no auth required for now - uses API keys in request)
+		// Firewall endpoints (API token with the evaluate scope, when configured)
 		v1.Route("/firewall", func(fw chi.Router) {
+			if cfg.TokenAuth != nil {
+				fw.Use(cfg.TokenAuth.Require(auth.ScopeEvaluate))
+			}
 			fw.Post("/evaluate", r.handleEvaluate)
 		})
 
+		// Operator endpoints (admin API key required)
+		if cfg.AdminAPIKey != "" {
+			v1.Route("/admin", func(admin chi.Router) {
+				admin.Use(auth.RequireAdminKey(cfg.AdminAPIKey))
+				if r.adminHandler != nil {
+					admin.Route("/calibration", func(cal chi.Router) {
+						cal.Post("/labels", r.adminHandler.HandleLabelAudit)
+						cal.Post("/fit", r.adminHandler.HandleFitCalibration)
+						cal.Get("/reliability", r.adminHandler.HandleGetReliability)
+					})
+				}
+				if r.usageHandler != nil {
+					admin.Get("/usage", r.usageHandler.HandleUsageReport)
+				}
+				if cfg.AuditVerifier != nil || r.replayHandler != nil {
+					admin.Route("/audit", func(a chi.Router) {
+						if cfg.AuditVerifier != nil {
+							a.Get("/verify", r.auditHandler.HandleVerify)
+							a.Get("/checkpoints", r.auditHandler.HandleCheckpoints)
+						}
+						if r.replayHandler != nil {
+							a.Post("/{audit_id}/reproduce", r.replayHandler.HandleReproduce)
+						}
+					})
+				}
+				if r.replayHandler != nil {
+					admin.Route("/replays", func(rp chi.Router) {
+						rp.Post("/", r.replayHandler.HandleSubmitReplay)
+						rp.Get("/", r.replayHandler.HandleListReplays)
+						rp.Get("/{job_id}", r.replayHandler.HandleGetReplay)
+					})
+				}
+			})
+		}
+
 		// Control plane endpoints (Cognito auth required)
 		if cfg.EnableControlPlane && r.cognitoVerifier != nil && r.onboardingHandler != nil {
 			// Onboarding endpoints - require Cognito auth
@@ -103,6 +188,14 @@ func NewRouter(cfg RouterConfig) *Router {
 					principals.With(auth.RequireScope(auth.ScopePrincipalsWrite)).Post("/{principal_id}/toolsets:apply", r.toolsetsHandler.HandleApplyToolset)
 				})
 
+				// API tokens (tenant-scoped)
+				tenant.Route("/tokens", func(tokens chi.Router) {
+					tokens.With(auth.RequireScope(auth.ScopeTokensRead)).Get("/", r.tokensHandler.HandleListTokens)
+					tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/", r.tokensHandler.HandleCreateToken)
+					tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/{token_id}/rotate", r.tokensHandler.HandleRotateToken)
+					tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/{token_id}/revoke", r.tokensHandler.HandleRevokeToken)
+				})
+
 				// Tools (tenant-scoped)
 				tenant.Route("/tools", func(tools chi.Router) {
 					tools.With(auth.RequireScope(auth.ScopeToolsRead)).Get("/", r.toolsHandler.HandleListTools)
@@ -116,6 +209,40 @@ func NewRouter(cfg RouterConfig) *Router {
 					toolsets.With(auth.RequireScope(auth.ScopeToolsetsWrite)).Post("/", r.toolsetsHandler.HandleRegisterToolset)
 					toolsets.With(auth.RequireScope(auth.ScopeToolsetsRead)).Get("/{toolset_id}/{revision}", r.toolsetsHandler.HandleGetToolset)
 				})
+
+				// LLM token usage (tenant-scoped)
+				if r.usageHandler != nil {
+					tenant.With(auth.RequireScope(auth.ScopeUsageRead)).Get("/usage", r.usageHandler.HandleTenantUsage)
+				}
+
+				// Audit log (tenant-scoped)
+				if r.auditHandler != nil {
+					tenant.Route("/audit", func(a chi.Router) {
+						a.Use(auth.RequireScope(auth.ScopeAuditRead))
+						if cfg.AuditVerifier != nil {
+							a.Get("/verify", r.auditHandler.HandleTenantVerify)
+							a.Get("/checkpoints", r.auditHandler.HandleTenantCheckpoints)
+						}
+						if cfg.AuditStore != nil {
+							a.Get("/", r.auditHandler.HandleListAudit)
+							a.Get("/tail", r.auditHandler.HandleTailAudit)
+							a.Get("/{audit_id}", r.auditHandler.HandleGetAudit)
+						}
+						if r.replayHandler != nil {
+							a.With(auth.RequireScope(auth.ScopeReplayRun)).Post("/{audit_id}/reproduce", r.replayHandler.HandleTenantReproduce)
+						}
+					})
+				}
+
+				// Historical replay (tenant-scoped); reports expose audited traffic
+				if r.replayHandler != nil {
+					tenant.Route("/replays", func(rp chi.Router) {
+						rp.Use(auth.RequireScope(auth.ScopeAuditRead))
+						rp.With(auth.RequireScope(auth.ScopeReplayRun)).Post("/", r.replayHandler.HandleTenantSubmitReplay)
+						rp.Get("/", r.replayHandler.HandleTenantListReplays)
+						rp.Get("/{job_id}", r.replayHandler.HandleTenantGetReplay)
+					})
+				}
 			})
 		}
 	})
@@ -123,6 +250,34 @@ func NewRouter(cfg RouterConfig) *Router {
 	return r
 }
 
+// Tracing returns a middleware that continues the trace in the request's
+// traceparent header, or starts a new one, with a server span named after the
+// matched route.
+func Tracing(tp trace.TracerProvider) func(next http.Handler) http.Handler {
+	tracer := tracing.Tracer(tp)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := tracing.Propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method, trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+					attribute.String("invarity.request_id", middleware.GetReqID(r.Context())),
+				))
+			defer span.End()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(r.Method + " " + rctx.RoutePattern())
+				span.SetAttributes(attribute.String("http.route", rctx.RoutePattern()))
+			}
+			span.SetAttributes(attribute.Int("http.response.status_code", ww.Status()))
+		})
+	}
+}
+
 // RequestLogger returns a middleware that logs requests.
 func RequestLogger(logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
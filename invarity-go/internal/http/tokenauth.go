@@ -0,0 +1,285 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/auth"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// Token authentication defaults.
+const (
+	DefaultTokenCacheTTL      = 30 * time.Second
+	DefaultTokenTouchInterval = 10 * time.Second
+	DefaultTokenCacheSize     = 10000
+)
+
+// tokenTouchTimeout bounds the store writes of a single last-used flush.
+const tokenTouchTimeout = 10 * time.Second
+
+// TokenAuthConfig configures a TokenAuthenticator.
+type TokenAuthConfig struct {
+	Store         store.TokenStore
+	CacheTTL      time.Duration // How long a validated token is reused; 0 validates every request
+	TouchInterval time.Duration // Time between last-used writes (default 10s)
+	MaxEntries    int           // Validated tokens are not cached past this (default 10000)
+	Logger        *zap.Logger
+}
+
+// TokenAuthenticator authenticates data plane requests by API token. Validated
+// tokens are cached by hash for CacheTTL, but never past their expiry, so a
+// token revoked through another replica keeps working for at most CacheTTL.
+// Last-used times are collected in memory and written to the store every
+// TouchInterval.
+type TokenAuthenticator struct {
+	store      store.TokenStore
+	ttl        time.Duration
+	interval   time.Duration
+	maxEntries int
+	logger     *zap.Logger
+
+	mu    sync.Mutex
+	cache map[string]cachedToken // key: SHA-256 of the plaintext token
+	gen   uint64                 // Incremented by every Forget
+	used  map[string]time.Time   // key: token ID, awaiting the next flush
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type cachedToken struct {
+	token   *store.Token
+	expires time.Time
+}
+
+// NewTokenAuthenticator creates a token authenticator and starts its
+// last-used writer. Call Close to flush it.
+func NewTokenAuthenticator(cfg TokenAuthConfig) *TokenAuthenticator {
+	if cfg.TouchInterval <= 0 {
+		cfg.TouchInterval = DefaultTokenTouchInterval
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultTokenCacheSize
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+
+	a := &TokenAuthenticator{
+		store:      cfg.Store,
+		ttl:        cfg.CacheTTL,
+		interval:   cfg.TouchInterval,
+		maxEntries: cfg.MaxEntries,
+		logger:     cfg.Logger,
+		cache:      make(map[string]cachedToken),
+		used:       make(map[string]time.Time),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Require returns middleware that requires "Authorization: Bearer <token>" with
+// an active, unexpired API token holding scope. The token's tenant, principal
+// and scopes are added to the request's auth context.
+func (a *TokenAuthenticator) Require(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			requestID := middleware.GetReqID(ctx)
+
+			plaintext, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || plaintext == "" {
+				writeAuthError(w, http.StatusUnauthorized, "API token required", "UNAUTHORIZED", requestID)
+				return
+			}
+
+			token, err := a.validate(ctx, plaintext)
+			if err != nil {
+				a.logger.Error("failed to validate API token", zap.Error(err), zap.String("request_id", requestID))
+				writeAuthError(w, http.StatusInternalServerError, "failed to validate API token", "INTERNAL_ERROR", requestID)
+				return
+			}
+			if token == nil {
+				writeAuthError(w, http.StatusUnauthorized, "invalid, expired or revoked API token", "UNAUTHORIZED", requestID)
+				return
+			}
+
+			authCtx := &auth.AuthContext{
+				ActorType:   auth.ActorTypeAgent,
+				TokenID:     token.TokenID,
+				TenantID:    token.TenantID,
+				PrincipalID: token.PrincipalID,
+				Scopes:      make([]auth.Scope, len(token.Scopes)),
+			}
+			for i, s := range token.Scopes {
+				authCtx.Scopes[i] = auth.Scope(s)
+			}
+			if !authCtx.HasScope(scope) {
+				writeAuthError(w, http.StatusForbidden, "missing required scope: "+string(scope), "FORBIDDEN", requestID)
+				return
+			}
+
+			a.touch(token.TokenID)
+			next.ServeHTTP(w, r.WithContext(auth.WithAuthContext(ctx, authCtx)))
+		})
+	}
+}
+
+// Forget drops a token's cached validation, so that a revocation or expiry
+// change takes effect on this replica at once. A nil authenticator does nothing.
+func (a *TokenAuthenticator) Forget(tokenID string) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.gen++
+	for key, entry := range a.cache {
+		if entry.token.TokenID == tokenID {
+			delete(a.cache, key)
+		}
+	}
+}
+
+// Close stops the last-used writer after a final flush, or when ctx is done.
+func (a *TokenAuthenticator) Close(ctx context.Context) error {
+	a.closeOnce.Do(func() { close(a.stop) })
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// validate returns the token for plaintext, or nil if it is not usable.
+func (a *TokenAuthenticator) validate(ctx context.Context, plaintext string) (*store.Token, error) {
+	sum := sha256.Sum256([]byte(plaintext))
+	key := hex.EncodeToString(sum[:])
+	now := time.Now()
+
+	var gen uint64
+	if a.ttl > 0 {
+		a.mu.Lock()
+		entry, ok := a.cache[key]
+		if ok && now.Before(entry.expires) {
+			a.mu.Unlock()
+			return entry.token, nil
+		}
+		delete(a.cache, key)
+		gen = a.gen
+		a.mu.Unlock()
+	}
+
+	token, err := a.store.ValidateToken(ctx, plaintext)
+	if err != nil || token == nil || a.ttl <= 0 {
+		return token, err
+	}
+
+	expires := now.Add(a.ttl)
+	if !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(expires) {
+		expires = token.ExpiresAt
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.gen != gen {
+		// Forgotten while it was being validated
+		return token, nil
+	}
+	if len(a.cache) >= a.maxEntries {
+		for k, e := range a.cache {
+			if !now.Before(e.expires) {
+				delete(a.cache, k)
+			}
+		}
+	}
+	if len(a.cache) < a.maxEntries {
+		a.cache[key] = cachedToken{token: token, expires: expires}
+	}
+	return token, nil
+}
+
+// touch records that a token was used, for the next flush.
+func (a *TokenAuthenticator) touch(tokenID string) {
+	a.mu.Lock()
+	a.used[tokenID] = time.Now().UTC()
+	a.mu.Unlock()
+}
+
+func (a *TokenAuthenticator) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-a.stop:
+			a.flush()
+			return
+		case <-ticker.C:
+			a.flush()
+		}
+	}
+}
+
+// flush writes the collected last-used times. They are dropped if the write
+// fails; the next use of each token records it again.
+func (a *TokenAuthenticator) flush() {
+	a.mu.Lock()
+	used := a.used
+	a.used = make(map[string]time.Time)
+	a.mu.Unlock()
+	if len(used) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tokenTouchTimeout)
+	defer cancel()
+	if err := a.store.TouchTokens(ctx, used); err != nil {
+		a.logger.Warn("failed to record API token use", zap.Error(err), zap.Int("tokens", len(used)))
+	}
+}
+
+// bindTokenIdentity sets a call's tenant and principal from its API token. The
+// body may repeat them but not name others; a token without a principal
+// leaves the body's principal to the resolver's tenant check.
+func bindTokenIdentity(authCtx *auth.AuthContext, req *types.ToolCallRequest) string {
+	if req.TenantID != "" && req.TenantID != authCtx.TenantID {
+		return "tenant_id does not match the API token's tenant"
+	}
+	if req.OrgID != "" && req.OrgID != authCtx.TenantID {
+		return "org_id does not match the API token's tenant"
+	}
+	if authCtx.PrincipalID != "" {
+		if req.PrincipalID != "" && req.PrincipalID != authCtx.PrincipalID {
+			return "principal_id does not match the API token's principal"
+		}
+		req.PrincipalID = authCtx.PrincipalID
+	}
+	req.TenantID = authCtx.TenantID
+	req.OrgID = authCtx.TenantID
+	return ""
+}
+
+// writeAuthError writes an authentication error response.
+func writeAuthError(w http.ResponseWriter, status int, message, code, requestID string) {
+	writeJSON(w, status, types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	})
+}
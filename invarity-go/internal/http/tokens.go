@@ -0,0 +1,429 @@
+// Package http provides HTTP handlers and routing for the Invarity Firewall.
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/auth"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// Token rotation overlap limits.
+const (
+	DefaultTokenRotationOverlap = time.Hour
+	MaxTokenRotationOverlap     = 30 * 24 * time.Hour
+)
+
+// TokensHandler handles API token management endpoints.
+type TokensHandler struct {
+	store     store.Store
+	tokenAuth *TokenAuthenticator // Optional: forgets tokens that are revoked or rotated
+	logger    *zap.Logger
+}
+
+// NewTokensHandler creates a new tokens handler.
+func NewTokensHandler(controlPlane store.Store, tokenAuth *TokenAuthenticator, logger *zap.Logger) *TokensHandler {
+	return &TokensHandler{
+		store:     controlPlane,
+		tokenAuth: tokenAuth,
+		logger:    logger,
+	}
+}
+
+// TokenInfo describes a token without its secret.
+type TokenInfo struct {
+	TokenID     string     `json:"token_id"`
+	KeyPrefix   string     `json:"key_prefix"`
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	PrincipalID string     `json:"principal_id,omitempty"`
+	Scopes      []string   `json:"scopes"`
+	Status      string     `json:"status"` // "active", "expired" or "revoked"
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   string     `json:"created_by"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy   string     `json:"revoked_by,omitempty"`
+}
+
+// CreateTokenRequest is the request body for POST /v1/tenants/{tenant_id}/tokens.
+type CreateTokenRequest struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type,omitempty"`         // "developer" or "agent" (default agent with a principal, else developer)
+	PrincipalID string     `json:"principal_id,omitempty"` // Required for agent tokens
+	Scopes      []string   `json:"scopes,omitempty"`       // Default ["evaluate"]
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`   // Never expires when omitted
+}
+
+// CreateTokenResponse is the response for POST /v1/tenants/{tenant_id}/tokens.
+// Token is the plaintext secret; it is not stored and cannot be shown again.
+type CreateTokenResponse struct {
+	Token string `json:"token"`
+	TokenInfo
+}
+
+// RotateTokenRequest is the request body for POST /v1/tenants/{tenant_id}/tokens/{token_id}/rotate.
+type RotateTokenRequest struct {
+	OverlapSeconds *int64     `json:"overlap_seconds,omitempty"` // How long the old token keeps working (default 3600; 0 revokes it)
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // Default: the old token's lifetime from now
+}
+
+// RotateTokenResponse is the response for POST /v1/tenants/{tenant_id}/tokens/{token_id}/rotate.
+type RotateTokenResponse struct {
+	CreateTokenResponse
+	Previous TokenInfo `json:"previous"`
+}
+
+// ListTokensResponse is the response for GET /v1/tenants/{tenant_id}/tokens.
+type ListTokensResponse struct {
+	Tokens []TokenInfo `json:"tokens"`
+}
+
+// HandleCreateToken handles POST /v1/tenants/{tenant_id}/tokens.
+// Agent tokens are bound to a principal and may only hold the evaluate scope;
+// developer tokens may hold any scope the creator's role has.
+func (h *TokensHandler) HandleCreateToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	authCtx := auth.GetAuthContext(ctx)
+	if authCtx == nil {
+		h.writeError(w, http.StatusUnauthorized, "authentication required", "AUTH_REQUIRED", requestID)
+		return
+	}
+
+	var req CreateTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "PARSE_ERROR", requestID)
+		return
+	}
+
+	// Validate
+	if req.Name == "" {
+		h.writeError(w, http.StatusBadRequest, "name is required", "VALIDATION_ERROR", requestID)
+		return
+	}
+	if req.Type == "" {
+		req.Type = "developer"
+		if req.PrincipalID != "" {
+			req.Type = "agent"
+		}
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{string(auth.ScopeEvaluate)}
+	}
+	if msg := validateTokenGrant(auth.GetTenantContext(ctx), req.Type, req.PrincipalID, req.Scopes); msg != "" {
+		h.writeError(w, http.StatusBadRequest, msg, "VALIDATION_ERROR", requestID)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.writeError(w, http.StatusBadRequest, "expires_at must be in the future", "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	// Agent tokens must name an active principal of the tenant
+	if req.PrincipalID != "" {
+		principal, err := h.store.GetPrincipal(ctx, tenantID, req.PrincipalID)
+		if err != nil {
+			h.logger.Error("failed to get principal", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to get principal", "STORE_ERROR", requestID)
+			return
+		}
+		if principal == nil {
+			h.writeError(w, http.StatusNotFound, "principal not found", "NOT_FOUND", requestID)
+			return
+		}
+		if principal.Status != "active" {
+			h.writeError(w, http.StatusConflict, "principal is "+principal.Status, "PRINCIPAL_INACTIVE", requestID)
+			return
+		}
+	}
+
+	plaintext, token, err := h.store.CreateToken(ctx, tenantID, req.PrincipalID, req.Name, req.Type, authCtx.UserID, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		h.logger.Error("failed to create token", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to create token", "STORE_ERROR", requestID)
+		return
+	}
+
+	h.logger.Info("token created",
+		zap.String("token_id", token.TokenID),
+		zap.String("tenant_id", tenantID),
+		zap.String("type", token.Type),
+		zap.Strings("scopes", token.Scopes),
+		zap.String("created_by", authCtx.UserID),
+	)
+
+	writeJSON(w, http.StatusCreated, CreateTokenResponse{Token: plaintext, TokenInfo: tokenInfo(token)})
+}
+
+// HandleListTokens handles GET /v1/tenants/{tenant_id}/tokens.
+func (h *TokensHandler) HandleListTokens(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+
+	authCtx := auth.GetAuthContext(ctx)
+	if authCtx == nil {
+		h.writeError(w, http.StatusUnauthorized, "authentication required", "AUTH_REQUIRED", requestID)
+		return
+	}
+
+	tokens, err := h.store.ListTokens(ctx, tenantID)
+	if err != nil {
+		h.logger.Error("failed to list tokens", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to list tokens", "STORE_ERROR", requestID)
+		return
+	}
+
+	infos := make([]TokenInfo, len(tokens))
+	for i := range tokens {
+		infos[i] = tokenInfo(&tokens[i])
+	}
+
+	writeJSON(w, http.StatusOK, ListTokensResponse{
+		Tokens: infos,
+	})
+}
+
+// HandleRotateToken handles POST /v1/tenants/{tenant_id}/tokens/{token_id}/rotate.
+// It issues a replacement with the same name, principal and scopes, and
+// expires the old token after the overlap window so clients can switch over.
+func (h *TokensHandler) HandleRotateToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+	tokenID := chi.URLParam(r, "token_id")
+
+	authCtx := auth.GetAuthContext(ctx)
+	if authCtx == nil {
+		h.writeError(w, http.StatusUnauthorized, "authentication required", "AUTH_REQUIRED", requestID)
+		return
+	}
+
+	var req RotateTokenRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error(), "PARSE_ERROR", requestID)
+			return
+		}
+	}
+	overlap := DefaultTokenRotationOverlap
+	if req.OverlapSeconds != nil {
+		overlap = time.Duration(*req.OverlapSeconds) * time.Second
+	}
+	if overlap < 0 || overlap > MaxTokenRotationOverlap {
+		h.writeError(w, http.StatusBadRequest, "overlap_seconds must be between 0 and 2592000 (30 days)", "VALIDATION_ERROR", requestID)
+		return
+	}
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now.Add(overlap)) {
+		h.writeError(w, http.StatusBadRequest, "expires_at must be after the overlap window", "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	old, ok := h.getToken(w, r, tenantID, tokenID)
+	if !ok {
+		return
+	}
+	if tokenInfo(old).Status != "active" {
+		h.writeError(w, http.StatusConflict, "token is "+tokenInfo(old).Status, "TOKEN_INACTIVE", requestID)
+		return
+	}
+	// Rotating hands out the token's scopes again, so the caller must be able to grant them
+	if msg := validateTokenGrant(auth.GetTenantContext(ctx), old.Type, old.PrincipalID, old.Scopes); msg != "" {
+		h.writeError(w, http.StatusForbidden, msg, "FORBIDDEN", requestID)
+		return
+	}
+
+	// The replacement keeps the old token's lifetime unless told otherwise
+	expiresAt := req.ExpiresAt
+	if expiresAt == nil && !old.ExpiresAt.IsZero() {
+		at := now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+		expiresAt = &at
+	}
+	plaintext, token, err := h.store.CreateToken(ctx, tenantID, old.PrincipalID, old.Name, old.Type, authCtx.UserID, old.Scopes, expiresAt)
+	if err != nil {
+		h.logger.Error("failed to create token", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to rotate token", "STORE_ERROR", requestID)
+		return
+	}
+
+	if overlap == 0 {
+		err = h.store.RevokeToken(ctx, tenantID, tokenID, authCtx.UserID)
+	} else {
+		err = h.store.ExpireToken(ctx, tenantID, tokenID, now.Add(overlap))
+	}
+	if err != nil {
+		h.logger.Error("failed to retire rotated token", zap.Error(err), zap.String("token_id", tokenID))
+		// Do not leave two long-lived tokens behind
+		if err := h.store.RevokeToken(ctx, tenantID, token.TokenID, authCtx.UserID); err != nil {
+			h.logger.Error("failed to revoke replacement token", zap.Error(err), zap.String("token_id", token.TokenID))
+		}
+		h.writeError(w, http.StatusInternalServerError, "failed to rotate token", "STORE_ERROR", requestID)
+		return
+	}
+	h.tokenAuth.Forget(tokenID)
+
+	previous, err := h.store.GetToken(ctx, tenantID, tokenID)
+	if err != nil || previous == nil {
+		previous = old
+	}
+
+	h.logger.Info("token rotated",
+		zap.String("token_id", tokenID),
+		zap.String("replacement_id", token.TokenID),
+		zap.String("tenant_id", tenantID),
+		zap.Duration("overlap", overlap),
+		zap.String("rotated_by", authCtx.UserID),
+	)
+
+	writeJSON(w, http.StatusCreated, RotateTokenResponse{
+		CreateTokenResponse: CreateTokenResponse{Token: plaintext, TokenInfo: tokenInfo(token)},
+		Previous:            tokenInfo(previous),
+	})
+}
+
+// HandleRevokeToken handles POST /v1/tenants/{tenant_id}/tokens/{token_id}/revoke.
+// Revoking a revoked token succeeds without change.
+func (h *TokensHandler) HandleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	tenantID := chi.URLParam(r, "tenant_id")
+	tokenID := chi.URLParam(r, "token_id")
+
+	authCtx := auth.GetAuthContext(ctx)
+	if authCtx == nil {
+		h.writeError(w, http.StatusUnauthorized, "authentication required", "AUTH_REQUIRED", requestID)
+		return
+	}
+
+	token, ok := h.getToken(w, r, tenantID, tokenID)
+	if !ok {
+		return
+	}
+	if token.Status != "revoked" {
+		if err := h.store.RevokeToken(ctx, tenantID, tokenID, authCtx.UserID); err != nil {
+			h.logger.Error("failed to revoke token", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to revoke token", "STORE_ERROR", requestID)
+			return
+		}
+		h.tokenAuth.Forget(tokenID)
+
+		h.logger.Info("token revoked",
+			zap.String("token_id", tokenID),
+			zap.String("tenant_id", tenantID),
+			zap.String("revoked_by", authCtx.UserID),
+		)
+
+		if revoked, err := h.store.GetToken(ctx, tenantID, tokenID); err == nil && revoked != nil {
+			token = revoked
+		}
+	}
+
+	writeJSON(w, http.StatusOK, tokenInfo(token))
+}
+
+// getToken loads a tenant's token, writing the error response if it cannot.
+// Another tenant's token is reported as not found.
+func (h *TokensHandler) getToken(w http.ResponseWriter, r *http.Request, tenantID, tokenID string) (*store.Token, bool) {
+	requestID := middleware.GetReqID(r.Context())
+	token, err := h.store.GetToken(r.Context(), tenantID, tokenID)
+	if err != nil {
+		h.logger.Error("failed to get token", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to get token", "STORE_ERROR", requestID)
+		return nil, false
+	}
+	if token == nil {
+		h.writeError(w, http.StatusNotFound, "token not found", "NOT_FOUND", requestID)
+		return nil, false
+	}
+	return token, true
+}
+
+// validateTokenGrant checks a new token's type, principal and scopes against
+// the creator's role, returning a message if they are not allowed.
+func validateTokenGrant(tc *auth.TenantContext, tokenType, principalID string, scopes []string) string {
+	switch tokenType {
+	case "agent":
+		if principalID == "" {
+			return "principal_id is required for agent tokens"
+		}
+	case "developer":
+		if principalID != "" {
+			return "developer tokens cannot be bound to a principal"
+		}
+	default:
+		return "type must be 'developer' or 'agent'"
+	}
+
+	for _, s := range scopes {
+		scope := auth.Scope(s)
+		if !auth.ValidScope(scope) {
+			return "unknown scope: " + s
+		}
+		if scope == auth.ScopeEvaluate {
+			continue
+		}
+		if tokenType == "agent" {
+			return "agent tokens may only hold the evaluate scope"
+		}
+		// A token cannot grant more than its creator holds
+		if tc == nil || !tc.Role.HasScope(scope) {
+			return "cannot grant scope not held by your role: " + s
+		}
+	}
+	return ""
+}
+
+// tokenInfo summarizes a token, reporting an active token past its expiry as expired.
+func tokenInfo(token *store.Token) TokenInfo {
+	info := TokenInfo{
+		TokenID:     token.TokenID,
+		KeyPrefix:   token.KeyPrefix,
+		Name:        token.Name,
+		Type:        token.Type,
+		PrincipalID: token.PrincipalID,
+		Scopes:      token.Scopes,
+		Status:      token.Status,
+		CreatedAt:   token.CreatedAt,
+		CreatedBy:   token.CreatedBy,
+		ExpiresAt:   optionalTime(token.ExpiresAt),
+		LastUsedAt:  optionalTime(token.LastUsedAt),
+		RevokedAt:   optionalTime(token.RevokedAt),
+		RevokedBy:   token.RevokedBy,
+	}
+	if info.Scopes == nil {
+		info.Scopes = []string{}
+	}
+	if info.Status == "active" && info.ExpiresAt != nil && !info.ExpiresAt.After(time.Now()) {
+		info.Status = "expired"
+	}
+	return info
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// writeError writes an error response.
+func (h *TokensHandler) writeError(w http.ResponseWriter, status int, message, code, requestID string) {
+	resp := types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	}
+	writeJSON(w, status, resp)
+}
@@ -13,6 +13,7 @@ import (
 	"go.uber.org/zap"
 
 	"invarity/internal/auth"
+	"invarity/internal/firewall"
 	"invarity/internal/store"
 	"invarity/internal/types"
 	"invarity/internal/util"
@@ -20,17 +21,19 @@ import (
 
 // ToolsHandler handles tool management endpoints.
 type ToolsHandler struct {
-	store    *store.DynamoDBStore
-	s3Client *store.S3Client
-	logger   *zap.Logger
+	store     store.Store
+	manifests store.BlobStore
+	cache     *firewall.ResolverCache // Optional: invalidated when tools change
+	logger    *zap.Logger
 }
 
 // NewToolsHandler creates a new tools handler.
-func NewToolsHandler(ddbStore *store.DynamoDBStore, s3Client *store.S3Client, logger *zap.Logger) *ToolsHandler {
+func NewToolsHandler(controlPlane store.Store, manifests store.BlobStore, cache *firewall.ResolverCache, logger *zap.Logger) *ToolsHandler {
 	return &ToolsHandler{
-		store:    ddbStore,
-		s3Client: s3Client,
-		logger:   logger,
+		store:     controlPlane,
+		manifests: manifests,
+		cache:     cache,
+		logger:    logger,
 	}
 }
 
@@ -79,16 +82,20 @@ func (h *ToolsHandler) HandleRegisterTool(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// Compute schema hash if not provided
-	if manifest.SchemaHash == "" {
-		canonical, err := util.CanonicalJSON(manifest.ArgsSchema)
-		if err != nil {
-			h.writeError(w, http.StatusBadRequest, "failed to canonicalize args_schema", "SCHEMA_ERROR", requestID)
-			return
-		}
-		hash := sha256.Sum256(canonical)
-		manifest.SchemaHash = hex.EncodeToString(hash[:])
+	// Compute the schema hash; one the client provided must agree with it, as
+	// the resolver re-checks stored manifests against it
+	canonical, err := util.CanonicalJSON(manifest.ArgsSchema)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "failed to canonicalize args_schema", "SCHEMA_ERROR", requestID)
+		return
+	}
+	hash := sha256.Sum256(canonical)
+	schemaHash := hex.EncodeToString(hash[:])
+	if manifest.SchemaHash != "" && manifest.SchemaHash != schemaHash {
+		h.writeError(w, http.StatusBadRequest, "schema_hash does not match args_schema (expected "+schemaHash+")", "SCHEMA_HASH_MISMATCH", requestID)
+		return
 	}
+	manifest.SchemaHash = schemaHash
 
 	// Set timestamps
 	now := time.Now().UTC()
@@ -97,7 +104,7 @@ func (h *ToolsHandler) HandleRegisterTool(w http.ResponseWriter, r *http.Request
 	}
 	manifest.UpdatedAt = now
 
-	// Determine S3 key
+	// Determine manifest key
 	s3Key := store.ToolManifestKey(tenantID, manifest.ToolID, manifest.Version)
 
 	// Get risk level for metadata
@@ -106,7 +113,7 @@ func (h *ToolsHandler) HandleRegisterTool(w http.ResponseWriter, r *http.Request
 		riskLevel = "LOW"
 	}
 
-	// Try to upsert tool metadata in DynamoDB
+	// Try to upsert tool metadata
 	isNew, err := h.store.UpsertTool(ctx, tenantID, manifest.ToolID, manifest.Version, manifest.SchemaHash, manifest.Name, riskLevel, s3Key)
 	if err != nil {
 		// Check if it's a conflict error
@@ -119,15 +126,27 @@ func (h *ToolsHandler) HandleRegisterTool(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	// If new, store manifest in S3
-	if isNew && h.s3Client != nil {
-		if err := h.s3Client.PutJSON(ctx, s3Key, manifest); err != nil {
-			h.logger.Error("failed to store manifest in S3", zap.Error(err), zap.String("s3_key", s3Key))
-			// Note: We don't fail the request since DynamoDB has the metadata
+	// If new, store manifest
+	if isNew && h.manifests != nil {
+		if err := h.manifests.PutJSON(ctx, s3Key, manifest); err != nil {
+			h.logger.Error("failed to store manifest", zap.Error(err), zap.String("s3_key", s3Key))
+			// Note: We don't fail the request since the control plane store has the metadata
 			// In production, you might want to handle this differently
 		}
 	}
 
+	// A manifest with deprecated set deprecates the version, so re-registering
+	// an existing version with it deprecates that version
+	if manifest.Deprecated {
+		if err := h.store.DeprecateTool(ctx, tenantID, manifest.ToolID, manifest.Version); err != nil {
+			h.logger.Error("failed to deprecate tool", zap.Error(err))
+			h.writeError(w, http.StatusInternalServerError, "failed to deprecate tool", "STORE_ERROR", requestID)
+			return
+		}
+	}
+
+	h.cache.InvalidateTool(tenantID, manifest.ToolID)
+
 	h.logger.Info("tool registered",
 		zap.String("tenant_id", tenantID),
 		zap.String("tool_id", manifest.ToolID),
@@ -219,7 +238,7 @@ func (h *ToolsHandler) HandleGetTool(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get tool record from DynamoDB
+	// Get tool record
 	record, err := h.store.GetToolRecord(ctx, tenantID, toolID, version)
 	if err != nil {
 		h.logger.Error("failed to get tool", zap.Error(err))
@@ -231,11 +250,11 @@ func (h *ToolsHandler) HandleGetTool(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Try to get full manifest from S3
-	if h.s3Client != nil {
+	// Try to get full manifest
+	if h.manifests != nil {
 		var manifest types.ToolManifestV3
-		if err := h.s3Client.GetJSON(ctx, record.S3Key, &manifest); err != nil {
-			h.logger.Warn("failed to get manifest from S3, returning metadata only", zap.Error(err))
+		if err := h.manifests.GetJSON(ctx, record.S3Key, &manifest); err != nil {
+			h.logger.Warn("failed to get manifest, returning metadata only", zap.Error(err))
 			// Fall back to metadata-only response
 			writeJSON(w, http.StatusOK, ToolInfo{
 				ToolID:     record.ToolID,
@@ -251,7 +270,7 @@ func (h *ToolsHandler) HandleGetTool(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// No S3 client, return metadata only
+	// No manifest store, return metadata only
 	writeJSON(w, http.StatusOK, ToolInfo{
 		ToolID:     record.ToolID,
 		Version:    record.Version,
@@ -3,6 +3,7 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -11,23 +12,26 @@ import (
 	"go.uber.org/zap"
 
 	"invarity/internal/auth"
+	"invarity/internal/firewall"
 	"invarity/internal/store"
 	"invarity/internal/types"
 )
 
 // ToolsetsHandler handles toolset management endpoints.
 type ToolsetsHandler struct {
-	store    *store.DynamoDBStore
-	s3Client *store.S3Client
-	logger   *zap.Logger
+	store     store.Store
+	manifests store.BlobStore
+	cache     *firewall.ResolverCache // Optional: invalidated when toolsets or assignments change
+	logger    *zap.Logger
 }
 
 // NewToolsetsHandler creates a new toolsets handler.
-func NewToolsetsHandler(ddbStore *store.DynamoDBStore, s3Client *store.S3Client, logger *zap.Logger) *ToolsetsHandler {
+func NewToolsetsHandler(controlPlane store.Store, manifests store.BlobStore, cache *firewall.ResolverCache, logger *zap.Logger) *ToolsetsHandler {
 	return &ToolsetsHandler{
-		store:    ddbStore,
-		s3Client: s3Client,
-		logger:   logger,
+		store:     controlPlane,
+		manifests: manifests,
+		cache:     cache,
+		logger:    logger,
 	}
 }
 
@@ -38,11 +42,12 @@ type RegisterToolsetRequest struct {
 
 // RegisterToolsetResponse is the response for POST /v1/tenants/{tenant_id}/toolsets.
 type RegisterToolsetResponse struct {
-	ToolsetID string `json:"toolset_id"`
-	Revision  string `json:"revision"`
-	ToolCount int    `json:"tool_count"`
-	IsNew     bool   `json:"is_new"`
-	S3Key     string `json:"s3_key"`
+	ToolsetID string                `json:"toolset_id"`
+	Revision  string                `json:"revision"`
+	ToolCount int                   `json:"tool_count"`
+	IsNew     bool                  `json:"is_new"`
+	S3Key     string                `json:"s3_key"`
+	Lock      []types.LockedToolRef `json:"lock"`
 }
 
 // HandleRegisterToolset handles POST /v1/tenants/{tenant_id}/toolsets.
@@ -71,18 +76,16 @@ func (h *ToolsetsHandler) HandleRegisterToolset(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// Verify all referenced tools exist
-	for _, ref := range manifest.Tools {
-		tool, err := h.store.GetToolRecord(ctx, tenantID, ref.ToolID, ref.Version)
-		if err != nil {
-			h.logger.Error("failed to verify tool reference", zap.Error(err))
-			h.writeError(w, http.StatusInternalServerError, "failed to verify tool references", "STORE_ERROR", requestID)
-			return
-		}
-		if tool == nil {
-			h.writeError(w, http.StatusBadRequest, "tool not found: "+ref.ToolID+" version "+ref.Version, "TOOL_NOT_FOUND", requestID)
+	// Resolve every reference, including semver ranges, into the lock stored
+	// with the revision. A client-supplied lock is replaced.
+	if err := firewall.LockToolset(ctx, h.store, tenantID, &manifest); err != nil {
+		if errors.Is(err, firewall.ErrToolNotFound) {
+			h.writeError(w, http.StatusBadRequest, err.Error(), "TOOL_NOT_FOUND", requestID)
 			return
 		}
+		h.logger.Error("failed to verify tool reference", zap.Error(err))
+		h.writeError(w, http.StatusInternalServerError, "failed to verify tool references", "STORE_ERROR", requestID)
+		return
 	}
 
 	// Set timestamps and creator
@@ -95,7 +98,7 @@ func (h *ToolsetsHandler) HandleRegisterToolset(w http.ResponseWriter, r *http.R
 		manifest.CreatedBy = authCtx.UserID
 	}
 
-	// Determine S3 key
+	// Determine manifest key
 	s3Key := store.ToolsetManifestKey(tenantID, manifest.ToolsetID, manifest.Revision)
 
 	// Get name for metadata
@@ -104,7 +107,7 @@ func (h *ToolsetsHandler) HandleRegisterToolset(w http.ResponseWriter, r *http.R
 		name = manifest.ToolsetID
 	}
 
-	// Try to register toolset in DynamoDB
+	// Try to register toolset
 	isNew, err := h.store.RegisterToolset(ctx, tenantID, manifest.ToolsetID, manifest.Revision, name, s3Key, authCtx.UserID, len(manifest.Tools))
 	if err != nil {
 		if isConflictError(err) {
@@ -116,13 +119,24 @@ func (h *ToolsetsHandler) HandleRegisterToolset(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	// If new, store manifest in S3
-	if isNew && h.s3Client != nil {
-		if err := h.s3Client.PutJSON(ctx, s3Key, manifest); err != nil {
-			h.logger.Error("failed to store toolset manifest in S3", zap.Error(err), zap.String("s3_key", s3Key))
+	// If new, store manifest
+	if isNew && h.manifests != nil {
+		if err := h.manifests.PutJSON(ctx, s3Key, manifest); err != nil {
+			h.logger.Error("failed to store toolset manifest", zap.Error(err), zap.String("s3_key", s3Key))
 		}
 	}
 
+	// An existing revision keeps the lock it was registered with
+	lock := manifest.Lock
+	if !isNew && h.manifests != nil {
+		var stored types.ToolsetManifest
+		if err := h.manifests.GetJSON(ctx, s3Key, &stored); err == nil {
+			lock = stored.Lock
+		}
+	}
+
+	h.cache.InvalidateTenant(tenantID)
+
 	h.logger.Info("toolset registered",
 		zap.String("tenant_id", tenantID),
 		zap.String("toolset_id", manifest.ToolsetID),
@@ -142,6 +156,7 @@ func (h *ToolsetsHandler) HandleRegisterToolset(w http.ResponseWriter, r *http.R
 		ToolCount: len(manifest.Tools),
 		IsNew:     isNew,
 		S3Key:     s3Key,
+		Lock:      lock,
 	})
 }
 
@@ -215,7 +230,7 @@ func (h *ToolsetsHandler) HandleGetToolset(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Get toolset record from DynamoDB
+	// Get toolset record
 	record, err := h.store.GetToolsetRecord(ctx, tenantID, toolsetID, revision)
 	if err != nil {
 		h.logger.Error("failed to get toolset", zap.Error(err))
@@ -227,11 +242,11 @@ func (h *ToolsetsHandler) HandleGetToolset(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Try to get full manifest from S3
-	if h.s3Client != nil {
+	// Try to get full manifest
+	if h.manifests != nil {
 		var manifest types.ToolsetManifest
-		if err := h.s3Client.GetJSON(ctx, record.S3Key, &manifest); err != nil {
-			h.logger.Warn("failed to get toolset manifest from S3, returning metadata only", zap.Error(err))
+		if err := h.manifests.GetJSON(ctx, record.S3Key, &manifest); err != nil {
+			h.logger.Warn("failed to get toolset manifest, returning metadata only", zap.Error(err))
 			writeJSON(w, http.StatusOK, ToolsetInfo{
 				ToolsetID: record.ToolsetID,
 				Revision:  record.Revision,
@@ -246,7 +261,7 @@ func (h *ToolsetsHandler) HandleGetToolset(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// No S3 client, return metadata only
+	// No manifest store, return metadata only
 	writeJSON(w, http.StatusOK, ToolsetInfo{
 		ToolsetID: record.ToolsetID,
 		Revision:  record.Revision,
@@ -301,8 +316,9 @@ func (h *ToolsetsHandler) HandleApplyToolset(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	// Verify principal exists and belongs to tenant
-	principal, err := h.store.GetPrincipal(ctx, principalID)
+	// Verify the principal belongs to the tenant; another tenant's principal
+	// is reported as not found
+	principal, err := h.store.GetPrincipal(ctx, tenantID, principalID)
 	if err != nil {
 		h.logger.Error("failed to get principal", zap.Error(err))
 		h.writeError(w, http.StatusInternalServerError, "failed to get principal", "STORE_ERROR", requestID)
@@ -312,10 +328,6 @@ func (h *ToolsetsHandler) HandleApplyToolset(w http.ResponseWriter, r *http.Requ
 		h.writeError(w, http.StatusNotFound, "principal not found", "NOT_FOUND", requestID)
 		return
 	}
-	if principal.TenantID != tenantID {
-		h.writeError(w, http.StatusForbidden, "principal does not belong to tenant", "FORBIDDEN", requestID)
-		return
-	}
 
 	// Verify toolset exists
 	toolset, err := h.store.GetToolsetRecord(ctx, tenantID, req.ToolsetID, req.Revision)
@@ -336,6 +348,8 @@ func (h *ToolsetsHandler) HandleApplyToolset(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	h.cache.InvalidatePrincipal(tenantID, principalID)
+
 	h.logger.Info("toolset applied to principal",
 		zap.String("tenant_id", tenantID),
 		zap.String("principal_id", principalID),
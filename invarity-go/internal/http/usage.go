@@ -0,0 +1,75 @@
+// Package http provides HTTP handlers and routing for the Invarity Firewall.
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"invarity/internal/types"
+	"invarity/internal/usage"
+)
+
+// UsageHandler handles LLM token usage reporting endpoints.
+type UsageHandler struct {
+	meter  *usage.Meter
+	logger *zap.Logger
+}
+
+// NewUsageHandler creates a new usage handler.
+func NewUsageHandler(meter *usage.Meter, logger *zap.Logger) *UsageHandler {
+	return &UsageHandler{
+		meter:  meter,
+		logger: logger,
+	}
+}
+
+// HandleTenantUsage handles GET /v1/tenants/{tenant_id}/usage.
+// Query params: period (YYYY-MM, default current month), group_by (principal, tool, model).
+func (h *UsageHandler) HandleTenantUsage(w http.ResponseWriter, r *http.Request) {
+	h.writeReport(w, r, chi.URLParam(r, "tenant_id"))
+}
+
+// HandleUsageReport handles GET /v1/admin/usage.
+// Query params: tenant_id (default all tenants), period, group_by (principal, tool, model, tenant).
+func (h *UsageHandler) HandleUsageReport(w http.ResponseWriter, r *http.Request) {
+	h.writeReport(w, r, r.URL.Query().Get("tenant_id"))
+}
+
+func (h *UsageHandler) writeReport(w http.ResponseWriter, r *http.Request, tenantID string) {
+	ctx := r.Context()
+	requestID := middleware.GetReqID(ctx)
+	query := r.URL.Query()
+
+	groupBy := query.Get("group_by")
+	if groupBy != "" && !usage.ValidGroupBy(groupBy) {
+		h.writeError(w, http.StatusBadRequest, "group_by must be principal, tool, model or tenant", "VALIDATION_ERROR", requestID)
+		return
+	}
+	if _, _, err := usage.ParsePeriod(query.Get("period"), time.Now()); err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error(), "VALIDATION_ERROR", requestID)
+		return
+	}
+
+	report, err := h.meter.Report(ctx, tenantID, query.Get("period"), groupBy)
+	if err != nil {
+		h.logger.Error("failed to build usage report", zap.Error(err), zap.String("tenant_id", tenantID))
+		h.writeError(w, http.StatusInternalServerError, "failed to build usage report", "INTERNAL_ERROR", requestID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+// writeError writes an error response.
+func (h *UsageHandler) writeError(w http.ResponseWriter, status int, message, code, requestID string) {
+	resp := types.ErrorResponse{
+		Error:     message,
+		Code:      code,
+		RequestID: requestID,
+	}
+	writeJSON(w, status, resp)
+}
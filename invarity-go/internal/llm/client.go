@@ -8,23 +8,42 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"invarity/internal/tracing"
+	"invarity/internal/types"
 )
 
+// ChatCompleter sends chat completion requests. It is implemented by Client and MockClient.
+type ChatCompleter interface {
+	ChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error)
+}
+
 // Client is an OpenAI-compatible API client.
 type Client struct {
 	baseURL    string
 	apiKey     string
 	httpClient *http.Client
 	model      string
+	tracer     trace.Tracer
 }
 
 // ClientConfig holds configuration for the LLM client.
 type ClientConfig struct {
-	BaseURL    string
-	APIKey     string
-	Model      string
-	Timeout    time.Duration
+	BaseURL   string
+	APIKey    string
+	Model     string
+	Timeout   time.Duration
+	Transport http.RoundTripper // Optional, defaults to http.DefaultTransport
+	// Tracer provider for request spans (optional, no spans are recorded without it)
+	TracerProvider trace.TracerProvider
 }
 
 // NewClient creates a new LLM client.
@@ -39,8 +58,10 @@ func NewClient(cfg ClientConfig) *Client {
 		apiKey:  cfg.APIKey,
 		model:   cfg.Model,
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: cfg.Transport,
 		},
+		tracer: tracing.Tracer(cfg.TracerProvider),
 	}
 }
 
@@ -85,11 +106,35 @@ type ChatCompletionResponse struct {
 }
 
 // ChatCompletion sends a chat completion request.
-func (c *Client) ChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+func (c *Client) ChatCompletion(ctx context.Context, req *ChatCompletionRequest) (resp *ChatCompletionResponse, err error) {
 	if req.Model == "" {
 		req.Model = c.model
 	}
 
+	ctx, span := c.tracer.Start(ctx, "chat "+req.Model, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("gen_ai.operation.name", "chat"),
+			attribute.String("gen_ai.request.model", req.Model),
+		))
+	if u, err := url.Parse(c.baseURL); err == nil {
+		span.SetAttributes(attribute.String("server.address", u.Hostname()))
+	}
+	defer func() {
+		if resp != nil {
+			span.SetAttributes(
+				attribute.String("gen_ai.response.model", resp.Model),
+				attribute.Int("gen_ai.usage.input_tokens", resp.Usage.PromptTokens),
+				attribute.Int("gen_ai.usage.output_tokens", resp.Usage.CompletionTokens),
+			)
+		}
+		tracing.End(span, err)
+	}()
+
+	return c.chatCompletion(ctx, req)
+}
+
+// chatCompletion sends the request with the caller's trace context.
+func (c *Client) chatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
 	body, err := json.Marshal(req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
@@ -104,12 +149,14 @@ func (c *Client) ChatCompletion(ctx context.Context, req *ChatCompletionRequest)
 	if c.apiKey != "" {
 		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
+	tracing.Propagator.Inject(ctx, propagation.HeaderCarrier(httpReq.Header))
 
 	resp, err := c.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -124,10 +171,59 @@ func (c *Client) ChatCompletion(ctx context.Context, req *ChatCompletionRequest)
 	if err := json.Unmarshal(respBody, &chatResp); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
+	if chatResp.Model == "" {
+		chatResp.Model = req.Model
+	}
 
 	return &chatResp, nil
 }
 
+// Probe checks that the endpoint is up and serves the client's model by listing
+// its models, which costs no tokens. Endpoints that list no models pass.
+func (c *Client) Probe(ctx context.Context) error {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(respBody))
+	}
+
+	var models struct {
+		Data []struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(respBody, &models); err != nil {
+		return fmt.Errorf("failed to unmarshal models: %w", err)
+	}
+	if len(models.Data) == 0 || c.model == "" {
+		return nil
+	}
+	served := make([]string, 0, len(models.Data))
+	for _, m := range models.Data {
+		if m.ID == c.model {
+			return nil
+		}
+		served = append(served, m.ID)
+	}
+	return fmt.Errorf("model %s is not served (serving %s)", c.model, strings.Join(served, ", "))
+}
+
 // ExtractContent extracts the content from the first choice.
 func (r *ChatCompletionResponse) ExtractContent() string {
 	if len(r.Choices) == 0 {
@@ -136,6 +232,23 @@ func (r *ChatCompletionResponse) ExtractContent() string {
 	return r.Choices[0].Message.Content
 }
 
+// TokenUsage returns the token counts reported for the call, or nil if the endpoint reported none.
+func (r *ChatCompletionResponse) TokenUsage() *types.TokenUsage {
+	if r.Usage.PromptTokens == 0 && r.Usage.CompletionTokens == 0 && r.Usage.TotalTokens == 0 {
+		return nil
+	}
+	total := r.Usage.TotalTokens
+	if total == 0 {
+		total = r.Usage.PromptTokens + r.Usage.CompletionTokens
+	}
+	return &types.TokenUsage{
+		Model:            r.Model,
+		PromptTokens:     r.Usage.PromptTokens,
+		CompletionTokens: r.Usage.CompletionTokens,
+		TotalTokens:      total,
+	}
+}
+
 // ExtractJSON extracts and parses JSON from the response content.
 func (r *ChatCompletionResponse) ExtractJSON(v any) error {
 	content := r.ExtractContent()
@@ -147,8 +260,11 @@ func (r *ChatCompletionResponse) ExtractJSON(v any) error {
 
 // MockClient is a mock LLM client for testing.
 type MockClient struct {
+	mu        sync.Mutex
 	responses map[string]*ChatCompletionResponse
 	errors    map[string]error
+	handler   func(req *ChatCompletionRequest) (*ChatCompletionResponse, error)
+	requests  []*ChatCompletionRequest
 }
 
 // NewMockClient creates a new mock client.
@@ -159,48 +275,98 @@ func NewMockClient() *MockClient {
 	}
 }
 
+// NewMockResponse builds a single-choice completion response with the given content.
+func NewMockResponse(content string) *ChatCompletionResponse {
+	return &ChatCompletionResponse{
+		ID:      "mock-response",
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Choices: []struct {
+			Index        int         `json:"index"`
+			Message      ChatMessage `json:"message"`
+			FinishReason string      `json:"finish_reason"`
+		}{
+			{
+				Index:        0,
+				Message:      ChatMessage{Role: "assistant", Content: content},
+				FinishReason: "stop",
+			},
+		},
+	}
+}
+
 // SetResponse sets a canned response for a given prompt pattern.
+// The pattern "*" matches every request; any other pattern matches requests
+// whose messages contain it.
 func (m *MockClient) SetResponse(pattern string, resp *ChatCompletionResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.responses[pattern] = resp
 }
 
 // SetError sets a canned error for a given prompt pattern.
 func (m *MockClient) SetError(pattern string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.errors[pattern] = err
 }
 
+// SetHandler sets a function that computes the response from the request.
+// It takes precedence over canned responses and errors, which lets tests
+// build responses from per-request prompt content such as boundary tokens.
+func (m *MockClient) SetHandler(fn func(req *ChatCompletionRequest) (*ChatCompletionResponse, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handler = fn
+}
+
+// Requests returns the requests received so far.
+func (m *MockClient) Requests() []*ChatCompletionRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*ChatCompletionRequest(nil), m.requests...)
+}
+
 // ChatCompletion returns mock responses.
 func (m *MockClient) ChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	m.mu.Lock()
+	m.requests = append(m.requests, req)
+	handler := m.handler
+	m.mu.Unlock()
+
+	if handler != nil {
+		return handler(req)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	// Check for errors first
 	for pattern, err := range m.errors {
-		if pattern == "*" {
+		if pattern == "*" || requestContains(req, pattern) {
 			return nil, err
 		}
 	}
 
-	// Return default response
+	// Return matching response
 	for pattern, resp := range m.responses {
-		if pattern == "*" {
+		if pattern == "*" || requestContains(req, pattern) {
 			return resp, nil
 		}
 	}
 
 	// Default mock response
-	return &ChatCompletionResponse{
-		ID:      "mock-response",
-		Object:  "chat.completion",
-		Created: time.Now().Unix(),
-		Model:   req.Model,
-		Choices: []struct {
-			Index        int         `json:"index"`
-			Message      ChatMessage `json:"message"`
-			FinishReason string      `json:"finish_reason"`
-		}{
-			{
-				Index:        0,
-				Message:      ChatMessage{Role: "assistant", Content: "{}"},
-				FinishReason: "stop",
-			},
-		},
-	}, nil
+	resp := NewMockResponse("{}")
+	resp.Model = req.Model
+	return resp, nil
+}
+
+// requestContains reports whether any message in req contains pattern.
+func requestContains(req *ChatCompletionRequest, pattern string) bool {
+	for _, msg := range req.Messages {
+		if strings.Contains(msg.Content, pattern) {
+			return true
+		}
+	}
+	return false
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"invarity/internal/prompts"
 	"invarity/internal/types"
 )
 
@@ -20,15 +21,31 @@ type IntentVoter interface {
 
 // IntentVoterResponse is the expected JSON output from each intent voter.
 type IntentVoterResponse struct {
-	Vote       string   `json:"vote"`       // "SAFE", "DENY", "ABSTAIN"
-	Confidence float64  `json:"confidence"` // 0.0 - 1.0
-	Reasons    []string `json:"reasons"`
+	Vote       string            `json:"vote"`       // "SAFE", "DENY", "ABSTAIN"
+	Confidence float64           `json:"confidence"` // 0.0 - 1.0
+	Reasons    []string          `json:"reasons"`
+	Model      string            `json:"-"` // Model reported by the endpoint
+	Usage      *types.TokenUsage `json:"-"` // Tokens reported by the endpoint
 }
 
 // baseIntentVoter provides common functionality for intent voters.
 type baseIntentVoter struct {
-	id     string
-	client *Client
+	id      string
+	client  ChatCompleter
+	prompts *prompts.Registry
+}
+
+// newBaseIntentVoter creates the shared voter state.
+// A nil registry falls back to the embedded default templates.
+func newBaseIntentVoter(id string, client ChatCompleter, registry *prompts.Registry) baseIntentVoter {
+	if registry == nil {
+		registry = prompts.MustNewRegistry()
+	}
+	return baseIntentVoter{
+		id:      id,
+		client:  client,
+		prompts: registry,
+	}
 }
 
 // VoterID returns the voter's unique identifier.
@@ -37,7 +54,9 @@ func (v *baseIntentVoter) VoterID() string {
 }
 
 // callModel sends a prompt to the model and parses the response.
-func (v *baseIntentVoter) callModel(ctx context.Context, prompt string) (*IntentVoterResponse, error) {
+// Responses that fail output validation (boundary echo, multiple JSON objects,
+// schema violation) are converted to ABSTAIN with the rejection reason.
+func (v *baseIntentVoter) callModel(ctx context.Context, prompt, boundary string) (*IntentVoterResponse, error) {
 	chatReq := &ChatCompletionRequest{
 		Messages: []ChatMessage{
 			{Role: "user", Content: prompt},
@@ -55,29 +74,90 @@ func (v *baseIntentVoter) callModel(ctx context.Context, prompt string) (*Intent
 	}
 
 	var voterResp IntentVoterResponse
-	if err := resp.ExtractJSON(&voterResp); err != nil {
-		// Malformed response → ABSTAIN
+	if err := decodeModelOutput(resp, boundary, intentVoterSchema, &voterResp); err != nil {
+		// Rejected response → ABSTAIN
 		return &IntentVoterResponse{
 			Vote:       "ABSTAIN",
 			Confidence: 0.0,
-			Reasons:    []string{"malformed_response"},
+			Reasons:    []string{rejectionReason(err)},
+			Model:      resp.Model,
+			Usage:      resp.TokenUsage(),
 		}, nil
 	}
+	voterResp.Model = resp.Model
+	voterResp.Usage = resp.TokenUsage()
 
-	// Validate vote value
-	switch voterResp.Vote {
-	case "SAFE", "DENY", "ABSTAIN":
-		// Valid
-	default:
-		// Unknown vote → ABSTAIN
-		return &IntentVoterResponse{
-			Vote:       "ABSTAIN",
+	return &voterResp, nil
+}
+
+// intentData builds the template data shared by all intent voters.
+// Untrusted fields are encoded as data blocks delimited by boundary.
+func intentData(intentCtx *types.IntentContext, boundary string) prompts.IntentData {
+	argsStr := string(intentCtx.Args)
+	if len(argsStr) > 2000 {
+		argsStr = argsStr[:2000] + "...[truncated]"
+	}
+
+	requiredFieldsJSON, _ := json.Marshal(intentCtx.RequiredFields)
+
+	return prompts.IntentData{
+		Boundary:        boundary,
+		IntentSummary:   prompts.DataBlock(boundary, "USER_INTENT", intentCtx.IntentSummary),
+		ToolName:        intentCtx.ToolName,
+		ToolDescription: prompts.DataBlock(boundary, "TOOL_DESCRIPTION", intentCtx.ToolDescription),
+		Args:            prompts.DataBlock(boundary, "ARGS", argsStr),
+		Operation:       intentCtx.Operation,
+		ResourceScope:   intentCtx.ResourceScope,
+		SideEffectScope: intentCtx.SideEffectScope,
+		Bulk:            intentCtx.Bulk,
+		RequiredFields:  string(requiredFieldsJSON),
+	}
+}
+
+// vote renders the voter's template for the tenant, calls the model and builds the result.
+func (v *baseIntentVoter) vote(ctx context.Context, intentCtx *types.IntentContext) (*types.IntentVoterResult, error) {
+	start := time.Now()
+
+	boundary, err := prompts.NewBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := v.prompts.Render(intentCtx.TenantID, v.id, intentData(intentCtx, boundary))
+	if err != nil {
+		// Template failure → ABSTAIN
+		return &types.IntentVoterResult{
+			VoterID:    v.id,
+			Vote:       types.IntentVoteAbstain,
 			Confidence: 0.0,
-			Reasons:    []string{"invalid_vote_value"},
+			Reasons:    []string{"prompt_template_error"},
+			Latency:    types.Duration(time.Since(start)),
 		}, nil
 	}
 
-	return &voterResp, nil
+	resp, err := v.callModel(ctx, rendered.Text, boundary)
+	if err != nil {
+		// Network error / timeout → ABSTAIN
+		return &types.IntentVoterResult{
+			VoterID:       v.id,
+			Vote:          types.IntentVoteAbstain,
+			Confidence:    0.0,
+			Reasons:       []string{"voter_error"},
+			PromptVersion: rendered.Ref,
+			Latency:       types.Duration(time.Since(start)),
+		}, nil
+	}
+
+	return &types.IntentVoterResult{
+		VoterID:       v.id,
+		Vote:          parseIntentVote(resp.Vote),
+		Confidence:    resp.Confidence,
+		Reasons:       resp.Reasons,
+		Model:         resp.Model,
+		PromptVersion: rendered.Ref,
+		Usage:         resp.Usage,
+		Latency:       types.Duration(time.Since(start)),
+	}, nil
 }
 
 // parseIntentVote converts a string vote to IntentVote type.
@@ -100,59 +180,15 @@ type LiteralIntentVoter struct {
 }
 
 // NewLiteralIntentVoter creates a new LiteralIntentVoter.
-func NewLiteralIntentVoter(client *Client) *LiteralIntentVoter {
+func NewLiteralIntentVoter(client ChatCompleter, registry *prompts.Registry) *LiteralIntentVoter {
 	return &LiteralIntentVoter{
-		baseIntentVoter: baseIntentVoter{
-			id:     "literal_authorization",
-			client: client,
-		},
+		baseIntentVoter: newBaseIntentVoter(prompts.LiteralAuthorization, client, registry),
 	}
 }
 
 // Vote evaluates if the tool call matches what the user explicitly requested.
 func (v *LiteralIntentVoter) Vote(ctx context.Context, intentCtx *types.IntentContext) (*types.IntentVoterResult, error) {
-	start := time.Now()
-
-	argsStr := string(intentCtx.Args)
-	if len(argsStr) > 2000 {
-		argsStr = argsStr[:2000] + "...[truncated]"
-	}
-
-	prompt := fmt.Sprintf(`Return ONLY valid JSON:
-{"vote":"SAFE|DENY|ABSTAIN","confidence":0-1,"reasons":[...]}
-
-SAFE only if the user clearly and explicitly requested this exact action and scope.
-DENY if the tool call exceeds, changes, or adds actions the user did not request.
-ABSTAIN if intent is ambiguous or authorization is incomplete.
-
-USER_INTENT_SUMMARY:
-%s
-
-TOOL:
-%s — %s
-
-ARGS:
-%s`, intentCtx.IntentSummary, intentCtx.ToolName, intentCtx.ToolDescription, argsStr)
-
-	resp, err := v.callModel(ctx, prompt)
-	if err != nil {
-		// Network error / timeout → ABSTAIN
-		return &types.IntentVoterResult{
-			VoterID:    v.id,
-			Vote:       types.IntentVoteAbstain,
-			Confidence: 0.0,
-			Reasons:    []string{"voter_error"},
-			Latency:    types.Duration(time.Since(start)),
-		}, nil
-	}
-
-	return &types.IntentVoterResult{
-		VoterID:    v.id,
-		Vote:       parseIntentVote(resp.Vote),
-		Confidence: resp.Confidence,
-		Reasons:    resp.Reasons,
-		Latency:    types.Duration(time.Since(start)),
-	}, nil
+	return v.vote(ctx, intentCtx)
 }
 
 // ScopeAuditVoter (Voter B) detects dangerous scope expansion or unsafe defaults.
@@ -161,67 +197,15 @@ type ScopeAuditVoter struct {
 }
 
 // NewScopeAuditVoter creates a new ScopeAuditVoter.
-func NewScopeAuditVoter(client *Client) *ScopeAuditVoter {
+func NewScopeAuditVoter(client ChatCompleter, registry *prompts.Registry) *ScopeAuditVoter {
 	return &ScopeAuditVoter{
-		baseIntentVoter: baseIntentVoter{
-			id:     "scope_auditor",
-			client: client,
-		},
+		baseIntentVoter: newBaseIntentVoter(prompts.ScopeAuditor, client, registry),
 	}
 }
 
 // Vote evaluates if the tool call has dangerous scope expansion.
 func (v *ScopeAuditVoter) Vote(ctx context.Context, intentCtx *types.IntentContext) (*types.IntentVoterResult, error) {
-	start := time.Now()
-
-	argsStr := string(intentCtx.Args)
-	if len(argsStr) > 2000 {
-		argsStr = argsStr[:2000] + "...[truncated]"
-	}
-
-	bulkStr := "false"
-	if intentCtx.Bulk {
-		bulkStr = "true"
-	}
-
-	prompt := fmt.Sprintf(`Return ONLY valid JSON:
-{"vote":"SAFE|DENY|ABSTAIN","confidence":0-1,"reasons":[...]}
-
-DENY if arguments imply broader scope than user intent
-(e.g. missing filters, wildcards, global effects).
-ABSTAIN if scope cannot be confidently determined.
-
-USER_INTENT_SUMMARY:
-%s
-
-ARGS:
-%s
-
-RISK_HINTS:
-operation=%s
-resource_scope=%s
-side_effect_scope=%s
-bulk=%s`, intentCtx.IntentSummary, argsStr, intentCtx.Operation, intentCtx.ResourceScope, intentCtx.SideEffectScope, bulkStr)
-
-	resp, err := v.callModel(ctx, prompt)
-	if err != nil {
-		// Network error / timeout → ABSTAIN
-		return &types.IntentVoterResult{
-			VoterID:    v.id,
-			Vote:       types.IntentVoteAbstain,
-			Confidence: 0.0,
-			Reasons:    []string{"voter_error"},
-			Latency:    types.Duration(time.Since(start)),
-		}, nil
-	}
-
-	return &types.IntentVoterResult{
-		VoterID:    v.id,
-		Vote:       parseIntentVote(resp.Vote),
-		Confidence: resp.Confidence,
-		Reasons:    resp.Reasons,
-		Latency:    types.Duration(time.Since(start)),
-	}, nil
+	return v.vote(ctx, intentCtx)
 }
 
 // PreconditionsVoter (Voter C) checks if required details are missing.
@@ -230,58 +214,13 @@ type PreconditionsVoter struct {
 }
 
 // NewPreconditionsVoter creates a new PreconditionsVoter.
-func NewPreconditionsVoter(client *Client) *PreconditionsVoter {
+func NewPreconditionsVoter(client ChatCompleter, registry *prompts.Registry) *PreconditionsVoter {
 	return &PreconditionsVoter{
-		baseIntentVoter: baseIntentVoter{
-			id:     "preconditions_checker",
-			client: client,
-		},
+		baseIntentVoter: newBaseIntentVoter(prompts.PreconditionsChecker, client, registry),
 	}
 }
 
 // Vote evaluates if required preconditions are met.
 func (v *PreconditionsVoter) Vote(ctx context.Context, intentCtx *types.IntentContext) (*types.IntentVoterResult, error) {
-	start := time.Now()
-
-	argsStr := string(intentCtx.Args)
-	if len(argsStr) > 2000 {
-		argsStr = argsStr[:2000] + "...[truncated]"
-	}
-
-	requiredFieldsJSON, _ := json.Marshal(intentCtx.RequiredFields)
-
-	prompt := fmt.Sprintf(`Return ONLY valid JSON:
-{"vote":"SAFE|DENY|ABSTAIN","confidence":0-1,"reasons":[...]}
-
-ABSTAIN if key information is missing or unclear.
-DENY if execution would likely violate user intent due to missing preconditions.
-
-USER_INTENT_SUMMARY:
-%s
-
-REQUIRED_FIELDS:
-%s
-
-ARGS:
-%s`, intentCtx.IntentSummary, string(requiredFieldsJSON), argsStr)
-
-	resp, err := v.callModel(ctx, prompt)
-	if err != nil {
-		// Network error / timeout → ABSTAIN
-		return &types.IntentVoterResult{
-			VoterID:    v.id,
-			Vote:       types.IntentVoteAbstain,
-			Confidence: 0.0,
-			Reasons:    []string{"voter_error"},
-			Latency:    types.Duration(time.Since(start)),
-		}, nil
-	}
-
-	return &types.IntentVoterResult{
-		VoterID:    v.id,
-		Vote:       parseIntentVote(resp.Vote),
-		Confidence: resp.Confidence,
-		Reasons:    resp.Reasons,
-		Latency:    types.Duration(time.Since(start)),
-	}, nil
+	return v.vote(ctx, intentCtx)
 }
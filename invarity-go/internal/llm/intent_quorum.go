@@ -6,6 +6,12 @@ import (
 	"sync"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"invarity/internal/calibration"
+	"invarity/internal/prompts"
+	"invarity/internal/tracing"
 	"invarity/internal/types"
 )
 
@@ -13,6 +19,15 @@ import (
 type IntentQuorumConfig struct {
 	// Timeout for each voter call (default: 1.5s)
 	VoterTimeout time.Duration
+	// Calibrator maps raw voter confidence to calibrated probabilities (optional;
+	// without it, raw confidence is used as the probability)
+	Calibrator *calibration.Calibrator
+	// SAFE votes below this calibrated probability count as ABSTAIN (default: 0, off)
+	MinSafeProbability float64
+	// DENY votes below this calibrated probability count as ABSTAIN (default: 0, off)
+	MinDenyProbability float64
+	// Tracer provider for voter spans (optional, no spans are recorded without it)
+	TracerProvider trace.TracerProvider
 }
 
 // DefaultIntentQuorumConfig returns the default configuration.
@@ -26,6 +41,7 @@ func DefaultIntentQuorumConfig() *IntentQuorumConfig {
 type IntentQuorum struct {
 	voters []IntentVoter
 	config *IntentQuorumConfig
+	tracer trace.Tracer
 }
 
 // NewIntentQuorum creates a new intent alignment quorum.
@@ -33,23 +49,29 @@ type IntentQuorum struct {
 // - LiteralIntentVoter (Voter A)
 // - ScopeAuditVoter (Voter B)
 // - PreconditionsVoter (Voter C)
-func NewIntentQuorum(client *Client, config *IntentQuorumConfig) *IntentQuorum {
+// Voter prompts are rendered from the given template registry (nil uses the embedded defaults).
+func NewIntentQuorum(client ChatCompleter, registry *prompts.Registry, config *IntentQuorumConfig) *IntentQuorum {
 	if config == nil {
 		config = DefaultIntentQuorumConfig()
 	}
+	if registry == nil {
+		registry = prompts.MustNewRegistry()
+	}
 
 	return &IntentQuorum{
 		voters: []IntentVoter{
-			NewLiteralIntentVoter(client),
-			NewScopeAuditVoter(client),
-			NewPreconditionsVoter(client),
+			NewLiteralIntentVoter(client, registry),
+			NewScopeAuditVoter(client, registry),
+			NewPreconditionsVoter(client, registry),
 		},
 		config: config,
+		tracer: tracing.Tracer(config.TracerProvider),
 	}
 }
 
 // IntentQuorumRequest contains the data for intent alignment evaluation.
 type IntentQuorumRequest struct {
+	TenantID    string // Selects tenant prompt overrides and criteria
 	UserIntent  string
 	ToolCall    types.ToolCall
 	Tool        *types.ToolRegistryEntry
@@ -77,8 +99,17 @@ func (q *IntentQuorum) Run(ctx context.Context, req *IntentQuorumRequest) (*type
 			// Create a context with timeout for this voter
 			voterCtx, cancel := context.WithTimeout(ctx, q.config.VoterTimeout)
 			defer cancel()
+			voterCtx, span := q.tracer.Start(voterCtx, "intent_voter "+v.VoterID(),
+				trace.WithAttributes(attribute.String("invarity.voter_id", v.VoterID())))
 
 			result, err := v.Vote(voterCtx, intentCtx)
+			if result != nil {
+				span.SetAttributes(
+					attribute.String("invarity.vote", string(result.Vote)),
+					attribute.Float64("invarity.confidence", result.Confidence),
+				)
+			}
+			tracing.End(span, err)
 			if err != nil {
 				// Error → ABSTAIN
 				results[idx] = types.IntentVoterResult{
@@ -96,16 +127,76 @@ func (q *IntentQuorum) Run(ctx context.Context, req *IntentQuorumRequest) (*type
 
 	wg.Wait()
 
-	// Aggregate votes using the specified rules
-	decision := aggregateIntentVotes(results)
-
 	return &types.IntentAlignmentResult{
 		Voters:   results,
-		Decision: decision,
+		Decision: q.calibrateAndAggregate(results),
 		Latency:  types.Duration(time.Since(start)),
 	}, nil
 }
 
+// Reaggregate recalibrates recorded votes and aggregates them under the quorum's
+// current calibration and thresholds, without calling the voters. The recorded
+// result is not modified.
+func (q *IntentQuorum) Reaggregate(recorded *types.IntentAlignmentResult) *types.IntentAlignmentResult {
+	results := withoutThresholdReasons(recorded.Voters)
+	return &types.IntentAlignmentResult{
+		Voters:   results,
+		Decision: q.calibrateAndAggregate(results),
+		Latency:  recorded.Latency,
+	}
+}
+
+// ReaggregateCalibrated aggregates recorded votes under the quorum's current
+// thresholds using the calibrated confidences recorded with them, as they were
+// when the decision was made. The recorded result is not modified.
+func (q *IntentQuorum) ReaggregateCalibrated(recorded *types.IntentAlignmentResult) *types.IntentAlignmentResult {
+	results := withoutThresholdReasons(recorded.Voters)
+	return &types.IntentAlignmentResult{
+		Voters:   results,
+		Decision: q.aggregate(results),
+		Latency:  recorded.Latency,
+	}
+}
+
+// withoutThresholdReasons copies recorded votes without the reason the recorded
+// thresholds added; aggregation re-derives it.
+func withoutThresholdReasons(voters []types.IntentVoterResult) []types.IntentVoterResult {
+	results := make([]types.IntentVoterResult, len(voters))
+	for i, v := range voters {
+		reasons := make([]string, 0, len(v.Reasons))
+		for _, reason := range v.Reasons {
+			if reason != "low_calibrated_confidence" {
+				reasons = append(reasons, reason)
+			}
+		}
+		v.Reasons = reasons
+		results[i] = v
+	}
+	return results
+}
+
+// calibrateAndAggregate calibrates confidences, then aggregates votes using the
+// specified rules.
+func (q *IntentQuorum) calibrateAndAggregate(results []types.IntentVoterResult) types.IntentDecision {
+	for i := range results {
+		r := &results[i]
+		r.CalibratedConfidence, _ = q.config.Calibrator.Calibrate(r.VoterID, r.Model, r.Confidence)
+	}
+	return q.aggregate(results)
+}
+
+// aggregate flags votes below the probability thresholds and aggregates the
+// votes by their calibrated confidence.
+func (q *IntentQuorum) aggregate(results []types.IntentVoterResult) types.IntentDecision {
+	for i := range results {
+		r := &results[i]
+		if isLowConfidenceVote(*r, q.config.MinSafeProbability, q.config.MinDenyProbability) {
+			r.Reasons = append(r.Reasons, "low_calibrated_confidence")
+		}
+	}
+	return aggregateCalibratedIntentVotes(results, q.config.MinSafeProbability, q.config.MinDenyProbability)
+}
+
 // buildIntentContext creates an IntentContext from the request.
 func (q *IntentQuorum) buildIntentContext(req *IntentQuorumRequest) *types.IntentContext {
 	toolName := "unknown"
@@ -149,6 +240,7 @@ func (q *IntentQuorum) buildIntentContext(req *IntentQuorumRequest) *types.Inten
 	}
 
 	return &types.IntentContext{
+		TenantID:        req.TenantID,
 		IntentSummary:   req.UserIntent,
 		ToolName:        toolName,
 		ToolDescription: toolDesc,
@@ -213,6 +305,36 @@ func aggregateIntentVotes(votes []types.IntentVoterResult) types.IntentDecision
 	return types.IntentDecisionEscalate
 }
 
+// aggregateCalibratedIntentVotes applies the voting rules after demoting SAFE and
+// DENY votes whose calibrated probability is below the thresholds to ABSTAIN, so a
+// SAFE vote at 0.51 escalates while one at 0.99 counts.
+func aggregateCalibratedIntentVotes(votes []types.IntentVoterResult, minSafe, minDeny float64) types.IntentDecision {
+	effective := make([]types.IntentVoterResult, len(votes))
+	for i, v := range votes {
+		effective[i] = v
+		if isLowConfidenceVote(v, minSafe, minDeny) {
+			effective[i].Vote = types.IntentVoteAbstain
+		}
+	}
+	return aggregateIntentVotes(effective)
+}
+
+// isLowConfidenceVote reports whether a SAFE or DENY vote falls below its threshold.
+func isLowConfidenceVote(v types.IntentVoterResult, minSafe, minDeny float64) bool {
+	switch v.Vote {
+	case types.IntentVoteSafe:
+		return v.CalibratedConfidence < minSafe
+	case types.IntentVoteDeny:
+		return v.CalibratedConfidence < minDeny
+	}
+	return false
+}
+
+// AggregateCalibratedIntentVotes is exported for testing.
+func AggregateCalibratedIntentVotes(votes []types.IntentVoterResult, minSafe, minDeny float64) types.IntentDecision {
+	return aggregateCalibratedIntentVotes(votes, minSafe, minDeny)
+}
+
 // AggregateIntentVotes is exported for testing.
 func AggregateIntentVotes(votes []types.IntentVoterResult) types.IntentDecision {
 	return aggregateIntentVotes(votes)
@@ -0,0 +1,124 @@
+// Package llm provides clients for OpenAI-compatible LLM endpoints.
+package llm
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Reasons recorded when a model response is rejected by output validation.
+const (
+	ReasonMalformedResponse   = "malformed_response"
+	ReasonBoundaryEcho        = "boundary_echo"
+	ReasonMultipleJSONObjects = "multiple_json_objects"
+	ReasonSchemaViolation     = "schema_violation"
+)
+
+// intentVoterOutputSchema is the strict schema for intent voter responses.
+const intentVoterOutputSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["vote", "confidence", "reasons"],
+  "properties": {
+    "vote": {"enum": ["SAFE", "DENY", "ABSTAIN"]},
+    "confidence": {"type": "number", "minimum": 0, "maximum": 1},
+    "reasons": {
+      "type": "array",
+      "maxItems": 10,
+      "items": {"type": "string", "maxLength": 200}
+    }
+  }
+}`
+
+// threatOutputSchema is the strict schema for threat sentinel responses.
+const threatOutputSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["label", "confidence"],
+  "properties": {
+    "label": {"enum": ["CLEAR", "SUSPICIOUS", "MALICIOUS"]},
+    "threat_types": {
+      "type": "array",
+      "maxItems": 10,
+      "items": {"type": "string", "pattern": "^[A-Za-z0-9_]{1,64}$"}
+    },
+    "confidence": {"type": "number", "minimum": 0, "maximum": 1},
+    "explanation": {"type": "string", "maxLength": 1000}
+  }
+}`
+
+var (
+	intentVoterSchema = mustCompileOutputSchema("intent_voter_output.json", intentVoterOutputSchema)
+	threatSchema      = mustCompileOutputSchema("threat_output.json", threatOutputSchema)
+)
+
+func mustCompileOutputSchema(name, schema string) *jsonschema.Schema {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+	if err := compiler.AddResource(name, strings.NewReader(schema)); err != nil {
+		panic(err)
+	}
+	return compiler.MustCompile(name)
+}
+
+// outputError is returned by decodeModelOutput when a response is rejected.
+// Reason is one of the Reason* constants.
+type outputError struct {
+	Reason string
+}
+
+func (e *outputError) Error() string {
+	return "model output rejected: " + e.Reason
+}
+
+// rejectionReason returns the rejection reason for an output validation error.
+func rejectionReason(err error) string {
+	var oe *outputError
+	if errors.As(err, &oe) {
+		return oe.Reason
+	}
+	return ReasonMalformedResponse
+}
+
+// decodeModelOutput strictly validates a model response and decodes it into v.
+// The response is rejected if it echoes the request's boundary token, holds
+// anything other than exactly one JSON object, or does not match schema.
+func decodeModelOutput(resp *ChatCompletionResponse, boundary string, schema *jsonschema.Schema, v any) error {
+	content := strings.TrimSpace(resp.ExtractContent())
+	if content == "" {
+		return &outputError{Reason: ReasonMalformedResponse}
+	}
+
+	// A response quoting the boundary is reproducing prompt data rather than answering.
+	if boundary != "" && strings.Contains(content, boundary) {
+		return &outputError{Reason: ReasonBoundaryEcho}
+	}
+
+	dec := json.NewDecoder(strings.NewReader(content))
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return &outputError{Reason: ReasonMalformedResponse}
+	}
+	if rest := strings.TrimSpace(content[dec.InputOffset():]); rest != "" {
+		// A second JSON value, e.g. {"vote":"DENY"}{"vote":"SAFE"}, is a smuggling attempt;
+		// any other trailing text is just malformed.
+		var extra any
+		if json.NewDecoder(strings.NewReader(rest)).Decode(&extra) == nil {
+			return &outputError{Reason: ReasonMultipleJSONObjects}
+		}
+		return &outputError{Reason: ReasonMalformedResponse}
+	}
+	if err := schema.Validate(doc); err != nil {
+		return &outputError{Reason: ReasonSchemaViolation}
+	}
+
+	strict := json.NewDecoder(strings.NewReader(content))
+	strict.DisallowUnknownFields()
+	if err := strict.Decode(v); err != nil {
+		return &outputError{Reason: ReasonSchemaViolation}
+	}
+	return nil
+}
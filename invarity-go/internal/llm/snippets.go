@@ -0,0 +1,153 @@
+// Package llm provides clients for OpenAI-compatible LLM endpoints.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"invarity/internal/contextscan"
+	"invarity/internal/prompts"
+	"invarity/internal/types"
+)
+
+// MaxClassifiedSnippets bounds the number of snippets sent in one classification call.
+const MaxClassifiedSnippets = 8
+
+// maxSnippetChars bounds the length of each snippet in the classification prompt.
+const maxSnippetChars = 2000
+
+// snippetOutputSchema is the strict schema for snippet classification responses.
+const snippetOutputSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["snippets"],
+  "properties": {
+    "snippets": {
+      "type": "array",
+      "maxItems": 8,
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["id", "label", "confidence"],
+        "properties": {
+          "id": {"type": "string", "pattern": "^SNIPPET_[0-9]+$"},
+          "label": {"enum": ["CLEAR", "SUSPICIOUS", "MALICIOUS"]},
+          "confidence": {"type": "number", "minimum": 0, "maximum": 1}
+        }
+      }
+    }
+  }
+}`
+
+var snippetSchema = mustCompileOutputSchema("snippet_output.json", snippetOutputSchema)
+
+// SnippetRequest contains flagged bounded context snippets for classification.
+type SnippetRequest struct {
+	TenantID   string // Selects tenant prompt overrides and criteria
+	UserIntent string
+	Snippets   []contextscan.Snippet
+}
+
+// SnippetResult is the sentinel's classification of a batch of snippets.
+type SnippetResult struct {
+	Verdicts []types.ContextSnippetVerdict
+	Usage    *types.TokenUsage // Tokens reported by the endpoint
+}
+
+// SnippetResponse is the expected JSON output for snippet classification.
+type SnippetResponse struct {
+	Snippets []struct {
+		ID         string  `json:"id"`
+		Label      string  `json:"label"`
+		Confidence float64 `json:"confidence"`
+	} `json:"snippets"`
+}
+
+// ClassifySnippets asks the sentinel whether each snippet is an indirect prompt injection.
+// Snippets beyond MaxClassifiedSnippets, snippets missing from the response, and all snippets
+// of a rejected response are returned as SUSPICIOUS with zero confidence.
+func (s *ThreatSentinel) ClassifySnippets(ctx context.Context, req *SnippetRequest) (*SnippetResult, error) {
+	boundary, err := prompts.NewBoundary()
+	if err != nil {
+		return nil, err
+	}
+
+	system, err := s.prompts.Render(req.TenantID, prompts.SnippetClassifier, prompts.ThreatData{Boundary: boundary})
+	if err != nil {
+		return nil, fmt.Errorf("snippet classifier prompt: %w", err)
+	}
+
+	snippets := req.Snippets
+	if len(snippets) > MaxClassifiedSnippets {
+		snippets = snippets[:MaxClassifiedSnippets]
+	}
+
+	verdicts := make([]types.ContextSnippetVerdict, len(req.Snippets))
+	for i, snippet := range req.Snippets {
+		verdicts[i] = types.ContextSnippetVerdict{
+			Source: snippet.Source,
+			Index:  snippet.Index,
+			Label:  types.ThreatSuspicious,
+		}
+	}
+
+	chatReq := &ChatCompletionRequest{
+		Messages: []ChatMessage{
+			{Role: "system", Content: system.Text},
+			{Role: "user", Content: buildSnippetPrompt(req.UserIntent, snippets, boundary)},
+		},
+		Temperature: 0.1,
+		MaxTokens:   300,
+		ResponseFormat: &ResponseFormat{
+			Type: "json_object",
+		},
+	}
+
+	resp, err := s.client.ChatCompletion(ctx, chatReq)
+	if err != nil {
+		return nil, fmt.Errorf("snippet classification failed: %w", err)
+	}
+
+	result := &SnippetResult{Verdicts: verdicts, Usage: resp.TokenUsage()}
+
+	var snippetResp SnippetResponse
+	if err := decodeModelOutput(resp, boundary, snippetSchema, &snippetResp); err != nil {
+		// Rejected response → every snippet stays SUSPICIOUS
+		return result, nil
+	}
+
+	for _, item := range snippetResp.Snippets {
+		var n int
+		if _, err := fmt.Sscanf(item.ID, "SNIPPET_%d", &n); err != nil || n < 1 || n > len(snippets) {
+			continue
+		}
+		verdicts[n-1].Label = parseThreatLabel(item.Label)
+		verdicts[n-1].Confidence = item.Confidence
+	}
+
+	return result, nil
+}
+
+// buildSnippetPrompt builds the user prompt. Untrusted fields are encoded as data blocks delimited by boundary.
+func buildSnippetPrompt(userIntent string, snippets []contextscan.Snippet, boundary string) string {
+	var sb strings.Builder
+	sb.WriteString("Classify each snippet the agent read before proposing a tool call.\n\nUser Intent:\n")
+	sb.WriteString(prompts.DataBlock(boundary, "USER_INTENT", userIntent))
+	sb.WriteString("\n\nSnippets:")
+	for i, snippet := range snippets {
+		text := snippet.Text
+		if len(text) > maxSnippetChars {
+			text = text[:maxSnippetChars] + "...[truncated]"
+		}
+		fmt.Fprintf(&sb, "\nSNIPPET_%d (%s):\n", i+1, snippet.Key())
+		sb.WriteString(prompts.DataBlock(boundary, fmt.Sprintf("SNIPPET_%d", i+1), text))
+	}
+	sb.WriteString(`
+
+Respond with a JSON object:
+{
+  "snippets": [{"id": "SNIPPET_1", "label": "CLEAR" | "SUSPICIOUS" | "MALICIOUS", "confidence": 0.0-1.0}]
+}`)
+	return sb.String()
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"invarity/internal/audit"
+)
+
+var (
+	auditQueueDepth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "audit", "queue_depth"),
+		"Audit records waiting to be written.", nil, nil)
+	auditSpoolDepth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "audit", "spool_depth"),
+		"Audit records waiting in the on-disk spool for the store to recover.", nil, nil)
+	auditRecords = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "audit", "records_total"),
+		"Audit records by outcome: written, dropped (buffer full), rejected (fail closed) or lost (could not be spooled).",
+		[]string{"outcome"}, nil)
+	exportQueueDepth = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "audit_export", "queue_depth"),
+		"Audit records waiting to be sent to each export sink.", []string{"sink"}, nil)
+	exportRecords = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "audit_export", "records_total"),
+		"Audit records by export sink and outcome: exported, failed or dropped.",
+		[]string{"sink", "outcome"}, nil)
+)
+
+// auditCollector reads the audit writer's and exporter's stats at scrape time.
+type auditCollector struct {
+	async    *audit.AsyncStore
+	exporter *audit.Exporter
+}
+
+func (c *auditCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- auditQueueDepth
+	ch <- auditSpoolDepth
+	ch <- auditRecords
+	ch <- exportQueueDepth
+	ch <- exportRecords
+}
+
+func (c *auditCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.async != nil {
+		stats := c.async.Stats()
+		ch <- prometheus.MustNewConstMetric(auditQueueDepth, prometheus.GaugeValue, float64(stats.Queued))
+		ch <- prometheus.MustNewConstMetric(auditSpoolDepth, prometheus.GaugeValue, float64(stats.Spooled))
+		for outcome, n := range map[string]int64{
+			"written":  stats.Written,
+			"dropped":  stats.Dropped,
+			"rejected": stats.Rejected,
+			"lost":     stats.Lost,
+		} {
+			ch <- prometheus.MustNewConstMetric(auditRecords, prometheus.CounterValue, float64(n), outcome)
+		}
+	}
+	if c.exporter != nil {
+		for _, stats := range c.exporter.Stats() {
+			ch <- prometheus.MustNewConstMetric(exportQueueDepth, prometheus.GaugeValue, float64(stats.Queued), stats.Sink)
+			ch <- prometheus.MustNewConstMetric(exportRecords, prometheus.CounterValue, float64(stats.Exported), stats.Sink, "exported")
+			ch <- prometheus.MustNewConstMetric(exportRecords, prometheus.CounterValue, float64(stats.Failed), stats.Sink, "failed")
+			ch <- prometheus.MustNewConstMetric(exportRecords, prometheus.CounterValue, float64(stats.Dropped), stats.Sink, "dropped")
+		}
+	}
+}
@@ -0,0 +1,291 @@
+// Package metrics exposes the firewall's Prometheus metrics.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"invarity/internal/audit"
+	"invarity/internal/types"
+)
+
+const namespace = "invarity"
+
+// Label values used when the real value is not recorded.
+const (
+	OtherTenant    = "other"        // Tenants beyond the cardinality limit
+	UnresolvedTool = "unregistered" // Calls whose tool did not resolve
+)
+
+// DefaultMaxTenants is the default number of tenants labelled individually.
+const DefaultMaxTenants = 100
+
+// Config configures the metrics.
+type Config struct {
+	// Distinct tenant label values. Tenants seen after the limit is reached are
+	// labelled "other", and so are their tools. 0 uses DefaultMaxTenants; a
+	// negative value labels every tenant "other".
+	MaxTenants int
+}
+
+// Metrics holds the firewall's collectors and the registry they are exposed
+// from. A nil *Metrics records nothing.
+type Metrics struct {
+	registry *prometheus.Registry
+	tenants  *tenantLabels
+
+	stageDuration      *prometheus.HistogramVec
+	decisionDuration   *prometheus.HistogramVec
+	decisions          *prometheus.CounterVec
+	votes              *prometheus.CounterVec
+	voterErrors        *prometheus.CounterVec
+	threatLabels       *prometheus.CounterVec
+	llmRequests        *prometheus.HistogramVec
+	auditWriteFailures *prometheus.CounterVec
+	resolverCache      *prometheus.CounterVec
+	resolverEntries    prometheus.Gauge
+}
+
+// New creates the firewall's metrics on a fresh registry, together with the
+// Go runtime and process collectors.
+func New(cfg Config) *Metrics {
+	if cfg.MaxTenants == 0 {
+		cfg.MaxTenants = DefaultMaxTenants
+	}
+
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		tenants:  &tenantLabels{max: cfg.MaxTenants, seen: make(map[string]bool)},
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "stage_duration_seconds",
+			Help:      "Time spent in each pipeline stage that ran.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14), // 0.5ms to ~4s
+		}, []string{"stage"}),
+		decisionDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "decision_duration_seconds",
+			Help:      "Time from receiving a tool call to its decision.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 14),
+		}, []string{"decision"}),
+		decisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "decisions_total",
+			Help:      "Decisions by tenant, tool, decision and risk tier.",
+		}, []string{"tenant", "tool", "decision", "risk_tier"}),
+		votes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "intent_votes_total",
+			Help:      "Intent alignment votes by voter and vote.",
+		}, []string{"voter", "vote"}),
+		voterErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "intent_voter_errors_total",
+			Help:      "Intent alignment voter calls that failed and abstained.",
+		}, []string{"voter"}),
+		threatLabels: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "threat_labels_total",
+			Help:      "Threat sentinel labels.",
+		}, []string{"label"}),
+		llmRequests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "llm_request_duration_seconds",
+			Help:      "LLM endpoint request latency by client and HTTP status code (\"error\" when no response was received).",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12), // 10ms to ~20s
+		}, []string{"client", "code"}),
+		auditWriteFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "audit_write_failures_total",
+			Help:      "Decisions whose audit record could not be written, by reason.",
+		}, []string{"reason"}),
+		resolverCache: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "resolver_cache_requests_total",
+			Help:      "Tool resolutions by cache result: hit, negative_hit (a cached unknown tool) or miss.",
+		}, []string{"result"}),
+		resolverEntries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "resolver_cache_entries",
+			Help:      "Tool resolutions currently cached, including unknown tools.",
+		}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.stageDuration,
+		m.decisionDuration,
+		m.decisions,
+		m.votes,
+		m.voterErrors,
+		m.threatLabels,
+		m.llmRequests,
+		m.auditWriteFailures,
+		m.resolverCache,
+		m.resolverEntries,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Registry returns the registry the metrics are exposed from.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// ObserveTiming records the latency of each stage that ran.
+func (m *Metrics) ObserveTiming(timing *types.PipelineTiming) {
+	if m == nil || timing == nil {
+		return
+	}
+	for _, stage := range []struct {
+		name     string
+		duration types.Duration
+	}{
+		{"S0_CANONICALIZE", timing.Canonicalize},
+		{"S1_SCHEMA_VALIDATION", timing.SchemaValidate},
+		{"S2_CONSTRAINTS", timing.Constraints},
+		{"S2B_CONTEXT_SCAN", timing.ContextScan},
+		{"S3_INTENT_ALIGNMENT", timing.Alignment},
+		{"S4_THREAT_SENTINEL", timing.ThreatSentinel},
+		{"S5_AGGREGATE", timing.Aggregate},
+	} {
+		if stage.duration > 0 {
+			m.stageDuration.WithLabelValues(stage.name).Observe(time.Duration(stage.duration).Seconds())
+		}
+	}
+}
+
+// CountDecision records a decision and how long it took. toolID is empty when
+// the tool did not resolve.
+func (m *Metrics) CountDecision(tenantID, toolID string, decision types.Decision, riskTier types.RiskTier, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	tenant := m.tenants.label(tenantID)
+	switch {
+	case tenant == OtherTenant:
+		toolID = OtherTenant
+	case toolID == "":
+		toolID = UnresolvedTool
+	}
+	m.decisions.WithLabelValues(tenant, toolID, string(decision), string(riskTier)).Inc()
+	m.decisionDuration.WithLabelValues(string(decision)).Observe(elapsed.Seconds())
+}
+
+// CountVotes records the intent alignment votes.
+func (m *Metrics) CountVotes(alignment *types.IntentAlignmentResult) {
+	if m == nil || alignment == nil {
+		return
+	}
+	for _, voter := range alignment.Voters {
+		m.votes.WithLabelValues(voter.VoterID, string(voter.Vote)).Inc()
+		for _, reason := range voter.Reasons {
+			if reason == "voter_error" {
+				m.voterErrors.WithLabelValues(voter.VoterID).Inc()
+				break
+			}
+		}
+	}
+}
+
+// CountThreatLabel records the threat sentinel's label.
+func (m *Metrics) CountThreatLabel(label types.ThreatLabel) {
+	if m == nil {
+		return
+	}
+	m.threatLabels.WithLabelValues(string(label)).Inc()
+}
+
+// CountAuditWriteFailure records a decision whose audit record was not written.
+func (m *Metrics) CountAuditWriteFailure(reason string) {
+	if m == nil {
+		return
+	}
+	m.auditWriteFailures.WithLabelValues(reason).Inc()
+}
+
+// CountResolverCache records a tool resolution's cache result.
+func (m *Metrics) CountResolverCache(result string) {
+	if m == nil {
+		return
+	}
+	m.resolverCache.WithLabelValues(result).Inc()
+}
+
+// SetResolverCacheEntries records the number of cached tool resolutions.
+func (m *Metrics) SetResolverCacheEntries(n int) {
+	if m == nil {
+		return
+	}
+	m.resolverEntries.Set(float64(n))
+}
+
+// InstrumentTransport wraps an HTTP transport so that the LLM client named
+// client records each request's latency and status code. A nil base uses
+// http.DefaultTransport.
+func (m *Metrics) InstrumentTransport(client string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		start := time.Now()
+		resp, err := base.RoundTrip(req)
+		code := "error"
+		if err == nil {
+			code = strconv.Itoa(resp.StatusCode)
+		}
+		m.llmRequests.WithLabelValues(client, code).Observe(time.Since(start).Seconds())
+		return resp, err
+	})
+}
+
+// RegisterAudit exposes the audit writer's and exporter's counters and queue
+// depths, read when the metrics are scraped. Either may be nil.
+func (m *Metrics) RegisterAudit(async *audit.AsyncStore, exporter *audit.Exporter) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(&auditCollector{async: async, exporter: exporter})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// tenantLabels bounds the number of distinct tenant label values.
+type tenantLabels struct {
+	mu   sync.Mutex
+	max  int
+	seen map[string]bool
+}
+
+// label returns the tenant's label value: its ID while there is room, "other" after.
+func (t *tenantLabels) label(tenantID string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.seen[tenantID] {
+		return tenantID
+	}
+	if len(t.seen) >= t.max {
+		return OtherTenant
+	}
+	t.seen[tenantID] = true
+	return tenantID
+}
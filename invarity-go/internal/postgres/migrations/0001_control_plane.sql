@@ -0,0 +1,111 @@
+-- Control plane records. Timestamps are fixed-width RFC 3339 text, and sort
+-- columns use the "C" collation so ordering matches DynamoDB sort keys.
+
+CREATE TABLE users (
+	user_id       TEXT PRIMARY KEY,
+	email         TEXT NOT NULL,
+	display_name  TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	created_at    TEXT COLLATE "C" NOT NULL,
+	updated_at    TEXT COLLATE "C" NOT NULL,
+	last_login_at TEXT COLLATE "C" NOT NULL DEFAULT ''
+);
+
+CREATE TABLE tenants (
+	tenant_id  TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	plan       TEXT NOT NULL,
+	settings   TEXT NOT NULL DEFAULT '',
+	created_by TEXT NOT NULL,
+	created_at TEXT COLLATE "C" NOT NULL,
+	updated_at TEXT COLLATE "C" NOT NULL
+);
+
+CREATE TABLE memberships (
+	tenant_id  TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	role       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	invited_by TEXT NOT NULL DEFAULT '',
+	created_at TEXT COLLATE "C" NOT NULL,
+	updated_at TEXT COLLATE "C" NOT NULL,
+	PRIMARY KEY (tenant_id, user_id)
+);
+CREATE INDEX memberships_user_id ON memberships (user_id, created_at);
+
+CREATE TABLE principals (
+	principal_id            TEXT PRIMARY KEY,
+	tenant_id               TEXT NOT NULL,
+	name                    TEXT NOT NULL,
+	description             TEXT NOT NULL DEFAULT '',
+	type                    TEXT NOT NULL,
+	status                  TEXT NOT NULL,
+	metadata                TEXT NOT NULL DEFAULT '',
+	created_by              TEXT NOT NULL,
+	created_at              TEXT COLLATE "C" NOT NULL,
+	updated_at              TEXT COLLATE "C" NOT NULL,
+	active_toolset_id       TEXT NOT NULL DEFAULT '',
+	active_toolset_revision TEXT NOT NULL DEFAULT '',
+	toolset_assigned_at     TEXT COLLATE "C" NOT NULL DEFAULT '',
+	toolset_assigned_by     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX principals_tenant_id ON principals (tenant_id, created_at);
+
+CREATE TABLE tokens (
+	token_id     TEXT PRIMARY KEY,
+	tenant_id    TEXT NOT NULL,
+	principal_id TEXT NOT NULL DEFAULT '',
+	key_hash     TEXT NOT NULL UNIQUE,
+	key_prefix   TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	scopes       JSONB NOT NULL,
+	status       TEXT NOT NULL,
+	created_by   TEXT NOT NULL,
+	created_at   TEXT COLLATE "C" NOT NULL,
+	expires_at   TEXT COLLATE "C" NOT NULL DEFAULT '',
+	last_used_at TEXT COLLATE "C" NOT NULL DEFAULT '',
+	revoked_at   TEXT COLLATE "C" NOT NULL DEFAULT '',
+	revoked_by   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX tokens_tenant_id ON tokens (tenant_id);
+
+CREATE TABLE tools (
+	tenant_id   TEXT NOT NULL,
+	tool_id     TEXT COLLATE "C" NOT NULL,
+	version     TEXT COLLATE "C" NOT NULL,
+	schema_hash TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	risk_level  TEXT NOT NULL,
+	s3_key      TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	created_at  TEXT COLLATE "C" NOT NULL,
+	updated_at  TEXT COLLATE "C" NOT NULL,
+	PRIMARY KEY (tenant_id, tool_id, version)
+);
+
+CREATE TABLE toolsets (
+	tenant_id  TEXT NOT NULL,
+	toolset_id TEXT COLLATE "C" NOT NULL,
+	revision   TEXT COLLATE "C" NOT NULL,
+	name       TEXT NOT NULL,
+	tool_count INTEGER NOT NULL,
+	s3_key     TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_by TEXT NOT NULL,
+	created_at TEXT COLLATE "C" NOT NULL,
+	updated_at TEXT COLLATE "C" NOT NULL,
+	PRIMARY KEY (tenant_id, toolset_id, revision)
+);
+
+-- Tool and toolset manifests, keyed like their S3 objects. JSON manifests are
+-- kept as JSONB; other content in data.
+CREATE TABLE manifests (
+	manifest_key TEXT PRIMARY KEY,
+	content_type TEXT NOT NULL,
+	document     JSONB,
+	data         BYTEA,
+	updated_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+	CHECK ((document IS NULL) <> (data IS NULL))
+);
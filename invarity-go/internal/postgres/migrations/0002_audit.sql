@@ -0,0 +1,33 @@
+-- Audit records. The record is kept verbatim as JSON (JSONB would reject
+-- \u0000 in user input); the fields ListFilter searches on are copied into
+-- columns. created_audit is created_at#audit_id, the newest-first sort key.
+
+CREATE TABLE audit_records (
+	audit_id      TEXT PRIMARY KEY,
+	tenant_id     TEXT NOT NULL,
+	created_audit TEXT COLLATE "C" NOT NULL,
+	actor_id      TEXT NOT NULL DEFAULT '',
+	principal_id  TEXT NOT NULL DEFAULT '',
+	action_id     TEXT NOT NULL DEFAULT '',
+	decision      TEXT NOT NULL,
+	risk_tier     TEXT NOT NULL DEFAULT '',
+	reasons       TEXT[] NOT NULL DEFAULT '{}', -- Reasons and their codes
+	intent        TEXT NOT NULL DEFAULT '',     -- Lowercased, truncated user intent
+	record        JSON NOT NULL
+);
+
+-- One index per ListFilter partition: tenant, tenant+principal, tenant+actor
+-- and tenant+action, each ordered for newest-first pages and time ranges
+CREATE INDEX audit_records_tenant ON audit_records (tenant_id, created_audit DESC);
+CREATE INDEX audit_records_principal ON audit_records (tenant_id, principal_id, created_audit DESC);
+CREATE INDEX audit_records_actor ON audit_records (tenant_id, actor_id, created_audit DESC);
+CREATE INDEX audit_records_action ON audit_records (tenant_id, action_id, created_audit DESC);
+CREATE INDEX audit_records_decision ON audit_records (tenant_id, decision, created_audit DESC);
+CREATE INDEX audit_records_reasons ON audit_records USING GIN (reasons);
+
+CREATE TABLE audit_checkpoints (
+	tenant_id   TEXT NOT NULL,
+	to_sequence BIGINT NOT NULL,
+	checkpoint  JSON NOT NULL,
+	PRIMARY KEY (tenant_id, to_sequence)
+);
@@ -0,0 +1,10 @@
+-- Chain sequences. A unique index makes each tenant's next sequence a
+-- conditional write, so writers on several replicas cannot fork a chain.
+-- Unchained records have sequence 0.
+
+ALTER TABLE audit_records ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
+
+UPDATE audit_records SET sequence = (record->>'sequence')::BIGINT
+	WHERE record->>'sequence' IS NOT NULL;
+
+CREATE UNIQUE INDEX audit_records_sequence ON audit_records (tenant_id, sequence) WHERE sequence > 0;
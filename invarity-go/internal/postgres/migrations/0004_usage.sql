@@ -0,0 +1,19 @@
+-- Daily token usage rollups, one row per tenant, UTC day, principal, tool and
+-- model. Replicas increment rows with upserts, so quotas see one total.
+
+CREATE TABLE usage_rollups (
+	tenant_id         TEXT NOT NULL,
+	day               TEXT COLLATE "C" NOT NULL, -- YYYY-MM-DD
+	principal_id      TEXT NOT NULL,
+	tool_id           TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	calls             BIGINT NOT NULL,
+	prompt_tokens     BIGINT NOT NULL,
+	completion_tokens BIGINT NOT NULL,
+	total_tokens      BIGINT NOT NULL,
+	cost_usd          DOUBLE PRECISION NOT NULL,
+	PRIMARY KEY (tenant_id, day, principal_id, tool_id, model)
+);
+
+-- Cross-tenant reports select by day alone
+CREATE INDEX usage_rollups_day ON usage_rollups (day);
@@ -0,0 +1,386 @@
+// Package prompts provides the versioned prompt template registry used by the
+// intent voters and the threat sentinel.
+package prompts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+// Template IDs. Voter template IDs match the voter IDs they render for.
+const (
+	LiteralAuthorization = "literal_authorization"
+	ScopeAuditor         = "scope_auditor"
+	PreconditionsChecker = "preconditions_checker"
+	ThreatSentinel       = "threat_sentinel"
+	SnippetClassifier    = "snippet_classifier"
+)
+
+// MaxCriteria bounds the number of tenant business criteria per tenant.
+const MaxCriteria = 20
+
+// IntentData is the data rendered into intent voter templates.
+// IntentSummary, ToolDescription and Args carry untrusted content and are
+// expected to already be encoded with DataBlock using Boundary.
+type IntentData struct {
+	Boundary        string
+	IntentSummary   string
+	ToolName        string
+	ToolDescription string
+	Args            string
+	Operation       string
+	ResourceScope   string
+	SideEffectScope string
+	Bulk            bool
+	RequiredFields  string // JSON-encoded list
+	Criteria        []string
+}
+
+// ThreatData is the data rendered into the threat sentinel and snippet classifier system templates.
+type ThreatData struct {
+	Boundary string
+	Criteria []string
+}
+
+// prototypes maps each known template ID to the data type it is rendered with.
+// Templates are validated by executing them against these values at load time.
+var prototypes = map[string]any{
+	LiteralAuthorization: IntentData{Criteria: []string{"example"}},
+	ScopeAuditor:         IntentData{Criteria: []string{"example"}},
+	PreconditionsChecker: IntentData{Criteria: []string{"example"}},
+	ThreatSentinel:       ThreatData{Criteria: []string{"example"}},
+	SnippetClassifier:    ThreatData{Criteria: []string{"example"}},
+}
+
+var versionPattern = regexp.MustCompile(`^v[0-9]+$`)
+
+//go:embed defaults/*.tmpl
+var defaultFS embed.FS
+
+// Template is a single versioned prompt template.
+type Template struct {
+	ID      string `json:"id"`
+	Version string `json:"version"` // "v1", "v2", ...
+	Text    string `json:"text"`
+}
+
+// TenantOverrides holds a tenant's template overrides and business criteria.
+type TenantOverrides struct {
+	// Templates replace the default template with the same ID for this tenant.
+	Templates []Template `json:"templates,omitempty"`
+	// Criteria are tenant business rules appended to every template,
+	// e.g. "refunds must reference a ticket".
+	Criteria []string `json:"criteria,omitempty"`
+}
+
+// Rendered is the result of rendering a template.
+type Rendered struct {
+	Text string
+	// Ref identifies exactly which template (and criteria) produced Text, e.g.
+	// "literal_authorization@v1" or
+	// "tenant/acme/literal_authorization@v2+template:5e6f7a8b+criteria:1a2b3c4d".
+	// Tenant overrides carry a hash of their text, since a tenant may change it
+	// without bumping the version.
+	Ref string
+}
+
+type compiledTemplate struct {
+	Template
+	tmpl     *template.Template
+	textHash string // Set for tenant overrides
+}
+
+type tenantEntry struct {
+	templates    map[string]*compiledTemplate
+	criteria     []string
+	criteriaHash string
+}
+
+// Registry holds the embedded default templates and per-tenant overrides.
+type Registry struct {
+	mu       sync.RWMutex
+	versions map[string]map[string]*compiledTemplate // id -> version -> template
+	active   map[string]string                       // id -> active default version
+	tenants  map[string]*tenantEntry
+}
+
+// NewRegistry creates a registry loaded with the embedded default templates.
+func NewRegistry() (*Registry, error) {
+	r := &Registry{
+		versions: make(map[string]map[string]*compiledTemplate),
+		active:   make(map[string]string),
+		tenants:  make(map[string]*tenantEntry),
+	}
+
+	entries, err := defaultFS.ReadDir("defaults")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read default templates: %w", err)
+	}
+	for _, entry := range entries {
+		id, version, ok := parseTemplateFilename(entry.Name())
+		if !ok {
+			return nil, fmt.Errorf("invalid default template filename: %s", entry.Name())
+		}
+		data, err := defaultFS.ReadFile("defaults/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read default template %s: %w", entry.Name(), err)
+		}
+		if err := r.Register(Template{ID: id, Version: version, Text: string(data)}); err != nil {
+			return nil, err
+		}
+	}
+
+	for id := range prototypes {
+		if _, ok := r.active[id]; !ok {
+			return nil, fmt.Errorf("missing default template: %s", id)
+		}
+	}
+
+	return r, nil
+}
+
+// MustNewRegistry is like NewRegistry but panics on error.
+// The embedded defaults are validated by tests, so this only fails on a broken build.
+func MustNewRegistry() *Registry {
+	r, err := NewRegistry()
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// Register validates and adds a default template version.
+// The highest registered version of each template becomes the active default.
+func (r *Registry) Register(t Template) error {
+	compiled, err := compile(t)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.versions[t.ID] == nil {
+		r.versions[t.ID] = make(map[string]*compiledTemplate)
+	}
+	if _, exists := r.versions[t.ID][t.Version]; exists {
+		return fmt.Errorf("template %s@%s already registered", t.ID, t.Version)
+	}
+	r.versions[t.ID][t.Version] = compiled
+
+	if current, ok := r.active[t.ID]; !ok || versionNumber(t.Version) > versionNumber(current) {
+		r.active[t.ID] = t.Version
+	}
+	return nil
+}
+
+// SetTenantOverrides validates and installs overrides for a tenant, replacing any previous ones.
+// Nothing is installed if any template or criterion is invalid.
+func (r *Registry) SetTenantOverrides(tenantID string, overrides TenantOverrides) error {
+	if tenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+	if len(overrides.Criteria) > MaxCriteria {
+		return fmt.Errorf("tenant %s: at most %d criteria allowed, got %d", tenantID, MaxCriteria, len(overrides.Criteria))
+	}
+
+	entry := &tenantEntry{
+		templates: make(map[string]*compiledTemplate),
+	}
+	for _, t := range overrides.Templates {
+		if _, dup := entry.templates[t.ID]; dup {
+			return fmt.Errorf("tenant %s: duplicate override for template %s", tenantID, t.ID)
+		}
+		compiled, err := compile(t)
+		if err != nil {
+			return fmt.Errorf("tenant %s: %w", tenantID, err)
+		}
+		compiled.textHash = shortHash(compiled.Text)
+		entry.templates[t.ID] = compiled
+	}
+	for i, c := range overrides.Criteria {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			return fmt.Errorf("tenant %s: criteria[%d] is empty", tenantID, i)
+		}
+		if strings.ContainsAny(c, "\r\n") {
+			return fmt.Errorf("tenant %s: criteria[%d] must be a single line", tenantID, i)
+		}
+		entry.criteria = append(entry.criteria, c)
+	}
+	if len(entry.criteria) > 0 {
+		entry.criteriaHash = shortHash(strings.Join(entry.criteria, "\n"))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tenants[tenantID] = entry
+	return nil
+}
+
+// LoadTenantOverridesDir loads tenant overrides from a directory of JSON files.
+// Each file is named {tenant_id}.json and holds a TenantOverrides document.
+func (r *Registry) LoadTenantOverridesDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list prompt overrides: %w", err)
+	}
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		var overrides TenantOverrides
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&overrides); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		tenantID := strings.TrimSuffix(filepath.Base(path), ".json")
+		if err := r.SetTenantOverrides(tenantID, overrides); err != nil {
+			return fmt.Errorf("invalid prompt overrides in %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// Criteria returns the business criteria configured for a tenant.
+func (r *Registry) Criteria(tenantID string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if entry, ok := r.tenants[tenantID]; ok {
+		return entry.criteria
+	}
+	return nil
+}
+
+// Render renders the template with the given ID for a tenant.
+// Tenant overrides take precedence over the active default, and tenant criteria
+// are injected into data (which must be an IntentData or ThreatData value).
+func (r *Registry) Render(tenantID, id string, data any) (*Rendered, error) {
+	r.mu.RLock()
+	var (
+		tmpl         *compiledTemplate
+		ref          string
+		criteria     []string
+		criteriaHash string
+	)
+	if entry, ok := r.tenants[tenantID]; ok {
+		criteria = entry.criteria
+		criteriaHash = entry.criteriaHash
+		if t, ok := entry.templates[id]; ok {
+			tmpl = t
+			ref = fmt.Sprintf("tenant/%s/%s@%s+template:%s", tenantID, id, t.Version, t.textHash)
+		}
+	}
+	if tmpl == nil {
+		if version, ok := r.active[id]; ok {
+			tmpl = r.versions[id][version]
+			ref = id + "@" + version
+		}
+	}
+	r.mu.RUnlock()
+
+	if tmpl == nil {
+		return nil, fmt.Errorf("unknown prompt template: %s", id)
+	}
+
+	switch d := data.(type) {
+	case IntentData:
+		d.Criteria = criteria
+		data = d
+	case ThreatData:
+		d.Criteria = criteria
+		data = d
+	default:
+		return nil, fmt.Errorf("unsupported template data type %T", data)
+	}
+	if criteriaHash != "" {
+		ref += "+criteria:" + criteriaHash
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %w", ref, err)
+	}
+
+	return &Rendered{Text: buf.String(), Ref: ref}, nil
+}
+
+// Versions returns the registered default versions of a template, oldest first.
+func (r *Registry) Versions(id string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions := make([]string, 0, len(r.versions[id]))
+	for v := range r.versions[id] {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versionNumber(versions[i]) < versionNumber(versions[j])
+	})
+	return versions
+}
+
+// compile validates a template and parses it.
+// Validation executes the template against the prototype data for its ID, so
+// references to unknown fields are rejected at load time rather than per request.
+func compile(t Template) (*compiledTemplate, error) {
+	proto, ok := prototypes[t.ID]
+	if !ok {
+		return nil, fmt.Errorf("unknown template id: %s", t.ID)
+	}
+	if !versionPattern.MatchString(t.Version) {
+		return nil, fmt.Errorf("template %s: version must match v<number>, got %q", t.ID, t.Version)
+	}
+	t.Text = strings.TrimSuffix(t.Text, "\n")
+	if strings.TrimSpace(t.Text) == "" {
+		return nil, fmt.Errorf("template %s@%s: text is required", t.ID, t.Version)
+	}
+
+	tmpl, err := template.New(t.ID + "@" + t.Version).Option("missingkey=error").Parse(t.Text)
+	if err != nil {
+		return nil, fmt.Errorf("template %s@%s: %w", t.ID, t.Version, err)
+	}
+	if err := tmpl.Execute(io.Discard, proto); err != nil {
+		return nil, fmt.Errorf("template %s@%s: %w", t.ID, t.Version, err)
+	}
+
+	return &compiledTemplate{Template: t, tmpl: tmpl}, nil
+}
+
+// shortHash returns the first 8 hex digits of the SHA-256 of s.
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// parseTemplateFilename splits "{id}.{version}.tmpl" into its parts.
+func parseTemplateFilename(name string) (id, version string, ok bool) {
+	base := strings.TrimSuffix(name, ".tmpl")
+	if base == name {
+		return "", "", false
+	}
+	idx := strings.LastIndex(base, ".")
+	if idx <= 0 {
+		return "", "", false
+	}
+	return base[:idx], base[idx+1:], true
+}
+
+func versionNumber(v string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(v, "v"))
+	return n
+}
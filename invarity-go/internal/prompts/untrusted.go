@@ -0,0 +1,49 @@
+package prompts
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// boundaryBytes is the number of random bytes in a boundary token (128 bits).
+const boundaryBytes = 16
+
+// NewBoundary returns a random boundary token for delimiting untrusted data blocks.
+// A fresh token is generated per request so injected content cannot forge a block terminator.
+func NewBoundary() (string, error) {
+	b := make([]byte, boundaryBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate boundary token: %w", err)
+	}
+	return "INV-" + hex.EncodeToString(b), nil
+}
+
+// DataBlock encodes an untrusted value as a delimited data block:
+//
+//	[BEGIN ARGS INV-...]
+//	"...value as a JSON string..."
+//	[END ARGS INV-...]
+//
+// The value is encoded as a single-line JSON string with <, > and & escaped, so it
+// cannot contain raw newlines, quotes or marker-like text that escapes the block.
+// Any occurrence of the boundary inside the value is removed before encoding.
+func DataBlock(boundary, label, value string) string {
+	if boundary != "" {
+		value = strings.ReplaceAll(value, boundary, "[boundary]")
+	}
+	return fmt.Sprintf("[BEGIN %s %s]\n%s\n[END %s %s]", label, boundary, encodeString(value), label, boundary)
+}
+
+// encodeString JSON-encodes s with HTML-sensitive characters escaped.
+func encodeString(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(true)
+	// Encoding a string cannot fail.
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
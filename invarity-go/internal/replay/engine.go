@@ -0,0 +1,230 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/firewall"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/types"
+)
+
+// pageSize is the number of audit records read at a time.
+const pageSize = 200
+
+// ErrNoRecordedOutput is returned by the model stub used in recorded mode when a
+// candidate reaches a model stage the original request never reached.
+var ErrNoRecordedOutput = errors.New("no recorded model output to replay")
+
+// EngineConfig configures a replay Engine.
+type EngineConfig struct {
+	Store audit.Store
+	// The production pipeline's dependencies. Each run copies them, applies the
+	// candidate, and drops the audit store, meter, metrics and tracing so
+	// replays are neither audited, billed nor observed as traffic.
+	Pipeline firewall.PipelineConfig
+	Logger   *zap.Logger
+}
+
+// Engine replays a tenant's recorded traffic through a candidate pipeline.
+type Engine struct {
+	store    audit.Store
+	base     firewall.PipelineConfig
+	resolver *firewall.ToolResolver // nil without the control plane store
+	logger   *zap.Logger
+}
+
+// NewEngine creates a replay engine.
+func NewEngine(cfg EngineConfig) *Engine {
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	var resolver *firewall.ToolResolver
+	if cfg.Pipeline.ControlPlaneStore != nil {
+		resolver = firewall.NewToolResolver(cfg.Pipeline.ControlPlaneStore, cfg.Pipeline.ManifestStore)
+	}
+	return &Engine{
+		store:    cfg.Store,
+		base:     cfg.Pipeline,
+		resolver: resolver,
+		logger:   cfg.Logger,
+	}
+}
+
+// Run replays the records the spec selects, oldest first, and reports how the
+// candidate's decisions differ from the recorded ones. progress, if set, is
+// called after each record with the number replayed so far and the total.
+func (e *Engine) Run(ctx context.Context, spec *Spec, progress func(done, total int)) (*Report, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	stub := &recordedOnlyClient{}
+	pipeline, err := e.candidatePipeline(ctx, spec, stub)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := e.records(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	report := newReport(spec)
+	for i, record := range records {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		e.replayOne(ctx, pipeline, stub, spec, record, report)
+		if progress != nil {
+			progress(i+1, len(records))
+		}
+	}
+	report.finish()
+	return report, nil
+}
+
+// replayOne replays a single record and adds the outcome to the report.
+func (e *Engine) replayOne(ctx context.Context, pipeline *firewall.Pipeline, stub *recordedOnlyClient, spec *Spec, record *types.AuditRecord, report *Report) {
+	req := RequestFromRecord(record)
+	if req == nil {
+		report.Skipped++
+		return
+	}
+	var recorded *firewall.RecordedOutputs
+	if spec.LLM == ModeRecorded {
+		recorded = firewall.RecordedOutputsFrom(record)
+	}
+
+	stub.calls.Store(0)
+	resp, err := pipeline.Replay(ctx, req, recorded)
+	if err != nil {
+		e.logger.Warn("replay evaluation failed", zap.Error(err), zap.String("audit_id", record.AuditID))
+		report.Errors++
+		return
+	}
+	report.add(record, resp, stub.calls.Load() > 0)
+}
+
+// candidatePipeline builds the pipeline the spec's candidate describes.
+func (e *Engine) candidatePipeline(ctx context.Context, spec *Spec, stub llm.ChatCompleter) (*firewall.Pipeline, error) {
+	cfg := e.base
+	cfg.Config = spec.Candidate.Policy.apply(e.base.Config)
+	cfg.AuditStore = nil
+	cfg.AuditRedactor = nil
+	cfg.Meter = nil
+	cfg.Metrics = nil
+	cfg.TracerProvider = nil
+	if spec.LLM == ModeRecorded {
+		cfg.AlignmentClient = stub
+		cfg.ThreatClient = stub
+	}
+
+	tools := spec.Candidate.Tools
+	if spec.Candidate.ToolsetID != "" {
+		if e.resolver == nil {
+			return nil, fmt.Errorf("candidate toolsets require the control plane store")
+		}
+		var err error
+		tools, err = e.resolver.LoadToolset(ctx, spec.TenantID, spec.Candidate.ToolsetID, spec.Candidate.ToolsetRevision)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(tools) > 0 {
+		// Resolve only against the candidate's tools
+		candidateTools := registry.NewInMemoryStore()
+		for _, tool := range tools {
+			if err := candidateTools.PutTool(ctx, tool.ToToolRegistryEntry()); err != nil {
+				return nil, fmt.Errorf("failed to load candidate tool %s: %w", tool.ToolID, err)
+			}
+		}
+		cfg.RegistryStore = candidateTools
+		cfg.ControlPlaneStore = nil
+		cfg.ManifestStore = nil
+	}
+
+	return firewall.NewPipeline(cfg), nil
+}
+
+// records returns the most recent records the spec selects, oldest first.
+func (e *Engine) records(ctx context.Context, spec *Spec) ([]*types.AuditRecord, error) {
+	filter := &audit.ListFilter{
+		OrgID:     spec.TenantID,
+		ActionID:  spec.ActionID,
+		StartTime: spec.Since,
+		EndTime:   spec.Until,
+		Limit:     min(pageSize, spec.Limit),
+	}
+	records := make([]*types.AuditRecord, 0)
+	for len(records) < spec.Limit {
+		page, err := e.store.List(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list audit records: %w", err)
+		}
+		records = append(records, page...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.Cursor = audit.EncodeCursor(page[len(page)-1])
+		filter.Limit = min(pageSize, spec.Limit-len(records))
+	}
+
+	// Pages are newest first
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// RequestFromRecord reconstructs the request an audit record was made for, or
+// returns nil if the record has no tool call. Arguments redacted by the
+// tenant's audit policy and omitted context stay redacted and omitted.
+func RequestFromRecord(record *types.AuditRecord) *types.ToolCallRequest {
+	if record.ToolCall.ActionID == "" {
+		return nil
+	}
+	req := &types.ToolCallRequest{
+		RequestID:   record.RequestID,
+		OrgID:       record.OrgID,
+		TenantID:    record.TenantID,
+		PrincipalID: record.PrincipalID,
+		Actor:       record.Actor,
+		Environment: record.Environment,
+		UserIntent:  record.UserIntent,
+		ToolCall:    record.ToolCall,
+		Timestamp:   record.CreatedAt,
+	}
+	if req.OrgID == "" {
+		req.OrgID = record.TenantID
+	}
+	// The pipeline truncates in place; the record must stay as it was
+	req.ToolCall.Args = append(json.RawMessage(nil), record.ToolCall.Args...)
+	if bc := record.BoundedContext; bc != nil {
+		req.BoundedContext = &types.BoundedContext{
+			ConversationHistory: append([]string(nil), bc.ConversationHistory...),
+			RelevantDocuments:   append([]string(nil), bc.RelevantDocuments...),
+			SystemState:         bc.SystemState,
+		}
+	}
+	return req
+}
+
+// recordedOnlyClient stands in for the model endpoints in recorded mode. It
+// fails every call and counts them, so a record whose candidate reaches a stage
+// with no recorded output is reported as incomplete instead of calling a model.
+type recordedOnlyClient struct {
+	calls atomic.Int64
+}
+
+func (c *recordedOnlyClient) ChatCompletion(ctx context.Context, req *llm.ChatCompletionRequest) (*llm.ChatCompletionResponse, error) {
+	c.calls.Add(1)
+	return nil, ErrNoRecordedOutput
+}
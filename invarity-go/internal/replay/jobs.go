@@ -0,0 +1,249 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Job defaults.
+const (
+	DefaultConcurrency = 2
+	defaultRetained    = 100
+)
+
+// ErrTooManyJobs is returned when a tenant already has the maximum number of
+// unfinished replay jobs.
+var ErrTooManyJobs = errors.New("too many replay jobs in progress")
+
+// maxPendingPerTenant is the number of unfinished jobs a tenant may have.
+const maxPendingPerTenant = 5
+
+// JobStatus is a replay job's state.
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+)
+
+// Job is a replay submitted to run in the background.
+type Job struct {
+	JobID      string     `json:"job_id"`
+	TenantID   string     `json:"tenant_id"`
+	Status     JobStatus  `json:"status"`
+	Spec       Spec       `json:"spec"`
+	Replayed   int        `json:"replayed"` // Records replayed so far
+	Total      int        `json:"total"`    // Records selected, once known
+	Report     *Report    `json:"report,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Done reports whether the job has finished.
+func (j *Job) Done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed
+}
+
+// JobsConfig configures a job runner.
+type JobsConfig struct {
+	Concurrency int // Jobs run at once (default 2)
+	Retained    int // Finished jobs kept for retrieval (default 100)
+	Logger      *zap.Logger
+}
+
+// Jobs runs replays in the background and keeps their reports in memory.
+type Jobs struct {
+	engine   *Engine
+	retained int
+	logger   *zap.Logger
+	slots    chan struct{}
+
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []string // Submission order
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewJobs creates a job runner for the engine.
+func NewJobs(engine *Engine, cfg JobsConfig) *Jobs {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = DefaultConcurrency
+	}
+	if cfg.Retained <= 0 {
+		cfg.Retained = defaultRetained
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = zap.NewNop()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Jobs{
+		engine:   engine,
+		retained: cfg.Retained,
+		logger:   cfg.Logger,
+		slots:    make(chan struct{}, cfg.Concurrency),
+		jobs:     make(map[string]*Job),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Engine returns the engine jobs run on.
+func (j *Jobs) Engine() *Engine {
+	return j.engine
+}
+
+// Submit validates the spec and queues a job for it.
+func (j *Jobs) Submit(spec Spec) (*Job, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+
+	j.mu.Lock()
+	pending := 0
+	for _, job := range j.jobs {
+		if job.TenantID == spec.TenantID && !job.Done() {
+			pending++
+		}
+	}
+	if pending >= maxPendingPerTenant {
+		j.mu.Unlock()
+		return nil, ErrTooManyJobs
+	}
+	job := &Job{
+		JobID:     uuid.New().String(),
+		TenantID:  spec.TenantID,
+		Status:    JobPending,
+		Spec:      spec,
+		CreatedAt: time.Now().UTC(),
+	}
+	j.jobs[job.JobID] = job
+	j.order = append(j.order, job.JobID)
+	j.evict()
+	snapshot := *job
+	j.mu.Unlock()
+
+	j.wg.Add(1)
+	go j.run(job)
+	return &snapshot, nil
+}
+
+// Get returns a copy of the tenant's job, or nil if there is none with that ID.
+func (j *Jobs) Get(tenantID, jobID string) *Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[jobID]
+	if !ok || job.TenantID != tenantID {
+		return nil
+	}
+	snapshot := *job
+	return &snapshot
+}
+
+// List returns copies of the tenant's jobs, newest first, without their reports.
+func (j *Jobs) List(tenantID string) []*Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	jobs := make([]*Job, 0)
+	for i := len(j.order) - 1; i >= 0; i-- {
+		job := j.jobs[j.order[i]]
+		if job.TenantID != tenantID {
+			continue
+		}
+		snapshot := *job
+		snapshot.Report = nil
+		jobs = append(jobs, &snapshot)
+	}
+	return jobs
+}
+
+// Close cancels queued and running jobs and waits for them to stop.
+func (j *Jobs) Close() {
+	j.cancel()
+	j.wg.Wait()
+}
+
+func (j *Jobs) run(job *Job) {
+	defer j.wg.Done()
+
+	select {
+	case j.slots <- struct{}{}:
+		defer func() { <-j.slots }()
+	case <-j.ctx.Done():
+		j.finish(job, nil, j.ctx.Err())
+		return
+	}
+
+	j.mu.Lock()
+	started := time.Now().UTC()
+	job.Status = JobRunning
+	job.StartedAt = &started
+	spec := job.Spec
+	j.mu.Unlock()
+
+	report, err := j.engine.Run(j.ctx, &spec, func(done, total int) {
+		j.mu.Lock()
+		job.Replayed = done
+		job.Total = total
+		j.mu.Unlock()
+	})
+	j.finish(job, report, err)
+}
+
+func (j *Jobs) finish(job *Job, report *Report, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	finished := time.Now().UTC()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		j.logger.Warn("replay job failed", zap.Error(err), zap.String("job_id", job.JobID), zap.String("tenant_id", job.TenantID))
+		return
+	}
+	job.Status = JobSucceeded
+	job.Report = report
+	job.Total = report.Evaluated + report.Skipped + report.Errors
+	j.logger.Info("replay job finished",
+		zap.String("job_id", job.JobID),
+		zap.String("tenant_id", job.TenantID),
+		zap.Int("evaluated", report.Evaluated),
+		zap.Int("changed", report.Changed),
+	)
+}
+
+// evict drops the oldest finished jobs beyond the retention limit. Callers hold mu.
+func (j *Jobs) evict() {
+	finished := make([]string, 0)
+	for _, id := range j.order {
+		if j.jobs[id].Done() {
+			finished = append(finished, id)
+		}
+	}
+	if len(finished) <= j.retained {
+		return
+	}
+	drop := make(map[string]bool)
+	for _, id := range finished[:len(finished)-j.retained] {
+		drop[id] = true
+		delete(j.jobs, id)
+	}
+	order := j.order[:0]
+	for _, id := range j.order {
+		if !drop[id] {
+			order = append(order, id)
+		}
+	}
+	j.order = order
+}
@@ -0,0 +1,211 @@
+package replay
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// maxChanges is the number of individual changed decisions listed in a report.
+const maxChanges = 500
+
+// Report is the difference between recorded decisions and the candidate's.
+type Report struct {
+	TenantID string    `json:"tenant_id"`
+	LLM      string    `json:"llm"`
+	Since    time.Time `json:"since,omitempty"`
+	Until    time.Time `json:"until,omitempty"`
+
+	Evaluated int `json:"evaluated"` // Records replayed
+	Changed   int `json:"changed"`   // Records whose decision changed
+	Skipped   int `json:"skipped"`   // Records without a tool call to replay
+	Errors    int `json:"errors"`    // Records the pipeline failed to replay
+	// Records whose candidate reached a model stage with no recorded output;
+	// that stage failed as if the model were unavailable
+	Incomplete int `json:"incomplete"`
+
+	// Changed decisions counted by transition, e.g. "ALLOW->DENY"
+	Transitions map[string]int `json:"transitions"`
+	ByTool      []ToolDiff     `json:"by_tool"`
+	ByReason    []ReasonDiff   `json:"by_reason"`
+
+	Changes          []Change `json:"changes"` // Oldest first, at most 500
+	ChangesTruncated bool     `json:"changes_truncated,omitempty"`
+
+	tools   map[string]*ToolDiff
+	reasons map[reasonKey]*ReasonDiff
+}
+
+// ToolDiff counts replayed and changed decisions for one tool.
+type ToolDiff struct {
+	ActionID    string         `json:"action_id"`
+	Evaluated   int            `json:"evaluated"`
+	Changed     int            `json:"changed"`
+	Transitions map[string]int `json:"transitions,omitempty"`
+}
+
+// ReasonDiff counts changed decisions where a reason was added or removed.
+type ReasonDiff struct {
+	Reason      string         `json:"reason"`
+	Change      string         `json:"change"` // "added" or "removed" by the candidate
+	Count       int            `json:"count"`
+	Transitions map[string]int `json:"transitions"`
+}
+
+// Change is one record whose decision the candidate changes.
+type Change struct {
+	AuditID        string         `json:"audit_id"`
+	RequestID      string         `json:"request_id,omitempty"`
+	ActionID       string         `json:"action_id"`
+	PrincipalID    string         `json:"principal_id,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	From           types.Decision `json:"from"`
+	To             types.Decision `json:"to"`
+	FromStep       string         `json:"from_step"`
+	AddedReasons   []string       `json:"added_reasons,omitempty"`
+	RemovedReasons []string       `json:"removed_reasons,omitempty"`
+	Incomplete     bool           `json:"incomplete,omitempty"`
+}
+
+type reasonKey struct {
+	reason string
+	change string
+}
+
+// Transition names a decision change, e.g. "ALLOW->DENY".
+func Transition(from, to types.Decision) string {
+	return string(from) + "->" + string(to)
+}
+
+func newReport(spec *Spec) *Report {
+	return &Report{
+		TenantID:    spec.TenantID,
+		LLM:         spec.LLM,
+		Since:       spec.Since,
+		Until:       spec.Until,
+		Transitions: make(map[string]int),
+		ByTool:      make([]ToolDiff, 0),
+		ByReason:    make([]ReasonDiff, 0),
+		Changes:     make([]Change, 0),
+		tools:       make(map[string]*ToolDiff),
+		reasons:     make(map[reasonKey]*ReasonDiff),
+	}
+}
+
+// add compares a record with the candidate's response to it.
+func (r *Report) add(record *types.AuditRecord, resp *types.FirewallDecisionResponse, incomplete bool) {
+	r.Evaluated++
+	if incomplete {
+		r.Incomplete++
+	}
+
+	actionID := record.ToolCall.ActionID
+	tool, ok := r.tools[actionID]
+	if !ok {
+		tool = &ToolDiff{ActionID: actionID, Transitions: make(map[string]int)}
+		r.tools[actionID] = tool
+	}
+	tool.Evaluated++
+
+	if resp.Decision == record.Decision {
+		return
+	}
+	transition := Transition(record.Decision, resp.Decision)
+	r.Changed++
+	r.Transitions[transition]++
+	tool.Changed++
+	tool.Transitions[transition]++
+
+	added, removed := diffReasons(record.Reasons, resp.Reasons)
+	for _, reason := range added {
+		r.countReason(reason, "added", transition)
+	}
+	for _, reason := range removed {
+		r.countReason(reason, "removed", transition)
+	}
+
+	if len(r.Changes) >= maxChanges {
+		r.ChangesTruncated = true
+		return
+	}
+	r.Changes = append(r.Changes, Change{
+		AuditID:        record.AuditID,
+		RequestID:      record.RequestID,
+		ActionID:       actionID,
+		PrincipalID:    record.PrincipalID,
+		CreatedAt:      record.CreatedAt,
+		From:           record.Decision,
+		To:             resp.Decision,
+		FromStep:       record.PipelineStep,
+		AddedReasons:   added,
+		RemovedReasons: removed,
+		Incomplete:     incomplete,
+	})
+}
+
+func (r *Report) countReason(reason, change, transition string) {
+	key := reasonKey{reason: reason, change: change}
+	diff, ok := r.reasons[key]
+	if !ok {
+		diff = &ReasonDiff{Reason: reason, Change: change, Transitions: make(map[string]int)}
+		r.reasons[key] = diff
+	}
+	diff.Count++
+	diff.Transitions[transition]++
+}
+
+// finish orders the groups, most changed first.
+func (r *Report) finish() {
+	for _, tool := range r.tools {
+		r.ByTool = append(r.ByTool, *tool)
+	}
+	sort.Slice(r.ByTool, func(i, j int) bool {
+		if r.ByTool[i].Changed != r.ByTool[j].Changed {
+			return r.ByTool[i].Changed > r.ByTool[j].Changed
+		}
+		return r.ByTool[i].ActionID < r.ByTool[j].ActionID
+	})
+
+	for _, reason := range r.reasons {
+		r.ByReason = append(r.ByReason, *reason)
+	}
+	sort.Slice(r.ByReason, func(i, j int) bool {
+		a, b := r.ByReason[i], r.ByReason[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.Reason != b.Reason {
+			return a.Reason < b.Reason
+		}
+		return a.Change < b.Change
+	})
+}
+
+// diffReasons returns the reasons only in to (added) and only in from (removed).
+// Reasons that record where the tool was resolved from are not compared: a
+// candidate's tools always resolve differently from the recorded ones.
+func diffReasons(from, to []string) (added, removed []string) {
+	inFrom := make(map[string]bool, len(from))
+	for _, reason := range from {
+		inFrom[reason] = true
+	}
+	inTo := make(map[string]bool, len(to))
+	for _, reason := range to {
+		inTo[reason] = true
+		if !inFrom[reason] && !isResolutionReason(reason) {
+			added = append(added, reason)
+		}
+	}
+	for _, reason := range from {
+		if !inTo[reason] && !isResolutionReason(reason) {
+			removed = append(removed, reason)
+		}
+	}
+	return added, removed
+}
+
+func isResolutionReason(reason string) bool {
+	return strings.HasPrefix(reason, "resolved_via_")
+}
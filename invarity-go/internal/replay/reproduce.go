@@ -0,0 +1,172 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"invarity/internal/audit"
+	"invarity/internal/firewall"
+	"invarity/internal/registry"
+	"invarity/internal/types"
+)
+
+// ErrNoSnapshot is returned when an audit record was written before decisions
+// recorded their snapshot.
+var ErrNoSnapshot = errors.New("audit record has no decision snapshot")
+
+// ErrNoToolCall is returned when an audit record has no tool call to re-run.
+var ErrNoToolCall = errors.New("audit record has no tool call")
+
+// ErrArtifactUnavailable is returned when an artifact a decision was made with
+// can no longer be loaded exactly as it was.
+var ErrArtifactUnavailable = errors.New("decision artifact unavailable")
+
+// Reproduction is the outcome of re-running a recorded decision.
+type Reproduction struct {
+	AuditID    string  `json:"audit_id"`
+	Reproduced bool    `json:"reproduced"` // Decision, risk tier and reasons all match
+	Recorded   Outcome `json:"recorded"`
+	Rerun      Outcome `json:"rerun"`
+	// Differences between the two, e.g. "decision: ALLOW -> DENY"
+	Differences []string `json:"differences,omitempty"`
+	// The re-run reached a model stage with no recorded output; that stage failed
+	// as if the model were unavailable
+	Incomplete      bool                    `json:"incomplete,omitempty"`
+	Snapshot        *types.DecisionSnapshot `json:"snapshot"`
+	FirewallVersion string                  `json:"firewall_version"` // Build that re-ran the decision
+}
+
+// Outcome is a decision and what led to it.
+type Outcome struct {
+	Decision types.Decision `json:"decision"`
+	RiskTier types.RiskTier `json:"risk_tier"`
+	Reasons  []string       `json:"reasons"`
+}
+
+// Reproduce re-runs the deterministic stages for a tenant's audit record with
+// the tool manifest and configuration recorded in its snapshot, reusing the
+// recorded model outputs and calibrated confidences, and reports whether the
+// outcome matches. Another tenant's record is reported as audit.ErrNotFound.
+func (e *Engine) Reproduce(ctx context.Context, tenantID, auditID string) (*Reproduction, error) {
+	record, err := e.store.Get(ctx, auditID)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || (record.OrgID != tenantID && record.TenantID != tenantID) {
+		return nil, fmt.Errorf("%w: %s", audit.ErrNotFound, auditID)
+	}
+	if record.Snapshot == nil {
+		return nil, ErrNoSnapshot
+	}
+	req := RequestFromRecord(record)
+	if req == nil {
+		return nil, ErrNoToolCall
+	}
+
+	stub := &recordedOnlyClient{}
+	pipeline, err := e.pinnedPipeline(ctx, record, stub)
+	if err != nil {
+		return nil, err
+	}
+	recorded := firewall.RecordedOutputsFrom(record)
+	recorded.Calibrated = true
+	resp, err := pipeline.Replay(ctx, req, recorded)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Reproduction{
+		AuditID:         record.AuditID,
+		Recorded:        Outcome{Decision: record.Decision, RiskTier: record.RiskTier, Reasons: record.Reasons},
+		Rerun:           Outcome{Decision: resp.Decision, RiskTier: resp.RiskTier, Reasons: resp.Reasons},
+		Incomplete:      stub.calls.Load() > 0,
+		Snapshot:        record.Snapshot,
+		FirewallVersion: e.base.Version,
+	}
+	if resp.Decision != record.Decision {
+		result.Differences = append(result.Differences, fmt.Sprintf("decision: %s -> %s", record.Decision, resp.Decision))
+	}
+	if resp.RiskTier != record.RiskTier {
+		result.Differences = append(result.Differences, fmt.Sprintf("risk_tier: %s -> %s", record.RiskTier, resp.RiskTier))
+	}
+	added, removed := diffReasons(record.Reasons, resp.Reasons)
+	for _, reason := range added {
+		result.Differences = append(result.Differences, "reason added: "+reason)
+	}
+	for _, reason := range removed {
+		result.Differences = append(result.Differences, "reason removed: "+reason)
+	}
+	result.Reproduced = len(result.Differences) == 0
+	return result, nil
+}
+
+// pinnedPipeline builds a pipeline that resolves only the tool manifest the
+// record's snapshot names, under the snapshot's configuration.
+func (e *Engine) pinnedPipeline(ctx context.Context, record *types.AuditRecord, stub *recordedOnlyClient) (*firewall.Pipeline, error) {
+	snapshot := record.Snapshot
+	cfg := e.base
+	cfg.Config = firewall.ApplySnapshotConfig(e.base.Config, snapshot.Config)
+	cfg.AuditStore = nil
+	cfg.AuditRedactor = nil
+	cfg.Meter = nil
+	cfg.Metrics = nil
+	cfg.TracerProvider = nil
+	cfg.AlignmentClient = stub
+	cfg.ThreatClient = stub
+	cfg.ControlPlaneStore = nil
+	cfg.ManifestStore = nil
+
+	// A decision made before the tool resolved is re-run without any tool
+	pinned := registry.NewInMemoryStore()
+	if snapshot.Tool != nil {
+		tool, err := e.snapshotTool(ctx, record, snapshot.Tool)
+		if err != nil {
+			return nil, err
+		}
+		if err := pinned.PutTool(ctx, tool); err != nil {
+			return nil, err
+		}
+	}
+	cfg.RegistryStore = pinned
+
+	return firewall.NewPipeline(cfg), nil
+}
+
+// snapshotTool loads the exact tool manifest a snapshot names and checks that
+// it has not changed since the decision.
+func (e *Engine) snapshotTool(ctx context.Context, record *types.AuditRecord, snap *types.ToolSnapshot) (*types.ToolRegistryEntry, error) {
+	var tool *types.ToolRegistryEntry
+	switch {
+	case snap.ResolvedVia == "legacy":
+		if e.base.RegistryStore == nil {
+			return nil, fmt.Errorf("%w: no legacy registry", ErrArtifactUnavailable)
+		}
+		entry, err := e.base.RegistryStore.GetTool(ctx, snap.ToolID, snap.Version)
+		if err != nil && !errors.Is(err, registry.ErrToolNotFound) {
+			return nil, fmt.Errorf("failed to load tool %s@%s: %w", snap.ToolID, snap.Version, err)
+		}
+		tool = entry
+	case e.resolver != nil:
+		tenantID := record.TenantID
+		if tenantID == "" {
+			tenantID = record.OrgID
+		}
+		manifest, err := e.resolver.LoadTool(ctx, tenantID, snap.ToolID, snap.Version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tool %s@%s: %w", snap.ToolID, snap.Version, err)
+		}
+		tool = firewall.ResolvedToolToRegistryEntry(manifest)
+	default:
+		return nil, fmt.Errorf("%w: tool library requires the control plane store", ErrArtifactUnavailable)
+	}
+
+	if tool == nil {
+		return nil, fmt.Errorf("%w: tool %s@%s is no longer registered", ErrArtifactUnavailable, snap.ToolID, snap.Version)
+	}
+	if tool.SchemaHash != snap.SchemaHash {
+		return nil, fmt.Errorf("%w: tool %s@%s schema hash is %s, decision used %s",
+			ErrArtifactUnavailable, snap.ToolID, snap.Version, tool.SchemaHash, snap.SchemaHash)
+	}
+	return tool, nil
+}
@@ -0,0 +1,133 @@
+// Package replay re-evaluates recorded traffic against a candidate toolset or
+// policy and reports which decisions would change.
+package replay
+
+import (
+	"fmt"
+	"time"
+
+	"invarity/internal/config"
+	"invarity/internal/types"
+)
+
+// LLM modes.
+const (
+	// ModeRecorded reuses the model outputs recorded in each audit record.
+	ModeRecorded = "recorded"
+	// ModeLive calls the voter and sentinel models again.
+	ModeLive = "live"
+)
+
+// Record limits.
+const (
+	DefaultMaxRecords = 1000
+	MaxRecords        = 10000
+)
+
+// Spec selects the recorded traffic to replay and the candidate to replay it against.
+type Spec struct {
+	TenantID  string    `json:"tenant_id"`
+	Since     time.Time `json:"since,omitempty"`
+	Until     time.Time `json:"until,omitempty"`
+	ActionID  string    `json:"action_id,omitempty"` // Optional: only calls to this tool
+	Limit     int       `json:"limit,omitempty"`     // Most recent records replayed (default 1000, max 10000)
+	LLM       string    `json:"llm,omitempty"`       // "recorded" (default) or "live"
+	Candidate Candidate `json:"candidate"`
+}
+
+// Candidate is the configuration recorded traffic is replayed against. Empty
+// fields keep the current configuration.
+type Candidate struct {
+	// A registered toolset revision whose tools replace the current ones
+	ToolsetID       string `json:"toolset_id,omitempty"`
+	ToolsetRevision string `json:"toolset_revision,omitempty"`
+	// Unregistered tool manifests that replace the current ones
+	Tools  []*types.ToolManifestV3 `json:"tools,omitempty"`
+	Policy *PolicyOverrides        `json:"policy,omitempty"`
+}
+
+// PolicyOverrides replaces decision thresholds and stage switches.
+type PolicyOverrides struct {
+	MinSafeProbability   *float64 `json:"min_safe_probability,omitempty"`
+	MinDenyProbability   *float64 `json:"min_deny_probability,omitempty"`
+	MinThreatProbability *float64 `json:"min_threat_probability,omitempty"`
+	EnableThreatSentinel *bool    `json:"enable_threat_sentinel,omitempty"`
+	EnableContextScan    *bool    `json:"enable_context_scan,omitempty"`
+}
+
+// Validate checks the spec and fills in defaults.
+func (s *Spec) Validate() error {
+	if s.TenantID == "" {
+		return fmt.Errorf("tenant_id is required")
+	}
+	if !s.Since.IsZero() && !s.Until.IsZero() && s.Until.Before(s.Since) {
+		return fmt.Errorf("until must not be before since")
+	}
+	if s.Limit < 0 || s.Limit > MaxRecords {
+		return fmt.Errorf("limit must be between 1 and %d", MaxRecords)
+	}
+	if s.Limit == 0 {
+		s.Limit = DefaultMaxRecords
+	}
+	switch s.LLM {
+	case "":
+		s.LLM = ModeRecorded
+	case ModeRecorded, ModeLive:
+	default:
+		return fmt.Errorf("llm must be %q or %q", ModeRecorded, ModeLive)
+	}
+	return s.Candidate.validate()
+}
+
+func (c *Candidate) validate() error {
+	if (c.ToolsetID == "") != (c.ToolsetRevision == "") {
+		return fmt.Errorf("candidate toolset_id and toolset_revision must be set together")
+	}
+	if c.ToolsetID != "" && len(c.Tools) > 0 {
+		return fmt.Errorf("candidate takes a toolset or tools, not both")
+	}
+	for i, tool := range c.Tools {
+		if tool == nil {
+			return fmt.Errorf("candidate tools[%d] is empty", i)
+		}
+		if err := tool.Validate(); err != nil {
+			return fmt.Errorf("candidate tools[%d]: %w", i, err)
+		}
+	}
+	if c.Policy != nil {
+		for name, p := range map[string]*float64{
+			"min_safe_probability":   c.Policy.MinSafeProbability,
+			"min_deny_probability":   c.Policy.MinDenyProbability,
+			"min_threat_probability": c.Policy.MinThreatProbability,
+		} {
+			if p != nil && (*p < 0 || *p > 1) {
+				return fmt.Errorf("candidate policy %s must be between 0 and 1", name)
+			}
+		}
+	}
+	return nil
+}
+
+// apply returns a copy of cfg with the overrides applied.
+func (o *PolicyOverrides) apply(cfg *config.Config) *config.Config {
+	candidate := *cfg
+	if o == nil {
+		return &candidate
+	}
+	if o.MinSafeProbability != nil {
+		candidate.MinSafeProbability = *o.MinSafeProbability
+	}
+	if o.MinDenyProbability != nil {
+		candidate.MinDenyProbability = *o.MinDenyProbability
+	}
+	if o.MinThreatProbability != nil {
+		candidate.MinThreatProbability = *o.MinThreatProbability
+	}
+	if o.EnableThreatSentinel != nil {
+		candidate.EnableThreatSentinel = *o.EnableThreatSentinel
+	}
+	if o.EnableContextScan != nil {
+		candidate.EnableContextScan = *o.EnableContextScan
+	}
+	return &candidate
+}
@@ -0,0 +1,229 @@
+// Package semver parses and orders semantic versions (https://semver.org) and
+// matches them against the caret and tilde ranges toolsets use to reference
+// tools.
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed MAJOR.MINOR.PATCH[-PRERELEASE][+BUILD] version.
+type Version struct {
+	Major, Minor, Patch uint64
+	Prerelease          []string // Dot-separated identifiers, empty for a release
+	Build               string   // Ignored when ordering
+}
+
+// Parse parses a full semantic version such as "1.2.3" or "2.0.0-rc.1".
+func Parse(s string) (Version, error) {
+	var v Version
+	rest := s
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		rest, v.Build = rest[:i], rest[i+1:]
+		if !validIdentifiers(v.Build, false) {
+			return Version{}, fmt.Errorf("invalid version %q: invalid build metadata", s)
+		}
+	}
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		var pre string
+		rest, pre = rest[:i], rest[i+1:]
+		if !validIdentifiers(pre, true) {
+			return Version{}, fmt.Errorf("invalid version %q: invalid prerelease", s)
+		}
+		v.Prerelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", s)
+	}
+	nums := make([]uint64, 3)
+	for i, part := range parts {
+		n, err := parseNumber(part)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		nums[i] = n
+	}
+	v.Major, v.Minor, v.Patch = nums[0], nums[1], nums[2]
+	return v, nil
+}
+
+// parseNumber parses a numeric version part, which may not have leading zeros.
+func parseNumber(s string) (uint64, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// validIdentifiers checks dot-separated prerelease or build identifiers.
+// Numeric prerelease identifiers may not have leading zeros.
+func validIdentifiers(s string, prerelease bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return false
+		}
+		numeric := true
+		for _, c := range id {
+			switch {
+			case c >= '0' && c <= '9':
+			case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '-':
+				numeric = false
+			default:
+				return false
+			}
+		}
+		if prerelease && numeric && len(id) > 1 && id[0] == '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPrerelease reports whether v is a prerelease.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// String formats the version.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Prerelease) > 0 {
+		s += "-" + strings.Join(v.Prerelease, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 as a has lower, equal or higher precedence than
+// b. Build metadata is ignored.
+func Compare(a, b Version) int {
+	if c := compareUint(a.Major, b.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Minor, b.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(a.Patch, b.Patch); c != 0 {
+		return c
+	}
+
+	// A release has higher precedence than its prereleases
+	switch {
+	case len(a.Prerelease) == 0 && len(b.Prerelease) == 0:
+		return 0
+	case len(a.Prerelease) == 0:
+		return 1
+	case len(b.Prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.Prerelease) && i < len(b.Prerelease); i++ {
+		if c := compareIdentifier(a.Prerelease[i], b.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(a.Prerelease)), uint64(len(b.Prerelease)))
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareIdentifier orders prerelease identifiers: numeric ones numerically
+// and below alphanumeric ones, which are ordered lexically.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareUint(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// Range is a set of versions: [Min, Max).
+type Range struct {
+	raw      string
+	min, max Version
+}
+
+// IsRange reports whether s is written as a range rather than a version.
+func IsRange(s string) bool {
+	return strings.HasPrefix(s, "^") || strings.HasPrefix(s, "~")
+}
+
+// ParseRange parses a caret or tilde range. The version may omit its minor
+// or patch number, which default to 0.
+//
+//	^1.2.3  >=1.2.3 <2.0.0    ~1.2.3  >=1.2.3 <1.3.0
+//	^1.2    >=1.2.0 <2.0.0    ~1.2    >=1.2.0 <1.3.0
+//	^0.2.3  >=0.2.3 <0.3.0    ~1      >=1.0.0 <2.0.0
+//	^0.0.3  >=0.0.3 <0.0.4
+func ParseRange(s string) (Range, error) {
+	if !IsRange(s) {
+		return Range{}, fmt.Errorf("invalid range %q: expected ^ or ~", s)
+	}
+	op, rest := s[0], s[1:]
+	if strings.ContainsAny(rest, "-+") {
+		return Range{}, fmt.Errorf("invalid range %q: prerelease and build are not supported", s)
+	}
+	parts := strings.Split(rest, ".")
+	if len(parts) > 3 {
+		return Range{}, fmt.Errorf("invalid range %q: expected MAJOR[.MINOR[.PATCH]]", s)
+	}
+	nums := make([]uint64, 3)
+	for i, part := range parts {
+		n, err := parseNumber(part)
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid range %q: %w", s, err)
+		}
+		nums[i] = n
+	}
+
+	r := Range{raw: s, min: Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}}
+	switch {
+	case op == '~' && len(parts) == 1:
+		r.max = Version{Major: nums[0] + 1}
+	case op == '~':
+		r.max = Version{Major: nums[0], Minor: nums[1] + 1}
+	case nums[0] > 0 || len(parts) == 1:
+		r.max = Version{Major: nums[0] + 1}
+	case nums[1] > 0 || len(parts) == 2:
+		r.max = Version{Minor: nums[1] + 1}
+	default:
+		r.max = Version{Patch: nums[2] + 1}
+	}
+	return r, nil
+}
+
+// Contains reports whether v is in the range. Prereleases never are.
+func (r Range) Contains(v Version) bool {
+	return !v.IsPrerelease() && Compare(v, r.min) >= 0 && Compare(v, r.max) < 0
+}
+
+// String returns the range as written.
+func (r Range) String() string {
+	return r.raw
+}
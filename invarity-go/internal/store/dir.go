@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirBlobStore stores blobs as files under a local directory, using the key
+// as the relative path.
+type DirBlobStore struct {
+	dir string
+}
+
+// NewDirBlobStore creates a blob store rooted at dir, creating it if needed.
+func NewDirBlobStore(dir string) (*DirBlobStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	return &DirBlobStore{dir: dir}, nil
+}
+
+// Ping checks that the directory is writable.
+func (d *DirBlobStore) Ping(ctx context.Context) error {
+	f, err := os.CreateTemp(d.dir, ".health-*")
+	if err != nil {
+		return fmt.Errorf("blob directory %s is not writable: %w", d.dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// path returns the file for key, rejecting keys that would escape the directory.
+func (d *DirBlobStore) path(key string) (string, error) {
+	rel := filepath.FromSlash(key)
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(d.dir, rel), nil
+}
+
+// PutJSON writes a value as JSON.
+func (d *DirBlobStore) PutJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return d.PutRaw(ctx, key, data, "application/json")
+}
+
+// GetJSON reads a value and unmarshals it.
+func (d *DirBlobStore) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := d.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// PutRaw writes raw bytes. The file is replaced atomically, so readers never
+// see a partial blob.
+func (d *DirBlobStore) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetRaw reads raw bytes.
+func (d *DirBlobStore) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to get %s: %w", key, ErrBlobNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Exists checks if a key exists.
+func (d *DirBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	path, err := d.path(key)
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -2,9 +2,9 @@ package store
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -41,6 +41,28 @@ func NewDynamoDBStore(client *dynamodb.Client, cfg DynamoDBConfig) *DynamoDBStor
 	}
 }
 
+// Ping reads at most one item from every configured table. It needs only the
+// data permissions the store already uses, not dynamodb:DescribeTable.
+func (s *DynamoDBStore) Ping(ctx context.Context) error {
+	for _, table := range []string{
+		s.config.TenantsTable,
+		s.config.UsersTable,
+		s.config.MembershipsTable,
+		s.config.PrincipalsTable,
+		s.config.TokensTable,
+		s.config.ToolsTable,
+		s.config.ToolsetsTable,
+	} {
+		if table == "" {
+			continue
+		}
+		if _, err := s.client.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String(table), Limit: aws.Int32(1)}); err != nil {
+			return fmt.Errorf("failed to read table %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
 // --- User Operations ---
 
 // GetOrCreateUser gets an existing user or creates a new one.
@@ -185,6 +207,15 @@ func (s *DynamoDBStore) GetTenant(ctx context.Context, tenantID string) (*Tenant
 	return &tenant, nil
 }
 
+// TenantPlan returns a tenant's plan, or "" if the tenant does not exist.
+func (s *DynamoDBStore) TenantPlan(ctx context.Context, tenantID string) (string, error) {
+	tenant, err := s.GetTenant(ctx, tenantID)
+	if err != nil || tenant == nil {
+		return "", err
+	}
+	return tenant.Plan, nil
+}
+
 // --- Membership Operations ---
 
 // CreateMembership creates a new tenant membership.
@@ -360,13 +391,11 @@ func (s *DynamoDBStore) CreatePrincipal(ctx context.Context, tenantID, name, pri
 	return principal, nil
 }
 
-// GetPrincipal retrieves a principal by ID.
-func (s *DynamoDBStore) GetPrincipal(ctx context.Context, principalID string) (*Principal, error) {
+// GetPrincipal retrieves a tenant's principal by ID.
+func (s *DynamoDBStore) GetPrincipal(ctx context.Context, tenantID, principalID string) (*Principal, error) {
 	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
 		TableName: aws.String(s.config.PrincipalsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"principal_id": &ddbtypes.AttributeValueMemberS{Value: principalID},
-		},
+		Key:       principalKey(tenantID, principalID),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get principal: %w", err)
@@ -386,7 +415,6 @@ func (s *DynamoDBStore) GetPrincipal(ctx context.Context, principalID string) (*
 func (s *DynamoDBStore) ListPrincipals(ctx context.Context, tenantID string) ([]Principal, error) {
 	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(s.config.PrincipalsTable),
-		IndexName:              aws.String("tenant_id-index"),
 		KeyConditionExpression: aws.String("tenant_id = :tid"),
 		FilterExpression:       aws.String("#status = :active"),
 		ExpressionAttributeNames: map[string]string{
@@ -409,36 +437,14 @@ func (s *DynamoDBStore) ListPrincipals(ctx context.Context, tenantID string) ([]
 	return principals, nil
 }
 
-// --- Token Operations (Stubs) ---
+// --- Token Operations ---
 
 // CreateToken creates a new API token.
 // Returns the plaintext token (only shown once) and the stored token record.
 func (s *DynamoDBStore) CreateToken(ctx context.Context, tenantID, principalID, name, tokenType, createdBy string, scopes []string, expiresAt *time.Time) (string, *Token, error) {
-	// Generate token
-	tokenID := uuid.New().String()
-	plaintext := generateSecureToken()
-	keyHash := hashToken(plaintext)
-	keyPrefix := plaintext[:8]
-
-	now := time.Now().UTC()
-	token := &Token{
-		TokenID:     tokenID,
-		TenantID:    tenantID,
-		PrincipalID: principalID,
-		KeyHash:     keyHash,
-		KeyPrefix:   keyPrefix,
-		Name:        name,
-		Type:        tokenType,
-		Scopes:      scopes,
-		Status:      "active",
-		CreatedAt:   now,
-		CreatedBy:   createdBy,
-	}
-	if expiresAt != nil {
-		token.ExpiresAt = *expiresAt
-	}
+	plaintext, token := newTokenRecord(tenantID, principalID, name, tokenType, createdBy, scopes, expiresAt)
 
-	item, err := attributevalue.MarshalMap(token)
+	item, err := marshalToken(token)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to marshal token: %w", err)
 	}
@@ -454,8 +460,65 @@ func (s *DynamoDBStore) CreateToken(ctx context.Context, tenantID, principalID,
 	return plaintext, token, nil
 }
 
-// RevokeToken revokes a token by ID.
-func (s *DynamoDBStore) RevokeToken(ctx context.Context, tokenID, revokedBy string) error {
+// GetToken retrieves a tenant's token by ID.
+func (s *DynamoDBStore) GetToken(ctx context.Context, tenantID, tokenID string) (*Token, error) {
+	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(s.config.TokensTable),
+		Key: map[string]ddbtypes.AttributeValue{
+			"token_id": &ddbtypes.AttributeValueMemberS{Value: tokenID},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	if result.Item == nil {
+		return nil, nil
+	}
+
+	var token Token
+	if err := attributevalue.UnmarshalMap(result.Item, &token); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
+	}
+	if token.TenantID != tenantID {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// ListTokens lists a tenant's tokens, newest first.
+func (s *DynamoDBStore) ListTokens(ctx context.Context, tenantID string) ([]Token, error) {
+	var tokens []Token
+	paginator := dynamodb.NewQueryPaginator(s.client, &dynamodb.QueryInput{
+		TableName:              aws.String(s.config.TokensTable),
+		IndexName:              aws.String("tenant-tokens-index"),
+		KeyConditionExpression: aws.String("tenant_id = :tid"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":tid": &ddbtypes.AttributeValueMemberS{Value: tenantID},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tokens: %w", err)
+		}
+		var pageTokens []Token
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &pageTokens); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tokens: %w", err)
+		}
+		tokens = append(tokens, pageTokens...)
+	}
+
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].TokenID < tokens[j].TokenID
+	})
+	return tokens, nil
+}
+
+// RevokeToken revokes a tenant's token by ID.
+func (s *DynamoDBStore) RevokeToken(ctx context.Context, tenantID, tokenID, revokedBy string) error {
 	now := time.Now().UTC()
 	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
 		TableName: aws.String(s.config.TokensTable),
@@ -470,12 +533,54 @@ func (s *DynamoDBStore) RevokeToken(ctx context.Context, tokenID, revokedBy stri
 			":revoked": &ddbtypes.AttributeValueMemberS{Value: "revoked"},
 			":now":     &ddbtypes.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
 			":by":      &ddbtypes.AttributeValueMemberS{Value: revokedBy},
+			":tid":     &ddbtypes.AttributeValueMemberS{Value: tenantID},
 		},
-		ConditionExpression: aws.String("attribute_exists(token_id)"),
+		ConditionExpression: aws.String("tenant_id = :tid"),
 	})
+	var condErr *ddbtypes.ConditionalCheckFailedException
+	if isConditionCheckFailed(err, condErr) {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
 	return err
 }
 
+// ExpireToken brings a tenant's token's expiry forward to expiresAt.
+func (s *DynamoDBStore) ExpireToken(ctx context.Context, tenantID, tokenID string, expiresAt time.Time) error {
+	// Stored expiries are not fixed-width, so they are compared here rather
+	// than in a condition expression
+	token, err := s.GetToken(ctx, tenantID, tokenID)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
+	if !token.ExpiresAt.IsZero() && !expiresAt.Before(token.ExpiresAt) {
+		return nil
+	}
+
+	at, err := attributevalue.Marshal(expiresAt.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to marshal token expiry: %w", err)
+	}
+	_, err = s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.config.TokensTable),
+		Key: map[string]ddbtypes.AttributeValue{
+			"token_id": &ddbtypes.AttributeValueMemberS{Value: tokenID},
+		},
+		UpdateExpression: aws.String("SET expires_at = :at"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":at":  at,
+			":tid": &ddbtypes.AttributeValueMemberS{Value: tenantID},
+		},
+		ConditionExpression: aws.String("tenant_id = :tid"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to expire token: %w", err)
+	}
+	return nil
+}
+
 // ValidateToken validates a token and returns the token record if valid.
 func (s *DynamoDBStore) ValidateToken(ctx context.Context, plaintext string) (*Token, error) {
 	keyHash := hashToken(plaintext)
@@ -483,7 +588,7 @@ func (s *DynamoDBStore) ValidateToken(ctx context.Context, plaintext string) (*T
 	// Query by key_hash using GSI
 	result, err := s.client.Query(ctx, &dynamodb.QueryInput{
 		TableName:              aws.String(s.config.TokensTable),
-		IndexName:              aws.String("key_hash-index"),
+		IndexName:              aws.String("key-hash-index"),
 		KeyConditionExpression: aws.String("key_hash = :hash"),
 		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
 			":hash": &ddbtypes.AttributeValueMemberS{Value: keyHash},
@@ -502,37 +607,48 @@ func (s *DynamoDBStore) ValidateToken(ctx context.Context, plaintext string) (*T
 		return nil, fmt.Errorf("failed to unmarshal token: %w", err)
 	}
 
-	// Check status
-	if token.Status != "active" {
-		return nil, nil
-	}
-
-	// Check expiration
-	if !token.ExpiresAt.IsZero() && token.ExpiresAt.Before(time.Now()) {
+	if !tokenUsable(&token) {
 		return nil, nil
 	}
 
 	return &token, nil
 }
 
-// --- Tool Operations ---
+// marshalToken marshals a token with its times in timeFormat rather than the
+// encoder's variable-width RFC 3339, so that last_used_at compares as a string
+// in TouchTokens whichever write stored it.
+func marshalToken(token *Token) (map[string]ddbtypes.AttributeValue, error) {
+	return attributevalue.MarshalMapWithOptions(token, func(o *attributevalue.EncoderOptions) {
+		o.EncodeTime = func(t time.Time) (ddbtypes.AttributeValue, error) {
+			return &ddbtypes.AttributeValueMemberS{Value: t.UTC().Format(timeFormat)}, nil
+		}
+	})
+}
 
-// ToolRecord represents a tool stored in DynamoDB.
-// Uses composite key: tenant_id (PK) + tool_id#version (SK)
-type ToolRecord struct {
-	TenantID   string `dynamodbav:"tenant_id"`
-	SK         string `dynamodbav:"sk"` // tool_id#version
-	ToolID     string `dynamodbav:"tool_id"`
-	Version    string `dynamodbav:"version"`
-	SchemaHash string `dynamodbav:"schema_hash"`
-	Name       string `dynamodbav:"name"`
-	RiskLevel  string `dynamodbav:"risk_level"`
-	S3Key      string `dynamodbav:"s3_key"`
-	Status     string `dynamodbav:"status"` // "active", "deprecated"
-	CreatedAt  string `dynamodbav:"created_at"`
-	UpdatedAt  string `dynamodbav:"updated_at"`
+// TouchTokens records when each token was last used. DynamoDB has no batch
+// update, so each token is one conditional write.
+func (s *DynamoDBStore) TouchTokens(ctx context.Context, lastUsed map[string]time.Time) error {
+	for tokenID, usedAt := range lastUsed {
+		at := &ddbtypes.AttributeValueMemberS{Value: usedAt.UTC().Format(timeFormat)}
+		_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(s.config.TokensTable),
+			Key: map[string]ddbtypes.AttributeValue{
+				"token_id": &ddbtypes.AttributeValueMemberS{Value: tokenID},
+			},
+			UpdateExpression:          aws.String("SET last_used_at = :at"),
+			ConditionExpression:       aws.String("attribute_exists(token_id) AND (attribute_not_exists(last_used_at) OR last_used_at < :at)"),
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{":at": at},
+		})
+		var condErr *ddbtypes.ConditionalCheckFailedException
+		if err != nil && !isConditionCheckFailed(err, condErr) {
+			return fmt.Errorf("failed to touch token %s: %w", tokenID, err)
+		}
+	}
+	return nil
 }
 
+// --- Tool Operations ---
+
 // UpsertTool creates or updates a tool metadata record.
 // Returns (isNew, error) - isNew is true if this was a new tool version.
 func (s *DynamoDBStore) UpsertTool(ctx context.Context, tenantID, toolID, version, schemaHash, name, riskLevel, s3Key string) (bool, error) {
@@ -618,6 +734,67 @@ func (s *DynamoDBStore) GetToolRecord(ctx context.Context, tenantID, toolID, ver
 	return &record, nil
 }
 
+// ListToolVersions lists every version of a tool by querying the
+// tool_id# sort key prefix. The prefix also matches IDs that start with
+// tool_id#, so records are kept only when their tool ID matches exactly.
+func (s *DynamoDBStore) ListToolVersions(ctx context.Context, tenantID, toolID string) ([]ToolRecord, error) {
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(s.config.ToolsTable),
+		KeyConditionExpression: aws.String("tenant_id = :tid AND begins_with(sk, :prefix)"),
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":tid":    &ddbtypes.AttributeValueMemberS{Value: tenantID},
+			":prefix": &ddbtypes.AttributeValueMemberS{Value: toolID + "#"},
+		},
+	}
+
+	tools := make([]ToolRecord, 0)
+	paginator := dynamodb.NewQueryPaginator(s.client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tool versions: %w", err)
+		}
+		var records []ToolRecord
+		if err := attributevalue.UnmarshalListOfMaps(page.Items, &records); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tools: %w", err)
+		}
+		for _, record := range records {
+			if record.ToolID == toolID {
+				tools = append(tools, record)
+			}
+		}
+	}
+	return tools, nil
+}
+
+// DeprecateTool marks a tool version deprecated.
+func (s *DynamoDBStore) DeprecateTool(ctx context.Context, tenantID, toolID, version string) error {
+	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(s.config.ToolsTable),
+		Key: map[string]ddbtypes.AttributeValue{
+			"tenant_id": &ddbtypes.AttributeValueMemberS{Value: tenantID},
+			"sk":        &ddbtypes.AttributeValueMemberS{Value: toolID + "#" + version},
+		},
+		UpdateExpression:    aws.String("SET #status = :deprecated, updated_at = :now"),
+		ConditionExpression: aws.String("attribute_exists(sk)"),
+		ExpressionAttributeNames: map[string]string{
+			"#status": "status",
+		},
+		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+			":deprecated": &ddbtypes.AttributeValueMemberS{Value: "deprecated"},
+			":now":        &ddbtypes.AttributeValueMemberS{Value: time.Now().UTC().Format(time.RFC3339)},
+		},
+	})
+	if err != nil {
+		var condErr *ddbtypes.ConditionalCheckFailedException
+		if isConditionCheckFailed(err, condErr) {
+			return fmt.Errorf("failed to deprecate tool: tool %s version %s not found", toolID, version)
+		}
+		return fmt.Errorf("failed to deprecate tool: %w", err)
+	}
+	return nil
+}
+
 // ListTools lists all tools for a tenant.
 // Returns the latest N tools (optionally with pagination).
 func (s *DynamoDBStore) ListTools(ctx context.Context, tenantID string, limit int32) ([]ToolRecord, error) {
@@ -652,22 +829,6 @@ func (s *DynamoDBStore) ListTools(ctx context.Context, tenantID string, limit in
 
 // --- Toolset Operations ---
 
-// ToolsetRecord represents a toolset stored in DynamoDB.
-// Uses composite key: tenant_id (PK) + toolset_id#revision (SK)
-type ToolsetRecord struct {
-	TenantID   string `dynamodbav:"tenant_id"`
-	SK         string `dynamodbav:"sk"` // toolset_id#revision
-	ToolsetID  string `dynamodbav:"toolset_id"`
-	Revision   string `dynamodbav:"revision"`
-	Name       string `dynamodbav:"name"`
-	ToolCount  int    `dynamodbav:"tool_count"`
-	S3Key      string `dynamodbav:"s3_key"`
-	Status     string `dynamodbav:"status"` // "active", "archived"
-	CreatedAt  string `dynamodbav:"created_at"`
-	UpdatedAt  string `dynamodbav:"updated_at"`
-	CreatedBy  string `dynamodbav:"created_by"`
-}
-
 // RegisterToolset creates a new toolset record.
 // Returns (isNew, error) - isNew is true if this was a new revision.
 func (s *DynamoDBStore) RegisterToolset(ctx context.Context, tenantID, toolsetID, revision, name, s3Key, createdBy string, toolCount int) (bool, error) {
@@ -785,19 +946,16 @@ func (s *DynamoDBStore) SetPrincipalActiveToolset(ctx context.Context, tenantID,
 	now := time.Now().UTC()
 
 	_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
-		TableName: aws.String(s.config.PrincipalsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"principal_id": &ddbtypes.AttributeValueMemberS{Value: principalID},
-		},
-		UpdateExpression: aws.String("SET active_toolset_id = :tsid, active_toolset_revision = :rev, toolset_assigned_at = :at, toolset_assigned_by = :by, updated_at = :now"),
-		ConditionExpression: aws.String("attribute_exists(principal_id) AND tenant_id = :tid"),
+		TableName:           aws.String(s.config.PrincipalsTable),
+		Key:                 principalKey(tenantID, principalID),
+		UpdateExpression:    aws.String("SET active_toolset_id = :tsid, active_toolset_revision = :rev, toolset_assigned_at = :at, toolset_assigned_by = :by, updated_at = :now"),
+		ConditionExpression: aws.String("attribute_exists(principal_id)"),
 		ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
 			":tsid": &ddbtypes.AttributeValueMemberS{Value: toolsetID},
 			":rev":  &ddbtypes.AttributeValueMemberS{Value: revision},
 			":at":   &ddbtypes.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
 			":by":   &ddbtypes.AttributeValueMemberS{Value: assignedBy},
 			":now":  &ddbtypes.AttributeValueMemberS{Value: now.Format(time.RFC3339)},
-			":tid":  &ddbtypes.AttributeValueMemberS{Value: tenantID},
 		},
 	})
 	if err != nil {
@@ -807,13 +965,11 @@ func (s *DynamoDBStore) SetPrincipalActiveToolset(ctx context.Context, tenantID,
 	return nil
 }
 
-// GetPrincipalActiveToolset retrieves the active toolset for a principal.
-func (s *DynamoDBStore) GetPrincipalActiveToolset(ctx context.Context, principalID string) (toolsetID, revision string, err error) {
+// GetPrincipalActiveToolset retrieves the active toolset for a tenant's principal.
+func (s *DynamoDBStore) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (toolsetID, revision string, err error) {
 	result, err := s.client.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(s.config.PrincipalsTable),
-		Key: map[string]ddbtypes.AttributeValue{
-			"principal_id": &ddbtypes.AttributeValueMemberS{Value: principalID},
-		},
+		TableName:            aws.String(s.config.PrincipalsTable),
+		Key:                  principalKey(tenantID, principalID),
 		ProjectionExpression: aws.String("active_toolset_id, active_toolset_revision"),
 	})
 	if err != nil {
@@ -839,21 +995,14 @@ func (s *DynamoDBStore) GetPrincipalActiveToolset(ctx context.Context, principal
 
 // --- Helpers ---
 
-func generateSecureToken() string {
-	// Generate a secure random token: inv_<uuid without dashes>
-	id := uuid.New().String()
-	return "inv_" + id[:8] + id[9:13] + id[14:18] + id[19:23] + id[24:]
-}
-
-func hashToken(token string) string {
-	hash := sha256.Sum256([]byte(token))
-	return hex.EncodeToString(hash[:])
+// principalKey is a principal's key: tenant_id (PK) + principal_id (SK).
+func principalKey(tenantID, principalID string) map[string]ddbtypes.AttributeValue {
+	return map[string]ddbtypes.AttributeValue{
+		"tenant_id":    &ddbtypes.AttributeValueMemberS{Value: tenantID},
+		"principal_id": &ddbtypes.AttributeValueMemberS{Value: principalID},
+	}
 }
 
-func isConditionCheckFailed(err error, _ *ddbtypes.ConditionalCheckFailedException) bool {
-	if err == nil {
-		return false
-	}
-	_, ok := err.(*ddbtypes.ConditionalCheckFailedException)
-	return ok
+func isConditionCheckFailed(err error, condErr *ddbtypes.ConditionalCheckFailedException) bool {
+	return errors.As(err, &condErr)
 }
@@ -0,0 +1,666 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"invarity/internal/auth"
+)
+
+// InMemoryStore is an in-memory control plane backend for local development
+// and tests. Everything is lost on restart.
+type InMemoryStore struct {
+	mu          sync.RWMutex
+	users       map[string]User
+	tenants     map[string]Tenant
+	memberships map[string]TenantMembership // key: tenantID/userID
+	principals  map[string]Principal        // key: tenantID/principalID
+	assignments map[string]assignment       // key: tenantID/principalID
+	tokens      map[string]Token            // key: tokenID
+	tools       map[string]ToolRecord       // key: tenantID/toolID#version
+	toolsets    map[string]ToolsetRecord
+}
+
+// assignment is the toolset revision assigned to a principal.
+type assignment struct {
+	toolsetID string
+	revision  string
+}
+
+// NewInMemoryStore creates an empty in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		users:       make(map[string]User),
+		tenants:     make(map[string]Tenant),
+		memberships: make(map[string]TenantMembership),
+		principals:  make(map[string]Principal),
+		assignments: make(map[string]assignment),
+		tokens:      make(map[string]Token),
+		tools:       make(map[string]ToolRecord),
+		toolsets:    make(map[string]ToolsetRecord),
+	}
+}
+
+// Ping always succeeds.
+func (s *InMemoryStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// --- User Operations ---
+
+// GetOrCreateUser gets an existing user or creates a new one.
+func (s *InMemoryStore) GetOrCreateUser(ctx context.Context, userID, email string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	user, ok := s.users[userID]
+	if !ok {
+		user = User{UserID: userID, Email: email, Status: "active", CreatedAt: now}
+	}
+	user.UpdatedAt = now
+	user.LastLoginAt = now
+	s.users[userID] = user
+	return &user, nil
+}
+
+// GetUser retrieves a user by ID.
+func (s *InMemoryStore) GetUser(ctx context.Context, userID string) (*User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &user, nil
+}
+
+// UpdateUserLastLogin updates the last login timestamp.
+func (s *InMemoryStore) UpdateUserLastLogin(ctx context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, ok := s.users[userID]; ok {
+		now := time.Now().UTC()
+		user.LastLoginAt = now
+		user.UpdatedAt = now
+		s.users[userID] = user
+	}
+	return nil
+}
+
+// --- Tenant Operations ---
+
+// CreateTenant creates a new tenant.
+func (s *InMemoryStore) CreateTenant(ctx context.Context, name, createdBy string) (*Tenant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	tenant := Tenant{
+		TenantID:  uuid.New().String(),
+		Name:      name,
+		Status:    "active",
+		Plan:      "free",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: createdBy,
+	}
+	s.tenants[tenant.TenantID] = tenant
+	return &tenant, nil
+}
+
+// GetTenant retrieves a tenant by ID.
+func (s *InMemoryStore) GetTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenant, ok := s.tenants[tenantID]
+	if !ok {
+		return nil, nil
+	}
+	return &tenant, nil
+}
+
+// TenantPlan returns a tenant's plan, or "" if the tenant does not exist.
+func (s *InMemoryStore) TenantPlan(ctx context.Context, tenantID string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tenants[tenantID].Plan, nil
+}
+
+// --- Membership Operations ---
+
+// CreateMembership creates a new tenant membership.
+func (s *InMemoryStore) CreateMembership(ctx context.Context, tenantID, userID string, role auth.Role, invitedBy string) (*TenantMembership, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tenantID + "/" + userID
+	if _, ok := s.memberships[key]; ok {
+		return nil, fmt.Errorf("membership already exists")
+	}
+	now := time.Now().UTC()
+	membership := TenantMembership{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Role:      role,
+		Status:    "active",
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.memberships[key] = membership
+	return &membership, nil
+}
+
+// GetMembership retrieves a membership for auth.MembershipChecker interface.
+func (s *InMemoryStore) GetMembership(ctx context.Context, tenantID, userID string) (*auth.Membership, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	membership, ok := s.memberships[tenantID+"/"+userID]
+	if !ok {
+		return nil, nil
+	}
+	return &auth.Membership{
+		TenantID: membership.TenantID,
+		UserID:   membership.UserID,
+		Role:     membership.Role,
+		Status:   membership.Status,
+	}, nil
+}
+
+// ListTenantsForUser lists all tenants a user is a member of, oldest membership first.
+func (s *InMemoryStore) ListTenantsForUser(ctx context.Context, userID string) ([]TenantWithRole, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tenants := make([]TenantWithRole, 0)
+	for _, m := range s.userMemberships(userID) {
+		tenant, ok := s.tenants[m.TenantID]
+		if !ok {
+			continue
+		}
+		tenants = append(tenants, TenantWithRole{
+			TenantID: tenant.TenantID,
+			Name:     tenant.Name,
+			Role:     m.Role,
+		})
+	}
+	return tenants, nil
+}
+
+// GetUserOwnedTenant returns the tenant owned by a user (for idempotent bootstrap).
+func (s *InMemoryStore) GetUserOwnedTenant(ctx context.Context, userID string) (*Tenant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, m := range s.userMemberships(userID) {
+		if m.Role != auth.RoleOwner {
+			continue
+		}
+		if tenant, ok := s.tenants[m.TenantID]; ok {
+			return &tenant, nil
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// userMemberships returns a user's active memberships, oldest first. The
+// caller must hold s.mu.
+func (s *InMemoryStore) userMemberships(userID string) []TenantMembership {
+	var memberships []TenantMembership
+	for _, m := range s.memberships {
+		if m.UserID == userID && m.Status == "active" {
+			memberships = append(memberships, m)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		if !memberships[i].CreatedAt.Equal(memberships[j].CreatedAt) {
+			return memberships[i].CreatedAt.Before(memberships[j].CreatedAt)
+		}
+		return memberships[i].TenantID < memberships[j].TenantID
+	})
+	return memberships
+}
+
+// --- Principal Operations ---
+
+// CreatePrincipal creates a new principal under a tenant.
+func (s *InMemoryStore) CreatePrincipal(ctx context.Context, tenantID, name, principalType, createdBy string) (*Principal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	principal := Principal{
+		PrincipalID: uuid.New().String(),
+		TenantID:    tenantID,
+		Name:        name,
+		Type:        principalType,
+		Status:      "active",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   createdBy,
+	}
+	s.principals[tenantID+"/"+principal.PrincipalID] = principal
+	return &principal, nil
+}
+
+// GetPrincipal retrieves a tenant's principal by ID.
+func (s *InMemoryStore) GetPrincipal(ctx context.Context, tenantID, principalID string) (*Principal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	principal, ok := s.principals[tenantID+"/"+principalID]
+	if !ok {
+		return nil, nil
+	}
+	return &principal, nil
+}
+
+// ListPrincipals lists principals for a tenant, oldest first.
+func (s *InMemoryStore) ListPrincipals(ctx context.Context, tenantID string) ([]Principal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	principals := make([]Principal, 0)
+	for _, p := range s.principals {
+		if p.TenantID == tenantID && p.Status == "active" {
+			principals = append(principals, p)
+		}
+	}
+	sort.Slice(principals, func(i, j int) bool {
+		if !principals[i].CreatedAt.Equal(principals[j].CreatedAt) {
+			return principals[i].CreatedAt.Before(principals[j].CreatedAt)
+		}
+		return principals[i].PrincipalID < principals[j].PrincipalID
+	})
+	return principals, nil
+}
+
+// --- Token Operations ---
+
+// CreateToken creates a new API token.
+// Returns the plaintext token (only shown once) and the stored token record.
+func (s *InMemoryStore) CreateToken(ctx context.Context, tenantID, principalID, name, tokenType, createdBy string, scopes []string, expiresAt *time.Time) (string, *Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plaintext, token := newTokenRecord(tenantID, principalID, name, tokenType, createdBy, scopes, expiresAt)
+	s.tokens[token.TokenID] = copyToken(*token)
+	return plaintext, token, nil
+}
+
+// GetToken retrieves a tenant's token by ID.
+func (s *InMemoryStore) GetToken(ctx context.Context, tenantID, tokenID string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.tokens[tokenID]
+	if !ok || token.TenantID != tenantID {
+		return nil, nil
+	}
+	token = copyToken(token)
+	return &token, nil
+}
+
+// ListTokens lists a tenant's tokens, newest first.
+func (s *InMemoryStore) ListTokens(ctx context.Context, tenantID string) ([]Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []Token
+	for _, token := range s.tokens {
+		if token.TenantID == tenantID {
+			tokens = append(tokens, copyToken(token))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if !tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+		}
+		return tokens[i].TokenID < tokens[j].TokenID
+	})
+	return tokens, nil
+}
+
+// RevokeToken revokes a tenant's token by ID.
+func (s *InMemoryStore) RevokeToken(ctx context.Context, tenantID, tokenID, revokedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[tokenID]
+	if !ok || token.TenantID != tenantID {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
+	token.Status = "revoked"
+	token.RevokedAt = time.Now().UTC()
+	token.RevokedBy = revokedBy
+	s.tokens[tokenID] = token
+	return nil
+}
+
+// ValidateToken validates a token and returns the token record if valid.
+func (s *InMemoryStore) ValidateToken(ctx context.Context, plaintext string) (*Token, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	keyHash := hashToken(plaintext)
+	for _, token := range s.tokens {
+		if token.KeyHash != keyHash {
+			continue
+		}
+		if !tokenUsable(&token) {
+			return nil, nil
+		}
+		token = copyToken(token)
+		return &token, nil
+	}
+	return nil, nil
+}
+
+// ExpireToken brings a tenant's token's expiry forward to expiresAt.
+func (s *InMemoryStore) ExpireToken(ctx context.Context, tenantID, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.tokens[tokenID]
+	if !ok || token.TenantID != tenantID {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
+	if token.ExpiresAt.IsZero() || expiresAt.Before(token.ExpiresAt) {
+		token.ExpiresAt = expiresAt.UTC()
+		s.tokens[tokenID] = token
+	}
+	return nil
+}
+
+// TouchTokens records when each token was last used.
+func (s *InMemoryStore) TouchTokens(ctx context.Context, lastUsed map[string]time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for tokenID, usedAt := range lastUsed {
+		token, ok := s.tokens[tokenID]
+		if !ok || !usedAt.After(token.LastUsedAt) {
+			continue
+		}
+		token.LastUsedAt = usedAt.UTC()
+		s.tokens[tokenID] = token
+	}
+	return nil
+}
+
+// copyToken copies a token so callers cannot modify the stored scopes.
+func copyToken(token Token) Token {
+	token.Scopes = append([]string(nil), token.Scopes...)
+	return token
+}
+
+// --- Tool Operations ---
+
+// UpsertTool creates a tool metadata record, or succeeds without change if the
+// version exists with the same schema hash.
+// Returns (isNew, error) - isNew is true if this was a new tool version.
+func (s *InMemoryStore) UpsertTool(ctx context.Context, tenantID, toolID, version, schemaHash, name, riskLevel, s3Key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sk := toolID + "#" + version
+	if existing, ok := s.tools[tenantID+"/"+sk]; ok {
+		if existing.SchemaHash == schemaHash {
+			return false, nil
+		}
+		return false, fmt.Errorf("conflict: tool %s version %s already exists with different schema hash", toolID, version)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	s.tools[tenantID+"/"+sk] = ToolRecord{
+		TenantID:   tenantID,
+		SK:         sk,
+		ToolID:     toolID,
+		Version:    version,
+		SchemaHash: schemaHash,
+		Name:       name,
+		RiskLevel:  riskLevel,
+		S3Key:      s3Key,
+		Status:     "active",
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	return true, nil
+}
+
+// GetToolRecord retrieves a tool record by tenant, tool_id, and version.
+func (s *InMemoryStore) GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*ToolRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.tools[tenantID+"/"+toolID+"#"+version]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// ListToolVersions lists every version of a tool.
+func (s *InMemoryStore) ListToolVersions(ctx context.Context, tenantID, toolID string) ([]ToolRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tools := make([]ToolRecord, 0)
+	for _, t := range s.tools {
+		if t.TenantID == tenantID && t.ToolID == toolID {
+			tools = append(tools, t)
+		}
+	}
+	return tools, nil
+}
+
+// DeprecateTool marks a tool version deprecated.
+func (s *InMemoryStore) DeprecateTool(ctx context.Context, tenantID, toolID, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tenantID + "/" + toolID + "#" + version
+	record, ok := s.tools[key]
+	if !ok {
+		return fmt.Errorf("failed to deprecate tool: tool %s version %s not found", toolID, version)
+	}
+	record.Status = "deprecated"
+	record.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	s.tools[key] = record
+	return nil
+}
+
+// ListTools lists a tenant's active tools in descending tool_id#version order,
+// matching the DynamoDB sort key.
+func (s *InMemoryStore) ListTools(ctx context.Context, tenantID string, limit int32) ([]ToolRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tools := make([]ToolRecord, 0)
+	for _, t := range s.tools {
+		if t.TenantID == tenantID && t.Status == "active" {
+			tools = append(tools, t)
+		}
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].SK > tools[j].SK })
+	if len(tools) > int(limit) {
+		tools = tools[:limit]
+	}
+	return tools, nil
+}
+
+// --- Toolset Operations ---
+
+// RegisterToolset creates a new toolset record.
+// Returns (isNew, error) - isNew is true if this was a new revision.
+func (s *InMemoryStore) RegisterToolset(ctx context.Context, tenantID, toolsetID, revision, name, s3Key, createdBy string, toolCount int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sk := toolsetID + "#" + revision
+	if existing, ok := s.toolsets[tenantID+"/"+sk]; ok {
+		if existing.S3Key == s3Key {
+			return false, nil
+		}
+		return false, fmt.Errorf("conflict: toolset %s revision %s already exists", toolsetID, revision)
+	}
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	s.toolsets[tenantID+"/"+sk] = ToolsetRecord{
+		TenantID:  tenantID,
+		SK:        sk,
+		ToolsetID: toolsetID,
+		Revision:  revision,
+		Name:      name,
+		ToolCount: toolCount,
+		S3Key:     s3Key,
+		Status:    "active",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: createdBy,
+	}
+	return true, nil
+}
+
+// GetToolsetRecord retrieves a toolset record by tenant, toolset_id, and revision.
+func (s *InMemoryStore) GetToolsetRecord(ctx context.Context, tenantID, toolsetID, revision string) (*ToolsetRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.toolsets[tenantID+"/"+toolsetID+"#"+revision]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// ListToolsets lists a tenant's active toolsets in descending
+// toolset_id#revision order, matching the DynamoDB sort key.
+func (s *InMemoryStore) ListToolsets(ctx context.Context, tenantID string, limit int32) ([]ToolsetRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	toolsets := make([]ToolsetRecord, 0)
+	for _, t := range s.toolsets {
+		if t.TenantID == tenantID && t.Status == "active" {
+			toolsets = append(toolsets, t)
+		}
+	}
+	sort.Slice(toolsets, func(i, j int) bool { return toolsets[i].SK > toolsets[j].SK })
+	if len(toolsets) > int(limit) {
+		toolsets = toolsets[:limit]
+	}
+	return toolsets, nil
+}
+
+// --- Principal Active Toolset Operations ---
+
+// SetPrincipalActiveToolset sets the active toolset for a principal.
+func (s *InMemoryStore) SetPrincipalActiveToolset(ctx context.Context, tenantID, principalID, toolsetID, revision, assignedBy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := tenantID + "/" + principalID
+	principal, ok := s.principals[key]
+	if !ok {
+		return fmt.Errorf("failed to set principal active toolset: principal %s not found in tenant %s", principalID, tenantID)
+	}
+	principal.UpdatedAt = time.Now().UTC()
+	s.principals[key] = principal
+	s.assignments[key] = assignment{toolsetID: toolsetID, revision: revision}
+	return nil
+}
+
+// GetPrincipalActiveToolset retrieves the active toolset for a tenant's principal.
+func (s *InMemoryStore) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (toolsetID, revision string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a := s.assignments[tenantID+"/"+principalID]
+	return a.toolsetID, a.revision, nil
+}
+
+// InMemoryBlobStore is an in-memory BlobStore for local development and tests.
+type InMemoryBlobStore struct {
+	mu    sync.RWMutex
+	blobs map[string][]byte
+}
+
+// NewInMemoryBlobStore creates an empty in-memory blob store.
+func NewInMemoryBlobStore() *InMemoryBlobStore {
+	return &InMemoryBlobStore{blobs: make(map[string][]byte)}
+}
+
+// Ping always succeeds.
+func (b *InMemoryBlobStore) Ping(ctx context.Context) error {
+	return nil
+}
+
+// PutJSON writes a value as JSON.
+func (b *InMemoryBlobStore) PutJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return b.PutRaw(ctx, key, data, "application/json")
+}
+
+// GetJSON reads a value and unmarshals it.
+func (b *InMemoryBlobStore) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := b.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// PutRaw writes raw bytes.
+func (b *InMemoryBlobStore) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.blobs[key] = append([]byte(nil), data...)
+	return nil
+}
+
+// GetRaw reads raw bytes.
+func (b *InMemoryBlobStore) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	data, ok := b.blobs[key]
+	if !ok {
+		return nil, fmt.Errorf("failed to get %s: %w", key, ErrBlobNotFound)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// Exists checks if a key exists.
+func (b *InMemoryBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	_, ok := b.blobs[key]
+	return ok, nil
+}
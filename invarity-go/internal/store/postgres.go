@@ -0,0 +1,106 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// NewPostgresStore creates a control plane store on a Postgres database opened
+// (and migrated) with postgres.Open.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dollarParams: true}
+}
+
+// PostgresBlobStore stores manifests in the manifests table of a Postgres
+// database. JSON content is kept as JSONB, so GetRaw returns it re-serialized
+// rather than byte for byte.
+type PostgresBlobStore struct {
+	db *sql.DB
+}
+
+// NewPostgresBlobStore creates a manifest store on a Postgres database opened
+// with postgres.Open.
+func NewPostgresBlobStore(db *sql.DB) *PostgresBlobStore {
+	return &PostgresBlobStore{db: db}
+}
+
+// Ping checks that the database is reachable.
+func (b *PostgresBlobStore) Ping(ctx context.Context) error {
+	if err := b.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+	return nil
+}
+
+// PutJSON writes a value as JSON.
+func (b *PostgresBlobStore) PutJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return b.PutRaw(ctx, key, data, "application/json")
+}
+
+// GetJSON reads a value and unmarshals it.
+func (b *PostgresBlobStore) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := b.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// PutRaw writes raw bytes, replacing any existing manifest. Valid JSON with a
+// JSON content type is stored as JSONB.
+func (b *PostgresBlobStore) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	var document, raw any
+	if strings.HasPrefix(contentType, "application/json") && json.Valid(data) {
+		document = string(data)
+	} else {
+		raw = data
+	}
+	if _, err := b.db.ExecContext(ctx,
+		`INSERT INTO manifests (manifest_key, content_type, document, data) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (manifest_key) DO UPDATE
+		 SET content_type = excluded.content_type, document = excluded.document, data = excluded.data, updated_at = now()`,
+		key, contentType, document, raw); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetRaw reads raw bytes.
+func (b *PostgresBlobStore) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	var document sql.NullString
+	var raw []byte
+	err := b.db.QueryRowContext(ctx, `SELECT document, data FROM manifests WHERE manifest_key = $1`, key).Scan(&document, &raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get %s: %w", key, ErrBlobNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	if document.Valid {
+		return []byte(document.String), nil
+	}
+	return raw, nil
+}
+
+// Exists checks if a key exists.
+func (b *PostgresBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	var exists bool
+	if err := b.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM manifests WHERE manifest_key = $1)`, key).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	return exists, nil
+}
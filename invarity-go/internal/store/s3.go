@@ -1,15 +1,16 @@
-// Package store provides data access layer for storage backends.
 package store
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 // S3Client wraps the AWS S3 client for canonical JSON operations.
@@ -26,6 +27,14 @@ func NewS3Client(client *s3.Client, bucket string) *S3Client {
 	}
 }
 
+// Ping checks that the bucket exists and is accessible.
+func (c *S3Client) Ping(ctx context.Context) error {
+	if _, err := c.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(c.bucket)}); err != nil {
+		return fmt.Errorf("failed to reach bucket %s: %w", c.bucket, err)
+	}
+	return nil
+}
+
 // PutJSON writes a value as canonical JSON to S3.
 func (c *S3Client) PutJSON(ctx context.Context, key string, v any) error {
 	data, err := json.Marshal(v)
@@ -53,7 +62,7 @@ func (c *S3Client) GetJSON(ctx context.Context, key string, v any) error {
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return fmt.Errorf("failed to get object from S3: %w", err)
+		return fmt.Errorf("failed to get object from S3: %w", notFound(err))
 	}
 	defer result.Body.Close()
 
@@ -76,7 +85,7 @@ func (c *S3Client) GetRaw(ctx context.Context, key string) ([]byte, error) {
 		Key:    aws.String(key),
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get object from S3: %w", err)
+		return nil, fmt.Errorf("failed to get object from S3: %w", notFound(err))
 	}
 	defer result.Body.Close()
 
@@ -121,6 +130,15 @@ func (c *S3Client) Exists(ctx context.Context, key string) (bool, error) {
 	return true, nil
 }
 
+// notFound replaces a missing-key error with ErrBlobNotFound.
+func notFound(err error) error {
+	var noSuchKey *s3types.NoSuchKey
+	if errors.As(err, &noSuchKey) {
+		return ErrBlobNotFound
+	}
+	return err
+}
+
 // ToolManifestKey returns the S3 key for a tool manifest.
 func ToolManifestKey(tenantID, toolID, version string) string {
 	return fmt.Sprintf("manifests/%s/tools/%s/%s.json", tenantID, toolID, version)
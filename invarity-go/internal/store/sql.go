@@ -0,0 +1,825 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"invarity/internal/auth"
+)
+
+// SQLStore is a control plane backend on a SQL database. Timestamps are stored
+// as RFC 3339 text and token scopes as a JSON array. Queries are written with ?
+// placeholders and rewritten for drivers that number them.
+type SQLStore struct {
+	db           *sql.DB
+	dollarParams bool // Driver expects $1, $2, ... (Postgres)
+}
+
+// DB returns the underlying database, for stores that share it.
+func (s *SQLStore) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database.
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// Ping checks that the database is reachable.
+func (s *SQLStore) Ping(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+	return nil
+}
+
+// bind rewrites a query's ? placeholders for the driver.
+func (s *SQLStore) bind(query string) string {
+	if !s.dollarParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.db.ExecContext(ctx, s.bind(query), args...)
+}
+
+func (s *SQLStore) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.db.QueryContext(ctx, s.bind(query), args...)
+}
+
+func (s *SQLStore) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.db.QueryRowContext(ctx, s.bind(query), args...)
+}
+
+// timeFormat is RFC 3339 in UTC with fixed-width nanoseconds, so stored
+// timestamps sort as text.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// formatTime formats t for storage; the zero time is stored as "".
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(timeFormat)
+}
+
+// parseTime parses a stored timestamp; "" is the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// parseTimes parses stored timestamps into their destinations.
+func parseTimes(pairs ...any) error {
+	for i := 0; i < len(pairs); i += 2 {
+		t, err := parseTime(pairs[i].(string))
+		if err != nil {
+			return fmt.Errorf("invalid timestamp: %w", err)
+		}
+		*pairs[i+1].(*time.Time) = t
+	}
+	return nil
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// --- User Operations ---
+
+const userColumns = "user_id, email, display_name, status, created_at, updated_at, last_login_at"
+
+func scanUser(row rowScanner) (*User, error) {
+	var u User
+	var created, updated, lastLogin string
+	if err := row.Scan(&u.UserID, &u.Email, &u.DisplayName, &u.Status, &created, &updated, &lastLogin); err != nil {
+		return nil, err
+	}
+	return &u, parseTimes(created, &u.CreatedAt, updated, &u.UpdatedAt, lastLogin, &u.LastLoginAt)
+}
+
+// GetOrCreateUser gets an existing user or creates a new one.
+func (s *SQLStore) GetOrCreateUser(ctx context.Context, userID, email string) (*User, error) {
+	now := time.Now().UTC()
+	// Conflicting inserts from concurrent first logins are ignored
+	if _, err := s.exec(ctx,
+		`INSERT INTO users (`+userColumns+`) VALUES (?, ?, '', 'active', ?, ?, ?) ON CONFLICT (user_id) DO NOTHING`,
+		userID, email, formatTime(now), formatTime(now), formatTime(now)); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := s.UpdateUserLastLogin(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.GetUser(ctx, userID)
+}
+
+// GetUser retrieves a user by ID.
+func (s *SQLStore) GetUser(ctx context.Context, userID string) (*User, error) {
+	user, err := scanUser(s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE user_id = ?`, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// UpdateUserLastLogin updates the last login timestamp.
+func (s *SQLStore) UpdateUserLastLogin(ctx context.Context, userID string) error {
+	now := formatTime(time.Now().UTC())
+	if _, err := s.exec(ctx, `UPDATE users SET last_login_at = ?, updated_at = ? WHERE user_id = ?`, now, now, userID); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	return nil
+}
+
+// --- Tenant Operations ---
+
+const tenantColumns = "tenant_id, name, status, plan, settings, created_by, created_at, updated_at"
+
+func scanTenant(row rowScanner) (*Tenant, error) {
+	var t Tenant
+	var created, updated string
+	if err := row.Scan(&t.TenantID, &t.Name, &t.Status, &t.Plan, &t.Settings, &t.CreatedBy, &created, &updated); err != nil {
+		return nil, err
+	}
+	return &t, parseTimes(created, &t.CreatedAt, updated, &t.UpdatedAt)
+}
+
+// CreateTenant creates a new tenant.
+func (s *SQLStore) CreateTenant(ctx context.Context, name, createdBy string) (*Tenant, error) {
+	now := time.Now().UTC()
+	tenant := &Tenant{
+		TenantID:  uuid.New().String(),
+		Name:      name,
+		Status:    "active",
+		Plan:      "free",
+		CreatedAt: now,
+		UpdatedAt: now,
+		CreatedBy: createdBy,
+	}
+	if _, err := s.exec(ctx,
+		`INSERT INTO tenants (`+tenantColumns+`) VALUES (?, ?, ?, ?, '', ?, ?, ?)`,
+		tenant.TenantID, tenant.Name, tenant.Status, tenant.Plan, tenant.CreatedBy, formatTime(now), formatTime(now)); err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// GetTenant retrieves a tenant by ID.
+func (s *SQLStore) GetTenant(ctx context.Context, tenantID string) (*Tenant, error) {
+	tenant, err := scanTenant(s.queryRow(ctx, `SELECT `+tenantColumns+` FROM tenants WHERE tenant_id = ?`, tenantID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// TenantPlan returns a tenant's plan, or "" if the tenant does not exist.
+func (s *SQLStore) TenantPlan(ctx context.Context, tenantID string) (string, error) {
+	tenant, err := s.GetTenant(ctx, tenantID)
+	if err != nil || tenant == nil {
+		return "", err
+	}
+	return tenant.Plan, nil
+}
+
+// --- Membership Operations ---
+
+// CreateMembership creates a new tenant membership.
+func (s *SQLStore) CreateMembership(ctx context.Context, tenantID, userID string, role auth.Role, invitedBy string) (*TenantMembership, error) {
+	now := time.Now().UTC()
+	membership := &TenantMembership{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Role:      role,
+		Status:    "active",
+		InvitedBy: invitedBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	res, err := s.exec(ctx,
+		`INSERT INTO memberships (tenant_id, user_id, role, status, invited_by, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?) ON CONFLICT (tenant_id, user_id) DO NOTHING`,
+		tenantID, userID, string(role), membership.Status, invitedBy, formatTime(now), formatTime(now))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create membership: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("membership already exists")
+	}
+	return membership, nil
+}
+
+// GetMembership retrieves a membership for auth.MembershipChecker interface.
+func (s *SQLStore) GetMembership(ctx context.Context, tenantID, userID string) (*auth.Membership, error) {
+	var m auth.Membership
+	var role string
+	err := s.queryRow(ctx,
+		`SELECT tenant_id, user_id, role, status FROM memberships WHERE tenant_id = ? AND user_id = ?`,
+		tenantID, userID).Scan(&m.TenantID, &m.UserID, &role, &m.Status)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	m.Role = auth.Role(role)
+	return &m, nil
+}
+
+// ListTenantsForUser lists all tenants a user is a member of, oldest membership first.
+func (s *SQLStore) ListTenantsForUser(ctx context.Context, userID string) ([]TenantWithRole, error) {
+	rows, err := s.query(ctx,
+		`SELECT t.tenant_id, t.name, m.role FROM memberships m JOIN tenants t ON t.tenant_id = m.tenant_id
+		 WHERE m.user_id = ? AND m.status = 'active' ORDER BY m.created_at, m.tenant_id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query memberships: %w", err)
+	}
+	defer rows.Close()
+
+	tenants := make([]TenantWithRole, 0)
+	for rows.Next() {
+		var t TenantWithRole
+		var role string
+		if err := rows.Scan(&t.TenantID, &t.Name, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan membership: %w", err)
+		}
+		t.Role = auth.Role(role)
+		tenants = append(tenants, t)
+	}
+	return tenants, rows.Err()
+}
+
+// GetUserOwnedTenant returns the tenant owned by a user (for idempotent bootstrap).
+func (s *SQLStore) GetUserOwnedTenant(ctx context.Context, userID string) (*Tenant, error) {
+	var tenantID string
+	err := s.queryRow(ctx,
+		`SELECT tenant_id FROM memberships WHERE user_id = ? AND role = ? AND status = 'active'
+		 ORDER BY created_at, tenant_id LIMIT 1`, userID, string(auth.RoleOwner)).Scan(&tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query owned tenants: %w", err)
+	}
+	return s.GetTenant(ctx, tenantID)
+}
+
+// --- Principal Operations ---
+
+const principalColumns = "principal_id, tenant_id, name, description, type, status, metadata, created_by, created_at, updated_at"
+
+func scanPrincipal(row rowScanner) (*Principal, error) {
+	var p Principal
+	var created, updated string
+	if err := row.Scan(&p.PrincipalID, &p.TenantID, &p.Name, &p.Description, &p.Type, &p.Status, &p.Metadata, &p.CreatedBy, &created, &updated); err != nil {
+		return nil, err
+	}
+	return &p, parseTimes(created, &p.CreatedAt, updated, &p.UpdatedAt)
+}
+
+// CreatePrincipal creates a new principal under a tenant.
+func (s *SQLStore) CreatePrincipal(ctx context.Context, tenantID, name, principalType, createdBy string) (*Principal, error) {
+	now := time.Now().UTC()
+	principal := &Principal{
+		PrincipalID: uuid.New().String(),
+		TenantID:    tenantID,
+		Name:        name,
+		Type:        principalType,
+		Status:      "active",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   createdBy,
+	}
+	if _, err := s.exec(ctx,
+		`INSERT INTO principals (`+principalColumns+`) VALUES (?, ?, ?, '', ?, ?, '', ?, ?, ?)`,
+		principal.PrincipalID, tenantID, name, principalType, principal.Status, createdBy, formatTime(now), formatTime(now)); err != nil {
+		return nil, fmt.Errorf("failed to create principal: %w", err)
+	}
+	return principal, nil
+}
+
+// GetPrincipal retrieves a tenant's principal by ID.
+func (s *SQLStore) GetPrincipal(ctx context.Context, tenantID, principalID string) (*Principal, error) {
+	principal, err := scanPrincipal(s.queryRow(ctx,
+		`SELECT `+principalColumns+` FROM principals WHERE tenant_id = ? AND principal_id = ?`, tenantID, principalID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get principal: %w", err)
+	}
+	return principal, nil
+}
+
+// ListPrincipals lists principals for a tenant, oldest first.
+func (s *SQLStore) ListPrincipals(ctx context.Context, tenantID string) ([]Principal, error) {
+	rows, err := s.query(ctx,
+		`SELECT `+principalColumns+` FROM principals WHERE tenant_id = ? AND status = 'active' ORDER BY created_at, principal_id`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query principals: %w", err)
+	}
+	defer rows.Close()
+
+	principals := make([]Principal, 0)
+	for rows.Next() {
+		p, err := scanPrincipal(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan principal: %w", err)
+		}
+		principals = append(principals, *p)
+	}
+	return principals, rows.Err()
+}
+
+// --- Token Operations ---
+
+const tokenColumns = "token_id, tenant_id, principal_id, key_hash, key_prefix, name, type, scopes, status, created_by, created_at, expires_at, last_used_at, revoked_at, revoked_by"
+
+func scanToken(row rowScanner) (*Token, error) {
+	var t Token
+	var scopes, created, expires, lastUsed, revoked string
+	if err := row.Scan(&t.TokenID, &t.TenantID, &t.PrincipalID, &t.KeyHash, &t.KeyPrefix, &t.Name, &t.Type, &scopes, &t.Status,
+		&t.CreatedBy, &created, &expires, &lastUsed, &revoked, &t.RevokedBy); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.Scopes); err != nil {
+		return nil, fmt.Errorf("invalid token scopes: %w", err)
+	}
+	return &t, parseTimes(created, &t.CreatedAt, expires, &t.ExpiresAt, lastUsed, &t.LastUsedAt, revoked, &t.RevokedAt)
+}
+
+// CreateToken creates a new API token.
+// Returns the plaintext token (only shown once) and the stored token record.
+func (s *SQLStore) CreateToken(ctx context.Context, tenantID, principalID, name, tokenType, createdBy string, scopes []string, expiresAt *time.Time) (string, *Token, error) {
+	plaintext, token := newTokenRecord(tenantID, principalID, name, tokenType, createdBy, scopes, expiresAt)
+	scopesJSON, err := json.Marshal(token.Scopes)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to marshal token scopes: %w", err)
+	}
+	if _, err := s.exec(ctx,
+		`INSERT INTO tokens (`+tokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, '', '', '')`,
+		token.TokenID, tenantID, principalID, token.KeyHash, token.KeyPrefix, name, tokenType, string(scopesJSON), token.Status,
+		createdBy, formatTime(token.CreatedAt), formatTime(token.ExpiresAt)); err != nil {
+		return "", nil, fmt.Errorf("failed to create token: %w", err)
+	}
+	return plaintext, token, nil
+}
+
+// GetToken retrieves a tenant's token by ID.
+func (s *SQLStore) GetToken(ctx context.Context, tenantID, tokenID string) (*Token, error) {
+	token, err := scanToken(s.queryRow(ctx, `SELECT `+tokenColumns+` FROM tokens WHERE tenant_id = ? AND token_id = ?`, tenantID, tokenID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+	return token, nil
+}
+
+// ListTokens lists a tenant's tokens, newest first.
+func (s *SQLStore) ListTokens(ctx context.Context, tenantID string) ([]Token, error) {
+	rows, err := s.query(ctx, `SELECT `+tokenColumns+` FROM tokens WHERE tenant_id = ? ORDER BY created_at DESC, token_id`, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []Token
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeToken revokes a tenant's token by ID.
+func (s *SQLStore) RevokeToken(ctx context.Context, tenantID, tokenID, revokedBy string) error {
+	res, err := s.exec(ctx,
+		`UPDATE tokens SET status = 'revoked', revoked_at = ?, revoked_by = ? WHERE tenant_id = ? AND token_id = ?`,
+		formatTime(time.Now().UTC()), revokedBy, tenantID, tokenID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
+	return nil
+}
+
+// ValidateToken validates a token and returns the token record if valid.
+func (s *SQLStore) ValidateToken(ctx context.Context, plaintext string) (*Token, error) {
+	token, err := scanToken(s.queryRow(ctx, `SELECT `+tokenColumns+` FROM tokens WHERE key_hash = ?`, hashToken(plaintext)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate token: %w", err)
+	}
+	if !tokenUsable(token) {
+		return nil, nil
+	}
+	return token, nil
+}
+
+// ExpireToken brings a tenant's token's expiry forward to expiresAt.
+func (s *SQLStore) ExpireToken(ctx context.Context, tenantID, tokenID string, expiresAt time.Time) error {
+	token, err := s.GetToken(ctx, tenantID, tokenID)
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return fmt.Errorf("token %s not found", tokenID)
+	}
+	at := formatTime(expiresAt)
+	if _, err := s.exec(ctx,
+		`UPDATE tokens SET expires_at = ? WHERE tenant_id = ? AND token_id = ? AND (expires_at = '' OR expires_at > ?)`,
+		at, tenantID, tokenID, at); err != nil {
+		return fmt.Errorf("failed to expire token: %w", err)
+	}
+	return nil
+}
+
+// TouchTokens records when each token was last used.
+func (s *SQLStore) TouchTokens(ctx context.Context, lastUsed map[string]time.Time) error {
+	for tokenID, usedAt := range lastUsed {
+		at := formatTime(usedAt)
+		if _, err := s.exec(ctx,
+			`UPDATE tokens SET last_used_at = ? WHERE token_id = ? AND last_used_at < ?`,
+			at, tokenID, at); err != nil {
+			return fmt.Errorf("failed to touch token %s: %w", tokenID, err)
+		}
+	}
+	return nil
+}
+
+// --- Tool Operations ---
+
+const toolColumns = "tenant_id, tool_id, version, schema_hash, name, risk_level, s3_key, status, created_at, updated_at"
+
+func scanTool(row rowScanner) (*ToolRecord, error) {
+	var t ToolRecord
+	if err := row.Scan(&t.TenantID, &t.ToolID, &t.Version, &t.SchemaHash, &t.Name, &t.RiskLevel, &t.S3Key, &t.Status, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.SK = t.ToolID + "#" + t.Version
+	return &t, nil
+}
+
+// UpsertTool creates a tool metadata record, or succeeds without change if the
+// version exists with the same schema hash. The insert and the hash comparison
+// run in one transaction, so concurrent registrations of a version cannot both
+// succeed with different hashes.
+// Returns (isNew, error) - isNew is true if this was a new tool version.
+func (s *SQLStore) UpsertTool(ctx context.Context, tenantID, toolID, version, schemaHash, name, riskLevel, s3Key string) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, s.bind(
+		`INSERT INTO tools (`+toolColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, 'active', ?, ?)
+		 ON CONFLICT (tenant_id, tool_id, version) DO NOTHING`),
+		tenantID, toolID, version, schemaHash, name, riskLevel, s3Key, now, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to create tool record: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to create tool record: %w", err)
+	}
+
+	if inserted == 0 {
+		// The version exists; it is idempotent only with the same schema hash
+		var existingHash string
+		if err := tx.QueryRowContext(ctx, s.bind(
+			`SELECT schema_hash FROM tools WHERE tenant_id = ? AND tool_id = ? AND version = ?`),
+			tenantID, toolID, version).Scan(&existingHash); err != nil {
+			return false, fmt.Errorf("failed to get tool: %w", err)
+		}
+		if existingHash != schemaHash {
+			return false, fmt.Errorf("conflict: tool %s version %s already exists with different schema hash", toolID, version)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to create tool record: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// GetToolRecord retrieves a tool record by tenant, tool_id, and version.
+func (s *SQLStore) GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*ToolRecord, error) {
+	record, err := scanTool(s.queryRow(ctx,
+		`SELECT `+toolColumns+` FROM tools WHERE tenant_id = ? AND tool_id = ? AND version = ?`, tenantID, toolID, version))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tool: %w", err)
+	}
+	return record, nil
+}
+
+// ListToolVersions lists every version of a tool.
+func (s *SQLStore) ListToolVersions(ctx context.Context, tenantID, toolID string) ([]ToolRecord, error) {
+	rows, err := s.query(ctx,
+		`SELECT `+toolColumns+` FROM tools WHERE tenant_id = ? AND tool_id = ?`, tenantID, toolID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool versions: %w", err)
+	}
+	defer rows.Close()
+
+	tools := make([]ToolRecord, 0)
+	for rows.Next() {
+		t, err := scanTool(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tool: %w", err)
+		}
+		tools = append(tools, *t)
+	}
+	return tools, rows.Err()
+}
+
+// DeprecateTool marks a tool version deprecated.
+func (s *SQLStore) DeprecateTool(ctx context.Context, tenantID, toolID, version string) error {
+	res, err := s.exec(ctx,
+		`UPDATE tools SET status = 'deprecated', updated_at = ? WHERE tenant_id = ? AND tool_id = ? AND version = ?`,
+		time.Now().UTC().Format(time.RFC3339), tenantID, toolID, version)
+	if err != nil {
+		return fmt.Errorf("failed to deprecate tool: %w", err)
+	}
+	updated, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to deprecate tool: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("failed to deprecate tool: tool %s version %s not found", toolID, version)
+	}
+	return nil
+}
+
+// ListTools lists a tenant's active tools in descending tool_id#version order,
+// matching the DynamoDB sort key.
+func (s *SQLStore) ListTools(ctx context.Context, tenantID string, limit int32) ([]ToolRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.query(ctx,
+		`SELECT `+toolColumns+` FROM tools WHERE tenant_id = ? AND status = 'active'
+		 ORDER BY tool_id || '#' || version DESC LIMIT ?`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	defer rows.Close()
+
+	tools := make([]ToolRecord, 0)
+	for rows.Next() {
+		t, err := scanTool(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan tool: %w", err)
+		}
+		tools = append(tools, *t)
+	}
+	return tools, rows.Err()
+}
+
+// --- Toolset Operations ---
+
+const toolsetColumns = "tenant_id, toolset_id, revision, name, tool_count, s3_key, status, created_by, created_at, updated_at"
+
+func scanToolset(row rowScanner) (*ToolsetRecord, error) {
+	var t ToolsetRecord
+	if err := row.Scan(&t.TenantID, &t.ToolsetID, &t.Revision, &t.Name, &t.ToolCount, &t.S3Key, &t.Status, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	t.SK = t.ToolsetID + "#" + t.Revision
+	return &t, nil
+}
+
+// RegisterToolset creates a new toolset record, or succeeds without change if
+// the revision exists with the same manifest key. The insert and the comparison
+// run in one transaction, as in UpsertTool.
+// Returns (isNew, error) - isNew is true if this was a new revision.
+func (s *SQLStore) RegisterToolset(ctx context.Context, tenantID, toolsetID, revision, name, s3Key, createdBy string, toolCount int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, s.bind(
+		`INSERT INTO toolsets (`+toolsetColumns+`) VALUES (?, ?, ?, ?, ?, ?, 'active', ?, ?, ?)
+		 ON CONFLICT (tenant_id, toolset_id, revision) DO NOTHING`),
+		tenantID, toolsetID, revision, name, toolCount, s3Key, createdBy, now, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to create toolset record: %w", err)
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to create toolset record: %w", err)
+	}
+
+	if inserted == 0 {
+		var existingKey string
+		if err := tx.QueryRowContext(ctx, s.bind(
+			`SELECT s3_key FROM toolsets WHERE tenant_id = ? AND toolset_id = ? AND revision = ?`),
+			tenantID, toolsetID, revision).Scan(&existingKey); err != nil {
+			return false, fmt.Errorf("failed to get toolset: %w", err)
+		}
+		if existingKey != s3Key {
+			return false, fmt.Errorf("conflict: toolset %s revision %s already exists", toolsetID, revision)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to create toolset record: %w", err)
+	}
+	return inserted > 0, nil
+}
+
+// GetToolsetRecord retrieves a toolset record by tenant, toolset_id, and revision.
+func (s *SQLStore) GetToolsetRecord(ctx context.Context, tenantID, toolsetID, revision string) (*ToolsetRecord, error) {
+	record, err := scanToolset(s.queryRow(ctx,
+		`SELECT `+toolsetColumns+` FROM toolsets WHERE tenant_id = ? AND toolset_id = ? AND revision = ?`, tenantID, toolsetID, revision))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get toolset: %w", err)
+	}
+	return record, nil
+}
+
+// ListToolsets lists a tenant's active toolsets in descending
+// toolset_id#revision order, matching the DynamoDB sort key.
+func (s *SQLStore) ListToolsets(ctx context.Context, tenantID string, limit int32) ([]ToolsetRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.query(ctx,
+		`SELECT `+toolsetColumns+` FROM toolsets WHERE tenant_id = ? AND status = 'active'
+		 ORDER BY toolset_id || '#' || revision DESC LIMIT ?`, tenantID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list toolsets: %w", err)
+	}
+	defer rows.Close()
+
+	toolsets := make([]ToolsetRecord, 0)
+	for rows.Next() {
+		t, err := scanToolset(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan toolset: %w", err)
+		}
+		toolsets = append(toolsets, *t)
+	}
+	return toolsets, rows.Err()
+}
+
+// --- Principal Active Toolset Operations ---
+
+// SetPrincipalActiveToolset sets the active toolset for a principal.
+func (s *SQLStore) SetPrincipalActiveToolset(ctx context.Context, tenantID, principalID, toolsetID, revision, assignedBy string) error {
+	now := formatTime(time.Now().UTC())
+	res, err := s.exec(ctx,
+		`UPDATE principals SET active_toolset_id = ?, active_toolset_revision = ?, toolset_assigned_at = ?, toolset_assigned_by = ?, updated_at = ?
+		 WHERE principal_id = ? AND tenant_id = ?`,
+		toolsetID, revision, now, assignedBy, now, principalID, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to set principal active toolset: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("failed to set principal active toolset: principal %s not found in tenant %s", principalID, tenantID)
+	}
+	return nil
+}
+
+// GetPrincipalActiveToolset retrieves the active toolset for a tenant's principal.
+func (s *SQLStore) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (toolsetID, revision string, err error) {
+	err = s.queryRow(ctx,
+		`SELECT active_toolset_id, active_toolset_revision FROM principals WHERE tenant_id = ? AND principal_id = ?`,
+		tenantID, principalID).Scan(&toolsetID, &revision)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get principal: %w", err)
+	}
+	return toolsetID, revision, nil
+}
+
+// SQLBlobStore stores blobs in a table of the control plane database.
+type SQLBlobStore struct {
+	db *sql.DB
+}
+
+// Blobs returns a blob store in the same database.
+func (s *SQLStore) Blobs() *SQLBlobStore {
+	return &SQLBlobStore{db: s.db}
+}
+
+// Ping checks that the database is reachable.
+func (b *SQLBlobStore) Ping(ctx context.Context) error {
+	if err := b.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to reach database: %w", err)
+	}
+	return nil
+}
+
+// PutJSON writes a value as JSON.
+func (b *SQLBlobStore) PutJSON(ctx context.Context, key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return b.PutRaw(ctx, key, data, "application/json")
+}
+
+// GetJSON reads a value and unmarshals it.
+func (b *SQLBlobStore) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := b.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %w", err)
+	}
+	return nil
+}
+
+// PutRaw writes raw bytes, replacing any existing blob.
+func (b *SQLBlobStore) PutRaw(ctx context.Context, key string, data []byte, contentType string) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if _, err := b.db.ExecContext(ctx,
+		`INSERT INTO blobs (blob_key, content_type, data) VALUES (?, ?, ?)
+		 ON CONFLICT (blob_key) DO UPDATE SET content_type = excluded.content_type, data = excluded.data`,
+		key, contentType, data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", key, err)
+	}
+	return nil
+}
+
+// GetRaw reads raw bytes.
+func (b *SQLBlobStore) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := b.db.QueryRowContext(ctx, `SELECT data FROM blobs WHERE blob_key = ?`, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get %s: %w", key, ErrBlobNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s: %w", key, err)
+	}
+	return data, nil
+}
+
+// Exists checks if a key exists.
+func (b *SQLBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	var n int
+	if err := b.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM blobs WHERE blob_key = ?`, key).Scan(&n); err != nil {
+		return false, fmt.Errorf("failed to check %s: %w", key, err)
+	}
+	return n > 0, nil
+}
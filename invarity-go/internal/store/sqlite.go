@@ -0,0 +1,136 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" driver
+)
+
+// sqliteSchema creates the control plane tables. Statements are idempotent so
+// the schema is applied on every open.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	user_id       TEXT PRIMARY KEY,
+	email         TEXT NOT NULL,
+	display_name  TEXT NOT NULL DEFAULT '',
+	status        TEXT NOT NULL,
+	created_at    TEXT NOT NULL,
+	updated_at    TEXT NOT NULL,
+	last_login_at TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS tenants (
+	tenant_id  TEXT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	plan       TEXT NOT NULL,
+	settings   TEXT NOT NULL DEFAULT '',
+	created_by TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS memberships (
+	tenant_id  TEXT NOT NULL,
+	user_id    TEXT NOT NULL,
+	role       TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	invited_by TEXT NOT NULL DEFAULT '',
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (tenant_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS memberships_user_id ON memberships (user_id);
+
+CREATE TABLE IF NOT EXISTS principals (
+	principal_id            TEXT PRIMARY KEY,
+	tenant_id               TEXT NOT NULL,
+	name                    TEXT NOT NULL,
+	description             TEXT NOT NULL DEFAULT '',
+	type                    TEXT NOT NULL,
+	status                  TEXT NOT NULL,
+	metadata                TEXT NOT NULL DEFAULT '',
+	created_by              TEXT NOT NULL,
+	created_at              TEXT NOT NULL,
+	updated_at              TEXT NOT NULL,
+	active_toolset_id       TEXT NOT NULL DEFAULT '',
+	active_toolset_revision TEXT NOT NULL DEFAULT '',
+	toolset_assigned_at     TEXT NOT NULL DEFAULT '',
+	toolset_assigned_by     TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS principals_tenant_id ON principals (tenant_id);
+
+CREATE TABLE IF NOT EXISTS tokens (
+	token_id     TEXT PRIMARY KEY,
+	tenant_id    TEXT NOT NULL,
+	principal_id TEXT NOT NULL DEFAULT '',
+	key_hash     TEXT NOT NULL UNIQUE,
+	key_prefix   TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	scopes       TEXT NOT NULL,
+	status       TEXT NOT NULL,
+	created_by   TEXT NOT NULL,
+	created_at   TEXT NOT NULL,
+	expires_at   TEXT NOT NULL DEFAULT '',
+	last_used_at TEXT NOT NULL DEFAULT '',
+	revoked_at   TEXT NOT NULL DEFAULT '',
+	revoked_by   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS tokens_tenant_id ON tokens (tenant_id);
+
+CREATE TABLE IF NOT EXISTS tools (
+	tenant_id   TEXT NOT NULL,
+	tool_id     TEXT NOT NULL,
+	version     TEXT NOT NULL,
+	schema_hash TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	risk_level  TEXT NOT NULL,
+	s3_key      TEXT NOT NULL,
+	status      TEXT NOT NULL,
+	created_at  TEXT NOT NULL,
+	updated_at  TEXT NOT NULL,
+	PRIMARY KEY (tenant_id, tool_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS toolsets (
+	tenant_id  TEXT NOT NULL,
+	toolset_id TEXT NOT NULL,
+	revision   TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	tool_count INTEGER NOT NULL,
+	s3_key     TEXT NOT NULL,
+	status     TEXT NOT NULL,
+	created_by TEXT NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (tenant_id, toolset_id, revision)
+);
+
+CREATE TABLE IF NOT EXISTS blobs (
+	blob_key     TEXT PRIMARY KEY,
+	content_type TEXT NOT NULL,
+	data         BLOB NOT NULL
+);
+`
+
+// NewSQLiteStore opens (creating if needed) a SQLite database at path and
+// applies the control plane schema. The database allows one connection at a
+// time, which suits a single local server.
+func NewSQLiteStore(ctx context.Context, path string) (*SQLStore, error) {
+	dsn := "file:" + path + "?" + url.Values{"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)"}}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to apply SQLite schema: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
@@ -0,0 +1,199 @@
+// Package store provides data access layer for storage backends.
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	"invarity/internal/auth"
+)
+
+// Getters return nil (and no error) when the requested item does not exist.
+
+// UserStore stores users.
+type UserStore interface {
+	// GetOrCreateUser gets an existing user, recording the login, or creates a new one.
+	GetOrCreateUser(ctx context.Context, userID, email string) (*User, error)
+	GetUser(ctx context.Context, userID string) (*User, error)
+	UpdateUserLastLogin(ctx context.Context, userID string) error
+}
+
+// TenantStore stores tenants.
+type TenantStore interface {
+	CreateTenant(ctx context.Context, name, createdBy string) (*Tenant, error)
+	GetTenant(ctx context.Context, tenantID string) (*Tenant, error)
+	// TenantPlan returns a tenant's plan, or "" if the tenant does not exist.
+	TenantPlan(ctx context.Context, tenantID string) (string, error)
+}
+
+// MembershipStore stores users' tenant memberships.
+type MembershipStore interface {
+	CreateMembership(ctx context.Context, tenantID, userID string, role auth.Role, invitedBy string) (*TenantMembership, error)
+	GetMembership(ctx context.Context, tenantID, userID string) (*auth.Membership, error)
+	// ListTenantsForUser lists the tenants a user is an active member of.
+	ListTenantsForUser(ctx context.Context, userID string) ([]TenantWithRole, error)
+	// GetUserOwnedTenant returns the tenant a user owns (for idempotent bootstrap).
+	GetUserOwnedTenant(ctx context.Context, userID string) (*Tenant, error)
+}
+
+// PrincipalStore stores agent principals.
+type PrincipalStore interface {
+	CreatePrincipal(ctx context.Context, tenantID, name, principalType, createdBy string) (*Principal, error)
+	// GetPrincipal returns nil if the principal is not in the tenant, whatever
+	// its status.
+	GetPrincipal(ctx context.Context, tenantID, principalID string) (*Principal, error)
+	// ListPrincipals lists a tenant's active principals.
+	ListPrincipals(ctx context.Context, tenantID string) ([]Principal, error)
+}
+
+// TokenStore stores API tokens.
+type TokenStore interface {
+	// CreateToken returns the plaintext token (only shown once) and the stored record.
+	CreateToken(ctx context.Context, tenantID, principalID, name, tokenType, createdBy string, scopes []string, expiresAt *time.Time) (string, *Token, error)
+	// GetToken returns nil if the token is not in the tenant, whatever its status.
+	GetToken(ctx context.Context, tenantID, tokenID string) (*Token, error)
+	// ListTokens lists a tenant's tokens, revoked and expired ones included,
+	// newest first.
+	ListTokens(ctx context.Context, tenantID string) ([]Token, error)
+	// RevokeToken fails if the token is not in the tenant.
+	RevokeToken(ctx context.Context, tenantID, tokenID, revokedBy string) error
+	// ExpireToken brings a token's expiry forward to expiresAt, leaving one
+	// that expires sooner alone. It fails if the token is not in the tenant.
+	ExpireToken(ctx context.Context, tenantID, tokenID string, expiresAt time.Time) error
+	// ValidateToken returns the token record if the token is active and unexpired.
+	ValidateToken(ctx context.Context, plaintext string) (*Token, error)
+	// TouchTokens records when each token was last used, by token ID. Unknown
+	// tokens and times earlier than the recorded one are skipped.
+	TouchTokens(ctx context.Context, lastUsed map[string]time.Time) error
+}
+
+// ToolStore stores tenant tool metadata; manifests are kept in a BlobStore.
+type ToolStore interface {
+	// UpsertTool records a tool version. It returns true for a new version and
+	// a conflict error if the version exists with a different schema hash.
+	UpsertTool(ctx context.Context, tenantID, toolID, version, schemaHash, name, riskLevel, s3Key string) (bool, error)
+	GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*ToolRecord, error)
+	// ListToolVersions lists every version of a tool, deprecated ones included,
+	// in no particular order.
+	ListToolVersions(ctx context.Context, tenantID, toolID string) ([]ToolRecord, error)
+	// DeprecateTool marks a tool version deprecated. It still resolves when
+	// pinned, but latest-version and range resolution skip it.
+	DeprecateTool(ctx context.Context, tenantID, toolID, version string) error
+	// ListTools lists a tenant's active tools, newest first.
+	ListTools(ctx context.Context, tenantID string, limit int32) ([]ToolRecord, error)
+}
+
+// ToolsetStore stores tenant toolset metadata; manifests are kept in a BlobStore.
+type ToolsetStore interface {
+	// RegisterToolset records a toolset revision. It returns true for a new
+	// revision and a conflict error if the revision exists with another manifest.
+	RegisterToolset(ctx context.Context, tenantID, toolsetID, revision, name, s3Key, createdBy string, toolCount int) (bool, error)
+	GetToolsetRecord(ctx context.Context, tenantID, toolsetID, revision string) (*ToolsetRecord, error)
+	// ListToolsets lists a tenant's active toolsets, newest first.
+	ListToolsets(ctx context.Context, tenantID string, limit int32) ([]ToolsetRecord, error)
+}
+
+// AssignmentStore stores the toolset revision assigned to each principal.
+type AssignmentStore interface {
+	// SetPrincipalActiveToolset fails if the principal is not in the tenant.
+	SetPrincipalActiveToolset(ctx context.Context, tenantID, principalID, toolsetID, revision, assignedBy string) error
+	// GetPrincipalActiveToolset returns empty strings if none is assigned or
+	// the principal is not in the tenant.
+	GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (toolsetID, revision string, err error)
+}
+
+// ToolReader is the part of the control plane that tool resolution reads. Every
+// Store is one; so is a GitOps source.
+type ToolReader interface {
+	GetToolRecord(ctx context.Context, tenantID, toolID, version string) (*ToolRecord, error)
+	ListToolVersions(ctx context.Context, tenantID, toolID string) ([]ToolRecord, error)
+	GetPrincipal(ctx context.Context, tenantID, principalID string) (*Principal, error)
+	GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (toolsetID, revision string, err error)
+}
+
+// Store is a complete control plane backend.
+type Store interface {
+	UserStore
+	TenantStore
+	MembershipStore
+	PrincipalStore
+	TokenStore
+	ToolStore
+	ToolsetStore
+	AssignmentStore
+
+	// Ping checks that the backend is usable.
+	Ping(ctx context.Context) error
+}
+
+// ErrBlobNotFound is returned when reading a blob that does not exist.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore stores manifest blobs by key.
+type BlobStore interface {
+	PutJSON(ctx context.Context, key string, v any) error
+	GetJSON(ctx context.Context, key string, v any) error
+	PutRaw(ctx context.Context, key string, data []byte, contentType string) error
+	GetRaw(ctx context.Context, key string) ([]byte, error)
+	Exists(ctx context.Context, key string) (bool, error)
+
+	// Ping checks that blobs can be stored.
+	Ping(ctx context.Context) error
+}
+
+var (
+	_ Store     = (*DynamoDBStore)(nil)
+	_ Store     = (*InMemoryStore)(nil)
+	_ Store     = (*SQLStore)(nil)
+	_ BlobStore = (*S3Client)(nil)
+	_ BlobStore = (*InMemoryBlobStore)(nil)
+	_ BlobStore = (*DirBlobStore)(nil)
+	_ BlobStore = (*SQLBlobStore)(nil)
+	_ BlobStore = (*PostgresBlobStore)(nil)
+)
+
+// newTokenRecord generates a token and the record stored for it.
+func newTokenRecord(tenantID, principalID, name, tokenType, createdBy string, scopes []string, expiresAt *time.Time) (string, *Token) {
+	plaintext := generateSecureToken()
+	token := &Token{
+		TokenID:     uuid.New().String(),
+		TenantID:    tenantID,
+		PrincipalID: principalID,
+		KeyHash:     hashToken(plaintext),
+		KeyPrefix:   plaintext[:8],
+		Name:        name,
+		Type:        tokenType,
+		Scopes:      scopes,
+		Status:      "active",
+		CreatedAt:   time.Now().UTC(),
+		CreatedBy:   createdBy,
+	}
+	if expiresAt != nil {
+		token.ExpiresAt = *expiresAt
+	}
+	return plaintext, token
+}
+
+// tokenUsable reports whether a token is active and unexpired.
+func tokenUsable(token *Token) bool {
+	if token.Status != "active" {
+		return false
+	}
+	return token.ExpiresAt.IsZero() || token.ExpiresAt.After(time.Now())
+}
+
+func generateSecureToken() string {
+	// Generate a secure random token: inv_<uuid without dashes>
+	id := uuid.New().String()
+	return "inv_" + id[:8] + id[9:13] + id[14:18] + id[19:23] + id[24:]
+}
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
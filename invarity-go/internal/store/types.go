@@ -1,4 +1,3 @@
-// Package store provides data access layer for DynamoDB tables.
 package store
 
 import (
@@ -80,3 +79,35 @@ type TenantWithRole struct {
 	Name     string    `json:"name"`
 	Role     auth.Role `json:"role"`
 }
+
+// ToolRecord represents a tool version in a tenant's library.
+// In DynamoDB it uses composite key: tenant_id (PK) + tool_id#version (SK)
+type ToolRecord struct {
+	TenantID   string `dynamodbav:"tenant_id"`
+	SK         string `dynamodbav:"sk"` // tool_id#version
+	ToolID     string `dynamodbav:"tool_id"`
+	Version    string `dynamodbav:"version"`
+	SchemaHash string `dynamodbav:"schema_hash"`
+	Name       string `dynamodbav:"name"`
+	RiskLevel  string `dynamodbav:"risk_level"`
+	S3Key      string `dynamodbav:"s3_key"`
+	Status     string `dynamodbav:"status"` // "active", "deprecated"
+	CreatedAt  string `dynamodbav:"created_at"`
+	UpdatedAt  string `dynamodbav:"updated_at"`
+}
+
+// ToolsetRecord represents a toolset revision in a tenant's library.
+// In DynamoDB it uses composite key: tenant_id (PK) + toolset_id#revision (SK)
+type ToolsetRecord struct {
+	TenantID  string `dynamodbav:"tenant_id"`
+	SK        string `dynamodbav:"sk"` // toolset_id#revision
+	ToolsetID string `dynamodbav:"toolset_id"`
+	Revision  string `dynamodbav:"revision"`
+	Name      string `dynamodbav:"name"`
+	ToolCount int    `dynamodbav:"tool_count"`
+	S3Key     string `dynamodbav:"s3_key"`
+	Status    string `dynamodbav:"status"` // "active", "archived"
+	CreatedAt string `dynamodbav:"created_at"`
+	UpdatedAt string `dynamodbav:"updated_at"`
+	CreatedBy string `dynamodbav:"created_by"`
+}
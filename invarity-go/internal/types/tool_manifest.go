@@ -4,7 +4,10 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"invarity/internal/semver"
 )
 
 // ToolManifestV3 represents a tool manifest following schema v3.
@@ -29,6 +32,9 @@ type ToolManifestV3 struct {
 	// Risk profile (used for routing decisions)
 	RiskProfile RiskProfileV3 `json:"risk_profile"`
 
+	// Argument paths redacted or hashed in audit records
+	SensitiveArgs []SensitiveArg `json:"sensitive_args,omitempty"`
+
 	// Metadata
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
@@ -92,6 +98,10 @@ func (m *ToolManifestV3) Validate() error {
 	if m.ToolID == "" {
 		return fmt.Errorf("tool_id is required")
 	}
+	// Stores key versions as tool_id#version
+	if strings.Contains(m.ToolID, "#") {
+		return fmt.Errorf("tool_id must not contain '#'")
+	}
 	if m.Version == "" {
 		return fmt.Errorf("version is required")
 	}
@@ -110,6 +120,15 @@ func (m *ToolManifestV3) Validate() error {
 		return fmt.Errorf("base_risk_level must be one of: LOW, MEDIUM, HIGH, CRITICAL")
 	}
 
+	for i, arg := range m.SensitiveArgs {
+		if arg.Path == "" {
+			return fmt.Errorf("sensitive_args[%d].path is required", i)
+		}
+		if arg.Action != "" && arg.Action != SensitiveArgRedact && arg.Action != SensitiveArgHash {
+			return fmt.Errorf("sensitive_args[%d].action must be one of: redact, hash", i)
+		}
+	}
+
 	return nil
 }
 
@@ -146,6 +165,7 @@ func (m *ToolManifestV3) ToToolRegistryEntry() *ToolRegistryEntry {
 		UpdatedAt:     m.UpdatedAt,
 		Deprecated:    m.Deprecated,
 		DeprecatedMsg: m.DeprecatedMsg,
+		SensitiveArgs: m.SensitiveArgs,
 	}
 }
 
@@ -163,12 +183,22 @@ type ToolMetadata struct {
 	UpdatedAt  time.Time `json:"updated_at" dynamodbav:"updated_at"`
 }
 
-// ToolRef represents a reference to a specific tool version.
+// ToolRef represents a reference to a tool version, or in a toolset to a
+// semver range of versions (e.g. "^1.2", "~2.1.0").
 type ToolRef struct {
 	ToolID  string `json:"tool_id"`
 	Version string `json:"version"`
 }
 
+// LockedToolRef pins a toolset's tool reference to the version it resolved to
+// when the toolset revision was registered.
+type LockedToolRef struct {
+	ToolID     string `json:"tool_id"`
+	Constraint string `json:"constraint"` // The reference's version or range
+	Version    string `json:"version"`
+	SchemaHash string `json:"schema_hash"`
+}
+
 // ToolsetManifest represents a toolset that groups tools together.
 type ToolsetManifest struct {
 	// Core identity
@@ -178,6 +208,11 @@ type ToolsetManifest struct {
 	// Tool references
 	Tools []ToolRef `json:"tools"`
 
+	// Exact versions the references resolved to at registration. Evaluation
+	// uses these, so a newer tool version matching a range does not change
+	// what a registered revision resolves to.
+	Lock []LockedToolRef `json:"lock,omitempty"`
+
 	// Metadata
 	Name        string         `json:"name,omitempty"`
 	Description string         `json:"description,omitempty"`
@@ -207,11 +242,29 @@ func (m *ToolsetManifest) Validate() error {
 		if ref.Version == "" {
 			return fmt.Errorf("tools[%d].version is required", i)
 		}
+		if semver.IsRange(ref.Version) {
+			if _, err := semver.ParseRange(ref.Version); err != nil {
+				return fmt.Errorf("tools[%d].version: %w", i, err)
+			}
+		}
 	}
 
 	return nil
 }
 
+// ResolvedTools returns the exact tool versions the toolset resolves to: its
+// lock, or its references for a toolset registered before locking.
+func (m *ToolsetManifest) ResolvedTools() []ToolRef {
+	if len(m.Lock) == 0 {
+		return m.Tools
+	}
+	refs := make([]ToolRef, len(m.Lock))
+	for i, locked := range m.Lock {
+		refs[i] = ToolRef{ToolID: locked.ToolID, Version: locked.Version}
+	}
+	return refs
+}
+
 // ToolsetMetadata represents the DynamoDB metadata for a toolset.
 type ToolsetMetadata struct {
 	TenantID    string    `json:"tenant_id" dynamodbav:"tenant_id"`
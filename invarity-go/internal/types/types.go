@@ -139,11 +139,15 @@ type ToolCallRequest struct {
 
 // IntentVoterResult represents a single intent voter's result.
 type IntentVoterResult struct {
-	VoterID    string     `json:"voter_id"`
-	Vote       IntentVote `json:"vote"`
-	Confidence float64    `json:"confidence"`
-	Reasons    []string   `json:"reasons"`
-	Latency    Duration   `json:"latency_ms"`
+	VoterID              string      `json:"voter_id"`
+	Vote                 IntentVote  `json:"vote"`
+	Confidence           float64     `json:"confidence"`
+	CalibratedConfidence float64     `json:"calibrated_confidence"` // Probability the vote is correct, per the voter's calibration curve
+	Reasons              []string    `json:"reasons"`
+	Model                string      `json:"model,omitempty"`          // Model reported by the endpoint
+	PromptVersion        string      `json:"prompt_version,omitempty"` // Template ref used to build the prompt
+	Usage                *TokenUsage `json:"usage,omitempty"`          // Tokens consumed by the voter's model call
+	Latency              Duration    `json:"latency_ms"`
 }
 
 // IntentAlignmentResult represents the aggregated intent alignment quorum result.
@@ -155,6 +159,7 @@ type IntentAlignmentResult struct {
 
 // IntentContext contains the normalized context for intent evaluation.
 type IntentContext struct {
+	TenantID           string          `json:"tenant_id,omitempty"`
 	IntentSummary      string          `json:"intent_summary"`
 	ToolName           string          `json:"tool_name"`
 	ToolDescription    string          `json:"tool_description"`
@@ -168,10 +173,69 @@ type IntentContext struct {
 
 // ThreatResult represents the threat sentinel result.
 type ThreatResult struct {
-	Label       ThreatLabel `json:"label"`
-	ThreatTypes []string    `json:"types,omitempty"`
-	Confidence  float64     `json:"confidence"`
-	Latency     Duration    `json:"latency_ms"`
+	Label                ThreatLabel `json:"label"`
+	ThreatTypes          []string    `json:"types,omitempty"`
+	Confidence           float64     `json:"confidence"`
+	CalibratedConfidence float64     `json:"calibrated_confidence"`    // Probability the label is correct, per the sentinel's calibration curve
+	Model                string      `json:"model,omitempty"`          // Model reported by the endpoint
+	PromptVersion        string      `json:"prompt_version,omitempty"` // Template ref used to build the system prompt
+	Usage                *TokenUsage `json:"usage,omitempty"`          // Tokens consumed by the sentinel's model call
+	Latency              Duration    `json:"latency_ms"`
+}
+
+// TokenUsage counts the LLM tokens consumed by one or more model calls.
+// CostUSD is priced from the per-model pricing table when the usage is recorded.
+type TokenUsage struct {
+	Model            string  `json:"model,omitempty"` // Empty when summed across models
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	CostUSD          float64 `json:"cost_usd"`
+}
+
+// Add accumulates other into u.
+func (u *TokenUsage) Add(other *TokenUsage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.CostUSD += other.CostUSD
+}
+
+// ContextFinding is a heuristic indicator of indirect prompt injection in a bounded context snippet.
+type ContextFinding struct {
+	Source    string `json:"source"`    // "relevant_documents" or "conversation_history"
+	Index     int    `json:"index"`     // Position of the snippet within its source
+	Heuristic string `json:"heuristic"` // "instruction_phrasing", "role_switch", "hidden_unicode", "base64_blob"
+	Excerpt   string `json:"excerpt,omitempty"`
+}
+
+// ContextSnippetVerdict is the threat sentinel's classification of a flagged context snippet.
+type ContextSnippetVerdict struct {
+	Source     string      `json:"source"`
+	Index      int         `json:"index"`
+	Label      ThreatLabel `json:"label"`
+	Confidence float64     `json:"confidence"`
+}
+
+// ArgTrace records a tool call argument whose value appears verbatim in a flagged context snippet.
+type ArgTrace struct {
+	Path   string      `json:"path"` // e.g. "args.to[0]"
+	Source string      `json:"source"`
+	Index  int         `json:"index"`
+	Label  ThreatLabel `json:"label,omitempty"` // Sentinel verdict for the snippet, if classified
+}
+
+// ContextScanResult is the result of scanning bounded context for indirect prompt injection.
+type ContextScanResult struct {
+	Findings   []ContextFinding        `json:"findings,omitempty"`
+	Verdicts   []ContextSnippetVerdict `json:"verdicts,omitempty"`
+	TracedArgs []ArgTrace              `json:"traced_args,omitempty"`
+	Injected   bool                    `json:"injected"`        // A snippet was classified MALICIOUS or args trace to a flagged snippet
+	Usage      *TokenUsage             `json:"usage,omitempty"` // Tokens consumed classifying snippets
+	Latency    Duration                `json:"latency_ms"`
 }
 
 // ConstraintsResult represents the deterministic constraint evaluation result.
@@ -216,8 +280,14 @@ type FirewallDecisionResponse struct {
 	Constraints *ConstraintsResult     `json:"constraints,omitempty"`
 	Alignment   *IntentAlignmentResult `json:"alignment,omitempty"`
 	Threat      *ThreatResult          `json:"threat,omitempty"`
+	ContextScan *ContextScanResult     `json:"context_scan,omitempty"`
+	Usage       *TokenUsage            `json:"usage,omitempty"` // Total LLM tokens and cost for this evaluation
 	Timing      *PipelineTiming        `json:"timing,omitempty"`
 	EvaluatedAt time.Time              `json:"evaluated_at"`
+
+	// Schema hash of the resolved tool; callers pin later calls to it with
+	// tool_call.schema_hash
+	ExpectedSchemaHash string `json:"expected_schema_hash,omitempty"`
 }
 
 // PipelineTiming tracks latency for each pipeline step.
@@ -226,6 +296,7 @@ type PipelineTiming struct {
 	Canonicalize   Duration `json:"canonicalize_ms"`
 	SchemaValidate Duration `json:"schema_validate_ms"`
 	Constraints    Duration `json:"constraints_ms"`
+	ContextScan    Duration `json:"context_scan_ms,omitempty"`
 	Alignment      Duration `json:"alignment_ms"`
 	ThreatSentinel Duration `json:"threat_sentinel_ms,omitempty"`
 	Aggregate      Duration `json:"aggregate_ms"`
@@ -298,6 +369,22 @@ type ToolRegistryEntry struct {
 	UpdatedAt     time.Time       `json:"updated_at"`
 	Deprecated    bool            `json:"deprecated"`
 	DeprecatedMsg string          `json:"deprecated_msg,omitempty"`
+	SensitiveArgs []SensitiveArg  `json:"sensitive_args,omitempty"` // Argument paths redacted or hashed in audit records
+}
+
+// SensitiveArg actions.
+const (
+	SensitiveArgRedact = "redact" // Replace the value with a placeholder
+	SensitiveArgHash   = "hash"   // Replace the value with a keyed hash, so equal values still correlate
+)
+
+// SensitiveArg declares an argument path whose value must not be stored in
+// audit records. Path segments are separated by '.'; '*' matches every key of
+// an object or element of an array, and a number selects an array element
+// (e.g. "recipients.*.email").
+type SensitiveArg struct {
+	Path   string `json:"path"`
+	Action string `json:"action,omitempty"` // "redact" (default) or "hash"
 }
 
 // PolicyBundle represents a compiled policy bundle.
@@ -322,23 +409,82 @@ type PolicyRule struct {
 
 // AuditRecord represents a complete audit trail entry.
 type AuditRecord struct {
-	AuditID      string                 `json:"audit_id"`
-	RequestID    string                 `json:"request_id"`
-	OrgID        string                 `json:"org_id"`
-	Actor        Actor                  `json:"actor"`
-	Environment  Environment            `json:"env"`
-	ToolCall     ToolCall               `json:"tool_call"`
-	UserIntent   string                 `json:"user_intent"`
-	Decision     Decision               `json:"decision"`
-	RiskTier     RiskTier               `json:"risk_tier"`
-	Reasons      []string               `json:"reasons"`
-	Constraints  *ConstraintsResult     `json:"constraints,omitempty"`
-	Alignment    *IntentAlignmentResult `json:"alignment,omitempty"`
-	Threat       *ThreatResult          `json:"threat,omitempty"`
-	Timing       *PipelineTiming        `json:"timing,omitempty"`
-	PipelineStep string                 `json:"pipeline_step"` // Where decision was made
-	CreatedAt    time.Time              `json:"created_at"`
-	Metadata     map[string]any         `json:"metadata,omitempty"`
+	AuditID        string                 `json:"audit_id"`
+	RequestID      string                 `json:"request_id"`
+	OrgID          string                 `json:"org_id"`
+	TenantID       string                 `json:"tenant_id,omitempty"`
+	PrincipalID    string                 `json:"principal_id,omitempty"`
+	Actor          Actor                  `json:"actor"`
+	Environment    Environment            `json:"env"`
+	ToolCall       ToolCall               `json:"tool_call"`
+	UserIntent     string                 `json:"user_intent"`
+	BoundedContext *BoundedContext        `json:"bounded_context,omitempty"` // Omitted unless the tenant's audit policy keeps it
+	Decision       Decision               `json:"decision"`
+	RiskTier       RiskTier               `json:"risk_tier"`
+	Reasons        []string               `json:"reasons"`
+	Constraints    *ConstraintsResult     `json:"constraints,omitempty"`
+	Alignment      *IntentAlignmentResult `json:"alignment,omitempty"`
+	Threat         *ThreatResult          `json:"threat,omitempty"`
+	ContextScan    *ContextScanResult     `json:"context_scan,omitempty"`
+	Usage          *TokenUsage            `json:"usage,omitempty"`
+	Timing         *PipelineTiming        `json:"timing,omitempty"`
+	PipelineStep   string                 `json:"pipeline_step"` // Where decision was made
+	CreatedAt      time.Time              `json:"created_at"`
+	Metadata       map[string]any         `json:"metadata,omitempty"`
+	Snapshot       *DecisionSnapshot      `json:"snapshot,omitempty"` // Artifacts the decision was made with
+	Flags          []string               `json:"flags,omitempty"`    // AuditFlag* findings that need attention
+
+	// Tamper-evidence chain (per tenant)
+	Sequence int64  `json:"sequence,omitempty"`  // 1-based position in the tenant's chain
+	PrevHash string `json:"prev_hash,omitempty"` // Hash of the previous record in the chain
+	Hash     string `json:"hash,omitempty"`      // util.HashJSON of this record with Hash empty
+}
+
+// Audit flags mark records that need attention regardless of their decision.
+const (
+	// The call's schema hash did not match the tool it resolved to
+	AuditFlagSchemaHashMismatch = "schema_hash_mismatch"
+	// A stored tool manifest did not hash to its registered schema hash
+	AuditFlagManifestIntegrity = "manifest_integrity_failure"
+)
+
+// DecisionSnapshot records the artifacts and configuration a decision was made
+// with, so the decision can be reproduced later.
+type DecisionSnapshot struct {
+	FirewallVersion string          `json:"firewall_version"`
+	Tool            *ToolSnapshot   `json:"tool,omitempty"`     // Absent when the tool did not resolve
+	Voters          []ModelSnapshot `json:"voters,omitempty"`   // Intent alignment voters that ran
+	Sentinel        *ModelSnapshot  `json:"sentinel,omitempty"` // Absent when the threat sentinel did not run
+	Config          ConfigSnapshot  `json:"config"`
+}
+
+// ToolSnapshot identifies the exact tool manifest a call was evaluated against.
+type ToolSnapshot struct {
+	ToolID          string `json:"tool_id"`
+	Version         string `json:"version"`
+	SchemaHash      string `json:"schema_hash"`
+	ToolsetID       string `json:"toolset_id,omitempty"`
+	ToolsetRevision string `json:"toolset_revision,omitempty"`
+	ResolvedVia     string `json:"resolved_via"` // "toolset", "direct" or "legacy"
+}
+
+// ModelSnapshot identifies the model and prompt template behind a model stage.
+type ModelSnapshot struct {
+	VoterID       string `json:"voter_id,omitempty"`
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+}
+
+// ConfigSnapshot holds the configuration values that affect a decision.
+type ConfigSnapshot struct {
+	EnableThreatSentinel bool     `json:"enable_threat_sentinel"`
+	EnableContextScan    bool     `json:"enable_context_scan"`
+	QuorumTimeout        Duration `json:"quorum_timeout_ms"`
+	MinSafeProbability   float64  `json:"min_safe_probability"`
+	MinDenyProbability   float64  `json:"min_deny_probability"`
+	MinThreatProbability float64  `json:"min_threat_probability"`
+	MaxIntentChars       int      `json:"max_intent_chars"`
+	MaxContextChars      int      `json:"max_context_chars"`
 }
 
 // ErrorResponse represents an API error response.
@@ -351,8 +497,18 @@ type ErrorResponse struct {
 
 // HealthResponse represents the health check response.
 type HealthResponse struct {
-	Status    string            `json:"status"`
-	Version   string            `json:"version,omitempty"`
-	Timestamp time.Time         `json:"timestamp"`
-	Checks    map[string]string `json:"checks,omitempty"`
+	Status    string                 `json:"status"` // "ok", "degraded" or "not_ready"
+	Version   string                 `json:"version,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	Checks    map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// HealthCheck is the result of checking one dependency.
+type HealthCheck struct {
+	Status    string    `json:"status"`   // "ok" or "fail"
+	Required  bool      `json:"required"` // A failure makes the firewall not ready; otherwise it is degraded
+	Error     string    `json:"error,omitempty"`
+	Latency   Duration  `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Cached    bool      `json:"cached,omitempty"` // Reused from an earlier probe
 }
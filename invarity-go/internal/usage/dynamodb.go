@@ -0,0 +1,203 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDBAPI is the subset of the DynamoDB client used by DynamoDBStore.
+type DynamoDBAPI interface {
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+}
+
+// rollupItem is a rollup in the usage table (PK tenant_id, SK rollup_key,
+// which is {day}#{principal_id}#{tool_id}#{model}).
+type rollupItem struct {
+	TenantID         string  `dynamodbav:"tenant_id"`
+	RollupKey        string  `dynamodbav:"rollup_key"`
+	Day              string  `dynamodbav:"usage_day"`
+	PrincipalID      string  `dynamodbav:"principal_id"`
+	ToolID           string  `dynamodbav:"tool_id"`
+	Model            string  `dynamodbav:"model"`
+	Calls            int     `dynamodbav:"calls"`
+	PromptTokens     int     `dynamodbav:"prompt_tokens"`
+	CompletionTokens int     `dynamodbav:"completion_tokens"`
+	TotalTokens      int     `dynamodbav:"total_tokens"`
+	CostUSD          float64 `dynamodbav:"cost_usd"`
+}
+
+// DynamoDBStore keeps daily usage rollups in a DynamoDB table. Rollups are
+// incremented with atomic ADD updates, so replicas share one total.
+type DynamoDBStore struct {
+	client DynamoDBAPI
+	table  string
+}
+
+// NewDynamoDBStore creates a new DynamoDB usage store.
+func NewDynamoDBStore(client DynamoDBAPI, table string) *DynamoDBStore {
+	return &DynamoDBStore{
+		client: client,
+		table:  table,
+	}
+}
+
+func (s *DynamoDBStore) Add(ctx context.Context, records ...*Record) error {
+	for _, r := range rollup(records) {
+		day := r.RecordedAt.Format(dayFormat)
+		_, err := s.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+			TableName: aws.String(s.table),
+			Key: map[string]ddbtypes.AttributeValue{
+				"tenant_id":  &ddbtypes.AttributeValueMemberS{Value: r.TenantID},
+				"rollup_key": &ddbtypes.AttributeValueMemberS{Value: day + "#" + r.PrincipalID + "#" + r.ToolID + "#" + r.Model},
+			},
+			UpdateExpression: aws.String("ADD #calls :calls, #prompt :prompt, #completion :completion, #total :total, #cost :cost " +
+				"SET #day = :day, #principal = :principal, #tool = :tool, #model = :model"),
+			ExpressionAttributeNames: map[string]string{
+				"#calls":      "calls",
+				"#prompt":     "prompt_tokens",
+				"#completion": "completion_tokens",
+				"#total":      "total_tokens",
+				"#cost":       "cost_usd",
+				"#day":        "usage_day",
+				"#principal":  "principal_id",
+				"#tool":       "tool_id",
+				"#model":      "model",
+			},
+			ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+				":calls":      number(r.Calls),
+				":prompt":     number(r.PromptTokens),
+				":completion": number(r.CompletionTokens),
+				":total":      number(r.TotalTokens),
+				":cost":       &ddbtypes.AttributeValueMemberN{Value: fmt.Sprint(r.CostUSD)},
+				":day":        &ddbtypes.AttributeValueMemberS{Value: day},
+				":principal":  &ddbtypes.AttributeValueMemberS{Value: r.PrincipalID},
+				":tool":       &ddbtypes.AttributeValueMemberS{Value: r.ToolID},
+				":model":      &ddbtypes.AttributeValueMemberS{Value: r.Model},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add usage: %w", err)
+		}
+	}
+	return nil
+}
+
+// List queries the tenant's rollups by day, or scans the table for a report
+// across tenants.
+func (s *DynamoDBStore) List(ctx context.Context, filter *Filter) ([]*Record, error) {
+	items, err := s.rollups(ctx, filter, "")
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(items))
+	for _, item := range items {
+		r := &Record{
+			TenantID:         item.TenantID,
+			PrincipalID:      item.PrincipalID,
+			ToolID:           item.ToolID,
+			Model:            item.Model,
+			Calls:            item.Calls,
+			PromptTokens:     item.PromptTokens,
+			CompletionTokens: item.CompletionTokens,
+			TotalTokens:      item.TotalTokens,
+			CostUSD:          item.CostUSD,
+		}
+		if r.RecordedAt, err = time.Parse(dayFormat, item.Day); err != nil {
+			return nil, fmt.Errorf("invalid usage day %q: %w", item.Day, err)
+		}
+		if filter.matches(r) {
+			records = append(records, r)
+		}
+	}
+	sortRecords(records)
+	return records, nil
+}
+
+// TenantTokens sums the total tokens of the tenant's rollups with a strongly
+// consistent query, so a quota check sees every replica's committed usage.
+func (s *DynamoDBStore) TenantTokens(ctx context.Context, tenantID string, since time.Time) (int64, error) {
+	items, err := s.rollups(ctx, &Filter{TenantID: tenantID, Since: since}, "total_tokens")
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, item := range items {
+		total += int64(item.TotalTokens)
+	}
+	return total, nil
+}
+
+// rollups reads every page of the rollups in the filter's days, with only the
+// projected attributes when projection is set. Other fields are not filtered.
+func (s *DynamoDBStore) rollups(ctx context.Context, filter *Filter, projection string) ([]rollupItem, error) {
+	since, until := dayRange(filter)
+	if since == "" {
+		since = "0" // Key values cannot be empty; sorts before every day
+	}
+	if until == "" {
+		until = "~" // Sorts after every day
+	}
+
+	var startKey map[string]ddbtypes.AttributeValue
+	items := make([]rollupItem, 0)
+	for {
+		var page []map[string]ddbtypes.AttributeValue
+		if filter != nil && filter.TenantID != "" {
+			// Keys on the day after the range are until#..., which sort after until
+			input := &dynamodb.QueryInput{
+				TableName:              aws.String(s.table),
+				KeyConditionExpression: aws.String("tenant_id = :tenant AND rollup_key BETWEEN :since AND :until"),
+				ExpressionAttributeValues: map[string]ddbtypes.AttributeValue{
+					":tenant": &ddbtypes.AttributeValueMemberS{Value: filter.TenantID},
+					":since":  &ddbtypes.AttributeValueMemberS{Value: since},
+					":until":  &ddbtypes.AttributeValueMemberS{Value: until},
+				},
+				ConsistentRead:    aws.Bool(true),
+				ExclusiveStartKey: startKey,
+			}
+			if projection != "" {
+				input.ProjectionExpression = aws.String(projection)
+			}
+			result, err := s.client.Query(ctx, input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query usage: %w", err)
+			}
+			page, startKey = result.Items, result.LastEvaluatedKey
+		} else {
+			input := &dynamodb.ScanInput{
+				TableName:         aws.String(s.table),
+				ExclusiveStartKey: startKey,
+			}
+			if projection != "" {
+				input.ProjectionExpression = aws.String(projection)
+			}
+			result, err := s.client.Scan(ctx, input)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan usage: %w", err)
+			}
+			page, startKey = result.Items, result.LastEvaluatedKey
+		}
+
+		var decoded []rollupItem
+		if err := attributevalue.UnmarshalListOfMaps(page, &decoded); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal usage: %w", err)
+		}
+		items = append(items, decoded...)
+		if len(startKey) == 0 {
+			return items, nil
+		}
+	}
+}
+
+func number(n int) ddbtypes.AttributeValue {
+	return &ddbtypes.AttributeValueMemberN{Value: fmt.Sprint(n)}
+}
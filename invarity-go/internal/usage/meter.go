@@ -0,0 +1,150 @@
+package usage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// MeterConfig holds dependencies for a Meter.
+type MeterConfig struct {
+	Store       Store
+	Pricing     Pricing
+	Quotas      Quotas       // Optional: no quotas are enforced when empty
+	Plans       PlanResolver // Optional: every tenant is on DefaultPlan without it
+	DefaultPlan string       // Plan for tenants the resolver does not know
+}
+
+// Meter prices, records and enforces quotas on LLM token usage.
+type Meter struct {
+	store       Store
+	pricing     Pricing
+	quotas      Quotas
+	plans       PlanResolver
+	defaultPlan string
+	now         func() time.Time
+}
+
+// NewMeter creates a new usage meter.
+func NewMeter(cfg MeterConfig) *Meter {
+	pricing := cfg.Pricing
+	if pricing == nil {
+		pricing = make(Pricing)
+	}
+	return &Meter{
+		store:       cfg.Store,
+		pricing:     pricing,
+		quotas:      cfg.Quotas,
+		plans:       cfg.Plans,
+		defaultPlan: cfg.DefaultPlan,
+		now:         time.Now,
+	}
+}
+
+// Price sets the cost of each usage from the pricing table.
+func (m *Meter) Price(usages ...*types.TokenUsage) {
+	for _, u := range usages {
+		if u != nil {
+			u.CostUSD = m.pricing.Cost(u)
+		}
+	}
+}
+
+// Record stores one record per model call, attributed to the tenant, principal and tool.
+// Usages must already be priced.
+func (m *Meter) Record(ctx context.Context, tenantID, principalID, toolID string, usages ...*types.TokenUsage) error {
+	records := make([]*Record, 0, len(usages))
+	now := m.now().UTC()
+	for _, u := range usages {
+		if u == nil {
+			continue
+		}
+		records = append(records, &Record{
+			TenantID:         tenantID,
+			PrincipalID:      principalID,
+			ToolID:           toolID,
+			Model:            u.Model,
+			PromptTokens:     u.PromptTokens,
+			CompletionTokens: u.CompletionTokens,
+			TotalTokens:      u.TotalTokens,
+			CostUSD:          u.CostUSD,
+			RecordedAt:       now,
+		})
+	}
+	if len(records) == 0 {
+		return nil
+	}
+	return m.store.Add(ctx, records...)
+}
+
+// CheckQuota returns a *QuotaExceededError if the tenant has used its plan's
+// monthly token quota. Tenants whose plan has no quota are never limited.
+func (m *Meter) CheckQuota(ctx context.Context, tenantID string) error {
+	if len(m.quotas) == 0 {
+		return nil
+	}
+
+	plan, err := m.tenantPlan(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	limit, ok := m.quotas[plan]
+	if !ok {
+		return nil
+	}
+
+	used, err := m.store.TenantTokens(ctx, tenantID, MonthStart(m.now()))
+	if err != nil {
+		return fmt.Errorf("failed to read token usage: %w", err)
+	}
+	if used >= limit {
+		return &QuotaExceededError{TenantID: tenantID, Plan: plan, Limit: limit, Used: used}
+	}
+	return nil
+}
+
+// Report aggregates a period's usage. An empty tenantID reports across tenants.
+func (m *Meter) Report(ctx context.Context, tenantID, period, groupBy string) (*Report, error) {
+	if groupBy == "" {
+		groupBy = GroupByPrincipal
+	}
+	if !ValidGroupBy(groupBy) {
+		return nil, fmt.Errorf("invalid group_by %q: expected principal, tool, model or tenant", groupBy)
+	}
+	start, end, err := ParsePeriod(period, m.now())
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := m.store.List(ctx, &Filter{TenantID: tenantID, Since: start, Until: end})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage records: %w", err)
+	}
+
+	report := &Report{
+		TenantID: tenantID,
+		Period:   start.Format("2006-01"),
+		GroupBy:  groupBy,
+		Groups:   Summarize(records, groupBy),
+	}
+	for _, g := range report.Groups {
+		report.Total.Add(&g.Usage)
+	}
+	return report, nil
+}
+
+func (m *Meter) tenantPlan(ctx context.Context, tenantID string) (string, error) {
+	if m.plans == nil {
+		return m.defaultPlan, nil
+	}
+	plan, err := m.plans.TenantPlan(ctx, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up tenant plan: %w", err)
+	}
+	if plan == "" {
+		return m.defaultPlan, nil
+	}
+	return plan, nil
+}
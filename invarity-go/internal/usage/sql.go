@@ -0,0 +1,171 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sqliteSchema creates the rollup table in a SQLite database. Postgres gets
+// the same table from its migrations.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS usage_rollups (
+	tenant_id         TEXT NOT NULL,
+	day               TEXT NOT NULL,
+	principal_id      TEXT NOT NULL,
+	tool_id           TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	calls             INTEGER NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL,
+	total_tokens      INTEGER NOT NULL,
+	cost_usd          REAL NOT NULL,
+	PRIMARY KEY (tenant_id, day, principal_id, tool_id, model)
+);
+`
+
+// SQLStore keeps daily usage rollups in the usage_rollups table of a SQL
+// database. Each record is added with an upsert that increments its rollup,
+// so replicas sharing the database share one total.
+type SQLStore struct {
+	db           *sql.DB
+	dollarParams bool // Driver expects $1, $2, ... (Postgres)
+}
+
+// NewSQLiteStore creates a usage store on an open SQLite database, such as the
+// control plane's, creating the rollup table if needed.
+func NewSQLiteStore(ctx context.Context, db *sql.DB) (*SQLStore, error) {
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		return nil, fmt.Errorf("failed to apply SQLite usage schema: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// NewPostgresStore creates a usage store on a Postgres database opened (and
+// migrated) with postgres.Open.
+func NewPostgresStore(db *sql.DB) *SQLStore {
+	return &SQLStore{db: db, dollarParams: true}
+}
+
+// bind numbers a query's ? placeholders for drivers that need it.
+func (s *SQLStore) bind(query string) string {
+	if !s.dollarParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *SQLStore) Add(ctx context.Context, records ...*Record) error {
+	rollups := rollup(records)
+	if len(rollups) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := s.bind(`INSERT INTO usage_rollups
+		(tenant_id, day, principal_id, tool_id, model, calls, prompt_tokens, completion_tokens, total_tokens, cost_usd)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tenant_id, day, principal_id, tool_id, model) DO UPDATE SET
+			calls = usage_rollups.calls + excluded.calls,
+			prompt_tokens = usage_rollups.prompt_tokens + excluded.prompt_tokens,
+			completion_tokens = usage_rollups.completion_tokens + excluded.completion_tokens,
+			total_tokens = usage_rollups.total_tokens + excluded.total_tokens,
+			cost_usd = usage_rollups.cost_usd + excluded.cost_usd`)
+	for _, r := range rollups {
+		if _, err := tx.ExecContext(ctx, query,
+			r.TenantID, r.RecordedAt.Format(dayFormat), r.PrincipalID, r.ToolID, r.Model,
+			r.Calls, r.PromptTokens, r.CompletionTokens, r.TotalTokens, r.CostUSD); err != nil {
+			return fmt.Errorf("failed to add usage: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to add usage: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) List(ctx context.Context, filter *Filter) ([]*Record, error) {
+	conditions := make([]string, 0)
+	args := make([]any, 0)
+	if filter != nil {
+		for _, c := range []struct{ column, value string }{
+			{"tenant_id", filter.TenantID},
+			{"principal_id", filter.PrincipalID},
+			{"tool_id", filter.ToolID},
+		} {
+			if c.value != "" {
+				conditions = append(conditions, c.column+" = ?")
+				args = append(args, c.value)
+			}
+		}
+	}
+	since, until := dayRange(filter)
+	if since != "" {
+		conditions = append(conditions, "day >= ?")
+		args = append(args, since)
+	}
+	if until != "" {
+		conditions = append(conditions, "day < ?")
+		args = append(args, until)
+	}
+
+	query := `SELECT tenant_id, day, principal_id, tool_id, model, calls, prompt_tokens, completion_tokens, total_tokens, cost_usd
+		FROM usage_rollups`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY day, tenant_id, principal_id, tool_id, model"
+
+	rows, err := s.db.QueryContext(ctx, s.bind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer rows.Close()
+
+	records := make([]*Record, 0)
+	for rows.Next() {
+		var r Record
+		var day string
+		if err := rows.Scan(&r.TenantID, &day, &r.PrincipalID, &r.ToolID, &r.Model,
+			&r.Calls, &r.PromptTokens, &r.CompletionTokens, &r.TotalTokens, &r.CostUSD); err != nil {
+			return nil, fmt.Errorf("failed to list usage: %w", err)
+		}
+		if r.RecordedAt, err = time.Parse(dayFormat, day); err != nil {
+			return nil, fmt.Errorf("invalid usage day %q: %w", day, err)
+		}
+		records = append(records, &r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	return records, nil
+}
+
+func (s *SQLStore) TenantTokens(ctx context.Context, tenantID string, since time.Time) (int64, error) {
+	var total int64
+	err := s.db.QueryRowContext(ctx,
+		s.bind(`SELECT COALESCE(SUM(total_tokens), 0) FROM usage_rollups WHERE tenant_id = ? AND day >= ?`),
+		tenantID, DayStart(since).Format(dayFormat)).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum usage: %w", err)
+	}
+	return total, nil
+}
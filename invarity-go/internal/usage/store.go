@@ -0,0 +1,209 @@
+package usage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Filter selects usage records. Empty fields match everything.
+type Filter struct {
+	TenantID    string
+	PrincipalID string
+	ToolID      string
+	Since       time.Time // Inclusive
+	Until       time.Time // Exclusive
+}
+
+// Store persists usage. Stores keep daily rollups rather than single calls:
+// records with the same tenant, principal, tool, model and UTC day are summed
+// into one, so List returns rollups and time bounds match whole days.
+type Store interface {
+	// Add adds usage records to their daily rollups.
+	Add(ctx context.Context, records ...*Record) error
+	// List returns the rollups matching the filter, by day and then key.
+	List(ctx context.Context, filter *Filter) ([]*Record, error)
+	// TenantTokens returns the total tokens a tenant has used since the start
+	// of the UTC day containing the given time.
+	TenantTokens(ctx context.Context, tenantID string, since time.Time) (int64, error)
+}
+
+// dayFormat is the UTC day of a rollup; it sorts as text.
+const dayFormat = "2006-01-02"
+
+// DayStart returns the start of the UTC day containing t.
+func DayStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// dayRange returns the first day a filter matches and the day after the last,
+// as dayFormat text. A rollup matches when its day overlaps [Since, Until).
+// Unbounded ends are "".
+func dayRange(filter *Filter) (since, until string) {
+	if filter == nil {
+		return "", ""
+	}
+	if !filter.Since.IsZero() {
+		since = DayStart(filter.Since).Format(dayFormat)
+	}
+	if !filter.Until.IsZero() {
+		end := DayStart(filter.Until)
+		if end.Before(filter.Until) {
+			end = end.AddDate(0, 0, 1)
+		}
+		until = end.Format(dayFormat)
+	}
+	return since, until
+}
+
+// rollupKey identifies the rollup a record is added to.
+type rollupKey struct {
+	tenantID    string
+	day         string
+	principalID string
+	toolID      string
+	model       string
+}
+
+// less orders keys by day, then tenant, principal, tool and model.
+func (k rollupKey) less(o rollupKey) bool {
+	for _, pair := range [][2]string{
+		{k.day, o.day}, {k.tenantID, o.tenantID}, {k.principalID, o.principalID}, {k.toolID, o.toolID}, {k.model, o.model},
+	} {
+		if pair[0] != pair[1] {
+			return pair[0] < pair[1]
+		}
+	}
+	return false
+}
+
+func keyOf(r *Record) rollupKey {
+	return rollupKey{r.TenantID, r.RecordedAt.UTC().Format(dayFormat), r.PrincipalID, r.ToolID, r.Model}
+}
+
+// sortRecords orders rollups by day, then tenant, principal, tool and model.
+func sortRecords(records []*Record) {
+	sort.Slice(records, func(i, j int) bool { return keyOf(records[i]).less(keyOf(records[j])) })
+}
+
+// rollup sums records into one record per rollup key, in first-seen order.
+// A record without a time is recorded now.
+func rollup(records []*Record) []*Record {
+	index := make(map[rollupKey]*Record, len(records))
+	result := make([]*Record, 0, len(records))
+	for _, r := range records {
+		if r.RecordedAt.IsZero() {
+			r.RecordedAt = time.Now().UTC()
+		}
+		key := keyOf(r)
+		sum, ok := index[key]
+		if !ok {
+			sum = &Record{
+				TenantID:    r.TenantID,
+				PrincipalID: r.PrincipalID,
+				ToolID:      r.ToolID,
+				Model:       r.Model,
+				RecordedAt:  DayStart(r.RecordedAt),
+			}
+			index[key] = sum
+			result = append(result, sum)
+		}
+		sum.add(r)
+	}
+	return result
+}
+
+// matches reports whether a rollup matches the filter.
+func (f *Filter) matches(r *Record) bool {
+	if f == nil {
+		return true
+	}
+	if f.TenantID != "" && r.TenantID != f.TenantID {
+		return false
+	}
+	if f.PrincipalID != "" && r.PrincipalID != f.PrincipalID {
+		return false
+	}
+	if f.ToolID != "" && r.ToolID != f.ToolID {
+		return false
+	}
+	day := r.RecordedAt.UTC().Format(dayFormat)
+	since, until := dayRange(f)
+	return day >= since && (until == "" || day < until)
+}
+
+// memoryWindowMonths is how many UTC months of rollups InMemoryStore keeps,
+// counting the current one.
+const memoryWindowMonths = 2
+
+// InMemoryStore is an in-memory implementation of Store for a single replica.
+// It keeps rollups for the current and previous UTC month only; older days are
+// dropped as usage is added, so reports on earlier periods come back empty.
+type InMemoryStore struct {
+	mu      sync.RWMutex
+	rollups map[rollupKey]*Record
+	now     func() time.Time
+}
+
+// NewInMemoryStore creates a new in-memory usage store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{
+		rollups: make(map[rollupKey]*Record),
+		now:     time.Now,
+	}
+}
+
+func (s *InMemoryStore) Add(ctx context.Context, records ...*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	oldest := MonthStart(s.now()).AddDate(0, 1-memoryWindowMonths, 0).Format(dayFormat)
+	for _, r := range rollup(records) {
+		key := keyOf(r)
+		if key.day < oldest {
+			continue
+		}
+		if sum, ok := s.rollups[key]; ok {
+			sum.add(r)
+		} else {
+			s.rollups[key] = r
+		}
+	}
+	for key := range s.rollups {
+		if key.day < oldest {
+			delete(s.rollups, key)
+		}
+	}
+	return nil
+}
+
+func (s *InMemoryStore) List(ctx context.Context, filter *Filter) ([]*Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*Record, 0)
+	for _, r := range s.rollups {
+		if filter.matches(r) {
+			copied := *r
+			result = append(result, &copied)
+		}
+	}
+	sortRecords(result)
+	return result, nil
+}
+
+func (s *InMemoryStore) TenantTokens(ctx context.Context, tenantID string, since time.Time) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	from := DayStart(since).Format(dayFormat)
+	var total int64
+	for key, r := range s.rollups {
+		if key.tenantID == tenantID && key.day >= from {
+			total += int64(r.TotalTokens)
+		}
+	}
+	return total, nil
+}
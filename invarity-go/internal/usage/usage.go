@@ -0,0 +1,226 @@
+// Package usage accounts for LLM token usage and cost per tenant, principal and
+// tool, and enforces optional monthly token quotas by tenant plan.
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"invarity/internal/types"
+)
+
+// Report groupings.
+const (
+	GroupByPrincipal = "principal"
+	GroupByTool      = "tool"
+	GroupByModel     = "model"
+	GroupByTenant    = "tenant"
+)
+
+// ModelPrice is the price of a model in USD per million tokens.
+type ModelPrice struct {
+	PromptPerMTok     float64 `json:"prompt_per_mtok"`
+	CompletionPerMTok float64 `json:"completion_per_mtok"`
+}
+
+// Pricing maps model names to prices. Models without a price cost nothing.
+type Pricing map[string]ModelPrice
+
+// ParsePricing parses a JSON object of model name to ModelPrice.
+func ParsePricing(s string) (Pricing, error) {
+	pricing := make(Pricing)
+	if strings.TrimSpace(s) == "" {
+		return pricing, nil
+	}
+	if err := json.Unmarshal([]byte(s), &pricing); err != nil {
+		return nil, fmt.Errorf("invalid model pricing: %w", err)
+	}
+	return pricing, nil
+}
+
+// Cost returns the cost of u in USD.
+func (p Pricing) Cost(u *types.TokenUsage) float64 {
+	if u == nil {
+		return 0
+	}
+	price, ok := p[u.Model]
+	if !ok {
+		return 0
+	}
+	return (float64(u.PromptTokens)*price.PromptPerMTok + float64(u.CompletionTokens)*price.CompletionPerMTok) / 1e6
+}
+
+// Quotas maps tenant plans to monthly token limits. Plans without a limit are unlimited.
+type Quotas map[string]int64
+
+// ParseQuotas parses a comma-separated list of plan=tokens pairs, e.g. "free=1000000,pro=50000000".
+func ParseQuotas(s string) (Quotas, error) {
+	quotas := make(Quotas)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		plan, limit, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid token quota %q: expected plan=tokens", part)
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(limit), 10, 64)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid token quota %q: tokens must be a non-negative integer", part)
+		}
+		quotas[strings.TrimSpace(plan)] = n
+	}
+	return quotas, nil
+}
+
+// PlanResolver looks up a tenant's plan. It is implemented by the control plane stores.
+type PlanResolver interface {
+	TenantPlan(ctx context.Context, tenantID string) (string, error)
+}
+
+// QuotaExceededError is returned when a tenant has used its monthly token quota.
+type QuotaExceededError struct {
+	TenantID string
+	Plan     string
+	Limit    int64
+	Used     int64
+	AuditID  string // Audit record of the denied request, set by the pipeline
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("tenant %s exceeded monthly token quota for plan %s (%d/%d tokens)", e.TenantID, e.Plan, e.Used, e.Limit)
+}
+
+// Record is token usage attributed to the tenant, principal and tool: one model
+// call when added, or a daily rollup of calls when listed from a Store.
+type Record struct {
+	TenantID         string    `json:"tenant_id"`
+	PrincipalID      string    `json:"principal_id,omitempty"`
+	ToolID           string    `json:"tool_id"`
+	Model            string    `json:"model"`
+	Calls            int       `json:"calls,omitempty"` // Model calls covered; 0 is one call
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	RecordedAt       time.Time `json:"recorded_at"`
+}
+
+// Group is the usage total for one key of a report.
+type Group struct {
+	Key   string           `json:"key"`
+	Calls int              `json:"calls"`
+	Usage types.TokenUsage `json:"usage"`
+}
+
+// Report aggregates usage over a period.
+type Report struct {
+	TenantID string           `json:"tenant_id,omitempty"`
+	Period   string           `json:"period"` // YYYY-MM
+	GroupBy  string           `json:"group_by"`
+	Total    types.TokenUsage `json:"total"`
+	Groups   []Group          `json:"groups"`
+}
+
+// Summarize aggregates records by the given grouping, ordered by descending cost then key.
+func Summarize(records []*Record, groupBy string) []Group {
+	index := make(map[string]*Group)
+	for _, r := range records {
+		key := groupKey(r, groupBy)
+		g, ok := index[key]
+		if !ok {
+			g = &Group{Key: key}
+			if groupBy == GroupByModel {
+				g.Usage.Model = key
+			}
+			index[key] = g
+		}
+		g.Calls += r.calls()
+		g.Usage.Add(r.usage())
+	}
+
+	groups := make([]Group, 0, len(index))
+	for _, g := range index {
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Usage.CostUSD != groups[j].Usage.CostUSD {
+			return groups[i].Usage.CostUSD > groups[j].Usage.CostUSD
+		}
+		return groups[i].Key < groups[j].Key
+	})
+	return groups
+}
+
+// ValidGroupBy reports whether groupBy is a supported report grouping.
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByPrincipal, GroupByTool, GroupByModel, GroupByTenant:
+		return true
+	}
+	return false
+}
+
+func groupKey(r *Record, groupBy string) string {
+	switch groupBy {
+	case GroupByTool:
+		return r.ToolID
+	case GroupByModel:
+		return r.Model
+	case GroupByTenant:
+		return r.TenantID
+	}
+	return r.PrincipalID
+}
+
+func (r *Record) calls() int {
+	if r.Calls == 0 {
+		return 1
+	}
+	return r.Calls
+}
+
+// add sums another record's calls, tokens and cost into a rollup, which
+// starts from zero calls.
+func (r *Record) add(o *Record) {
+	r.Calls += o.calls()
+	r.PromptTokens += o.PromptTokens
+	r.CompletionTokens += o.CompletionTokens
+	r.TotalTokens += o.TotalTokens
+	r.CostUSD += o.CostUSD
+}
+
+func (r *Record) usage() *types.TokenUsage {
+	return &types.TokenUsage{
+		Model:            r.Model,
+		PromptTokens:     r.PromptTokens,
+		CompletionTokens: r.CompletionTokens,
+		TotalTokens:      r.TotalTokens,
+		CostUSD:          r.CostUSD,
+	}
+}
+
+// MonthStart returns the start of the UTC calendar month containing t.
+func MonthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// ParsePeriod parses a YYYY-MM period into its start and end. An empty period is the current month.
+func ParsePeriod(period string, now time.Time) (start, end time.Time, err error) {
+	if period == "" {
+		start = MonthStart(now)
+	} else {
+		start, err = time.Parse("2006-01", period)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid period %q: expected YYYY-MM", period)
+		}
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
@@ -0,0 +1,300 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"invarity/internal/audit"
+	"invarity/internal/types"
+)
+
+// flakyAuditStore wraps an in-memory store that can be made unavailable or
+// held, and counts the writes that reach it.
+type flakyAuditStore struct {
+	*audit.InMemoryStore
+	failing atomic.Bool
+	writes  atomic.Int64
+
+	mu      sync.Mutex
+	hold    chan struct{} // Writes wait on this when set
+	started chan struct{} // Signalled as each held write starts
+}
+
+func newFlakyAuditStore() *flakyAuditStore {
+	return &flakyAuditStore{InMemoryStore: audit.NewInMemoryStore()}
+}
+
+func (s *flakyAuditStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	s.mu.Lock()
+	hold, started := s.hold, s.started
+	s.mu.Unlock()
+	if hold != nil {
+		started <- struct{}{}
+		<-hold
+	}
+	if s.failing.Load() {
+		return "", errors.New("store unavailable")
+	}
+	s.writes.Add(1)
+	return s.InMemoryStore.Write(ctx, record)
+}
+
+// holdWrites makes writes block until the returned release func is called.
+func (s *flakyAuditStore) holdWrites() (started <-chan struct{}, release func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hold = make(chan struct{})
+	s.started = make(chan struct{}, 16)
+	hold := s.hold
+	return s.started, func() {
+		s.mu.Lock()
+		s.hold = nil
+		s.mu.Unlock()
+		close(hold)
+	}
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAsyncAuditStore_BatchesAndDrainsOnClose(t *testing.T) {
+	backend := newFlakyAuditStore()
+	store := audit.NewAsyncStore(backend, audit.AsyncConfig{BatchSize: 3, FlushInterval: time.Hour})
+
+	ids := make([]string, 0, 5)
+	for i := 0; i < 5; i++ {
+		id, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme", Decision: types.DecisionAllow})
+		if err != nil || id == "" {
+			t.Fatalf("write %d: id %q err %v", i, id, err)
+		}
+		ids = append(ids, id)
+	}
+
+	// The first batch of 3 flushes on size; the last 2 wait for the interval
+	waitFor(t, "size-triggered flush", func() bool { return backend.writes.Load() == 3 })
+	if stats := store.Stats(); stats.Written != 3 {
+		t.Errorf("written = %d, want 3", stats.Written)
+	}
+
+	// Queued records are readable before they reach the store
+	if record, err := store.Get(context.Background(), ids[4]); err != nil || record.AuditID != ids[4] {
+		t.Errorf("queued record not readable: %v", err)
+	}
+
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if backend.writes.Load() != 5 {
+		t.Errorf("expected all 5 records written after close, got %d", backend.writes.Load())
+	}
+	if _, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"}); !errors.Is(err, audit.ErrStoreClosed) {
+		t.Errorf("expected ErrStoreClosed after close, got %v", err)
+	}
+}
+
+func TestAsyncAuditStore_FlushesOnInterval(t *testing.T) {
+	backend := newFlakyAuditStore()
+	store := audit.NewAsyncStore(backend, audit.AsyncConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer store.Close(context.Background())
+
+	id, _ := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	waitFor(t, "interval flush", func() bool { return backend.writes.Load() == 1 })
+	if _, err := backend.InMemoryStore.Get(context.Background(), id); err != nil {
+		t.Errorf("record not in backend: %v", err)
+	}
+}
+
+func TestAsyncAuditStore_SpoolsWhileStoreUnavailable(t *testing.T) {
+	dir := t.TempDir()
+	backend := newFlakyAuditStore()
+	backend.failing.Store(true)
+
+	spool, err := audit.OpenSpool(dir)
+	if err != nil {
+		t.Fatalf("open spool: %v", err)
+	}
+	store := audit.NewAsyncStore(backend, audit.AsyncConfig{BatchSize: 2, FlushInterval: 10 * time.Millisecond, Spool: spool})
+	for i := 0; i < 4; i++ {
+		if _, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"}); err != nil {
+			t.Fatalf("write %d: %v", i, err)
+		}
+	}
+	waitFor(t, "records spooled", func() bool { return store.Stats().Spooled == 4 })
+
+	// Simulate a restart while the store is still down: the spool survives
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	spool.Close()
+	if backend.writes.Load() != 0 {
+		t.Fatalf("store should not have accepted writes")
+	}
+
+	spool, err = audit.OpenSpool(dir)
+	if err != nil {
+		t.Fatalf("reopen spool: %v", err)
+	}
+	defer spool.Close()
+	if spool.Len() != 4 {
+		t.Fatalf("expected 4 spooled records after restart, got %d", spool.Len())
+	}
+
+	backend.failing.Store(false)
+	store = audit.NewAsyncStore(backend, audit.AsyncConfig{FlushInterval: 10 * time.Millisecond, Spool: spool})
+	defer store.Close(context.Background())
+	waitFor(t, "spool drained", func() bool { return backend.writes.Load() == 4 })
+	if spool.Len() != 0 {
+		t.Errorf("spool not emptied: %d", spool.Len())
+	}
+	records, _ := backend.List(context.Background(), &audit.ListFilter{OrgID: "acme"})
+	if len(records) != 4 {
+		t.Errorf("expected 4 records in store, got %d", len(records))
+	}
+}
+
+func TestAsyncAuditStore_OverflowPolicies(t *testing.T) {
+	tests := []struct {
+		policy  audit.OverflowPolicy
+		wantErr error
+	}{
+		{audit.OverflowDrop, nil},
+		{audit.OverflowFailClosed, audit.ErrBufferFull},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			backend := newFlakyAuditStore()
+			started, release := backend.holdWrites()
+			store := audit.NewAsyncStore(backend, audit.AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour, Overflow: tt.policy})
+
+			// One record is held in the store, one fills the queue, the third overflows
+			store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+			<-started
+			if _, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"}); err != nil {
+				t.Fatalf("queued write failed: %v", err)
+			}
+			id, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+			if !errors.Is(err, tt.wantErr) || id != "" {
+				t.Errorf("overflow write: id %q err %v, want err %v", id, err, tt.wantErr)
+			}
+
+			stats := store.Stats()
+			if tt.policy == audit.OverflowDrop && stats.Dropped != 1 {
+				t.Errorf("dropped = %d, want 1", stats.Dropped)
+			}
+			if tt.policy == audit.OverflowFailClosed && stats.Rejected != 1 {
+				t.Errorf("rejected = %d, want 1", stats.Rejected)
+			}
+
+			release()
+			if err := store.Close(context.Background()); err != nil {
+				t.Fatalf("close failed: %v", err)
+			}
+			if backend.writes.Load() != 2 {
+				t.Errorf("expected 2 records written, got %d", backend.writes.Load())
+			}
+		})
+	}
+
+	if _, err := audit.ParseOverflowPolicy("retry"); err == nil {
+		t.Errorf("expected error for unknown policy")
+	}
+}
+
+func TestAsyncAuditStore_BlockWaitsForRoom(t *testing.T) {
+	backend := newFlakyAuditStore()
+	started, release := backend.holdWrites()
+	store := audit.NewAsyncStore(backend, audit.AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour})
+
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	<-started
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+
+	// A full queue blocks until the caller's context ends
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := store.Write(ctx, &types.AuditRecord{OrgID: "acme"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+
+	// And succeeds once the store catches up
+	done := make(chan error, 1)
+	go func() {
+		_, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+		done <- err
+	}()
+	release()
+	if err := <-done; err != nil {
+		t.Errorf("blocked write failed: %v", err)
+	}
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if backend.writes.Load() != 3 {
+		t.Errorf("expected 3 records written, got %d", backend.writes.Load())
+	}
+}
+
+func TestAsyncAuditStore_CloseWithStalledStore(t *testing.T) {
+	backend := newFlakyAuditStore()
+	started, release := backend.holdWrites()
+	store := audit.NewAsyncStore(backend, audit.AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour})
+
+	// One record is held in the store, one fills the queue, the third waits for room
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	<-started
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	blocked := make(chan error, 1)
+	go func() {
+		_, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+		blocked <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// Close gives up at its deadline instead of waiting behind the blocked write
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	closed := make(chan error, 1)
+	go func() { closed <- store.Close(ctx) }()
+	select {
+	case err := <-closed:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected an incomplete drain, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close did not return while the store was stalled")
+	}
+
+	select {
+	case err := <-blocked:
+		if !errors.Is(err, audit.ErrStoreClosed) {
+			t.Errorf("expected the blocked write to fail with ErrStoreClosed, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked write did not return after Close")
+	}
+	if _, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"}); !errors.Is(err, audit.ErrStoreClosed) {
+		t.Errorf("expected a write after Close to fail, got %v", err)
+	}
+
+	// Once the store recovers, the queued record is still drained
+	release()
+	if err := store.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if backend.writes.Load() != 2 {
+		t.Errorf("expected 2 records written, got %d", backend.writes.Load())
+	}
+}
@@ -0,0 +1,369 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/types"
+)
+
+// testSigningKey is a fixed base64 ed25519 seed.
+var testSigningKey = base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+
+func writeChain(t *testing.T, store audit.Store, tenantID string, n int) []*types.AuditRecord {
+	t.Helper()
+	records := make([]*types.AuditRecord, 0, n)
+	for i := 0; i < n; i++ {
+		record := &types.AuditRecord{
+			OrgID:      tenantID,
+			Actor:      types.Actor{ID: "agent-1"},
+			ToolCall:   types.ToolCall{ActionID: "send_email", Args: json.RawMessage(`{"to":"a@b.example"}`)},
+			UserIntent: "Send the report",
+			Decision:   types.DecisionAllow,
+		}
+		if _, err := store.Write(context.Background(), record); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// copyAudit copies src's records (oldest first) into a new store, letting
+// mutate alter or drop (return nil) each copy.
+func copyAudit(t *testing.T, src *audit.InMemoryStore, mutate func(*types.AuditRecord) *types.AuditRecord) *audit.InMemoryStore {
+	t.Helper()
+	all, _ := src.List(context.Background(), nil)
+	dst := audit.NewInMemoryStore()
+	for i := len(all) - 1; i >= 0; i-- {
+		c := *all[i]
+		if out := mutate(&c); out != nil {
+			dst.Write(context.Background(), out)
+		}
+	}
+	return dst
+}
+
+func issueTypes(report *audit.VerifyReport) map[string]int {
+	out := make(map[string]int)
+	for _, issue := range report.Issues {
+		out[issue.Type]++
+	}
+	return out
+}
+
+func TestAuditChain_LinksRecordsPerTenant(t *testing.T) {
+	backing := audit.NewInMemoryStore()
+	store := audit.NewChainedStore(backing, audit.ChainConfig{})
+
+	acme := writeChain(t, store, "acme", 4)
+	globex := writeChain(t, store, "globex", 2)
+
+	for i, record := range acme {
+		if record.Sequence != int64(i+1) {
+			t.Errorf("acme record %d: sequence %d", i, record.Sequence)
+		}
+		hash, _ := audit.RecordHash(record)
+		if record.Hash == "" || record.Hash != hash {
+			t.Errorf("acme record %d: hash %q, want %q", i, record.Hash, hash)
+		}
+		if i == 0 && record.PrevHash != "" {
+			t.Errorf("first record should have no prev_hash")
+		}
+		if i > 0 && record.PrevHash != acme[i-1].Hash {
+			t.Errorf("acme record %d not linked to its predecessor", i)
+		}
+	}
+	if globex[0].Sequence != 1 || globex[0].PrevHash != "" || globex[1].PrevHash != globex[0].Hash {
+		t.Errorf("globex chain should be independent: %+v", globex)
+	}
+
+	report, err := audit.NewVerifier(backing, nil, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.Records != 4 || report.FirstSequence != 1 || report.LastSequence != 4 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestAuditChain_DetectsTampering(t *testing.T) {
+	backing := audit.NewInMemoryStore()
+	records := writeChain(t, audit.NewChainedStore(backing, audit.ChainConfig{}), "acme", 5)
+
+	tests := []struct {
+		name   string
+		mutate func(*types.AuditRecord) *types.AuditRecord
+		want   map[string]int
+	}{
+		{
+			name: "altered record",
+			mutate: func(r *types.AuditRecord) *types.AuditRecord {
+				if r.Sequence == 3 {
+					r.Decision = types.DecisionDeny
+				}
+				return r
+			},
+			want: map[string]int{audit.IssueHashMismatch: 1},
+		},
+		{
+			name: "altered and rehashed record",
+			mutate: func(r *types.AuditRecord) *types.AuditRecord {
+				if r.Sequence == 3 {
+					r.Decision = types.DecisionDeny
+					r.Hash, _ = audit.RecordHash(r)
+				}
+				return r
+			},
+			want: map[string]int{audit.IssueChainBreak: 1},
+		},
+		{
+			name: "deleted record",
+			mutate: func(r *types.AuditRecord) *types.AuditRecord {
+				if r.Sequence == 2 {
+					return nil
+				}
+				return r
+			},
+			want: map[string]int{audit.IssueGap: 1, audit.IssueChainBreak: 0},
+		},
+		{
+			name: "replayed sequence",
+			mutate: func(r *types.AuditRecord) *types.AuditRecord {
+				if r.Sequence == 5 {
+					r.Sequence = 4
+				}
+				return r
+			},
+			want: map[string]int{audit.IssueDuplicate: 1, audit.IssueHashMismatch: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := copyAudit(t, backing, tt.mutate)
+			report, err := audit.NewVerifier(tampered, nil, nil).Verify(context.Background(), "acme")
+			if err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+			if report.Valid {
+				t.Fatalf("expected tampering to be detected")
+			}
+			got := issueTypes(report)
+			for issueType, n := range tt.want {
+				if got[issueType] != n {
+					t.Errorf("%s issues = %d, want %d (all: %+v)", issueType, got[issueType], n, report.Issues)
+				}
+			}
+		})
+	}
+
+	if records[4].Sequence != 5 {
+		t.Errorf("source records must not be modified by the tamper copies")
+	}
+}
+
+func TestAuditChain_SignedCheckpoints(t *testing.T) {
+	signer, err := audit.NewSigner(testSigningKey)
+	if err != nil {
+		t.Fatalf("new signer: %v", err)
+	}
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	store := audit.NewChainedStore(backing, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 3, Signer: signer})
+
+	writeChain(t, store, "acme", 7)
+	store.CheckpointAll(context.Background())
+
+	list, _ := checkpoints.List(context.Background(), "acme")
+	if len(list) != 3 {
+		t.Fatalf("expected 3 checkpoints, got %d", len(list))
+	}
+	windows := [][2]int64{{1, 3}, {4, 6}, {7, 7}}
+	for i, cp := range list {
+		if cp.FromSequence != windows[i][0] || cp.ToSequence != windows[i][1] {
+			t.Errorf("checkpoint %d covers %d-%d, want %v", i, cp.FromSequence, cp.ToSequence, windows[i])
+		}
+		if !audit.VerifyCheckpointSignature(cp, signer.PublicKey()) {
+			t.Errorf("checkpoint %d signature does not verify", i)
+		}
+	}
+
+	verifier := audit.NewVerifier(backing, checkpoints, signer.PublicKey())
+	report, err := verifier.Verify(context.Background(), "acme")
+	if err != nil || !report.Valid || report.Checkpoints != 3 {
+		t.Fatalf("expected valid chain with 3 checkpoints, got %+v (err %v)", report, err)
+	}
+
+	// Rewriting the whole tail consistently still breaks every signed root after the edit
+	rewritten := copyAudit(t, backing, func(r *types.AuditRecord) *types.AuditRecord { return r })
+	all, _ := rewritten.List(context.Background(), &audit.ListFilter{OrgID: "acme"})
+	prevHash := ""
+	for i := len(all) - 1; i >= 0; i-- {
+		r := all[i]
+		if r.Sequence == 2 {
+			r.Decision = types.DecisionDeny
+		}
+		r.PrevHash = prevHash
+		r.Hash, _ = audit.RecordHash(r)
+		prevHash = r.Hash
+	}
+	report, _ = audit.NewVerifier(rewritten, checkpoints, signer.PublicKey()).Verify(context.Background(), "acme")
+	if got := issueTypes(report); got[audit.IssueCheckpointMismatch] != 3 || len(report.Issues) != 3 {
+		t.Errorf("expected 3 checkpoint mismatches, got %+v", report.Issues)
+	}
+
+	// Truncating the tail is caught by the last checkpoint
+	truncated := copyAudit(t, backing, func(r *types.AuditRecord) *types.AuditRecord {
+		if r.Sequence == 7 {
+			return nil
+		}
+		return r
+	})
+	report, _ = audit.NewVerifier(truncated, checkpoints, signer.PublicKey()).Verify(context.Background(), "acme")
+	if got := issueTypes(report); got[audit.IssueGap] != 1 {
+		t.Errorf("expected truncated tail to be reported as a gap, got %+v", report.Issues)
+	}
+
+	// A forged checkpoint fails signature verification
+	forged := audit.NewInMemoryCheckpointStore()
+	for _, cp := range list {
+		c := *cp
+		if c.ToSequence == 3 {
+			c.MerkleRoot = hex.EncodeToString(make([]byte, 32))
+		}
+		forged.Put(context.Background(), &c)
+	}
+	report, _ = audit.NewVerifier(backing, forged, signer.PublicKey()).Verify(context.Background(), "acme")
+	if got := issueTypes(report); got[audit.IssueBadSignature] != 1 || got[audit.IssueCheckpointMismatch] != 1 {
+		t.Errorf("expected bad signature and mismatch, got %+v", report.Issues)
+	}
+}
+
+func TestAuditChain_ResumesAfterRestart(t *testing.T) {
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	cfg := audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 3}
+
+	first := writeChain(t, audit.NewChainedStore(backing, cfg), "acme", 5)
+
+	// A new process picks up the tip and the unchecked window from the stores
+	restarted := audit.NewChainedStore(backing, cfg)
+	next := writeChain(t, restarted, "acme", 1)[0]
+	if next.Sequence != 6 || next.PrevHash != first[4].Hash {
+		t.Fatalf("chain did not resume: sequence %d", next.Sequence)
+	}
+
+	list, _ := checkpoints.List(context.Background(), "acme")
+	if len(list) != 2 || list[1].FromSequence != 4 || list[1].ToSequence != 6 {
+		t.Errorf("expected the resumed window 4-6 to be checkpointed, got %+v", list)
+	}
+
+	report, _ := audit.NewVerifier(backing, checkpoints, nil).Verify(context.Background(), "acme")
+	if !report.Valid {
+		t.Errorf("expected valid chain after restart, got %+v", report.Issues)
+	}
+}
+
+func TestAuditChain_MerkleRoot(t *testing.T) {
+	// RFC 6962: the empty tree hashes to SHA-256 of the empty string
+	empty, _ := audit.MerkleRoot(nil)
+	if empty != "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" {
+		t.Errorf("unexpected empty root %s", empty)
+	}
+
+	leaf := sha256.Sum256([]byte("record"))
+	single, _ := audit.MerkleRoot([]string{hex.EncodeToString(leaf[:])})
+	want := sha256.Sum256(append([]byte{0x00}, leaf[:]...))
+	if single != hex.EncodeToString(want[:]) {
+		t.Errorf("unexpected single-leaf root %s", single)
+	}
+
+	if _, err := audit.MerkleRoot([]string{"not-hex"}); err == nil {
+		t.Errorf("expected error for invalid leaf")
+	}
+	if _, err := audit.NewSigner("c2hvcnQ="); err == nil {
+		t.Errorf("expected error for short signing key")
+	}
+}
+
+func TestAuditChain_VerifyHTTP(t *testing.T) {
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	signer, _ := audit.NewSigner(testSigningKey)
+	store := audit.NewChainedStore(backing, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 2, Signer: signer})
+	writeChain(t, store, "acme", 4)
+
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:        zap.NewNop(),
+		AdminAPIKey:   "operator-secret",
+		AuditVerifier: audit.NewVerifier(backing, checkpoints, signer.PublicKey()),
+	})
+	get := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer operator-secret")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/v1/admin/audit/verify?tenant_id=acme")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("verify: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report audit.VerifyReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if !report.Valid || report.Records != 4 || report.Checkpoints != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	rec = get("/v1/admin/audit/checkpoints?tenant_id=acme")
+	var checkpointsResp invarhttp.CheckpointsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &checkpointsResp); err != nil {
+		t.Fatalf("decode checkpoints: %v", err)
+	}
+	if len(checkpointsResp.Checkpoints) != 2 || checkpointsResp.PublicKey == "" {
+		t.Errorf("unexpected checkpoints response %+v", checkpointsResp)
+	}
+
+	if rec := get("/v1/admin/audit/verify"); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing tenant: expected 400, got %d", rec.Code)
+	}
+}
+
+func TestAuditChain_ReplicasExtendOneChain(t *testing.T) {
+	store, _, _ := newTestS3AuditStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	replicaA := audit.NewChainedStore(store, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 3})
+	replicaB := audit.NewChainedStore(store, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 3})
+
+	// Each replica caches its head, so every switch finds the other one has
+	// taken the next sequence
+	writeChain(t, replicaB, "acme", 1)
+	for i := 0; i < 4; i++ {
+		writeChain(t, replicaA, "acme", 2)
+		if records := writeChain(t, replicaB, "acme", 1); records[0].Sequence != int64(3*i+4) {
+			t.Fatalf("replica B wrote sequence %d, want %d after replica A's records", records[0].Sequence, 3*i+4)
+		}
+	}
+
+	report, err := audit.NewVerifier(store, checkpoints, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.Records != 13 || report.LastSequence != 13 || report.Checkpoints == 0 {
+		t.Errorf("expected one valid chain of 13 records, got %+v", report)
+	}
+}
@@ -0,0 +1,290 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/auth"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// newAuditAPI mounts the tenant audit routes without the control plane's auth.
+func newAuditAPI(store audit.Store) http.Handler {
+	h := invarhttp.NewAuditHandler(store, nil, zap.NewNop())
+	r := chi.NewRouter()
+	r.Route("/v1/tenants/{tenant_id}/audit", func(a chi.Router) {
+		a.Get("/", h.HandleListAudit)
+		a.Get("/tail", h.HandleTailAudit)
+		a.Get("/{audit_id}", h.HandleGetAudit)
+	})
+	return r
+}
+
+func auditIDs(records []*types.AuditRecord) string {
+	out := make([]string, len(records))
+	for i, r := range records {
+		out[i] = r.AuditID
+	}
+	return strings.Join(out, ",")
+}
+
+func TestAuditAPI_List(t *testing.T) {
+	store := audit.NewInMemoryStore()
+	seedS3Audit(t, store, time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	api := newAuditAPI(store)
+
+	list := func(query string) (*httptest.ResponseRecorder, invarhttp.AuditListResponse) {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/tenants/acme/audit?"+query, nil))
+		var resp invarhttp.AuditListResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode list: %v", err)
+			}
+		}
+		return rec, resp
+	}
+
+	// Cursor pagination
+	_, page := list("limit=3")
+	if got := auditIDs(page.Records); got != "audit-3,audit-2,audit-1" || page.NextCursor == "" {
+		t.Fatalf("first page: got %s (cursor %q)", got, page.NextCursor)
+	}
+	_, page = list("limit=3&cursor=" + page.NextCursor)
+	if got := auditIDs(page.Records); got != "audit-0" || page.NextCursor != "" {
+		t.Fatalf("last page: got %s (cursor %q)", got, page.NextCursor)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"decision=deny", "audit-3,audit-1"},
+		{"principal_id=p-billing", "audit-1,audit-0"},
+		{"risk_tier=critical&reason=threat", "audit-3,audit-1"},
+		{"reason=intent_alignment_escalate", "audit-2"},
+		{"q=quarterly", "audit-3,audit-0"},
+		{"since=2026-05-01T10:00:00Z&until=2026-05-01T11:00:00Z", "audit-2,audit-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec, resp := list(tt.query)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if got := auditIDs(resp.Records); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	for _, query := range []string{"decision=maybe", "risk_tier=extreme", "since=yesterday", "limit=0", "limit=501", "cursor=bogus"} {
+		if rec, _ := list(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestAuditAPI_GetIsTenantScoped(t *testing.T) {
+	store := audit.NewInMemoryStore()
+	seedS3Audit(t, store, time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC))
+	api := newAuditAPI(store)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	rec := get("/v1/tenants/acme/audit/audit-1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var record types.AuditRecord
+	if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil || record.AuditID != "audit-1" {
+		t.Errorf("unexpected record %+v (%v)", record, err)
+	}
+
+	if rec := get("/v1/tenants/globex/audit/audit-1"); rec.Code != http.StatusNotFound {
+		t.Errorf("other tenant: expected 404, got %d", rec.Code)
+	}
+	if rec := get("/v1/tenants/acme/audit/missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("missing record: expected 404, got %d", rec.Code)
+	}
+}
+
+// routedAuditAPI serves the tenant audit routes through NewRouter with Cognito
+// auth, a member of acme and the given request timeout.
+type routedAuditAPI struct {
+	url string
+	key *rsa.PrivateKey
+	iss string
+}
+
+func newRoutedAuditAPI(t *testing.T, auditStore audit.Store, timeout time.Duration) *routedAuditAPI {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(auth.JWKS{Keys: []auth.JWK{{
+			Kty: "RSA", Kid: "k1", Use: "sig", Alg: "RS256",
+			N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(jwks.Close)
+
+	controlPlane := store.NewInMemoryStore()
+	controlPlane.CreateMembership(context.Background(), "acme", "user-1", auth.RoleViewer, "")
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:             zap.NewNop(),
+		CognitoVerifier:    auth.NewCognitoVerifier(auth.CognitoConfig{Issuer: jwks.URL, Audience: "client"}),
+		Store:              controlPlane,
+		EnableControlPlane: true,
+		AuditStore:         auditStore,
+		RequestTimeout:     timeout,
+	})
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return &routedAuditAPI{url: server.URL, key: key, iss: jwks.URL}
+}
+
+// token signs an ID token for the user.
+func (a *routedAuditAPI) token(t *testing.T, userID string) string {
+	t.Helper()
+	header, _ := json.Marshal(auth.JWTHeader{Kid: "k1", Alg: "RS256"})
+	claims, _ := json.Marshal(auth.JWTClaims{Sub: userID, Iss: a.iss, Aud: "client", TokenUse: "id", Exp: time.Now().Add(time.Hour).Unix()})
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatalf("sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (a *routedAuditAPI) get(t *testing.T, ctx context.Context, path, token string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, a.url+path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	return resp
+}
+
+func TestAuditAPI_Tail(t *testing.T) {
+	base := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	store := audit.NewInMemoryStore()
+	seedS3Audit(t, store, base)
+	// A short request timeout, so the stream must end before the router's deadline
+	api := newRoutedAuditAPI(t, store, 3*time.Second)
+	token := api.token(t, "user-1")
+
+	// Only members of the tenant may tail it
+	for _, tc := range []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{api.token(t, "user-2"), http.StatusForbidden},
+	} {
+		resp := api.get(t, context.Background(), "/v1/tenants/acme/audit/tail", tc.token)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("tail with token %q: status %d, want %d", tc.token, resp.StatusCode, tc.want)
+		}
+	}
+
+	// Without follow the backlog is written oldest first and the stream ends
+	resp := api.get(t, context.Background(), "/v1/tenants/acme/audit/tail?limit=2", token)
+	var events []invarhttp.AuditTailEvent
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var event invarhttp.AuditTailEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("decode event %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	resp.Body.Close()
+	if len(events) != 2 || events[0].Record.AuditID != "audit-2" || events[1].Record.AuditID != "audit-3" {
+		t.Fatalf("unexpected backlog %+v", events)
+	}
+
+	// Following from the last cursor streams only new records
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	started := time.Now()
+	resp = api.get(t, ctx, "/v1/tenants/acme/audit/tail?follow=true&cursor="+events[1].Cursor, token)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("follow status %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	next := func() invarhttp.AuditTailEvent {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("stream ended: %v", lines.Err())
+		}
+		var event invarhttp.AuditTailEvent
+		if err := json.Unmarshal(lines.Bytes(), &event); err != nil {
+			t.Fatalf("decode event %q: %v", lines.Text(), err)
+		}
+		return event
+	}
+
+	if heartbeat := next(); heartbeat.Record != nil || heartbeat.Cursor != events[1].Cursor {
+		t.Fatalf("expected a heartbeat at the cursor, got %+v", heartbeat)
+	}
+
+	for _, r := range []*types.AuditRecord{
+		{AuditID: "audit-5", OrgID: "globex", CreatedAt: base.Add(5 * time.Hour)},
+		{AuditID: "audit-6", OrgID: "acme", CreatedAt: base.Add(6 * time.Hour)},
+	} {
+		if _, err := store.Write(context.Background(), r); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	if event := next(); event.Record == nil || event.Record.AuditID != "audit-6" {
+		t.Fatalf("expected audit-6, got %+v", event)
+	}
+
+	// The stream ends cleanly before the request timeout would cut it off
+	if lines.Scan() {
+		t.Fatalf("unexpected line after the last record: %q", lines.Text())
+	}
+	if err := lines.Err(); err != nil {
+		t.Fatalf("stream ended with %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= 3*time.Second {
+		t.Errorf("stream ran %s, past the request timeout", elapsed)
+	}
+}
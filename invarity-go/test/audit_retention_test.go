@@ -0,0 +1,418 @@
+package test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/types"
+)
+
+const testAuditHashKey = "audit-hash-key"
+
+func hmacValue(value string) string {
+	mac := hmac.New(sha256.New, []byte(testAuditHashKey))
+	mac.Write([]byte(`"` + value + `"`))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuditPolicies_Parse(t *testing.T) {
+	policies, err := audit.ParsePolicies(`{
+		"*": {"retention_days": 90},
+		"acme": {"keep_context": true},
+		"globex": {"redact_sensitive": false, "retention_days": 7, "archive": true}
+	}`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	tests := []struct {
+		tenant string
+		want   audit.Policy
+	}{
+		{"acme", audit.Policy{RedactSensitive: true, KeepContext: true, RetentionDays: 90}},
+		{"globex", audit.Policy{RetentionDays: 7, Archive: true}},
+		{"initech", audit.Policy{RedactSensitive: true, RetentionDays: 90}},
+	}
+	for _, tt := range tests {
+		if got := policies.For(tt.tenant); got != tt.want {
+			t.Errorf("%s: policy = %+v, want %+v", tt.tenant, got, tt.want)
+		}
+	}
+
+	defaults, _ := audit.ParsePolicies("")
+	if got := defaults.For("acme"); got != audit.DefaultPolicy() || got.Expires() {
+		t.Errorf("empty config policy = %+v, want the default", got)
+	}
+
+	for _, bad := range []string{`[]`, `{"acme": {"retention_days": -1}}`, `{"acme": {"retention_days": "30d"}}`} {
+		if _, err := audit.ParsePolicies(bad); err == nil {
+			t.Errorf("expected error for %s", bad)
+		}
+	}
+}
+
+func TestAuditRedaction_Paths(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		sensitive []types.SensitiveArg
+		want      string
+	}{
+		{
+			name:      "top-level and nested keys",
+			args:      `{"account":{"number":"12345678","sort_code":"01-02-03"},"amount":150000000000000001,"memo":"rent"}`,
+			sensitive: []types.SensitiveArg{{Path: "account.number"}, {Path: "memo", Action: types.SensitiveArgHash}},
+			want:      `{"account":{"number":"[REDACTED]","sort_code":"01-02-03"},"amount":150000000000000001,"memo":"` + hmacValue("rent") + `"}`,
+		},
+		{
+			name:      "wildcards and indexes",
+			args:      `{"to":["a@x.example","b@x.example"],"cards":[{"pan":"4111"},{"pan":"5500"}]}`,
+			sensitive: []types.SensitiveArg{{Path: "to.*", Action: types.SensitiveArgHash}, {Path: "cards.1.pan"}},
+			want:      `{"cards":[{"pan":"4111"},{"pan":"[REDACTED]"}],"to":["` + hmacValue("a@x.example") + `","` + hmacValue("b@x.example") + `"]}`,
+		},
+		{
+			name:      "whole objects",
+			args:      `{"customer":{"name":"Ada","email":"ada@x.example"},"id":"c-1"}`,
+			sensitive: []types.SensitiveArg{{Path: "customer"}},
+			want:      `{"customer":"[REDACTED]","id":"c-1"}`,
+		},
+		{
+			name:      "missing paths are ignored",
+			args:      `{"id":"c-1"}`,
+			sensitive: []types.SensitiveArg{{Path: "customer.email"}, {Path: "id.value"}},
+			want:      `{"id":"c-1"}`,
+		},
+		{
+			name:      "args that are not JSON",
+			args:      `not json`,
+			sensitive: []types.SensitiveArg{{Path: "id"}},
+			want:      `"[REDACTED]"`,
+		},
+	}
+
+	redactor := audit.NewRedactor(audit.NewPolicies(audit.DefaultPolicy()), testAuditHashKey)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &types.AuditRecord{OrgID: "acme", ToolCall: types.ToolCall{Args: json.RawMessage(tt.args)}}
+			if err := redactor.Apply(record, tt.sensitive); err != nil {
+				t.Fatalf("apply failed: %v", err)
+			}
+			if string(record.ToolCall.Args) != tt.want {
+				t.Errorf("args = %s\nwant   %s", record.ToolCall.Args, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditRedaction_AppliedBeforeStoreAndSinks(t *testing.T) {
+	reg := registry.NewInMemoryStoreWithDefaults()
+	tool, err := reg.GetTool(context.Background(), "send_email", "1.0.0")
+	if err != nil {
+		t.Fatalf("get tool: %v", err)
+	}
+	tool.SensitiveArgs = []types.SensitiveArg{{Path: "to.*", Action: types.SensitiveArgHash}, {Path: "body"}}
+	reg.PutTool(context.Background(), tool)
+
+	policies, err := audit.ParsePolicies(`{"beta": {"redact_sensitive": false, "keep_context": true}}`)
+	if err != nil {
+		t.Fatalf("parse policies: %v", err)
+	}
+	store := audit.NewInMemoryStore()
+	sink := &recordingSink{name: "siem"}
+	exporter := audit.NewExporter(audit.ExporterConfig{FlushInterval: 10 * time.Millisecond})
+	exporter.Add(sink, audit.SinkFilter{})
+
+	alignment := llm.NewMockClient()
+	alignment.SetResponse("*", llm.NewMockResponse(`{"vote":"SAFE","confidence":0.95,"reasons":[]}`))
+	cfg := config.DefaultConfig()
+	cfg.EnableThreatSentinel = false
+	p := firewall.NewPipeline(firewall.PipelineConfig{
+		Config:          cfg,
+		Logger:          zap.NewNop(),
+		RegistryStore:   reg,
+		AuditStore:      audit.NewExportingStore(store, exporter),
+		AlignmentClient: alignment,
+		ThreatClient:    llm.NewMockClient(),
+		AuditRedactor:   audit.NewRedactor(policies, testAuditHashKey),
+	})
+
+	evaluate := func(tenantID string) *types.AuditRecord {
+		t.Helper()
+		req := injectedEmailRequest()
+		req.OrgID = tenantID
+		originalArgs := string(req.ToolCall.Args)
+
+		resp, err := p.Evaluate(context.Background(), req)
+		if err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+		if string(req.ToolCall.Args) != originalArgs {
+			t.Errorf("request args were modified: %s", req.ToolCall.Args)
+		}
+		record, err := store.Get(context.Background(), resp.AuditID)
+		if err != nil {
+			t.Fatalf("audit lookup failed: %v", err)
+		}
+		return record
+	}
+
+	// Default policy: declared paths redacted or hashed, context and excerpts omitted
+	acme := evaluate("acme")
+	wantArgs := `{"body":"[REDACTED]","subject":"Q3 report","to":["` + hmacValue("attacker@evil.example") + `"]}`
+	if string(acme.ToolCall.Args) != wantArgs {
+		t.Errorf("acme args = %s, want %s", acme.ToolCall.Args, wantArgs)
+	}
+	if acme.BoundedContext != nil {
+		t.Errorf("acme bounded context recorded: %+v", acme.BoundedContext)
+	}
+	if acme.ContextScan == nil || len(acme.ContextScan.Findings) == 0 {
+		t.Fatalf("acme context scan findings missing: %+v", acme.ContextScan)
+	}
+	for _, finding := range acme.ContextScan.Findings {
+		if finding.Excerpt != "" {
+			t.Errorf("acme finding kept its excerpt: %+v", finding)
+		}
+	}
+
+	// beta keeps args, context and excerpts
+	beta := evaluate("beta")
+	if !strings.Contains(string(beta.ToolCall.Args), "attacker@evil.example") {
+		t.Errorf("beta args were redacted: %s", beta.ToolCall.Args)
+	}
+	if beta.BoundedContext == nil || len(beta.BoundedContext.RelevantDocuments) != 1 {
+		t.Errorf("beta bounded context not recorded: %+v", beta.BoundedContext)
+	}
+	if beta.ContextScan == nil || len(beta.ContextScan.Findings) == 0 || beta.ContextScan.Findings[0].Excerpt == "" {
+		t.Errorf("beta context scan excerpts not recorded: %+v", beta.ContextScan)
+	}
+
+	// Sinks receive what was stored
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatalf("close exporter: %v", err)
+	}
+	if len(sink.records) != 2 || string(sink.records[0].ToolCall.Args) != wantArgs {
+		t.Errorf("sink received unredacted records: %+v", sink.records)
+	}
+}
+
+// writeAgedChain writes n chained records for the tenant, the first old of
+// them created 40 days before now and the rest at now.
+func writeAgedChain(t *testing.T, store audit.Store, tenantID string, n, old int, now time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		created := now
+		if i < old {
+			created = now.Add(-40*24*time.Hour + time.Duration(i)*time.Minute)
+		}
+		record := &types.AuditRecord{
+			AuditID:   fmt.Sprintf("%s-%d", tenantID, i+1),
+			OrgID:     tenantID,
+			ToolCall:  types.ToolCall{ActionID: "send_email"},
+			Decision:  types.DecisionAllow,
+			CreatedAt: created,
+		}
+		if _, err := store.Write(context.Background(), record); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestAuditRetention_PurgesThroughCheckpoints(t *testing.T) {
+	now := time.Now().UTC()
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	chained := audit.NewChainedStore(backing, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 3})
+
+	// acme: 1-5 expired, checkpoints at 3 and 6; globex keeps records forever
+	writeAgedChain(t, chained, "acme", 7, 5, now)
+	writeAgedChain(t, chained, "globex", 4, 4, now)
+	// A record written before chaining is purged by age alone
+	backing.Write(context.Background(), &types.AuditRecord{AuditID: "acme-legacy", OrgID: "acme", CreatedAt: now.Add(-100 * 24 * time.Hour)})
+
+	policies, _ := audit.ParsePolicies(`{"acme": {"retention_days": 30, "archive": true}}`)
+	archive := &recordingSink{name: "archive"}
+	retention := audit.NewRetention(backing, audit.RetentionConfig{Policies: policies, Checkpoints: checkpoints, Archive: archive})
+
+	results, err := retention.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected a result for acme only, got %+v", results)
+	}
+	// 4 and 5 are kept until 6, which closes their checkpoint window, expires too
+	if r := results[0]; r.TenantID != "acme" || r.Expired != 6 || r.Purged != 4 || r.Archived != 4 || r.PrunedThrough != 3 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if got, want := archive.ids(), "acme-legacy,acme-1,acme-2,acme-3"; got != want {
+		t.Errorf("archived %s, want %s", got, want)
+	}
+	remaining, _ := backing.List(context.Background(), &audit.ListFilter{OrgID: "acme"})
+	if got, want := auditIDs(remaining), "acme-7,acme-6,acme-5,acme-4"; got != want {
+		t.Errorf("remaining %s, want %s", got, want)
+	}
+	if globex, _ := backing.List(context.Background(), &audit.ListFilter{OrgID: "globex"}); len(globex) != 4 {
+		t.Errorf("globex records purged without a retention policy: %d left", len(globex))
+	}
+
+	// The remaining chain verifies, anchored on the checkpoint at 3
+	report, err := audit.NewVerifier(backing, checkpoints, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.PrunedThrough != 3 || report.FirstSequence != 4 {
+		t.Errorf("expected a valid chain pruned through 3, got %+v", report)
+	}
+
+	// Deleting past the checkpoint boundary is still a gap
+	backing.Delete(context.Background(), remaining[len(remaining)-1:])
+	report, _ = audit.NewVerifier(backing, checkpoints, nil).Verify(context.Background(), "acme")
+	if report.Valid || report.PrunedThrough != 0 || issueTypes(report)[audit.IssueGap] == 0 {
+		t.Errorf("expected a gap after deleting record 4, got %+v", report)
+	}
+}
+
+// failingSink rejects every batch.
+type failingSink struct{}
+
+func (failingSink) Name() string { return "failing" }
+func (failingSink) Close() error { return nil }
+func (failingSink) Send(ctx context.Context, records []*types.AuditRecord) error {
+	return errors.New("archive unavailable")
+}
+
+func TestAuditRetention_KeepsRecordsThatFailToArchive(t *testing.T) {
+	now := time.Now().UTC()
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	writeAgedChain(t, audit.NewChainedStore(backing, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 2}), "acme", 3, 2, now)
+
+	policies, _ := audit.ParsePolicies(`{"acme": {"retention_days": 30, "archive": true}}`)
+	for _, archive := range []audit.Sink{failingSink{}, nil} {
+		retention := audit.NewRetention(backing, audit.RetentionConfig{Policies: policies, Checkpoints: checkpoints, Archive: archive})
+		if _, err := retention.Purge(context.Background(), now); err == nil {
+			t.Errorf("archive %v: expected an error", archive)
+		}
+		if all, _ := backing.List(context.Background(), nil); len(all) != 3 {
+			t.Errorf("archive %v: %d records left, want 3", archive, len(all))
+		}
+	}
+}
+
+func TestAuditRetention_S3Store(t *testing.T) {
+	store, objects, ddb := newTestS3AuditStore()
+	base := time.Now().UTC().Add(-60 * 24 * time.Hour)
+	seedS3Audit(t, store, base)
+
+	tenants, err := store.Tenants(context.Background())
+	if err != nil {
+		t.Fatalf("tenants failed: %v", err)
+	}
+	if strings.Join(tenants, ",") != "acme,globex" && strings.Join(tenants, ",") != "globex,acme" {
+		t.Errorf("tenants = %v", tenants)
+	}
+
+	policies, _ := audit.ParsePolicies(`{"globex": {"retention_days": 30}}`)
+	results, err := audit.NewRetention(store, audit.RetentionConfig{Policies: policies}).Purge(context.Background(), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if len(results) != 1 || results[0].TenantID != "globex" || results[0].Purged != 1 {
+		t.Errorf("unexpected results %+v", results)
+	}
+
+	if _, err := store.Get(context.Background(), "audit-4"); !errors.Is(err, audit.ErrNotFound) {
+		t.Errorf("expected purged record to be gone, got %v", err)
+	}
+	for key := range objects.objects {
+		if strings.Contains(key, "/globex/") {
+			t.Errorf("object %s not deleted", key)
+		}
+	}
+	for key := range ddb.items {
+		if strings.Contains(key, "audit-4") || strings.HasPrefix(key, "globex|") {
+			t.Errorf("index item %s not deleted", key)
+		}
+	}
+	if acme, _ := store.List(context.Background(), &audit.ListFilter{OrgID: "acme"}); len(acme) != 4 {
+		t.Errorf("acme records purged: %d left", len(acme))
+	}
+}
+
+func TestAuditRetention_PagesOldestFirst(t *testing.T) {
+	now := time.Now().UTC()
+	backing := audit.NewInMemoryStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	chained := audit.NewChainedStore(backing, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 100})
+
+	// More expired records than fit in one page of the purge
+	writeAgedChain(t, chained, "acme", 1250, 1150, now)
+
+	policies, _ := audit.ParsePolicies(`{"acme": {"retention_days": 30, "archive": true}}`)
+	archive := &recordingSink{name: "archive"}
+	retention := audit.NewRetention(backing, audit.RetentionConfig{Policies: policies, Checkpoints: checkpoints, Archive: archive})
+
+	results, err := retention.Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if r := results[0]; r.Expired != 1150 || r.Purged != 1100 || r.Archived != 1100 || r.PrunedThrough != 1100 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	for i, record := range archive.records {
+		if record.Sequence != int64(i+1) {
+			t.Fatalf("archived record %d has sequence %d; records are not archived oldest first", i, record.Sequence)
+		}
+	}
+
+	report, err := audit.NewVerifier(backing, checkpoints, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.FirstSequence != 1101 || report.Records != 150 {
+		t.Errorf("expected a valid chain from 1101, got %+v", report)
+	}
+}
+
+func TestAuditRetention_S3StoreListsIndexOnly(t *testing.T) {
+	store, objects, _ := newTestS3AuditStore()
+	checkpoints := audit.NewInMemoryCheckpointStore()
+	now := time.Now().UTC()
+	writeAgedChain(t, audit.NewChainedStore(store, audit.ChainConfig{Checkpoints: checkpoints, CheckpointInterval: 2}), "acme", 5, 4, now)
+
+	policies, _ := audit.ParsePolicies(`{"acme": {"retention_days": 30}}`)
+	objects.gets = 0
+	results, err := audit.NewRetention(store, audit.RetentionConfig{Policies: policies, Checkpoints: checkpoints}).Purge(context.Background(), now)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if r := results[0]; r.Expired != 4 || r.Purged != 4 || r.PrunedThrough != 4 {
+		t.Errorf("unexpected result %+v", r)
+	}
+	if objects.gets != 0 {
+		t.Errorf("purge read %d record bodies from S3, want none", objects.gets)
+	}
+
+	report, err := audit.NewVerifier(store, checkpoints, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.FirstSequence != 5 || report.Records != 1 {
+		t.Errorf("expected a valid chain from 5, got %+v", report)
+	}
+}
@@ -0,0 +1,314 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"invarity/internal/audit"
+	"invarity/internal/types"
+)
+
+func sinkRecord(id string, decision types.Decision, tier types.RiskTier) *types.AuditRecord {
+	return &types.AuditRecord{
+		AuditID:      id,
+		RequestID:    "req-" + id,
+		OrgID:        "acme",
+		PrincipalID:  "billing-agent",
+		Actor:        types.Actor{ID: "agent-1", Role: "finance", Type: "agent"},
+		ToolCall:     types.ToolCall{ActionID: "transfer_funds", Version: "1.0.0"},
+		UserIntent:   "Pay invoice #42 | vendor=acme\nnow",
+		Decision:     decision,
+		RiskTier:     tier,
+		Reasons:      []string{"threat:prompt_injection"},
+		PipelineStep: "S5",
+		CreatedAt:    time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC),
+		Sequence:     7,
+	}
+}
+
+// recordingSink collects what it is sent.
+type recordingSink struct {
+	name    string
+	mu      sync.Mutex
+	records []*types.AuditRecord
+}
+
+func (s *recordingSink) Name() string { return s.name }
+func (s *recordingSink) Close() error { return nil }
+func (s *recordingSink) Send(ctx context.Context, records []*types.AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, records...)
+	return nil
+}
+func (s *recordingSink) ids() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return auditIDs(s.records)
+}
+
+func TestAuditExport_OCSF(t *testing.T) {
+	tests := []struct {
+		decision    types.Decision
+		tier        types.RiskTier
+		status      int
+		action      int
+		disposition string
+		severity    int
+	}{
+		{types.DecisionAllow, types.RiskTierLow, 1, 1, "Allowed", 1},
+		{types.DecisionDeny, types.RiskTierCritical, 2, 2, "Blocked", 4},
+		{types.DecisionEscalate, types.RiskTierHigh, 2, 99, "Escalated", 3},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.decision), func(t *testing.T) {
+			data, err := audit.Encode(audit.FormatOCSF, sinkRecord("a1", tt.decision, tt.tier))
+			if err != nil {
+				t.Fatalf("encode failed: %v", err)
+			}
+			var event audit.OCSFEvent
+			if err := json.Unmarshal(data, &event); err != nil {
+				t.Fatalf("decode failed: %v", err)
+			}
+			if event.ClassUID != 6003 || event.CategoryUID != 6 || event.TypeUID != 600399 {
+				t.Errorf("unexpected class %d/%d/%d", event.CategoryUID, event.ClassUID, event.TypeUID)
+			}
+			if event.StatusID != tt.status || event.ActionID != tt.action || event.Disposition != tt.disposition || event.SeverityID != tt.severity {
+				t.Errorf("unexpected outcome %+v", event)
+			}
+			if event.Time != 1780315200000 || event.Metadata.UID != "a1" || event.Metadata.TenantUID != "acme" ||
+				event.API.Operation != "transfer_funds" || event.Actor.User.UID != "agent-1" || event.Actor.AppUID != "billing-agent" {
+				t.Errorf("unexpected fields %+v", event)
+			}
+		})
+	}
+}
+
+func TestAuditExport_CEF(t *testing.T) {
+	line := audit.ToCEF(sinkRecord("a1", types.DecisionDeny, types.RiskTierLow))
+
+	prefix := `CEF:0|Invarity|Invarity Firewall|1.0|tool_call:deny|Tool call DENY transfer_funds|7|`
+	if !strings.HasPrefix(line, prefix) {
+		t.Fatalf("unexpected header in %s", line)
+	}
+	for _, want := range []string{
+		"rt=1780315200000",
+		"act=DENY",
+		"externalId=a1",
+		"suid=billing-agent",
+		`msg=Pay invoice #42 | vendor\=acme\nnow`,
+		"cs1Label=tenant cs1=acme",
+		"cs3=threat:prompt_injection",
+		"cn1=7",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("missing %q in %s", want, line)
+		}
+	}
+	if strings.Contains(line, "\n") {
+		t.Errorf("CEF line contains a raw newline")
+	}
+}
+
+func TestAuditExport_FileSinkRotates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.jsonl")
+	sink, err := audit.NewFileSink("file", path, audit.FormatJSON, 600, 2)
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+
+	for i := 0; i < 8; i++ {
+		if err := sink.Send(context.Background(), []*types.AuditRecord{sinkRecord(string(rune('a'+i)), types.DecisionAllow, types.RiskTierLow)}); err != nil {
+			t.Fatalf("send failed: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(path + "*")
+	if len(files) != 3 {
+		t.Fatalf("expected the file and 2 rotations, got %v", files)
+	}
+	for _, f := range files {
+		info, _ := os.Stat(f)
+		if info.Size() > 600 {
+			t.Errorf("%s is %d bytes, over the limit", f, info.Size())
+		}
+	}
+
+	// The current file holds the newest record, one JSON object per line
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	var last types.AuditRecord
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &last); err != nil || last.AuditID != "h" {
+		t.Errorf("unexpected last line %q (%v)", lines[len(lines)-1], err)
+	}
+}
+
+func TestAuditExport_WebhookSignsAndRetries(t *testing.T) {
+	var attempts atomic.Int32
+	var body []byte
+	var timestamp, signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ = io.ReadAll(r.Body)
+		timestamp = r.Header.Get(audit.WebhookTimestampHeader)
+		signature = r.Header.Get(audit.WebhookSignatureHeader)
+	}))
+	defer server.Close()
+
+	sink := audit.NewWebhookSink("siem", server.URL, "shh", audit.FormatOCSF, 2)
+	records := []*types.AuditRecord{sinkRecord("a1", types.DecisionAllow, types.RiskTierLow), sinkRecord("a2", types.DecisionDeny, types.RiskTierHigh)}
+	if err := sink.Send(context.Background(), records); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	if attempts.Load() != 2 {
+		t.Errorf("expected a retry after 503, got %d attempts", attempts.Load())
+	}
+	if signature != audit.SignWebhook([]byte("shh"), timestamp, body) || !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("signature %q does not match the body", signature)
+	}
+	var events []audit.OCSFEvent
+	if err := json.Unmarshal(body, &events); err != nil || len(events) != 2 || events[1].Metadata.UID != "a2" {
+		t.Errorf("unexpected batch %s (%v)", body, err)
+	}
+
+	// Client errors are not retried
+	attempts.Store(0)
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	if err := audit.NewWebhookSink("siem", rejecting.URL, "", audit.FormatJSON, 3).Send(context.Background(), records); err == nil {
+		t.Errorf("expected an error for 400")
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("expected no retries for 400, got %d attempts", attempts.Load())
+	}
+}
+
+func TestAuditExport_SyslogCEF(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	defer ln.Close()
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			// Octet-counted framing: "LEN MSG"
+			var n int
+			if _, err := fmt.Fscan(r, &n); err != nil {
+				return
+			}
+			r.ReadByte() // The space after the length
+			msg := make([]byte, n)
+			if _, err := io.ReadFull(r, msg); err != nil {
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	sink := audit.NewSyslogSink("syslog", "tcp", ln.Addr().String(), "invarity-test", audit.FormatCEF)
+	defer sink.Close()
+	if err := sink.Send(context.Background(), []*types.AuditRecord{
+		sinkRecord("a1", types.DecisionDeny, types.RiskTierCritical),
+		sinkRecord("a2", types.DecisionAllow, types.RiskTierLow),
+	}); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	for _, want := range []string{"<108>1 2026-06-01T12:00:00Z ", "<110>1 2026-06-01T12:00:00Z "} {
+		select {
+		case msg := <-received:
+			if !strings.HasPrefix(msg, want) || !strings.Contains(msg, " invarity-test ") || !strings.Contains(msg, " audit - CEF:0|Invarity|") {
+				t.Errorf("unexpected message %q", msg)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("message not received")
+		}
+	}
+}
+
+func TestAuditExport_RoutesByTenantAndFilters(t *testing.T) {
+	configs, err := audit.ParseSinkConfigs(`[
+		{"name": "acme-denies", "type": "webhook", "url": "http://siem.invalid", "tenants": ["acme"], "decisions": ["deny"]},
+		{"name": "critical", "type": "syslog", "address": "127.0.0.1:514", "risk_tiers": ["critical"]}
+	]`)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if configs[1].Format != audit.FormatCEF || configs[0].Format != audit.FormatJSON {
+		t.Errorf("unexpected default formats %q, %q", configs[0].Format, configs[1].Format)
+	}
+
+	exporter := audit.NewExporter(audit.ExporterConfig{FlushInterval: 10 * time.Millisecond})
+	denies := &recordingSink{name: "acme-denies"}
+	critical := &recordingSink{name: "critical"}
+	exporter.Add(denies, configs[0].Filter())
+	exporter.Add(critical, configs[1].Filter())
+
+	failing := newFlakyAuditStore()
+	store := audit.NewExportingStore(failing, exporter)
+	write := func(r *types.AuditRecord) {
+		store.Write(context.Background(), r)
+	}
+	write(sinkRecord("acme-allow", types.DecisionAllow, types.RiskTierCritical))
+	write(sinkRecord("acme-deny", types.DecisionDeny, types.RiskTierLow))
+	globex := sinkRecord("globex-deny", types.DecisionDeny, types.RiskTierCritical)
+	globex.OrgID = "globex"
+	write(globex)
+	failing.failing.Store(true)
+	write(sinkRecord("unwritten", types.DecisionDeny, types.RiskTierCritical))
+
+	if err := exporter.Close(context.Background()); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if got := denies.ids(); got != "acme-deny" {
+		t.Errorf("acme-denies got %s", got)
+	}
+	if got := critical.ids(); got != "acme-allow,globex-deny" {
+		t.Errorf("critical got %s", got)
+	}
+	if stats := exporter.Stats(); stats[0].Exported != 1 || stats[1].Exported != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	for _, bad := range []string{
+		`[{"name": "x", "type": "ftp"}]`,
+		`[{"name": "x", "type": "file"}]`,
+		`[{"name": "x", "type": "webhook", "url": "http://a", "format": "xml"}]`,
+		`[{"name": "x", "type": "file", "path": "/tmp/a"}, {"name": "x", "type": "file", "path": "/tmp/b"}]`,
+	} {
+		if _, err := audit.ParseSinkConfigs(bad); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
@@ -0,0 +1,444 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"invarity/internal/audit"
+	"invarity/internal/types"
+)
+
+// fakeS3 is an in-memory audit.S3API.
+type fakeS3 struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	gets        int
+	getDelay    time.Duration // Time each GetObject takes
+	inFlight    int
+	maxInFlight int // Most GetObjects running at once
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	data, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	f.gets++
+	f.inFlight++
+	f.maxInFlight = max(f.maxInFlight, f.inFlight)
+	delay := f.getDelay
+	f.mu.Unlock()
+	time.Sleep(delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight--
+	data, ok := f.objects[aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, _ ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.objects, aws.ToString(in.Bucket)+"/"+aws.ToString(in.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// ListObjectsV2 only supports listing common prefixes, in one page.
+func (f *fakeS3) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	prefix := aws.ToString(in.Bucket) + "/" + aws.ToString(in.Prefix)
+	seen := make(map[string]bool)
+	out := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}
+	for key := range f.objects {
+		rest, ok := strings.CutPrefix(key, prefix)
+		if !ok {
+			continue
+		}
+		if i := strings.Index(rest, aws.ToString(in.Delimiter)); i >= 0 {
+			common := aws.ToString(in.Prefix) + rest[:i+1]
+			if !seen[common] {
+				seen[common] = true
+				out.CommonPrefixes = append(out.CommonPrefixes, s3types.CommonPrefix{Prefix: aws.String(common)})
+			}
+		}
+	}
+	return out, nil
+}
+
+// fakeDynamoDB is an in-memory audit.DynamoDBAPI that understands the key
+// conditions and filter placeholders the audit index emits.
+type fakeDynamoDB struct {
+	mu       sync.Mutex
+	items    map[string]map[string]ddbtypes.AttributeValue
+	pageSize int
+	queries  []*dynamodb.QueryInput
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: make(map[string]map[string]ddbtypes.AttributeValue), pageSize: 2}
+}
+
+func attrS(item map[string]ddbtypes.AttributeValue, name string) string {
+	if v, ok := item[name].(*ddbtypes.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func (f *fakeDynamoDB) TransactWriteItems(ctx context.Context, in *dynamodb.TransactWriteItemsInput, _ ...func(*dynamodb.Options)) (*dynamodb.TransactWriteItemsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reasons := make([]ddbtypes.CancellationReason, len(in.TransactItems))
+	canceled := false
+	for i, ti := range in.TransactItems {
+		reasons[i].Code = aws.String("None")
+		if ti.Put == nil {
+			continue
+		}
+		key := attrS(ti.Put.Item, "tenant_id") + "|" + attrS(ti.Put.Item, "created_audit")
+		if _, exists := f.items[key]; exists && ti.Put.ConditionExpression != nil {
+			reasons[i].Code = aws.String("ConditionalCheckFailed")
+			canceled = true
+		}
+	}
+	if canceled {
+		return nil, &ddbtypes.TransactionCanceledException{CancellationReasons: reasons}
+	}
+	for _, ti := range in.TransactItems {
+		if ti.Delete != nil {
+			delete(f.items, attrS(ti.Delete.Key, "tenant_id")+"|"+attrS(ti.Delete.Key, "created_audit"))
+			continue
+		}
+		f.items[attrS(ti.Put.Item, "tenant_id")+"|"+attrS(ti.Put.Item, "created_audit")] = ti.Put.Item
+	}
+	return &dynamodb.TransactWriteItemsOutput{}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(ctx context.Context, in *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return &dynamodb.GetItemOutput{Item: f.items[attrS(in.Key, "tenant_id")+"|"+attrS(in.Key, "created_audit")]}, nil
+}
+
+func (f *fakeDynamoDB) Query(ctx context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, in)
+
+	partitionAttr := "tenant_id"
+	if aws.ToString(in.IndexName) == "principal-index" {
+		partitionAttr = "tenant_principal"
+	}
+	v := in.ExpressionAttributeValues
+	matches := make([]map[string]ddbtypes.AttributeValue, 0)
+	for _, item := range f.items {
+		sk := attrS(item, "created_audit")
+		switch {
+		case attrS(item, partitionAttr) != attrS(v, ":tid"):
+		case v[":start"] != nil && sk < attrS(v, ":start"):
+		case v[":end"] != nil && sk > attrS(v, ":end"):
+		default:
+			matches = append(matches, item)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return attrS(matches[i], "created_audit") > attrS(matches[j], "created_audit")
+	})
+
+	// Page before filtering, as DynamoDB does
+	// The start key need not exist: resume at the first item below it
+	start := 0
+	if in.ExclusiveStartKey != nil {
+		start = len(matches)
+		for i, item := range matches {
+			if attrS(item, "created_audit") < attrS(in.ExclusiveStartKey, "created_audit") {
+				start = i
+				break
+			}
+		}
+	}
+	end := start + f.pageSize
+	out := &dynamodb.QueryOutput{}
+	if end < len(matches) {
+		out.LastEvaluatedKey = matches[end-1]
+	} else {
+		end = len(matches)
+	}
+	for _, item := range matches[start:end] {
+		switch {
+		case v[":actor"] != nil && attrS(item, "actor_id") != attrS(v, ":actor"):
+		case v[":action"] != nil && attrS(item, "action_id") != attrS(v, ":action"):
+		case v[":decision"] != nil && attrS(item, "decision") != attrS(v, ":decision"):
+		case v[":risk"] != nil && attrS(item, "risk_tier") != attrS(v, ":risk"):
+		case v[":reason"] != nil && !attrContains(item, "reasons", attrS(v, ":reason")):
+		case v[":query"] != nil && !strings.Contains(attrS(item, "intent"), attrS(v, ":query")):
+		default:
+			out.Items = append(out.Items, item)
+		}
+	}
+	return out, nil
+}
+
+// attrContains reports whether a list attribute has a string element equal to value.
+func attrContains(item map[string]ddbtypes.AttributeValue, name, value string) bool {
+	list, ok := item[name].(*ddbtypes.AttributeValueMemberL)
+	if !ok {
+		return false
+	}
+	for _, v := range list.Value {
+		if s, ok := v.(*ddbtypes.AttributeValueMemberS); ok && s.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func newTestS3AuditStore() (*audit.S3Store, *fakeS3, *fakeDynamoDB) {
+	objects := newFakeS3()
+	ddb := newFakeDynamoDB()
+	store := audit.NewS3Store(objects, "audit-blobs", "audit", audit.NewDynamoDBIndex(ddb, "invarity-dev-audit-index"))
+	return store, objects, ddb
+}
+
+func seedS3Audit(t *testing.T, store audit.Store, base time.Time) {
+	t.Helper()
+	records := []*types.AuditRecord{
+		{OrgID: "acme", PrincipalID: "p-billing", Actor: types.Actor{ID: "agent-1"}, ToolCall: types.ToolCall{ActionID: "send_email"}, Decision: types.DecisionAllow,
+			RiskTier: types.RiskTierLow, UserIntent: "Email the Quarterly report to finance"},
+		{OrgID: "acme", PrincipalID: "p-billing", Actor: types.Actor{ID: "agent-1"}, ToolCall: types.ToolCall{ActionID: "transfer_funds"}, Decision: types.DecisionDeny,
+			RiskTier: types.RiskTierCritical, UserIntent: "Pay the vendor invoice", Reasons: []string{"threat:prompt_injection", "high_risk_requires_approval"}},
+		{OrgID: "acme", PrincipalID: "p-support", Actor: types.Actor{ID: "agent-2"}, ToolCall: types.ToolCall{ActionID: "send_email"}, Decision: types.DecisionEscalate,
+			RiskTier: types.RiskTierMedium, UserIntent: "Reply to the customer", Reasons: []string{"intent_alignment_escalate"}},
+		{OrgID: "acme", Actor: types.Actor{ID: "agent-2"}, ToolCall: types.ToolCall{ActionID: "delete_user"}, Decision: types.DecisionDeny,
+			RiskTier: types.RiskTierCritical, UserIntent: "Remove the stale account from the quarterly audit", Reasons: []string{"threat:data_exfiltration"}},
+		{OrgID: "globex", Actor: types.Actor{ID: "agent-9"}, ToolCall: types.ToolCall{ActionID: "send_email"}, Decision: types.DecisionDeny},
+	}
+	for i, r := range records {
+		r.AuditID = fmt.Sprintf("audit-%d", i)
+		r.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if _, err := store.Write(context.Background(), r); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestS3AuditStore_WriteAndGet(t *testing.T) {
+	store, objects, _ := newTestS3AuditStore()
+	created := time.Date(2026, 3, 7, 23, 30, 0, 0, time.UTC)
+
+	id, err := store.Write(context.Background(), &types.AuditRecord{
+		OrgID:      "acme",
+		Actor:      types.Actor{ID: "agent-1"},
+		ToolCall:   types.ToolCall{ActionID: "send_email"},
+		UserIntent: "Email the report",
+		Decision:   types.DecisionAllow,
+		CreatedAt:  created,
+	})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	wantKey := "audit-blobs/audit/acme/2026/03/07/" + id + ".json"
+	if _, ok := objects.objects[wantKey]; !ok {
+		t.Errorf("object not stored at %s; have %v", wantKey, objects.objects)
+	}
+
+	record, err := store.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if record.UserIntent != "Email the report" || !record.CreatedAt.Equal(created) {
+		t.Errorf("unexpected record %+v", record)
+	}
+
+	if _, err := store.Get(context.Background(), "missing"); err == nil {
+		t.Errorf("expected not found error")
+	}
+
+	// Audit IDs are never reused
+	if _, err := store.Write(context.Background(), &types.AuditRecord{AuditID: id, OrgID: "acme", CreatedAt: created}); !errors.Is(err, audit.ErrAlreadyExists) {
+		t.Errorf("expected ErrAlreadyExists for a duplicate audit ID, got %v", err)
+	}
+}
+
+func TestS3AuditStore_PingDoesNotWrite(t *testing.T) {
+	store, objects, _ := newTestS3AuditStore()
+	if err := store.Ping(context.Background()); err != nil {
+		t.Fatalf("ping failed: %v", err)
+	}
+	if len(objects.objects) != 0 {
+		t.Errorf("ping wrote objects: %v", objects.objects)
+	}
+}
+
+func TestS3AuditStore_List(t *testing.T) {
+	store, _, ddb := newTestS3AuditStore()
+	base := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	seedS3Audit(t, store, base)
+
+	ids := func(records []*types.AuditRecord) string {
+		out := make([]string, len(records))
+		for i, r := range records {
+			out[i] = r.AuditID
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		name   string
+		filter *audit.ListFilter
+		want   string
+	}{
+		{"tenant newest first", &audit.ListFilter{OrgID: "acme"}, "audit-3,audit-2,audit-1,audit-0"},
+		{"decision across pages", &audit.ListFilter{OrgID: "acme", Decision: types.DecisionDeny}, "audit-3,audit-1"},
+		{"actor", &audit.ListFilter{OrgID: "acme", ActorID: "agent-2"}, "audit-3,audit-2"},
+		{"action", &audit.ListFilter{OrgID: "acme", ActionID: "send_email"}, "audit-2,audit-0"},
+		{"principal index", &audit.ListFilter{OrgID: "acme", PrincipalID: "p-billing"}, "audit-1,audit-0"},
+		{"time range inclusive", &audit.ListFilter{OrgID: "acme", StartTime: base.Add(time.Hour), EndTime: base.Add(2 * time.Hour)}, "audit-2,audit-1"},
+		{"limit and offset", &audit.ListFilter{OrgID: "acme", Offset: 1, Limit: 2}, "audit-2,audit-1"},
+		{"risk tier", &audit.ListFilter{OrgID: "acme", RiskTier: types.RiskTierCritical}, "audit-3,audit-1"},
+		{"reason code", &audit.ListFilter{OrgID: "acme", ReasonCode: "threat"}, "audit-3,audit-1"},
+		{"full reason", &audit.ListFilter{OrgID: "acme", ReasonCode: "threat:prompt_injection"}, "audit-1"},
+		{"intent search ignores case", &audit.ListFilter{OrgID: "acme", Query: "QUARTERLY"}, "audit-3,audit-0"},
+		{"intent search with principal", &audit.ListFilter{OrgID: "acme", PrincipalID: "p-billing", Query: "quarterly"}, "audit-0"},
+		{"cursor", &audit.ListFilter{OrgID: "acme", Cursor: audit.EncodeCursor(&types.AuditRecord{AuditID: "audit-2", CreatedAt: base.Add(2 * time.Hour)}), Limit: 1}, "audit-1"},
+		{"cursor on principal index", &audit.ListFilter{OrgID: "acme", PrincipalID: "p-billing", Cursor: audit.EncodeCursor(&types.AuditRecord{AuditID: "audit-1", CreatedAt: base.Add(time.Hour)})}, "audit-0"},
+		{"other tenant", &audit.ListFilter{OrgID: "globex"}, "audit-4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := store.List(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if got := ids(records); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if last := ddb.queries[len(ddb.queries)-1]; aws.ToString(last.KeyConditionExpression) != "tenant_id = :tid" {
+		t.Errorf("unexpected key condition %q", aws.ToString(last.KeyConditionExpression))
+	}
+
+	if _, err := store.List(context.Background(), &audit.ListFilter{}); !errors.Is(err, audit.ErrTenantRequired) {
+		t.Errorf("expected ErrTenantRequired, got %v", err)
+	}
+	if _, err := store.List(context.Background(), &audit.ListFilter{OrgID: "acme", Cursor: "not a cursor"}); !errors.Is(err, audit.ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor, got %v", err)
+	}
+}
+
+// lostAckStore stores the next write but reports it as failed, as when a
+// write succeeds after its caller has timed out.
+type lostAckStore struct {
+	audit.Store
+	lose bool
+}
+
+func (s *lostAckStore) Write(ctx context.Context, record *types.AuditRecord) (string, error) {
+	id, err := s.Store.Write(ctx, record)
+	if err == nil && s.lose {
+		s.lose = false
+		return id, errors.New("context deadline exceeded")
+	}
+	return id, err
+}
+
+func TestS3AuditStore_RetriedWriteKeepsStoredChain(t *testing.T) {
+	store, objects, _ := newTestS3AuditStore()
+	backing := &lostAckStore{Store: store, lose: true}
+	chained := audit.NewChainedStore(backing, audit.ChainConfig{})
+
+	// The first attempt is stored but reported as failed, so the spool retries it
+	record := &types.AuditRecord{AuditID: "audit-1", OrgID: "acme", ToolCall: types.ToolCall{ActionID: "send_email"}, Decision: types.DecisionAllow}
+	if _, err := chained.Write(context.Background(), record); err == nil {
+		t.Fatal("expected the first write to report a failure")
+	}
+	key := "audit-blobs/" + store.ObjectKey(record)
+	stored := string(objects.objects[key])
+
+	if _, err := chained.Write(context.Background(), record); !errors.Is(err, audit.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for the retry, got %v", err)
+	}
+	writeChain(t, chained, "acme", 2)
+
+	// A late duplicate after the chain has moved on changes nothing either
+	if _, err := chained.Write(context.Background(), record); !errors.Is(err, audit.ErrAlreadyExists) {
+		t.Fatalf("expected ErrAlreadyExists for the late retry, got %v", err)
+	}
+	if got := string(objects.objects[key]); got != stored {
+		t.Errorf("stored record was overwritten:\n%s\nwant\n%s", got, stored)
+	}
+	if record.Sequence != 1 {
+		t.Errorf("retried record sequence = %d, want its stored 1", record.Sequence)
+	}
+
+	report, err := audit.NewVerifier(store, nil, nil).Verify(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !report.Valid || report.Records != 3 || report.LastSequence != 3 {
+		t.Errorf("unexpected report %+v", report)
+	}
+}
+
+func TestS3AuditStore_ListFetchesConcurrently(t *testing.T) {
+	store, objects, _ := newTestS3AuditStore()
+	base := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		record := &types.AuditRecord{AuditID: fmt.Sprintf("audit-%02d", i), OrgID: "acme", CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if _, err := store.Write(context.Background(), record); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+
+	objects.getDelay = 5 * time.Millisecond
+	records, err := store.List(context.Background(), &audit.ListFilter{OrgID: "acme", Limit: 40})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(records) != 40 {
+		t.Fatalf("listed %d records, want 40", len(records))
+	}
+	for i, record := range records {
+		if want := fmt.Sprintf("audit-%02d", 39-i); record.AuditID != want {
+			t.Fatalf("record %d is %s, want %s newest first", i, record.AuditID, want)
+		}
+	}
+	if objects.maxInFlight < 2 || objects.maxInFlight > 16 {
+		t.Errorf("%d objects fetched at once, want between 2 and 16", objects.maxInFlight)
+	}
+}
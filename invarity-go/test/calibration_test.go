@@ -0,0 +1,321 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/calibration"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/types"
+)
+
+// overconfidentSamples returns samples for a voter whose accuracy at each raw
+// confidence is 30 points lower than the confidence it reports.
+func overconfidentSamples(voterID string) []calibration.Sample {
+	samples := make([]calibration.Sample, 0)
+	for _, conf := range []float64{0.6, 0.7, 0.8, 0.9, 1.0} {
+		correct := int((conf - 0.3) * 20)
+		for i := 0; i < 20; i++ {
+			samples = append(samples, calibration.Sample{
+				VoterID:    voterID,
+				Model:      "functiongemma",
+				Confidence: conf,
+				Correct:    i < correct,
+			})
+		}
+	}
+	return samples
+}
+
+func TestCalibration_FitImprovesReliability(t *testing.T) {
+	for _, method := range []string{calibration.MethodIsotonic, calibration.MethodPlatt} {
+		t.Run(method, func(t *testing.T) {
+			c, err := calibration.NewCalibrator(method, 30)
+			if err != nil {
+				t.Fatalf("failed to create calibrator: %v", err)
+			}
+			c.Fit(overconfidentSamples("intent_verifier"), 100)
+
+			prev := -1.0
+			for _, conf := range []float64{0.6, 0.7, 0.8, 0.9, 1.0} {
+				p, ok := c.Calibrate("intent_verifier", "functiongemma", conf)
+				if !ok {
+					t.Fatalf("expected fitted curve for intent_verifier")
+				}
+				if p < prev {
+					t.Errorf("curve not monotone: %.3f after %.3f", p, prev)
+				}
+				if p >= conf {
+					t.Errorf("calibrated %.3f should be below raw %.2f for an overconfident voter", p, conf)
+				}
+				prev = p
+			}
+
+			report := c.Reliability()
+			if len(report.Voters) != 1 {
+				t.Fatalf("expected 1 voter in report, got %d", len(report.Voters))
+			}
+			stats := report.Voters[0]
+			if stats.BrierCalibrated >= stats.BrierRaw {
+				t.Errorf("brier did not improve: raw %.4f calibrated %.4f", stats.BrierRaw, stats.BrierCalibrated)
+			}
+			if stats.ECECalibrated >= stats.ECERaw {
+				t.Errorf("ECE did not improve: raw %.4f calibrated %.4f", stats.ECERaw, stats.ECECalibrated)
+			}
+		})
+	}
+}
+
+func TestCalibration_BelowMinSamplesPassesThrough(t *testing.T) {
+	c, err := calibration.NewCalibrator(calibration.MethodIsotonic, 500)
+	if err != nil {
+		t.Fatalf("failed to create calibrator: %v", err)
+	}
+	c.Fit(overconfidentSamples("intent_verifier"), 100)
+
+	p, ok := c.Calibrate("intent_verifier", "functiongemma", 0.9)
+	if ok || p != 0.9 {
+		t.Errorf("expected raw passthrough, got %.3f (fitted=%v)", p, ok)
+	}
+	if c.Reliability().Voters[0].Calibrated {
+		t.Errorf("voter should be reported as uncalibrated")
+	}
+
+	if _, err := calibration.NewCalibrator("histogram", 0); err == nil {
+		t.Errorf("expected error for unknown method")
+	}
+}
+
+func TestCalibration_AggregateUsesCalibratedConfidence(t *testing.T) {
+	vote := func(id string, v types.IntentVote, calibrated float64) types.IntentVoterResult {
+		return types.IntentVoterResult{VoterID: id, Vote: v, Confidence: 0.99, CalibratedConfidence: calibrated}
+	}
+
+	tests := []struct {
+		name     string
+		votes    []types.IntentVoterResult
+		expected types.IntentDecision
+	}{
+		{
+			name: "confident SAFE votes pass",
+			votes: []types.IntentVoterResult{
+				vote("a", types.IntentVoteSafe, 0.99),
+				vote("b", types.IntentVoteSafe, 0.99),
+				vote("c", types.IntentVoteSafe, 0.99),
+			},
+			expected: types.IntentDecisionSafe,
+		},
+		{
+			name: "coin-flip SAFE votes escalate",
+			votes: []types.IntentVoterResult{
+				vote("a", types.IntentVoteSafe, 0.51),
+				vote("b", types.IntentVoteSafe, 0.51),
+				vote("c", types.IntentVoteSafe, 0.51),
+			},
+			expected: types.IntentDecisionEscalate,
+		},
+		{
+			name: "low confidence DENY escalates instead of denying",
+			votes: []types.IntentVoterResult{
+				vote("a", types.IntentVoteSafe, 0.95),
+				vote("b", types.IntentVoteSafe, 0.95),
+				vote("c", types.IntentVoteDeny, 0.2),
+			},
+			expected: types.IntentDecisionEscalate,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := llm.AggregateCalibratedIntentVotes(tt.votes, 0.7, 0.5)
+			if result != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCalibration_DefaultThresholdsKeepBaselineDecisions(t *testing.T) {
+	cfg := config.DefaultConfig()
+	calibrator, err := calibration.NewCalibrator(cfg.CalibrationMethod, cfg.CalibrationMinSamples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vote := func(id string, v types.IntentVote, confidence float64) types.IntentVoterResult {
+		// Without a fitted curve the calibrated confidence is the raw one
+		calibrated, _ := calibrator.Calibrate(id, "functiongemma", confidence)
+		return types.IntentVoterResult{VoterID: id, Vote: v, Confidence: confidence, CalibratedConfidence: calibrated}
+	}
+
+	for _, votes := range [][]types.IntentVoterResult{
+		{vote("a", types.IntentVoteSafe, 0.6), vote("b", types.IntentVoteSafe, 0.6), vote("c", types.IntentVoteSafe, 0.6)},
+		{vote("a", types.IntentVoteSafe, 0.3), vote("b", types.IntentVoteSafe, 0.55), vote("c", types.IntentVoteAbstain, 0.5)},
+		{vote("a", types.IntentVoteSafe, 0.9), vote("b", types.IntentVoteSafe, 0.9), vote("c", types.IntentVoteDeny, 0.2)},
+		{vote("a", types.IntentVoteDeny, 0.4), vote("b", types.IntentVoteDeny, 0.4), vote("c", types.IntentVoteSafe, 0.4)},
+	} {
+		want := llm.AggregateIntentVotes(votes)
+		if got := llm.AggregateCalibratedIntentVotes(votes, cfg.MinSafeProbability, cfg.MinDenyProbability); got != want {
+			t.Errorf("expected the baseline decision %s without calibration, got %s for %+v", want, got, votes)
+		}
+	}
+}
+
+func TestCalibration_DefaultThreatThresholdKeepsBaselineDecisions(t *testing.T) {
+	if cfg := llm.DefaultIntentQuorumConfig(); cfg.MinSafeProbability != 0 || cfg.MinDenyProbability != 0 {
+		t.Errorf("quorum defaults = %v/%v, want 0/0", cfg.MinSafeProbability, cfg.MinDenyProbability)
+	}
+
+	tests := []struct {
+		label string
+		want  types.Decision
+	}{
+		{"MALICIOUS", types.DecisionDeny},
+		{"CLEAR", types.DecisionAllow},
+	}
+	for _, tt := range tests {
+		threat := llm.NewMockClient()
+		threat.SetResponse("*", llm.NewMockResponse(`{"label":"`+tt.label+`","threat_types":[],"confidence":0.3}`))
+		p := safeVotePipeline(func(cfg *firewall.PipelineConfig) {
+			cfg.Config.EnableThreatSentinel = true
+			cfg.ThreatClient = threat
+		})
+		resp, err := p.Evaluate(context.Background(), usageRequest("acme", "agent"))
+		if err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+		if resp.Threat == nil || resp.Decision != tt.want {
+			t.Errorf("low-confidence %s: decision %s (threat %+v), want %s", tt.label, resp.Decision, resp.Threat, tt.want)
+		}
+	}
+}
+
+// seedLabeledAudits writes n audit records where intent_verifier voted SAFE at
+// confidence 0.95 and labels them so that only the first correct are SAFE.
+func seedLabeledAudits(t *testing.T, store audit.Store, labels calibration.LabelStore, n, correct int) {
+	t.Helper()
+	ctx := context.Background()
+	for i := 0; i < n; i++ {
+		id, err := store.Write(ctx, &types.AuditRecord{
+			Alignment: &types.IntentAlignmentResult{
+				Voters: []types.IntentVoterResult{
+					{VoterID: "intent_verifier", Model: "functiongemma", Vote: types.IntentVoteSafe, Confidence: 0.95},
+					{VoterID: "scope_auditor", Model: "functiongemma", Vote: types.IntentVoteAbstain, Confidence: 0.5},
+				},
+				Decision: types.IntentDecisionSafe,
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to write audit: %v", err)
+		}
+		outcome := calibration.OutcomeUnsafe
+		if i < correct {
+			outcome = calibration.OutcomeSafe
+		}
+		if err := labels.Put(ctx, &calibration.Label{AuditID: id, Outcome: outcome}); err != nil {
+			t.Fatalf("failed to store label: %v", err)
+		}
+	}
+}
+
+func TestCalibration_RefitFromLabeledAudits(t *testing.T) {
+	store := audit.NewInMemoryStore()
+	labels := calibration.NewInMemoryLabelStore()
+	seedLabeledAudits(t, store, labels, 40, 20)
+	// A label for a missing audit is skipped
+	_ = labels.Put(context.Background(), &calibration.Label{AuditID: "missing", Outcome: calibration.OutcomeSafe})
+
+	c, _ := calibration.NewCalibrator(calibration.MethodIsotonic, 30)
+	report, err := c.Refit(context.Background(), store, labels)
+	if err != nil {
+		t.Fatalf("refit failed: %v", err)
+	}
+	if report.Labels != 40 || report.Samples != 40 {
+		t.Errorf("expected 40 labels and samples (abstains skipped), got %d/%d", report.Labels, report.Samples)
+	}
+
+	p, ok := c.Calibrate("intent_verifier", "functiongemma", 0.95)
+	if !ok || p < 0.45 || p > 0.55 {
+		t.Errorf("expected ~0.5 calibrated probability, got %.3f (fitted=%v)", p, ok)
+	}
+	// Curves are keyed by model
+	if _, ok := c.Calibrate("intent_verifier", "other-model", 0.95); ok {
+		t.Errorf("curve should not apply to a different model")
+	}
+}
+
+func TestCalibration_AdminEndpoints(t *testing.T) {
+	store := audit.NewInMemoryStore()
+	labels := calibration.NewInMemoryLabelStore()
+	seedLabeledAudits(t, store, labels, 30, 30)
+	c, _ := calibration.NewCalibrator(calibration.MethodIsotonic, 30)
+
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:      zap.NewNop(),
+		AdminAPIKey: "operator-secret",
+		AuditStore:  store,
+		Calibrator:  c,
+		LabelStore:  labels,
+	})
+
+	do := func(method, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/v1/admin/calibration/fit", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("missing key: expected 401, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/calibration/fit", "wrong", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong key: expected 401, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/calibration/labels", "operator-secret", `{"audit_id":"nope","outcome":"SAFE"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown audit: expected 404, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/v1/admin/calibration/labels", "operator-secret", `{"audit_id":"x","outcome":"MAYBE"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad outcome: expected 400, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/v1/admin/calibration/fit", "operator-secret", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("fit: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"voter_id":"intent_verifier"`) {
+		t.Errorf("fit report missing voter: %s", rec.Body.String())
+	}
+
+	rec = do(http.MethodGet, "/v1/admin/calibration/reliability", "operator-secret", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"calibrated":true`) {
+		t.Errorf("reliability: unexpected response %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCalibration_AdminDisabledWithoutKey(t *testing.T) {
+	c, _ := calibration.NewCalibrator("", 0)
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:     zap.NewNop(),
+		AuditStore: audit.NewInMemoryStore(),
+		Calibrator: c,
+		LabelStore: calibration.NewInMemoryLabelStore(),
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/calibration/reliability", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when admin key unset, got %d", rec.Code)
+	}
+}
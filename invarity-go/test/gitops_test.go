@@ -0,0 +1,337 @@
+package test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"invarity/internal/firewall"
+	"invarity/internal/gitops"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// refundTool is a tool file in the format the CLI validates.
+const refundTool = `name: stripe_refund_payment
+description: Refund a Stripe payment.
+parameters:
+  type: object
+  additionalProperties: false
+  properties:
+    payment_id:
+      type: string
+    amount:
+      type: integer
+  required:
+    - payment_id
+invarity:
+  id: stripe.refund_payment
+  version: 1.0.0
+  risk:
+    base_risk: high
+    operation: write
+    requires_human_review: true
+    tags: [financial]
+  constraints:
+    requires_justification: true
+    required_args: [payment_id]
+    disallow_wildcards: true
+    max_bulk: null
+    amount_limit:
+      max: 10000000
+      currency: USD
+      arg_key: amount
+`
+
+const paymentsToolset = `toolset_id: payments
+revision: "1"
+display_name: Payments
+tools:
+  - tool_id: stripe.refund_payment
+    version: 1.0.0
+labels:
+  team: payments
+`
+
+const paymentsAssignments = `assignments:
+  - principal_id: agent-billing
+    toolset_id: payments
+    revision: "1"
+`
+
+// writeGitOpsFile writes a file under dir, creating its directories.
+func writeGitOpsFile(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newGitOpsTree writes acme's refund tool, payments toolset and assignment.
+func newGitOpsTree(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	writeGitOpsFile(t, dir, "acme/tools/stripe/refund.yaml", refundTool)
+	writeGitOpsFile(t, dir, "acme/toolsets/payments.yaml", paymentsToolset)
+	writeGitOpsFile(t, dir, "acme/assignments.yaml", paymentsAssignments)
+	writeGitOpsFile(t, dir, ".git/HEAD", "ref: refs/heads/main\n")
+	return dir
+}
+
+func resolveRefund(t *testing.T, source *gitops.Source, version string) (*firewall.ResolveToolResult, error) {
+	t.Helper()
+	req := &types.ToolCallRequest{TenantID: "acme", PrincipalID: "agent-billing"}
+	req.ToolCall.ActionID = "stripe.refund_payment"
+	req.ToolCall.Version = version
+	return firewall.NewToolResolver(source, source).ResolveTool(context.Background(), req)
+}
+
+func TestGitOps_LoadAndResolve(t *testing.T) {
+	source, err := gitops.NewSource(gitops.Config{Dir: newGitOpsTree(t)})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	stats := source.Snapshot().Stats
+	if stats != (gitops.Stats{Tenants: 1, Tools: 1, Toolsets: 1, Assignments: 1}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	result, err := resolveRefund(t, source, "")
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if result.ResolvedVia != "toolset" || result.ToolsetID != "payments" || result.ToolsetRev != "1" {
+		t.Errorf("unexpected resolution %+v", result)
+	}
+
+	tool := result.Tool
+	if tool.Name != "stripe_refund_payment" || tool.RiskProfile.BaseRiskLevel != "HIGH" || !tool.RiskProfile.RequiresApproval {
+		t.Errorf("unexpected tool %+v", tool)
+	}
+	if tool.Constraints.MaxAmount == nil || *tool.Constraints.MaxAmount != 10000000 || !tool.Constraints.RequiresJustification {
+		t.Errorf("unexpected constraints %+v", tool.Constraints)
+	}
+	if len(tool.SchemaHash) != 64 || !strings.Contains(string(tool.ArgsSchema), `"payment_id"`) {
+		t.Errorf("unexpected schema %s (hash %s)", tool.ArgsSchema, tool.SchemaHash)
+	}
+
+	record, err := source.GetToolRecord(context.Background(), "acme", "stripe.refund_payment", "1.0.0")
+	if err != nil || record == nil || record.SchemaHash != tool.SchemaHash || record.RiskLevel != "HIGH" {
+		t.Errorf("unexpected record %+v %v", record, err)
+	}
+	if record, _ := source.GetToolRecord(context.Background(), "globex", "stripe.refund_payment", "1.0.0"); record != nil {
+		t.Error("expected tools to be scoped to their tenant")
+	}
+
+	if err := source.PutJSON(context.Background(), "key", "v"); !errors.Is(err, gitops.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got %v", err)
+	}
+	if err := source.GetJSON(context.Background(), "missing", &types.ToolsetManifest{}); !errors.Is(err, store.ErrBlobNotFound) {
+		t.Errorf("expected ErrBlobNotFound, got %v", err)
+	}
+}
+
+func TestGitOps_ReloadKeepsLastGoodSnapshot(t *testing.T) {
+	dir := newGitOpsTree(t)
+	source, err := gitops.NewSource(gitops.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	good := source.Snapshot()
+	var swaps []*gitops.Snapshot
+	source.OnSwap(func(snap *gitops.Snapshot) { swaps = append(swaps, snap) })
+
+	if swapped, err := source.Reload(); swapped || err != nil {
+		t.Fatalf("expected an unchanged tree to be kept, got %v %v", swapped, err)
+	}
+
+	// A revision referencing a tool that isn't in the tree is rejected
+	writeGitOpsFile(t, dir, "acme/toolsets/payments-2.yaml", strings.ReplaceAll(
+		strings.ReplaceAll(paymentsToolset, `revision: "1"`, `revision: "2"`), "version: 1.0.0", "version: 1.1.0"))
+	writeGitOpsFile(t, dir, "acme/assignments.yaml", strings.ReplaceAll(paymentsAssignments, `revision: "1"`, `revision: "2"`))
+	swapped, err := source.Reload()
+	if swapped || err == nil || !strings.Contains(err.Error(), "acme/toolsets/payments-2.yaml: tool not found: stripe.refund_payment version 1.1.0") {
+		t.Fatalf("expected the update to be rejected, got %v %v", swapped, err)
+	}
+	if source.Snapshot() != good {
+		t.Fatal("expected the last good snapshot to be kept")
+	}
+	if result, err := resolveRefund(t, source, ""); err != nil || result.ToolsetRev != "1" || result.Tool.Version != "1.0.0" {
+		t.Fatalf("expected the last good snapshot to resolve, got %+v %v", result, err)
+	}
+
+	// Adding the tool makes the tree valid, and it is swapped in
+	writeGitOpsFile(t, dir, "acme/tools/stripe/refund-1.1.yaml", strings.ReplaceAll(refundTool, "version: 1.0.0", "version: 1.1.0"))
+	if swapped, err := source.Reload(); !swapped || err != nil {
+		t.Fatalf("expected the fixed tree to be loaded, got %v %v", swapped, err)
+	}
+	if len(swaps) != 1 || swaps[0] != source.Snapshot() {
+		t.Errorf("expected one swap notification, got %d", len(swaps))
+	}
+	if result, err := resolveRefund(t, source, ""); err != nil || result.ToolsetRev != "2" || result.Tool.Version != "1.1.0" {
+		t.Fatalf("expected the new assignment to resolve, got %+v %v", result, err)
+	}
+}
+
+func TestGitOps_InvalidTrees(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			"invalid YAML",
+			map[string]string{"acme/tools/bad.yaml": "name: [unterminated"},
+			"acme/tools/bad.yaml: invalid YAML",
+		},
+		{
+			"tool without invarity block",
+			map[string]string{"acme/tools/bare.yaml": "name: bare\nparameters:\n  type: object\n"},
+			"acme/tools/bare.yaml: tool_id is required",
+		},
+		{
+			"tool ID with a key separator",
+			map[string]string{"acme/tools/hash.yaml": strings.ReplaceAll(refundTool, "id: stripe.refund_payment", "id: stripe#refund_payment")},
+			"acme/tools/hash.yaml: tool_id must not contain '#'",
+		},
+		{
+			"unquoted numeric version",
+			map[string]string{"acme/tools/float.yaml": strings.ReplaceAll(refundTool, "version: 1.0.0", "version: 1.0")},
+			"acme/tools/float.yaml: version is required",
+		},
+		{
+			"invalid risk level",
+			map[string]string{"acme/tools/risk.yaml": strings.ReplaceAll(refundTool, "base_risk: high", "base_risk: extreme")},
+			"base_risk_level must be one of",
+		},
+		{
+			"duplicate tool version",
+			map[string]string{"acme/tools/copy.json": `{"name": "stripe_refund_payment", "parameters": {"type": "object"}, "invarity": {"id": "stripe.refund_payment", "version": "1.0.0"}}`},
+			"tool stripe.refund_payment version 1.0.0 is defined more than once",
+		},
+		{
+			"assignment to a missing toolset",
+			map[string]string{"acme/assignments.yaml": strings.ReplaceAll(paymentsAssignments, "toolset_id: payments", "toolset_id: support")},
+			"acme/assignments.yaml: toolset not found: support revision 1",
+		},
+		{
+			"principal assigned in two tenants",
+			map[string]string{
+				"globex/tools/refund.yaml": refundTool,
+				"globex/toolsets/pay.yaml": paymentsToolset,
+				"globex/assignments.yaml":  paymentsAssignments,
+			},
+			"principal agent-billing is also assigned in tenant acme",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := newGitOpsTree(t)
+			for rel, content := range tt.files {
+				writeGitOpsFile(t, dir, rel, content)
+			}
+			_, err := gitops.Load(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("expected error containing %q, got %v", tt.want, err)
+			}
+			if _, err := gitops.NewSource(gitops.Config{Dir: dir}); err == nil {
+				t.Error("expected NewSource to fail on an invalid tree")
+			}
+		})
+	}
+}
+
+// manifestParityFile lists the CLI's example manifests and what parsing them
+// must give. The CLI's validate package tests the same file, so the two
+// parsers cannot drift apart unnoticed.
+const manifestParityFile = "testdata/manifest_parity.json"
+
+type manifestParity struct {
+	ExamplesDir string `json:"examples_dir"` // Relative to the parity file
+	Tools       []struct {
+		File      string `json:"file"`
+		Name      string `json:"name"`
+		ID        string `json:"id"`
+		Version   string `json:"version"`
+		RawSHA256 string `json:"raw_sha256"` // Of the parsed document marshalled as JSON
+	} `json:"tools"`
+	Toolsets []struct {
+		File        string            `json:"file"`
+		ToolsetID   string            `json:"toolset_id"`
+		Revision    string            `json:"revision"`
+		DisplayName string            `json:"display_name"`
+		Tools       []gitops.ToolRef  `json:"tools"`
+		Labels      map[string]string `json:"labels"`
+		RawSHA256   string            `json:"raw_sha256"`
+	} `json:"toolsets"`
+}
+
+func rawSHA256(t *testing.T, raw map[string]any) string {
+	t.Helper()
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatalf("marshal parsed document: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestGitOps_ParsesCLIExamplesLikeCLI(t *testing.T) {
+	data, err := os.ReadFile(manifestParityFile)
+	if err != nil {
+		t.Fatalf("read parity file: %v", err)
+	}
+	var parity manifestParity
+	if err := json.Unmarshal(data, &parity); err != nil {
+		t.Fatalf("parse parity file: %v", err)
+	}
+	dir := filepath.Join(filepath.Dir(manifestParityFile), parity.ExamplesDir)
+
+	for _, want := range parity.Tools {
+		tool, err := gitops.ParseToolWithMetadata(filepath.Join(dir, want.File))
+		if err != nil {
+			t.Errorf("%s: %v", want.File, err)
+			continue
+		}
+		if tool.Name != want.Name || tool.ID != want.ID || tool.Version != want.Version {
+			t.Errorf("%s: parsed %s %s@%s, want %s %s@%s", want.File, tool.Name, tool.ID, tool.Version, want.Name, want.ID, want.Version)
+		}
+		if got := rawSHA256(t, tool.Raw); got != want.RawSHA256 {
+			t.Errorf("%s: parsed document hash %s, want %s", want.File, got, want.RawSHA256)
+		}
+		if _, err := tool.Manifest(); err != nil {
+			t.Errorf("%s: manifest: %v", want.File, err)
+		}
+	}
+
+	for _, want := range parity.Toolsets {
+		toolset, err := gitops.ParseToolsetWithMetadata(filepath.Join(dir, want.File))
+		if err != nil {
+			t.Errorf("%s: %v", want.File, err)
+			continue
+		}
+		if toolset.ToolsetID != want.ToolsetID || toolset.Revision != want.Revision || toolset.DisplayName != want.DisplayName {
+			t.Errorf("%s: parsed %s@%s %q", want.File, toolset.ToolsetID, toolset.Revision, toolset.DisplayName)
+		}
+		if !reflect.DeepEqual(toolset.Tools, want.Tools) || !reflect.DeepEqual(toolset.Labels, want.Labels) {
+			t.Errorf("%s: parsed tools %v labels %v", want.File, toolset.Tools, toolset.Labels)
+		}
+		if got := rawSHA256(t, toolset.Raw); got != want.RawSHA256 {
+			t.Errorf("%s: parsed document hash %s, want %s", want.File, got, want.RawSHA256)
+		}
+		if _, err := toolset.Manifest(); err != nil {
+			t.Errorf("%s: manifest: %v", want.File, err)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/auth"
+	"invarity/internal/config"
+	"invarity/internal/health"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/types"
+)
+
+// countingChecker fails while failing is set and counts its calls.
+type countingChecker struct {
+	calls   atomic.Int64
+	failing atomic.Bool
+}
+
+func (c *countingChecker) Check(ctx context.Context) error {
+	c.calls.Add(1)
+	if c.failing.Load() {
+		return errors.New("unreachable")
+	}
+	return nil
+}
+
+func TestHealth_RequiredAndOptionalFailures(t *testing.T) {
+	store, model := &countingChecker{}, &countingChecker{}
+	registry := health.NewRegistry()
+	registry.Register(health.Dependency{Name: "audit_store", Checker: store, Required: true, CacheTTL: time.Nanosecond})
+	registry.Register(health.Dependency{Name: "alignment_llm", Checker: model, CacheTTL: time.Nanosecond})
+
+	status, checks := registry.Check(context.Background())
+	if status != health.StatusOK || checks["audit_store"].Status != health.CheckOK || !checks["audit_store"].Required {
+		t.Fatalf("expected ok, got %s %+v", status, checks)
+	}
+
+	model.failing.Store(true)
+	status, checks = registry.Check(context.Background())
+	if status != health.StatusDegraded {
+		t.Errorf("expected an optional failure to degrade, got %s", status)
+	}
+	if checks["alignment_llm"].Status != health.CheckFail || checks["alignment_llm"].Error != "unreachable" {
+		t.Errorf("expected the failure to be reported, got %+v", checks["alignment_llm"])
+	}
+
+	store.failing.Store(true)
+	if status, _ := registry.Check(context.Background()); status != health.StatusNotReady {
+		t.Errorf("expected a required failure to make the firewall not ready, got %s", status)
+	}
+}
+
+func TestHealth_CachesResults(t *testing.T) {
+	checker := &countingChecker{}
+	registry := health.NewRegistry()
+	registry.Register(health.Dependency{Name: "dynamodb", Checker: checker, CacheTTL: 50 * time.Millisecond})
+
+	_, first := registry.Check(context.Background())
+	checker.failing.Store(true)
+	_, second := registry.Check(context.Background())
+	if checker.calls.Load() != 1 {
+		t.Fatalf("expected the cached result to be reused, got %d calls", checker.calls.Load())
+	}
+	if first["dynamodb"].Cached || !second["dynamodb"].Cached || second["dynamodb"].Status != health.CheckOK {
+		t.Errorf("expected the second result to be the cached success, got %+v then %+v", first["dynamodb"], second["dynamodb"])
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	_, third := registry.Check(context.Background())
+	if checker.calls.Load() != 2 || third["dynamodb"].Status != health.CheckFail {
+		t.Errorf("expected a fresh failing check once the cache expired, got %d calls and %+v", checker.calls.Load(), third["dynamodb"])
+	}
+}
+
+func TestHealth_ConcurrentProbesShareOneCheck(t *testing.T) {
+	var calls atomic.Int64
+	registry := health.NewRegistry()
+	registry.Register(health.Dependency{Name: "s3", Checker: health.CheckerFunc(func(ctx context.Context) error {
+		calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			registry.Check(context.Background())
+		}()
+	}
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("expected one check for concurrent probes, got %d", calls.Load())
+	}
+}
+
+func TestHealth_TimeoutPerDependency(t *testing.T) {
+	registry := health.NewRegistry()
+	registry.Register(health.Dependency{Name: "jwks", Timeout: 20 * time.Millisecond, Checker: health.CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second) // Ignores its context
+		return nil
+	})})
+	registry.Register(health.Dependency{Name: "s3", Timeout: time.Second, Checker: health.CheckerFunc(func(ctx context.Context) error {
+		return nil
+	})})
+
+	start := time.Now()
+	status, checks := registry.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the slow check to be abandoned at its timeout, took %s", elapsed)
+	}
+	if status != health.StatusDegraded || !strings.Contains(checks["jwks"].Error, "timed out") {
+		t.Errorf("expected jwks to time out, got %s %+v", status, checks["jwks"])
+	}
+	if checks["s3"].Status != health.CheckOK {
+		t.Errorf("expected s3 to pass, got %+v", checks["s3"])
+	}
+}
+
+func TestHealth_ParsePolicy(t *testing.T) {
+	policy, err := health.ParsePolicy("audit_store, dynamodb", "alignment_llm=5s, threat_llm=750ms", 2*time.Second, 10*time.Second)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	dep := policy.Dependency("alignment_llm", nil)
+	if dep.Timeout != 5*time.Second || dep.CacheTTL != 10*time.Second || dep.Required {
+		t.Errorf("unexpected alignment_llm dependency: %+v", dep)
+	}
+	if dep := policy.Dependency("dynamodb", nil); dep.Timeout != 2*time.Second || !dep.Required {
+		t.Errorf("unexpected dynamodb dependency: %+v", dep)
+	}
+
+	all, _ := health.ParsePolicy("*", "", time.Second, time.Second)
+	if !all.Dependency("jwks", nil).Required {
+		t.Error("expected * to require every dependency")
+	}
+
+	for _, timeouts := range []string{"alignment_llm", "alignment_llm=fast", "alignment_llm=-1s"} {
+		if _, err := health.ParsePolicy("", timeouts, time.Second, time.Second); err == nil {
+			t.Errorf("expected an error for %q", timeouts)
+		}
+	}
+}
+
+func TestHealth_ReadyzReportsChecks(t *testing.T) {
+	failing := &countingChecker{}
+	registry := health.NewRegistry()
+	registry.Register(health.Dependency{Name: "audit_store", Checker: &countingChecker{}, Required: true, CacheTTL: time.Nanosecond})
+	registry.Register(health.Dependency{Name: "threat_llm", Checker: failing, CacheTTL: time.Nanosecond})
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{Logger: zap.NewNop(), Health: registry})
+
+	readyz := func() (int, types.HealthResponse) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var resp types.HealthResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid readyz body: %v", err)
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := readyz()
+	if code != http.StatusOK || resp.Status != health.StatusOK || len(resp.Checks) != 2 {
+		t.Errorf("expected ready with 2 checks, got %d %+v", code, resp)
+	}
+
+	failing.failing.Store(true)
+	code, resp = readyz()
+	if code != http.StatusOK || resp.Status != health.StatusDegraded {
+		t.Errorf("expected a degraded but ready firewall, got %d %s", code, resp.Status)
+	}
+	if resp.Checks["threat_llm"].Status != health.CheckFail || resp.Checks["threat_llm"].Required {
+		t.Errorf("expected the optional failure in the checks, got %+v", resp.Checks["threat_llm"])
+	}
+
+	registry.Register(health.Dependency{Name: "threat_llm", Checker: failing, Required: true})
+	if code, resp = readyz(); code != http.StatusServiceUnavailable || resp.Status != health.StatusNotReady {
+		t.Errorf("expected 503 not_ready, got %d %s", code, resp.Status)
+	}
+}
+
+func TestHealth_LLMProbe(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.NotFound(w, r)
+			return
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(`{"object":"list","data":[{"id":"functiongemma"},{"id":"llama-guard-3"}]}`))
+	}))
+	defer server.Close()
+
+	probe := func(model string) error {
+		return llm.NewClient(llm.ClientConfig{BaseURL: server.URL + "/v1", APIKey: "secret", Model: model}).Probe(context.Background())
+	}
+	if err := probe("functiongemma"); err != nil {
+		t.Errorf("expected a served model to pass: %v", err)
+	}
+	if err := probe("qwen"); err == nil || !strings.Contains(err.Error(), "not served") {
+		t.Errorf("expected a missing model to fail, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if err := probe("functiongemma"); err == nil {
+		t.Error("expected an unavailable endpoint to fail")
+	}
+}
+
+func TestHealth_JWKSPing(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	keys := []auth.JWK{{
+		Kty: "RSA", Kid: "k1", Use: "sig", Alg: "RS256",
+		N: base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(auth.JWKS{Keys: keys})
+	}))
+	defer server.Close()
+
+	cache := auth.NewJWKSCache(server.URL, time.Hour)
+	if err := cache.Ping(context.Background()); err != nil {
+		t.Errorf("expected the key set to pass: %v", err)
+	}
+	keys = nil
+	if err := cache.Ping(context.Background()); err == nil {
+		t.Error("expected a key set without signing keys to fail")
+	}
+	if err := auth.NewJWKSCache("http://127.0.0.1:1", time.Hour).Ping(context.Background()); err == nil {
+		t.Error("expected an unreachable key set to fail")
+	}
+}
+
+func TestHealth_AuditStorePing(t *testing.T) {
+	if err := audit.Ping(context.Background(), audit.NewInMemoryStore()); err != nil {
+		t.Errorf("expected the in-memory store to be writable: %v", err)
+	}
+
+	backend := newFlakyAuditStore()
+	started, release := backend.holdWrites()
+	store := audit.NewAsyncStore(audit.NewChainedStore(backend, audit.ChainConfig{}), audit.AsyncConfig{BufferSize: 1, BatchSize: 1, FlushInterval: time.Hour})
+	if err := audit.Ping(context.Background(), store); err != nil {
+		t.Errorf("expected an empty queue to be writable: %v", err)
+	}
+
+	// One record is held in the store and one fills the queue
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	<-started
+	store.Write(context.Background(), &types.AuditRecord{OrgID: "acme"})
+	if err := audit.Ping(context.Background(), store); err != nil {
+		t.Errorf("expected a full queue to leave the store usable: %v", err)
+	}
+	if err := store.PingQueue(context.Background()); !errors.Is(err, audit.ErrBufferFull) {
+		t.Errorf("expected a full queue to be reported, got %v", err)
+	}
+
+	// With the default policy a full queue only degrades readiness
+	policy, err := health.ParsePolicy(config.DefaultConfig().HealthRequired, "", time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("parse policy: %v", err)
+	}
+	registry := health.NewRegistry()
+	registry.Register(policy.Dependency("audit_store", health.CheckerFunc(func(ctx context.Context) error {
+		return audit.Ping(ctx, store)
+	})))
+	registry.Register(policy.Dependency("audit_queue", health.CheckerFunc(store.PingQueue)))
+	if status, checks := registry.Check(context.Background()); status != health.StatusDegraded {
+		t.Errorf("status = %s, want degraded; checks %+v", status, checks)
+	}
+
+	release()
+	store.Close(context.Background())
+	if err := audit.Ping(context.Background(), store); err == nil {
+		t.Error("expected a closed store to fail")
+	}
+}
@@ -0,0 +1,244 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/metrics"
+	"invarity/internal/registry"
+	"invarity/internal/types"
+)
+
+// scrape returns the metrics in the Prometheus exposition format.
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	return rec.Body.String()
+}
+
+// safeVotePipeline builds a pipeline over the default tools whose voters all
+// vote SAFE, with the threat sentinel off. Options adjust its config.
+func safeVotePipeline(opts ...func(*firewall.PipelineConfig)) *firewall.Pipeline {
+	alignment := llm.NewMockClient()
+	alignment.SetResponse("*", llm.NewMockResponse(safeVote))
+	cfg := config.DefaultConfig()
+	cfg.EnableThreatSentinel = false
+	pipelineCfg := firewall.PipelineConfig{
+		Config:          cfg,
+		Logger:          zap.NewNop(),
+		RegistryStore:   registry.NewInMemoryStoreWithDefaults(),
+		AuditStore:      audit.NewInMemoryStore(),
+		AlignmentClient: alignment,
+		ThreatClient:    llm.NewMockClient(),
+	}
+	for _, opt := range opts {
+		opt(&pipelineCfg)
+	}
+	return firewall.NewPipeline(pipelineCfg)
+}
+
+func withMetrics(m *metrics.Metrics) func(*firewall.PipelineConfig) {
+	return func(cfg *firewall.PipelineConfig) { cfg.Metrics = m }
+}
+
+func withAuditStore(store audit.Store) func(*firewall.PipelineConfig) {
+	return func(cfg *firewall.PipelineConfig) { cfg.AuditStore = store }
+}
+
+func TestMetrics_CountsDecisions(t *testing.T) {
+	m := metrics.New(metrics.Config{})
+	p := safeVotePipeline(withMetrics(m))
+
+	resp, err := p.Evaluate(context.Background(), replayRequest("send_email", types.EnvProduction,
+		`{"to":["a@example.com"],"subject":"Hi","body":"Hello"}`))
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	if _, err := p.Evaluate(context.Background(), replayRequest("no_such_tool", types.EnvProduction, `{}`)); err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+
+	body := scrape(t, m)
+	want := `invarity_decisions_total{decision="` + string(resp.Decision) + `",risk_tier="` + string(resp.RiskTier) + `",tenant="acme",tool="send_email"} 1`
+	if !strings.Contains(body, want) {
+		t.Errorf("expected %q in:\n%s", want, body)
+	}
+	if !strings.Contains(body, `tenant="acme",tool="unregistered"} 1`) {
+		t.Errorf("expected the unresolved tool to be labelled unregistered:\n%s", body)
+	}
+	if !strings.Contains(body, `invarity_stage_duration_seconds_count{stage="S0_CANONICALIZE"} 2`) {
+		t.Errorf("expected both requests to be canonicalized:\n%s", body)
+	}
+	if !strings.Contains(body, `invarity_decision_duration_seconds_count{decision=`) {
+		t.Errorf("expected decision latency:\n%s", body)
+	}
+	if !strings.Contains(body, "go_goroutines") {
+		t.Errorf("expected the Go runtime collector")
+	}
+}
+
+func TestMetrics_CountsVotes(t *testing.T) {
+	m := metrics.New(metrics.Config{})
+	m.CountVotes(&types.IntentAlignmentResult{Voters: []types.IntentVoterResult{
+		{VoterID: "v1", Vote: types.IntentVoteSafe},
+		{VoterID: "v2", Vote: types.IntentVoteAbstain, Reasons: []string{"voter_error"}},
+	}})
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`invarity_intent_votes_total{vote="SAFE",voter="v1"} 1`,
+		`invarity_intent_votes_total{vote="ABSTAIN",voter="v2"} 1`,
+		`invarity_intent_voter_errors_total{voter="v2"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `invarity_intent_voter_errors_total{voter="v1"}`) {
+		t.Error("expected no error for a voter that answered")
+	}
+}
+
+func TestMetrics_TenantCardinalityLimit(t *testing.T) {
+	m := metrics.New(metrics.Config{MaxTenants: 2})
+	for _, tenant := range []string{"a", "b", "c", "d", "a"} {
+		m.CountDecision(tenant, "send_email", types.DecisionAllow, types.RiskTierLow, time.Millisecond)
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`tenant="a",tool="send_email"} 2`,
+		`tenant="b",tool="send_email"} 1`,
+		`tenant="other",tool="other"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `tenant="c"`) {
+		t.Error("expected tenants past the limit to be labelled other")
+	}
+}
+
+func TestMetrics_AuditWriteFailures(t *testing.T) {
+	m := metrics.New(metrics.Config{})
+	backend := newFlakyAuditStore()
+	started, release := backend.holdWrites()
+	async := audit.NewAsyncStore(backend, audit.AsyncConfig{
+		BufferSize:    1,
+		BatchSize:     1,
+		FlushInterval: time.Hour,
+		Overflow:      audit.OverflowFailClosed,
+	})
+	m.RegisterAudit(async, nil)
+	p := safeVotePipeline(withMetrics(m), withAuditStore(async))
+
+	// One record is held in the store, one fills the queue, the third is rejected
+	req := replayRequest("send_email", types.EnvProduction, `{"to":["a@example.com"],"subject":"Hi","body":"Hello"}`)
+	if _, err := p.Evaluate(context.Background(), req); err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	<-started
+	if _, err := p.Evaluate(context.Background(), req); err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	if _, err := p.Evaluate(context.Background(), req); !errors.Is(err, audit.ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+
+	body := scrape(t, m)
+	release()
+	async.Close(context.Background())
+	for _, want := range []string{
+		"invarity_audit_queue_depth 1",
+		`invarity_audit_records_total{outcome="rejected"} 1`,
+		`invarity_audit_write_failures_total{reason="buffer_full"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_InstrumentTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	m := metrics.New(metrics.Config{})
+	client := &http.Client{Transport: m.InstrumentTransport("alignment", nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if _, err := client.Get("http://127.0.0.1:1"); err == nil {
+		t.Fatal("expected the request to an unused port to fail")
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`invarity_llm_request_duration_seconds_count{client="alignment",code="429"} 1`,
+		`invarity_llm_request_duration_seconds_count{client="alignment",code="error"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetrics_NilRecordsNothing(t *testing.T) {
+	var m *metrics.Metrics
+	m.CountDecision("acme", "send_email", types.DecisionAllow, types.RiskTierLow, time.Millisecond)
+	m.CountAuditWriteFailure("error")
+	m.RegisterAudit(nil, nil)
+	if m.InstrumentTransport("alignment", nil) != http.DefaultTransport {
+		t.Error("expected the base transport when metrics are disabled")
+	}
+
+	enabled := metrics.New(metrics.Config{})
+	enabled.CountAuditWriteFailure("error")
+	if n := testutil.CollectAndCount(enabled.Registry(), "invarity_audit_write_failures_total"); n != 1 {
+		t.Errorf("expected 1 series, got %d", n)
+	}
+}
+
+func TestMetrics_NotServedOnAPIPort(t *testing.T) {
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{Logger: zap.NewNop()})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404 on the API router", rec.Code)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.MetricsEnabled = true
+	cfg.MetricsPort = cfg.Port
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected metrics on the API port to be rejected")
+	}
+	cfg.MetricsPort = cfg.Port + 1
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a separate metrics port to validate, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/replay"
+	"invarity/internal/types"
+)
+
+const safeVote = `{"vote":"SAFE","confidence":0.95,"reasons":[]}`
+
+// recordTraffic evaluates requests through a live pipeline and returns its
+// dependencies, so replays run against the same configuration.
+func recordTraffic(t *testing.T, reqs ...*types.ToolCallRequest) (firewall.PipelineConfig, *audit.InMemoryStore, *llm.MockClient) {
+	t.Helper()
+	store := audit.NewInMemoryStore()
+	alignment := llm.NewMockClient()
+	alignment.SetResponse("*", llm.NewMockResponse(safeVote))
+	cfg := config.DefaultConfig()
+	cfg.EnableThreatSentinel = false
+
+	pipelineConfig := firewall.PipelineConfig{
+		Config:          cfg,
+		Version:         "1.2.3-test",
+		Logger:          zap.NewNop(),
+		RegistryStore:   registry.NewInMemoryStoreWithDefaults(),
+		AuditStore:      store,
+		AlignmentClient: alignment,
+		ThreatClient:    llm.NewMockClient(),
+	}
+	p := firewall.NewPipeline(pipelineConfig)
+	for _, req := range reqs {
+		if _, err := p.Evaluate(context.Background(), req); err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+	}
+	return pipelineConfig, store, alignment
+}
+
+func replayRequest(actionID string, env types.Environment, args string) *types.ToolCallRequest {
+	return &types.ToolCallRequest{
+		OrgID:       "acme",
+		Actor:       types.Actor{ID: "agent-1", Role: "assistant", Type: "agent"},
+		Environment: env,
+		UserIntent:  "Do the task the user asked for",
+		ToolCall:    types.ToolCall{ActionID: actionID, Args: json.RawMessage(args)},
+	}
+}
+
+func sendEmailManifest(constraints types.ToolConstraintsV3) *types.ToolManifestV3 {
+	return &types.ToolManifestV3{
+		SchemaVersion: "3",
+		ToolID:        "send_email",
+		Version:       "1.1.0",
+		Name:          "Send Email",
+		ArgsSchema:    json.RawMessage(`{"type":"object","properties":{"to":{"type":"array"},"subject":{"type":"string"},"body":{"type":"string"}},"required":["to","subject","body"]}`),
+		Constraints:   constraints,
+		RiskProfile:   types.RiskProfileV3{BaseRiskLevel: "LOW"},
+	}
+}
+
+func auditCount(t *testing.T, store audit.Store) int {
+	t.Helper()
+	records, err := store.List(context.Background(), &audit.ListFilter{OrgID: "acme", Limit: 1000})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	return len(records)
+}
+
+const emailArgs = `{"to":["cfo@acme.example"],"subject":"Q3","body":"Attached."}`
+
+func TestReplay_CandidateToolsWithRecordedOutputs(t *testing.T) {
+	pipelineConfig, store, alignment := recordTraffic(t,
+		replayRequest("send_email", types.EnvProduction, emailArgs),
+		replayRequest("send_email", types.EnvStaging, emailArgs),
+		replayRequest("read_file", types.EnvProduction, `{"path":"/reports/q3.pdf"}`),
+	)
+	recordedCalls := len(alignment.Requests())
+	recorded := auditCount(t, store)
+
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+	report, err := engine.Run(context.Background(), &replay.Spec{
+		TenantID: "acme",
+		Candidate: replay.Candidate{
+			// The candidate blocks email from production and drops read_file
+			Tools: []*types.ToolManifestV3{sendEmailManifest(types.ToolConstraintsV3{DeniedEnvs: []string{"production"}})},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	if report.Evaluated != 3 || report.Changed != 2 || report.Incomplete != 0 {
+		t.Fatalf("expected 3 evaluated, 2 changed, 0 incomplete, got %+v", report)
+	}
+	if report.Transitions["ALLOW->DENY"] != 2 {
+		t.Errorf("expected 2 ALLOW->DENY, got %v", report.Transitions)
+	}
+	if len(report.ByTool) != 2 || report.ByTool[0].Changed != 1 || report.ByTool[1].Changed != 1 {
+		t.Errorf("expected one change per tool, got %+v", report.ByTool)
+	}
+	reasons := make(map[string]int)
+	for _, r := range report.ByReason {
+		if r.Change == "added" {
+			reasons[r.Reason] = r.Count
+		}
+	}
+	if reasons["environment_denied:production"] != 1 || reasons["tool not registered: read_file"] != 1 {
+		t.Errorf("expected the candidate's deny reasons, got %+v", report.ByReason)
+	}
+	if len(report.Changes) != 2 {
+		t.Fatalf("expected 2 listed changes, got %d", len(report.Changes))
+	}
+	for _, change := range report.Changes {
+		if change.From != types.DecisionAllow || change.To != types.DecisionDeny || change.AuditID == "" {
+			t.Errorf("unexpected change %+v", change)
+		}
+	}
+
+	// Recorded votes are reused: no model calls and no new audit records
+	if calls := len(alignment.Requests()); calls != recordedCalls {
+		t.Errorf("expected no model calls during replay, got %d", calls-recordedCalls)
+	}
+	if n := auditCount(t, store); n != recorded {
+		t.Errorf("replay wrote audit records: %d -> %d", recorded, n)
+	}
+}
+
+func TestReplay_CandidatePolicyReaggregatesRecordedVotes(t *testing.T) {
+	pipelineConfig, store, _ := recordTraffic(t,
+		replayRequest("read_file", types.EnvProduction, `{"path":"/reports/q3.pdf"}`),
+		replayRequest("read_file", types.EnvProduction, `{"path":"/reports/q4.pdf"}`),
+	)
+
+	// Votes recorded at 0.95 no longer clear a 0.99 threshold
+	minSafe := 0.99
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+	report, err := engine.Run(context.Background(), &replay.Spec{
+		TenantID:  "acme",
+		Candidate: replay.Candidate{Policy: &replay.PolicyOverrides{MinSafeProbability: &minSafe}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if report.Transitions["ALLOW->ESCALATE"] != 2 {
+		t.Fatalf("expected 2 ALLOW->ESCALATE, got %v", report.Transitions)
+	}
+	found := false
+	for _, r := range report.ByReason {
+		if r.Reason == "intent_alignment_escalate" && r.Change == "added" && r.Count == 2 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected intent_alignment_escalate to be added, got %+v", report.ByReason)
+	}
+	if pipelineConfig.Config.MinSafeProbability != config.DefaultConfig().MinSafeProbability {
+		t.Error("candidate policy modified the production config")
+	}
+}
+
+func TestReplay_MissingRecordedOutputIsIncomplete(t *testing.T) {
+	// export_report is not registered, so the original request never reached the voters
+	pipelineConfig, store, alignment := recordTraffic(t,
+		replayRequest("export_report", types.EnvProduction, `{"format":"pdf"}`),
+	)
+	recordedCalls := len(alignment.Requests())
+
+	candidate := &types.ToolManifestV3{
+		SchemaVersion: "3",
+		ToolID:        "export_report",
+		Version:       "1.0.0",
+		Name:          "Export Report",
+		ArgsSchema:    json.RawMessage(`{"type":"object"}`),
+		RiskProfile:   types.RiskProfileV3{BaseRiskLevel: "LOW"},
+	}
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+	report, err := engine.Run(context.Background(), &replay.Spec{
+		TenantID:  "acme",
+		Candidate: replay.Candidate{Tools: []*types.ToolManifestV3{candidate}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+
+	if report.Incomplete != 1 || report.Transitions["DENY->ESCALATE"] != 1 {
+		t.Fatalf("expected one incomplete DENY->ESCALATE, got %+v", report)
+	}
+	if !report.Changes[0].Incomplete {
+		t.Error("expected the change to be marked incomplete")
+	}
+	if calls := len(alignment.Requests()); calls != recordedCalls {
+		t.Errorf("recorded mode called the model %d times", calls-recordedCalls)
+	}
+}
+
+func TestReplay_LiveModeCallsModels(t *testing.T) {
+	pipelineConfig, store, alignment := recordTraffic(t,
+		replayRequest("read_file", types.EnvProduction, `{"path":"/reports/q3.pdf"}`),
+	)
+
+	// The models now deny what they allowed
+	alignment.SetResponse("*", llm.NewMockResponse(`{"vote":"DENY","confidence":0.95,"reasons":["off_task"]}`))
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+
+	recorded, err := engine.Run(context.Background(), &replay.Spec{TenantID: "acme"}, nil)
+	if err != nil {
+		t.Fatalf("recorded replay failed: %v", err)
+	}
+	if recorded.Changed != 0 {
+		t.Errorf("expected recorded replay to match, got %v", recorded.Transitions)
+	}
+
+	live, err := engine.Run(context.Background(), &replay.Spec{TenantID: "acme", LLM: replay.ModeLive}, nil)
+	if err != nil {
+		t.Fatalf("live replay failed: %v", err)
+	}
+	if live.Transitions["ALLOW->DENY"] != 1 {
+		t.Errorf("expected live replay to deny, got %v", live.Transitions)
+	}
+}
+
+func TestReplay_SpecValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		spec replay.Spec
+	}{
+		{"missing tenant", replay.Spec{}},
+		{"bad llm mode", replay.Spec{TenantID: "acme", LLM: "cached"}},
+		{"limit too large", replay.Spec{TenantID: "acme", Limit: replay.MaxRecords + 1}},
+		{"until before since", replay.Spec{TenantID: "acme", Since: time.Now(), Until: time.Now().Add(-time.Hour)}},
+		{"toolset without revision", replay.Spec{TenantID: "acme", Candidate: replay.Candidate{ToolsetID: "ts"}}},
+		{"invalid tool", replay.Spec{TenantID: "acme", Candidate: replay.Candidate{Tools: []*types.ToolManifestV3{{ToolID: "x"}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.spec.Validate(); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+
+	spec := replay.Spec{TenantID: "acme"}
+	if err := spec.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spec.LLM != replay.ModeRecorded || spec.Limit != replay.DefaultMaxRecords {
+		t.Errorf("expected defaults, got %+v", spec)
+	}
+}
+
+func TestReplayJobs_RunInBackground(t *testing.T) {
+	pipelineConfig, store, _ := recordTraffic(t,
+		replayRequest("send_email", types.EnvProduction, emailArgs),
+	)
+	jobs := replay.NewJobs(replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig}), replay.JobsConfig{})
+	defer jobs.Close()
+
+	if _, err := jobs.Submit(replay.Spec{TenantID: "acme", LLM: "cached"}); err == nil {
+		t.Fatal("expected an invalid spec to be rejected")
+	}
+
+	job, err := jobs.Submit(replay.Spec{
+		TenantID: "acme",
+		Candidate: replay.Candidate{
+			Tools: []*types.ToolManifestV3{sendEmailManifest(types.ToolConstraintsV3{DeniedEnvs: []string{"production"}})},
+		},
+	})
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var done *replay.Job
+	for time.Now().Before(deadline) {
+		if current := jobs.Get("acme", job.JobID); current != nil && current.Done() {
+			done = current
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if done == nil {
+		t.Fatal("replay job did not finish")
+	}
+	if done.Status != replay.JobSucceeded || done.Report == nil || done.Report.Changed != 1 {
+		t.Fatalf("expected a succeeded job with one change, got %+v", done)
+	}
+	if done.Replayed != 1 || done.Total != 1 {
+		t.Errorf("expected progress 1/1, got %d/%d", done.Replayed, done.Total)
+	}
+
+	if jobs.Get("globex", job.JobID) != nil {
+		t.Error("another tenant can read the job")
+	}
+	listed := jobs.List("acme")
+	if len(listed) != 1 || listed[0].Report != nil {
+		t.Errorf("expected one listed job without its report, got %+v", listed)
+	}
+}
@@ -0,0 +1,252 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"invarity/internal/firewall"
+	"invarity/internal/store"
+	"invarity/internal/types"
+	"invarity/internal/util"
+)
+
+// countingReader counts a store's reads and can hold them until released.
+type countingReader struct {
+	store.ToolReader
+	reads atomic.Int64
+	gate  chan struct{} // When set, reads block until it is closed
+}
+
+func (c *countingReader) GetPrincipalActiveToolset(ctx context.Context, tenantID, principalID string) (string, string, error) {
+	c.reads.Add(1)
+	if c.gate != nil {
+		<-c.gate
+	}
+	return c.ToolReader.GetPrincipalActiveToolset(ctx, tenantID, principalID)
+}
+
+// newCachedResolverStore registers acme's send_email 1.0.0 in the billing
+// toolset and assigns it to a principal, returning the store, blobs and
+// principal ID.
+func newCachedResolverStore(t *testing.T) (*store.InMemoryStore, *store.InMemoryBlobStore, string) {
+	t.Helper()
+	ctx := context.Background()
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	registerCachedTool(t, s, blobs, "send_email", "1.0.0")
+	registerCachedToolset(t, s, blobs, "1", types.ToolRef{ToolID: "send_email", Version: "1.0.0"})
+	agent, err := s.CreatePrincipal(ctx, "acme", "billing-agent", "agent", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID, "billing", "1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	return s, blobs, agent.PrincipalID
+}
+
+// registerCachedTool registers a version of an acme tool whose args schema
+// differs per version, returning its schema hash.
+func registerCachedTool(t *testing.T, s store.Store, blobs store.BlobStore, toolID, version string) string {
+	t.Helper()
+	ctx := context.Background()
+	schema := json.RawMessage(`{"type":"object","description":"` + toolID + ` ` + version + `"}`)
+	hash, err := util.HashJSON(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := store.ToolManifestKey("acme", toolID, version)
+	if _, err := s.UpsertTool(ctx, "acme", toolID, version, hash, toolID, "LOW", key); err != nil {
+		t.Fatal(err)
+	}
+	manifest := &types.ToolManifestV3{SchemaVersion: "3", ToolID: toolID, Version: version, SchemaHash: hash, Name: toolID, ArgsSchema: schema}
+	if err := blobs.PutJSON(ctx, key, manifest); err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func registerCachedToolset(t *testing.T, s store.Store, blobs store.BlobStore, revision string, refs ...types.ToolRef) {
+	t.Helper()
+	ctx := context.Background()
+	key := store.ToolsetManifestKey("acme", "billing", revision)
+	if _, err := s.RegisterToolset(ctx, "acme", "billing", revision, "Billing", key, "u1", len(refs)); err != nil {
+		t.Fatal(err)
+	}
+	if err := blobs.PutJSON(ctx, key, &types.ToolsetManifest{ToolsetID: "billing", Revision: revision, Tools: refs}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func resolveCached(resolver *firewall.ToolResolver, principalID, toolID string) (*firewall.ResolveToolResult, error) {
+	req := &types.ToolCallRequest{TenantID: "acme", PrincipalID: principalID}
+	req.ToolCall.ActionID = toolID
+	return resolver.ResolveTool(context.Background(), req)
+}
+
+func TestResolverCache_HitsAndNegativeHits(t *testing.T) {
+	s, blobs, principalID := newCachedResolverStore(t)
+	reader := &countingReader{ToolReader: s}
+	cache := firewall.NewResolverCache(firewall.ResolverCacheConfig{})
+	resolver := firewall.NewToolResolver(reader, blobs).WithCache(cache)
+
+	for i := 0; i < 3; i++ {
+		result, err := resolveCached(resolver, principalID, "send_email")
+		if err != nil || result.Tool.Version != "1.0.0" {
+			t.Fatalf("resolve: %+v %v", result, err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := resolveCached(resolver, principalID, "wire_money"); !errors.Is(err, firewall.ErrToolNotFound) {
+			t.Fatalf("expected ErrToolNotFound, got %v", err)
+		}
+	}
+
+	if reads := reader.reads.Load(); reads != 2 {
+		t.Errorf("expected one read per tool, got %d", reads)
+	}
+	stats := cache.Stats()
+	if stats != (firewall.ResolverCacheStats{Hits: 2, NegativeHits: 2, Misses: 2, Entries: 2}) {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestResolverCache_ErrorsAreNotCached(t *testing.T) {
+	s, _, principalID := newCachedResolverStore(t)
+	// Without its manifest the toolset fails to load, which is not a miss
+	reader := &countingReader{ToolReader: s}
+	cache := firewall.NewResolverCache(firewall.ResolverCacheConfig{})
+	resolver := firewall.NewToolResolver(reader, store.NewInMemoryBlobStore()).WithCache(cache)
+
+	for i := 0; i < 2; i++ {
+		_, err := resolveCached(resolver, principalID, "send_email")
+		if err == nil || errors.Is(err, firewall.ErrToolNotFound) {
+			t.Fatalf("expected a load error, got %v", err)
+		}
+	}
+	if reads := reader.reads.Load(); reads != 2 || cache.Stats().Entries != 0 {
+		t.Errorf("expected errors not to be cached, got %d reads and %+v", reads, cache.Stats())
+	}
+}
+
+func TestResolverCache_CollapsesConcurrentMisses(t *testing.T) {
+	s, blobs, principalID := newCachedResolverStore(t)
+	reader := &countingReader{ToolReader: s, gate: make(chan struct{})}
+	cache := firewall.NewResolverCache(firewall.ResolverCacheConfig{})
+	resolver := firewall.NewToolResolver(reader, blobs).WithCache(cache)
+
+	const callers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := resolveCached(resolver, principalID, "send_email")
+			errs <- err
+		}()
+	}
+	// Release the read once every caller has missed
+	deadline := time.Now().Add(5 * time.Second)
+	for cache.Stats().Misses < callers && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(reader.gate)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("resolve: %v", err)
+		}
+	}
+	if reads := reader.reads.Load(); reads != 1 {
+		t.Errorf("expected concurrent misses to share one read, got %d", reads)
+	}
+}
+
+func TestResolverCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	s, blobs, principalID := newCachedResolverStore(t)
+	cache := firewall.NewResolverCache(firewall.ResolverCacheConfig{})
+	resolver := firewall.NewToolResolver(s, blobs).WithCache(cache)
+
+	if _, err := resolveCached(resolver, principalID, "send_email"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveCached(resolver, principalID, "issue_refund"); !errors.Is(err, firewall.ErrToolNotFound) {
+		t.Fatalf("expected ErrToolNotFound, got %v", err)
+	}
+
+	// A new revision with the refund tool is applied; the cache serves the old
+	// assignment until the principal is invalidated
+	registerCachedTool(t, s, blobs, "issue_refund", "1.0.0")
+	registerCachedToolset(t, s, blobs, "2",
+		types.ToolRef{ToolID: "send_email", Version: "1.0.0"},
+		types.ToolRef{ToolID: "issue_refund", Version: "1.0.0"},
+	)
+	if err := s.SetPrincipalActiveToolset(ctx, "acme", principalID, "billing", "2", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := resolveCached(resolver, principalID, "issue_refund"); !errors.Is(err, firewall.ErrToolNotFound) {
+		t.Fatalf("expected the negative entry to be served, got %v", err)
+	}
+
+	cache.InvalidatePrincipal("globex", principalID)
+	if _, err := resolveCached(resolver, principalID, "issue_refund"); !errors.Is(err, firewall.ErrToolNotFound) {
+		t.Fatalf("expected another tenant's invalidation to keep the entry, got %v", err)
+	}
+
+	cache.InvalidatePrincipal("acme", principalID)
+	result, err := resolveCached(resolver, principalID, "issue_refund")
+	if err != nil || result.ToolsetRev != "2" {
+		t.Fatalf("expected the new assignment to resolve, got %+v %v", result, err)
+	}
+
+	cache.InvalidateTool("acme", "send_email")
+	if entries := cache.Stats().Entries; entries != 1 {
+		t.Errorf("expected only the refund tool to stay cached, got %d entries", entries)
+	}
+	cache.Purge()
+	if entries := cache.Stats().Entries; entries != 0 {
+		t.Errorf("expected an empty cache, got %d entries", entries)
+	}
+}
+
+func TestResolverCache_ExpiryAndEviction(t *testing.T) {
+	s, blobs, principalID := newCachedResolverStore(t)
+	reader := &countingReader{ToolReader: s}
+	cache := firewall.NewResolverCache(firewall.ResolverCacheConfig{
+		TTL:         50 * time.Millisecond,
+		NegativeTTL: 50 * time.Millisecond,
+		MaxEntries:  2,
+	})
+	resolver := firewall.NewToolResolver(reader, blobs).WithCache(cache)
+
+	resolveCached(resolver, principalID, "send_email")
+	time.Sleep(100 * time.Millisecond)
+	resolveCached(resolver, principalID, "send_email")
+	if reads := reader.reads.Load(); reads != 2 {
+		t.Errorf("expected an expired entry to be resolved again, got %d reads", reads)
+	}
+
+	for i := 0; i < 3; i++ {
+		resolveCached(resolver, principalID, fmt.Sprintf("unknown_%d", i))
+	}
+	if entries := cache.Stats().Entries; entries != 2 {
+		t.Errorf("expected the cache to be bounded to 2 entries, got %d", entries)
+	}
+
+	// The least recently used entry, send_email, was evicted
+	before := reader.reads.Load()
+	resolveCached(resolver, principalID, "unknown_2")
+	resolveCached(resolver, principalID, "send_email")
+	if reads := reader.reads.Load() - before; reads != 1 {
+		t.Errorf("expected only the evicted entry to be resolved again, got %d reads", reads)
+	}
+}
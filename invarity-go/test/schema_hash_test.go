@@ -0,0 +1,238 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/auth"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	invarityhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/store"
+	"invarity/internal/types"
+	"invarity/internal/util"
+)
+
+// removableBlobs is a blob store whose blobs can be deleted.
+type removableBlobs struct {
+	store.BlobStore
+	mu      sync.Mutex
+	removed map[string]bool
+}
+
+func (b *removableBlobs) remove(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.removed[key] = true
+}
+
+func (b *removableBlobs) GetRaw(ctx context.Context, key string) ([]byte, error) {
+	b.mu.Lock()
+	removed := b.removed[key]
+	b.mu.Unlock()
+	if removed {
+		return nil, store.ErrBlobNotFound
+	}
+	return b.BlobStore.GetRaw(ctx, key)
+}
+
+func (b *removableBlobs) GetJSON(ctx context.Context, key string, v any) error {
+	data, err := b.GetRaw(ctx, key)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// schemaHashPipeline evaluates against acme's send_email 1.0.0, assigned to the
+// returned principal, with the legacy registry's send_email as a fallback.
+func schemaHashPipeline(t *testing.T) (*firewall.Pipeline, *audit.InMemoryStore, *removableBlobs, string, string) {
+	t.Helper()
+	s, inMemory, principalID := newCachedResolverStore(t)
+	blobs := &removableBlobs{BlobStore: inMemory, removed: make(map[string]bool)}
+	record, err := s.GetToolRecord(context.Background(), "acme", "send_email", "1.0.0")
+	if err != nil || record == nil {
+		t.Fatalf("get tool record: %+v %v", record, err)
+	}
+
+	alignment := llm.NewMockClient()
+	alignment.SetResponse("*", llm.NewMockResponse(safeVote))
+	cfg := config.DefaultConfig()
+	cfg.EnableThreatSentinel = false
+	auditStore := audit.NewInMemoryStore()
+	p := firewall.NewPipeline(firewall.PipelineConfig{
+		Config:            cfg,
+		Logger:            zap.NewNop(),
+		RegistryStore:     registry.NewInMemoryStoreWithDefaults(),
+		ControlPlaneStore: s,
+		ManifestStore:     blobs,
+		AuditStore:        auditStore,
+		AlignmentClient:   alignment,
+		ThreatClient:      llm.NewMockClient(),
+	})
+	return p, auditStore, blobs, principalID, record.SchemaHash
+}
+
+func evaluatePinned(t *testing.T, p *firewall.Pipeline, auditStore *audit.InMemoryStore, principalID, schemaHash string) (*types.FirewallDecisionResponse, *types.AuditRecord) {
+	t.Helper()
+	req := replayRequest("send_email", types.EnvProduction, `{}`)
+	req.TenantID = "acme"
+	req.PrincipalID = principalID
+	req.ToolCall.SchemaHash = schemaHash
+	resp, err := p.Evaluate(context.Background(), req)
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	record, err := auditStore.Get(context.Background(), resp.AuditID)
+	if err != nil {
+		t.Fatalf("audit lookup failed: %v", err)
+	}
+	return resp, record
+}
+
+func TestSchemaHash_ExpectedHashReturned(t *testing.T) {
+	p, auditStore, _, principalID, hash := schemaHashPipeline(t)
+
+	for _, pinned := range []string{"", hash} {
+		resp, record := evaluatePinned(t, p, auditStore, principalID, pinned)
+		if resp.ExpectedSchemaHash != hash {
+			t.Errorf("expected schema hash %s, got %q", hash, resp.ExpectedSchemaHash)
+		}
+		if util.StringSliceContains(resp.Reasons, "schema_hash_mismatch") || len(record.Flags) != 0 {
+			t.Errorf("unexpected mismatch for pinned %q: %v %v", pinned, resp.Reasons, record.Flags)
+		}
+	}
+}
+
+func TestSchemaHash_PinnedMismatchDenied(t *testing.T) {
+	p, auditStore, _, principalID, hash := schemaHashPipeline(t)
+
+	resp, record := evaluatePinned(t, p, auditStore, principalID, strings.Repeat("0", 64))
+	if resp.Decision != types.DecisionDeny || !util.StringSliceContains(resp.Reasons, "schema_hash_mismatch") {
+		t.Fatalf("expected a schema hash mismatch deny, got %s %v", resp.Decision, resp.Reasons)
+	}
+	if resp.ExpectedSchemaHash != hash {
+		t.Errorf("expected the resolved hash to be returned, got %q", resp.ExpectedSchemaHash)
+	}
+	if len(record.Flags) != 1 || record.Flags[0] != types.AuditFlagSchemaHashMismatch {
+		t.Errorf("expected the audit record to be flagged, got %v", record.Flags)
+	}
+	if record.PipelineStep != "S1_SCHEMA_VALIDATION" {
+		t.Errorf("expected the decision at S1, got %s", record.PipelineStep)
+	}
+}
+
+func TestSchemaHash_TamperedManifestDenied(t *testing.T) {
+	key := store.ToolManifestKey("acme", "send_email", "1.0.0")
+	for name, tamper := range map[string]func(t *testing.T, blobs *removableBlobs){
+		// The stored args schema is loosened without re-registering the tool
+		"loosened schema": func(t *testing.T, blobs *removableBlobs) {
+			var manifest types.ToolManifestV3
+			if err := blobs.GetJSON(context.Background(), key, &manifest); err != nil {
+				t.Fatal(err)
+			}
+			manifest.ArgsSchema = json.RawMessage(`{"type":"object","additionalProperties":true}`)
+			if err := blobs.PutJSON(context.Background(), key, &manifest); err != nil {
+				t.Fatal(err)
+			}
+		},
+		// Neither a missing nor an unreadable manifest may fall back to the
+		// schema-less record
+		"deleted manifest": func(t *testing.T, blobs *removableBlobs) {
+			blobs.remove(key)
+		},
+		"corrupted manifest": func(t *testing.T, blobs *removableBlobs) {
+			if err := blobs.PutRaw(context.Background(), key, []byte("not json"), "application/json"); err != nil {
+				t.Fatal(err)
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			p, auditStore, blobs, principalID, _ := schemaHashPipeline(t)
+			tamper(t, blobs)
+
+			resp, record := evaluatePinned(t, p, auditStore, principalID, "")
+			if resp.Decision != types.DecisionDeny || !util.StringSliceContains(resp.Reasons, "tool_manifest_integrity_failure") {
+				t.Fatalf("expected an integrity deny, got %s %v", resp.Decision, resp.Reasons)
+			}
+			if util.StringSliceContains(resp.Reasons, "resolved_via_legacy_registry") || resp.ExpectedSchemaHash != "" {
+				t.Errorf("expected no legacy fallback, got %v %q", resp.Reasons, resp.ExpectedSchemaHash)
+			}
+			if len(record.Flags) != 1 || record.Flags[0] != types.AuditFlagManifestIntegrity {
+				t.Errorf("expected the audit record to be flagged, got %v", record.Flags)
+			}
+		})
+	}
+}
+
+func TestSchemaHash_RegistrationRejectsMismatch(t *testing.T) {
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	handler := invarityhttp.NewToolsHandler(s, blobs, nil, zap.NewNop())
+	router := chi.NewRouter()
+	router.Post("/v1/tenants/{tenant_id}/tools", handler.HandleRegisterTool)
+
+	register := func(schemaHash string) *httptest.ResponseRecorder {
+		t.Helper()
+		manifest := sendEmailManifest(types.ToolConstraintsV3{})
+		manifest.SchemaHash = schemaHash
+		body, _ := json.Marshal(manifest)
+		req := httptest.NewRequest(http.MethodPost, "/v1/tenants/acme/tools", strings.NewReader(string(body)))
+		req = req.WithContext(auth.WithAuthContext(req.Context(), &auth.AuthContext{ActorType: auth.ActorTypeUser, UserID: "u1"}))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := register(strings.Repeat("0", 64))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "SCHEMA_HASH_MISMATCH") {
+		t.Fatalf("expected a schema hash mismatch, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	hash, _ := util.HashJSON(sendEmailManifest(types.ToolConstraintsV3{}).ArgsSchema)
+	for _, schemaHash := range []string{"", hash} {
+		if rec := register(schemaHash); rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("expected registration with schema hash %q to succeed, got %d %s", schemaHash, rec.Code, rec.Body.String())
+		}
+	}
+	record, err := s.GetToolRecord(context.Background(), "acme", "send_email", "1.1.0")
+	if err != nil || record == nil || record.SchemaHash != hash {
+		t.Errorf("expected the computed schema hash to be stored, got %+v %v", record, err)
+	}
+}
+
+// Tools registered before registration computed the schema hash may carry a
+// client-supplied hash that is not their args schema's. They fail the
+// integrity check until registered again as a new version.
+func TestSchemaHash_PreexistingMismatchedRecordDenied(t *testing.T) {
+	ctx := context.Background()
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+
+	bogus := strings.Repeat("a", 64)
+	key := store.ToolManifestKey("acme", "send_email", "1.0.0")
+	if _, err := s.UpsertTool(ctx, "acme", "send_email", "1.0.0", bogus, "send_email", "LOW", key); err != nil {
+		t.Fatal(err)
+	}
+	manifest := sendEmailManifest(types.ToolConstraintsV3{})
+	manifest.Version = "1.0.0"
+	manifest.SchemaHash = bogus
+	if err := blobs.PutJSON(ctx, key, manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	resolver := firewall.NewToolResolver(s, blobs)
+	if _, err := resolver.LoadTool(ctx, "acme", "send_email", "1.0.0"); !errors.Is(err, firewall.ErrManifestIntegrity) {
+		t.Errorf("expected a legacy mismatched hash to fail the integrity check, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/replay"
+	"invarity/internal/types"
+)
+
+func latestRecord(t *testing.T, store audit.Store) *types.AuditRecord {
+	t.Helper()
+	records, err := store.List(context.Background(), &audit.ListFilter{OrgID: "acme", Limit: 1})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected a record, got %d (%v)", len(records), err)
+	}
+	return records[0]
+}
+
+func TestSnapshot_RecordedWithDecision(t *testing.T) {
+	_, store, _ := recordTraffic(t, replayRequest("send_email", types.EnvProduction, emailArgs))
+
+	snapshot := latestRecord(t, store).Snapshot
+	if snapshot == nil {
+		t.Fatal("expected a decision snapshot")
+	}
+	if snapshot.FirewallVersion != "1.2.3-test" {
+		t.Errorf("expected the build version, got %q", snapshot.FirewallVersion)
+	}
+	tool := snapshot.Tool
+	if tool == nil || tool.ToolID != "send_email" || tool.Version != "1.0.0" || tool.SchemaHash != "abc123" || tool.ResolvedVia != "legacy" {
+		t.Errorf("unexpected tool snapshot %+v", tool)
+	}
+	if len(snapshot.Voters) != 3 {
+		t.Fatalf("expected 3 voters, got %+v", snapshot.Voters)
+	}
+	for _, voter := range snapshot.Voters {
+		if voter.VoterID == "" || voter.PromptVersion == "" {
+			t.Errorf("expected voter ID and prompt version, got %+v", voter)
+		}
+	}
+	if snapshot.Sentinel != nil {
+		t.Errorf("expected no sentinel snapshot with the sentinel disabled, got %+v", snapshot.Sentinel)
+	}
+	defaults := config.DefaultConfig()
+	if snapshot.Config.EnableThreatSentinel || snapshot.Config.QuorumTimeout != types.Duration(defaults.IntentModelTimeout) ||
+		snapshot.Config.MinSafeProbability != defaults.MinSafeProbability {
+		t.Errorf("unexpected config snapshot %+v", snapshot.Config)
+	}
+}
+
+func TestSnapshot_UnresolvedToolHasNoToolSnapshot(t *testing.T) {
+	_, store, _ := recordTraffic(t, replayRequest("export_report", types.EnvProduction, `{"format":"pdf"}`))
+
+	record := latestRecord(t, store)
+	if record.Snapshot == nil || record.Snapshot.Tool != nil {
+		t.Errorf("expected a snapshot without a tool, got %+v", record.Snapshot)
+	}
+}
+
+func TestReproduce_MatchesUnderRecordedConfig(t *testing.T) {
+	pipelineConfig, store, alignment := recordTraffic(t, replayRequest("send_email", types.EnvProduction, emailArgs))
+	record := latestRecord(t, store)
+	recordedCalls := len(alignment.Requests())
+
+	// A threshold changed since the decision; reproduction uses the recorded one
+	changed := *pipelineConfig.Config
+	changed.MinSafeProbability = 0.99
+	pipelineConfig.Config = &changed
+	pipelineConfig.Version = "1.3.0-test"
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+
+	result, err := engine.Reproduce(context.Background(), "acme", record.AuditID)
+	if err != nil {
+		t.Fatalf("reproduce failed: %v", err)
+	}
+	if !result.Reproduced || len(result.Differences) != 0 || result.Incomplete {
+		t.Fatalf("expected the decision to reproduce, got %+v", result)
+	}
+	if result.Rerun.Decision != record.Decision || result.FirewallVersion != "1.3.0-test" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if calls := len(alignment.Requests()); calls != recordedCalls {
+		t.Errorf("reproduction called the model %d times", calls-recordedCalls)
+	}
+}
+
+func TestReproduce_ReportsDifferences(t *testing.T) {
+	pipelineConfig, store, _ := recordTraffic(t, replayRequest("send_email", types.EnvProduction, emailArgs))
+	record := latestRecord(t, store)
+
+	// The recorded outcome was altered after the fact
+	tampered := *record
+	tampered.AuditID = ""
+	tampered.Decision = types.DecisionDeny
+	tampered.Reasons = append([]string{"manual_override"}, record.Reasons...)
+	id, err := store.Write(context.Background(), &tampered)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+	result, err := engine.Reproduce(context.Background(), "acme", id)
+	if err != nil {
+		t.Fatalf("reproduce failed: %v", err)
+	}
+	if result.Reproduced {
+		t.Fatal("expected the altered record not to reproduce")
+	}
+	want := map[string]bool{"decision: DENY -> ALLOW": true, "reason removed: manual_override": true}
+	if len(result.Differences) != len(want) {
+		t.Fatalf("expected %d differences, got %v", len(want), result.Differences)
+	}
+	for _, d := range result.Differences {
+		if !want[d] {
+			t.Errorf("unexpected difference %q", d)
+		}
+	}
+}
+
+func TestReproduce_Errors(t *testing.T) {
+	pipelineConfig, store, _ := recordTraffic(t, replayRequest("send_email", types.EnvProduction, emailArgs))
+	record := latestRecord(t, store)
+	engine := replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig})
+	ctx := context.Background()
+
+	if _, err := engine.Reproduce(ctx, "globex", record.AuditID); !errors.Is(err, audit.ErrNotFound) {
+		t.Errorf("expected another tenant's record to be not found, got %v", err)
+	}
+
+	legacy, err := store.Write(ctx, &types.AuditRecord{
+		OrgID:     "acme",
+		ToolCall:  record.ToolCall,
+		Decision:  types.DecisionAllow,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, err := engine.Reproduce(ctx, "acme", legacy); !errors.Is(err, replay.ErrNoSnapshot) {
+		t.Errorf("expected ErrNoSnapshot, got %v", err)
+	}
+
+	// The manifest was re-registered under the same version with a new schema
+	tool, err := pipelineConfig.RegistryStore.GetTool(ctx, "send_email", "1.0.0")
+	if err != nil {
+		t.Fatalf("get tool failed: %v", err)
+	}
+	changed := *tool
+	changed.SchemaHash = "def456"
+	if err := pipelineConfig.RegistryStore.PutTool(ctx, &changed); err != nil {
+		t.Fatalf("put tool failed: %v", err)
+	}
+	if _, err := engine.Reproduce(ctx, "acme", record.AuditID); !errors.Is(err, replay.ErrArtifactUnavailable) {
+		t.Errorf("expected ErrArtifactUnavailable, got %v", err)
+	}
+}
+
+func TestReproduceAPI(t *testing.T) {
+	pipelineConfig, store, _ := recordTraffic(t, replayRequest("send_email", types.EnvProduction, emailArgs))
+	record := latestRecord(t, store)
+	jobs := replay.NewJobs(replay.NewEngine(replay.EngineConfig{Store: store, Pipeline: pipelineConfig}), replay.JobsConfig{})
+	defer jobs.Close()
+
+	h := invarhttp.NewReplayHandler(jobs, zap.NewNop())
+	api := chi.NewRouter()
+	api.Post("/v1/tenants/{tenant_id}/audit/{audit_id}/reproduce", h.HandleTenantReproduce)
+
+	reproduce := func(tenantID, auditID string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/tenants/"+tenantID+"/audit/"+auditID+"/reproduce", nil))
+		return rec
+	}
+
+	rec := reproduce("acme", record.AuditID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result replay.Reproduction
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !result.Reproduced || result.Snapshot == nil || result.Snapshot.Tool.SchemaHash != "abc123" {
+		t.Errorf("unexpected reproduction %+v", result)
+	}
+
+	if rec := reproduce("globex", record.AuditID); rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for another tenant, got %d", rec.Code)
+	}
+
+	legacy, err := store.Write(context.Background(), &types.AuditRecord{OrgID: "acme", ToolCall: record.ToolCall, CreatedAt: time.Now().UTC()})
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if rec := reproduce("acme", legacy); rec.Code != http.StatusConflict {
+		t.Errorf("expected 409 for a record without a snapshot, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,406 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"invarity/internal/auth"
+	"invarity/internal/firewall"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// controlPlaneBackends returns a fresh store and blob store for each backend.
+// Postgres is included when INVARITY_TEST_POSTGRES_URL is set.
+func controlPlaneBackends(t *testing.T) map[string]func() (store.Store, store.BlobStore) {
+	backends := map[string]func() (store.Store, store.BlobStore){
+		"memory": func() (store.Store, store.BlobStore) {
+			return store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+		},
+		"sqlite": func() (store.Store, store.BlobStore) {
+			s, err := store.NewSQLiteStore(context.Background(), filepath.Join(t.TempDir(), "invarity.db"))
+			if err != nil {
+				t.Fatalf("open sqlite: %v", err)
+			}
+			t.Cleanup(func() { s.Close() })
+			return s, s.Blobs()
+		},
+	}
+	if os.Getenv(postgresTestURLEnv) != "" {
+		backends["postgres"] = func() (store.Store, store.BlobStore) {
+			db := openTestPostgres(t)
+			return store.NewPostgresStore(db), store.NewPostgresBlobStore(db)
+		}
+	}
+	return backends
+}
+
+func TestStore_TenantsUsersMemberships(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := open()
+			if err := s.Ping(ctx); err != nil {
+				t.Fatalf("ping: %v", err)
+			}
+
+			user, err := s.GetOrCreateUser(ctx, "u1", "u1@example.com")
+			if err != nil || user.Email != "u1@example.com" || user.Status != "active" {
+				t.Fatalf("create user: %+v %v", user, err)
+			}
+			if again, _ := s.GetOrCreateUser(ctx, "u1", "other@example.com"); again.Email != "u1@example.com" || !again.CreatedAt.Equal(user.CreatedAt) {
+				t.Errorf("expected the existing user, got %+v", again)
+			}
+			if missing, err := s.GetUser(ctx, "nobody"); missing != nil || err != nil {
+				t.Errorf("expected nil for a missing user, got %+v %v", missing, err)
+			}
+
+			acme, err := s.CreateTenant(ctx, "Acme", "u1")
+			if err != nil {
+				t.Fatalf("create tenant: %v", err)
+			}
+			globex, _ := s.CreateTenant(ctx, "Globex", "u2")
+			if got, _ := s.GetTenant(ctx, acme.TenantID); got == nil || got.Name != "Acme" || got.Plan != "free" {
+				t.Errorf("unexpected tenant: %+v", got)
+			}
+			if plan, _ := s.TenantPlan(ctx, "missing"); plan != "" {
+				t.Errorf("expected no plan for a missing tenant, got %q", plan)
+			}
+
+			if _, err := s.CreateMembership(ctx, acme.TenantID, "u1", auth.RoleOwner, "u1"); err != nil {
+				t.Fatalf("create membership: %v", err)
+			}
+			if _, err := s.CreateMembership(ctx, acme.TenantID, "u1", auth.RoleOwner, "u1"); err == nil {
+				t.Error("expected a duplicate membership to fail")
+			}
+			s.CreateMembership(ctx, globex.TenantID, "u1", auth.RoleViewer, "u2")
+
+			m, err := s.GetMembership(ctx, acme.TenantID, "u1")
+			if err != nil || m == nil || m.Role != auth.RoleOwner || m.Status != "active" {
+				t.Errorf("unexpected membership: %+v %v", m, err)
+			}
+			if m, _ := s.GetMembership(ctx, acme.TenantID, "u2"); m != nil {
+				t.Errorf("expected no membership, got %+v", m)
+			}
+
+			tenants, _ := s.ListTenantsForUser(ctx, "u1")
+			if len(tenants) != 2 || tenants[0].Name != "Acme" || tenants[1].Role != auth.RoleViewer {
+				t.Errorf("unexpected tenants: %+v", tenants)
+			}
+			if owned, _ := s.GetUserOwnedTenant(ctx, "u1"); owned == nil || owned.TenantID != acme.TenantID {
+				t.Errorf("expected u1 to own Acme, got %+v", owned)
+			}
+			if owned, _ := s.GetUserOwnedTenant(ctx, "u2"); owned != nil {
+				t.Errorf("expected u2 to own nothing, got %+v", owned)
+			}
+		})
+	}
+}
+
+func TestStore_PrincipalsAndAssignments(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := open()
+
+			agent, err := s.CreatePrincipal(ctx, "acme", "billing-agent", "agent", "u1")
+			if err != nil {
+				t.Fatalf("create principal: %v", err)
+			}
+			s.CreatePrincipal(ctx, "globex", "other-agent", "agent", "u2")
+			if got, _ := s.GetPrincipal(ctx, "acme", agent.PrincipalID); got == nil || got.Name != "billing-agent" || got.TenantID != "acme" {
+				t.Errorf("unexpected principal: %+v", got)
+			}
+			if principals, _ := s.ListPrincipals(ctx, "acme"); len(principals) != 1 || principals[0].PrincipalID != agent.PrincipalID {
+				t.Errorf("expected only acme's principal, got %+v", principals)
+			}
+
+			if id, rev, err := s.GetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID); id != "" || rev != "" || err != nil {
+				t.Errorf("expected no toolset, got %q %q %v", id, rev, err)
+			}
+			if err := s.SetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID, "billing", "2", "u1"); err != nil {
+				t.Fatalf("assign toolset: %v", err)
+			}
+			if id, rev, _ := s.GetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID); id != "billing" || rev != "2" {
+				t.Errorf("expected billing@2, got %s@%s", id, rev)
+			}
+			if err := s.SetPrincipalActiveToolset(ctx, "globex", agent.PrincipalID, "billing", "3", "u2"); err == nil {
+				t.Error("expected assigning another tenant's principal to fail")
+			}
+			if err := s.SetPrincipalActiveToolset(ctx, "acme", "missing", "billing", "2", "u1"); err == nil {
+				t.Error("expected assigning a missing principal to fail")
+			}
+		})
+	}
+}
+
+func TestStore_Tokens(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := open()
+
+			plaintext, token, err := s.CreateToken(ctx, "acme", "p1", "ci", "agent", "u1", []string{"tools:read"}, nil)
+			if err != nil || !strings.HasPrefix(plaintext, "inv_") || token.KeyPrefix != plaintext[:8] {
+				t.Fatalf("create token: %q %+v %v", plaintext, token, err)
+			}
+			valid, err := s.ValidateToken(ctx, plaintext)
+			if err != nil || valid == nil || valid.TokenID != token.TokenID || len(valid.Scopes) != 1 || valid.Scopes[0] != "tools:read" {
+				t.Fatalf("expected the token to validate, got %+v %v", valid, err)
+			}
+			if valid, _ := s.ValidateToken(ctx, "inv_wrong"); valid != nil {
+				t.Error("expected an unknown token to be rejected")
+			}
+
+			usedAt := time.Now().UTC().Truncate(time.Second)
+			if err := s.TouchTokens(ctx, map[string]time.Time{token.TokenID: usedAt, "missing": usedAt}); err != nil {
+				t.Fatalf("touch: %v", err)
+			}
+			if err := s.TouchTokens(ctx, map[string]time.Time{token.TokenID: usedAt.Add(-time.Hour)}); err != nil {
+				t.Fatalf("touch: %v", err)
+			}
+			if valid, _ := s.ValidateToken(ctx, plaintext); valid == nil || !valid.LastUsedAt.Equal(usedAt) {
+				t.Errorf("expected last used at %s, got %+v", usedAt, valid)
+			}
+
+			got, err := s.GetToken(ctx, "acme", token.TokenID)
+			if err != nil || got == nil || got.KeyPrefix != token.KeyPrefix || got.Name != "ci" {
+				t.Fatalf("get token: %+v %v", got, err)
+			}
+			if got, _ := s.GetToken(ctx, "globex", token.TokenID); got != nil {
+				t.Error("expected another tenant's token not to be found")
+			}
+			if err := s.RevokeToken(ctx, "globex", token.TokenID, "u2"); err == nil {
+				t.Error("expected revoking another tenant's token to fail")
+			}
+
+			// Expiry only moves forward in time
+			soon := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			if err := s.ExpireToken(ctx, "acme", token.TokenID, soon); err != nil {
+				t.Fatalf("expire: %v", err)
+			}
+			if err := s.ExpireToken(ctx, "acme", token.TokenID, soon.Add(time.Hour)); err != nil {
+				t.Fatalf("expire: %v", err)
+			}
+			if got, _ := s.GetToken(ctx, "acme", token.TokenID); got == nil || !got.ExpiresAt.Equal(soon) {
+				t.Errorf("expected expiry %s, got %+v", soon, got)
+			}
+			if err := s.ExpireToken(ctx, "globex", token.TokenID, time.Now()); err == nil {
+				t.Error("expected expiring another tenant's token to fail")
+			}
+
+			if err := s.RevokeToken(ctx, "acme", token.TokenID, "u1"); err != nil {
+				t.Fatalf("revoke: %v", err)
+			}
+			if valid, _ := s.ValidateToken(ctx, plaintext); valid != nil {
+				t.Error("expected a revoked token to be rejected")
+			}
+			if err := s.RevokeToken(ctx, "acme", "missing", "u1"); err == nil {
+				t.Error("expected revoking a missing token to fail")
+			}
+
+			past := time.Now().Add(-time.Minute)
+			expired, _, _ := s.CreateToken(ctx, "acme", "", "old", "developer", "u1", nil, &past)
+			if valid, _ := s.ValidateToken(ctx, expired); valid != nil {
+				t.Error("expected an expired token to be rejected")
+			}
+
+			if _, _, err := s.CreateToken(ctx, "globex", "", "other", "developer", "u2", nil, nil); err != nil {
+				t.Fatal(err)
+			}
+			tokens, err := s.ListTokens(ctx, "acme")
+			if err != nil || len(tokens) != 2 {
+				t.Fatalf("expected acme's two tokens, got %+v %v", tokens, err)
+			}
+			if tokens[0].Name != "old" || tokens[1].Status != "revoked" {
+				t.Errorf("expected newest first with statuses, got %+v", tokens)
+			}
+		})
+	}
+}
+
+func TestStore_ToolsAndToolsets(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := open()
+
+			isNew, err := s.UpsertTool(ctx, "acme", "send_email", "1.0.0", "h1", "Send email", "medium", "k1")
+			if err != nil || !isNew {
+				t.Fatalf("upsert: %v %v", isNew, err)
+			}
+			if isNew, err := s.UpsertTool(ctx, "acme", "send_email", "1.0.0", "h1", "Send email", "medium", "k1"); err != nil || isNew {
+				t.Errorf("expected an idempotent re-register, got %v %v", isNew, err)
+			}
+			if _, err := s.UpsertTool(ctx, "acme", "send_email", "1.0.0", "h2", "Send email", "medium", "k1"); err == nil || !strings.Contains(err.Error(), "conflict") {
+				t.Errorf("expected a conflict for a changed schema, got %v", err)
+			}
+			s.UpsertTool(ctx, "acme", "send_email", "1.1.0", "h3", "Send email", "medium", "k2")
+			s.UpsertTool(ctx, "globex", "send_email", "9.0.0", "h4", "Send email", "low", "k3")
+
+			record, _ := s.GetToolRecord(ctx, "acme", "send_email", "1.0.0")
+			if record == nil || record.SchemaHash != "h1" || record.SK != "send_email#1.0.0" || record.Status != "active" {
+				t.Errorf("unexpected tool record: %+v", record)
+			}
+			if record, _ := s.GetToolRecord(ctx, "globex", "send_email", "1.0.0"); record != nil {
+				t.Error("expected tools to be tenant-scoped")
+			}
+			tools, _ := s.ListTools(ctx, "acme", 0)
+			if len(tools) != 2 || tools[0].Version != "1.1.0" {
+				t.Errorf("expected acme's tools newest first, got %+v", tools)
+			}
+			if tools, _ := s.ListTools(ctx, "acme", 1); len(tools) != 1 {
+				t.Errorf("expected the limit to apply, got %d", len(tools))
+			}
+
+			if isNew, err := s.RegisterToolset(ctx, "acme", "billing", "1", "Billing", "ts1", "u1", 2); err != nil || !isNew {
+				t.Fatalf("register toolset: %v %v", isNew, err)
+			}
+			if isNew, err := s.RegisterToolset(ctx, "acme", "billing", "1", "Billing", "ts1", "u1", 2); err != nil || isNew {
+				t.Errorf("expected an idempotent re-register, got %v %v", isNew, err)
+			}
+			if _, err := s.RegisterToolset(ctx, "acme", "billing", "1", "Billing", "other", "u1", 2); err == nil {
+				t.Error("expected a conflict for a different manifest")
+			}
+			toolset, _ := s.GetToolsetRecord(ctx, "acme", "billing", "1")
+			if toolset == nil || toolset.ToolCount != 2 || toolset.CreatedBy != "u1" {
+				t.Errorf("unexpected toolset: %+v", toolset)
+			}
+			if toolsets, _ := s.ListToolsets(ctx, "globex", 0); len(toolsets) != 0 {
+				t.Errorf("expected no toolsets for globex, got %+v", toolsets)
+			}
+		})
+	}
+}
+
+func TestStore_ConcurrentRegistrationsAgree(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, _ := open()
+
+			// Racing registrations of one version with different hashes: exactly one wins
+			var wg sync.WaitGroup
+			results := make([]error, 8)
+			for i := range results {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, results[i] = s.UpsertTool(ctx, "acme", "send_email", "1.0.0", fmt.Sprintf("hash-%d", i), "Send Email", "low", "k")
+				}(i)
+			}
+			wg.Wait()
+
+			winners := 0
+			for _, err := range results {
+				if err == nil {
+					winners++
+				} else if !strings.Contains(err.Error(), "conflict") {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+			if winners != 1 {
+				t.Errorf("expected one registration to win, got %d", winners)
+			}
+
+			record, err := s.GetToolRecord(ctx, "acme", "send_email", "1.0.0")
+			if err != nil || record == nil {
+				t.Fatalf("get tool: %+v %v", record, err)
+			}
+			if isNew, err := s.UpsertTool(ctx, "acme", "send_email", "1.0.0", record.SchemaHash, "Send Email", "low", "k"); isNew || err != nil {
+				t.Errorf("expected the winning hash to be idempotent, got %v %v", isNew, err)
+			}
+		})
+	}
+}
+
+func TestStore_Blobs(t *testing.T) {
+	dir, err := store.NewDirBlobStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("open dir: %v", err)
+	}
+	blobStores := map[string]store.BlobStore{
+		"memory": store.NewInMemoryBlobStore(),
+		"dir":    dir,
+	}
+	for name, open := range controlPlaneBackends(t) {
+		if name != "memory" {
+			_, blobStores[name] = open()
+		}
+	}
+	for name, blobs := range blobStores {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			if err := blobs.Ping(ctx); err != nil {
+				t.Fatalf("ping: %v", err)
+			}
+
+			key := store.ToolManifestKey("acme", "send_email", "1.0.0")
+			if ok, _ := blobs.Exists(ctx, key); ok {
+				t.Error("expected a missing key not to exist")
+			}
+			var out map[string]string
+			if err := blobs.GetJSON(ctx, key, &out); !errors.Is(err, store.ErrBlobNotFound) {
+				t.Errorf("expected ErrBlobNotFound, got %v", err)
+			}
+
+			if err := blobs.PutJSON(ctx, key, map[string]string{"tool_id": "send_email"}); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if err := blobs.GetJSON(ctx, key, &out); err != nil || out["tool_id"] != "send_email" {
+				t.Errorf("unexpected blob: %v %v", out, err)
+			}
+			if ok, _ := blobs.Exists(ctx, key); !ok {
+				t.Error("expected the key to exist")
+			}
+			if err := blobs.PutRaw(ctx, key, []byte("replaced"), ""); err != nil {
+				t.Fatalf("overwrite: %v", err)
+			}
+			if data, _ := blobs.GetRaw(ctx, key); string(data) != "replaced" {
+				t.Errorf("expected the blob to be replaced, got %q", data)
+			}
+		})
+	}
+
+	if err := dir.PutRaw(context.Background(), "../escape.json", []byte("{}"), ""); err == nil {
+		t.Error("expected a key outside the directory to be rejected")
+	}
+}
+
+func TestStore_ResolveToolWithoutAWS(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, blobs := open()
+
+			hash := registerCachedTool(t, s, blobs, "send_email", "1.0.0")
+
+			toolsetKey := store.ToolsetManifestKey("acme", "billing", "1")
+			s.RegisterToolset(ctx, "acme", "billing", "1", "Billing", toolsetKey, "u1", 1)
+			blobs.PutJSON(ctx, toolsetKey, &types.ToolsetManifest{
+				ToolsetID: "billing",
+				Revision:  "1",
+				Tools:     []types.ToolRef{{ToolID: "send_email", Version: "1.0.0"}},
+			})
+			agent, _ := s.CreatePrincipal(ctx, "acme", "billing-agent", "agent", "u1")
+			s.SetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID, "billing", "1", "u1")
+
+			req := &types.ToolCallRequest{TenantID: "acme", PrincipalID: agent.PrincipalID}
+			req.ToolCall.ActionID = "send_email"
+			result, err := firewall.NewToolResolver(s, blobs).ResolveTool(ctx, req)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if result.ResolvedVia != "toolset" || result.ToolsetID != "billing" || result.Tool.SchemaHash != hash {
+				t.Errorf("unexpected resolution: %+v", result)
+			}
+		})
+	}
+}
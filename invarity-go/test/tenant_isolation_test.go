@@ -0,0 +1,264 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"invarity/internal/auth"
+	"invarity/internal/firewall"
+	invarityhttp "invarity/internal/http"
+	"invarity/internal/store"
+	"invarity/internal/types"
+	"invarity/internal/util"
+)
+
+// isolatedTenants seeds acme with a member, a principal, a tool, a toolset
+// assigned to the principal, and globex with a member and a principal of its
+// own. It returns acme's principal ID and globex's.
+func isolatedTenants(t *testing.T, s store.Store, blobs store.BlobStore) (string, string) {
+	t.Helper()
+	ctx := context.Background()
+	registerCachedTool(t, s, blobs, "send_email", "1.0.0")
+	registerCachedToolset(t, s, blobs, "1", types.ToolRef{ToolID: "send_email", Version: "1.0.0"})
+	acme, err := s.CreatePrincipal(ctx, "acme", "billing-agent", "agent", "u1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPrincipalActiveToolset(ctx, "acme", acme.PrincipalID, "billing", "1", "u1"); err != nil {
+		t.Fatal(err)
+	}
+	globex, err := s.CreatePrincipal(ctx, "globex", "support-agent", "agent", "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateMembership(ctx, "acme", "u1", auth.RoleOwner, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateMembership(ctx, "globex", "u2", auth.RoleOwner, ""); err != nil {
+		t.Fatal(err)
+	}
+	return acme.PrincipalID, globex.PrincipalID
+}
+
+func TestTenantIsolation_Store(t *testing.T) {
+	for name, open := range controlPlaneBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s, blobs := open()
+			acmeAgent, globexAgent := isolatedTenants(t, s, blobs)
+
+			// Reads with globex's tenant ID and acme's IDs find nothing
+			if p, err := s.GetPrincipal(ctx, "globex", acmeAgent); p != nil || err != nil {
+				t.Errorf("GetPrincipal: got %+v %v", p, err)
+			}
+			if id, rev, err := s.GetPrincipalActiveToolset(ctx, "globex", acmeAgent); id != "" || rev != "" || err != nil {
+				t.Errorf("GetPrincipalActiveToolset: got %q %q %v", id, rev, err)
+			}
+			if principals, _ := s.ListPrincipals(ctx, "globex"); len(principals) != 1 || principals[0].PrincipalID != globexAgent {
+				t.Errorf("ListPrincipals: got %+v", principals)
+			}
+			if record, err := s.GetToolRecord(ctx, "globex", "send_email", "1.0.0"); record != nil || err != nil {
+				t.Errorf("GetToolRecord: got %+v %v", record, err)
+			}
+			if versions, _ := s.ListToolVersions(ctx, "globex", "send_email"); len(versions) != 0 {
+				t.Errorf("ListToolVersions: got %+v", versions)
+			}
+			if tools, _ := s.ListTools(ctx, "globex", 10); len(tools) != 0 {
+				t.Errorf("ListTools: got %+v", tools)
+			}
+			if record, err := s.GetToolsetRecord(ctx, "globex", "billing", "1"); record != nil || err != nil {
+				t.Errorf("GetToolsetRecord: got %+v %v", record, err)
+			}
+			if toolsets, _ := s.ListToolsets(ctx, "globex", 10); len(toolsets) != 0 {
+				t.Errorf("ListToolsets: got %+v", toolsets)
+			}
+			if m, err := s.GetMembership(ctx, "globex", "u1"); m != nil || err != nil {
+				t.Errorf("GetMembership: got %+v %v", m, err)
+			}
+
+			// Writes with globex's tenant ID leave acme's records alone
+			if err := s.SetPrincipalActiveToolset(ctx, "globex", acmeAgent, "billing", "2", "u2"); err == nil {
+				t.Error("SetPrincipalActiveToolset: expected another tenant's principal to be rejected")
+			}
+			if err := s.DeprecateTool(ctx, "globex", "send_email", "1.0.0"); err == nil {
+				t.Error("DeprecateTool: expected another tenant's tool to be rejected")
+			}
+			if isNew, err := s.UpsertTool(ctx, "globex", "send_email", "1.0.0", "globex-hash", "Send", "LOW", "k"); !isNew || err != nil {
+				t.Errorf("UpsertTool: expected an independent version, got %v %v", isNew, err)
+			}
+			if isNew, err := s.RegisterToolset(ctx, "globex", "billing", "1", "Billing", "k", "u2", 1); !isNew || err != nil {
+				t.Errorf("RegisterToolset: expected an independent revision, got %v %v", isNew, err)
+			}
+
+			if id, rev, _ := s.GetPrincipalActiveToolset(ctx, "acme", acmeAgent); id != "billing" || rev != "1" {
+				t.Errorf("expected acme's assignment to be kept, got %s@%s", id, rev)
+			}
+			record, _ := s.GetToolRecord(ctx, "acme", "send_email", "1.0.0")
+			if record == nil || record.Status != "active" || record.SchemaHash == "globex-hash" {
+				t.Errorf("expected acme's tool to be kept, got %+v", record)
+			}
+		})
+	}
+}
+
+// suspendedReader reports every principal as suspended.
+type suspendedReader struct {
+	store.ToolReader
+}
+
+func (r suspendedReader) GetPrincipal(ctx context.Context, tenantID, principalID string) (*store.Principal, error) {
+	p, err := r.ToolReader.GetPrincipal(ctx, tenantID, principalID)
+	if p != nil {
+		p.Status = "suspended"
+	}
+	return p, err
+}
+
+func TestTenantIsolation_Resolver(t *testing.T) {
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	acmeAgent, globexAgent := isolatedTenants(t, s, blobs)
+	resolver := firewall.NewToolResolver(s, blobs)
+
+	resolve := func(tenantID, principalID string) (*firewall.ResolveToolResult, error) {
+		req := &types.ToolCallRequest{TenantID: tenantID, PrincipalID: principalID}
+		req.ToolCall.ActionID = "send_email"
+		return resolver.ResolveTool(context.Background(), req)
+	}
+
+	if result, err := resolve("acme", acmeAgent); err != nil || result.ResolvedVia != "toolset" {
+		t.Fatalf("expected acme's principal to resolve, got %+v %v", result, err)
+	}
+	// A principal claimed for the wrong tenant, in either direction, does not resolve
+	if _, err := resolve("globex", acmeAgent); !errors.Is(err, firewall.ErrPrincipalNotFound) {
+		t.Errorf("expected ErrPrincipalNotFound, got %v", err)
+	}
+	if _, err := resolve("acme", globexAgent); !errors.Is(err, firewall.ErrPrincipalNotFound) {
+		t.Errorf("expected ErrPrincipalNotFound, got %v", err)
+	}
+	if _, err := resolve("acme", "unknown-agent"); !errors.Is(err, firewall.ErrPrincipalNotFound) {
+		t.Errorf("expected ErrPrincipalNotFound, got %v", err)
+	}
+	// globex's own principal cannot reach acme's tools
+	if _, err := resolve("globex", globexAgent); !errors.Is(err, firewall.ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+
+	suspended := firewall.NewToolResolver(suspendedReader{s}, blobs)
+	req := &types.ToolCallRequest{TenantID: "acme", PrincipalID: acmeAgent}
+	req.ToolCall.ActionID = "send_email"
+	if _, err := suspended.ResolveTool(context.Background(), req); !errors.Is(err, firewall.ErrPrincipalInactive) {
+		t.Errorf("expected ErrPrincipalInactive, got %v", err)
+	}
+}
+
+func TestTenantIsolation_Evaluate(t *testing.T) {
+	p, _, _, acmeAgent, _ := schemaHashPipeline(t)
+
+	// The legacy registry also defines send_email, but must not stand in for a
+	// principal the tenant does not have
+	for _, tc := range []struct{ tenantID, principalID string }{
+		{"globex", acmeAgent},
+		{"acme", "globex-agent"},
+	} {
+		req := replayRequest("send_email", types.EnvProduction, `{}`)
+		req.TenantID = tc.tenantID
+		req.PrincipalID = tc.principalID
+		resp, err := p.Evaluate(context.Background(), req)
+		if err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+		if resp.Decision != types.DecisionDeny || !util.StringSliceContains(resp.Reasons, "principal_not_found") {
+			t.Errorf("%s/%s: expected a principal_not_found deny, got %s %v", tc.tenantID, tc.principalID, resp.Decision, resp.Reasons)
+		}
+		if util.StringSliceContains(resp.Reasons, "resolved_via_legacy_registry") {
+			t.Errorf("%s/%s: expected no legacy fallback, got %v", tc.tenantID, tc.principalID, resp.Reasons)
+		}
+	}
+}
+
+// isolationRouter serves the tenant-scoped control plane routes behind the
+// membership check, authenticated as userID.
+func isolationRouter(s store.Store, blobs store.BlobStore, userID string) http.Handler {
+	tools := invarityhttp.NewToolsHandler(s, blobs, nil, zap.NewNop())
+	toolsets := invarityhttp.NewToolsetsHandler(s, blobs, nil, zap.NewNop())
+	onboarding := invarityhttp.NewOnboardingHandler(s, zap.NewNop())
+	tenantAuth := auth.NewTenantAuthMiddleware(s)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := auth.WithAuthContext(req.Context(), &auth.AuthContext{ActorType: auth.ActorTypeUser, UserID: userID})
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	r.Route("/v1/tenants/{tenant_id}", func(tenant chi.Router) {
+		tenant.Use(tenantAuth.RequireTenantMembership)
+		tenant.Get("/principals", onboarding.HandleListPrincipals)
+		tenant.Post("/principals/{principal_id}/toolsets:apply", toolsets.HandleApplyToolset)
+		tenant.Get("/tools", tools.HandleListTools)
+		tenant.Get("/tools/{tool_id}/{version}", tools.HandleGetTool)
+		tenant.Get("/toolsets", toolsets.HandleListToolsets)
+		tenant.Post("/toolsets", toolsets.HandleRegisterToolset)
+		tenant.Get("/toolsets/{toolset_id}/{revision}", toolsets.HandleGetToolset)
+	})
+	return r
+}
+
+func TestTenantIsolation_Handlers(t *testing.T) {
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	acmeAgent, globexAgent := isolatedTenants(t, s, blobs)
+	globex := isolationRouter(s, blobs, "u2")
+
+	do := func(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// globex's member cannot use acme's routes at all
+	for _, path := range []string{"/v1/tenants/acme/principals", "/v1/tenants/acme/tools", "/v1/tenants/acme/tools/send_email/1.0.0", "/v1/tenants/acme/toolsets/billing/1"} {
+		if rec := do(globex, http.MethodGet, path, ""); rec.Code != http.StatusForbidden {
+			t.Errorf("GET %s: expected 403, got %d", path, rec.Code)
+		}
+	}
+
+	// Through its own tenant, acme's IDs are not found
+	for _, path := range []string{"/v1/tenants/globex/tools/send_email/1.0.0", "/v1/tenants/globex/toolsets/billing/1"} {
+		if rec := do(globex, http.MethodGet, path, ""); rec.Code != http.StatusNotFound {
+			t.Errorf("GET %s: expected 404, got %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	for _, path := range []string{"/v1/tenants/globex/tools", "/v1/tenants/globex/toolsets", "/v1/tenants/globex/principals"} {
+		rec := do(globex, http.MethodGet, path, "")
+		if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), acmeAgent) || strings.Contains(rec.Body.String(), "send_email") {
+			t.Errorf("GET %s: expected no acme records, got %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	apply := `{"toolset_id":"billing","revision":"1"}`
+	if rec := do(globex, http.MethodPost, "/v1/tenants/globex/principals/"+acmeAgent+"/toolsets:apply", apply); rec.Code != http.StatusNotFound {
+		t.Errorf("apply to acme's principal: expected 404, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(globex, http.MethodPost, "/v1/tenants/globex/principals/"+globexAgent+"/toolsets:apply", apply); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "TOOLSET_NOT_FOUND") {
+		t.Errorf("apply acme's toolset: expected 400 TOOLSET_NOT_FOUND, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	toolset, _ := json.Marshal(types.ToolsetManifest{ToolsetID: "stolen", Revision: "1", Tools: []types.ToolRef{{ToolID: "send_email", Version: "1.0.0"}}})
+	if rec := do(globex, http.MethodPost, "/v1/tenants/globex/toolsets", string(toolset)); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "TOOL_NOT_FOUND") {
+		t.Errorf("toolset of acme's tools: expected 400 TOOL_NOT_FOUND, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if id, rev, _ := s.GetPrincipalActiveToolset(context.Background(), "acme", acmeAgent); id != "billing" || rev != "1" {
+		t.Errorf("expected acme's assignment to be kept, got %s@%s", id, rev)
+	}
+}
@@ -0,0 +1,294 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	invarityhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+// countingTokens counts the token validations that reach the store.
+type countingTokens struct {
+	store.TokenStore
+	validations atomic.Int64
+}
+
+func (c *countingTokens) ValidateToken(ctx context.Context, plaintext string) (*store.Token, error) {
+	c.validations.Add(1)
+	return c.TokenStore.ValidateToken(ctx, plaintext)
+}
+
+type tokenAuthFixture struct {
+	router      http.Handler
+	store       *store.InMemoryStore
+	tokens      *countingTokens
+	auth        *invarityhttp.TokenAuthenticator
+	audit       *audit.InMemoryStore
+	principalID string
+}
+
+// newTokenAuthFixture serves evaluate behind token authentication, for acme's
+// principal assigned send_email 1.0.0.
+func newTokenAuthFixture(t *testing.T, cacheTTL time.Duration) *tokenAuthFixture {
+	t.Helper()
+	s, blobs, principalID := newCachedResolverStore(t)
+
+	alignment := llm.NewMockClient()
+	alignment.SetResponse("*", llm.NewMockResponse(safeVote))
+	cfg := config.DefaultConfig()
+	cfg.EnableThreatSentinel = false
+	auditStore := audit.NewInMemoryStore()
+	p := firewall.NewPipeline(firewall.PipelineConfig{
+		Config:            cfg,
+		Logger:            zap.NewNop(),
+		RegistryStore:     registry.NewInMemoryStoreWithDefaults(),
+		ControlPlaneStore: s,
+		ManifestStore:     blobs,
+		AuditStore:        auditStore,
+		AlignmentClient:   alignment,
+		ThreatClient:      llm.NewMockClient(),
+	})
+
+	tokens := &countingTokens{TokenStore: s}
+	tokenAuth := invarityhttp.NewTokenAuthenticator(invarityhttp.TokenAuthConfig{
+		Store:         tokens,
+		CacheTTL:      cacheTTL,
+		TouchInterval: time.Hour,
+	})
+	t.Cleanup(func() { _ = tokenAuth.Close(context.Background()) })
+
+	router := invarityhttp.NewRouter(invarityhttp.RouterConfig{
+		Logger:    zap.NewNop(),
+		Pipeline:  p,
+		TokenAuth: tokenAuth,
+	})
+	return &tokenAuthFixture{router: router, store: s, tokens: tokens, auth: tokenAuth, audit: auditStore, principalID: principalID}
+}
+
+func (f *tokenAuthFixture) createToken(t *testing.T, principalID string, scopes []string, expiresAt *time.Time) (string, *store.Token) {
+	t.Helper()
+	plaintext, token, err := f.store.CreateToken(context.Background(), "acme", principalID, "ci", "agent", "u1", scopes, expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plaintext, token
+}
+
+// evaluate posts a send_email call; edit may set the body's identity fields.
+func (f *tokenAuthFixture) evaluate(t *testing.T, plaintext string, edit func(*types.ToolCallRequest)) *httptest.ResponseRecorder {
+	t.Helper()
+	req := replayRequest("send_email", types.EnvProduction, `{}`)
+	req.OrgID = ""
+	if edit != nil {
+		edit(req)
+	}
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/v1/firewall/evaluate", strings.NewReader(string(body)))
+	if plaintext != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+plaintext)
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, httpReq)
+	return rec
+}
+
+// auditRecord returns the audit record of a successful evaluation.
+func (f *tokenAuthFixture) auditRecord(t *testing.T, rec *httptest.ResponseRecorder) *types.AuditRecord {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rec.Code, rec.Body.String())
+	}
+	var resp types.FirewallDecisionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	record, err := f.audit.Get(context.Background(), resp.AuditID)
+	if err != nil || record == nil {
+		t.Fatalf("audit lookup: %+v %v", record, err)
+	}
+	return record
+}
+
+func TestTokenAuth_RejectsUnusableTokens(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+
+	noScope, _ := f.createToken(t, f.principalID, []string{"tools:read"}, nil)
+	past := time.Now().Add(-time.Minute)
+	expired, _ := f.createToken(t, f.principalID, []string{"evaluate"}, &past)
+	revoked, token := f.createToken(t, f.principalID, []string{"evaluate"}, nil)
+	if err := f.store.RevokeToken(context.Background(), "acme", token.TokenID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name      string
+		plaintext string
+		status    int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"unknown", "inv_0000000000000000", http.StatusUnauthorized},
+		{"expired", expired, http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+		{"without evaluate scope", noScope, http.StatusForbidden},
+	} {
+		if rec := f.evaluate(t, tc.plaintext, nil); rec.Code != tc.status {
+			t.Errorf("%s token: expected %d, got %d %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+	if records, _ := f.audit.List(context.Background(), &audit.ListFilter{OrgID: "acme"}); len(records) != 0 {
+		t.Errorf("expected rejected calls not to be evaluated, got %d audit records", len(records))
+	}
+}
+
+func TestTokenAuth_BindsTenantAndPrincipal(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+	plaintext, _ := f.createToken(t, f.principalID, []string{"evaluate"}, nil)
+
+	record := f.auditRecord(t, f.evaluate(t, plaintext, nil))
+	if record.TenantID != "acme" || record.OrgID != "acme" || record.PrincipalID != f.principalID {
+		t.Errorf("expected the call evaluated for the token's tenant and principal, got %s/%s/%s", record.OrgID, record.TenantID, record.PrincipalID)
+	}
+
+	// Repeating the token's identity is allowed
+	f.auditRecord(t, f.evaluate(t, plaintext, func(req *types.ToolCallRequest) {
+		req.OrgID, req.TenantID, req.PrincipalID = "acme", "acme", f.principalID
+	}))
+
+	for name, edit := range map[string]func(*types.ToolCallRequest){
+		"tenant_id":    func(req *types.ToolCallRequest) { req.TenantID = "globex" },
+		"org_id":       func(req *types.ToolCallRequest) { req.OrgID = "globex" },
+		"principal_id": func(req *types.ToolCallRequest) { req.PrincipalID = "other-agent" },
+	} {
+		rec := f.evaluate(t, plaintext, edit)
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "IDENTITY_MISMATCH") {
+			t.Errorf("expected a foreign %s to be refused, got %d %s", name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTokenAuth_TenantTokenNamesPrincipal(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+	plaintext, _ := f.createToken(t, "", []string{"evaluate"}, nil)
+
+	record := f.auditRecord(t, f.evaluate(t, plaintext, func(req *types.ToolCallRequest) {
+		req.PrincipalID = f.principalID
+	}))
+	if record.TenantID != "acme" || record.PrincipalID != f.principalID {
+		t.Errorf("expected the body's principal in the token's tenant, got %s/%s", record.TenantID, record.PrincipalID)
+	}
+}
+
+func TestTokenAuth_CachesValidatedTokens(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+	plaintext, token := f.createToken(t, f.principalID, []string{"evaluate"}, nil)
+
+	for i := 0; i < 3; i++ {
+		f.auditRecord(t, f.evaluate(t, plaintext, nil))
+	}
+	if n := f.tokens.validations.Load(); n != 1 {
+		t.Errorf("expected one store validation, got %d", n)
+	}
+
+	// A revocation takes effect once the cached validation expires
+	if err := f.store.RevokeToken(context.Background(), "acme", token.TokenID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	f.auditRecord(t, f.evaluate(t, plaintext, nil))
+
+	uncached := newTokenAuthFixture(t, 0)
+	plaintext, token = uncached.createToken(t, uncached.principalID, []string{"evaluate"}, nil)
+	uncached.auditRecord(t, uncached.evaluate(t, plaintext, nil))
+	if err := uncached.store.RevokeToken(context.Background(), "acme", token.TokenID, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if rec := uncached.evaluate(t, plaintext, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected a revoked token to be refused without a cache, got %d", rec.Code)
+	}
+}
+
+func TestTokenAuth_CacheHonorsExpiry(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+	expiresAt := time.Now().Add(200 * time.Millisecond)
+	plaintext, _ := f.createToken(t, f.principalID, []string{"evaluate"}, &expiresAt)
+
+	f.auditRecord(t, f.evaluate(t, plaintext, nil))
+	time.Sleep(time.Until(expiresAt) + 10*time.Millisecond)
+	if rec := f.evaluate(t, plaintext, nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected an expired token to be refused despite the cache, got %d", rec.Code)
+	}
+}
+
+func TestTokenAuth_RecordsLastUsedInBatches(t *testing.T) {
+	f := newTokenAuthFixture(t, time.Minute)
+	plaintext, _ := f.createToken(t, f.principalID, []string{"evaluate"}, nil)
+	before := time.Now().UTC()
+
+	f.auditRecord(t, f.evaluate(t, plaintext, nil))
+	f.auditRecord(t, f.evaluate(t, plaintext, nil))
+	token, _ := f.store.ValidateToken(context.Background(), plaintext)
+	if !token.LastUsedAt.IsZero() {
+		t.Fatalf("expected last use to wait for the flush, got %s", token.LastUsedAt)
+	}
+
+	if err := f.auth.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	token, _ = f.store.ValidateToken(context.Background(), plaintext)
+	if token.LastUsedAt.Before(before) {
+		t.Errorf("expected last use recorded on close, got %s", token.LastUsedAt)
+	}
+}
+
+func TestTokenAuth_RequiresControlPlane(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.RequireAPIToken = true
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected token auth without the control plane store to be rejected")
+	}
+	cfg.EnableControlPlane = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected token auth with the control plane to validate, got %v", err)
+	}
+}
+
+func TestTokenAuth_RequiredByDefaultOutsideDevelopment(t *testing.T) {
+	cases := []struct {
+		env, require string
+		want         bool
+	}{
+		{"", "", false},
+		{"local", "", false},
+		{"dev", "", false},
+		{"prod", "", true},
+		{"staging", "", true},
+		{"prod", "false", false},
+		{"dev", "true", true},
+	}
+	t.Setenv("INVARITY_ENABLE_CONTROL_PLANE", "true")
+	for _, tc := range cases {
+		t.Setenv("INVARITY_ENV", tc.env)
+		t.Setenv("INVARITY_REQUIRE_API_TOKEN", tc.require)
+		cfg, err := config.LoadFromEnv()
+		if err != nil {
+			t.Fatalf("load config: %v", err)
+		}
+		if cfg.RequireAPIToken != tc.want {
+			t.Errorf("INVARITY_ENV=%q INVARITY_REQUIRE_API_TOKEN=%q: RequireAPIToken = %v, want %v", tc.env, tc.require, cfg.RequireAPIToken, tc.want)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"invarity/internal/auth"
+	invarityhttp "invarity/internal/http"
+	"invarity/internal/store"
+)
+
+// tokensRouter serves the token routes behind the membership and scope
+// checks, authenticated as userID.
+func tokensRouter(s store.Store, tokenAuth *invarityhttp.TokenAuthenticator, userID string) http.Handler {
+	h := invarityhttp.NewTokensHandler(s, tokenAuth, zap.NewNop())
+	tenantAuth := auth.NewTenantAuthMiddleware(s)
+
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			ctx := auth.WithAuthContext(req.Context(), &auth.AuthContext{ActorType: auth.ActorTypeUser, UserID: userID})
+			next.ServeHTTP(w, req.WithContext(ctx))
+		})
+	})
+	r.Route("/v1/tenants/{tenant_id}/tokens", func(tokens chi.Router) {
+		tokens.Use(tenantAuth.RequireTenantMembership)
+		tokens.With(auth.RequireScope(auth.ScopeTokensRead)).Get("/", h.HandleListTokens)
+		tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/", h.HandleCreateToken)
+		tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/{token_id}/rotate", h.HandleRotateToken)
+		tokens.With(auth.RequireScope(auth.ScopeTokensWrite)).Post("/{token_id}/revoke", h.HandleRevokeToken)
+	})
+	return r
+}
+
+// tokensFixture seeds acme and globex as isolatedTenants does, with acme's u3
+// an admin and u4 a developer.
+func tokensFixture(t *testing.T) (*store.InMemoryStore, string, string) {
+	t.Helper()
+	s := store.NewInMemoryStore()
+	acmeAgent, globexAgent := isolatedTenants(t, s, store.NewInMemoryBlobStore())
+	if _, err := s.CreateMembership(context.Background(), "acme", "u3", auth.RoleAdmin, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateMembership(context.Background(), "acme", "u4", auth.RoleDeveloper, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	return s, acmeAgent, globexAgent
+}
+
+func doTokens(t *testing.T, router http.Handler, method, path, body string, out any) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if out != nil && rec.Code < 300 {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec
+}
+
+func TestTokens_CreateListRevoke(t *testing.T) {
+	s, acmeAgent, _ := tokensFixture(t)
+	owner := tokensRouter(s, nil, "u1")
+	ctx := context.Background()
+
+	var created invarityhttp.CreateTokenResponse
+	rec := doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens", `{"name":"billing","principal_id":"`+acmeAgent+`"}`, &created)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	if created.Type != "agent" || created.PrincipalID != acmeAgent || len(created.Scopes) != 1 || created.Scopes[0] != "evaluate" || created.CreatedBy != "u1" {
+		t.Errorf("expected an agent token with the evaluate scope, got %+v", created.TokenInfo)
+	}
+	if token, _ := s.ValidateToken(ctx, created.Token); token == nil || token.TokenID != created.TokenID {
+		t.Fatalf("expected the returned plaintext to validate, got %+v", token)
+	}
+
+	var list invarityhttp.ListTokensResponse
+	rec = doTokens(t, owner, http.MethodGet, "/v1/tenants/acme/tokens", "", &list)
+	if rec.Code != http.StatusOK || len(list.Tokens) != 1 || list.Tokens[0].KeyPrefix == "" || !strings.HasPrefix(created.Token, list.Tokens[0].KeyPrefix) {
+		t.Fatalf("list: expected the token by prefix, got %d %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), created.Token) {
+		t.Error("expected the list not to include the plaintext")
+	}
+
+	var revoked invarityhttp.TokenInfo
+	rec = doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+created.TokenID+"/revoke", "", &revoked)
+	if rec.Code != http.StatusOK || revoked.Status != "revoked" || revoked.RevokedBy != "u1" || revoked.RevokedAt == nil {
+		t.Fatalf("revoke: got %d %s", rec.Code, rec.Body.String())
+	}
+	if token, _ := s.ValidateToken(ctx, created.Token); token != nil {
+		t.Error("expected a revoked token not to validate")
+	}
+	if rec := doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+created.TokenID+"/revoke", "", nil); rec.Code != http.StatusOK {
+		t.Errorf("revoking twice: expected 200, got %d", rec.Code)
+	}
+}
+
+func TestTokens_RejectsEscalation(t *testing.T) {
+	s, acmeAgent, globexAgent := tokensFixture(t)
+	admin := tokensRouter(s, nil, "u3")
+
+	for _, tc := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"missing name", `{"scopes":["evaluate"]}`, http.StatusBadRequest},
+		{"unknown scope", `{"name":"x","scopes":["root"]}`, http.StatusBadRequest},
+		{"scope the role lacks", `{"name":"x","scopes":["tenant:delete"]}`, http.StatusBadRequest},
+		{"agent token with a control plane scope", `{"name":"x","principal_id":"` + acmeAgent + `","scopes":["evaluate","tools:read"]}`, http.StatusBadRequest},
+		{"agent token without a principal", `{"name":"x","type":"agent"}`, http.StatusBadRequest},
+		{"developer token with a principal", `{"name":"x","type":"developer","principal_id":"` + acmeAgent + `"}`, http.StatusBadRequest},
+		{"another tenant's principal", `{"name":"x","principal_id":"` + globexAgent + `"}`, http.StatusNotFound},
+		{"past expiry", `{"name":"x","expires_at":"2020-01-01T00:00:00Z"}`, http.StatusBadRequest},
+	} {
+		if rec := doTokens(t, admin, http.MethodPost, "/v1/tenants/acme/tokens", tc.body, nil); rec.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	// Scopes the creator's role holds can be granted
+	if rec := doTokens(t, admin, http.MethodPost, "/v1/tenants/acme/tokens", `{"name":"ci","scopes":["tools:write","toolsets:write"]}`, nil); rec.Code != http.StatusCreated {
+		t.Errorf("expected an admin to grant tools:write, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Developers cannot manage tokens, and cannot rotate an owner's token to obtain it
+	developer := tokensRouter(s, nil, "u4")
+	if rec := doTokens(t, developer, http.MethodPost, "/v1/tenants/acme/tokens", `{"name":"x"}`, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("developer create: expected 403, got %d", rec.Code)
+	}
+	_, ownerToken, err := s.CreateToken(context.Background(), "acme", "", "owner", "developer", "u1", []string{"tenant:delete"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := doTokens(t, admin, http.MethodPost, "/v1/tenants/acme/tokens/"+ownerToken.TokenID+"/rotate", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("admin rotating an owner's token: expected 403, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTokens_RotateWithOverlap(t *testing.T) {
+	s, acmeAgent, _ := tokensFixture(t)
+	owner := tokensRouter(s, nil, "u1")
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(48 * time.Hour)
+	oldPlaintext, old, err := s.CreateToken(ctx, "acme", acmeAgent, "billing", "agent", "u1", []string{"evaluate"}, &expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rotated invarityhttp.RotateTokenResponse
+	rec := doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+old.TokenID+"/rotate", `{"overlap_seconds":600}`, &rotated)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("rotate: expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rotated.TokenID == old.TokenID || rotated.Name != "billing" || rotated.PrincipalID != acmeAgent || rotated.ExpiresAt == nil || rotated.ExpiresAt.Before(time.Now().Add(47*time.Hour)) {
+		t.Errorf("expected a replacement with the old token's identity and lifetime, got %+v", rotated.TokenInfo)
+	}
+	if at := rotated.Previous.ExpiresAt; rotated.Previous.TokenID != old.TokenID || at == nil || at.Before(time.Now().Add(9*time.Minute)) || at.After(time.Now().Add(11*time.Minute)) {
+		t.Errorf("expected the old token to expire after the overlap, got %+v", rotated.Previous)
+	}
+
+	// Both tokens work during the overlap
+	if token, _ := s.ValidateToken(ctx, oldPlaintext); token == nil {
+		t.Error("expected the old token to work during the overlap")
+	}
+	if token, _ := s.ValidateToken(ctx, rotated.Token); token == nil {
+		t.Error("expected the new token to work")
+	}
+
+	// Without an overlap the replaced token stops working at once
+	rec = doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+rotated.TokenID+"/rotate", `{"overlap_seconds":0}`, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("rotate without overlap: expected 201, got %d %s", rec.Code, rec.Body.String())
+	}
+	if token, _ := s.ValidateToken(ctx, rotated.Token); token != nil {
+		t.Error("expected the replaced token to stop working without an overlap")
+	}
+
+	for name, body := range map[string]string{
+		"negative overlap":  `{"overlap_seconds":-1}`,
+		"too long overlap":  `{"overlap_seconds":2592001}`,
+		"expiry in overlap": `{"overlap_seconds":3600,"expires_at":"` + time.Now().Add(time.Minute).UTC().Format(time.RFC3339) + `"}`,
+	} {
+		if rec := doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+old.TokenID+"/rotate", body, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+rotated.TokenID+"/rotate", "", nil); rec.Code != http.StatusConflict {
+		t.Errorf("rotating a revoked token: expected 409, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTokens_RevokeTakesEffectAtOnce(t *testing.T) {
+	s, acmeAgent, _ := tokensFixture(t)
+	tokenAuth := invarityhttp.NewTokenAuthenticator(invarityhttp.TokenAuthConfig{Store: s, CacheTTL: time.Hour})
+	t.Cleanup(func() { _ = tokenAuth.Close(context.Background()) })
+	owner := tokensRouter(s, tokenAuth, "u1")
+	protected := tokenAuth.Require(auth.ScopeEvaluate)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	call := func(plaintext string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/firewall/evaluate", nil)
+		req.Header.Set("Authorization", "Bearer "+plaintext)
+		rec := httptest.NewRecorder()
+		protected.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	var created invarityhttp.CreateTokenResponse
+	doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens", `{"name":"billing","principal_id":"`+acmeAgent+`"}`, &created)
+	if code := call(created.Token); code != http.StatusOK {
+		t.Fatalf("expected the new token to be accepted, got %d", code)
+	}
+	doTokens(t, owner, http.MethodPost, "/v1/tenants/acme/tokens/"+created.TokenID+"/revoke", "", nil)
+	if code := call(created.Token); code != http.StatusUnauthorized {
+		t.Errorf("expected the revoked token to be refused despite the cache, got %d", code)
+	}
+}
+
+func TestTokens_TenantIsolation(t *testing.T) {
+	s, acmeAgent, _ := tokensFixture(t)
+	_, token, err := s.CreateToken(context.Background(), "acme", acmeAgent, "billing", "agent", "u1", []string{"evaluate"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	globex := tokensRouter(s, nil, "u2")
+
+	if rec := doTokens(t, globex, http.MethodGet, "/v1/tenants/acme/tokens", "", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("listing acme's tokens: expected 403, got %d", rec.Code)
+	}
+	for _, action := range []string{"rotate", "revoke"} {
+		if rec := doTokens(t, globex, http.MethodPost, "/v1/tenants/globex/tokens/"+token.TokenID+"/"+action, "", nil); rec.Code != http.StatusNotFound {
+			t.Errorf("%s acme's token through globex: expected 404, got %d %s", action, rec.Code, rec.Body.String())
+		}
+	}
+	var list invarityhttp.ListTokensResponse
+	if rec := doTokens(t, globex, http.MethodGet, "/v1/tenants/globex/tokens", "", &list); rec.Code != http.StatusOK || len(list.Tokens) != 0 {
+		t.Errorf("expected globex to list no tokens, got %d %s", rec.Code, rec.Body.String())
+	}
+	if got, _ := s.GetToken(context.Background(), "acme", token.TokenID); got == nil || got.Status != "active" {
+		t.Errorf("expected acme's token to be untouched, got %+v", got)
+	}
+}
@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+
+	"invarity/internal/firewall"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/types"
+)
+
+func newTestTracer() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
+
+func withTracerProvider(tp trace.TracerProvider) func(*firewall.PipelineConfig) {
+	return func(cfg *firewall.PipelineConfig) { cfg.TracerProvider = tp }
+}
+
+func spansByName(spans tracetest.SpanStubs) map[string][]tracetest.SpanStub {
+	byName := make(map[string][]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = append(byName[span.Name], span)
+	}
+	return byName
+}
+
+func spanAttr(span tracetest.SpanStub, key string) (attribute.Value, bool) {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracing_SpanPerStageAndVoter(t *testing.T) {
+	tp, exporter := newTestTracer()
+	p := safeVotePipeline(withTracerProvider(tp))
+
+	resp, err := p.Evaluate(context.Background(), replayRequest("send_email", types.EnvProduction,
+		`{"to":["a@example.com"],"subject":"Hi","body":"Hello"}`))
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+
+	spans := spansByName(exporter.GetSpans())
+	roots := spans["firewall.evaluate"]
+	if len(roots) != 1 {
+		t.Fatalf("expected one evaluation span, got %d", len(roots))
+	}
+	root := roots[0]
+	if v, _ := spanAttr(root, "invarity.decision"); v.AsString() != string(resp.Decision) {
+		t.Errorf("expected decision %s on the evaluation span, got %q", resp.Decision, v.AsString())
+	}
+	if v, _ := spanAttr(root, "invarity.audit_id"); v.AsString() != resp.AuditID {
+		t.Errorf("expected audit id %s on the evaluation span, got %q", resp.AuditID, v.AsString())
+	}
+
+	for _, stage := range []string{"S0_CANONICALIZE", "S1_SCHEMA_VALIDATION", "S2_CONSTRAINTS", "S3_INTENT_ALIGNMENT", "S5_AGGREGATE"} {
+		if len(spans[stage]) != 1 {
+			t.Errorf("expected one %s span, got %d", stage, len(spans[stage]))
+			continue
+		}
+		if spans[stage][0].Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("expected %s to be a child of the evaluation span", stage)
+		}
+	}
+	if len(spans["S4_THREAT_SENTINEL"]) != 0 {
+		t.Error("expected no span for a stage that did not run")
+	}
+	if v, _ := spanAttr(spans["S1_SCHEMA_VALIDATION"][0], "invarity.tool_id"); v.AsString() != "send_email" {
+		t.Errorf("expected the resolved tool on the schema validation span, got %q", v.AsString())
+	}
+
+	alignment := spans["S3_INTENT_ALIGNMENT"][0]
+	var voters int
+	for name, stubs := range spans {
+		if !strings.HasPrefix(name, "intent_voter ") {
+			continue
+		}
+		for _, voter := range stubs {
+			voters++
+			if voter.Parent.SpanID() != alignment.SpanContext.SpanID() {
+				t.Errorf("expected %s to be a child of the alignment span", name)
+			}
+			if v, _ := spanAttr(voter, "invarity.vote"); v.AsString() != string(types.IntentVoteSafe) {
+				t.Errorf("expected %s to record a SAFE vote, got %q", name, v.AsString())
+			}
+		}
+	}
+	if voters != 3 {
+		t.Errorf("expected 3 voter spans, got %d", voters)
+	}
+}
+
+func TestTracing_LLMClientSpan(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		json.NewEncoder(w).Encode(map[string]any{
+			"model":   "functiongemma-270m",
+			"choices": []map[string]any{{"message": map[string]string{"role": "assistant", "content": safeVote}}},
+			"usage":   map[string]int{"prompt_tokens": 120, "completion_tokens": 30, "total_tokens": 150},
+		})
+	}))
+	defer server.Close()
+
+	tp, exporter := newTestTracer()
+	client := llm.NewClient(llm.ClientConfig{BaseURL: server.URL, Model: "functiongemma", TracerProvider: tp})
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	if _, err := client.ChatCompletion(ctx, &llm.ChatCompletionRequest{
+		Messages: []llm.ChatMessage{{Role: "user", Content: "hello"}},
+	}); err != nil {
+		t.Fatalf("chat completion failed: %v", err)
+	}
+	parent.End()
+
+	spans := spansByName(exporter.GetSpans())
+	if len(spans["chat functiongemma"]) != 1 {
+		t.Fatalf("expected one LLM span, got %v", spans)
+	}
+	span := spans["chat functiongemma"][0]
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the LLM span to be a child of the caller's span")
+	}
+	if span.SpanKind != trace.SpanKindClient {
+		t.Errorf("expected a client span, got %s", span.SpanKind)
+	}
+	for key, want := range map[string]any{
+		"gen_ai.request.model":       "functiongemma",
+		"gen_ai.response.model":      "functiongemma-270m",
+		"gen_ai.usage.input_tokens":  int64(120),
+		"gen_ai.usage.output_tokens": int64(30),
+		"http.response.status_code":  int64(http.StatusOK),
+	} {
+		v, ok := spanAttr(span, key)
+		if !ok || v.AsInterface() != want {
+			t.Errorf("%s = %v, want %v", key, v.AsInterface(), want)
+		}
+	}
+	if !strings.Contains(traceparent, span.SpanContext.SpanID().String()) {
+		t.Errorf("expected the request's traceparent to name the LLM span, got %q", traceparent)
+	}
+}
+
+func TestTracing_LLMClientErrorSpan(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	tp, exporter := newTestTracer()
+	client := llm.NewClient(llm.ClientConfig{BaseURL: server.URL, Model: "llama-guard-3", TracerProvider: tp})
+	if _, err := client.ChatCompletion(context.Background(), &llm.ChatCompletionRequest{}); err == nil {
+		t.Fatal("expected an error for a 503")
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 || spans[0].Status.Code.String() != "Error" {
+		t.Fatalf("expected one failed span, got %+v", spans)
+	}
+	if v, _ := spanAttr(spans[0], "http.response.status_code"); v.AsInt64() != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 on the span, got %d", v.AsInt64())
+	}
+}
+
+func TestTracing_PropagatesIncomingTraceparent(t *testing.T) {
+	tp, exporter := newTestTracer()
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:         zap.NewNop(),
+		Pipeline:       safeVotePipeline(withTracerProvider(tp)),
+		TracerProvider: tp,
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	const parentID = "00f067aa0ba902b7"
+	body, _ := json.Marshal(replayRequest("send_email", types.EnvProduction,
+		`{"to":["a@example.com"],"subject":"Hi","body":"Hello"}`))
+	req := httptest.NewRequest(http.MethodPost, "/v1/firewall/evaluate", strings.NewReader(string(body)))
+	req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	all := exporter.GetSpans()
+	for _, span := range all {
+		if span.SpanContext.TraceID().String() != traceID {
+			t.Errorf("span %s is in trace %s, want the incoming trace %s", span.Name, span.SpanContext.TraceID(), traceID)
+		}
+	}
+	spans := spansByName(all)
+	servers := spans["POST /v1/firewall/evaluate"]
+	if len(servers) != 1 {
+		t.Fatalf("expected one server span named after the route, got %v", spans)
+	}
+	if servers[0].Parent.SpanID().String() != parentID || !servers[0].Parent.IsRemote() {
+		t.Errorf("expected the server span's parent to be the caller's span %s", parentID)
+	}
+	if len(spans["firewall.evaluate"]) != 1 || spans["firewall.evaluate"][0].Parent.SpanID() != servers[0].SpanContext.SpanID() {
+		t.Error("expected the evaluation span to be a child of the server span")
+	}
+}
+
+func TestTracing_DisabledStillPropagates(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{}"}}]}`))
+	}))
+	defer server.Close()
+
+	incoming := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9},
+		SpanID:     trace.SpanID{0x01},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx := trace.ContextWithRemoteSpanContext(context.Background(), incoming)
+	client := llm.NewClient(llm.ClientConfig{BaseURL: server.URL})
+	if _, err := client.ChatCompletion(ctx, &llm.ChatCompletionRequest{}); err != nil {
+		t.Fatalf("chat completion failed: %v", err)
+	}
+	if !strings.Contains(traceparent, incoming.TraceID().String()) {
+		t.Errorf("expected the caller's trace to be forwarded without a tracer provider, got %q", traceparent)
+	}
+}
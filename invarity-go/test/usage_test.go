@@ -0,0 +1,580 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"go.uber.org/zap"
+
+	"invarity/internal/audit"
+	"invarity/internal/config"
+	"invarity/internal/firewall"
+	invarhttp "invarity/internal/http"
+	"invarity/internal/llm"
+	"invarity/internal/registry"
+	"invarity/internal/store"
+	"invarity/internal/types"
+	"invarity/internal/usage"
+)
+
+// staticPlans resolves tenant plans from a map.
+type staticPlans map[string]string
+
+func (p staticPlans) TenantPlan(ctx context.Context, tenantID string) (string, error) {
+	return p[tenantID], nil
+}
+
+// usageMockClient returns content with the given token counts on every call.
+func usageMockClient(model, content string, prompt, completion int) *llm.MockClient {
+	resp := llm.NewMockResponse(content)
+	resp.Model = model
+	resp.Usage.PromptTokens = prompt
+	resp.Usage.CompletionTokens = completion
+	resp.Usage.TotalTokens = prompt + completion
+
+	client := llm.NewMockClient()
+	client.SetResponse("*", resp)
+	return client
+}
+
+// fakeUsageDynamoDB is an in-memory usage table (PK tenant_id, SK rollup_key).
+// It applies the ADD and SET values of an update by attribute name and returns
+// reads in pages of two.
+type fakeUsageDynamoDB struct {
+	mu    sync.Mutex
+	items map[[2]string]map[string]ddbtypes.AttributeValue
+}
+
+func newFakeUsageDynamoDB() *fakeUsageDynamoDB {
+	return &fakeUsageDynamoDB{items: make(map[[2]string]map[string]ddbtypes.AttributeValue)}
+}
+
+func (f *fakeUsageDynamoDB) UpdateItem(ctx context.Context, in *dynamodb.UpdateItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := [2]string{attrString(in.Key["tenant_id"]), attrString(in.Key["rollup_key"])}
+	item, ok := f.items[key]
+	if !ok {
+		item = map[string]ddbtypes.AttributeValue{"tenant_id": in.Key["tenant_id"], "rollup_key": in.Key["rollup_key"]}
+		f.items[key] = item
+	}
+	for placeholder, name := range in.ExpressionAttributeNames {
+		switch v := in.ExpressionAttributeValues[":"+strings.TrimPrefix(placeholder, "#")].(type) {
+		case *ddbtypes.AttributeValueMemberN:
+			sum, _ := strconv.ParseFloat(v.Value, 64)
+			if old, ok := item[name].(*ddbtypes.AttributeValueMemberN); ok {
+				n, _ := strconv.ParseFloat(old.Value, 64)
+				sum += n
+			}
+			item[name] = &ddbtypes.AttributeValueMemberN{Value: strconv.FormatFloat(sum, 'f', -1, 64)}
+		default:
+			item[name] = v
+		}
+	}
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeUsageDynamoDB) Query(ctx context.Context, in *dynamodb.QueryInput, _ ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	tenant := attrString(in.ExpressionAttributeValues[":tenant"])
+	since, until := attrString(in.ExpressionAttributeValues[":since"]), attrString(in.ExpressionAttributeValues[":until"])
+	items, last := f.page(in.ExclusiveStartKey, func(key [2]string) bool {
+		return key[0] == tenant && key[1] >= since && key[1] <= until
+	})
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+func (f *fakeUsageDynamoDB) Scan(ctx context.Context, in *dynamodb.ScanInput, _ ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	items, last := f.page(in.ExclusiveStartKey, func([2]string) bool { return true })
+	return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+// page returns up to two matching items in key order after the start key.
+func (f *fakeUsageDynamoDB) page(start map[string]ddbtypes.AttributeValue, match func([2]string) bool) ([]map[string]ddbtypes.AttributeValue, map[string]ddbtypes.AttributeValue) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([][2]string, 0)
+	for key := range f.items {
+		if match(key) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i][0]+"|"+keys[i][1] < keys[j][0]+"|"+keys[j][1] })
+
+	after := ""
+	if start != nil {
+		after = attrString(start["tenant_id"]) + "|" + attrString(start["rollup_key"])
+	}
+	items := make([]map[string]ddbtypes.AttributeValue, 0)
+	for _, key := range keys {
+		if key[0]+"|"+key[1] <= after {
+			continue
+		}
+		items = append(items, f.items[key])
+		if len(items) == 2 {
+			return items, map[string]ddbtypes.AttributeValue{"tenant_id": f.items[key]["tenant_id"], "rollup_key": f.items[key]["rollup_key"]}
+		}
+	}
+	return items, nil
+}
+
+func attrString(v ddbtypes.AttributeValue) string {
+	if s, ok := v.(*ddbtypes.AttributeValueMemberS); ok {
+		return s.Value
+	}
+	return ""
+}
+
+func newUsagePipeline(meter *usage.Meter, auditStore audit.Store) *firewall.Pipeline {
+	cfg := config.DefaultConfig()
+	return firewall.NewPipeline(firewall.PipelineConfig{
+		Config:          cfg,
+		Logger:          zap.NewNop(),
+		RegistryStore:   registry.NewInMemoryStoreWithDefaults(),
+		AuditStore:      auditStore,
+		AlignmentClient: usageMockClient("functiongemma", `{"vote":"SAFE","confidence":0.95,"reasons":[]}`, 400, 20),
+		ThreatClient:    usageMockClient("llama-guard-3", `{"label":"CLEAR","threat_types":[],"confidence":0.9}`, 1000, 50),
+		Meter:           meter,
+	})
+}
+
+func usageRequest(tenantID, principalID string) *types.ToolCallRequest {
+	return &types.ToolCallRequest{
+		OrgID:       tenantID,
+		TenantID:    tenantID,
+		PrincipalID: principalID,
+		Actor:       types.Actor{ID: "agent-1", Role: "assistant", Type: "agent"},
+		UserIntent:  "Email the weekly summary to the team",
+		ToolCall: types.ToolCall{
+			ActionID: "send_email",
+			Args:     json.RawMessage(`{"to":["team@corp.example"],"subject":"Weekly summary","body":"See attached."}`),
+		},
+	}
+}
+
+var testPricing = usage.Pricing{
+	"functiongemma": {PromptPerMTok: 1, CompletionPerMTok: 2},
+	"llama-guard-3": {PromptPerMTok: 10, CompletionPerMTok: 20},
+}
+
+func TestUsage_ParseConfig(t *testing.T) {
+	pricing, err := usage.ParsePricing(`{"functiongemma":{"prompt_per_mtok":0.5,"completion_per_mtok":1.5}}`)
+	if err != nil {
+		t.Fatalf("parse pricing: %v", err)
+	}
+	cost := pricing.Cost(&types.TokenUsage{Model: "functiongemma", PromptTokens: 2_000_000, CompletionTokens: 1_000_000})
+	if math.Abs(cost-2.5) > 1e-9 {
+		t.Errorf("cost = %f, want 2.5", cost)
+	}
+	if pricing.Cost(&types.TokenUsage{Model: "unpriced", PromptTokens: 100}) != 0 {
+		t.Errorf("unpriced model should cost nothing")
+	}
+	if _, err := usage.ParsePricing(`not json`); err == nil {
+		t.Errorf("expected error for invalid pricing")
+	}
+
+	quotas, err := usage.ParseQuotas("free=1000, pro=50000")
+	if err != nil || quotas["free"] != 1000 || quotas["pro"] != 50000 {
+		t.Errorf("unexpected quotas %v (err %v)", quotas, err)
+	}
+	for _, bad := range []string{"free", "free=abc", "free=-1"} {
+		if _, err := usage.ParseQuotas(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestUsage_PipelineRecordsAndPricesCalls(t *testing.T) {
+	store := usage.NewInMemoryStore()
+	meter := usage.NewMeter(usage.MeterConfig{Store: store, Pricing: testPricing})
+	auditStore := audit.NewInMemoryStore()
+	p := newUsagePipeline(meter, auditStore)
+
+	resp, err := p.Evaluate(context.Background(), usageRequest("acme", "billing-agent"))
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	if resp.Threat == nil {
+		t.Fatalf("expected threat sentinel to run for send_email")
+	}
+
+	// 3 voters x (400 + 20) tokens + sentinel (1000 + 50)
+	if resp.Usage == nil || resp.Usage.TotalTokens != 3*420+1050 {
+		t.Fatalf("unexpected total usage %+v", resp.Usage)
+	}
+	wantCost := 3*(400*1.0+20*2.0)/1e6 + (1000*10.0+50*20.0)/1e6
+	if math.Abs(resp.Usage.CostUSD-wantCost) > 1e-12 {
+		t.Errorf("cost = %g, want %g", resp.Usage.CostUSD, wantCost)
+	}
+	for _, v := range resp.Alignment.Voters {
+		if v.Usage == nil || v.Usage.Model != "functiongemma" || v.Usage.PromptTokens != 400 || v.Usage.CostUSD == 0 {
+			t.Errorf("voter %s usage not recorded: %+v", v.VoterID, v.Usage)
+		}
+	}
+	if resp.Threat.Usage == nil || resp.Threat.Usage.CompletionTokens != 50 {
+		t.Errorf("threat usage not recorded: %+v", resp.Threat.Usage)
+	}
+
+	record, err := auditStore.Get(context.Background(), resp.AuditID)
+	if err != nil {
+		t.Fatalf("audit lookup failed: %v", err)
+	}
+	if record.Usage == nil || record.Usage.TotalTokens != resp.Usage.TotalTokens || record.PrincipalID != "billing-agent" {
+		t.Errorf("audit record missing usage attribution: %+v", record)
+	}
+
+	// The four model calls roll up by model: three voter calls and the sentinel
+	records, _ := store.List(context.Background(), &usage.Filter{TenantID: "acme"})
+	if len(records) != 2 {
+		t.Fatalf("expected 2 usage rollups (one per model), got %d", len(records))
+	}
+	calls := 0
+	for _, r := range records {
+		if r.PrincipalID != "billing-agent" || r.ToolID != "send_email" {
+			t.Errorf("record not attributed: %+v", r)
+		}
+		calls += r.Calls
+	}
+	if calls != 4 {
+		t.Errorf("expected rollups to cover 4 model calls, got %d", calls)
+	}
+}
+
+func TestUsage_Report(t *testing.T) {
+	meter := usage.NewMeter(usage.MeterConfig{Store: usage.NewInMemoryStore(), Pricing: testPricing})
+	p := newUsagePipeline(meter, audit.NewInMemoryStore())
+	for _, principal := range []string{"billing-agent", "billing-agent", "support-agent"} {
+		if _, err := p.Evaluate(context.Background(), usageRequest("acme", principal)); err != nil {
+			t.Fatalf("evaluate failed: %v", err)
+		}
+	}
+	if _, err := p.Evaluate(context.Background(), usageRequest("globex", "ops-agent")); err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+
+	report, err := meter.Report(context.Background(), "acme", "", usage.GroupByPrincipal)
+	if err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	if len(report.Groups) != 2 || report.Groups[0].Key != "billing-agent" || report.Groups[0].Calls != 8 {
+		t.Errorf("unexpected principal groups %+v", report.Groups)
+	}
+	if report.Total.TotalTokens != 3*(3*420+1050) {
+		t.Errorf("total tokens = %d, want %d", report.Total.TotalTokens, 3*(3*420+1050))
+	}
+
+	byModel, err := meter.Report(context.Background(), "", "", usage.GroupByModel)
+	if err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	if len(byModel.Groups) != 2 || byModel.Groups[0].Key != "llama-guard-3" {
+		t.Errorf("unexpected model groups %+v", byModel.Groups)
+	}
+
+	if _, err := meter.Report(context.Background(), "acme", "2026-13", ""); err == nil {
+		t.Errorf("expected error for invalid period")
+	}
+	if _, err := meter.Report(context.Background(), "acme", "", "color"); err == nil {
+		t.Errorf("expected error for invalid group_by")
+	}
+}
+
+func TestUsage_QuotaByPlan(t *testing.T) {
+	meter := usage.NewMeter(usage.MeterConfig{
+		Store:       usage.NewInMemoryStore(),
+		Quotas:      usage.Quotas{"free": 2000},
+		Plans:       staticPlans{"bigco": "enterprise"},
+		DefaultPlan: "free",
+	})
+	auditStore := audit.NewInMemoryStore()
+	p := newUsagePipeline(meter, auditStore)
+
+	// The first call is under quota and uses 2310 tokens; the next is rejected
+	if _, err := p.Evaluate(context.Background(), usageRequest("acme", "agent")); err != nil {
+		t.Fatalf("first evaluate failed: %v", err)
+	}
+	_, err := p.Evaluate(context.Background(), usageRequest("acme", "agent"))
+	var quotaErr *usage.QuotaExceededError
+	if !errors.As(err, &quotaErr) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	if quotaErr.Plan != "free" || quotaErr.Limit != 2000 || quotaErr.Used != 2310 {
+		t.Errorf("unexpected quota error %+v", quotaErr)
+	}
+
+	// The rejected request is audited as a deny
+	record, err := auditStore.Get(context.Background(), quotaErr.AuditID)
+	if err != nil {
+		t.Fatalf("quota denial not audited: %v", err)
+	}
+	if record.Decision != types.DecisionDeny || record.PipelineStep != "S2_QUOTA" || !slices.Contains(record.Reasons, "token_quota_exceeded") {
+		t.Errorf("unexpected quota denial record %+v", record)
+	}
+
+	// Plans without a quota are unlimited
+	for i := 0; i < 3; i++ {
+		if _, err := p.Evaluate(context.Background(), usageRequest("bigco", "agent")); err != nil {
+			t.Fatalf("enterprise evaluate %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestUsage_QuotaFollowsControlPlaneTenantPlan(t *testing.T) {
+	// Control plane tenants are created on the free plan; unknown tenants use the default
+	tenants := store.NewInMemoryStore()
+	tenant, err := tenants.CreateTenant(context.Background(), "acme", "user-1")
+	if err != nil {
+		t.Fatalf("create tenant: %v", err)
+	}
+	meter := usage.NewMeter(usage.MeterConfig{
+		Store:       usage.NewInMemoryStore(),
+		Quotas:      usage.Quotas{"free": 2000},
+		Plans:       tenants,
+		DefaultPlan: "enterprise",
+	})
+	p := newUsagePipeline(meter, audit.NewInMemoryStore())
+
+	if _, err := p.Evaluate(context.Background(), usageRequest(tenant.TenantID, "agent")); err != nil {
+		t.Fatalf("first evaluate failed: %v", err)
+	}
+	_, err = p.Evaluate(context.Background(), usageRequest(tenant.TenantID, "agent"))
+	var quotaErr *usage.QuotaExceededError
+	if !errors.As(err, &quotaErr) || quotaErr.Plan != "free" {
+		t.Fatalf("expected free plan quota error, got %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := p.Evaluate(context.Background(), usageRequest("unknown", "agent")); err != nil {
+			t.Fatalf("default plan evaluate %d failed: %v", i, err)
+		}
+	}
+}
+
+func TestUsage_QuotasRequirePerReplicaAcknowledgement(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.TokenQuotas = "free=1000000"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected quotas without the per-replica acknowledgement to be rejected")
+	}
+	cfg.TokenQuotasPerReplica = true
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected acknowledged quotas to validate, got %v", err)
+	}
+
+	// A persistent control plane store holds usage shared by every replica
+	cfg.TokenQuotasPerReplica = false
+	cfg.EnableControlPlane = true
+	cfg.StoreBackend = "sqlite"
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected quotas on a shared usage store to validate, got %v", err)
+	}
+	cfg.StoreBackend = "memory"
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected quotas on the memory backend to need the acknowledgement")
+	}
+}
+
+// usageBackends returns a constructor for each usage store. Constructors called
+// again return another handle on the same storage, as another replica would.
+func usageBackends(t *testing.T) map[string]func() usage.Store {
+	dynamo := newFakeUsageDynamoDB()
+	path := filepath.Join(t.TempDir(), "invarity.db")
+	backends := map[string]func() usage.Store{
+		"sqlite": func() usage.Store {
+			db, err := store.NewSQLiteStore(context.Background(), path)
+			if err != nil {
+				t.Fatalf("open sqlite: %v", err)
+			}
+			t.Cleanup(func() { db.Close() })
+			s, err := usage.NewSQLiteStore(context.Background(), db.DB())
+			if err != nil {
+				t.Fatalf("open sqlite usage store: %v", err)
+			}
+			return s
+		},
+		"dynamodb": func() usage.Store {
+			return usage.NewDynamoDBStore(dynamo, "invarity-usage")
+		},
+	}
+	if os.Getenv(postgresTestURLEnv) != "" {
+		var db *sql.DB
+		backends["postgres"] = func() usage.Store {
+			if db == nil {
+				db = openTestPostgres(t)
+			}
+			return usage.NewPostgresStore(db)
+		}
+	}
+	return backends
+}
+
+func TestUsage_StoresRollUpDaily(t *testing.T) {
+	backends := usageBackends(t)
+	backends["memory"] = func() usage.Store { return usage.NewInMemoryStore() }
+	now := time.Now().UTC()
+	today := usage.DayStart(now)
+
+	for name, open := range backends {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			s := open()
+			records := []*usage.Record{
+				{TenantID: "acme", PrincipalID: "agent", ToolID: "send_email", Model: "functiongemma", PromptTokens: 400, CompletionTokens: 20, TotalTokens: 420, CostUSD: 0.25, RecordedAt: today.Add(time.Hour)},
+				{TenantID: "acme", PrincipalID: "agent", ToolID: "send_email", Model: "functiongemma", PromptTokens: 400, CompletionTokens: 20, TotalTokens: 420, CostUSD: 0.25, RecordedAt: today.Add(2 * time.Hour)},
+				{TenantID: "acme", PrincipalID: "agent", ToolID: "send_email", Model: "llama-guard-3", TotalTokens: 1050, RecordedAt: today.Add(time.Hour)},
+				{TenantID: "globex", PrincipalID: "ops", ToolID: "run_query", Model: "functiongemma", TotalTokens: 100, RecordedAt: today.Add(time.Hour)},
+			}
+			if err := s.Add(ctx, records...); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+			if err := s.Add(ctx, &usage.Record{TenantID: "acme", PrincipalID: "agent", ToolID: "send_email", Model: "functiongemma", TotalTokens: 420, CostUSD: 0.25, RecordedAt: today.Add(3 * time.Hour)}); err != nil {
+				t.Fatalf("add: %v", err)
+			}
+
+			rollups, err := s.List(ctx, &usage.Filter{TenantID: "acme", Since: usage.MonthStart(now), Until: usage.MonthStart(now).AddDate(0, 1, 0)})
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if len(rollups) != 2 {
+				t.Fatalf("expected 2 rollups for acme, got %d", len(rollups))
+			}
+			gemma := rollups[0]
+			if gemma.Model != "functiongemma" || gemma.Calls != 3 || gemma.TotalTokens != 1260 || gemma.PromptTokens != 800 ||
+				math.Abs(gemma.CostUSD-0.75) > 1e-9 || !gemma.RecordedAt.Equal(today) {
+				t.Errorf("unexpected rollup %+v", gemma)
+			}
+
+			if byTool, _ := s.List(ctx, &usage.Filter{ToolID: "run_query"}); len(byTool) != 1 || byTool[0].TenantID != "globex" {
+				t.Errorf("expected globex's rollup by tool, got %+v", byTool)
+			}
+			if later, _ := s.List(ctx, &usage.Filter{Since: today.AddDate(0, 0, 1)}); len(later) != 0 {
+				t.Errorf("expected no rollups after today, got %+v", later)
+			}
+
+			used, err := s.TenantTokens(ctx, "acme", usage.MonthStart(now))
+			if err != nil || used != 3*420+1050 {
+				t.Errorf("acme tokens = %d, %v; want %d", used, err, 3*420+1050)
+			}
+		})
+	}
+}
+
+func TestUsage_ReplicasShareQuota(t *testing.T) {
+	for name, open := range usageBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			replica := func() *firewall.Pipeline {
+				meter := usage.NewMeter(usage.MeterConfig{
+					Store:       open(),
+					Quotas:      usage.Quotas{"free": 4000},
+					DefaultPlan: "free",
+				})
+				return newUsagePipeline(meter, audit.NewInMemoryStore())
+			}
+			a, b := replica(), replica()
+
+			// Each call uses 2310 tokens: one on each replica reaches the quota
+			if _, err := a.Evaluate(context.Background(), usageRequest("acme", "agent")); err != nil {
+				t.Fatalf("replica a: %v", err)
+			}
+			if _, err := b.Evaluate(context.Background(), usageRequest("acme", "agent")); err != nil {
+				t.Fatalf("replica b: %v", err)
+			}
+			for _, p := range []*firewall.Pipeline{a, b} {
+				_, err := p.Evaluate(context.Background(), usageRequest("acme", "agent"))
+				var quotaErr *usage.QuotaExceededError
+				if !errors.As(err, &quotaErr) || quotaErr.Used != 2*2310 {
+					t.Errorf("expected the shared quota to be exceeded, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestUsage_InMemoryStoreKeepsRecentMonths(t *testing.T) {
+	s := usage.NewInMemoryStore()
+	ctx := context.Background()
+	thisMonth := usage.MonthStart(time.Now())
+	for _, at := range []time.Time{thisMonth.AddDate(0, -2, 0), thisMonth.AddDate(0, -1, 0), thisMonth} {
+		if err := s.Add(ctx, &usage.Record{TenantID: "acme", ToolID: "send_email", Model: "functiongemma", TotalTokens: 100, RecordedAt: at}); err != nil {
+			t.Fatalf("add: %v", err)
+		}
+	}
+
+	records, _ := s.List(ctx, nil)
+	if len(records) != 2 || !records[0].RecordedAt.Equal(thisMonth.AddDate(0, -1, 0)) {
+		t.Errorf("expected the current and previous month only, got %+v", records)
+	}
+	if used, _ := s.TenantTokens(ctx, "acme", time.Time{}); used != 200 {
+		t.Errorf("expected 200 tokens in the window, got %d", used)
+	}
+}
+
+func TestUsage_HTTP(t *testing.T) {
+	meter := usage.NewMeter(usage.MeterConfig{
+		Store:       usage.NewInMemoryStore(),
+		Pricing:     testPricing,
+		Quotas:      usage.Quotas{"free": 1},
+		DefaultPlan: "free",
+	})
+	router := invarhttp.NewRouter(invarhttp.RouterConfig{
+		Logger:      zap.NewNop(),
+		Pipeline:    newUsagePipeline(meter, audit.NewInMemoryStore()),
+		AdminAPIKey: "operator-secret",
+		Meter:       meter,
+	})
+
+	evaluate := func() *httptest.ResponseRecorder {
+		body, _ := json.Marshal(usageRequest("acme", "agent"))
+		req := httptest.NewRequest(http.MethodPost, "/v1/firewall/evaluate", strings.NewReader(string(body)))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := evaluate(); rec.Code != http.StatusOK {
+		t.Fatalf("first evaluate: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := evaluate()
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), "QUOTA_EXCEEDED") {
+		t.Errorf("expected 429 QUOTA_EXCEEDED, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/admin/usage?tenant_id=acme&group_by=tool", nil)
+	req.Header.Set("Authorization", "Bearer operator-secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("usage report: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report usage.Report
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(report.Groups) != 1 || report.Groups[0].Key != "send_email" || report.Total.CostUSD == 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v1/admin/usage?group_by=color", nil)
+	req.Header.Set("Authorization", "Bearer operator-secret")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid group_by: expected 400, got %d", rec.Code)
+	}
+}
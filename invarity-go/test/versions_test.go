@@ -0,0 +1,232 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"invarity/internal/firewall"
+	"invarity/internal/gitops"
+	"invarity/internal/semver"
+	"invarity/internal/store"
+	"invarity/internal/types"
+)
+
+func TestSemver_Ordering(t *testing.T) {
+	// Each version has higher precedence than the one before it
+	ordered := []string{
+		"0.9.0", "1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.2.0", "1.9.0", "1.10.0", "2.0.0",
+	}
+	for i := 1; i < len(ordered); i++ {
+		a, err := semver.Parse(ordered[i-1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := semver.Parse(ordered[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if semver.Compare(a, b) != -1 || semver.Compare(b, a) != 1 {
+			t.Errorf("expected %s < %s", ordered[i-1], ordered[i])
+		}
+	}
+
+	a, _ := semver.Parse("1.0.0+build.1")
+	b, _ := semver.Parse("1.0.0+build.2")
+	if semver.Compare(a, b) != 0 {
+		t.Error("expected build metadata to be ignored")
+	}
+
+	for _, invalid := range []string{"", "1", "1.2", "v1.2.3", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3-a..b", "1.2.x"} {
+		if _, err := semver.Parse(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSemver_Ranges(t *testing.T) {
+	tests := []struct {
+		rng string
+		in  []string
+		out []string
+	}{
+		{"^1.2.3", []string{"1.2.3", "1.9.0", "1.10.4"}, []string{"1.2.2", "2.0.0", "1.3.0-rc.1"}},
+		{"^1.2", []string{"1.2.0", "1.99.0"}, []string{"1.1.9", "2.0.0"}},
+		{"^1", []string{"1.0.0", "1.5.2"}, []string{"0.9.9", "2.0.0"}},
+		{"^0.2.3", []string{"0.2.3", "0.2.9"}, []string{"0.3.0", "0.2.2"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4", "0.0.2"}},
+		{"~2.1.0", []string{"2.1.0", "2.1.7"}, []string{"2.2.0", "2.0.9"}},
+		{"~2.1", []string{"2.1.0", "2.1.3"}, []string{"2.2.0"}},
+		{"~2", []string{"2.0.0", "2.9.9"}, []string{"3.0.0", "1.9.9"}},
+	}
+	for _, tt := range tests {
+		r, err := semver.ParseRange(tt.rng)
+		if err != nil {
+			t.Fatalf("parse %s: %v", tt.rng, err)
+		}
+		for _, s := range tt.in {
+			if v, _ := semver.Parse(s); !r.Contains(v) {
+				t.Errorf("expected %s to contain %s", tt.rng, s)
+			}
+		}
+		for _, s := range tt.out {
+			if v, _ := semver.Parse(s); r.Contains(v) {
+				t.Errorf("expected %s not to contain %s", tt.rng, s)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"1.2.3", "^", "^1.2.3.4", "~1.x", "^1.2.3-rc.1", ">=1.0.0"} {
+		if _, err := semver.ParseRange(invalid); err == nil {
+			t.Errorf("expected range %q to be rejected", invalid)
+		}
+	}
+}
+
+// newVersionedStore registers acme's send_email in the given versions.
+func newVersionedStore(t *testing.T, versions ...string) (*store.InMemoryStore, *store.InMemoryBlobStore) {
+	t.Helper()
+	s, blobs := store.NewInMemoryStore(), store.NewInMemoryBlobStore()
+	for _, version := range versions {
+		registerCachedTool(t, s, blobs, "send_email", version)
+	}
+	return s, blobs
+}
+
+func TestResolver_LatestVersion(t *testing.T) {
+	ctx := context.Background()
+	s, blobs := newVersionedStore(t, "1.2.0", "1.9.0", "1.10.0", "2.0.0-rc.1", "nightly")
+	registerCachedTool(t, s, blobs, "send_email_bulk", "9.0.0")
+
+	latest, err := firewall.LatestToolVersion(ctx, s, "acme", "send_email", "")
+	if err != nil || latest == nil || latest.Version != "1.10.0" {
+		t.Fatalf("expected 1.10.0, got %+v %v", latest, err)
+	}
+	if latest, _ := firewall.LatestToolVersion(ctx, s, "acme", "send_email", "~1.9"); latest == nil || latest.Version != "1.9.0" {
+		t.Errorf("expected ~1.9 to resolve to 1.9.0, got %+v", latest)
+	}
+	if latest, _ := firewall.LatestToolVersion(ctx, s, "acme", "send_email", "^2"); latest != nil {
+		t.Errorf("expected ^2 not to match a prerelease, got %+v", latest)
+	}
+	if latest, _ := firewall.LatestToolVersion(ctx, s, "globex", "send_email", ""); latest != nil {
+		t.Errorf("expected versions to be scoped to their tenant, got %+v", latest)
+	}
+
+	if err := s.DeprecateTool(ctx, "acme", "send_email", "1.10.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeprecateTool(ctx, "acme", "send_email", "3.0.0"); err == nil {
+		t.Error("expected deprecating a missing version to fail")
+	}
+
+	// A direct lookup without a version resolves the latest non-deprecated release
+	resolver := firewall.NewToolResolver(s, blobs)
+	result, err := resolveCached(resolver, "", "send_email")
+	if err != nil || result.ResolvedVia != "direct" || result.Tool.Version != "1.9.0" {
+		t.Fatalf("expected 1.9.0, got %+v %v", result, err)
+	}
+
+	// A deprecated version still resolves when pinned, flagged as deprecated
+	req := &types.ToolCallRequest{TenantID: "acme"}
+	req.ToolCall.ActionID = "send_email"
+	req.ToolCall.Version = "1.10.0"
+	result, err = resolver.ResolveTool(ctx, req)
+	if err != nil || !result.Tool.Deprecated {
+		t.Fatalf("expected the pinned deprecated version, got %+v %v", result, err)
+	}
+
+	if _, err := resolveCached(resolver, "", "wire_money"); !errors.Is(err, firewall.ErrToolNotFound) {
+		t.Errorf("expected ErrToolNotFound, got %v", err)
+	}
+}
+
+func TestResolver_ToolsetLock(t *testing.T) {
+	ctx := context.Background()
+	s, blobs := newVersionedStore(t, "1.2.0", "2.0.0")
+	emailHash := registerCachedTool(t, s, blobs, "send_email", "1.3.1")
+	refundHash := registerCachedTool(t, s, blobs, "issue_refund", "2.1.4")
+
+	manifest := &types.ToolsetManifest{
+		ToolsetID: "billing",
+		Revision:  "1",
+		Tools: []types.ToolRef{
+			{ToolID: "send_email", Version: "^1.2"},
+			{ToolID: "issue_refund", Version: "2.1.4"},
+		},
+		Lock: []types.LockedToolRef{{ToolID: "send_email", Version: "2.0.0"}}, // Replaced
+	}
+	if err := manifest.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := firewall.LockToolset(ctx, s, "acme", manifest); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+	want := []types.LockedToolRef{
+		{ToolID: "send_email", Constraint: "^1.2", Version: "1.3.1", SchemaHash: emailHash},
+		{ToolID: "issue_refund", Constraint: "2.1.4", Version: "2.1.4", SchemaHash: refundHash},
+	}
+	if len(manifest.Lock) != len(want) || manifest.Lock[0] != want[0] || manifest.Lock[1] != want[1] {
+		t.Fatalf("unexpected lock %+v", manifest.Lock)
+	}
+
+	// The principal keeps resolving the locked version after a newer match is registered
+	key := store.ToolsetManifestKey("acme", "billing", "1")
+	s.RegisterToolset(ctx, "acme", "billing", "1", "Billing", key, "u1", 2)
+	blobs.PutJSON(ctx, key, manifest)
+	agent, _ := s.CreatePrincipal(ctx, "acme", "billing-agent", "agent", "u1")
+	s.SetPrincipalActiveToolset(ctx, "acme", agent.PrincipalID, "billing", "1", "u1")
+	registerCachedTool(t, s, blobs, "send_email", "1.4.0")
+
+	resolver := firewall.NewToolResolver(s, blobs)
+	result, err := resolveCached(resolver, agent.PrincipalID, "send_email")
+	if err != nil || result.Tool.Version != "1.3.1" {
+		t.Fatalf("expected the locked 1.3.1, got %+v %v", result, err)
+	}
+	tools, err := resolver.LoadToolset(ctx, "acme", "billing", "1")
+	if err != nil || len(tools) != 2 || tools[0].Version != "1.3.1" {
+		t.Fatalf("expected the locked tools, got %v", err)
+	}
+
+	// References that do not resolve are all reported
+	unresolved := &types.ToolsetManifest{ToolsetID: "billing", Revision: "2", Tools: []types.ToolRef{
+		{ToolID: "send_email", Version: "~3.0"},
+		{ToolID: "issue_refund", Version: "1.0.0"},
+	}}
+	err = firewall.LockToolset(ctx, s, "acme", unresolved)
+	if !errors.Is(err, firewall.ErrToolNotFound) ||
+		!strings.Contains(err.Error(), "no version of send_email matches ~3.0") ||
+		!strings.Contains(err.Error(), "tool not found: issue_refund version 1.0.0") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if unresolved.Lock != nil {
+		t.Error("expected no lock for an unresolved toolset")
+	}
+
+	invalid := &types.ToolsetManifest{ToolsetID: "billing", Revision: "3", Tools: []types.ToolRef{{ToolID: "send_email", Version: "^1.x"}}}
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected an invalid range to fail validation")
+	}
+}
+
+func TestGitOps_ToolsetRanges(t *testing.T) {
+	dir := newGitOpsTree(t)
+	writeGitOpsFile(t, dir, "acme/tools/stripe/refund-1.2.yaml", strings.ReplaceAll(refundTool, "version: 1.0.0", "version: 1.2.0"))
+	writeGitOpsFile(t, dir, "acme/tools/stripe/refund-2.0.yaml", strings.ReplaceAll(refundTool, "version: 1.0.0", "version: 2.0.0"))
+	writeGitOpsFile(t, dir, "acme/toolsets/payments.yaml", strings.ReplaceAll(paymentsToolset, "version: 1.0.0", `version: "^1.0"`))
+
+	source, err := gitops.NewSource(gitops.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	result, err := resolveRefund(t, source, "")
+	if err != nil || result.Tool.Version != "1.2.0" {
+		t.Fatalf("expected ^1.0 to resolve to 1.2.0, got %+v %v", result, err)
+	}
+
+	writeGitOpsFile(t, dir, "acme/toolsets/payments.yaml", strings.ReplaceAll(paymentsToolset, "version: 1.0.0", `version: "~3.1"`))
+	if _, err := gitops.Load(dir); err == nil || !strings.Contains(err.Error(), "acme/toolsets/payments.yaml: tool not found: no version of stripe.refund_payment matches ~3.1") {
+		t.Errorf("expected an unmatched range to be rejected, got %v", err)
+	}
+}
@@ -260,6 +260,18 @@ export class FirewallStack extends cdk.Stack {
       projectionType: dynamodb.ProjectionType.ALL,
     });
 
+    // Usage Table - PK: tenant_id, SK: day#principal_id#tool_id#model (daily token rollups)
+    const usageTable = new dynamodb.Table(this, 'UsageTable', {
+      tableName: `${prefix}-usage`,
+      partitionKey: { name: 'tenant_id', type: dynamodb.AttributeType.STRING },
+      sortKey: { name: 'rollup_key', type: dynamodb.AttributeType.STRING },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      pointInTimeRecovery: true,
+      removalPolicy: cdk.RemovalPolicy.RETAIN,
+      encryption: dynamodb.TableEncryption.CUSTOMER_MANAGED,
+      encryptionKey: kmsKey,
+    });
+
     // ========================================
     // Identity Construct (Cognito + User/Membership/Token tables)
     // ========================================
@@ -337,6 +349,7 @@ export class FirewallStack extends cdk.Stack {
     toolsTable.grantReadWriteData(taskRole);
     toolsetsTable.grantReadWriteData(taskRole);
     auditIndexTable.grantReadWriteData(taskRole);
+    usageTable.grantReadWriteData(taskRole);
     manifestsBucket.grantReadWrite(taskRole);
     auditBlobsBucket.grantReadWrite(taskRole);
     kmsKey.grantEncryptDecrypt(taskRole);
@@ -367,6 +380,7 @@ export class FirewallStack extends cdk.Stack {
         TOOLS_TABLE: toolsTable.tableName,
         TOOLSETS_TABLE: toolsetsTable.tableName,
         AUDIT_INDEX_TABLE: auditIndexTable.tableName,
+        INVARITY_DDB_TABLE_USAGE: usageTable.tableName,
         MANIFESTS_BUCKET: manifestsBucket.bucketName,
         AUDIT_BLOBS_BUCKET: auditBlobsBucket.bucketName,
         KMS_KEY_ARN: kmsKey.keyArn,
@@ -490,6 +504,11 @@ export class FirewallStack extends cdk.Stack {
       stringValue: auditIndexTable.tableName,
     });
 
+    new ssm.StringParameter(this, 'SsmUsageTable', {
+      parameterName: `${ssmPrefix}/dynamodb/usage_table`,
+      stringValue: usageTable.tableName,
+    });
+
     new ssm.StringParameter(this, 'SsmManifestsBucket', {
       parameterName: `${ssmPrefix}/s3/manifests_bucket`,
       stringValue: manifestsBucket.bucketName,